@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Names of the queues reported by GET /admin/queues
+const (
+	queueJobs         = "jobs"
+	queueWebhooks     = "webhooks"
+	queuePluginReload = "plugin_reload"
+)
+
+// QueueInfo describes one internal queue
+type QueueInfo struct {
+	Name             string                 `json:"name"`
+	Description      string                 `json:"description"`
+	Depth            int                    `json:"depth"`
+	OldestAgeSeconds *float64               `json:"oldest_age_seconds"` // null when the queue is empty or does not record ages
+	Details          map[string]interface{} `json:"details,omitempty"`
+}
+
+// QueuesResponse is the response for GET /admin/queues
+type QueuesResponse struct {
+	Success   bool        `json:"success"`
+	Queues    []QueueInfo `json:"queues"`
+	Timestamp string      `json:"timestamp"`
+}
+
+// QueueFlushResponse is the response for POST /admin/queues/{name}/flush
+type QueueFlushResponse struct {
+	Success   bool   `json:"success"`
+	Queue     string `json:"queue"`
+	Flushed   int    `json:"flushed"`
+	Timestamp string `json:"timestamp"`
+}
+
+// queueAge returns the age of a queue's oldest item in seconds, or nil when
+// there is none
+func queueAge(oldest time.Time) *float64 {
+	if oldest.IsZero() {
+		return nil
+	}
+	age := math.Round(time.Since(oldest).Seconds()*1000) / 1000
+	return &age
+}
+
+// inspectQueues reports the depth of each internal queue. Each queue is read
+// under its own short lock, so this never waits for queued work.
+func (s *SecAutoServer) inspectQueues() []QueueInfo {
+	var queues []QueueInfo
+
+	if s.jobManager != nil {
+		jobIDs, oldest := s.jobManager.queue.Waiting()
+		queues = append(queues, QueueInfo{
+			Name:             queueJobs,
+			Description:      "Pending jobs waiting for a worker",
+			Depth:            len(jobIDs),
+			OldestAgeSeconds: queueAge(oldest),
+			Details:          s.jobManager.queue.Stats(),
+		})
+	}
+
+	if s.webhookManager != nil {
+		pending, oldest := s.webhookManager.PendingDeliveries()
+		queues = append(queues, QueueInfo{
+			Name:             queueWebhooks,
+			Description:      "Webhook notifications being delivered or waiting to retry",
+			Depth:            pending,
+			OldestAgeSeconds: queueAge(oldest),
+		})
+	}
+
+	if s.pluginManager != nil {
+		queues = append(queues, QueueInfo{
+			Name:        queuePluginReload,
+			Description: "Plugin file changes waiting to be reloaded",
+			Depth:       s.pluginManager.PendingReloads(),
+		})
+	}
+
+	return queues
+}
+
+// flushQueue drains a queue and returns how many items were removed. Pending
+// jobs are cancelled rather than dropped, so they do not stay pending forever.
+func (s *SecAutoServer) flushQueue(name, caller string) (int, bool) {
+	switch name {
+	case queueJobs:
+		if s.jobManager == nil {
+			return 0, false
+		}
+		jobIDs, _ := s.jobManager.queue.Waiting()
+		flushed := 0
+		for _, jobID := range jobIDs {
+			if cancelled, _ := s.jobManager.CancelJob(jobID, "job queue flushed by an administrator", caller); cancelled {
+				flushed++
+			}
+		}
+		return flushed, true
+	case queueWebhooks:
+		if s.webhookManager == nil {
+			return 0, false
+		}
+		return s.webhookManager.FlushDeliveries(), true
+	case queuePluginReload:
+		if s.pluginManager == nil {
+			return 0, false
+		}
+		return s.pluginManager.FlushReloads(), true
+	}
+	return 0, false
+}
+
+// adminQueuesHandler handles GET /admin/queues, which reports the depth and
+// oldest item age of the job, webhook delivery and plugin reload queues, and
+// POST /admin/queues/{name}/flush, which drains one of them. Both require an
+// admin API key.
+func (s *SecAutoServer) adminQueuesHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(r) {
+		logAdminAudit(r, "queues", false, nil)
+		http.Error(w, "Forbidden: admin API key required", http.StatusForbidden)
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/admin/queues"), "/")
+	if path == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		queues := s.inspectQueues()
+		if queues == nil {
+			queues = []QueueInfo{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(QueuesResponse{
+			Success:   true,
+			Queues:    queues,
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		})
+		return
+	}
+
+	name, action, found := strings.Cut(path, "/")
+	if !found || action != "flush" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flushed, ok := s.flushQueue(name, apiKeyCaller(getRequestAPIKey(r)))
+	if !ok {
+		http.Error(w, "Unknown queue: "+name, http.StatusNotFound)
+		return
+	}
+	logAdminAudit(r, "queue_flush", true, map[string]interface{}{
+		"queue":   name,
+		"flushed": flushed,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(QueueFlushResponse{
+		Success:   true,
+		Queue:     name,
+		Flushed:   flushed,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
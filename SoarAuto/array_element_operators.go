@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// evaluateArrayElement handles {"first": array}, {"last": array} and
+// {"nth": [array, index]}, which pick one element of an evaluated array, such
+// as the top hit of an enrichment. A negative nth index counts from the end,
+// so -1 is the last element. A trailing argument is the default returned when
+// the array is missing or empty or the index is out of range; without one
+// that is null. An argument list is always an array, so a literal array is
+// written as {"first": [[1, 2, 3]]}.
+func (re *RuleEngine) evaluateArrayElement(operation map[string]interface{}, op string, data map[string]interface{}) (interface{}, error) {
+	args, isList := operation[op].([]interface{})
+	if !isList {
+		args = []interface{}{operation[op]}
+	}
+
+	required := 1
+	if op == "nth" {
+		required = 2
+	}
+	if len(args) < required || len(args) > required+1 {
+		if op == "nth" {
+			return nil, fmt.Errorf("nth operator requires an array, an index and an optional default")
+		}
+		return nil, fmt.Errorf("%s operator requires an array and an optional default", op)
+	}
+
+	evaluated, err := re.evaluate(args[0], data)
+	if err != nil {
+		return nil, fmt.Errorf("%s failed to evaluate array: %v", op, err)
+	}
+	var items []interface{}
+	switch v := evaluated.(type) {
+	case nil:
+		// No element to pick, e.g. before a lookup has returned results
+	case []interface{}:
+		items = v
+	default:
+		return nil, fmt.Errorf("%s operator requires an array, got %T", op, evaluated)
+	}
+
+	index := 0
+	switch op {
+	case "last":
+		index = len(items) - 1
+	case "nth":
+		value, err := re.evaluate(args[1], data)
+		if err != nil {
+			return nil, fmt.Errorf("nth failed to evaluate index: %v", err)
+		}
+		number, ok := comparableNumber(re.normalizeValue(value))
+		if !ok || number != math.Trunc(number) {
+			return nil, fmt.Errorf("nth operator requires a whole number index, got %v (%T)", value, value)
+		}
+		if math.Abs(number) > float64(len(items)) {
+			index = len(items) // Out of range, without overflowing int
+			break
+		}
+		index = int(number)
+		if index < 0 {
+			index += len(items)
+		}
+	}
+
+	if index >= 0 && index < len(items) {
+		return items[index], nil
+	}
+	if len(args) > required {
+		return re.evaluate(args[required], data)
+	}
+	return nil, nil
+}
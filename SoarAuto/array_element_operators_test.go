@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArrayElementEmptyAndOutOfRange(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	var data map[string]interface{}
+	if err := unmarshalContextJSON([]byte(`{
+		"hits": ["a", "b", "c"],
+		"empty": [],
+		"index": 1,
+		"fallback": "none"
+	}`), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`{"first": {"var": "hits"}}`, `"a"`},
+		{`{"last": {"var": "hits"}}`, `"c"`},
+		{`{"first": [[1, 2, 3]]}`, `1`},
+
+		// Empty and missing arrays give the default, or null
+		{`{"first": {"var": "empty"}}`, `null`},
+		{`{"last": {"var": "empty"}}`, `null`},
+		{`{"first": [{"var": "empty"}, "none"]}`, `"none"`},
+		{`{"last": [{"var": "missing"}, {"var": "fallback"}]}`, `"none"`},
+		{`{"nth": [{"var": "empty"}, 0]}`, `null`},
+		{`{"nth": [{"var": "empty"}, -1, "none"]}`, `"none"`},
+
+		// Indexes at and just past either end
+		{`{"nth": [{"var": "hits"}, 0]}`, `"a"`},
+		{`{"nth": [{"var": "hits"}, 2]}`, `"c"`},
+		{`{"nth": [{"var": "hits"}, 3]}`, `null`},
+		{`{"nth": [{"var": "hits"}, 3, "none"]}`, `"none"`},
+		{`{"nth": [{"var": "hits"}, -1]}`, `"c"`},
+		{`{"nth": [{"var": "hits"}, -3]}`, `"a"`},
+		{`{"nth": [{"var": "hits"}, -4, "none"]}`, `"none"`},
+		{`{"nth": [{"var": "hits"}, 1e300, "none"]}`, `"none"`},
+		{`{"nth": [{"var": "hits"}, -9223372036854775808, "none"]}`, `"none"`},
+
+		// Indexes from the context, including numeric strings
+		{`{"nth": [{"var": "hits"}, {"var": "index"}]}`, `"b"`},
+		{`{"nth": [{"var": "hits"}, "2"]}`, `"c"`},
+		{`{"nth": [{"var": "hits"}, 1.0]}`, `"b"`},
+	}
+
+	for _, test := range tests {
+		var expr, want interface{}
+		if err := unmarshalContextJSON([]byte(test.expr), &expr); err != nil {
+			t.Fatalf("parse %s: %v", test.expr, err)
+		}
+		if err := unmarshalContextJSON([]byte(test.want), &want); err != nil {
+			t.Fatalf("parse %s: %v", test.want, err)
+		}
+		got, err := engine.evaluate(expr, data)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s = %v (%T), want %v", test.expr, got, got, want)
+		}
+	}
+}
+
+func TestArrayElementErrors(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	data := map[string]interface{}{"host": map[string]interface{}{"name": "web-1"}, "hits": []interface{}{"a"}}
+
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`{"first": {"var": "host"}}`, "requires an array, got"},
+		{`{"first": [{"var": "hits"}, "x", "y"]}`, "an array and an optional default"},
+		{`{"nth": [{"var": "hits"}]}`, "an array, an index and an optional default"},
+		{`{"nth": [{"var": "hits"}, 0.5]}`, "whole number index"},
+		{`{"nth": [{"var": "hits"}, "first"]}`, "whole number index"},
+		{`{"nth": [{"var": "hits"}, null]}`, "whole number index"},
+	}
+
+	for _, test := range tests {
+		var expr interface{}
+		if err := unmarshalContextJSON([]byte(test.expr), &expr); err != nil {
+			t.Fatalf("parse %s: %v", test.expr, err)
+		}
+		if _, err := engine.evaluate(expr, data); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s error = %v, want one containing %q", test.expr, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+)
+
+// errorTypeAssertion is the error_type of runs failed by an "assert" operation
+const errorTypeAssertion = "assertion_failed"
+
+// AssertionError is returned when the condition of an "assert" operation is
+// falsy. Like PlaybookAbort it is passed up through nested playbooks and
+// loops unwrapped, so callers can report it apart from other failures.
+type AssertionError struct {
+	Message   string
+	Condition interface{}
+	Rule      int // Index of the top-level rule that failed, from 1
+}
+
+func (ae *AssertionError) Error() string {
+	if ae.Rule > 0 {
+		return fmt.Sprintf("assertion failed at rule %d: %s", ae.Rule, ae.Message)
+	}
+	return fmt.Sprintf("assertion failed: %s", ae.Message)
+}
+
+// playbookErrorType classifies a playbook error for the error_type field of
+// responses and jobs; generic failures have no type
+func playbookErrorType(err error) string {
+	if _, ok := err.(*AssertionError); ok {
+		return errorTypeAssertion
+	}
+	return ""
+}
+
+// evaluateAssertOperation handles the "assert" operation, which fails the
+// playbook with an AssertionError when "that" evaluates falsy. The message
+// defaults to the condition itself.
+func (re *RuleEngine) evaluateAssertOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("assert operation requires an object")
+	}
+	condition, exists := specMap["that"]
+	if !exists {
+		return nil, fmt.Errorf("assert operation requires a condition in that")
+	}
+
+	result, err := re.evaluate(condition, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate assert condition: %v", err)
+	}
+	if re.isTruthy(result) {
+		return map[string]interface{}{"assert": "passed"}, nil
+	}
+
+	message := compactJSON(condition)
+	if value, exists := specMap["message"]; exists && value != nil {
+		message = fmt.Sprintf("%v", value)
+	}
+
+	logger.Warning("Playbook assertion failed", map[string]interface{}{
+		"component": "rules_engine",
+		"message":   message,
+		"condition": condition,
+	})
+	return nil, &AssertionError{Message: message, Condition: condition}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	importStatementRegex = regexp.MustCompile(`^import\s+(.+)$`)
+	fromImportRegex      = regexp.MustCompile(`^from\s+([\w.]+)\s+import\s+(.+)$`)
+	dynamicImportRegex   = regexp.MustCompile(`(?:importlib\.import_module|__import__)\(\s*['"]([\w.]+)['"]`)
+)
+
+// automationImport is a module imported by an automation script
+type automationImport struct {
+	Module string
+	Line   int
+}
+
+// parseAutomationImports lists the modules a Python script imports, including
+// modules loaded by name through importlib.import_module or __import__
+func parseAutomationImports(content []byte) []automationImport {
+	var imports []automationImport
+	for i, rawLine := range strings.Split(string(content), "\n") {
+		line := strings.TrimSpace(rawLine)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if comment := strings.Index(line, " #"); comment >= 0 {
+			line = strings.TrimSpace(line[:comment])
+		}
+
+		if match := importStatementRegex.FindStringSubmatch(line); match != nil {
+			for _, name := range splitImportNames(match[1]) {
+				imports = append(imports, automationImport{Module: name, Line: i + 1})
+			}
+		} else if match := fromImportRegex.FindStringSubmatch(line); match != nil {
+			module := match[1]
+			imports = append(imports, automationImport{Module: module, Line: i + 1})
+			// "from integrations import x" imports the integrations.x module
+			if module == "integrations" {
+				for _, name := range splitImportNames(match[2]) {
+					imports = append(imports, automationImport{Module: module + "." + name, Line: i + 1})
+				}
+			}
+		}
+
+		for _, match := range dynamicImportRegex.FindAllStringSubmatch(line, -1) {
+			imports = append(imports, automationImport{Module: match[1], Line: i + 1})
+		}
+	}
+	return imports
+}
+
+// splitImportNames splits "a, b as c, (d)" into module names
+func splitImportNames(list string) []string {
+	var names []string
+	for _, part := range strings.Split(strings.Trim(list, "() "), ",") {
+		fields := strings.Fields(part)
+		if len(fields) > 0 && fields[0] != "*" {
+			names = append(names, fields[0])
+		}
+	}
+	return names
+}
+
+// integrationModuleName returns the integration an imported module belongs to.
+// A module is an integration when it lives in the integrations package, is
+// named like one (*_integration), or matches an uploaded integration file.
+func integrationModuleName(module string) (string, bool) {
+	if name, found := strings.CutPrefix(module, "integrations."); found {
+		return strings.SplitN(name, ".", 2)[0], true
+	}
+
+	name := strings.SplitN(module, ".", 2)[0]
+	if strings.HasSuffix(name, "_integration") {
+		return name, true
+	}
+	if _, err := os.Stat(filepath.Join("../integrations", name+".py")); err == nil {
+		return name, true
+	}
+	return "", false
+}
+
+// validateIntegrationImports reports imports of integration modules that are
+// not on the configured allowlist. An empty allowlist allows every integration.
+func (s *SecAutoServer) validateIntegrationImports(content []byte) []ValidationError {
+	allowlist := s.engine.config.Security.InputValidation.AllowedIntegrationImports
+	if len(allowlist) == 0 {
+		return nil
+	}
+
+	allowed := make(map[string]bool, len(allowlist))
+	for _, name := range allowlist {
+		allowed[strings.TrimSuffix(name, ".py")] = true
+	}
+
+	var errors []ValidationError
+	reported := make(map[string]bool)
+	for _, imp := range parseAutomationImports(content) {
+		name, isIntegration := integrationModuleName(imp.Module)
+		if !isIntegration || allowed[name] || reported[name] {
+			continue
+		}
+		reported[name] = true
+		errors = append(errors, ValidationError{
+			Field:   "imports",
+			Message: fmt.Sprintf("Integration %s is not on the allowed integration imports list (line %d)", name, imp.Line),
+			Value:   imp.Module,
+		})
+	}
+	return errors
+}
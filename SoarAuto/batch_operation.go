@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultBatchConcurrency is how many elements a batch runs at once by default
+	defaultBatchConcurrency = 5
+	// maxBatchConcurrency caps the concurrency a batch may request
+	maxBatchConcurrency = 50
+)
+
+// batchReservedKeys are the batch settings; other keys are script parameters
+var batchReservedKeys = map[string]bool{
+	"run": true, "over": true, "as": true, "concurrency": true, "output_var": true, "timeout": true,
+}
+
+// batchElementKey is the key of an element's result in the batch output: the
+// element itself when it is a string, otherwise its compact JSON
+func batchElementKey(element interface{}) string {
+	if str, ok := element.(string); ok {
+		return str
+	}
+	return compactJSON(element)
+}
+
+// evaluateBatchOperation handles the "batch" operation, which runs one
+// automation for each element of "over", up to "concurrency" at a time. Each
+// run sees the context with the element under "as"; the parameters'
+// template variables are resolved per element. Script results are not merged
+// into the context: they are stored in "output_var" keyed by element, with
+// failed elements recorded as {"error": ...}. An optional "timeout" bounds
+// the whole batch; elements still pending or running when it expires are
+// recorded as timed out.
+func (re *RuleEngine) evaluateBatchOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("batch operation requires an object")
+	}
+
+	scriptName, ok := specMap["run"].(string)
+	if !ok || scriptName == "" {
+		return nil, fmt.Errorf("batch operation requires an automation name in run")
+	}
+	overExpr, exists := specMap["over"]
+	if !exists {
+		return nil, fmt.Errorf("batch operation requires an array in over")
+	}
+
+	as := "item"
+	if value, exists := specMap["as"]; exists {
+		as, _ = value.(string)
+		if as == "" {
+			return nil, fmt.Errorf("batch as must be a non-empty variable name")
+		}
+	}
+
+	outputVar := scriptName + "_results"
+	if value, exists := specMap["output_var"]; exists {
+		outputVar, _ = value.(string)
+		if outputVar == "" {
+			return nil, fmt.Errorf("batch output_var must be a non-empty string")
+		}
+	}
+
+	concurrency := defaultBatchConcurrency
+	if value, exists := specMap["concurrency"]; exists {
+		number, ok := jsonLogicNumeric(value)
+		if !ok || number < 1 || number != float64(int(number)) {
+			return nil, fmt.Errorf("batch concurrency must be a positive whole number")
+		}
+		concurrency = int(number)
+		if concurrency > maxBatchConcurrency {
+			concurrency = maxBatchConcurrency
+		}
+	}
+
+	var timeout time.Duration
+	if value, exists := specMap["timeout"]; exists {
+		timeoutStr, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("batch timeout must be a duration string")
+		}
+		parsed, err := time.ParseDuration(timeoutStr)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("batch timeout must be a positive duration, got %q", timeoutStr)
+		}
+		timeout = parsed
+	}
+
+	evaluated, err := re.evaluatePlaybookRule(overExpr, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate batch over: %v", err)
+	}
+	var elements []interface{}
+	switch v := evaluated.(type) {
+	case nil:
+		// Nothing to run
+	case []interface{}:
+		elements = v
+	default:
+		return nil, fmt.Errorf("batch over must be an array, got %T", evaluated)
+	}
+	if err := re.checkCollectionSize("batch", len(elements)); err != nil {
+		return nil, err
+	}
+
+	params := make(map[string]interface{})
+	for key, value := range specMap {
+		if !batchReservedKeys[key] {
+			params[key] = value
+		}
+	}
+
+	// Each run reads its own shallow copy of the context, so runs never
+	// share a map that is being written
+	snapshot := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		snapshot[key] = value
+	}
+	scriptPath := re.getScriptPath(scriptName)
+
+	runElement := func(element interface{}) (interface{}, error) {
+		scope := make(map[string]interface{}, len(snapshot)+1)
+		for key, value := range snapshot {
+			scope[key] = value
+		}
+		scope[as] = element
+
+		processedData := make(map[string]interface{}, len(scope)+len(params))
+		for key, value := range scope {
+			processedData[key] = value
+		}
+		if resolved, ok := re.processTemplateVariables(params, scope).(map[string]interface{}); ok {
+			for key, value := range resolved {
+				processedData[key] = value
+			}
+		}
+		return re.runScript(scriptName, scriptPath, processedData)
+	}
+
+	logger.Info("Running batch", map[string]interface{}{
+		"component":   "rules_engine",
+		"script":      scriptName,
+		"elements":    len(elements),
+		"concurrency": concurrency,
+	})
+
+	var mutex sync.Mutex
+	outcomes := make([]interface{}, len(elements))
+	failed := make([]bool, len(elements))
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	go func() {
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, concurrency)
+	dispatch:
+		for i, element := range elements {
+			select {
+			case slots <- struct{}{}:
+			case <-stop:
+				break dispatch
+			}
+			wg.Add(1)
+			go func(i int, element interface{}) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				result, err := runElement(element)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				if err != nil {
+					outcomes[i] = map[string]interface{}{"error": err.Error()}
+					failed[i] = true
+				} else {
+					outcomes[i] = result
+				}
+			}(i, element)
+		}
+		wg.Wait()
+		close(done)
+	}()
+
+	timedOut := false
+	select {
+	case <-done:
+	case <-deadline:
+		timedOut = true
+		close(stop)
+	}
+
+	// Runs still going after a timeout finish in the background; their
+	// results are discarded
+	mutex.Lock()
+	results := make(map[string]interface{}, len(elements))
+	succeeded, failures := 0, 0
+	for i, element := range elements {
+		outcome := outcomes[i]
+		switch {
+		case outcome == nil:
+			outcome = map[string]interface{}{"error": fmt.Sprintf("batch timed out after %s", timeout)}
+			failures++
+		case failed[i]:
+			failures++
+		default:
+			succeeded++
+		}
+		results[batchElementKey(element)] = outcome
+	}
+	mutex.Unlock()
+
+	if err := setContextPath(data, strings.Split(outputVar, "."), results); err != nil {
+		return nil, fmt.Errorf("batch operation failed: %v", err)
+	}
+
+	logger.Info("Completed batch", map[string]interface{}{
+		"component": "rules_engine",
+		"script":    scriptName,
+		"succeeded": succeeded,
+		"failed":    failures,
+		"timed_out": timedOut,
+	})
+
+	return map[string]interface{}{
+		"batch":     scriptName,
+		"total":     len(elements),
+		"succeeded": succeeded,
+		"failed":    failures,
+		"timed_out": timedOut,
+	}, nil
+}
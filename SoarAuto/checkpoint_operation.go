@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+const (
+	// defaultMaxCheckpoints applies when rules_engine.max_checkpoints is unset
+	defaultMaxCheckpoints = 10
+	// defaultMaxCheckpointBytes applies when rules_engine.max_checkpoint_bytes is unset
+	defaultMaxCheckpointBytes = 10 << 20
+)
+
+// contextCheckpoints holds the context snapshots taken by "checkpoint" during
+// one execution, shared by its nested playbooks. Snapshots are kept as JSON,
+// which is both a deep copy and the measure of their size.
+type contextCheckpoints struct {
+	mutex     sync.Mutex
+	snapshots map[string][]byte
+	size      int
+}
+
+func newContextCheckpoints() *contextCheckpoints {
+	return &contextCheckpoints{snapshots: make(map[string][]byte)}
+}
+
+// checkpointLabel returns the label of a checkpoint or restore rule, given as
+// a string or as {"label": "..."}
+func checkpointLabel(op string, spec interface{}) (string, error) {
+	if specMap, ok := spec.(map[string]interface{}); ok {
+		spec = specMap["label"]
+	}
+	label, ok := spec.(string)
+	if !ok || label == "" {
+		return "", fmt.Errorf("%s operation requires a non-empty label", op)
+	}
+	return label, nil
+}
+
+// evaluateCheckpointOperation handles {"checkpoint": "label"}, which
+// snapshots the current context under label so "restore" can return to it.
+// Taking a checkpoint again under the same label replaces it. The number of
+// checkpoints and their total size per run are bounded by max_checkpoints
+// and max_checkpoint_bytes.
+func (re *RuleEngine) evaluateCheckpointOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	label, err := checkpointLabel("checkpoint", spec)
+	if err != nil {
+		return nil, err
+	}
+	if re.checkpoints == nil {
+		return nil, fmt.Errorf("checkpoint operation is not available outside a playbook run")
+	}
+
+	if err := re.checkContextBounds(data); err != nil {
+		return nil, fmt.Errorf("checkpoint %s: %v", label, err)
+	}
+	snapshot, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("checkpoint %s: failed to snapshot context: %v", label, err)
+	}
+
+	maxCheckpoints := re.maxCheckpoints
+	if maxCheckpoints <= 0 {
+		maxCheckpoints = defaultMaxCheckpoints
+	}
+	maxBytes := re.maxCheckpointBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxCheckpointBytes
+	}
+
+	cp := re.checkpoints
+	cp.mutex.Lock()
+	defer cp.mutex.Unlock()
+
+	previous, replacing := cp.snapshots[label]
+	if !replacing && len(cp.snapshots) >= maxCheckpoints {
+		return nil, fmt.Errorf("checkpoint %s: run already holds max_checkpoints of %d", label, maxCheckpoints)
+	}
+	size := cp.size - len(previous) + len(snapshot)
+	if size > maxBytes {
+		return nil, fmt.Errorf("checkpoint %s: snapshots would take %d bytes, exceeding max_checkpoint_bytes of %d", label, size, maxBytes)
+	}
+	cp.snapshots[label] = snapshot
+	cp.size = size
+
+	logger.Debug("Context checkpoint taken", map[string]interface{}{
+		"component": "rules_engine",
+		"label":     label,
+		"bytes":     len(snapshot),
+	})
+
+	return map[string]interface{}{
+		"checkpoint": label,
+		"keys":       len(data),
+	}, nil
+}
+
+// evaluateRestoreOperation handles {"restore": "label"}, which replaces the
+// context with the snapshot taken by the checkpoint of that label, discarding
+// every change made since. The checkpoint is kept and may be restored again.
+// Only the context is rolled back: automations, plugins and outputs that ran
+// in between have already had their effects.
+func (re *RuleEngine) evaluateRestoreOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	label, err := checkpointLabel("restore", spec)
+	if err != nil {
+		return nil, err
+	}
+	if re.checkpoints == nil {
+		return nil, fmt.Errorf("restore operation is not available outside a playbook run")
+	}
+
+	re.checkpoints.mutex.Lock()
+	snapshot, exists := re.checkpoints.snapshots[label]
+	re.checkpoints.mutex.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("restore: no checkpoint named %s", label)
+	}
+
+	var restored map[string]interface{}
+	if err := json.Unmarshal(snapshot, &restored); err != nil {
+		return nil, fmt.Errorf("restore %s: failed to decode snapshot: %v", label, err)
+	}
+
+	// The context map is shared with the caller, so it is replaced in place
+	discarded := DiffContexts(restored, data).Count()
+	for key := range data {
+		delete(data, key)
+	}
+	for key, value := range restored {
+		data[key] = value
+	}
+
+	logger.Info("Context restored from checkpoint", map[string]interface{}{
+		"component": "rules_engine",
+		"label":     label,
+		"discarded": discarded,
+	})
+
+	return map[string]interface{}{
+		"restore":           label,
+		"discarded_changes": discarded,
+	}, nil
+}
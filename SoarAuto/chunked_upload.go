@@ -0,0 +1,594 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// uploadOffsetHeader carries the byte offset a chunk starts at
+	uploadOffsetHeader = "Upload-Offset"
+	// defaultUploadChunkSize bounds a single PATCH body when not configured
+	defaultUploadChunkSize = 5 << 20
+	// uploadSweepInterval is how often expired sessions are removed
+	uploadSweepInterval = time.Minute
+	// defaultMaxUploadSessions bounds the sessions open at once when not configured
+	defaultMaxUploadSessions = 50
+)
+
+// uploadSizeLimits are the largest files accepted per upload type, matching
+// the limits the file validators enforce
+var uploadSizeLimits = map[string]int64{
+	"automation":  1 << 20,
+	"playbook":    1 << 20,
+	"plugin":      10 << 20,
+	"integration": 1 << 20,
+}
+
+// UploadSession tracks a chunked upload in progress. Chunks are appended to a
+// temporary file until Received reaches Size.
+type UploadSession struct {
+	ID         string
+	Type       string
+	PluginType string
+	Filename   string
+	Size       int64
+	Checksum   string
+	Received   int64
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+
+	path  string
+	mutex sync.Mutex
+	// assembled is set once the file is complete and verified, after which
+	// it no longer counts as staged. Guarded by the manager's mutex.
+	assembled bool
+}
+
+// UploadManager keeps chunked upload sessions and their temporary files
+type UploadManager struct {
+	dir          string
+	sessionTTL   time.Duration
+	maxChunkSize int64
+	maxSessions  int
+	sessions     map[string]*UploadSession
+	mutex        sync.RWMutex
+	stopChan     chan struct{}
+	stopOnce     sync.Once
+}
+
+// NewUploadManager creates an upload manager. Sessions live in memory only,
+// so temporary files left by a previous run are removed.
+func NewUploadManager(config UploadsConfig) (*UploadManager, error) {
+	sessionTTL := time.Hour
+	if config.SessionTTL != "" {
+		parsed, err := time.ParseDuration(config.SessionTTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid uploads session_ttl: %v", err)
+		}
+		sessionTTL = parsed
+	}
+
+	maxChunkSize := int64(config.MaxChunkSize)
+	if maxChunkSize <= 0 {
+		maxChunkSize = defaultUploadChunkSize
+	}
+
+	maxSessions := config.MaxSessions
+	if maxSessions <= 0 {
+		maxSessions = defaultMaxUploadSessions
+	}
+
+	if err := os.MkdirAll(config.TempDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	entries, err := os.ReadDir(config.TempDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload directory: %v", err)
+	}
+	for _, entry := range entries {
+		// Only touch files named like session IDs
+		if _, err := uuid.Parse(entry.Name()); err == nil && entry.Type().IsRegular() {
+			os.Remove(filepath.Join(config.TempDir, entry.Name()))
+		}
+	}
+
+	return &UploadManager{
+		dir:          config.TempDir,
+		sessionTTL:   sessionTTL,
+		maxChunkSize: maxChunkSize,
+		maxSessions:  maxSessions,
+		sessions:     make(map[string]*UploadSession),
+		stopChan:     make(chan struct{}),
+	}, nil
+}
+
+// Create starts a session for a file of the given type, size and SHA-256.
+// It fails with an uploadSessionLimitError when the session limit is reached.
+func (um *UploadManager) Create(uploadType, pluginType, filename string, size int64, checksum string) (*UploadSession, error) {
+	session := &UploadSession{
+		ID:         uuid.New().String(),
+		Type:       uploadType,
+		PluginType: pluginType,
+		Filename:   filename,
+		Size:       size,
+		Checksum:   strings.ToLower(checksum),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	session.path = filepath.Join(um.dir, session.ID)
+
+	// Take the slot before creating the file so concurrent requests cannot
+	// both take the last one
+	um.mutex.Lock()
+	if len(um.sessions) >= um.maxSessions {
+		um.mutex.Unlock()
+		return nil, &uploadSessionLimitError{limit: um.maxSessions}
+	}
+	um.sessions[session.ID] = session
+	um.mutex.Unlock()
+
+	file, err := os.OpenFile(session.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		um.mutex.Lock()
+		delete(um.sessions, session.ID)
+		um.mutex.Unlock()
+		return nil, fmt.Errorf("failed to create upload file: %v", err)
+	}
+	file.Close()
+	return session, nil
+}
+
+// StagedBytes returns the declared size of every upload not yet assembled.
+// Sessions reserve their full size when they start, so chunks still to come
+// cannot take space another upload was promised.
+func (um *UploadManager) StagedBytes() int64 {
+	um.mutex.RLock()
+	defer um.mutex.RUnlock()
+
+	var staged int64
+	for _, session := range um.sessions {
+		if !session.assembled {
+			staged += session.Size
+		}
+	}
+	return staged
+}
+
+// Get returns a session by ID
+func (um *UploadManager) Get(id string) (*UploadSession, bool) {
+	um.mutex.RLock()
+	defer um.mutex.RUnlock()
+	session, exists := um.sessions[id]
+	return session, exists
+}
+
+// Remove deletes a session and its temporary file
+func (um *UploadManager) Remove(id string) {
+	um.mutex.Lock()
+	session, exists := um.sessions[id]
+	delete(um.sessions, id)
+	um.mutex.Unlock()
+
+	if exists {
+		os.Remove(session.path)
+	}
+}
+
+// AppendChunk writes a chunk starting at offset. The offset must equal the
+// bytes received so far; a mismatch reports the current offset so the client
+// can resume from it.
+func (um *UploadManager) AppendChunk(session *UploadSession, offset int64, chunk io.Reader) (int64, error) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if offset != session.Received {
+		return session.Received, &uploadOffsetError{expected: session.Received}
+	}
+
+	remaining := session.Size - session.Received
+	limit := min(remaining, um.maxChunkSize)
+
+	file, err := os.OpenFile(session.path, os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return session.Received, fmt.Errorf("failed to open upload file: %v", err)
+	}
+	defer file.Close()
+
+	// Read one byte past the limit to detect oversized chunks
+	written, err := io.Copy(file, io.LimitReader(chunk, limit+1))
+	if written > limit {
+		file.Truncate(session.Received)
+		return session.Received, fmt.Errorf("chunk exceeds the %d bytes allowed at this offset", limit)
+	}
+	if err != nil {
+		// Keep only what was fully written so the client can resume
+		session.Received += written
+		session.UpdatedAt = time.Now()
+		return session.Received, fmt.Errorf("failed to write chunk: %v", err)
+	}
+
+	session.Received += written
+	session.UpdatedAt = time.Now()
+	return session.Received, nil
+}
+
+// Assemble verifies a complete upload against its checksum and opens the
+// assembled file for reading
+func (um *UploadManager) Assemble(session *UploadSession) (*os.File, error) {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	if session.Received != session.Size {
+		return nil, fmt.Errorf("upload incomplete: received %d of %d bytes", session.Received, session.Size)
+	}
+
+	file, err := os.Open(session.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload file: %v", err)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read upload file: %v", err)
+	}
+	if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != session.Checksum {
+		file.Close()
+		return nil, &uploadChecksumError{expected: session.Checksum, actual: checksum}
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to rewind upload file: %v", err)
+	}
+
+	// The file is now saved through the regular quota check, which must not
+	// count it a second time
+	um.mutex.Lock()
+	session.assembled = true
+	um.mutex.Unlock()
+	return file, nil
+}
+
+// Start begins removing sessions idle for longer than the session TTL
+func (um *UploadManager) Start() {
+	go func() {
+		ticker := time.NewTicker(uploadSweepInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				um.removeExpired()
+			case <-um.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the expiry sweep
+func (um *UploadManager) Stop() {
+	um.stopOnce.Do(func() {
+		close(um.stopChan)
+	})
+}
+
+// removeExpired removes sessions that have not received data within the TTL
+func (um *UploadManager) removeExpired() {
+	cutoff := time.Now().Add(-um.sessionTTL)
+
+	um.mutex.RLock()
+	var expired []string
+	for id, session := range um.sessions {
+		session.mutex.Lock()
+		if session.UpdatedAt.Before(cutoff) {
+			expired = append(expired, id)
+		}
+		session.mutex.Unlock()
+	}
+	um.mutex.RUnlock()
+
+	for _, id := range expired {
+		um.Remove(id)
+		logger.Info("Expired upload session removed", map[string]interface{}{
+			"component": "uploads",
+			"upload_id": id,
+		})
+	}
+}
+
+// uploadOffsetError reports a chunk sent for the wrong offset
+type uploadOffsetError struct {
+	expected int64
+}
+
+func (e *uploadOffsetError) Error() string {
+	return fmt.Sprintf("chunk offset mismatch, expected offset %d", e.expected)
+}
+
+// uploadSessionLimitError reports a new upload refused because the
+// configured number of sessions is already open
+type uploadSessionLimitError struct {
+	limit int
+}
+
+func (e *uploadSessionLimitError) Error() string {
+	return fmt.Sprintf("too many uploads in progress: the limit is %d", e.limit)
+}
+
+// uploadChecksumError reports an assembled file that does not match the
+// checksum given when the upload started
+type uploadChecksumError struct {
+	expected string
+	actual   string
+}
+
+func (e *uploadChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch: expected %s, got %s", e.expected, e.actual)
+}
+
+// uploadSessionResponse builds the response describing a session
+func (um *UploadManager) uploadSessionResponse(session *UploadSession, message string) UploadSessionResponse {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+
+	return UploadSessionResponse{
+		Success:    true,
+		Message:    message,
+		UploadID:   session.ID,
+		Type:       session.Type,
+		PluginType: session.PluginType,
+		Filename:   session.Filename,
+		Size:       session.Size,
+		Received:   session.Received,
+		ExpiresAt:  session.UpdatedAt.Add(um.sessionTTL).UTC().Format(time.RFC3339),
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// uploadsHandler handles POST /uploads, which starts a chunked upload
+func (s *SecAutoServer) uploadsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req UploadSessionRequest
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	validationResult := s.validateUploadSessionRequest(&req)
+	if !validationResult.Valid {
+		response := ValidationResponse{
+			Success:   false,
+			Valid:     false,
+			Errors:    validationResult.Errors,
+			Message:   "Upload validation failed",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	// Hold the storage lock while the session reserves its size
+	s.storageMutex.Lock()
+	session, err := s.startUpload(&req)
+	s.storageMutex.Unlock()
+	if err != nil {
+		if writeStorageQuotaError(w, err) {
+			return
+		}
+		if _, ok := err.(*uploadSessionLimitError); ok {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		logger.Error("Failed to start upload", map[string]interface{}{
+			"component": "uploads",
+			"error":     err.Error(),
+		})
+		http.Error(w, fmt.Sprintf("Failed to start upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Upload started", map[string]interface{}{
+		"component": "uploads",
+		"upload_id": session.ID,
+		"type":      session.Type,
+		"filename":  session.Filename,
+		"size":      session.Size,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/uploads/"+session.ID)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(s.uploadManager.uploadSessionResponse(session, "Upload started"))
+}
+
+// startUpload checks that the declared size fits the storage limit and
+// starts a session for it. Callers hold storageMutex.
+func (s *SecAutoServer) startUpload(req *UploadSessionRequest) (*UploadSession, error) {
+	if err := s.checkStagingQuota(req.Size); err != nil {
+		return nil, err
+	}
+	return s.uploadManager.Create(req.Type, req.PluginType, req.Filename, req.Size, req.Checksum)
+}
+
+// validateUploadSessionRequest checks the type, file name, size and checksum
+// of a new upload
+func (s *SecAutoServer) validateUploadSessionRequest(req *UploadSessionRequest) ValidationResult {
+	var errors []ValidationError
+
+	limit, validType := uploadSizeLimits[req.Type]
+	if !validType {
+		errors = append(errors, ValidationError{
+			Field:   "type",
+			Message: "Type must be one of automation, playbook, plugin, integration",
+			Value:   req.Type,
+		})
+	}
+	if req.Type == "plugin" && !s.isValidPluginType(req.PluginType) {
+		errors = append(errors, ValidationError{
+			Field:   "plugin_type",
+			Message: "Invalid plugin type. Supported types: linux, windows, python, go",
+			Value:   req.PluginType,
+		})
+	}
+
+	if filenameErr := s.validator.ValidateFilename(req.Filename); filenameErr != nil {
+		errors = append(errors, *filenameErr)
+	}
+
+	if req.Size <= 0 {
+		errors = append(errors, ValidationError{
+			Field:   "size",
+			Message: "Size must be positive",
+		})
+	} else if validType && req.Size > limit {
+		errors = append(errors, ValidationError{
+			Field:   "size",
+			Message: fmt.Sprintf("Size exceeds the %d byte limit for %s uploads", limit, req.Type),
+		})
+	}
+
+	if decoded, err := hex.DecodeString(req.Checksum); err != nil || len(decoded) != sha256.Size {
+		errors = append(errors, ValidationError{
+			Field:   "checksum",
+			Message: "Checksum must be a hex-encoded SHA-256 digest",
+		})
+	}
+
+	return ValidationResult{
+		Valid:  len(errors) == 0,
+		Errors: errors,
+	}
+}
+
+// uploadHandler handles /uploads/{id}: GET reports progress, PATCH appends a
+// chunk, DELETE abandons the upload, and POST /uploads/{id}/complete finishes it
+func (s *SecAutoServer) uploadHandler(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/uploads/"), "/")
+	uploadID := pathParts[0]
+	if uploadID == "" || len(pathParts) > 2 || (len(pathParts) == 2 && pathParts[1] != "complete") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	session, exists := s.uploadManager.Get(uploadID)
+	if !exists {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+
+	if len(pathParts) == 2 {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.completeUpload(w, session)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		response := s.uploadManager.uploadSessionResponse(session, "Upload in progress")
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(response.Received, 10))
+		json.NewEncoder(w).Encode(response)
+
+	case http.MethodPatch:
+		offset, err := strconv.ParseInt(r.Header.Get(uploadOffsetHeader), 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("%s header is required", uploadOffsetHeader), http.StatusBadRequest)
+			return
+		}
+
+		received, err := s.uploadManager.AppendChunk(session, offset, r.Body)
+		w.Header().Set(uploadOffsetHeader, strconv.FormatInt(received, 10))
+		if err != nil {
+			status := http.StatusBadRequest
+			if _, ok := err.(*uploadOffsetError); ok {
+				status = http.StatusConflict
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.uploadManager.uploadSessionResponse(session, "Chunk received"))
+
+	case http.MethodDelete:
+		s.uploadManager.Remove(session.ID)
+		logger.Info("Upload abandoned", map[string]interface{}{
+			"component": "uploads",
+			"upload_id": session.ID,
+		})
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// completeUpload verifies an assembled upload and hands it to the save and
+// validate path for its type, which writes the same response as a regular
+// multipart upload. The session ends whatever the outcome, except when
+// chunks are still missing.
+func (s *SecAutoServer) completeUpload(w http.ResponseWriter, session *UploadSession) {
+	file, err := s.uploadManager.Assemble(session)
+	if err != nil {
+		if _, ok := err.(*uploadChecksumError); ok {
+			s.uploadManager.Remove(session.ID)
+		}
+		logger.Error("Failed to complete upload", map[string]interface{}{
+			"component": "uploads",
+			"upload_id": session.ID,
+			"error":     err.Error(),
+		})
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer s.uploadManager.Remove(session.ID)
+	defer file.Close()
+
+	header := &multipart.FileHeader{
+		Filename: session.Filename,
+		Size:     session.Size,
+	}
+
+	logger.Info("Upload assembled", map[string]interface{}{
+		"component": "uploads",
+		"upload_id": session.ID,
+		"type":      session.Type,
+		"filename":  session.Filename,
+	})
+
+	switch session.Type {
+	case "automation":
+		s.processAutomationUpload(w, file, header)
+	case "playbook":
+		s.processPlaybookUpload(w, file, header)
+	case "plugin":
+		s.processPluginUpload(w, file, header, session.PluginType)
+	case "integration":
+		s.processIntegrationUpload(w, file, header)
+	}
+}
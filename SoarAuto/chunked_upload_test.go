@@ -0,0 +1,108 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newUploadTestServer returns a server with an upload manager limited to
+// maxSessions and a total byte limit leaving room bytes free
+func newUploadTestServer(t *testing.T, maxSessions int, room int64) *SecAutoServer {
+	t.Helper()
+	uploads, err := NewUploadManager(UploadsConfig{TempDir: t.TempDir(), MaxSessions: maxSessions})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &SecAutoServer{uploadManager: uploads, validator: NewValidator()}
+	usage, err := server.storageUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.storageLimits.MaxTotalBytes = usage.TotalBytes + room
+	return server
+}
+
+// startUploadRequest posts a new playbook upload of size bytes
+func startUploadRequest(server *SecAutoServer, filename string, size int) *httptest.ResponseRecorder {
+	checksum := sha256.Sum256([]byte(strings.Repeat("x", size)))
+	body := `{"type":"playbook","filename":"` + filename + `","size":` + strconv.Itoa(size) +
+		`,"checksum":"` + hex.EncodeToString(checksum[:]) + `"}`
+	recorder := httptest.NewRecorder()
+	server.uploadsHandler(recorder, httptest.NewRequest(http.MethodPost, "/uploads", strings.NewReader(body)))
+	return recorder
+}
+
+func TestUploadSessionLimit(t *testing.T) {
+	server := newUploadTestServer(t, 2, 1<<20)
+
+	for _, name := range []string{"one.json", "two.json"} {
+		if response := startUploadRequest(server, name, 10); response.Code != http.StatusCreated {
+			t.Fatalf("start %s: %d %s", name, response.Code, response.Body.String())
+		}
+	}
+
+	response := startUploadRequest(server, "three.json", 10)
+	if response.Code != http.StatusTooManyRequests {
+		t.Fatalf("third upload: %d %s, want 429", response.Code, response.Body.String())
+	}
+
+	// Abandoning an upload frees its slot
+	for id := range server.uploadManager.sessions {
+		server.uploadManager.Remove(id)
+		break
+	}
+	if response := startUploadRequest(server, "three.json", 10); response.Code != http.StatusCreated {
+		t.Fatalf("upload after one was abandoned: %d %s", response.Code, response.Body.String())
+	}
+}
+
+func TestStagedUploadsCountAgainstStorageQuota(t *testing.T) {
+	server := newUploadTestServer(t, 10, 100)
+
+	first := startUploadRequest(server, "first.json", 60)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first upload: %d %s", first.Code, first.Body.String())
+	}
+
+	// The first upload has sent no chunks yet but still holds its 60 bytes
+	second := startUploadRequest(server, "second.json", 60)
+	if second.Code != http.StatusInsufficientStorage {
+		t.Fatalf("second upload: %d %s, want 507", second.Code, second.Body.String())
+	}
+	var rejected StorageResponse
+	if err := json.NewDecoder(second.Body).Decode(&rejected); err != nil {
+		t.Fatal(err)
+	}
+	if rejected.Usage.StagedBytes != 60 {
+		t.Errorf("staged_bytes = %d, want 60", rejected.Usage.StagedBytes)
+	}
+
+	var started UploadSessionResponse
+	if err := json.NewDecoder(first.Body).Decode(&started); err != nil {
+		t.Fatal(err)
+	}
+	session, _ := server.uploadManager.Get(started.UploadID)
+	if _, err := server.uploadManager.AppendChunk(session, 0, strings.NewReader(strings.Repeat("x", 60))); err != nil {
+		t.Fatal(err)
+	}
+	file, err := server.uploadManager.Assemble(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	// Once assembled the file is checked as a stored file, not twice
+	if staged := server.uploadManager.StagedBytes(); staged != 0 {
+		t.Errorf("staged bytes after assembly = %d, want 0", staged)
+	}
+	if err := server.checkStorageQuota("playbooks", "../playbooks/first.json", 60); err != nil {
+		t.Errorf("saving the assembled upload: %v", err)
+	}
+}
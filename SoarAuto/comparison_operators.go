@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// evaluateMembership handles {"in": [needle, haystack]} and its inverse
+// {"not_in": [needle, haystack]}. The haystack is an array, whose elements
+// are compared strictly (numbers by value, other values by type and content),
+// or a string, which is searched for the needle as a substring. A missing
+// (null) haystack contains nothing.
+func (re *RuleEngine) evaluateMembership(operation map[string]interface{}, op string, data map[string]interface{}) (bool, error) {
+	operands, ok := operation[op].([]interface{})
+	if !ok || len(operands) != 2 {
+		return false, fmt.Errorf("%s operator requires exactly 2 operands: a value and an array or string", op)
+	}
+
+	needle, err := re.evaluate(operands[0], data)
+	if err != nil {
+		return false, err
+	}
+	haystack, err := re.evaluate(operands[1], data)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+	switch h := haystack.(type) {
+	case nil:
+	case []interface{}:
+		if err := re.checkCollectionSize(op, len(h)); err != nil {
+			return false, err
+		}
+		for _, item := range h {
+			if membershipEquals(needle, item) {
+				found = true
+				break
+			}
+		}
+	case string:
+		needleStr, ok := needle.(string)
+		if !ok {
+			return false, fmt.Errorf("%s operator requires a string value to search a string, got %T", op, needle)
+		}
+		found = strings.Contains(h, needleStr)
+	default:
+		return false, fmt.Errorf("%s operator requires an array or string to search, got %T", op, haystack)
+	}
+
+	if op == "not_in" {
+		return !found, nil
+	}
+	return found, nil
+}
+
+// membershipEquals reports whether an array element matches the value
+// searched for
+func membershipEquals(needle, item interface{}) bool {
+	// Integers that arrived as JSON are compared exactly, not as float64
+	if equal, ok := compareJSONIntegers(needle, item, "eq"); ok {
+		return equal
+	}
+	needleNumber, needleIsNumber := jsonLogicNumeric(needle)
+	itemNumber, itemIsNumber := jsonLogicNumeric(item)
+	if needleIsNumber || itemIsNumber {
+		return needleIsNumber && itemIsNumber && needleNumber == itemNumber
+	}
+	return reflect.DeepEqual(needle, item)
+}
+
+// evaluateBetween handles {"between": [value, low, high]}, which is true when
+// low <= value <= high. Numeric strings such as "7" are compared as numbers.
+func (re *RuleEngine) evaluateBetween(operation map[string]interface{}, data map[string]interface{}) (bool, error) {
+	operands, ok := operation["between"].([]interface{})
+	if !ok || len(operands) != 3 {
+		return false, fmt.Errorf("between operator requires exactly 3 operands: a value, a low and a high bound")
+	}
+
+	var values [3]interface{}
+	var bounds [3]float64
+	for i, operand := range operands {
+		value, err := re.evaluate(operand, data)
+		if err != nil {
+			return false, err
+		}
+		number, ok := comparableNumber(re.normalizeValue(value))
+		if !ok {
+			return false, fmt.Errorf("between operator requires numeric operands, got %v (%T)", value, value)
+		}
+		values[i], bounds[i] = value, number
+	}
+
+	// Integers that arrived as JSON are compared exactly, not as float64
+	aboveLow, exact := compareJSONIntegers(values[1], values[0], "lte")
+	if !exact {
+		aboveLow = bounds[1] <= bounds[0]
+	}
+	belowHigh, exact := compareJSONIntegers(values[0], values[2], "lte")
+	if !exact {
+		belowHigh = bounds[0] <= bounds[2]
+	}
+	return aboveLow && belowHigh, nil
+}
+
+// comparableNumber returns a number, or a string holding one, as a float64
+func comparableNumber(value interface{}) (float64, bool) {
+	if number, ok := jsonLogicNumeric(value); ok {
+		return number, true
+	}
+	if str, ok := value.(string); ok {
+		number, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+		return number, err == nil
+	}
+	return 0, false
+}
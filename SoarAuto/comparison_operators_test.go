@@ -0,0 +1,103 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// comparisonTestData is the context the operator tests evaluate against.
+// Numbers are decoded the way request contexts are.
+const comparisonTestData = `{
+	"severity": 7,
+	"score": "7.5",
+	"ports": [22, 443, 8443],
+	"tags": ["phishing", "1"],
+	"title": "Suspicious login",
+	"empty": []
+}`
+
+func TestMembershipAndBetweenBoundaries(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	var data map[string]interface{}
+	if err := unmarshalContextJSON([]byte(comparisonTestData), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		// between is inclusive at both ends
+		{`{"between": [{"var": "severity"}, 7, 10]}`, true},
+		{`{"between": [{"var": "severity"}, 1, 7]}`, true},
+		{`{"between": [{"var": "severity"}, 7.0001, 10]}`, false},
+		{`{"between": [{"var": "severity"}, 1, 6.9999]}`, false},
+		{`{"between": [{"var": "severity"}, 7, 7]}`, true},
+		{`{"between": [{"var": "severity"}, 10, 1]}`, false},
+		{`{"between": [{"var": "score"}, "7.5", 8]}`, true},
+		{`{"between": [-0.5, -1, 0]}`, true},
+
+		// Numbers match by value, never a string that looks like one
+		{`{"in": [443, {"var": "ports"}]}`, true},
+		{`{"in": [443.0, {"var": "ports"}]}`, true},
+		{`{"in": ["443", {"var": "ports"}]}`, false},
+		{`{"in": [1, {"var": "tags"}]}`, false},
+		{`{"in": ["1", {"var": "tags"}]}`, true},
+		{`{"in": [8443, {"var": "ports"}]}`, true},
+		{`{"in": [22, {"var": "empty"}]}`, false},
+
+		// A string haystack is searched for a substring
+		{`{"in": ["login", {"var": "title"}]}`, true},
+		{`{"in": ["Login", {"var": "title"}]}`, false},
+		{`{"in": ["", {"var": "title"}]}`, true},
+
+		// A missing haystack contains nothing
+		{`{"in": [22, {"var": "missing"}]}`, false},
+		{`{"not_in": [22, {"var": "missing"}]}`, true},
+		{`{"not_in": [22, {"var": "ports"}]}`, false},
+		{`{"not_in": [23, {"var": "ports"}]}`, true},
+		{`{"not_in": [22, {"var": "empty"}]}`, true},
+	}
+
+	for _, test := range tests {
+		var condition interface{}
+		if err := unmarshalContextJSON([]byte(test.condition), &condition); err != nil {
+			t.Fatalf("parse %s: %v", test.condition, err)
+		}
+		got, err := engine.evaluate(condition, data)
+		if err != nil {
+			t.Errorf("%s: %v", test.condition, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s = %v, want %v", test.condition, got, test.want)
+		}
+	}
+}
+
+func TestMembershipAndBetweenErrors(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	data := map[string]interface{}{"title": "Suspicious login", "host": map[string]interface{}{"name": "web-1"}}
+
+	tests := []struct {
+		condition string
+		want      string
+	}{
+		{`{"between": [7, 1]}`, "exactly 3 operands"},
+		{`{"between": [7, "low", 10]}`, "numeric operands"},
+		{`{"between": [{"var": "missing"}, 1, 10]}`, "numeric operands"},
+		{`{"in": [1]}`, "exactly 2 operands"},
+		{`{"in": [1, {"var": "title"}]}`, "string value to search a string"},
+		{`{"not_in": ["web-1", {"var": "host"}]}`, "array or string to search"},
+	}
+
+	for _, test := range tests {
+		var condition interface{}
+		if err := unmarshalContextJSON([]byte(test.condition), &condition); err != nil {
+			t.Fatalf("parse %s: %v", test.condition, err)
+		}
+		if _, err := engine.evaluate(condition, data); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s error = %v, want one containing %q", test.condition, err, test.want)
+		}
+	}
+}
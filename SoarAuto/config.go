@@ -2,9 +2,11 @@ package main
 
 import (
 	"log"
+	"net"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -27,6 +29,11 @@ type Config struct {
 	Backup        BackupConfig        `yaml:"backup"`
 	Notifications NotificationsConfig `yaml:"notifications"`
 	Integrations  IntegrationsConfig  `yaml:"integrations"`
+	Uploads       UploadsConfig       `yaml:"uploads"`
+	Storage       StorageConfig       `yaml:"storage"`
+	GRPC          GRPCConfig          `yaml:"grpc"`
+	PlaybookGit   PlaybookGitConfig   `yaml:"playbook_git"`
+	Events        EventsConfig        `yaml:"events"`
 	Environments  map[string]Config   `yaml:"environments"`
 }
 
@@ -43,14 +50,23 @@ type ServerConfig struct {
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level            string         `yaml:"level"`
-	Destination      string         `yaml:"destination"`
-	File             string         `yaml:"file"`
-	Rotation         RotationConfig `yaml:"rotation"`
-	Format           string         `yaml:"format"`
-	IncludeTimestamp bool           `yaml:"include_timestamp"`
-	IncludeComponent bool           `yaml:"include_component"`
-	IncludeRequestID bool           `yaml:"include_request_id"`
+	Level            string          `yaml:"level"`
+	Destination      string          `yaml:"destination"`
+	File             string          `yaml:"file"`
+	Rotation         RotationConfig  `yaml:"rotation"`
+	Format           string          `yaml:"format"`
+	IncludeTimestamp bool            `yaml:"include_timestamp"`
+	IncludeComponent bool            `yaml:"include_component"`
+	IncludeRequestID bool            `yaml:"include_request_id"`
+	Redaction        RedactionConfig `yaml:"redaction"`
+}
+
+// RedactionConfig holds the patterns masked in logs and job responses
+type RedactionConfig struct {
+	Enabled     bool     `yaml:"enabled"`
+	UseDefaults bool     `yaml:"use_defaults"` // Include the built-in JWT, AWS key and Authorization patterns
+	Patterns    []string `yaml:"patterns"`     // Extra regular expressions; a capture group limits the mask to the group
+	Keys        []string `yaml:"keys"`         // Extra object keys whose values are always masked
 }
 
 // RotationConfig holds log rotation configuration
@@ -63,7 +79,85 @@ type RotationConfig struct {
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
-	RedisURL string `yaml:"redis_url"` // Redis connection URL
+	JobStore      string                `yaml:"job_store"`   // redis (default), memory or sqlite
+	SQLitePath    string                `yaml:"sqlite_path"` // Database file of the sqlite job store
+	RedisURL      string                `yaml:"redis_url"`   // Redis connection URL
+	Connection    RedisConnectionConfig `yaml:"connection"`
+	JobTTL        JobTTLConfig          `yaml:"job_ttl"`
+	Archive       ArchiveConfig         `yaml:"archive"`
+	Deduplication DeduplicationConfig   `yaml:"deduplication"`
+	Idempotency   IdempotencyConfig     `yaml:"idempotency"`
+	Results       ResultsConfig         `yaml:"results"`
+
+	EnrichmentCache EnrichmentCacheConfig `yaml:"enrichment_cache"`
+}
+
+// RedisConnectionConfig holds how the job store connects to Redis and
+// detects outages
+type RedisConnectionConfig struct {
+	StartupAttempts     int    `yaml:"startup_attempts"`      // Connection attempts before startup fails
+	StartupBackoff      string `yaml:"startup_backoff"`       // Wait before the first retry, doubled after each
+	HealthCheckInterval string `yaml:"health_check_interval"` // How often Redis is pinged while running
+}
+
+// JobTTLConfig holds how long finished jobs are kept in Redis, per status (0 keeps them)
+type JobTTLConfig struct {
+	CompletedSeconds int `yaml:"completed_seconds"`
+	FailedSeconds    int `yaml:"failed_seconds"`
+	CancelledSeconds int `yaml:"cancelled_seconds"`
+}
+
+// ArchiveConfig holds cold storage settings for expired jobs
+type ArchiveConfig struct {
+	Enabled       bool                `yaml:"enabled"`
+	Backend       string              `yaml:"backend"`        // filesystem, s3 or gcs
+	Directory     string              `yaml:"directory"`      // Directory for filesystem backend
+	ObjectStorage ObjectStorageConfig `yaml:"object_storage"` // Bucket for s3 and gcs backends
+	Interval      string              `yaml:"interval"`       // How often expired jobs are archived
+}
+
+// ObjectStorageConfig locates a bucket on S3, GCS or another S3-compatible service
+type ObjectStorageConfig struct {
+	Bucket          string `yaml:"bucket"`
+	Prefix          string `yaml:"prefix"`            // Prepended to every object key
+	Region          string `yaml:"region"`            // Defaults to us-east-1 for s3
+	Endpoint        string `yaml:"endpoint"`          // For S3-compatible services such as MinIO
+	AccessKeyID     string `yaml:"access_key_id"`     // HMAC key for gcs; empty uses the AWS credential chain
+	SecretAccessKey string `yaml:"secret_access_key"` // HMAC secret for gcs
+	ForcePathStyle  bool   `yaml:"force_path_style"`  // Address the bucket in the path rather than the host
+}
+
+// ResultsConfig caps the size of the results a run stores and returns
+type ResultsConfig struct {
+	MaxBytes      int                 `yaml:"max_bytes"`      // Serialized size above which results are truncated (0 = no cap)
+	Offload       bool                `yaml:"offload"`        // Keep the full results of truncated runs, served at /results/{id}
+	Backend       string              `yaml:"backend"`        // filesystem, s3 or gcs
+	Directory     string              `yaml:"directory"`      // Directory for filesystem backend
+	ObjectStorage ObjectStorageConfig `yaml:"object_storage"` // Bucket for s3 and gcs backends
+}
+
+// DeduplicationConfig holds settings for Bloom filter deduplication of job submissions
+type DeduplicationConfig struct {
+	Enabled           bool    `yaml:"enabled"`
+	ExpectedItems     int     `yaml:"expected_items"`      // Submissions expected per reset interval
+	FalsePositiveRate float64 `yaml:"false_positive_rate"` // Target false positive rate (0-1)
+	ResetInterval     string  `yaml:"reset_interval"`      // How often the filter is cleared
+}
+
+// IdempotencyConfig holds settings for replaying responses to retried
+// integration config mutations that carry an X-Idempotency-Key header
+type IdempotencyConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	TTL     string `yaml:"ttl"`      // How long a response is replayed for
+	MaxKeys int    `yaml:"max_keys"` // Keys remembered per integration
+}
+
+// EnrichmentCacheConfig holds settings for caching run and plugin lookups that
+// declare a cache key, such as the indicator they enrich
+type EnrichmentCacheConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	DefaultTTL string `yaml:"default_ttl"` // TTL of lookups that declare none
+	MaxTTL     string `yaml:"max_ttl"`     // Longer declared TTLs are capped to this
 }
 
 // Note: Removed unused database configuration structs after implementing Redis job store
@@ -92,6 +186,14 @@ type ClusterConfig struct {
 	LoadBalancing       string `yaml:"load_balancing"`
 	HealthCheckInterval int    `yaml:"health_check_interval"`
 	FailoverEnabled     bool   `yaml:"failover_enabled"`
+
+	RateLimiting ClusterRateLimitingConfig `yaml:"rate_limiting"`
+}
+
+// ClusterRateLimitingConfig holds settings for rate limit counters shared by
+// the nodes of a cluster
+type ClusterRateLimitingConfig struct {
+	Namespace string `yaml:"namespace"` // Redis key namespace; defaults to cluster_name
 }
 
 // SchedulerConfig holds job scheduler configuration
@@ -106,6 +208,9 @@ type SchedulerConfig struct {
 	ScheduleCleanupInterval int    `yaml:"schedule_cleanup_interval"`
 	FailedJobRetention      int    `yaml:"failed_job_retention"`
 	SuccessfulJobRetention  int    `yaml:"successful_job_retention"`
+	// MaxCatchUpRuns bounds the missed runs a fire_all schedule makes up on
+	// startup, unless the schedule sets its own bound
+	MaxCatchUpRuns int `yaml:"max_catch_up_runs"`
 }
 
 // PluginsConfig holds plugin system configuration
@@ -121,6 +226,7 @@ type PlatformConfig struct {
 	MaxMemory           int      `yaml:"max_memory,omitempty"`
 	AllowNetworkAccess  bool     `yaml:"allow_network_access,omitempty"`
 	AllowFileAccess     bool     `yaml:"allow_file_access,omitempty"`
+	WASICapabilities    []string `yaml:"wasi_capabilities,omitempty"` // Granted to wasm plugins (fd_read, fd_write, sock_accept)
 }
 
 // PlatformInfo holds platform-specific metadata
@@ -145,6 +251,8 @@ type PluginsConfig struct {
 	AllowGoPlugins   bool     `yaml:"allow_go_plugins"`
 	PluginValidation bool     `yaml:"plugin_validation"`
 	PluginLogging    bool     `yaml:"plugin_logging"`
+	BuildTempDir     string   `yaml:"build_temp_dir"` // Base directory for Go plugin compilation (empty = system temp)
+	WorkDir          string   `yaml:"work_dir"`       // Base directory of the per-execution working directories of plugins (empty = system temp)
 
 	// Platform-specific configurations
 	Platforms map[string]PlatformConfig `yaml:"platforms"`
@@ -153,11 +261,20 @@ type PluginsConfig struct {
 // SecurityConfig holds security configuration
 type SecurityConfig struct {
 	APIKeys                  []string              `yaml:"api_keys"`
+	AdminAPIKeys             []string              `yaml:"admin_api_keys"`
 	IntegrationEncryptionKey string                `yaml:"integration_encryption_key"`
 	RateLimiting             RateLimitingConfig    `yaml:"rate_limiting"`
 	InputValidation          InputValidationConfig `yaml:"input_validation"`
 	CORS                     CORSConfig            `yaml:"cors"`
 	TLS                      TLSConfig             `yaml:"tls"`
+	DownloadLinks            DownloadLinkConfig    `yaml:"download_links"`
+}
+
+// DownloadLinkConfig holds settings for signed, single-use export links
+type DownloadLinkConfig struct {
+	SigningKey string `yaml:"signing_key"` // HMAC key; a random per-process key is used when empty
+	DefaultTTL string `yaml:"default_ttl"` // Validity of links issued without a ttl
+	MaxTTL     string `yaml:"max_ttl"`     // Longest validity a link may be issued with
 }
 
 // RateLimitingConfig holds rate limiting settings
@@ -169,6 +286,11 @@ type RateLimitingConfig struct {
 
 	// Endpoint-specific rate limits
 	Endpoints EndpointRateLimits `yaml:"endpoints"`
+
+	// Requests from these CIDRs (or single IPs) are never rate limited
+	TrustedCIDRRanges []string `yaml:"trusted_cidr_ranges"`
+	// Proxies whose X-Forwarded-For and X-Real-IP headers are believed
+	TrustedProxies []string `yaml:"trusted_proxies"`
 }
 
 // EndpointRateLimits holds rate limits for specific endpoints
@@ -195,7 +317,12 @@ type InputValidationConfig struct {
 	MaxPlaybookSize         int      `yaml:"max_playbook_size"`
 	MaxScriptSize           int      `yaml:"max_script_size"`
 	AllowedScriptExtensions []string `yaml:"allowed_script_extensions"`
+	FilenamePattern         string   `yaml:"filename_pattern"`
 	SanitizeInputs          bool     `yaml:"sanitize_inputs"`
+
+	// AllowedIntegrationImports lists the integration modules uploaded
+	// automations may import; empty allows all
+	AllowedIntegrationImports []string `yaml:"allowed_integration_imports"`
 }
 
 // CORSConfig holds CORS settings
@@ -217,13 +344,16 @@ type TLSConfig struct {
 
 // WebhooksConfig holds webhook configuration
 type WebhooksConfig struct {
-	Enabled        bool              `yaml:"enabled"`
-	Timeout        int               `yaml:"timeout"`
-	RetryCount     int               `yaml:"retry_count"`
-	RetryDelay     int               `yaml:"retry_delay"`
-	MaxWebhooks    int               `yaml:"max_webhooks"`
-	Events         []string          `yaml:"events"`
-	DefaultHeaders map[string]string `yaml:"default_headers"`
+	Enabled     bool `yaml:"enabled"`
+	Timeout     int  `yaml:"timeout"`
+	RetryCount  int  `yaml:"retry_count"`
+	RetryDelay  int  `yaml:"retry_delay"`
+	MaxWebhooks int  `yaml:"max_webhooks"`
+	// DeliveryHistory is the number of deliveries kept per webhook for
+	// GET /webhooks/deliveries and re-sending
+	DeliveryHistory int               `yaml:"delivery_history"`
+	Events          []string          `yaml:"events"`
+	DefaultHeaders  map[string]string `yaml:"default_headers"`
 }
 
 // PythonConfig holds Python integration configuration
@@ -239,6 +369,27 @@ type PythonConfig struct {
 	SandboxMode        bool   `yaml:"sandbox_mode"`
 	HotReload          bool   `yaml:"hot_reload"`
 	ScriptValidation   bool   `yaml:"script_validation"`
+	// ContextPassing is how automations receive their context: "stdin"
+	// (default), "file" or "env". AutomationContextPassing overrides it
+	// per automation name.
+	ContextPassing           string            `yaml:"context_passing"`
+	AutomationContextPassing map[string]string `yaml:"automation_context_passing"`
+	// Pool runs the listed automations in warm interpreters
+	Pool PythonPoolConfig `yaml:"pool"`
+	// MaxConcurrentProcesses caps the automations running at once across
+	// the whole process, pooled or spawned; 0 leaves them unlimited
+	MaxConcurrentProcesses int `yaml:"max_concurrent_processes"`
+	// WorkDir is the base directory under which each automation run gets a
+	// private working directory, removed when the run ends (empty = system temp)
+	WorkDir string `yaml:"work_dir"`
+}
+
+// PythonPoolConfig holds settings for the warm Python interpreter pool
+type PythonPoolConfig struct {
+	Enabled          bool     `yaml:"enabled"`
+	Size             int      `yaml:"size"`                // Interpreters kept per venv
+	MaxRunsPerWorker int      `yaml:"max_runs_per_worker"` // Runs before an interpreter is replaced
+	Automations      []string `yaml:"automations"`         // Automations that opt into the pool
 }
 
 // RulesEngineConfig holds rules engine configuration
@@ -251,6 +402,27 @@ type RulesEngineConfig struct {
 	AllowCustomFunctions   bool `yaml:"allow_custom_functions"`
 	MaxExecutionTime       int  `yaml:"max_execution_time"`
 	MemoryLimit            int  `yaml:"memory_limit"`
+	JSONLogicMode          bool `yaml:"jsonlogic_mode"`   // Evaluate core operators per the JSONLogic spec
+	StrictTemplates        bool `yaml:"strict_templates"` // Fail rules that reference unresolved template variables
+	// MaxCollectionSize caps the elements of an array or object one
+	// operation iterates over, such as foreach items or a filter input
+	MaxCollectionSize int `yaml:"max_collection_size"`
+	// MaxCheckpoints and MaxCheckpointBytes bound the context snapshots one
+	// playbook run may hold through the checkpoint operation
+	MaxCheckpoints     int `yaml:"max_checkpoints"`
+	MaxCheckpointBytes int `yaml:"max_checkpoint_bytes"`
+	// MaxContextDepth and MaxContextValues bound how deeply a context may
+	// nest and how many values it may hold, checked on input and whenever
+	// automation or plugin results are merged into it
+	MaxContextDepth  int `yaml:"max_context_depth"`
+	MaxContextValues int `yaml:"max_context_values"`
+	// DefaultContextFile is a JSON or YAML file of context values merged
+	// under the context of every playbook run; empty disables it
+	DefaultContextFile string `yaml:"default_context_file"`
+	// JSONNumbers is "preserve" to keep context numbers exactly as written,
+	// so large integer IDs are not rounded, or "float" to decode every
+	// number as float64
+	JSONNumbers string `yaml:"json_numbers"`
 }
 
 // MonitoringConfig holds monitoring configuration
@@ -259,11 +431,55 @@ type MonitoringConfig struct {
 	MetricsInterval     int  `yaml:"metrics_interval"`
 	HealthCheckInterval int  `yaml:"health_check_interval"`
 	PerformanceTracking bool `yaml:"performance_tracking"`
-	SlowQueryThreshold  int  `yaml:"slow_query_threshold"`
+	SlowQueryThreshold  int  `yaml:"slow_query_threshold"` // Milliseconds; slower run/plugin/play operations are logged
 	MemoryUsageTracking bool `yaml:"memory_usage_tracking"`
 	CPUUsageTracking    bool `yaml:"cpu_usage_tracking"`
 	DiskUsageTracking   bool `yaml:"disk_usage_tracking"`
-	CustomMetrics       bool `yaml:"custom_metrics"`
+	CustomMetrics       bool `yaml:"custom_metrics"`    // Record metric operations and serve them on /metrics
+	MaxMetricSeries     int  `yaml:"max_metric_series"` // Label combinations kept per playbook metric
+	// LibraryValidationInterval schedules validation of all playbooks,
+	// automations and integrations (e.g. "24h"); empty disables it
+	LibraryValidationInterval string `yaml:"library_validation_interval"`
+}
+
+// UploadsConfig holds chunked upload settings
+type UploadsConfig struct {
+	TempDir      string `yaml:"temp_dir"`       // Where chunks are assembled
+	SessionTTL   string `yaml:"session_ttl"`    // Idle time before an unfinished upload is discarded
+	MaxChunkSize int    `yaml:"max_chunk_size"` // Largest chunk accepted per request, in bytes
+	MaxSessions  int    `yaml:"max_sessions"`   // Uploads that may be in progress at once
+}
+
+// StorageConfig limits what uploads may store on disk. Zero leaves a limit
+// unset.
+type StorageConfig struct {
+	MaxPlaybooks    int   `yaml:"max_playbooks"`
+	MaxAutomations  int   `yaml:"max_automations"`
+	MaxPlugins      int   `yaml:"max_plugins"`
+	MaxIntegrations int   `yaml:"max_integrations"`
+	MaxTotalBytes   int64 `yaml:"max_total_bytes"` // Across all four categories
+}
+
+// GRPCConfig holds settings for the gRPC API served alongside REST
+type GRPCConfig struct {
+	Enabled bool `yaml:"enabled"`
+	Port    int  `yaml:"port"`
+}
+
+// PlaybookGitConfig configures syncing playbooks from a Git repository
+type PlaybookGitConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	RepoURL      string `yaml:"repo_url"`
+	Branch       string `yaml:"branch"`        // Defaults to main
+	Path         string `yaml:"path"`          // Directory of the repository holding playbooks
+	SyncInterval string `yaml:"sync_interval"` // Empty syncs only at startup and on POST /playbooks/sync
+	CheckoutDir  string `yaml:"checkout_dir"`  // Defaults to data/playbook_git
+}
+
+// EventsConfig configures the activity feed served by GET /events
+type EventsConfig struct {
+	Backend     string `yaml:"backend"`      // "memory" or "redis"; redis shares the feed between nodes
+	HistorySize int    `yaml:"history_size"` // Newest events kept; defaults to 1000
 }
 
 // PerformanceConfig holds performance configuration
@@ -352,6 +568,17 @@ type IntegrationsConfig struct {
 	ExternalAPIs ExternalAPIsConfig `yaml:"external_apis"`
 	FileSystems  FileSystemsConfig  `yaml:"file_systems"`
 	Network      NetworkConfig      `yaml:"network"`
+	Vault        VaultConfig        `yaml:"vault"`
+}
+
+// VaultConfig holds the HashiCorp Vault server that vault:path#field
+// references in integration configs are read from
+type VaultConfig struct {
+	Address   string `yaml:"address"`   // Falls back to VAULT_ADDR
+	Token     string `yaml:"token"`     // Falls back to VAULT_TOKEN
+	Namespace string `yaml:"namespace"` // Vault Enterprise namespace, if any
+	Timeout   int    `yaml:"timeout"`   // Request timeout in seconds
+	CacheTTL  int    `yaml:"cache_ttl"` // Seconds a read secret is reused; 0 reads Vault on every use
 }
 
 // ExternalAPIsConfig holds external API settings
@@ -397,6 +624,10 @@ func LoadConfig(configPath string) (*Config, error) {
 			IncludeTimestamp: true,
 			IncludeComponent: true,
 			IncludeRequestID: true,
+			Redaction: RedactionConfig{
+				Enabled:     true,
+				UseDefaults: true,
+			},
 			Rotation: RotationConfig{
 				MaxSizeMB:  100,
 				MaxBackups: 5,
@@ -405,7 +636,28 @@ func LoadConfig(configPath string) (*Config, error) {
 			},
 		},
 		Database: DatabaseConfig{
-			RedisURL: "redis://localhost:6379/0",
+			JobStore:   "redis",
+			SQLitePath: "data/jobs.db",
+			RedisURL:   "redis://localhost:6379/0",
+			Connection: RedisConnectionConfig{
+				StartupAttempts:     5,
+				StartupBackoff:      "1s",
+				HealthCheckInterval: "5s",
+			},
+			Archive: ArchiveConfig{
+				Backend:   "filesystem",
+				Directory: "data/archive",
+				Interval:  "1h",
+			},
+			Deduplication: DeduplicationConfig{
+				ExpectedItems:     1000000,
+				FalsePositiveRate: 0.01,
+				ResetInterval:     "1h",
+			},
+			Idempotency: IdempotencyConfig{
+				TTL:     "24h",
+				MaxKeys: 100,
+			},
 		},
 		Cluster: ClusterConfig{
 			Enabled:             false,
@@ -495,6 +747,17 @@ func LoadConfig(configPath string) (*Config, error) {
 					AllowNetworkAccess:  true,
 					AllowFileAccess:     true,
 				},
+				"wasm": {
+					Enabled:             false,
+					Directory:           "../plugins/wasm",
+					SupportedExtensions: []string{".wasm"},
+					Timeout:             60,
+					SandboxMode:         true,
+					MaxMemory:           128,
+					AllowNetworkAccess:  false,
+					AllowFileAccess:     false,
+					WASICapabilities:    []string{"fd_read", "fd_write"},
+				},
 			},
 		},
 		Security: SecurityConfig{
@@ -526,13 +789,14 @@ func LoadConfig(configPath string) (*Config, error) {
 				MaxPlaybookSize:         1000,
 				MaxScriptSize:           500,
 				AllowedScriptExtensions: []string{".py", ".ps1", ".bat"},
+				FilenamePattern:         defaultFilenamePattern,
 				SanitizeInputs:          true,
 			},
 			CORS: CORSConfig{
 				Enabled:        false,
 				AllowedOrigins: []string{"*"},
-				AllowedMethods: []string{"GET", "POST", "PUT", "DELETE"},
-				AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+				AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+				AllowedHeaders: []string{"Content-Type", "X-API-Key", "Upload-Offset", "X-Idempotency-Key"},
 				MaxAge:         86400,
 			},
 			TLS: TLSConfig{
@@ -543,12 +807,13 @@ func LoadConfig(configPath string) (*Config, error) {
 			},
 		},
 		Webhooks: WebhooksConfig{
-			Enabled:     true,
-			Timeout:     30,
-			RetryCount:  3,
-			RetryDelay:  5,
-			MaxWebhooks: 50,
-			Events:      []string{"job_started", "job_completed", "job_failed", "job_cancelled", "schedule_created", "schedule_updated", "schedule_deleted"},
+			Enabled:         true,
+			Timeout:         30,
+			RetryCount:      3,
+			RetryDelay:      5,
+			MaxWebhooks:     50,
+			DeliveryHistory: 100,
+			Events:          []string{"job_started", "job_completed", "job_failed", "job_cancelled", "job_aborted", "schedule_created", "schedule_updated", "schedule_deleted"},
 			DefaultHeaders: map[string]string{
 				"Content-Type": "application/json",
 				"User-Agent":   "SecAuto-Webhook/1.0",
@@ -566,6 +831,7 @@ func LoadConfig(configPath string) (*Config, error) {
 			SandboxMode:        false,
 			HotReload:          true,
 			ScriptValidation:   true,
+			ContextPassing:     contextPassingStdin,
 		},
 		RulesEngine: RulesEngineConfig{
 			MaxNestingDepth:        10,
@@ -576,6 +842,12 @@ func LoadConfig(configPath string) (*Config, error) {
 			AllowCustomFunctions:   false,
 			MaxExecutionTime:       3600,
 			MemoryLimit:            1024,
+			MaxCollectionSize:      defaultMaxCollectionSize,
+			MaxCheckpoints:         defaultMaxCheckpoints,
+			MaxCheckpointBytes:     defaultMaxCheckpointBytes,
+			MaxContextDepth:        defaultMaxContextDepth,
+			MaxContextValues:       defaultMaxContextValues,
+			JSONNumbers:            jsonNumbersPreserve,
 		},
 		Monitoring: MonitoringConfig{
 			Enabled:             true,
@@ -587,6 +859,7 @@ func LoadConfig(configPath string) (*Config, error) {
 			CPUUsageTracking:    true,
 			DiskUsageTracking:   true,
 			CustomMetrics:       true,
+			MaxMetricSeries:     defaultMaxMetricSeries,
 		},
 		Performance: PerformanceConfig{
 			WorkerPoolSize:        5,
@@ -667,6 +940,23 @@ func LoadConfig(configPath string) (*Config, error) {
 				MaxConnections:    100,
 				ConnectionTimeout: 30,
 			},
+			Vault: VaultConfig{
+				Timeout:  10,
+				CacheTTL: 300,
+			},
+		},
+		Uploads: UploadsConfig{
+			TempDir:      "data/uploads",
+			SessionTTL:   "1h",
+			MaxChunkSize: 5242880,
+			MaxSessions:  50,
+		},
+		GRPC: GRPCConfig{
+			Port: defaultGRPCPort,
+		},
+		Events: EventsConfig{
+			Backend:     "memory",
+			HistorySize: defaultEventHistorySize,
 		},
 	}
 
@@ -710,6 +1000,9 @@ func mergeConfig(cfg *Config, defaults *Config) {
 	if len(cfg.Security.APIKeys) == 0 {
 		cfg.Security = defaults.Security
 	}
+	if cfg.Uploads.TempDir == "" {
+		cfg.Uploads = defaults.Uploads
+	}
 	// Add more merges as needed
 }
 
@@ -739,6 +1032,32 @@ func (c *Config) GetVenvPath() string {
 	return c.Python.VenvPath
 }
 
+// GetContextPassing returns how the named automation receives its context
+func (c *Config) GetContextPassing(scriptName string) string {
+	if mode, exists := c.Python.AutomationContextPassing[strings.TrimSuffix(scriptName, ".py")]; exists && mode != "" {
+		return mode
+	}
+	if c.Python.ContextPassing != "" {
+		return c.Python.ContextPassing
+	}
+	return contextPassingStdin
+}
+
+// UsesPythonPool reports whether an automation runs in the warm interpreter
+// pool. Only automations reading their context from stdin can be pooled.
+func (c *Config) UsesPythonPool(scriptName string) bool {
+	if !c.Python.Pool.Enabled || c.GetContextPassing(scriptName) != contextPassingStdin {
+		return false
+	}
+	name := strings.TrimSuffix(scriptName, ".py")
+	for _, pooled := range c.Python.Pool.Automations {
+		if strings.TrimSuffix(pooled, ".py") == name {
+			return true
+		}
+	}
+	return false
+}
+
 // GetScriptPath returns the full path to a Python script
 func (c *Config) GetScriptPath(scriptName string) string {
 	if scriptName == "" {
@@ -761,6 +1080,44 @@ func (c *Config) GetPlaybookPath(playbookName string) string {
 	return filepath.Join(c.Python.PlaybooksPath, playbookName)
 }
 
+// IsHostAllowed reports whether outbound connections to host are permitted.
+// Entries may be "*", an exact host name, a "*.domain" wildcard, an IP address,
+// or a CIDR range; blocked hosts take precedence over allowed hosts.
+func (n NetworkConfig) IsHostAllowed(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, pattern := range n.BlockedHosts {
+		if hostMatches(host, pattern) {
+			return false
+		}
+	}
+	for _, pattern := range n.AllowedHosts {
+		if hostMatches(host, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostMatches checks a host against a single allow/block list entry
+func hostMatches(host, pattern string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasPrefix(pattern, "*."):
+		return strings.HasSuffix(host, pattern[1:])
+	case strings.Contains(pattern, "/"):
+		_, network, err := net.ParseCIDR(pattern)
+		if err != nil {
+			return false
+		}
+		ip := net.ParseIP(host)
+		return ip != nil && network.Contains(ip)
+	default:
+		return host == pattern
+	}
+}
+
 // GetDataDirectory returns the default data directory (for compatibility)
 func (c *Config) GetDataDirectory() string {
 	return "data"
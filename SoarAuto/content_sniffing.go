@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// contentSniffLength is how much of a file the CSV upload reads to sniff
+// its type; other uploads are sniffed in full
+const contentSniffLength = 8192
+
+// Content types reported for uploads, beyond those of http.DetectContentType
+const (
+	contentTypeJSON       = "application/json"
+	contentTypeCSV        = "text/csv"
+	contentTypePython     = "text/x-python"
+	contentTypeShell      = "text/x-shellscript"
+	contentTypePowerShell = "text/x-powershell"
+	contentTypeBatch      = "text/x-msdos-batch"
+	contentTypeGo         = "text/x-go"
+	contentTypeText       = "text/plain"
+	contentTypeELF        = "application/x-elf"
+	contentTypePE         = "application/vnd.microsoft.portable-executable"
+	contentTypeMachO      = "application/x-mach-binary"
+)
+
+// uploadContentTypes is the type the content of an uploaded file must have,
+// by extension. Files without an extension are Linux plugins, which may be
+// an ELF binary or a script with a shebang line.
+var uploadContentTypes = map[string]string{
+	".py":   contentTypePython,
+	".json": contentTypeJSON,
+	".csv":  contentTypeCSV,
+	".sh":   contentTypeShell,
+	".ps1":  contentTypePowerShell,
+	".bat":  contentTypeBatch,
+	".go":   contentTypeGo,
+	".exe":  contentTypePE,
+	"":      contentTypeELF,
+}
+
+// shebangContentTypes maps the interpreter named by a shebang line to the
+// type of the script
+var shebangContentTypes = map[string]string{
+	"python":  contentTypePython,
+	"python2": contentTypePython,
+	"python3": contentTypePython,
+	"sh":      contentTypeShell,
+	"bash":    contentTypeShell,
+	"dash":    contentTypeShell,
+	"zsh":     contentTypeShell,
+	"ksh":     contentTypeShell,
+	"pwsh":    contentTypePowerShell,
+}
+
+// validateUploadContentType sniffs the content of an uploaded file and
+// reports a content_type error when it does not match the type its extension
+// claims, such as a binary renamed to .py or a script uploaded as .json.
+// Extensions without a known type are not checked.
+func validateUploadContentType(filename string, content []byte) *ValidationError {
+	ext := strings.ToLower(filepath.Ext(filename))
+	expected, known := uploadContentTypes[ext]
+	if !known {
+		return nil
+	}
+
+	detected := detectUploadContentType(content)
+	if uploadContentMatches(ext, expected, detected, content) {
+		return nil
+	}
+
+	claimed := ext + " file"
+	if ext == "" {
+		claimed = "file without an extension"
+		expected = contentTypeELF + " or a script with a shebang line"
+	}
+	return &ValidationError{
+		Field:   "content_type",
+		Message: fmt.Sprintf("File content was detected as %s, but a %s must be %s", detected, claimed, expected),
+		Value:   detected,
+	}
+}
+
+// detectUploadContentType returns the type of content: an executable format
+// by its magic number, a script by its shebang line, JSON by its first
+// character, and otherwise the type reported by http.DetectContentType
+// without parameters
+func detectUploadContentType(content []byte) string {
+	switch {
+	case bytes.HasPrefix(content, []byte("\x7fELF")):
+		return contentTypeELF
+	case bytes.HasPrefix(content, []byte("MZ")) && !isTextContent(content):
+		return contentTypePE
+	case bytes.HasPrefix(content, []byte{0xfe, 0xed, 0xfa, 0xce}),
+		bytes.HasPrefix(content, []byte{0xfe, 0xed, 0xfa, 0xcf}),
+		bytes.HasPrefix(content, []byte{0xce, 0xfa, 0xed, 0xfe}),
+		bytes.HasPrefix(content, []byte{0xcf, 0xfa, 0xed, 0xfe}):
+		return contentTypeMachO
+	}
+
+	if !isTextContent(content) {
+		detected, _, _ := strings.Cut(http.DetectContentType(content), ";")
+		if strings.HasPrefix(detected, "text/") {
+			// Text by its first bytes but binary further on
+			return "application/octet-stream"
+		}
+		return detected
+	}
+
+	text := bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))
+	if interpreter := shebangInterpreter(text); interpreter != "" {
+		if detected, known := shebangContentTypes[interpreter]; known {
+			return detected
+		}
+		return "text/x-script." + interpreter
+	}
+
+	trimmed := bytes.TrimSpace(text)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return contentTypeJSON
+	}
+	return contentTypeText
+}
+
+// uploadContentMatches reports whether content detected as detected may be
+// stored with the extension ext, whose type is expected. Only JSON and
+// executable formats are recognised with certainty; any other text is
+// accepted for a text extension unless its shebang names another language.
+func uploadContentMatches(ext, expected, detected string, content []byte) bool {
+	switch ext {
+	case ".exe":
+		return detected == contentTypePE
+	case "":
+		return detected == contentTypeELF || (isTextContent(content) && shebangInterpreter(content) != "")
+	case ".json":
+		return detected == contentTypeJSON
+	}
+
+	switch detected {
+	case expected, contentTypeText:
+		return true
+	case contentTypeJSON:
+		// Text starting with a bracket may be a script; a whole JSON
+		// document never is, though a CSV may hold one
+		return ext == ".csv" || !json.Valid(content)
+	}
+	return false
+}
+
+// shebangInterpreter returns the interpreter named by the shebang line of a
+// script, such as "python3" for "#!/usr/bin/env python3", or "" when there is
+// no shebang line
+func shebangInterpreter(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" {
+		// Skip options such as -S given to env
+		interpreter = ""
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = filepath.Base(field)
+				break
+			}
+		}
+	}
+	return strings.ToLower(interpreter)
+}
+
+// isTextContent reports whether content is UTF-8 text without NUL bytes. A
+// multi-byte character cut off at the end, as in a sniffed prefix, is allowed.
+func isTextContent(content []byte) bool {
+	if bytes.IndexByte(content, 0) >= 0 {
+		return false
+	}
+	for len(content) > 0 {
+		r, size := utf8.DecodeRune(content)
+		if r == utf8.RuneError && size <= 1 {
+			return len(content) < utf8.UTFMax && !utf8.FullRune(content)
+		}
+		content = content[size:]
+	}
+	return true
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+const (
+	// defaultMaxContextDepth applies when rules_engine.max_context_depth is unset
+	defaultMaxContextDepth = 64
+	// defaultMaxContextValues applies when rules_engine.max_context_values is unset
+	defaultMaxContextValues = 1000000
+)
+
+// contextBoundsWalker checks a context value for nesting deeper than
+// maxDepth, more than maxValues values in total, and objects or arrays that
+// contain themselves. Go plugins can return such values, and copying,
+// diffing or snapshotting them would never finish or exhaust memory.
+type contextBoundsWalker struct {
+	maxDepth  int
+	maxValues int
+	values    int
+	ancestors map[uintptr]bool
+}
+
+func (re *RuleEngine) newContextBoundsWalker() *contextBoundsWalker {
+	walker := &contextBoundsWalker{
+		maxDepth:  re.maxContextDepth,
+		maxValues: re.maxContextValues,
+		ancestors: make(map[uintptr]bool),
+	}
+	if walker.maxDepth <= 0 {
+		walker.maxDepth = defaultMaxContextDepth
+	}
+	if walker.maxValues <= 0 {
+		walker.maxValues = defaultMaxContextValues
+	}
+	return walker
+}
+
+// walk checks value, found at path, nested depth levels deep
+func (w *contextBoundsWalker) walk(path string, value interface{}, depth int) error {
+	w.values++
+	if w.values > w.maxValues {
+		return fmt.Errorf("context holds more than max_context_values of %d values", w.maxValues)
+	}
+
+	var pointer uintptr
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		pointer = reflect.ValueOf(v).Pointer()
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		pointer = reflect.ValueOf(v).Pointer()
+	default:
+		return nil
+	}
+
+	if depth >= w.maxDepth {
+		return fmt.Errorf("context value %s is nested deeper than max_context_depth of %d", path, w.maxDepth)
+	}
+	// Only a value that contains itself is a cycle; the same object
+	// referenced twice side by side is not
+	if w.ancestors[pointer] {
+		return fmt.Errorf("context value %s contains a reference to itself", path)
+	}
+	w.ancestors[pointer] = true
+	defer delete(w.ancestors, pointer)
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			childPath := key
+			if path != "" {
+				childPath = path + "." + key
+			}
+			if err := w.walk(childPath, child, depth+1); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, child := range v {
+			if err := w.walk(fmt.Sprintf("%s[%d]", path, i), child, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkContextBounds checks that a context is within max_context_depth and
+// max_context_values and has no reference cycles
+func (re *RuleEngine) checkContextBounds(context map[string]interface{}) error {
+	walker := re.newContextBoundsWalker()
+	for key, value := range context {
+		if err := walker.walk(key, value, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkContextMerge checks that merging the result of an automation or plugin
+// into the context keeps it within bounds, before anything is merged
+func (re *RuleEngine) checkContextMerge(data, result map[string]interface{}) error {
+	walker := re.newContextBoundsWalker()
+	for key, value := range result {
+		if err := walker.walk(key, value, 1); err != nil {
+			return err
+		}
+	}
+	for key, value := range data {
+		if _, replaced := result[key]; replaced {
+			continue
+		}
+		if err := walker.walk(key, value, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ContextChange describes a single difference between two contexts
+type ContextChange struct {
+	Path   string      `json:"path"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+// ContextDiff lists the keys a playbook run added, removed, or changed
+type ContextDiff struct {
+	Added   []ContextChange `json:"added"`
+	Removed []ContextChange `json:"removed"`
+	Changed []ContextChange `json:"changed"`
+}
+
+// DiffContexts computes a recursive diff between two contexts. Nested objects
+// and arrays are compared element by element; paths use dot notation for
+// object keys and [n] for array indexes.
+func DiffContexts(before, after map[string]interface{}) *ContextDiff {
+	diff := &ContextDiff{
+		Added:   []ContextChange{},
+		Removed: []ContextChange{},
+		Changed: []ContextChange{},
+	}
+	diff.diffMaps("", before, after)
+	return diff
+}
+
+// diffMaps compares two objects key by key in sorted order
+func (d *ContextDiff) diffMaps(path string, before, after map[string]interface{}) {
+	keys := make([]string, 0, len(before)+len(after))
+	for key := range before {
+		keys = append(keys, key)
+	}
+	for key := range after {
+		if _, exists := before[key]; !exists {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+
+		beforeValue, inBefore := before[key]
+		afterValue, inAfter := after[key]
+		switch {
+		case !inBefore:
+			d.Added = append(d.Added, ContextChange{Path: childPath, After: afterValue})
+		case !inAfter:
+			d.Removed = append(d.Removed, ContextChange{Path: childPath, Before: beforeValue})
+		default:
+			d.diffValues(childPath, beforeValue, afterValue)
+		}
+	}
+}
+
+// diffArrays compares two arrays index by index
+func (d *ContextDiff) diffArrays(path string, before, after []interface{}) {
+	for i := 0; i < len(before) || i < len(after); i++ {
+		childPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= len(before):
+			d.Added = append(d.Added, ContextChange{Path: childPath, After: after[i]})
+		case i >= len(after):
+			d.Removed = append(d.Removed, ContextChange{Path: childPath, Before: before[i]})
+		default:
+			d.diffValues(childPath, before[i], after[i])
+		}
+	}
+}
+
+// diffValues recurses into matching containers and records leaf changes
+func (d *ContextDiff) diffValues(path string, before, after interface{}) {
+	beforeMap, beforeIsMap := before.(map[string]interface{})
+	afterMap, afterIsMap := after.(map[string]interface{})
+	if beforeIsMap && afterIsMap {
+		d.diffMaps(path, beforeMap, afterMap)
+		return
+	}
+
+	beforeArray, beforeIsArray := before.([]interface{})
+	afterArray, afterIsArray := after.([]interface{})
+	if beforeIsArray && afterIsArray {
+		d.diffArrays(path, beforeArray, afterArray)
+		return
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		d.Changed = append(d.Changed, ContextChange{Path: path, Before: before, After: after})
+	}
+}
+
+// Count returns the total number of differences
+func (d *ContextDiff) Count() int {
+	return len(d.Added) + len(d.Removed) + len(d.Changed)
+}
+
+// Paths returns the sorted paths of every difference
+func (d *ContextDiff) Paths() []interface{} {
+	paths := make([]string, 0, d.Count())
+	for _, changes := range [][]ContextChange{d.Added, d.Removed, d.Changed} {
+		for _, change := range changes {
+			paths = append(paths, change.Path)
+		}
+	}
+	sort.Strings(paths)
+
+	result := make([]interface{}, len(paths))
+	for i, path := range paths {
+		result[i] = path
+	}
+	return result
+}
+
+// ContextValue converts the diff into plain maps and slices so it can be stored
+// in a playbook context. Empty categories are omitted, so a diff without
+// changes is an empty object.
+func (d *ContextDiff) ContextValue() map[string]interface{} {
+	result := make(map[string]interface{})
+	for name, changes := range map[string][]ContextChange{"added": d.Added, "removed": d.Removed, "changed": d.Changed} {
+		if len(changes) == 0 {
+			continue
+		}
+		entries := make([]interface{}, len(changes))
+		for i, change := range changes {
+			entry := map[string]interface{}{"path": change.Path}
+			if name != "added" {
+				entry["before"] = change.Before
+			}
+			if name != "removed" {
+				entry["after"] = change.After
+			}
+			entries[i] = entry
+		}
+		result[name] = entries
+	}
+	return result
+}
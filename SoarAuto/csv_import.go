@@ -0,0 +1,174 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxCSVImportSize is the maximum size of an uploaded CSV file
+	maxCSVImportSize = 10 << 20
+	// maxCSVImportRows caps the number of data rows accepted in one import
+	maxCSVImportRows = 10000
+	// defaultCSVContextVariable is the context key used for imported rows
+	defaultCSVContextVariable = "rows"
+)
+
+// CSVImportError reports CSV content that does not match the column mapping
+type CSVImportError struct {
+	Errors []ValidationError
+}
+
+func (e *CSVImportError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, validationErr := range e.Errors {
+		messages[i] = validationErr.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// csvImportError builds a CSVImportError for a single field
+func csvImportError(field, message string) *CSVImportError {
+	return &CSVImportError{Errors: []ValidationError{{Field: field, Message: message}}}
+}
+
+// ParseCSVContext reads CSV data and builds one context object per row.
+// columnMapping maps CSV column headers to dotted context paths; a leading
+// "context." prefix is accepted and stripped. Every mapped column must be
+// present in the header row.
+func ParseCSVContext(reader io.Reader, columnMapping map[string]string) ([]map[string]interface{}, error) {
+	if len(columnMapping) == 0 {
+		return nil, fmt.Errorf("column_mapping must map at least one column")
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return nil, csvImportError("file", "CSV file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %v", err)
+	}
+
+	// Resolve mapped columns against the header before reading any rows
+	columnIndex := make(map[string]int, len(header))
+	for i, name := range header {
+		columnIndex[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	columns := make([]string, 0, len(columnMapping))
+	for column := range columnMapping {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	var errors []ValidationError
+	paths := make(map[string][]string, len(columnMapping))
+	for _, column := range columns {
+		if _, exists := columnIndex[column]; !exists {
+			errors = append(errors, ValidationError{
+				Field:   "column_mapping",
+				Message: fmt.Sprintf("expected column %q not found in CSV header", column),
+				Value:   column,
+			})
+			continue
+		}
+		path := strings.TrimPrefix(columnMapping[column], "context.")
+		segments := strings.Split(path, ".")
+		for _, segment := range segments {
+			if segment == "" {
+				errors = append(errors, ValidationError{
+					Field:   "column_mapping",
+					Message: fmt.Sprintf("invalid context path %q for column %q", columnMapping[column], column),
+					Value:   columnMapping[column],
+				})
+				break
+			}
+		}
+		paths[column] = segments
+	}
+	errors = append(errors, overlappingContextPaths(columnMapping)...)
+	if len(errors) > 0 {
+		return nil, &CSVImportError{Errors: errors}
+	}
+
+	rows := make([]map[string]interface{}, 0)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row %d: %v", len(rows)+2, err)
+		}
+		if len(rows) >= maxCSVImportRows {
+			return nil, csvImportError("file", fmt.Sprintf("CSV file exceeds the maximum of %d rows", maxCSVImportRows))
+		}
+
+		row := make(map[string]interface{})
+		for _, column := range columns {
+			index := columnIndex[column]
+			value := ""
+			if index < len(record) {
+				value = record[index]
+			}
+			setContextPath(row, paths[column], value)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// setContextPath sets a value at a dotted path, creating intermediate objects;
+// an existing value at the path is replaced
+func setContextPath(target map[string]interface{}, path []string, value interface{}) error {
+	current := target
+	for i, segment := range path[:len(path)-1] {
+		next, exists := current[segment]
+		if !exists {
+			child := make(map[string]interface{})
+			current[segment] = child
+			current = child
+			continue
+		}
+		child, ok := next.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("context path %q is not an object", strings.Join(path[:i+1], "."))
+		}
+		current = child
+	}
+
+	current[path[len(path)-1]] = value
+	return nil
+}
+
+// overlappingContextPaths reports mapped context paths that are duplicated or
+// nested inside another mapped path, since one would overwrite the other
+func overlappingContextPaths(columnMapping map[string]string) []ValidationError {
+	paths := make([]string, 0, len(columnMapping))
+	for _, path := range columnMapping {
+		paths = append(paths, strings.TrimPrefix(path, "context."))
+	}
+	sort.Strings(paths)
+
+	var errors []ValidationError
+	for i := 0; i < len(paths); i++ {
+		for j := i + 1; j < len(paths); j++ {
+			if paths[j] == paths[i] || strings.HasPrefix(paths[j], paths[i]+".") {
+				errors = append(errors, ValidationError{
+					Field:   "column_mapping",
+					Message: fmt.Sprintf("context path %q overlaps with %q", paths[j], paths[i]),
+					Value:   paths[j],
+				})
+			}
+		}
+	}
+	return errors
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultContextCache holds the parsed default context file, reloaded when
+// the file changes
+var defaultContextCache struct {
+	sync.Mutex
+	path    string
+	modTime time.Time
+	data    []byte // JSON encoding of the defaults, decoded afresh for each run
+}
+
+// loadDefaultContext returns a private copy of the default context in path.
+// The file is JSON, or YAML when named .yaml or .yml.
+func loadDefaultContext(path string) (map[string]interface{}, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read default context file: %v", err)
+	}
+
+	defaultContextCache.Lock()
+	defer defaultContextCache.Unlock()
+
+	if defaultContextCache.path != path || !defaultContextCache.modTime.Equal(info.ModTime()) {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read default context file: %v", err)
+		}
+
+		var defaults map[string]interface{}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml":
+			err = yaml.Unmarshal(raw, &defaults)
+		default:
+			err = unmarshalContextJSON(raw, &defaults)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse default context file %s: %v", path, err)
+		}
+
+		// Stored as JSON so YAML values take the same types as request context
+		data, err := json.Marshal(defaults)
+		if err != nil {
+			return nil, fmt.Errorf("default context file %s is not JSON-compatible: %v", path, err)
+		}
+
+		defaultContextCache.path = path
+		defaultContextCache.modTime = info.ModTime()
+		defaultContextCache.data = data
+
+		logger.Info("Loaded default playbook context", map[string]interface{}{
+			"component":    "rules_engine",
+			"path":         path,
+			"context_keys": len(defaults),
+		})
+	}
+
+	var defaults map[string]interface{}
+	if err := unmarshalContextJSON(defaultContextCache.data, &defaults); err != nil {
+		return nil, err
+	}
+	return defaults, nil
+}
+
+// mergeDefaultContext deep-merges defaults under context in place: values in
+// context win, and objects present in both are merged key by key
+func mergeDefaultContext(context, defaults map[string]interface{}) {
+	for key, defaultValue := range defaults {
+		value, exists := context[key]
+		if !exists {
+			context[key] = defaultValue
+			continue
+		}
+		valueMap, isMap := value.(map[string]interface{})
+		defaultMap, defaultIsMap := defaultValue.(map[string]interface{})
+		if isMap && defaultIsMap {
+			mergeDefaultContext(valueMap, defaultMap)
+		}
+	}
+}
+
+// applyDefaultContext merges the configured default context file, if any,
+// under the context of a playbook run
+func (re *RuleEngine) applyDefaultContext(context map[string]interface{}) error {
+	path := re.config.RulesEngine.DefaultContextFile
+	if path == "" {
+		return nil
+	}
+
+	defaults, err := loadDefaultContext(path)
+	if err != nil {
+		return err
+	}
+	mergeDefaultContext(context, defaults)
+
+	logger.Info("Effective playbook context", map[string]interface{}{
+		"component":    "rules_engine",
+		"default_keys": len(defaults),
+		"context_keys": len(context),
+		"context":      context,
+	})
+	return nil
+}
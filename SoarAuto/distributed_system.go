@@ -44,6 +44,7 @@ type DistributedJob struct {
 	ID          string                 `json:"id"`
 	Playbook    []interface{}          `json:"playbook"`
 	Context     map[string]interface{} `json:"context"`
+	Env         map[string]string      `json:"env,omitempty"`
 	Status      string                 `json:"status"`
 	SubmittedAt time.Time              `json:"submitted_at"`
 	StartedAt   *time.Time             `json:"started_at,omitempty"`
@@ -51,9 +52,42 @@ type DistributedJob struct {
 	AssignedTo  string                 `json:"assigned_to,omitempty"`
 	Results     []interface{}          `json:"results,omitempty"`
 	Error       string                 `json:"error,omitempty"`
+	ErrorType   string                 `json:"error_type,omitempty"` // "assertion_failed" when an assert operation failed
 	RetryCount  int                    `json:"retry_count"`
 	Priority    int                    `json:"priority"`
 	Tags        []string               `json:"tags"`
+
+	// Set when Results were cut down to the size cap
+	ResultTruncation
+}
+
+// jobRecord returns the job in the form every job endpoint returns
+func (dj *DistributedJob) jobRecord() *Job {
+	priority := defaultJobPriority
+	if dj.Priority != 0 {
+		priority = strconv.Itoa(dj.Priority)
+	}
+	return &Job{
+		ID:              dj.ID,
+		Status:          dj.Status,
+		Playbook:        dj.Playbook,
+		Context:         dj.Context,
+		Env:             dj.Env,
+		Priority:        priority,
+		Labels:          dj.Tags,
+		Retries:         dj.RetryCount,
+		Node:            dj.AssignedTo,
+		Results:         dj.Results,
+		Error:           dj.Error,
+		ErrorType:       dj.ErrorType,
+		CreatedAt:       dj.SubmittedAt,
+		StartedAt:       dj.StartedAt,
+		CompletedAt:     dj.CompletedAt,
+		DurationSeconds: jobDurationSeconds(dj.StartedAt, dj.CompletedAt),
+		SchemaVersion:   currentJobSchemaVersion,
+
+		ResultTruncation: dj.ResultTruncation,
+	}
 }
 
 // ClusterManager manages the distributed cluster
@@ -259,15 +293,38 @@ func (cm *ClusterManager) processJob(job *DistributedJob) {
 	cm.mutex.Unlock()
 
 	// Execute the job using the existing engine
-	results, err := cm.server.engine.EvaluatePlaybook(job.Playbook)
+	results, err := cm.server.engine.WithEnv(job.Env).EvaluatePlaybook(job.Playbook, NewPlaybookContext(job.Context))
+	results, job.ResultTruncation = resultCap.Apply(job.ID, results)
 
 	// Update job with results
-	job.CompletedAt = &now
+	completedAt := time.Now()
+	job.CompletedAt = &completedAt
 	cm.nodeInfo.JobsRunning--
 
-	if err != nil {
+	if abort, ok := err.(*PlaybookAbort); ok {
+		job.Status = abort.Status
+		job.Results = results
+		cm.nodeInfo.JobsCompleted++
+		cm.logger.Info("Job aborted by playbook", map[string]interface{}{
+			"component": "cluster_manager",
+			"job_id":    job.ID,
+			"status":    abort.Status,
+			"reason":    abort.Reason,
+		})
+	} else if ruleErrors, ok := err.(*PlaybookRuleErrors); ok {
+		job.Status = jobStatusCompletedWithErrors
+		job.Error = ruleErrors.Error()
+		job.Results = results
+		cm.nodeInfo.JobsCompleted++
+		cm.logger.Info("Job completed with failed rules", map[string]interface{}{
+			"component": "cluster_manager",
+			"job_id":    job.ID,
+			"failed":    ruleErrors.Count,
+		})
+	} else if err != nil {
 		job.Status = "failed"
 		job.Error = err.Error()
+		job.ErrorType = playbookErrorType(err)
 		cm.nodeInfo.JobsFailed++
 		cm.logger.Error("Job execution failed", map[string]interface{}{
 			"component": "cluster_manager",
@@ -352,12 +409,14 @@ func (cm *ClusterManager) discoverNodes() {
 	})
 }
 
-// SubmitJob submits a job to the distributed queue
-func (cm *ClusterManager) SubmitJob(playbook []interface{}, context map[string]interface{}) (string, error) {
+// SubmitJob submits a job to the distributed queue; env holds extra
+// environment variables for its Python automations
+func (cm *ClusterManager) SubmitJob(playbook []interface{}, context map[string]interface{}, env map[string]string) (string, error) {
 	job := &DistributedJob{
 		ID:          uuid.New().String(),
 		Playbook:    playbook,
 		Context:     context,
+		Env:         env,
 		Status:      "pending",
 		SubmittedAt: time.Now(),
 		RetryCount:  0,
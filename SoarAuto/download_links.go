@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultDownloadLinkTTL applies when security.download_links.default_ttl is unset
+	defaultDownloadLinkTTL = 15 * time.Minute
+	// defaultMaxDownloadLinkTTL applies when security.download_links.max_ttl is unset
+	defaultMaxDownloadLinkTTL = 24 * time.Hour
+	// downloadLinkUsedPrefix prefixes the Redis keys of links already fetched
+	downloadLinkUsedPrefix = "download:used:"
+)
+
+// DownloadLinkRequest is the body of POST /exports/links
+type DownloadLinkRequest struct {
+	Path string `json:"path"`          // Export to share, e.g. /playbooks/triage/export?format=yaml
+	TTL  string `json:"ttl,omitempty"` // How long the link is valid, e.g. "10m"
+}
+
+// DownloadLinkResponse is the response for POST /exports/links
+type DownloadLinkResponse struct {
+	Success   bool   `json:"success"`
+	URL       string `json:"url"`
+	Path      string `json:"path"`
+	ExpiresAt string `json:"expires_at"`
+	Timestamp string `json:"timestamp"`
+}
+
+// DownloadLinks issues and checks signed links that let an export be fetched
+// once, before it expires, without an API key. A link carries its export
+// path and expiry and is signed with HMAC-SHA256, so nothing is stored when
+// it is issued; only links already fetched are remembered, in Redis when the
+// job store uses it so a link is single-use across nodes.
+type DownloadLinks struct {
+	key        []byte
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+	client     *redis.Client
+	ctx        context.Context
+
+	mutex sync.Mutex
+	used  map[string]time.Time // Fetched link IDs and when they expire
+}
+
+// NewDownloadLinks creates the link signer. Used links are recorded in Redis
+// when the job store is Redis. Without a configured signing key a random one
+// is used, so links do not survive a restart and only work on the node that
+// issued them.
+func NewDownloadLinks(config DownloadLinkConfig, store JobStoreInterface) (*DownloadLinks, error) {
+	defaultTTL, err := parseDownloadLinkTTL("default_ttl", config.DefaultTTL, defaultDownloadLinkTTL)
+	if err != nil {
+		return nil, err
+	}
+	maxTTL, err := parseDownloadLinkTTL("max_ttl", config.MaxTTL, defaultMaxDownloadLinkTTL)
+	if err != nil {
+		return nil, err
+	}
+	if defaultTTL > maxTTL {
+		return nil, fmt.Errorf("download link default_ttl cannot exceed max_ttl")
+	}
+
+	key := []byte(config.SigningKey)
+	if len(key) == 0 {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate download link signing key: %v", err)
+		}
+		logger.Warning("No download link signing key configured, links are valid on this node until restart", map[string]interface{}{
+			"component": "download_links",
+		})
+	}
+
+	links := &DownloadLinks{
+		key:        key,
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+		ctx:        context.Background(),
+		used:       make(map[string]time.Time),
+	}
+	if redisStore, ok := store.(*RedisJobStore); ok {
+		links.client = redisStore.client
+	}
+	return links, nil
+}
+
+// parseDownloadLinkTTL parses a configured TTL, returning fallback when unset
+func parseDownloadLinkTTL(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid download link %s: %v", name, err)
+	}
+	if parsed <= 0 {
+		return 0, fmt.Errorf("download link %s must be positive", name)
+	}
+	return parsed, nil
+}
+
+// sign returns the signature of a link
+func (dl *DownloadLinks) sign(id, path string, expires int64) string {
+	mac := hmac.New(sha256.New, dl.key)
+	fmt.Fprintf(mac, "%s\n%s\n%d", id, path, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Create issues a link to path valid for ttl, or the default TTL when ttl is
+// zero
+func (dl *DownloadLinks) Create(path string, ttl time.Duration) (string, time.Time, error) {
+	if ttl == 0 {
+		ttl = dl.defaultTTL
+	}
+	if ttl < 0 || ttl > dl.maxTTL {
+		return "", time.Time{}, fmt.Errorf("ttl must be positive and at most %s", dl.maxTTL)
+	}
+
+	id := uuid.New().String()
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	expires := expiresAt.Unix()
+
+	query := url.Values{}
+	query.Set("id", id)
+	query.Set("path", path)
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("sig", dl.sign(id, path, expires))
+	return "/download?" + query.Encode(), expiresAt, nil
+}
+
+// downloadLinkError is a link that cannot be used, with the status to answer
+type downloadLinkError struct {
+	status  int
+	message string
+}
+
+func (e *downloadLinkError) Error() string {
+	return e.message
+}
+
+// Redeem checks a link's signature and expiry and marks it used, returning
+// the export path it grants. A link can be redeemed once.
+func (dl *DownloadLinks) Redeem(query url.Values) (string, error) {
+	id, path, sig := query.Get("id"), query.Get("path"), query.Get("sig")
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	if id == "" || path == "" || sig == "" || err != nil {
+		return "", &downloadLinkError{http.StatusBadRequest, "Incomplete download link"}
+	}
+	if !hmac.Equal([]byte(sig), []byte(dl.sign(id, path, expires))) {
+		return "", &downloadLinkError{http.StatusForbidden, "Invalid download link signature"}
+	}
+
+	expiresAt := time.Unix(expires, 0)
+	remaining := time.Until(expiresAt)
+	if remaining <= 0 {
+		return "", &downloadLinkError{http.StatusGone, "Download link has expired"}
+	}
+
+	first, err := dl.markUsed(id, expiresAt, remaining)
+	if err != nil {
+		return "", &downloadLinkError{http.StatusServiceUnavailable, fmt.Sprintf("Failed to check download link: %v", err)}
+	}
+	if !first {
+		return "", &downloadLinkError{http.StatusGone, "Download link has already been used"}
+	}
+	return path, nil
+}
+
+// markUsed records that a link was fetched, reporting false if it already was
+func (dl *DownloadLinks) markUsed(id string, expiresAt time.Time, remaining time.Duration) (bool, error) {
+	if dl.client != nil {
+		return dl.client.SetNX(dl.ctx, downloadLinkUsedPrefix+id, 1, remaining).Result()
+	}
+
+	dl.mutex.Lock()
+	defer dl.mutex.Unlock()
+
+	// Forget links that have expired anyway
+	now := time.Now()
+	for usedID, usedExpiry := range dl.used {
+		if usedExpiry.Before(now) {
+			delete(dl.used, usedID)
+		}
+	}
+	if _, exists := dl.used[id]; exists {
+		return false, nil
+	}
+	dl.used[id] = expiresAt
+	return true, nil
+}
+
+// exportHandler returns the handler serving an export, or nil when the path
+// is not an export that may be shared through a download link
+func (s *SecAutoServer) exportHandler(target *url.URL) http.HandlerFunc {
+	switch {
+	case target.Path == "/archive":
+		return s.archiveHandler
+	case strings.HasPrefix(target.Path, "/playbooks/") && strings.HasSuffix(target.Path, "/export"):
+		return s.playbookExportHandler
+	}
+	return nil
+}
+
+// downloadLinksHandler handles POST /exports/links, which issues a signed,
+// single-use link to an export
+func (s *SecAutoServer) downloadLinksHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req DownloadLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	target, err := url.Parse(req.Path)
+	if err != nil || req.Path == "" || target.IsAbs() || target.Host != "" || s.exportHandler(target) == nil {
+		http.Error(w, "path must be an export: /archive or /playbooks/{name}/export", http.StatusBadRequest)
+		return
+	}
+
+	var ttl time.Duration
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid ttl: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	link, expiresAt, err := s.downloadLinks.Create(target.RequestURI(), ttl)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Download link issued", map[string]interface{}{
+		"component":  "download_links",
+		"path":       target.Path,
+		"issued_by":  apiKeyCaller(getRequestAPIKey(r)),
+		"expires_at": expiresAt.UTC().Format(time.RFC3339),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(DownloadLinkResponse{
+		Success:   true,
+		URL:       link,
+		Path:      target.RequestURI(),
+		ExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// downloadHandler handles GET /download, which serves the export a signed
+// link grants without an API key, once
+func (s *SecAutoServer) downloadHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path, err := s.downloadLinks.Redeem(r.URL.Query())
+	if err != nil {
+		linkErr := err.(*downloadLinkError)
+		logger.Warning("Download link rejected", map[string]interface{}{
+			"component":   "download_links",
+			"remote_addr": r.RemoteAddr,
+			"error":       linkErr.message,
+		})
+		http.Error(w, linkErr.message, linkErr.status)
+		return
+	}
+
+	target, err := url.Parse(path)
+	if err != nil {
+		http.Error(w, "Invalid download link", http.StatusBadRequest)
+		return
+	}
+	handler := s.exportHandler(target)
+	if handler == nil {
+		http.Error(w, "Invalid download link", http.StatusBadRequest)
+		return
+	}
+
+	logger.Info("Download link used", map[string]interface{}{
+		"component":   "download_links",
+		"path":        target.Path,
+		"remote_addr": r.RemoteAddr,
+	})
+
+	exportRequest := r.Clone(r.Context())
+	exportRequest.URL = target
+	exportRequest.RequestURI = target.RequestURI()
+	exportRequest.Header.Del("X-API-Key")
+	w.Header().Set("Cache-Control", "no-store")
+	handler(w, exportRequest)
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+const (
+	// elasticsearchIntegrationType is the integration type holding Elasticsearch connection settings
+	elasticsearchIntegrationType = "elasticsearch"
+	// defaultElasticsearchIntegration is the integration used when none is named
+	defaultElasticsearchIntegration = "elasticsearch"
+	// defaultElasticsearchBatchSize is the number of queued documents that
+	// makes an execution send a bulk request
+	defaultElasticsearchBatchSize = 500
+	// maxElasticsearchBatchSize bounds configured bulk batch sizes
+	maxElasticsearchBatchSize = 10000
+	// elasticsearchRequestTimeout bounds each request to the cluster
+	elasticsearchRequestTimeout = 30 * time.Second
+	// maxElasticsearchResponseSize bounds the response body read from the cluster
+	maxElasticsearchResponseSize = 10 << 20
+)
+
+// ElasticsearchClient indexes documents with the official Elasticsearch client
+type ElasticsearchClient struct {
+	es *elasticsearch.Client
+}
+
+// NewElasticsearchClient creates a client from an elasticsearch integration
+// config. The optional tls_ca setting is a PEM-encoded CA certificate or a
+// path to one, trusted in addition to the system roots.
+func NewElasticsearchClient(config *IntegrationConfig) (*ElasticsearchClient, error) {
+	if config.Type != elasticsearchIntegrationType {
+		return nil, fmt.Errorf("integration %s is of type %q, not %q", config.Name, config.Type, elasticsearchIntegrationType)
+	}
+	parsed, err := url.Parse(config.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("integration %s has an invalid url %q", config.Name, config.URL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = elasticsearchRequestTimeout
+	if caSetting, _ := config.Settings["tls_ca"].(string); caSetting != "" {
+		pool, err := loadElasticsearchCA(caSetting)
+		if err != nil {
+			return nil, fmt.Errorf("integration %s: %v", config.Name, err)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	es, err := elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: []string{strings.TrimRight(config.URL, "/")},
+		Username:  config.Username,
+		Password:  config.Password,
+		APIKey:    config.APIKey,
+		Transport: transport,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("integration %s: %v", config.Name, err)
+	}
+	return &ElasticsearchClient{es: es}, nil
+}
+
+// loadElasticsearchCA builds a certificate pool from inline PEM or a PEM file
+func loadElasticsearchCA(caSetting string) (*x509.CertPool, error) {
+	pemData := []byte(caSetting)
+	if !strings.Contains(caSetting, "-----BEGIN") {
+		data, err := os.ReadFile(caSetting)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls_ca: %v", err)
+		}
+		pemData = data
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, fmt.Errorf("tls_ca contains no valid certificates")
+	}
+	return pool, nil
+}
+
+// IndexDocument indexes a single document, using id when it is not empty
+func (ec *ElasticsearchClient) IndexDocument(index, id string, document interface{}) (map[string]interface{}, error) {
+	body, err := json.Marshal(document)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal document: %v", err)
+	}
+
+	options := []func(*esapi.IndexRequest){ec.es.Index.WithContext(context.Background())}
+	if id != "" {
+		options = append(options, ec.es.Index.WithDocumentID(id))
+	}
+	resp, err := ec.es.Index(index, bytes.NewReader(body), options...)
+	if err != nil {
+		return nil, fmt.Errorf("elasticsearch request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxElasticsearchResponseSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read elasticsearch response: %v", err)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("elasticsearch returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("invalid elasticsearch response: %v", err)
+	}
+	return result, nil
+}
+
+// NewBulkIndexer creates a bulk indexer sending through the client. One
+// worker keeps the documents of an execution in order.
+func (ec *ElasticsearchClient) NewBulkIndexer(onError func(error)) (esutil.BulkIndexer, error) {
+	return esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:     ec.es,
+		NumWorkers: 1,
+		OnError: func(ctx context.Context, err error) {
+			onError(err)
+		},
+	})
+}
+
+// evaluateElasticsearchIndexOperation handles the "elasticsearch_index"
+// operation, which indexes a context value as a document. With "bulk" the
+// documents are queued for the execution's bulk indexer instead, so
+// documents from many rules and foreach iterations share requests.
+func (re *RuleEngine) evaluateElasticsearchIndexOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("elasticsearch_index operation requires an object")
+	}
+
+	index, ok := specMap["index"].(string)
+	if !ok || index == "" {
+		return nil, fmt.Errorf("elasticsearch_index operation requires an index")
+	}
+
+	document, err := re.resolveOperationValue(specMap["document_var"], data)
+	if err != nil {
+		return nil, fmt.Errorf("elasticsearch_index document_var: %v", err)
+	}
+
+	id := ""
+	if value, exists := specMap["id"]; exists && value != nil {
+		id = fmt.Sprintf("%v", value)
+	}
+
+	var result map[string]interface{}
+	status := "completed"
+	bulk, _ := specMap["bulk"].(bool)
+	if bulk {
+		status = "queued"
+		result, err = re.queueBulkDocuments(index, id, document, specMap)
+	} else {
+		var client *ElasticsearchClient
+		client, err = re.elasticsearchClient(specMap["integration"])
+		if err == nil {
+			result, err = client.IndexDocument(index, id, document)
+		}
+	}
+	if err != nil {
+		logger.Error("Elasticsearch indexing failed", map[string]interface{}{
+			"component": "rules_engine",
+			"index":     index,
+			"error":     err.Error(),
+		})
+		return nil, fmt.Errorf("elasticsearch_index failed: %v", err)
+	}
+
+	if responseVar, _ := specMap["response_var"].(string); responseVar != "" {
+		if err := setContextPath(data, strings.Split(responseVar, "."), result); err != nil {
+			return nil, fmt.Errorf("elasticsearch_index failed: %v", err)
+		}
+	}
+
+	logger.Info("Indexed Elasticsearch documents", map[string]interface{}{
+		"component": "rules_engine",
+		"index":     index,
+		"bulk":      bulk,
+	})
+
+	return map[string]interface{}{
+		"elasticsearch_index": index,
+		"status":              status,
+		"response":            result,
+	}, nil
+}
+
+// queueBulkDocuments adds a document, or each document of an array, to the
+// execution's bulk indexer and reports how many were queued
+func (re *RuleEngine) queueBulkDocuments(index, id string, value interface{}, specMap map[string]interface{}) (map[string]interface{}, error) {
+	if re.elasticsearchBulk == nil {
+		return nil, fmt.Errorf("bulk indexing is only available while a playbook runs")
+	}
+
+	batchSize := defaultElasticsearchBatchSize
+	if size, ok := jsonInteger(specMap["batch_size"]); ok {
+		batchSize = int(size)
+	} else if specMap["batch_size"] != nil {
+		batchSize = 0
+	}
+	if batchSize <= 0 || batchSize > maxElasticsearchBatchSize {
+		return nil, fmt.Errorf("batch_size must be between 1 and %d", maxElasticsearchBatchSize)
+	}
+
+	documents, isArray := value.([]interface{})
+	if !isArray {
+		documents = []interface{}{value}
+	}
+
+	// Document ids are read from a field of each document, if configured,
+	// and otherwise a single document may take id
+	ids := make([]string, len(documents))
+	idField, _ := specMap["id_field"].(string)
+	for i, document := range documents {
+		if idField != "" {
+			if documentMap, ok := document.(map[string]interface{}); ok {
+				if documentID, err := re.evaluateDotNotation(idField, documentMap); err == nil && documentID != nil {
+					ids[i] = fmt.Sprintf("%v", documentID)
+				}
+			}
+		} else if !isArray {
+			ids[i] = id
+		}
+	}
+
+	integration := defaultElasticsearchIntegration
+	if name, ok := specMap["integration"].(string); ok && name != "" {
+		integration = name
+	}
+	if err := re.elasticsearchBulk.Add(integration, index, documents, ids, batchSize); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"queued": len(documents)}, nil
+}
+
+// maxElasticsearchBulkFailures bounds the failed documents kept for the
+// error an execution reports
+const maxElasticsearchBulkFailures = 10
+
+// elasticsearchBulkBuffer collects the bulk-indexed documents of one playbook
+// execution, across rules and foreach iterations, and sends them through one
+// bulk indexer per integration. An indexer is flushed once batch_size
+// documents are queued on it, and the rest when the playbook ends.
+type elasticsearchBulkBuffer struct {
+	engine   *RuleEngine
+	mutex    sync.Mutex
+	indexers map[string]*elasticsearchBulkIndexer
+
+	// Outcomes are reported by the indexers' workers
+	resultMutex sync.Mutex
+	indexed     int
+	failed      int
+	failures    []string
+	errors      []string
+}
+
+// elasticsearchBulkIndexer is the open bulk indexer of one integration
+type elasticsearchBulkIndexer struct {
+	indexer esutil.BulkIndexer
+	queued  int
+}
+
+// newElasticsearchBulkBuffer creates a bulk buffer that sends through
+// engine's integrations
+func newElasticsearchBulkBuffer(engine *RuleEngine) *elasticsearchBulkBuffer {
+	return &elasticsearchBulkBuffer{
+		engine:   engine,
+		indexers: make(map[string]*elasticsearchBulkIndexer),
+	}
+}
+
+// Add queues documents for an integration's indexer, flushing the indexer
+// when batchSize documents are queued on it. ids holds an id (or "") per
+// document.
+func (bb *elasticsearchBulkBuffer) Add(integration, index string, documents []interface{}, ids []string, batchSize int) error {
+	bb.mutex.Lock()
+	defer bb.mutex.Unlock()
+
+	for i, document := range documents {
+		body, err := json.Marshal(document)
+		if err != nil {
+			return fmt.Errorf("failed to marshal document %d: %v", i, err)
+		}
+
+		open, err := bb.indexer(integration)
+		if err != nil {
+			return err
+		}
+		err = open.indexer.Add(context.Background(), esutil.BulkIndexerItem{
+			Action:     "index",
+			Index:      index,
+			DocumentID: ids[i],
+			Body:       bytes.NewReader(body),
+			OnSuccess:  bb.recordSuccess,
+			OnFailure:  bb.recordFailure,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to queue document %d: %v", i, err)
+		}
+
+		open.queued++
+		if open.queued >= batchSize {
+			if err := bb.close(integration); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// indexer returns the open indexer of an integration, creating it if needed
+func (bb *elasticsearchBulkBuffer) indexer(integration string) (*elasticsearchBulkIndexer, error) {
+	if open, exists := bb.indexers[integration]; exists {
+		return open, nil
+	}
+
+	client, err := bb.engine.elasticsearchClient(integration)
+	if err != nil {
+		return nil, err
+	}
+	indexer, err := client.NewBulkIndexer(bb.recordError)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bulk indexer: %v", err)
+	}
+	open := &elasticsearchBulkIndexer{indexer: indexer}
+	bb.indexers[integration] = open
+	return open, nil
+}
+
+// close flushes an integration's indexer and waits for its requests
+func (bb *elasticsearchBulkBuffer) close(integration string) error {
+	open := bb.indexers[integration]
+	delete(bb.indexers, integration)
+	if err := open.indexer.Close(context.Background()); err != nil {
+		return fmt.Errorf("failed to flush bulk indexer: %v", err)
+	}
+	return bb.requestError()
+}
+
+// Flush sends the queued documents of every integration. It fails if a bulk
+// request or any document of the execution failed.
+func (bb *elasticsearchBulkBuffer) Flush() error {
+	bb.mutex.Lock()
+	defer bb.mutex.Unlock()
+
+	for integration := range bb.indexers {
+		if err := bb.close(integration); err != nil {
+			return err
+		}
+	}
+
+	bb.resultMutex.Lock()
+	defer bb.resultMutex.Unlock()
+	if bb.indexed > 0 || bb.failed > 0 {
+		logger.Info("Sent Elasticsearch bulk documents", map[string]interface{}{
+			"component": "rules_engine",
+			"indexed":   bb.indexed,
+			"failed":    bb.failed,
+		})
+	}
+	if bb.failed > 0 {
+		return fmt.Errorf("%d of %d documents failed to index: %s", bb.failed, bb.indexed+bb.failed, strings.Join(bb.failures, "; "))
+	}
+	return nil
+}
+
+// requestError returns the first bulk request failure, if any
+func (bb *elasticsearchBulkBuffer) requestError() error {
+	bb.resultMutex.Lock()
+	defer bb.resultMutex.Unlock()
+	if len(bb.errors) > 0 {
+		return fmt.Errorf("bulk request failed: %s", bb.errors[0])
+	}
+	return nil
+}
+
+func (bb *elasticsearchBulkBuffer) recordSuccess(ctx context.Context, item esutil.BulkIndexerItem, response esutil.BulkIndexerResponseItem) {
+	bb.resultMutex.Lock()
+	defer bb.resultMutex.Unlock()
+	bb.indexed++
+}
+
+func (bb *elasticsearchBulkBuffer) recordFailure(ctx context.Context, item esutil.BulkIndexerItem, response esutil.BulkIndexerResponseItem, err error) {
+	bb.resultMutex.Lock()
+	defer bb.resultMutex.Unlock()
+	bb.failed++
+	if len(bb.failures) >= maxElasticsearchBulkFailures {
+		return
+	}
+	if err != nil {
+		bb.failures = append(bb.failures, err.Error())
+	} else {
+		bb.failures = append(bb.failures, fmt.Sprintf("%s %s: %s", response.Index, response.DocumentID, response.Error.Reason))
+	}
+}
+
+func (bb *elasticsearchBulkBuffer) recordError(err error) {
+	bb.resultMutex.Lock()
+	defer bb.resultMutex.Unlock()
+	bb.errors = append(bb.errors, err.Error())
+}
+
+// elasticsearchClient creates a client for the named (or default) enabled
+// elasticsearch integration
+func (re *RuleEngine) elasticsearchClient(integration interface{}) (*ElasticsearchClient, error) {
+	if re.integrations == nil {
+		return nil, fmt.Errorf("integration configs are not available")
+	}
+
+	name := defaultElasticsearchIntegration
+	if value, ok := integration.(string); ok && value != "" {
+		name = value
+	}
+
+	config, exists, err := re.integrations.GetResolvedConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("integration %s not found", name)
+	}
+	if !config.Enabled {
+		return nil, fmt.Errorf("integration %s is disabled", name)
+	}
+	return NewElasticsearchClient(config)
+}
+
+// resolveOperationValue resolves an operation parameter that names a context
+// value: template references are already resolved by evaluate, and a plain
+// string is looked up as a dotted context path
+func (re *RuleEngine) resolveOperationValue(value interface{}, data map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, fmt.Errorf("value is required")
+	case string:
+		resolved, err := re.evaluateDotNotation(strings.Trim(v, "{} "), data)
+		if err != nil || resolved == nil {
+			return nil, fmt.Errorf("context variable %q not found", v)
+		}
+		return resolved, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeElasticsearch records the documents sent to its _bulk and _doc
+// endpoints. Documents with a "reject" field fail to index.
+type fakeElasticsearch struct {
+	mutex     sync.Mutex
+	bulkSizes []int
+	documents []map[string]interface{}
+	paths     []string
+}
+
+func (fe *fakeElasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fe.mutex.Lock()
+	defer fe.mutex.Unlock()
+
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	fe.paths = append(fe.paths, r.Method+" "+r.URL.Path)
+
+	if r.URL.Path != "/_bulk" {
+		var document map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&document)
+		fe.documents = append(fe.documents, document)
+		w.Write([]byte(`{"result": "created", "_id": "generated"}`))
+		return
+	}
+
+	var items []interface{}
+	scanner := bufio.NewScanner(r.Body)
+	for scanner.Scan() {
+		var action map[string]map[string]interface{}
+		json.Unmarshal(scanner.Bytes(), &action)
+		scanner.Scan()
+		var document map[string]interface{}
+		json.Unmarshal(scanner.Bytes(), &document)
+		fe.documents = append(fe.documents, document)
+
+		item := map[string]interface{}{"_index": action["index"]["_index"], "_id": action["index"]["_id"], "status": 201, "result": "created"}
+		if document["reject"] != nil {
+			item["status"] = 400
+			item["error"] = map[string]interface{}{"type": "mapper_parsing_exception", "reason": "bad field"}
+		}
+		items = append(items, map[string]interface{}{"index": item})
+	}
+	fe.bulkSizes = append(fe.bulkSizes, len(items))
+	json.NewEncoder(w).Encode(map[string]interface{}{"errors": false, "items": items})
+}
+
+// newElasticsearchTestEngine returns an engine whose default elasticsearch
+// integration points at a fake cluster
+func newElasticsearchTestEngine(t *testing.T) (*RuleEngine, *fakeElasticsearch) {
+	t.Helper()
+	cluster := &fakeElasticsearch{}
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+
+	integrations, err := NewIntegrationConfigManager(filepath.Join(t.TempDir(), "integrations.enc"), "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := integrations.SetConfig("elasticsearch", &IntegrationConfig{Name: "elasticsearch", Type: "elasticsearch", URL: server.URL, Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewRuleEngine(&Config{})
+	engine.SetIntegrationConfigManager(integrations)
+	return engine, cluster
+}
+
+func TestElasticsearchBulkBatchesAcrossRules(t *testing.T) {
+	engine, cluster := newElasticsearchTestEngine(t)
+	playbook := parsePlaybook(t, `[
+		{"foreach": {"items": {"var": "alerts"}, "as": "alert", "do": [
+			{"elasticsearch_index": {"index": "alerts", "document_var": "alert", "id_field": "id", "bulk": true, "batch_size": 2}}
+		]}},
+		{"elasticsearch_index": {"index": "incidents", "document_var": "incident", "id": "{{incident.id}}", "bulk": true, "batch_size": 2, "response_var": "queued"}}
+	]`)
+	context := map[string]interface{}{
+		"alerts": []interface{}{
+			map[string]interface{}{"id": "a1"},
+			map[string]interface{}{"id": "a2"},
+			map[string]interface{}{"id": "a3"},
+		},
+		"incident": map[string]interface{}{"id": "i1"},
+	}
+
+	if _, err := engine.EvaluatePlaybook(playbook, context); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	// Three single documents from the loop and one from the last rule make
+	// two batches of two
+	if len(cluster.bulkSizes) != 2 || cluster.bulkSizes[0] != 2 || cluster.bulkSizes[1] != 2 {
+		t.Errorf("bulk request sizes = %v, want [2 2]", cluster.bulkSizes)
+	}
+	if len(cluster.documents) != 4 || cluster.documents[3]["id"] != "i1" {
+		t.Errorf("documents = %v", cluster.documents)
+	}
+	if queued, _ := context["queued"].(map[string]interface{}); queued["queued"] != 1 {
+		t.Errorf("response_var = %v, want one queued document", context["queued"])
+	}
+}
+
+func TestElasticsearchBulkFlushesRemainderAndReportsFailures(t *testing.T) {
+	engine, cluster := newElasticsearchTestEngine(t)
+	playbook := parsePlaybook(t, `[
+		{"elasticsearch_index": {"index": "iocs", "document_var": "iocs", "bulk": true}}
+	]`)
+	context := map[string]interface{}{"iocs": []interface{}{
+		map[string]interface{}{"value": "1.2.3.4"},
+		map[string]interface{}{"value": "evil.example", "reject": true},
+	}}
+
+	_, err := engine.EvaluatePlaybook(playbook, context)
+	if err == nil || !strings.Contains(err.Error(), "1 of 2 documents failed") {
+		t.Errorf("evaluate error = %v, want the failed document reported", err)
+	}
+	if len(cluster.bulkSizes) != 1 || cluster.bulkSizes[0] != 2 {
+		t.Errorf("bulk request sizes = %v, want the remainder sent when the playbook ends", cluster.bulkSizes)
+	}
+}
+
+func TestElasticsearchIndexSingleDocument(t *testing.T) {
+	engine, cluster := newElasticsearchTestEngine(t)
+	playbook := parsePlaybook(t, `[
+		{"elasticsearch_index": {"index": "incidents", "document_var": "{{incident}}", "id": "{{incident.id}}", "response_var": "es_response"}}
+	]`)
+	context := map[string]interface{}{"incident": map[string]interface{}{"id": "inc-7", "severity": "high"}}
+
+	if _, err := engine.EvaluatePlaybook(playbook, context); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	if len(cluster.paths) != 1 || cluster.paths[0] != "PUT /incidents/_doc/inc-7" {
+		t.Errorf("requests = %v, want one PUT of the document", cluster.paths)
+	}
+	if response, _ := context["es_response"].(map[string]interface{}); response["result"] != "created" {
+		t.Errorf("es_response = %v", context["es_response"])
+	}
+}
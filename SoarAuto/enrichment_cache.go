@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// enrichmentCacheKeyPrefix prefixes the Redis keys of cached enrichment results
+const enrichmentCacheKeyPrefix = "enrichment:"
+
+// EnrichmentCache caches the results of "run" and "plugin" operations that
+// declare a cache key, typically the indicator they look up, so jobs that
+// enrich the same IP, hash or domain within the TTL reuse one result instead
+// of each calling the external API.
+type EnrichmentCache struct {
+	client     *redis.Client
+	ctx        context.Context
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+
+	hits   atomic.Int64
+	misses atomic.Int64
+	errors atomic.Int64
+}
+
+// NewEnrichmentCache creates a Redis-backed enrichment cache
+func NewEnrichmentCache(client *redis.Client, config EnrichmentCacheConfig) (*EnrichmentCache, error) {
+	defaultTTL, err := parseEnrichmentTTL("default_ttl", config.DefaultTTL, 15*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	maxTTL, err := parseEnrichmentTTL("max_ttl", config.MaxTTL, 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	if defaultTTL > maxTTL {
+		return nil, fmt.Errorf("enrichment cache default_ttl cannot exceed max_ttl")
+	}
+
+	logger.Info("Enrichment cache enabled", map[string]interface{}{
+		"component":   "enrichment_cache",
+		"default_ttl": defaultTTL.String(),
+		"max_ttl":     maxTTL.String(),
+	})
+
+	return &EnrichmentCache{
+		client:     client,
+		ctx:        context.Background(),
+		defaultTTL: defaultTTL,
+		maxTTL:     maxTTL,
+	}, nil
+}
+
+// parseEnrichmentTTL parses a configured TTL, returning fallback when unset
+func parseEnrichmentTTL(name, value string, fallback time.Duration) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid enrichment cache %s: %v", name, err)
+	}
+	if parsed <= 0 {
+		return 0, fmt.Errorf("enrichment cache %s must be positive", name)
+	}
+	return parsed, nil
+}
+
+// enrichmentCacheKey is the Redis key of a lookup. The operation kind and
+// the script or plugin name are part of the key, so the same indicator looked
+// up by different automations is cached separately.
+func enrichmentCacheKey(kind, name string, key interface{}) (string, error) {
+	encoded, err := json.Marshal(key)
+	if err != nil {
+		return "", fmt.Errorf("cache key must be JSON-encodable: %v", err)
+	}
+	hash := sha256.Sum256(encoded)
+	return enrichmentCacheKeyPrefix + kind + ":" + name + ":" + hex.EncodeToString(hash[:]), nil
+}
+
+// Get returns the cached result stored under key, if any
+func (ec *EnrichmentCache) Get(key string) (interface{}, bool) {
+	data, err := ec.client.Get(ec.ctx, key).Bytes()
+	if err == redis.Nil {
+		ec.misses.Add(1)
+		return nil, false
+	}
+	if err != nil {
+		ec.errors.Add(1)
+		logger.Warning("Enrichment cache lookup failed", map[string]interface{}{
+			"component": "enrichment_cache",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(data, &result); err != nil {
+		ec.errors.Add(1)
+		logger.Warning("Discarding undecodable enrichment cache entry", map[string]interface{}{
+			"component": "enrichment_cache",
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+
+	ec.hits.Add(1)
+	return result, true
+}
+
+// Set caches a result under key for ttl, or the default TTL when ttl is 0.
+// Failures are logged; the lookup has already succeeded and is not failed.
+func (ec *EnrichmentCache) Set(key string, result interface{}, ttl time.Duration) {
+	if ttl == 0 {
+		ttl = ec.defaultTTL
+	}
+
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = ec.client.Set(ec.ctx, key, data, ttl).Err()
+	}
+	if err != nil {
+		ec.errors.Add(1)
+		logger.Warning("Failed to cache enrichment result", map[string]interface{}{
+			"component": "enrichment_cache",
+			"error":     err.Error(),
+		})
+	}
+}
+
+// Stats returns the cache's hit and miss counters
+func (ec *EnrichmentCache) Stats() map[string]interface{} {
+	hits := ec.hits.Load()
+	misses := ec.misses.Load()
+
+	hitRate := 0.0
+	if hits+misses > 0 {
+		hitRate = float64(hits) / float64(hits+misses)
+	}
+
+	return map[string]interface{}{
+		"default_ttl":  ec.defaultTTL.String(),
+		"max_ttl":      ec.maxTTL.String(),
+		"hits_total":   hits,
+		"misses_total": misses,
+		"errors_total": ec.errors.Load(),
+		"hit_rate":     hitRate,
+	}
+}
+
+// parseEnrichmentCacheSpec reads an operation's "cache" setting, which is
+// either the cache key or an object with "key" and an optional "ttl" duration
+func (ec *EnrichmentCache) parseEnrichmentCacheSpec(spec interface{}) (interface{}, time.Duration, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return spec, 0, nil
+	}
+
+	key, exists := specMap["key"]
+	if !exists {
+		return nil, 0, fmt.Errorf("cache requires a key")
+	}
+
+	var ttl time.Duration
+	if ttlValue, exists := specMap["ttl"]; exists {
+		ttlStr, ok := ttlValue.(string)
+		if !ok {
+			return nil, 0, fmt.Errorf("cache ttl must be a duration string")
+		}
+		parsed, err := time.ParseDuration(ttlStr)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid cache ttl: %v", err)
+		}
+		if parsed <= 0 {
+			return nil, 0, fmt.Errorf("cache ttl must be positive")
+		}
+		if parsed > ec.maxTTL {
+			parsed = ec.maxTTL
+		}
+		ttl = parsed
+	}
+	return key, ttl, nil
+}
+
+// cachedEnrichment returns the cached result of a lookup declaring a cache
+// setting, or performs the lookup and caches its result. Lookups without a
+// cache setting, or run while the cache is disabled, always run. Cached
+// results pass through JSON, so numbers come back as floats.
+func (re *RuleEngine) cachedEnrichment(kind, name string, cacheSpec interface{}, lookup func() (interface{}, error)) (interface{}, bool, error) {
+	if cacheSpec == nil || re.enrichmentCache == nil {
+		result, err := lookup()
+		return result, false, err
+	}
+
+	key, ttl, err := re.enrichmentCache.parseEnrichmentCacheSpec(cacheSpec)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s %s: %v", kind, name, err)
+	}
+	if keyStr, isString := key.(string); key == nil || (isString && (keyStr == "" || strings.Contains(keyStr, "{{"))) {
+		// The indicator is missing from the context; nothing to share
+		result, err := lookup()
+		return result, false, err
+	}
+
+	redisKey, err := enrichmentCacheKey(kind, name, key)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s %s: %v", kind, name, err)
+	}
+
+	if result, found := re.enrichmentCache.Get(redisKey); found {
+		logger.Info("Using cached enrichment result", map[string]interface{}{
+			"component": "enrichment_cache",
+			"kind":      kind,
+			"name":      name,
+		})
+		return result, true, nil
+	}
+
+	result, err := lookup()
+	if err != nil {
+		return nil, false, err
+	}
+	re.enrichmentCache.Set(redisKey, result, ttl)
+	return result, false, nil
+}
@@ -0,0 +1,466 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// defaultEventHistorySize applies when events.history_size is unset
+	defaultEventHistorySize = 1000
+	// eventSubscriberBuffer is how many events a slow stream may fall behind
+	// before it misses events
+	eventSubscriberBuffer = 64
+	// eventStreamKeepalive is how often an idle stream sends a comment, so
+	// proxies do not close it
+	eventStreamKeepalive = 15 * time.Second
+	// eventHistoryKey is the Redis list holding the newest events first
+	eventHistoryKey = "events:history"
+	// eventChannel is the Redis channel events are published on
+	eventChannel = "events:stream"
+)
+
+// ActivityEvent is one entry of the activity feed
+type ActivityEvent struct {
+	ID          string                 `json:"id"`
+	Type        string                 `json:"type"` // e.g. "job_submitted", "job_completed", "schedule_fired", "integration_updated"
+	Timestamp   string                 `json:"timestamp"`
+	JobID       string                 `json:"job_id,omitempty"`
+	Playbook    string                 `json:"playbook,omitempty"`
+	ScheduleID  string                 `json:"schedule_id,omitempty"`
+	Plugin      string                 `json:"plugin,omitempty"`
+	Integration string                 `json:"integration,omitempty"`
+	Status      string                 `json:"status,omitempty"`
+	Details     map[string]interface{} `json:"details,omitempty"`
+}
+
+// EventsResponse is the response for GET /events
+type EventsResponse struct {
+	Success   bool            `json:"success"`
+	Events    []ActivityEvent `json:"events"`
+	Count     int             `json:"count"`
+	Timestamp string          `json:"timestamp"`
+}
+
+// eventFilter selects the events a feed reader asked for
+type eventFilter struct {
+	types map[string]bool
+	jobID string
+	since time.Time
+}
+
+// matches reports whether an event passes the filter
+func (f eventFilter) matches(event ActivityEvent) bool {
+	if len(f.types) > 0 && !f.types[event.Type] {
+		return false
+	}
+	if f.jobID != "" && event.JobID != f.jobID {
+		return false
+	}
+	if !f.since.IsZero() {
+		timestamp, err := time.Parse(time.RFC3339Nano, event.Timestamp)
+		if err != nil || timestamp.Before(f.since) {
+			return false
+		}
+	}
+	return true
+}
+
+// EventBus collects what happens in the server, such as jobs submitted and
+// finished, schedules fired, plugins reloaded and integrations changed, into
+// one chronological feed. The newest events.history_size events are kept for
+// GET /events and streamed to GET /events/stream. With events.backend
+// "redis" the feed is shared by every node using the Redis job store.
+type EventBus struct {
+	size   int
+	client *redis.Client
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex       sync.RWMutex
+	history     []ActivityEvent // Oldest first
+	subscribers map[chan ActivityEvent]eventFilter
+	dropped     int64 // Events not delivered to streams that fell behind
+}
+
+// eventBus is the feed the server publishes to; events published before the
+// configured bus replaces it are kept in process only
+var eventBus = NewMemoryEventBus(defaultEventHistorySize)
+
+// NewMemoryEventBus creates a bus that keeps the newest size events in the
+// process
+func NewMemoryEventBus(size int) *EventBus {
+	if size <= 0 {
+		size = defaultEventHistorySize
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EventBus{
+		size:        size,
+		ctx:         ctx,
+		cancel:      cancel,
+		subscribers: make(map[chan ActivityEvent]eventFilter),
+	}
+}
+
+// NewEventBus creates the bus configured by events. The Redis backend needs
+// the Redis job store; with another store the feed stays in process.
+func NewEventBus(config EventsConfig, store JobStoreInterface) (*EventBus, error) {
+	bus := NewMemoryEventBus(config.HistorySize)
+
+	switch config.Backend {
+	case "", "memory":
+		return bus, nil
+	case "redis":
+	default:
+		return nil, fmt.Errorf("unknown events backend %q: must be memory or redis", config.Backend)
+	}
+
+	redisStore, ok := store.(*RedisJobStore)
+	if !ok {
+		logger.Warning("Events backend redis needs the Redis job store, keeping the activity feed on this node", map[string]interface{}{
+			"component": "events",
+		})
+		return bus, nil
+	}
+	bus.client = redisStore.client
+
+	// Start with the history other nodes have recorded
+	stored, err := bus.client.LRange(bus.ctx, eventHistoryKey, 0, int64(bus.size-1)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event history: %v", err)
+	}
+	for i := len(stored) - 1; i >= 0; i-- {
+		var event ActivityEvent
+		if json.Unmarshal([]byte(stored[i]), &event) == nil {
+			bus.history = append(bus.history, event)
+		}
+	}
+
+	subscription := bus.client.Subscribe(bus.ctx, eventChannel)
+	if _, err := subscription.Receive(bus.ctx); err != nil {
+		subscription.Close()
+		return nil, fmt.Errorf("failed to subscribe to events: %v", err)
+	}
+	go bus.receive(subscription)
+
+	return bus, nil
+}
+
+// receive delivers the events published by every node
+func (eb *EventBus) receive(subscription *redis.PubSub) {
+	defer subscription.Close()
+	for message := range subscription.Channel() {
+		var event ActivityEvent
+		if err := json.Unmarshal([]byte(message.Payload), &event); err != nil {
+			continue
+		}
+		eb.deliver(event)
+	}
+}
+
+// Publish adds an event to the feed, filling in its ID and timestamp
+func (eb *EventBus) Publish(event ActivityEvent) {
+	if eb == nil {
+		return
+	}
+	event.ID = uuid.New().String()
+	if event.Timestamp == "" {
+		event.Timestamp = time.Now().UTC().Format(time.RFC3339Nano)
+	}
+	event.Details = outputRedactor.RedactMap(event.Details)
+
+	if eb.client != nil {
+		payload, err := json.Marshal(event)
+		if err == nil {
+			pipe := eb.client.TxPipeline()
+			pipe.LPush(eb.ctx, eventHistoryKey, payload)
+			pipe.LTrim(eb.ctx, eventHistoryKey, 0, int64(eb.size-1))
+			pipe.Publish(eb.ctx, eventChannel, payload)
+			if _, err = pipe.Exec(eb.ctx); err == nil {
+				return // Delivered when it arrives on the channel
+			}
+		}
+		logger.Warning("Failed to publish event to Redis, delivering on this node only", map[string]interface{}{
+			"component": "events",
+			"error":     err.Error(),
+		})
+	}
+	eb.deliver(event)
+}
+
+// deliver records an event and sends it to the matching streams
+func (eb *EventBus) deliver(event ActivityEvent) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	eb.history = append(eb.history, event)
+	if len(eb.history) > eb.size {
+		eb.history = append([]ActivityEvent(nil), eb.history[len(eb.history)-eb.size:]...)
+	}
+
+	for subscriber, filter := range eb.subscribers {
+		if !filter.matches(event) {
+			continue
+		}
+		select {
+		case subscriber <- event:
+		default:
+			eb.dropped++
+		}
+	}
+}
+
+// Recent returns up to limit of the newest events matching filter, oldest
+// first
+func (eb *EventBus) Recent(filter eventFilter, limit int) []ActivityEvent {
+	eb.mutex.RLock()
+	defer eb.mutex.RUnlock()
+
+	var events []ActivityEvent
+	for i := len(eb.history) - 1; i >= 0 && len(events) < limit; i-- {
+		if filter.matches(eb.history[i]) {
+			events = append(events, eb.history[i])
+		}
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events
+}
+
+// Subscribe returns the events matching filter recorded after the event
+// lastID, when it is still retained, and a channel of those published from
+// now on. cancel must be called when the stream ends.
+func (eb *EventBus) Subscribe(filter eventFilter, lastID string) ([]ActivityEvent, <-chan ActivityEvent, func()) {
+	eb.mutex.Lock()
+	defer eb.mutex.Unlock()
+
+	var missed []ActivityEvent
+	if lastID != "" {
+		for i := len(eb.history) - 1; i >= 0; i-- {
+			if eb.history[i].ID != lastID {
+				continue
+			}
+			for _, event := range eb.history[i+1:] {
+				if filter.matches(event) {
+					missed = append(missed, event)
+				}
+			}
+			break
+		}
+	}
+
+	subscriber := make(chan ActivityEvent, eventSubscriberBuffer)
+	eb.subscribers[subscriber] = filter
+	cancel := func() {
+		eb.mutex.Lock()
+		defer eb.mutex.Unlock()
+		delete(eb.subscribers, subscriber)
+	}
+	return missed, subscriber, cancel
+}
+
+// Stats returns the size of the feed and its streams
+func (eb *EventBus) Stats() map[string]interface{} {
+	eb.mutex.RLock()
+	defer eb.mutex.RUnlock()
+
+	backend := "memory"
+	if eb.client != nil {
+		backend = "redis"
+	}
+	return map[string]interface{}{
+		"backend":        backend,
+		"retained":       len(eb.history),
+		"history_size":   eb.size,
+		"streams":        len(eb.subscribers),
+		"dropped_events": eb.dropped,
+	}
+}
+
+// Close stops receiving events from other nodes
+func (eb *EventBus) Close() {
+	eb.cancel()
+}
+
+// parseEventFilter reads the type, job_id and since query parameters. type
+// may be repeated or comma-separated.
+func parseEventFilter(r *http.Request) (eventFilter, error) {
+	query := r.URL.Query()
+	filter := eventFilter{jobID: query.Get("job_id")}
+
+	for _, value := range query["type"] {
+		for _, eventType := range strings.Split(value, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				if filter.types == nil {
+					filter.types = make(map[string]bool)
+				}
+				filter.types[eventType] = true
+			}
+		}
+	}
+
+	if value := query.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, fmt.Errorf("since must be an RFC 3339 timestamp")
+		}
+		filter.since = since
+	}
+	return filter, nil
+}
+
+// eventsHandler handles GET /events?type=job_failed,job_aborted&job_id=...&since=...&limit=100,
+// which returns the newest matching events in chronological order
+func (s *SecAutoServer) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	events := eventBus.Recent(filter, limit)
+	if events == nil {
+		events = []ActivityEvent{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(EventsResponse{
+		Success:   true,
+		Events:    events,
+		Count:     len(events),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// eventsStreamHandler handles GET /events/stream, which streams matching
+// events as Server-Sent Events. A client reconnecting with Last-Event-ID
+// first receives the events it missed, if they are still retained.
+func (s *SecAutoServer) eventsStreamHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	controller := http.NewResponseController(w)
+	// A stream outlasts the server's write timeout
+	controller.SetWriteDeadline(time.Time{})
+
+	missed, events, cancel := eventBus.Subscribe(filter, r.Header.Get("Last-Event-ID"))
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event ActivityEvent) error {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, payload); err != nil {
+			return err
+		}
+		return controller.Flush()
+	}
+
+	for _, event := range missed {
+		if send(event) != nil {
+			return
+		}
+	}
+	if controller.Flush() != nil {
+		return
+	}
+
+	keepalive := time.NewTicker(eventStreamKeepalive)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case event := <-events:
+			if send(event) != nil {
+				return
+			}
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil || controller.Flush() != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// activityEventFromWebhook turns a webhook notification into a feed event, so
+// everything that notifies webhooks also appears in the feed
+func activityEventFromWebhook(event WebhookEvent) ActivityEvent {
+	details := make(map[string]interface{})
+	if event.Error != "" {
+		details["error"] = event.Error
+	}
+	if event.Reason != "" {
+		details["reason"] = event.Reason
+	}
+	if event.Duration > 0 {
+		details["duration_seconds"] = event.Duration
+	}
+	if event.CancelledBy != "" {
+		details["cancelled_by"] = event.CancelledBy
+	}
+	if event.PluginVersion != "" {
+		details["plugin_version"] = event.PluginVersion
+		details["previous_version"] = event.PreviousVersion
+	}
+	if len(event.AffectedJobs) > 0 {
+		details["affected_jobs"] = len(event.AffectedJobs)
+	}
+	if len(event.Problems) > 0 {
+		details["problems"] = len(event.Problems)
+	}
+	if len(event.Findings) > 0 {
+		details["findings"] = len(event.Findings)
+	}
+
+	activity := ActivityEvent{
+		Type:   event.Event,
+		JobID:  event.JobID,
+		Status: event.Status,
+		Plugin: event.Plugin,
+	}
+	if len(details) > 0 {
+		activity.Details = details
+	}
+	return activity
+}
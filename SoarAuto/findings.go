@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxFindings bounds the findings kept for one execution
+const maxFindings = 1000
+
+// findingSeverities lists the accepted finding severities, least severe first
+var findingSeverities = []string{"info", "low", "medium", "high", "critical"}
+
+// Finding is an analyst-relevant outcome recorded by the finding operation,
+// kept apart from the raw results of the rules that led to it
+type Finding struct {
+	Severity   string      `json:"severity"`
+	Title      string      `json:"title"`
+	Detail     interface{} `json:"detail,omitempty"`
+	RecordedAt time.Time   `json:"recorded_at"`
+}
+
+// PlaybookFindings collects the findings of one playbook execution, including
+// those of its nested playbooks and parallel foreach and batch iterations
+type PlaybookFindings struct {
+	mu       sync.Mutex
+	findings []Finding
+	dropped  int
+}
+
+// NewPlaybookFindings creates an empty findings collector
+func NewPlaybookFindings() *PlaybookFindings {
+	return &PlaybookFindings{}
+}
+
+// add records a finding, reporting false once maxFindings have been recorded
+func (pf *PlaybookFindings) add(finding Finding) bool {
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	if len(pf.findings) == maxFindings {
+		pf.dropped++
+		return false
+	}
+	pf.findings = append(pf.findings, finding)
+	return true
+}
+
+// List returns the findings recorded so far, in the order they were recorded
+func (pf *PlaybookFindings) List() []Finding {
+	if pf == nil {
+		return nil
+	}
+	pf.mu.Lock()
+	defer pf.mu.Unlock()
+	if len(pf.findings) == 0 {
+		return nil
+	}
+	return append([]Finding(nil), pf.findings...)
+}
+
+// WithFindings returns an engine that records findings in findings, so the
+// caller can report them once the playbook has run
+func (re *RuleEngine) WithFindings(findings *PlaybookFindings) *RuleEngine {
+	engine := *re
+	engine.findings = findings
+	return &engine
+}
+
+// isFindingSeverity reports whether severity is one of findingSeverities
+func isFindingSeverity(severity string) bool {
+	for _, accepted := range findingSeverities {
+		if severity == accepted {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluateFindingOperation handles {"finding": {"severity": "high", "title":
+// "Malicious URL", "detail": {"var": "virustotal"}}}. It records a finding
+// on the job or playbook response, in the findings array separate from the
+// results, and returns it. severity defaults to "info"; detail may be any
+// expression.
+func (re *RuleEngine) evaluateFindingOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("finding operation requires an object with a title")
+	}
+	for key := range specMap {
+		if key != "severity" && key != "title" && key != "detail" {
+			return nil, fmt.Errorf("finding operation has unknown field %q", key)
+		}
+	}
+
+	finding := Finding{Severity: "info", RecordedAt: time.Now().UTC()}
+	if expression, exists := specMap["severity"]; exists {
+		severity, err := re.evaluate(expression, data)
+		if err != nil {
+			return nil, fmt.Errorf("finding failed to evaluate severity: %v", err)
+		}
+		text, _ := severity.(string)
+		text = strings.ToLower(text)
+		if !isFindingSeverity(text) {
+			return nil, fmt.Errorf("finding severity must be one of %s, got %v", strings.Join(findingSeverities, ", "), severity)
+		}
+		finding.Severity = text
+	}
+
+	title, err := re.evaluate(specMap["title"], data)
+	if err != nil {
+		return nil, fmt.Errorf("finding failed to evaluate title: %v", err)
+	}
+	if finding.Title, _ = title.(string); strings.TrimSpace(finding.Title) == "" {
+		return nil, fmt.Errorf("finding operation requires a title")
+	}
+
+	if expression, exists := specMap["detail"]; exists {
+		detail, err := re.evaluate(expression, data)
+		if err != nil {
+			return nil, fmt.Errorf("finding failed to evaluate detail: %v", err)
+		}
+		// Copy the detail so later rules changing the context do not change it
+		if finding.Detail, err = deepCopyJSON(detail); err != nil {
+			return nil, fmt.Errorf("finding detail is not valid JSON: %v", err)
+		}
+	}
+
+	if re.findings != nil && !re.findings.add(finding) {
+		logger.Warning("Finding dropped, execution reached the findings limit", map[string]interface{}{
+			"component": "rules_engine",
+			"title":     finding.Title,
+			"limit":     maxFindings,
+		})
+	}
+	return finding, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// groupCountUnknown is the bucket of elements without a usable grouping value
+const groupCountUnknown = "unknown"
+
+// parseGroupCountSpec reads {"in": <array expression>, "by": "field.path"}
+func parseGroupCountSpec(spec interface{}) (interface{}, string, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, "", fmt.Errorf("group_count operation requires an object with in and by")
+	}
+	for key := range specMap {
+		if key != "in" && key != "by" {
+			return nil, "", fmt.Errorf("group_count operation has unknown field %q", key)
+		}
+	}
+	items, exists := specMap["in"]
+	if !exists {
+		return nil, "", fmt.Errorf("group_count operation requires an in expression")
+	}
+	by, ok := specMap["by"].(string)
+	if !ok || strings.Trim(by, ".") == "" {
+		return nil, "", fmt.Errorf("group_count operation requires a by field name")
+	}
+	return items, by, nil
+}
+
+// groupCountKey returns the bucket of one element: the value of the by path
+// rendered like a switch case key, or "unknown" when the element is not an
+// object, lacks the field, or holds null, an empty string, an object or an
+// array there
+func (re *RuleEngine) groupCountKey(item interface{}, by string) string {
+	itemMap, ok := item.(map[string]interface{})
+	if !ok {
+		return groupCountUnknown
+	}
+	value, err := re.evaluateDotNotation(by, itemMap)
+	if err != nil || value == nil {
+		return groupCountUnknown
+	}
+	key, ok := switchCaseKey(value)
+	if !ok || key == "" {
+		return groupCountUnknown
+	}
+	return key
+}
+
+// evaluateGroupCountOperation handles {"group_count": {"in": {"var":
+// "indicators"}, "by": "verdict"}}, which counts the elements of an array by
+// the value of a field, e.g. {"malicious": 3, "benign": 5, "unknown": 1}.
+// Elements that have no such field, or are not objects, are counted as
+// "unknown". With an "as" key the counts are also stored in the context.
+func (re *RuleEngine) evaluateGroupCountOperation(spec interface{}, operation map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	itemsExpr, by, err := parseGroupCountSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	evaluated, err := re.evaluate(itemsExpr, data)
+	if err != nil {
+		return nil, fmt.Errorf("group_count failed to evaluate in: %v", err)
+	}
+	var items []interface{}
+	switch v := evaluated.(type) {
+	case nil:
+		// Nothing to count, e.g. before a lookup has returned results
+	case []interface{}:
+		items = v
+	default:
+		return nil, fmt.Errorf("group_count in must be an array, got %T", evaluated)
+	}
+	if err := re.checkCollectionSize("group_count", len(items)); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]interface{})
+	for _, item := range items {
+		key := re.groupCountKey(item, by)
+		count, _ := counts[key].(int)
+		counts[key] = count + 1
+	}
+
+	if target, exists := operation["as"]; exists {
+		targetKey, ok := target.(string)
+		if !ok || targetKey == "" {
+			return nil, fmt.Errorf("group_count operation 'as' must be a non-empty string")
+		}
+		if err := setContextPath(data, strings.Split(targetKey, "."), counts); err != nil {
+			return nil, fmt.Errorf("group_count operation failed: %v", err)
+		}
+	}
+
+	return counts, nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGroupCountBuckets(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	var context map[string]interface{}
+	if err := unmarshalContextJSON([]byte(`{"indicators": [
+		{"verdict": "malicious", "score": 90, "source": {"feed": "otx"}},
+		{"verdict": "malicious", "score": 90.0, "source": {"feed": "otx"}},
+		{"verdict": " benign ", "score": 10, "source": {"feed": "misp"}},
+		{"verdict": "", "score": null},
+		{"verdict": null, "source": {}},
+		{"verdict": ["malicious"], "score": 10},
+		{"score": true},
+		"1.2.3.4",
+		null
+	]}`), &context); err != nil {
+		t.Fatal(err)
+	}
+	playbook := parsePlaybook(t, `[
+		{"group_count": {"in": {"var": "indicators"}, "by": "verdict"}, "as": "by_verdict"},
+		{"group_count": {"in": {"var": "indicators"}, "by": "score"}, "as": "summary.by_score"},
+		{"group_count": {"in": {"var": "indicators"}, "by": "source.feed"}, "as": "by_feed"},
+		{"group_count": {"in": {"var": "missing"}, "by": "verdict"}, "as": "none"}
+	]`)
+
+	if _, err := engine.EvaluatePlaybook(playbook, context); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	tests := []struct {
+		key  string
+		want map[string]interface{}
+	}{
+		// Strings are trimmed like switch keys; empty, null, array and
+		// non-object elements are unknown
+		{"by_verdict", map[string]interface{}{"malicious": 2, "benign": 1, "unknown": 6}},
+		// 90 and 90.0 share a bucket; a bool is rendered like a switch key
+		{"summary.by_score", map[string]interface{}{"90": 2, "10": 2, "true": 1, "unknown": 4}},
+		{"by_feed", map[string]interface{}{"otx": 2, "misp": 1, "unknown": 6}},
+		// A missing array has nothing to count
+		{"none", map[string]interface{}{}},
+	}
+	for _, test := range tests {
+		got, _ := engine.evaluateDotNotation(test.key, context)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s = %v, want %v", test.key, got, test.want)
+		}
+	}
+}
+
+func TestGroupCountErrors(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	context := map[string]interface{}{"host": map[string]interface{}{"name": "web-1"}}
+
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{`{"group_count": [{"var": "host"}, "name"]}`, "requires an object with in and by"},
+		{`{"group_count": {"by": "name"}}`, "requires an in expression"},
+		{`{"group_count": {"in": {"var": "host"}}}`, "requires a by field name"},
+		{`{"group_count": {"in": {"var": "host"}, "by": "."}}`, "requires a by field name"},
+		{`{"group_count": {"in": {"var": "host"}, "by": "name", "as": "x"}}`, `unknown field "as"`},
+		{`{"group_count": {"in": {"var": "host"}, "by": "name"}}`, "in must be an array"},
+		{`{"group_count": {"in": [], "by": "name"}, "as": 7}`, "'as' must be a non-empty string"},
+	}
+
+	for _, test := range tests {
+		rule := parsePlaybook(t, "["+test.rule+"]")[0]
+		if _, err := engine.EvaluateRule(rule, context); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s error = %v, want one containing %q", test.rule, err, test.want)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"SoarAuto/secautopb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	// defaultGRPCPort is the port of the gRPC API when none is configured
+	defaultGRPCPort = 50051
+	// grpcAPIKeyMetadata is the metadata entry carrying the API key
+	grpcAPIKeyMetadata = "x-api-key"
+	// grpcShutdownTimeout bounds how long open calls may finish on shutdown
+	grpcShutdownTimeout = 10 * time.Second
+)
+
+// GRPCServer serves the gRPC API. Its handlers use the same managers as the
+// REST handlers, so both APIs share one execution path.
+type GRPCServer struct {
+	server      *grpc.Server
+	port        int
+	rateLimiter *RateLimiter
+}
+
+// NewGRPCServer creates a gRPC server for the SecAuto service
+func NewGRPCServer(s *SecAutoServer, rateLimiter *RateLimiter, config GRPCConfig) *GRPCServer {
+	port := config.Port
+	if port == 0 {
+		port = defaultGRPCPort
+	}
+
+	gs := &GRPCServer{
+		port:        port,
+		rateLimiter: rateLimiter,
+	}
+	gs.server = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(gs.unaryInterceptor),
+		grpc.ChainStreamInterceptor(gs.streamInterceptor),
+	)
+	secautopb.RegisterSecAutoServer(gs.server, &grpcService{server: s})
+	return gs
+}
+
+// Start listens on the configured port and serves in the background
+func (gs *GRPCServer) Start() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", gs.port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %v", gs.port, err)
+	}
+
+	logger.Info("gRPC server listening", map[string]interface{}{
+		"component": "grpc",
+		"port":      gs.port,
+	})
+
+	go func() {
+		if err := gs.server.Serve(listener); err != nil {
+			logger.Error("gRPC server stopped", map[string]interface{}{
+				"component": "grpc",
+				"error":     err.Error(),
+			})
+		}
+	}()
+	return nil
+}
+
+// Stop lets open calls finish, then closes any still running after the
+// shutdown timeout, such as job watches
+func (gs *GRPCServer) Stop() {
+	stopped := make(chan struct{})
+	go func() {
+		gs.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(grpcShutdownTimeout):
+		gs.server.Stop()
+	}
+}
+
+// authorize applies API key authentication and rate limiting to a call
+func (gs *GRPCServer) authorize(ctx context.Context, method string) error {
+	key := grpcRequestAPIKey(ctx)
+
+	remoteAddr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+
+	if !isAllowedAPIKey(key) {
+		logger.Error("Unauthorized gRPC access", map[string]interface{}{
+			"component":   "auth",
+			"remote_addr": remoteAddr,
+			"method":      method,
+		})
+		return status.Error(codes.Unauthenticated, "missing or invalid API key")
+	}
+
+	ip := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		ip = host
+	}
+	if gs.rateLimiter.isTrusted(ip) {
+		logger.Info("Rate limit bypassed for trusted network", map[string]interface{}{
+			"component": "rate_limit",
+			"ip":        ip,
+			"method":    method,
+		})
+		return nil
+	}
+	if allowed, _, limit, _ := gs.rateLimiter.isAllowed(ip, method); !allowed {
+		logger.Warning("Rate limit exceeded", map[string]interface{}{
+			"component": "rate_limit",
+			"ip":        ip,
+			"method":    method,
+			"limit":     limit,
+		})
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+	return nil
+}
+
+// logCall logs a finished call
+func logCall(method string, start time.Time, err error) {
+	logger.Info("gRPC call", map[string]interface{}{
+		"component":   "grpc",
+		"method":      method,
+		"code":        status.Code(err).String(),
+		"duration_ms": float64(time.Since(start).Milliseconds()),
+	})
+}
+
+// grpcRequestAPIKey returns the API key supplied with a call
+func grpcRequestAPIKey(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(grpcAPIKeyMetadata); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// unaryInterceptor authorizes and logs unary calls
+func (gs *GRPCServer) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	if err := gs.authorize(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	resp, err := handler(ctx, req)
+	logCall(info.FullMethod, start, err)
+	return resp, err
+}
+
+// streamInterceptor authorizes and logs streaming calls
+func (gs *GRPCServer) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	if err := gs.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	err := handler(srv, stream)
+	logCall(info.FullMethod, start, err)
+	return err
+}
+
+// grpcService implements the SecAuto gRPC service
+type grpcService struct {
+	secautopb.UnimplementedSecAutoServer
+	server *SecAutoServer
+}
+
+// ExecutePlaybook runs a playbook synchronously. A failed playbook is
+// reported in the result rather than as an error.
+func (gsvc *grpcService) ExecutePlaybook(ctx context.Context, in *secautopb.PlaybookRequest) (*secautopb.PlaybookResult, error) {
+	// Priority orders the job queue, which synchronous runs never enter
+	if in.Priority != "" {
+		return nil, status.Error(codes.InvalidArgument, "priority only applies to SubmitPlaybook")
+	}
+
+	req, playbook, err := gsvc.playbookRequest(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := gsvc.server.runPlaybook(req, playbook)
+	if err == errTooManyExecutions {
+		return nil, status.Error(codes.ResourceExhausted, "too many concurrent playbook executions, submit the playbook or retry later")
+	}
+
+	result := &secautopb.PlaybookResult{
+		Success:     response.Success,
+		Status:      response.Status,
+		AbortReason: response.AbortReason,
+		Error:       response.Error,
+	}
+	if result.Results, err = toProtoList(response.Results); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode results: %v", err)
+	}
+	if result.Context, err = toProtoStruct(response.Context); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode context: %v", err)
+	}
+	return result, nil
+}
+
+// SubmitPlaybook submits a playbook for asynchronous execution
+func (gsvc *grpcService) SubmitPlaybook(ctx context.Context, in *secautopb.PlaybookRequest) (*secautopb.JobSubmission, error) {
+	req, playbook, err := gsvc.playbookRequest(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	response := gsvc.server.submitPlaybook(req, playbook)
+	return &secautopb.JobSubmission{
+		JobId:             response.JobID,
+		Status:            response.Status,
+		ProbableDuplicate: response.ProbableDuplicate,
+		Fingerprint:       response.Fingerprint,
+	}, nil
+}
+
+// WatchJob sends the job whenever its version is newer than the last one
+// sent, and ends once the job has finished
+func (gsvc *grpcService) WatchJob(in *secautopb.WatchJobRequest, stream secautopb.SecAuto_WatchJobServer) error {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	sinceVersion := in.SinceVersion
+	for {
+		job, version, err := gsvc.loadJob(in.JobId)
+		if err != nil {
+			return err
+		}
+
+		if version > sinceVersion {
+			if err := stream.Send(job); err != nil {
+				return err
+			}
+			sinceVersion = version
+		}
+		if isFinishedJobStatus(job.Status) {
+			return nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
+
+// GetJob returns a job
+func (gsvc *grpcService) GetJob(ctx context.Context, in *secautopb.GetJobRequest) (*secautopb.Job, error) {
+	job, _, err := gsvc.loadJob(in.JobId)
+	return job, err
+}
+
+// ListJobs lists jobs, optionally filtered by status
+func (gsvc *grpcService) ListJobs(ctx context.Context, in *secautopb.ListJobsRequest) (*secautopb.ListJobsResponse, error) {
+	limit := int(in.Limit)
+	if limit <= 0 {
+		limit = 50
+	}
+
+	response := &secautopb.ListJobsResponse{}
+	for _, job := range gsvc.server.jobManager.ListJobs(in.Status, limit) {
+		converted, err := jobToProto(job, 0)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to encode job %s: %v", job.ID, err)
+		}
+		response.Jobs = append(response.Jobs, converted)
+	}
+	return response, nil
+}
+
+// CancelJob cancels a pending job, recording the reason and the caller
+func (gsvc *grpcService) CancelJob(ctx context.Context, in *secautopb.CancelJobRequest) (*secautopb.CancelJobResponse, error) {
+	if _, exists := gsvc.server.jobManager.GetJob(in.JobId); !exists {
+		return nil, status.Error(codes.NotFound, "job not found")
+	}
+
+	cancelled, message := gsvc.server.jobManager.CancelJob(in.JobId, in.Reason, apiKeyCaller(grpcRequestAPIKey(ctx)))
+	return &secautopb.CancelJobResponse{Cancelled: cancelled, Message: message}, nil
+}
+
+// playbookRequest validates a gRPC playbook request, attributed to the
+// caller's API key, and resolves its playbook
+func (gsvc *grpcService) playbookRequest(ctx context.Context, in *secautopb.PlaybookRequest) (*PlaybookRequest, []interface{}, error) {
+	req := &PlaybookRequest{
+		PlaybookName: in.PlaybookName,
+		Env:          in.Env,
+		Priority:     in.Priority,
+		Caller:       apiKeyCaller(grpcRequestAPIKey(ctx)),
+	}
+	if in.Playbook != nil {
+		req.Playbook = in.Playbook.AsSlice()
+	}
+	if in.Context != nil {
+		req.Context = in.Context.AsMap()
+	}
+
+	validationResult := gsvc.server.validator.ValidatePlaybookRequest(req)
+	if !validationResult.Valid {
+		messages := make([]string, len(validationResult.Errors))
+		for i, validationError := range validationResult.Errors {
+			messages[i] = fmt.Sprintf("%s: %s", validationError.Field, validationError.Message)
+		}
+		return nil, nil, status.Errorf(codes.InvalidArgument, "validation failed: %s", strings.Join(messages, "; "))
+	}
+
+	playbook, err := gsvc.server.resolvePlaybook(req)
+	if err != nil {
+		return nil, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return req, playbook, nil
+}
+
+// loadJob returns a job and its version
+func (gsvc *grpcService) loadJob(jobID string) (*secautopb.Job, int64, error) {
+	job, exists := gsvc.server.jobManager.GetJob(jobID)
+	if !exists {
+		return nil, 0, status.Error(codes.NotFound, "job not found")
+	}
+
+	version, err := gsvc.server.jobManager.GetJobVersion(jobID)
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "failed to get job version: %v", err)
+	}
+
+	converted, err := jobToProto(job, version)
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "failed to encode job: %v", err)
+	}
+	return converted, version, nil
+}
+
+// jobToProto converts a job to its gRPC message
+func jobToProto(job *Job, version int64) (*secautopb.Job, error) {
+	job = outputRedactor.RedactJob(job)
+	converted := &secautopb.Job{
+		Id:           job.ID,
+		Status:       job.Status,
+		Priority:     job.Priority,
+		Error:        job.Error,
+		AbortReason:  job.AbortReason,
+		CancelReason: job.CancelReason,
+		CancelledBy:  job.CancelledBy,
+		PlaybookName: job.PlaybookName,
+		TriggeredBy:  job.TriggeredBy,
+		CreatedAt:    timestamppb.New(job.CreatedAt),
+		Version:      version,
+	}
+	if job.StartedAt != nil {
+		converted.StartedAt = timestamppb.New(*job.StartedAt)
+	}
+	if job.CompletedAt != nil {
+		converted.CompletedAt = timestamppb.New(*job.CompletedAt)
+	}
+
+	var err error
+	if converted.Context, err = toProtoStruct(job.Context); err != nil {
+		return nil, err
+	}
+	if converted.Results, err = toProtoList(job.Results); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// toProtoStruct converts a context map to a Struct. Values are passed through
+// JSON first so they take the same form as in REST responses. Struct numbers
+// are float64, so integers beyond 2^53 are carried as strings instead of
+// being rounded.
+func toProtoStruct(value map[string]interface{}) (*structpb.Struct, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var generic interface{}
+	if err := jsonRoundTrip(value, &generic); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(generic.(map[string]interface{}))
+}
+
+// toProtoList converts a result list to a ListValue, as toProtoStruct does
+func toProtoList(value []interface{}) (*structpb.ListValue, error) {
+	if value == nil {
+		return nil, nil
+	}
+	var generic interface{}
+	if err := jsonRoundTrip(value, &generic); err != nil {
+		return nil, err
+	}
+	return structpb.NewList(generic.([]interface{}))
+}
+
+// jsonRoundTrip encodes value as JSON and decodes it into target with the
+// numbers converted by protoNumbers
+func jsonRoundTrip(value interface{}, target *interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+	*target = protoNumbers(*target)
+	return nil
+}
+
+// protoNumbers converts the json.Number values of a decoded value to the
+// float64 a Struct holds, except integers a float64 cannot hold exactly,
+// which become their decimal string
+func protoNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if integer, err := v.Int64(); err != nil || integer > 1<<53 || integer < -(1<<53) {
+				return v.String()
+			}
+		}
+		number, err := v.Float64()
+		if err != nil {
+			return v.String()
+		}
+		return number
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = protoNumbers(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = protoNumbers(item)
+		}
+		return v
+	}
+	return value
+}
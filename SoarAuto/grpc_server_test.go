@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"SoarAuto/secautopb"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestProtoStructKeepsLargeIntegers(t *testing.T) {
+	converted, err := toProtoStruct(map[string]interface{}{
+		"incident_id": json.Number("9007199254740993"),
+		"negative":    json.Number("-9007199254740993"),
+		"huge":        json.Number("123456789012345678901234567890"),
+		"count":       json.Number("42"),
+		"limit":       json.Number("9007199254740992"),
+		"ratio":       0.25,
+		"ids":         []interface{}{json.Number("18014398509481985"), 7},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := converted.AsMap()
+	want := map[string]interface{}{
+		"incident_id": "9007199254740993",
+		"negative":    "-9007199254740993",
+		"huge":        "123456789012345678901234567890",
+		"count":       float64(42),
+		"limit":       float64(9007199254740992),
+		"ratio":       0.25,
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %v (%T), want %v (%T)", key, fields[key], fields[key], value, value)
+		}
+	}
+	if ids, _ := fields["ids"].([]interface{}); len(ids) != 2 || ids[0] != "18014398509481985" || ids[1] != float64(7) {
+		t.Errorf("ids = %v", fields["ids"])
+	}
+
+	list, err := toProtoList([]interface{}{map[string]interface{}{"id": json.Number("9007199254740993")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, _ := list.AsSlice()[0].(map[string]interface{}); first["id"] != "9007199254740993" {
+		t.Errorf("results = %v", list.AsSlice())
+	}
+}
+
+func TestGRPCPlaybookRequestsRecordCaller(t *testing.T) {
+	const key = "grpc-caller-key-0123456789"
+	gsvc := &grpcService{server: &SecAutoServer{validator: NewValidator()}}
+	playbook, err := structpb.NewList([]interface{}{
+		map[string]interface{}{"if": map[string]interface{}{"conditions": []interface{}{true}, "true": []interface{}{}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpcAPIKeyMetadata, key))
+
+	req, _, err := gsvc.playbookRequest(ctx, &secautopb.PlaybookRequest{Playbook: playbook})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Caller != apiKeyCaller(key) {
+		t.Errorf("caller = %q, want %q", req.Caller, apiKeyCaller(key))
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// idempotencyKeyHeader carries the client's key for a retryable request
+	idempotencyKeyHeader = "X-Idempotency-Key"
+	// idempotencyReplayedHeader marks a response replayed from the store
+	idempotencyReplayedHeader = "X-Idempotency-Replayed"
+	// idempotencyKeyPrefix prefixes the Redis keys of stored responses
+	idempotencyKeyPrefix = "idempotency:"
+	// maxIdempotencyKeyLength bounds the length of a client's key
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotentResponse is a stored response to a request with an idempotency key
+type IdempotentResponse struct {
+	StatusCode int    `json:"status_code"`
+	ETag       string `json:"etag,omitempty"`
+	Body       []byte `json:"body"`
+}
+
+// IdempotencyStore keeps responses to requests that carried an idempotency
+// key, so a retried request gets the original response instead of being
+// applied twice. Responses are grouped by scope, such as an integration name;
+// only the latest max keys of a scope are kept, each for the TTL.
+type IdempotencyStore struct {
+	client  *redis.Client
+	ctx     context.Context
+	ttl     time.Duration
+	maxKeys int
+}
+
+// NewIdempotencyStore creates a Redis-backed idempotency store
+func NewIdempotencyStore(client *redis.Client, config IdempotencyConfig) (*IdempotencyStore, error) {
+	ttl := 24 * time.Hour
+	if config.TTL != "" {
+		parsed, err := time.ParseDuration(config.TTL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid idempotency ttl: %v", err)
+		}
+		if parsed <= 0 {
+			return nil, fmt.Errorf("idempotency ttl must be positive")
+		}
+		ttl = parsed
+	}
+
+	maxKeys := config.MaxKeys
+	if maxKeys < 0 {
+		return nil, fmt.Errorf("idempotency max_keys cannot be negative")
+	}
+	if maxKeys == 0 {
+		maxKeys = 100
+	}
+
+	logger.Info("Idempotency keys enabled", map[string]interface{}{
+		"component": "idempotency",
+		"ttl":       ttl.String(),
+		"max_keys":  maxKeys,
+	})
+
+	return &IdempotencyStore{
+		client:  client,
+		ctx:     context.Background(),
+		ttl:     ttl,
+		maxKeys: maxKeys,
+	}, nil
+}
+
+// idempotencyFingerprint identifies a request by its idempotency key and,
+// when body is not nil, the hash of its body. Including the body makes the
+// same key with a different body a distinct operation.
+func idempotencyFingerprint(key string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(key))
+	if body != nil {
+		bodyHash := sha256.Sum256(body)
+		hash.Write([]byte{0})
+		hash.Write(bodyHash[:])
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// scopeKey is the sorted set of a scope's fingerprints, scored by record time
+func (is *IdempotencyStore) scopeKey(scope string) string {
+	return idempotencyKeyPrefix + scope
+}
+
+// responseKey is the stored response of a fingerprint within a scope
+func (is *IdempotencyStore) responseKey(scope, fingerprint string) string {
+	return idempotencyKeyPrefix + scope + ":" + fingerprint
+}
+
+// Lookup returns the stored response for a fingerprint, if one is still kept
+func (is *IdempotencyStore) Lookup(scope, fingerprint string) (*IdempotentResponse, bool, error) {
+	data, err := is.client.Get(is.ctx, is.responseKey(scope, fingerprint)).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to look up idempotency key: %v", err)
+	}
+
+	var response IdempotentResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, false, fmt.Errorf("failed to decode stored response: %v", err)
+	}
+	return &response, true, nil
+}
+
+// Record stores the response for a fingerprint and forgets the scope's oldest
+// fingerprints beyond the configured maximum
+func (is *IdempotencyStore) Record(scope, fingerprint string, response *IdempotentResponse) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %v", err)
+	}
+
+	scopeKey := is.scopeKey(scope)
+	pipe := is.client.TxPipeline()
+	pipe.Set(is.ctx, is.responseKey(scope, fingerprint), data, is.ttl)
+	pipe.ZAdd(is.ctx, scopeKey, redis.Z{Score: float64(time.Now().UnixNano()), Member: fingerprint})
+	evicted := pipe.ZRange(is.ctx, scopeKey, 0, int64(-is.maxKeys-1))
+	pipe.ZRemRangeByRank(is.ctx, scopeKey, 0, int64(-is.maxKeys-1))
+	pipe.Expire(is.ctx, scopeKey, is.ttl)
+	if _, err := pipe.Exec(is.ctx); err != nil {
+		return fmt.Errorf("failed to record idempotency key: %v", err)
+	}
+
+	if fingerprints := evicted.Val(); len(fingerprints) > 0 {
+		keys := make([]string, len(fingerprints))
+		for i, evictedFingerprint := range fingerprints {
+			keys[i] = is.responseKey(scope, evictedFingerprint)
+		}
+		if err := is.client.Del(is.ctx, keys...).Err(); err != nil {
+			return fmt.Errorf("failed to evict idempotency keys: %v", err)
+		}
+	}
+	return nil
+}
+
+// integrationRequestFingerprint returns the fingerprint of an integration
+// mutation carrying an idempotency key, or "" when it carries none. Updates
+// include the body, so different updates under one key are not conflated.
+func integrationRequestFingerprint(r *http.Request, body []byte) (string, error) {
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		return "", nil
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return "", fmt.Errorf("%s must be at most %d characters", idempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+
+	if r.Method == http.MethodPut {
+		return idempotencyFingerprint(key, body), nil
+	}
+	return idempotencyFingerprint(key, nil), nil
+}
+
+// replayIntegrationResponse writes the stored response to an earlier mutation
+// with the same fingerprint, reporting whether there was one
+func (s *SecAutoServer) replayIntegrationResponse(w http.ResponseWriter, integrationName, fingerprint string) bool {
+	stored, found := s.integrationConfigManager.LookupIdempotentResponse(integrationName, fingerprint)
+	if !found {
+		return false
+	}
+
+	if stored.ETag != "" {
+		w.Header().Set("ETag", stored.ETag)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(idempotencyReplayedHeader, "true")
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.Body)
+	return true
+}
+
+// writeIntegrationResponse writes the response to an integration mutation and
+// stores it for replay when the request carried an idempotency key
+func (s *SecAutoServer) writeIntegrationResponse(w http.ResponseWriter, statusCode int, etag string, response IntegrationResponse, integrationName, fingerprint string) {
+	var body bytes.Buffer
+	json.NewEncoder(&body).Encode(response)
+
+	if statusCode >= 200 && statusCode < 300 {
+		s.integrationConfigManager.RecordIdempotentResponse(integrationName, fingerprint, &IdempotentResponse{
+			StatusCode: statusCode,
+			ETag:       etag,
+			Body:       body.Bytes(),
+		})
+	}
+
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body.Bytes())
+}
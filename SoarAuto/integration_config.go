@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -8,8 +9,11 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 )
@@ -28,16 +32,41 @@ type IntegrationConfig struct {
 	Description string                 `json:"description,omitempty"`
 	Version     string                 `json:"version,omitempty"`
 	Settings    map[string]interface{} `json:"settings,omitempty"`
+	Revision    int                    `json:"revision"`
 	CreatedAt   time.Time              `json:"created_at"`
 	UpdatedAt   time.Time              `json:"updated_at"`
+
+	// Credential rotation policy
+	ExpiresAt          *time.Time `json:"expires_at,omitempty"`
+	RotationWebhookURL string     `json:"rotation_webhook_url,omitempty"`
+
+	// Derived expiry status, populated only in API responses
+	DaysUntilExpiry *int `json:"days_until_expiry,omitempty"`
+	IsExpired       bool `json:"is_expired"`
 }
 
+const (
+	// expiryCheckInterval is how often integrations are checked for credential expiry
+	expiryCheckInterval = 24 * time.Hour
+	// expiryReminderWindow is how long before expiry rotation reminders are sent
+	expiryReminderWindow = 7 * 24 * time.Hour
+)
+
 // IntegrationConfigManager handles integration configurations with encryption
 type IntegrationConfigManager struct {
 	configPath    string
 	encryptionKey []byte
 	mutex         sync.RWMutex
 	configs       map[string]*IntegrationConfig
+	httpClient    *http.Client
+	stopChan      chan struct{}
+	stopOnce      sync.Once
+
+	// idempotency replays responses to retried mutations; nil disables it
+	idempotency *IdempotencyStore
+
+	// secrets resolves env: and vault: references in config values
+	secrets *SecretResolver
 }
 
 // NewIntegrationConfigManager creates a new integration config manager
@@ -50,6 +79,8 @@ func NewIntegrationConfigManager(configPath string, encryptionKey string) (*Inte
 		configPath:    configPath,
 		encryptionKey: key,
 		configs:       make(map[string]*IntegrationConfig),
+		httpClient:    &http.Client{Timeout: 10 * time.Second},
+		stopChan:      make(chan struct{}),
 	}
 
 	// Load existing configurations
@@ -98,6 +129,13 @@ func (icm *IntegrationConfigManager) loadConfigs() error {
 		return fmt.Errorf("failed to parse config JSON: %v", err)
 	}
 
+	// Configurations saved before revisions were tracked start at revision 1
+	for _, config := range configs {
+		if config.Revision == 0 {
+			config.Revision = 1
+		}
+	}
+
 	icm.configs = configs
 	return nil
 }
@@ -141,11 +179,27 @@ func (icm *IntegrationConfigManager) GetConfig(integrationName string) (*Integra
 	return config, exists
 }
 
-// SetConfig sets or updates an integration configuration
+// SetConfig sets or updates an integration configuration. When config.Revision is
+// non-zero it must match the stored revision, otherwise errRevisionConflict is returned.
 func (icm *IntegrationConfigManager) SetConfig(integrationName string, config *IntegrationConfig) error {
 	icm.mutex.Lock()
 	defer icm.mutex.Unlock()
 
+	// Optimistic concurrency check
+	existing, exists := icm.configs[integrationName]
+	if config.Revision != 0 && (!exists || existing.Revision != config.Revision) {
+		return errRevisionConflict
+	}
+	if exists {
+		config.Revision = existing.Revision + 1
+	} else {
+		config.Revision = 1
+	}
+
+	// Expiry status is derived, never stored
+	config.DaysUntilExpiry = nil
+	config.IsExpired = false
+
 	// Set timestamps
 	now := time.Now()
 	if config.CreatedAt.IsZero() {
@@ -156,7 +210,18 @@ func (icm *IntegrationConfigManager) SetConfig(integrationName string, config *I
 	icm.configs[integrationName] = config
 
 	// Save to disk
-	return icm.saveConfigsToFile(icm.configs)
+	if err := icm.saveConfigsToFile(icm.configs); err != nil {
+		return err
+	}
+
+	eventBus.Publish(ActivityEvent{
+		Type:        "integration_updated",
+		Integration: integrationName,
+		Details: map[string]interface{}{
+			"revision": config.Revision,
+		},
+	})
+	return nil
 }
 
 // DeleteConfig removes an integration configuration
@@ -167,7 +232,80 @@ func (icm *IntegrationConfigManager) DeleteConfig(integrationName string) error
 	delete(icm.configs, integrationName)
 
 	// Save to disk
-	return icm.saveConfigsToFile(icm.configs)
+	if err := icm.saveConfigsToFile(icm.configs); err != nil {
+		return err
+	}
+
+	eventBus.Publish(ActivityEvent{
+		Type:        "integration_deleted",
+		Integration: integrationName,
+	})
+	return nil
+}
+
+// SetSecretResolver sets how env: and vault: references are resolved
+func (icm *IntegrationConfigManager) SetSecretResolver(secrets *SecretResolver) {
+	icm.secrets = secrets
+}
+
+// GetResolvedConfig retrieves an integration configuration with its env: and
+// vault: references replaced by the secrets they name. References are
+// resolved on every call, so a rotated secret is picked up without saving
+// the integration again.
+func (icm *IntegrationConfigManager) GetResolvedConfig(integrationName string) (*IntegrationConfig, bool, error) {
+	config, exists := icm.GetConfig(integrationName)
+	if !exists {
+		return nil, false, nil
+	}
+
+	named := *config
+	named.Name = integrationName
+	resolved, err := icm.secrets.ResolveConfig(&named)
+	if err != nil {
+		return nil, true, err
+	}
+	return resolved, true, nil
+}
+
+// SetIdempotencyStore enables idempotency keys for integration mutations
+func (icm *IntegrationConfigManager) SetIdempotencyStore(store *IdempotencyStore) {
+	icm.idempotency = store
+}
+
+// LookupIdempotentResponse returns the stored response to an earlier mutation
+// of an integration with the same fingerprint. Store errors are logged and
+// treated as a miss, so an unavailable store never blocks a mutation.
+func (icm *IntegrationConfigManager) LookupIdempotentResponse(integrationName, fingerprint string) (*IdempotentResponse, bool) {
+	if icm.idempotency == nil || fingerprint == "" {
+		return nil, false
+	}
+
+	response, found, err := icm.idempotency.Lookup("integrations:"+integrationName, fingerprint)
+	if err != nil {
+		logger.Warning("Failed to look up integration idempotency key", map[string]interface{}{
+			"component":   "integration_config",
+			"integration": integrationName,
+			"error":       err.Error(),
+		})
+		return nil, false
+	}
+	return response, found
+}
+
+// RecordIdempotentResponse stores the response to a mutation of an
+// integration so retries with the same fingerprint are replayed
+func (icm *IntegrationConfigManager) RecordIdempotentResponse(integrationName, fingerprint string, response *IdempotentResponse) {
+	if icm.idempotency == nil || fingerprint == "" {
+		return
+	}
+
+	if err := icm.idempotency.Record("integrations:"+integrationName, fingerprint, response); err != nil {
+		logger.Warning("Failed to record integration idempotency key", map[string]interface{}{
+			"component":   "integration_config",
+			"integration": integrationName,
+			"error":       err.Error(),
+		})
+	}
 }
 
 // ListConfigs returns all integration configurations
@@ -184,9 +322,19 @@ func (icm *IntegrationConfigManager) ListConfigs() map[string]*IntegrationConfig
 	return configs
 }
 
-// GetConfigValue retrieves a specific value from an integration configuration
+// GetConfigValue retrieves a specific value from an integration
+// configuration, with secret references resolved. A reference that cannot be
+// resolved is logged and reported as missing.
 func (icm *IntegrationConfigManager) GetConfigValue(integrationName, key string) (string, bool) {
-	config, exists := icm.GetConfig(integrationName)
+	config, exists, err := icm.GetResolvedConfig(integrationName)
+	if err != nil {
+		logger.Error("Failed to resolve integration secret", map[string]interface{}{
+			"component":   "integration_config",
+			"integration": integrationName,
+			"error":       err.Error(),
+		})
+		return "", false
+	}
 	if !exists {
 		return "", false
 	}
@@ -293,6 +441,30 @@ func (icm *IntegrationConfigManager) CreateDefaultConfigs() error {
 				"username": "SecAuto Bot",
 			},
 		},
+		"elasticsearch": {
+			Name:        "elasticsearch",
+			Type:        elasticsearchIntegrationType,
+			URL:         "https://localhost:9200",
+			Username:    "",
+			Password:    "",
+			Enabled:     false,
+			Description: "Elasticsearch document indexing for playbook output",
+			Version:     "1.0.0",
+			Settings: map[string]interface{}{
+				"tls_ca": "",
+			},
+		},
+		"splunk": {
+			Name:        "splunk",
+			Type:        splunkIntegrationType,
+			Token:       "",
+			Enabled:     false,
+			Description: "Splunk HTTP Event Collector output for playbook events",
+			Version:     "1.0.0",
+			Settings: map[string]interface{}{
+				"hec_url": "https://localhost:8088",
+			},
+		},
 		"email": {
 			Name:        "email",
 			Type:        "email",
@@ -324,8 +496,338 @@ func (icm *IntegrationConfigManager) ValidateConfig(config *IntegrationConfig) e
 		return fmt.Errorf("integration type is required")
 	}
 
-	// Basic validation - allow empty credentials for initial setup
-	// Users can configure credentials later through the API
+	// An integration may be saved without its credentials for initial
+	// setup, but it cannot be enabled until its type's required fields are set
+	if config.Enabled {
+		if missing := missingIntegrationFields(config); len(missing) > 0 {
+			return fmt.Errorf("%s requires %s", config.Type, joinFieldNames(missing))
+		}
+	}
+
+	for _, value := range []string{config.URL, config.APIKey, config.Username, config.Password, config.Token, config.Secret} {
+		if err := validateSecretReference(value); err != nil {
+			return err
+		}
+	}
+	for _, value := range config.Settings {
+		if text, ok := value.(string); ok {
+			if err := validateSecretReference(text); err != nil {
+				return err
+			}
+		}
+	}
+
+	if config.Type == elasticsearchIntegrationType && config.URL != "" {
+		parsed, err := url.Parse(config.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("elasticsearch url must be an http(s) URL")
+		}
+	}
+
+	if config.Type == splunkIntegrationType {
+		hecURL, _ := config.Settings["hec_url"].(string)
+		if hecURL != "" || config.URL != "" {
+			if _, err := splunkHECBaseURL(config); err != nil {
+				return fmt.Errorf("splunk hec_url must be an http(s) URL")
+			}
+		}
+	}
+
+	return nil
+}
+
+// Redacted returns a copy of the configuration with secret fields masked and
+// the expiry status filled in, suitable for API responses
+func (config *IntegrationConfig) Redacted() *IntegrationConfig {
+	redacted := *config
+	redacted.APIKey = maskCredential(config.APIKey)
+	redacted.Password = maskCredential(config.Password)
+	redacted.Token = maskCredential(config.Token)
+	redacted.Secret = maskCredential(config.Secret)
+	redacted.Settings = redactSettings(config.Settings)
+	redacted.applyExpiryStatus(time.Now())
+	return &redacted
+}
 
+// sensitiveSettingWords mark a settings key as holding a secret when its
+// lowercased name contains one of them, e.g. "smtp_password" or "bot_token"
+var sensitiveSettingWords = []string{
+	"password", "passwd", "secret", "token", "apikey", "api_key", "private_key",
+	"credential", "auth",
+}
+
+// isSensitiveSetting reports whether a settings key looks like it holds a secret
+func isSensitiveSetting(key string) bool {
+	key = strings.ToLower(key)
+	for _, word := range sensitiveSettingWords {
+		if strings.Contains(key, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSettings returns a copy of settings with the values of
+// sensitive-looking keys masked, including those of nested objects. The
+// original map is not changed.
+func redactSettings(settings map[string]interface{}) map[string]interface{} {
+	if settings == nil {
+		return nil
+	}
+	redacted := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		sensitive := isSensitiveSetting(key)
+		switch typed := value.(type) {
+		case nil:
+			redacted[key] = nil
+		case string:
+			if sensitive {
+				typed = maskCredential(typed)
+			}
+			redacted[key] = typed
+		case map[string]interface{}:
+			if sensitive {
+				redacted[key] = "****"
+			} else {
+				redacted[key] = redactSettings(typed)
+			}
+		default:
+			if sensitive {
+				redacted[key] = "****"
+			} else {
+				redacted[key] = value
+			}
+		}
+	}
+	return redacted
+}
+
+// applyExpiryStatus sets DaysUntilExpiry and IsExpired relative to now
+func (config *IntegrationConfig) applyExpiryStatus(now time.Time) {
+	config.DaysUntilExpiry = nil
+	config.IsExpired = false
+	if config.ExpiresAt == nil {
+		return
+	}
+
+	days := config.daysUntilExpiry(now)
+	config.DaysUntilExpiry = &days
+	config.IsExpired = !now.Before(*config.ExpiresAt)
+}
+
+// daysUntilExpiry returns the whole days remaining before expiry, rounded up;
+// it is zero or negative once the key has expired
+func (config *IntegrationConfig) daysUntilExpiry(now time.Time) int {
+	remaining := config.ExpiresAt.Sub(now)
+	if remaining <= 0 {
+		return -int(-remaining / (24 * time.Hour))
+	}
+	return int((remaining + 24*time.Hour - 1) / (24 * time.Hour))
+}
+
+// maskCredential masks a stored credential. An env: or vault: reference only
+// names where the secret is kept, so it is shown as is.
+func maskCredential(value string) string {
+	if isSecretReference(value) {
+		return value
+	}
+	return maskSecret(value)
+}
+
+// maskSecret hides a secret value, keeping only the last 4 characters of longer values
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "****"
+	}
+	return "****" + value[len(value)-4:]
+}
+
+// isMaskedSecret reports whether a value is a mask written by maskSecret
+// rather than a credential
+func isMaskedSecret(value string) bool {
+	return strings.HasPrefix(value, "****")
+}
+
+// RestoreMaskedSecrets puts the stored value back in place of every secret
+// field or sensitive setting that still holds the mask a GET returned, so a
+// configuration read, edited and written back keeps its credentials
+func (config *IntegrationConfig) RestoreMaskedSecrets(stored *IntegrationConfig) {
+	restore := func(value *string, storedValue string) {
+		if isMaskedSecret(*value) {
+			*value = storedValue
+		}
+	}
+	restore(&config.APIKey, stored.APIKey)
+	restore(&config.Password, stored.Password)
+	restore(&config.Token, stored.Token)
+	restore(&config.Secret, stored.Secret)
+	restoreMaskedSettings(config.Settings, stored.Settings)
+}
+
+// restoreMaskedSettings is RestoreMaskedSecrets for settings, including
+// those of nested objects
+func restoreMaskedSettings(settings, stored map[string]interface{}) {
+	for key, value := range settings {
+		storedValue, exists := stored[key]
+		if !exists {
+			continue
+		}
+		switch typed := value.(type) {
+		case string:
+			if isSensitiveSetting(key) && isMaskedSecret(typed) {
+				settings[key] = storedValue
+			}
+		case map[string]interface{}:
+			if storedMap, ok := storedValue.(map[string]interface{}); ok {
+				restoreMaskedSettings(typed, storedMap)
+			}
+		}
+	}
+}
+
+// StartExpiryMonitor starts a background goroutine that checks integration
+// credentials for expiry once a day
+func (icm *IntegrationConfigManager) StartExpiryMonitor() {
+	go func() {
+		ticker := time.NewTicker(expiryCheckInterval)
+		defer ticker.Stop()
+
+		icm.CheckExpiry()
+		for {
+			select {
+			case <-ticker.C:
+				icm.CheckExpiry()
+			case <-icm.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the expiry monitor
+func (icm *IntegrationConfigManager) Stop() {
+	icm.stopOnce.Do(func() {
+		close(icm.stopChan)
+	})
+}
+
+// integrationExpiryEvent is the payload posted to an integration's rotation webhook
+type integrationExpiryEvent struct {
+	Event           string `json:"event"`
+	Integration     string `json:"integration"`
+	Type            string `json:"type"`
+	ExpiresAt       string `json:"expires_at"`
+	DaysUntilExpiry int    `json:"days_until_expiry"`
+	Disabled        bool   `json:"disabled"`
+	Timestamp       string `json:"timestamp"`
+}
+
+// CheckExpiry sends rotation reminders for integrations expiring within the
+// reminder window and disables integrations whose credentials have expired
+func (icm *IntegrationConfigManager) CheckExpiry() {
+	now := time.Now()
+	var events []integrationExpiryEvent
+	var webhookURLs []string
+
+	icm.mutex.Lock()
+	changed := false
+	for name, config := range icm.configs {
+		if config.ExpiresAt == nil {
+			continue
+		}
+
+		event := integrationExpiryEvent{
+			Integration:     name,
+			Type:            config.Type,
+			ExpiresAt:       config.ExpiresAt.UTC().Format(time.RFC3339),
+			DaysUntilExpiry: config.daysUntilExpiry(now),
+			Timestamp:       now.UTC().Format(time.RFC3339),
+		}
+
+		switch {
+		case !now.Before(*config.ExpiresAt):
+			if !config.Enabled {
+				// Already disabled, the expiry notification has been sent
+				continue
+			}
+			config.Enabled = false
+			config.Revision++
+			config.UpdatedAt = now
+			changed = true
+
+			event.Event = "integration.expired"
+			event.Disabled = true
+			logger.Warning("Integration credentials expired, integration disabled", map[string]interface{}{
+				"component":   "integration_config",
+				"integration": name,
+			})
+		case config.ExpiresAt.Sub(now) <= expiryReminderWindow:
+			if !config.Enabled {
+				continue
+			}
+			event.Event = "integration.rotation_reminder"
+			logger.Info("Integration credentials expiring soon", map[string]interface{}{
+				"component":   "integration_config",
+				"integration": name,
+			})
+		default:
+			continue
+		}
+
+		if config.RotationWebhookURL != "" {
+			events = append(events, event)
+			webhookURLs = append(webhookURLs, config.RotationWebhookURL)
+		}
+	}
+
+	if changed {
+		if err := icm.saveConfigsToFile(icm.configs); err != nil {
+			logger.Error("Failed to save integration configs after expiry check", map[string]interface{}{
+				"component": "integration_config",
+				"error":     err.Error(),
+			})
+		}
+	}
+	icm.mutex.Unlock()
+
+	// Deliver notifications outside the lock
+	for i, event := range events {
+		if err := icm.postExpiryEvent(webhookURLs[i], event); err != nil {
+			logger.Error("Failed to send integration expiry notification", map[string]interface{}{
+				"component":   "integration_config",
+				"integration": event.Integration,
+				"webhook_url": webhookURLs[i],
+				"error":       err.Error(),
+			})
+		}
+	}
+}
+
+// postExpiryEvent posts an expiry event to a rotation webhook
+func (icm *IntegrationConfigManager) postExpiryEvent(url string, event integrationExpiryEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal expiry event: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SecAuto-Webhook/1.0")
+
+	resp, err := icm.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
 	return nil
 }
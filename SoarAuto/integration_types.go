@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// IntegrationField describes one field of an integration type: a top-level
+// configuration field such as url or apikey, or a setting as settings.<key>
+type IntegrationField struct {
+	Name        string `json:"name"`
+	Required    bool   `json:"required"`
+	Secret      bool   `json:"secret,omitempty"`   // Masked in responses; may be a secret reference
+	Fallback    string `json:"fallback,omitempty"` // Field used instead when this one is empty
+	Description string `json:"description"`
+}
+
+// IntegrationType describes the fields a type of integration is configured
+// with, so configurations can be checked and forms rendered per type
+type IntegrationType struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description"`
+	Fields      []IntegrationField `json:"fields"`
+}
+
+// IntegrationTypesResponse is the response for GET /integrations/types
+type IntegrationTypesResponse struct {
+	Success   bool              `json:"success"`
+	Types     []IntegrationType `json:"types"`
+	Timestamp string            `json:"timestamp"`
+}
+
+// integrationTypes is the registry of known integration types. Types not
+// listed here may still be configured; they are only validated generically.
+var integrationTypes = []IntegrationType{
+	{
+		Type:        "virustotal",
+		Description: "VirusTotal URL and file scanning",
+		Fields: []IntegrationField{
+			{Name: "apikey", Required: true, Secret: true, Description: "VirusTotal API key"},
+			{Name: "url", Description: "API base URL"},
+			{Name: "settings.timeout", Description: "Request timeout in seconds"},
+			{Name: "settings.retries", Description: "Retries of a failed request"},
+		},
+	},
+	{
+		Type:        "slack",
+		Description: "Slack webhook notifications",
+		Fields: []IntegrationField{
+			{Name: "url", Required: true, Description: "Incoming webhook URL"},
+			{Name: "token", Secret: true, Description: "Bot token, for posting through the API instead"},
+			{Name: "settings.channel", Description: "Channel to post to, e.g. #security"},
+			{Name: "settings.username", Description: "Name messages are posted as"},
+		},
+	},
+	{
+		Type:        "email",
+		Description: "Email notifications",
+		Fields: []IntegrationField{
+			{Name: "url", Required: true, Description: "SMTP server as host:port"},
+			{Name: "settings.from", Required: true, Description: "Sender address"},
+			{Name: "settings.to", Required: true, Description: "Recipient address"},
+			{Name: "username", Description: "SMTP user name"},
+			{Name: "password", Secret: true, Description: "SMTP password"},
+		},
+	},
+	{
+		Type:        elasticsearchIntegrationType,
+		Description: "Elasticsearch document indexing for playbook output",
+		Fields: []IntegrationField{
+			{Name: "url", Required: true, Description: "Cluster URL, e.g. https://localhost:9200"},
+			{Name: "username", Description: "User for basic authentication"},
+			{Name: "password", Secret: true, Description: "Password for basic authentication"},
+			{Name: "apikey", Secret: true, Description: "API key, used instead of basic authentication"},
+			{Name: "settings.tls_ca", Description: "PEM CA certificate, or a path to one, to verify the cluster"},
+		},
+	},
+	{
+		Type:        splunkIntegrationType,
+		Description: "Splunk HTTP Event Collector output for playbook events",
+		Fields: []IntegrationField{
+			{Name: "token", Required: true, Secret: true, Description: "HEC token"},
+			{Name: "settings.hec_url", Required: true, Fallback: "url", Description: "HEC base URL, e.g. https://localhost:8088"},
+			{Name: "url", Description: "HEC base URL when settings.hec_url is not set"},
+		},
+	},
+}
+
+// lookupIntegrationType returns the registered description of an integration type
+func lookupIntegrationType(integrationType string) (IntegrationType, bool) {
+	for _, registered := range integrationTypes {
+		if registered.Type == integrationType {
+			return registered, true
+		}
+	}
+	return IntegrationType{}, false
+}
+
+// integrationFieldValue returns the value of a field named as in
+// IntegrationField, or nil when the configuration does not set it
+func integrationFieldValue(config *IntegrationConfig, name string) interface{} {
+	if key, isSetting := strings.CutPrefix(name, "settings."); isSetting {
+		return config.Settings[key]
+	}
+	values := map[string]string{
+		"url":      config.URL,
+		"apikey":   config.APIKey,
+		"username": config.Username,
+		"password": config.Password,
+		"token":    config.Token,
+		"secret":   config.Secret,
+	}
+	if value := values[name]; value != "" {
+		return value
+	}
+	return nil
+}
+
+// integrationFieldSet reports whether a configuration gives a field a value
+func integrationFieldSet(config *IntegrationConfig, name string) bool {
+	switch value := integrationFieldValue(config, name).(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(value) != ""
+	}
+	return true
+}
+
+// missingIntegrationFields returns the required fields of the configuration's
+// type that it leaves empty, e.g. ["url"] for a slack integration without a
+// webhook URL. Unregistered types have no required fields.
+func missingIntegrationFields(config *IntegrationConfig) []string {
+	registered, exists := lookupIntegrationType(config.Type)
+	if !exists {
+		return nil
+	}
+	var missing []string
+	for _, field := range registered.Fields {
+		if !field.Required || integrationFieldSet(config, field.Name) {
+			continue
+		}
+		if field.Fallback != "" {
+			if integrationFieldSet(config, field.Fallback) {
+				continue
+			}
+			missing = append(missing, fmt.Sprintf("%s (or %s)", field.Name, field.Fallback))
+			continue
+		}
+		missing = append(missing, field.Name)
+	}
+	return missing
+}
+
+// joinFieldNames lists field names as "a", "a and b" or "a, b and c"
+func joinFieldNames(names []string) string {
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
+
+// integrationTypesHandler handles GET /integrations/types, listing the
+// registered integration types and their fields
+func (s *SecAutoServer) integrationTypesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := IntegrationTypesResponse{
+		Success:   true,
+		Types:     integrationTypes,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
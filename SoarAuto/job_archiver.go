@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// archiveScanLimit bounds the number of jobs inspected per archival pass
+const archiveScanLimit = 10000
+
+// ArchivedJob is a job record as written to the cold store
+type ArchivedJob struct {
+	*Job
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// ColdStore persists archived jobs outside of Redis
+type ColdStore interface {
+	WriteJobs(jobs []*Job, archivedAt time.Time) error
+	ReadJobs(from, to time.Time) ([]*ArchivedJob, error)
+}
+
+// NewColdStore creates the cold storage backend described by the archive config
+func NewColdStore(config ArchiveConfig) (ColdStore, error) {
+	switch config.Backend {
+	case "", "filesystem":
+		directory := config.Directory
+		if directory == "" {
+			directory = filepath.Join("data", "archive")
+		}
+		return NewFileColdStore(directory)
+	case "s3", "gcs":
+		objectStore, err := NewObjectStore(config.Backend, config.ObjectStorage)
+		if err != nil {
+			return nil, err
+		}
+		return NewObjectColdStore(objectStore), nil
+	default:
+		return nil, fmt.Errorf("unknown archive backend: %s", config.Backend)
+	}
+}
+
+// FileColdStore stores archived jobs as daily NDJSON files on the local filesystem
+type FileColdStore struct {
+	directory string
+	mutex     sync.Mutex
+}
+
+// NewFileColdStore creates a filesystem cold store rooted at directory
+func NewFileColdStore(directory string) (*FileColdStore, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory: %v", err)
+	}
+	return &FileColdStore{directory: directory}, nil
+}
+
+// archiveDate returns the date an archived job is filed under
+func archiveDate(job *Job) time.Time {
+	if job.CompletedAt != nil {
+		return job.CompletedAt.UTC()
+	}
+	return job.CreatedAt.UTC()
+}
+
+// filePath returns the NDJSON file holding jobs for the given day
+func (fcs *FileColdStore) filePath(day time.Time) string {
+	return filepath.Join(fcs.directory, fmt.Sprintf("jobs-%s.ndjson", day.Format(archiveDayLayout)))
+}
+
+// WriteJobs appends jobs to the daily archive files
+func (fcs *FileColdStore) WriteJobs(jobs []*Job, archivedAt time.Time) error {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+
+	// Group jobs by archive file
+	byFile := make(map[string][]*Job)
+	for _, job := range jobs {
+		path := fcs.filePath(archiveDate(job))
+		byFile[path] = append(byFile[path], job)
+	}
+
+	for path, fileJobs := range byFile {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open archive file: %v", err)
+		}
+
+		encoder := json.NewEncoder(file)
+		for _, job := range fileJobs {
+			if err := encoder.Encode(ArchivedJob{Job: job, ArchivedAt: archivedAt}); err != nil {
+				file.Close()
+				return fmt.Errorf("failed to write archived job %s: %v", job.ID, err)
+			}
+		}
+
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("failed to close archive file: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// archiveDayLayout is the date format of archive file and object names
+const archiveDayLayout = "2006-01-02"
+
+// parseArchiveDay returns the day in an archive name such as
+// jobs-2024-01-31.ndjson or jobs-2024-01-31/..., or false for other names
+func parseArchiveDay(name string) (time.Time, bool) {
+	rest, found := strings.CutPrefix(name, "jobs-")
+	if !found || len(rest) < len(archiveDayLayout) {
+		return time.Time{}, false
+	}
+	day, err := time.Parse(archiveDayLayout, rest[:len(archiveDayLayout)])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// archiveDayInRange reports whether a day may hold jobs completed between
+// from and to
+func archiveDayInRange(day, from, to time.Time) bool {
+	startDay := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	return !day.Before(startDay) && !day.After(to)
+}
+
+// decodeArchivedJobs reads NDJSON archived jobs, keeping those completed
+// between from and to. Malformed lines are skipped.
+func decodeArchivedJobs(reader io.Reader, from, to time.Time) ([]*ArchivedJob, error) {
+	var jobs []*ArchivedJob
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var archived ArchivedJob
+		if err := json.Unmarshal(scanner.Bytes(), &archived); err != nil || archived.Job == nil {
+			continue
+		}
+
+		date := archiveDate(archived.Job)
+		if date.Before(from) || date.After(to) {
+			continue
+		}
+		jobs = append(jobs, &archived)
+	}
+	return jobs, scanner.Err()
+}
+
+// sortArchivedJobs orders archived jobs by completion time
+func sortArchivedJobs(jobs []*ArchivedJob) {
+	sort.Slice(jobs, func(i, j int) bool {
+		return archiveDate(jobs[i].Job).Before(archiveDate(jobs[j].Job))
+	})
+}
+
+// ReadJobs returns archived jobs completed between from and to (inclusive).
+// Only the daily files that exist are read, however wide the range.
+func (fcs *FileColdStore) ReadJobs(from, to time.Time) ([]*ArchivedJob, error) {
+	fcs.mutex.Lock()
+	defer fcs.mutex.Unlock()
+
+	entries, err := os.ReadDir(fcs.directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list archive directory: %v", err)
+	}
+
+	var jobs []*ArchivedJob
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".ndjson") {
+			continue
+		}
+		day, ok := parseArchiveDay(entry.Name())
+		if !ok || !archiveDayInRange(day, from, to) {
+			continue
+		}
+
+		file, err := os.Open(filepath.Join(fcs.directory, entry.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to open archive file: %v", err)
+		}
+		fileJobs, err := decodeArchivedJobs(file, from, to)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive file: %v", err)
+		}
+		jobs = append(jobs, fileJobs...)
+	}
+
+	sortArchivedJobs(jobs)
+	return jobs, nil
+}
+
+// ObjectColdStore stores archived jobs in an S3 or GCS bucket. Objects are
+// immutable, so each archival pass writes a new NDJSON object per day, keyed
+// jobs-YYYY-MM-DD/<time>-<id>.ndjson.
+type ObjectColdStore struct {
+	objects *ObjectStore
+}
+
+// NewObjectColdStore creates a cold store on an object store
+func NewObjectColdStore(objects *ObjectStore) *ObjectColdStore {
+	return &ObjectColdStore{objects: objects}
+}
+
+// WriteJobs writes jobs to new objects, one per archive day
+func (ocs *ObjectColdStore) WriteJobs(jobs []*Job, archivedAt time.Time) error {
+	byDay := make(map[string]*bytes.Buffer)
+	for _, job := range jobs {
+		day := archiveDate(job).Format(archiveDayLayout)
+		buffer, exists := byDay[day]
+		if !exists {
+			buffer = &bytes.Buffer{}
+			byDay[day] = buffer
+		}
+		if err := json.NewEncoder(buffer).Encode(ArchivedJob{Job: job, ArchivedAt: archivedAt}); err != nil {
+			return fmt.Errorf("failed to write archived job %s: %v", job.ID, err)
+		}
+	}
+
+	for day, buffer := range byDay {
+		key := fmt.Sprintf("jobs-%s/%d-%s.ndjson", day, archivedAt.UnixNano(), uuid.New().String())
+		if err := ocs.objects.Put(key, buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadJobs returns archived jobs completed between from and to (inclusive),
+// reading only the objects of days in the range
+func (ocs *ObjectColdStore) ReadJobs(from, to time.Time) ([]*ArchivedJob, error) {
+	keys, err := ocs.objects.List("jobs-")
+	if err != nil {
+		return nil, err
+	}
+
+	var jobs []*ArchivedJob
+	for _, key := range keys {
+		day, ok := parseArchiveDay(key)
+		if !ok || !archiveDayInRange(day, from, to) {
+			continue
+		}
+
+		data, err := ocs.objects.Get(key)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		objectJobs, err := decodeArchivedJobs(bytes.NewReader(data), from, to)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive object %s: %v", key, err)
+		}
+		jobs = append(jobs, objectJobs...)
+	}
+
+	sortArchivedJobs(jobs)
+	return jobs, nil
+}
+
+// JobArchiver periodically moves expired jobs from the job store to cold storage
+type JobArchiver struct {
+	store     JobStoreInterface
+	coldStore ColdStore
+	ttl       map[string]time.Duration
+	interval  time.Duration
+	ticker    *time.Ticker
+}
+
+// NewJobArchiver creates a job archiver from the database configuration.
+// It returns nil when no job TTL is configured.
+func NewJobArchiver(store JobStoreInterface, config DatabaseConfig) (*JobArchiver, error) {
+	ttl := make(map[string]time.Duration)
+	if config.JobTTL.CompletedSeconds > 0 {
+		ttl["completed"] = time.Duration(config.JobTTL.CompletedSeconds) * time.Second
+		// Deliberately aborted jobs finished cleanly and expire like completed ones
+		ttl[abortStatusAborted] = ttl["completed"]
+		ttl[abortStatusSkipped] = ttl["completed"]
+		ttl[jobStatusCompletedWithErrors] = ttl["completed"]
+	}
+	if config.JobTTL.FailedSeconds > 0 {
+		ttl["failed"] = time.Duration(config.JobTTL.FailedSeconds) * time.Second
+	}
+	if config.JobTTL.CancelledSeconds > 0 {
+		ttl["cancelled"] = time.Duration(config.JobTTL.CancelledSeconds) * time.Second
+	}
+	if len(ttl) == 0 {
+		return nil, nil
+	}
+
+	interval := time.Hour
+	if config.Archive.Interval != "" {
+		parsed, err := time.ParseDuration(config.Archive.Interval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive interval: %v", err)
+		}
+		interval = parsed
+	}
+
+	archiver := &JobArchiver{
+		store:    store,
+		ttl:      ttl,
+		interval: interval,
+	}
+
+	if config.Archive.Enabled {
+		coldStore, err := NewColdStore(config.Archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create cold store: %v", err)
+		}
+		archiver.coldStore = coldStore
+	}
+
+	return archiver, nil
+}
+
+// Start runs archival passes in the background
+func (ja *JobArchiver) Start() {
+	ja.ticker = time.NewTicker(ja.interval)
+	go func() {
+		for range ja.ticker.C {
+			if err := ja.ArchiveExpiredJobs(); err != nil {
+				logger.Error("Failed to archive expired jobs", map[string]interface{}{
+					"component": "job_archiver",
+					"error":     err.Error(),
+				})
+			}
+		}
+	}()
+}
+
+// Stop stops the background archival task
+func (ja *JobArchiver) Stop() {
+	if ja.ticker != nil {
+		ja.ticker.Stop()
+	}
+}
+
+// ArchiveExpiredJobs exports expired jobs to cold storage and removes them from the job store
+func (ja *JobArchiver) ArchiveExpiredJobs() error {
+	now := time.Now()
+
+	var expired []*Job
+	for _, job := range ja.store.ListJobs("", archiveScanLimit) {
+		ttl, ok := ja.ttl[job.Status]
+		if !ok || job.CompletedAt == nil {
+			continue
+		}
+		if now.Sub(*job.CompletedAt) >= ttl {
+			expired = append(expired, job)
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil
+	}
+
+	// Jobs are only deleted once they are safely in cold storage
+	if ja.coldStore != nil {
+		if err := ja.coldStore.WriteJobs(expired, now); err != nil {
+			return fmt.Errorf("failed to export jobs to cold store: %v", err)
+		}
+	}
+
+	deleted := 0
+	for _, job := range expired {
+		if err := ja.store.DeleteJob(job.ID); err != nil {
+			logger.Error("Failed to delete archived job", map[string]interface{}{
+				"component": "job_archiver",
+				"job_id":    job.ID,
+				"error":     err.Error(),
+			})
+			continue
+		}
+		deleted++
+	}
+
+	logger.Info("Archived expired jobs", map[string]interface{}{
+		"component": "job_archiver",
+		"archived":  deleted,
+		"exported":  ja.coldStore != nil,
+	})
+
+	return nil
+}
+
+// ReadArchive returns archived jobs completed between from and to
+func (ja *JobArchiver) ReadArchive(from, to time.Time) ([]*ArchivedJob, error) {
+	if ja.coldStore == nil {
+		return nil, fmt.Errorf("cold storage is not enabled")
+	}
+	return ja.coldStore.ReadJobs(from, to)
+}
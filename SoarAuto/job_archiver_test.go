@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func archivedJobIDs(jobs []*ArchivedJob) []string {
+	ids := make([]string, 0, len(jobs))
+	for _, job := range jobs {
+		ids = append(ids, job.ID)
+	}
+	return ids
+}
+
+func completedJob(id string, completedAt time.Time) *Job {
+	return &Job{ID: id, Status: "completed", CreatedAt: completedAt.Add(-time.Minute), CompletedAt: &completedAt}
+}
+
+func coldStores(t *testing.T) map[string]ColdStore {
+	t.Helper()
+	fileStore, err := NewFileColdStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("create file cold store: %v", err)
+	}
+	s3Store, _ := newTestObjectStore(t, "s3")
+	gcsStore, _ := newTestObjectStore(t, "gcs")
+	return map[string]ColdStore{
+		"filesystem": fileStore,
+		"s3":         NewObjectColdStore(s3Store),
+		"gcs":        NewObjectColdStore(gcsStore),
+	}
+}
+
+func TestColdStoreWriteAndReadJobs(t *testing.T) {
+	day1 := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := time.Date(2024, 3, 2, 23, 30, 0, 0, time.UTC)
+	day3 := time.Date(2024, 3, 5, 8, 0, 0, 0, time.UTC)
+
+	for name, store := range coldStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.WriteJobs([]*Job{completedJob("b", day2), completedJob("a", day1)}, day3); err != nil {
+				t.Fatalf("write jobs: %v", err)
+			}
+			// A second pass adds to the same day
+			if err := store.WriteJobs([]*Job{completedJob("c", day2.Add(time.Minute)), completedJob("d", day3)}, day3); err != nil {
+				t.Fatalf("write jobs: %v", err)
+			}
+
+			jobs, err := store.ReadJobs(day1, day3)
+			if err != nil {
+				t.Fatalf("read jobs: %v", err)
+			}
+			if ids := archivedJobIDs(jobs); !equalStrings(ids, []string{"a", "b", "c", "d"}) {
+				t.Errorf("all jobs = %v, want [a b c d]", ids)
+			}
+			if !jobs[0].ArchivedAt.Equal(day3) {
+				t.Errorf("archived_at = %v, want %v", jobs[0].ArchivedAt, day3)
+			}
+
+			// The range is inclusive and cuts within a day
+			jobs, err = store.ReadJobs(day1.Add(time.Second), day2)
+			if err != nil {
+				t.Fatalf("read jobs: %v", err)
+			}
+			if ids := archivedJobIDs(jobs); !equalStrings(ids, []string{"b"}) {
+				t.Errorf("jobs in range = %v, want [b]", ids)
+			}
+		})
+	}
+}
+
+func TestColdStoreReadJobsWideRange(t *testing.T) {
+	completed := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	for name, store := range coldStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.WriteJobs([]*Job{completedJob("a", completed)}, completed); err != nil {
+				t.Fatalf("write jobs: %v", err)
+			}
+
+			// Reading a range of centuries must not visit every day in it
+			done := make(chan []*ArchivedJob, 1)
+			go func() {
+				jobs, err := store.ReadJobs(time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC))
+				if err != nil {
+					t.Errorf("read jobs: %v", err)
+				}
+				done <- jobs
+			}()
+			select {
+			case jobs := <-done:
+				if ids := archivedJobIDs(jobs); !equalStrings(ids, []string{"a"}) {
+					t.Errorf("jobs = %v, want [a]", ids)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("reading a wide range did not finish")
+			}
+		})
+	}
+}
+
+func TestNewColdStoreBackends(t *testing.T) {
+	if _, err := NewColdStore(ArchiveConfig{Backend: "filesystem", Directory: t.TempDir()}); err != nil {
+		t.Errorf("filesystem backend: %v", err)
+	}
+	if _, err := NewColdStore(ArchiveConfig{Backend: "s3", ObjectStorage: ObjectStorageConfig{Bucket: "secauto"}}); err != nil {
+		t.Errorf("s3 backend: %v", err)
+	}
+	if _, err := NewColdStore(ArchiveConfig{Backend: "gcs"}); err == nil {
+		t.Error("gcs backend without a bucket was accepted")
+	}
+	if _, err := NewColdStore(ArchiveConfig{Backend: "tape"}); err == nil {
+		t.Error("unknown backend was accepted")
+	}
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// dedupBloomKey is the Redis key holding the Bloom filter bitset
+	dedupBloomKey = "jobs:dedup:bloom"
+	// maxBloomBits is the largest bitset Redis supports (512MB)
+	maxBloomBits = uint64(1) << 32
+)
+
+// JobDeduplicator detects probable duplicate job submissions with a
+// Redis-backed Bloom filter. Lookups may report false positives at the
+// configured rate but never false negatives until the filter is reset.
+type JobDeduplicator struct {
+	client        *redis.Client
+	ctx           context.Context
+	bits          uint64
+	hashCount     int
+	resetInterval time.Duration
+
+	duplicates atomic.Int64
+	checks     atomic.Int64
+	lastReset  atomic.Int64
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewJobDeduplicator sizes a Bloom filter for the expected number of
+// submissions per reset interval and the target false positive rate
+func NewJobDeduplicator(client *redis.Client, config DeduplicationConfig) (*JobDeduplicator, error) {
+	if config.ExpectedItems <= 0 {
+		return nil, fmt.Errorf("deduplication expected_items must be positive")
+	}
+	if config.FalsePositiveRate <= 0 || config.FalsePositiveRate >= 1 {
+		return nil, fmt.Errorf("deduplication false_positive_rate must be between 0 and 1")
+	}
+
+	resetInterval := time.Hour
+	if config.ResetInterval != "" {
+		parsed, err := time.ParseDuration(config.ResetInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deduplication reset_interval: %v", err)
+		}
+		resetInterval = parsed
+	}
+
+	bits, hashCount := bloomParameters(config.ExpectedItems, config.FalsePositiveRate)
+
+	dedup := &JobDeduplicator{
+		client:        client,
+		ctx:           context.Background(),
+		bits:          bits,
+		hashCount:     hashCount,
+		resetInterval: resetInterval,
+		stopChan:      make(chan struct{}),
+	}
+	dedup.lastReset.Store(time.Now().Unix())
+
+	logger.Info("Job deduplication enabled", map[string]interface{}{
+		"component":      "job_dedup",
+		"bits":           bits,
+		"hash_functions": hashCount,
+		"reset_interval": resetInterval.String(),
+	})
+
+	return dedup, nil
+}
+
+// bloomParameters returns the optimal bitset size and hash function count for
+// n items at false positive rate p
+func bloomParameters(n int, p float64) (uint64, int) {
+	m := math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2))
+	bits := uint64(m)
+	if bits > maxBloomBits {
+		bits = maxBloomBits
+	}
+	if bits < 64 {
+		bits = 64
+	}
+
+	k := int(math.Round(float64(bits) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return bits, k
+}
+
+// JobFingerprint returns the SHA-256 of a playbook and context. Map keys are
+// serialized in sorted order, so equal submissions share a fingerprint.
+func JobFingerprint(playbook []interface{}, context map[string]interface{}) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"playbook": playbook,
+		"context":  context,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint job: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CheckAndAdd records a fingerprint in the filter and reports whether it was
+// probably seen before
+func (jd *JobDeduplicator) CheckAndAdd(fingerprint string) (bool, error) {
+	digest, err := hex.DecodeString(fingerprint)
+	if err != nil || len(digest) < 16 {
+		return false, fmt.Errorf("invalid fingerprint")
+	}
+
+	// Derive k bit positions by double hashing the fingerprint
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16])
+
+	// SETBIT returns the previous bit; the item was present if all were set.
+	// The transaction keeps concurrent identical submissions from both passing.
+	cmds, err := jd.client.TxPipelined(jd.ctx, func(pipe redis.Pipeliner) error {
+		for i := 0; i < jd.hashCount; i++ {
+			offset := (h1 + uint64(i)*h2) % jd.bits
+			pipe.SetBit(jd.ctx, dedupBloomKey, int64(offset), 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to update dedup filter: %v", err)
+	}
+
+	jd.checks.Add(1)
+	for _, cmd := range cmds {
+		if cmd.(*redis.IntCmd).Val() == 0 {
+			return false, nil
+		}
+	}
+	jd.duplicates.Add(1)
+	return true, nil
+}
+
+// Start begins resetting the filter on the configured schedule
+func (jd *JobDeduplicator) Start() {
+	go func() {
+		ticker := time.NewTicker(jd.resetInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				jd.Reset()
+			case <-jd.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the reset schedule
+func (jd *JobDeduplicator) Stop() {
+	jd.stopOnce.Do(func() {
+		close(jd.stopChan)
+	})
+}
+
+// Reset clears the filter
+func (jd *JobDeduplicator) Reset() {
+	if err := jd.client.Del(jd.ctx, dedupBloomKey).Err(); err != nil {
+		logger.Error("Failed to reset dedup filter", map[string]interface{}{
+			"component": "job_dedup",
+			"error":     err.Error(),
+		})
+		return
+	}
+	jd.lastReset.Store(time.Now().Unix())
+
+	logger.Info("Dedup filter reset", map[string]interface{}{
+		"component": "job_dedup",
+	})
+}
+
+// Stats returns filter sizing and counters
+func (jd *JobDeduplicator) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"bits":             jd.bits,
+		"hash_functions":   jd.hashCount,
+		"reset_interval":   jd.resetInterval.String(),
+		"last_reset":       time.Unix(jd.lastReset.Load(), 0).UTC().Format(time.RFC3339),
+		"checks_total":     jd.checks.Load(),
+		"duplicates_total": jd.duplicates.Load(),
+	}
+}
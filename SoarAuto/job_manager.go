@@ -7,26 +7,114 @@ import (
 	"github.com/google/uuid"
 )
 
-// Job represents an asynchronous playbook execution job
+// Job represents an asynchronous playbook execution job. It is also the
+// record every job endpoint returns, including those for cluster jobs, and
+// the Job schema of the OpenAPI spec.
 type Job struct {
-	ID          string                 `json:"id"`
-	Status      string                 `json:"status"` // "pending", "running", "completed", "failed"
-	Playbook    []interface{}          `json:"playbook"`
-	Context     map[string]interface{} `json:"context"`
-	Results     []interface{}          `json:"results,omitempty"`
-	Error       string                 `json:"error,omitempty"`
-	CreatedAt   time.Time              `json:"created_at"`
-	StartedAt   *time.Time             `json:"started_at,omitempty"`
-	CompletedAt *time.Time             `json:"completed_at,omitempty"`
+	ID       string                 `json:"id"`
+	Status   string                 `json:"status"` // "pending", "running", "completed", "completed_with_errors", "failed", "cancelled", "aborted", "skipped"
+	Playbook []interface{}          `json:"playbook"`
+	Context  map[string]interface{} `json:"context"`
+	// InitialContext holds the submitted context once Context has been replaced
+	// by the final context of a completed run
+	InitialContext map[string]interface{} `json:"initial_context,omitempty"`
+	Metadata       map[string]interface{} `json:"metadata,omitempty"`
+	// Env holds extra environment variables passed to Python automations
+	Env map[string]string `json:"env,omitempty"`
+	// Priority is the priority the job was submitted with
+	Priority string `json:"priority,omitempty"`
+	// Labels tag the job; only cluster jobs carry them
+	Labels []string `json:"labels,omitempty"`
+	// Retries counts the times the job was run again after failing
+	Retries int `json:"retries"`
+	// Node is the cluster node a cluster job was assigned to
+	Node string `json:"node,omitempty"`
+	// ContinueOnError lets rules without their own continue_on_error flag
+	// fail without stopping the playbook
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+	// Strict overrides the configured strictness of the run when set
+	Strict *bool `json:"strict,omitempty"`
+	// PlaybookName is the stored playbook the job runs, or inline-<hash> for
+	// an inline playbook; TriggeredBy is the caller or schedule that submitted it
+	PlaybookName string `json:"playbook_name,omitempty"`
+	TriggeredBy  string `json:"triggered_by,omitempty"`
+
+	// Versions of the plugins the playbook references, when the job was
+	// submitted and when it started executing
+	PluginVersionsAtSubmission map[string]string    `json:"plugin_versions_at_submission,omitempty"`
+	PluginVersionsAtExecution  map[string]string    `json:"plugin_versions_at_execution,omitempty"`
+	Results                    []interface{}        `json:"results,omitempty"`
+	Error                      string               `json:"error,omitempty"`
+	ErrorType                  string               `json:"error_type,omitempty"`     // "assertion_failed" when an assert operation failed
+	AbortReason                string               `json:"abort_reason,omitempty"`   // Reason given when the playbook aborted
+	CancelReason               string               `json:"cancel_reason,omitempty"`  // Reason given when the job was cancelled
+	CancelledBy                string               `json:"cancelled_by,omitempty"`   // Caller that cancelled the job
+	Warnings                   []TemplateWarning    `json:"warnings,omitempty"`       // Template variables that could not be resolved
+	Findings                   []Finding            `json:"findings,omitempty"`       // Recorded by finding operations, apart from Results
+	Provenance                 *ExecutionProvenance `json:"provenance,omitempty"`     // Hashes of the code the run executed
+	ResourceUsage              *JobResourceUsage    `json:"resource_usage,omitempty"` // CPU and memory used by the run's automations
+	CreatedAt                  time.Time            `json:"created_at"`
+	StartedAt                  *time.Time           `json:"started_at,omitempty"`
+	CompletedAt                *time.Time           `json:"completed_at,omitempty"`
+	DurationSeconds            float64              `json:"duration_seconds,omitempty"` // From start to completion; unset for jobs that never started
+	ResultTruncation                                // Set when Results were cut down to the size cap
+
+	// SchemaVersion is the record layout version, used to migrate stored jobs
+	SchemaVersion int `json:"schema_version"`
 }
 
 // JobManager manages asynchronous job execution
 type JobManager struct {
 	store          JobStoreInterface
-	workerPool     chan struct{}
+	queue          *JobQueue
 	webhookManager *WebhookManager
 	cleanupTicker  *time.Ticker
 	backupTicker   *time.Ticker
+	archiver       *JobArchiver
+	pluginManager  *PlatformPluginManager
+	deduplicator   *JobDeduplicator
+	enrichment     *EnrichmentCache
+	throttle       *PlaybookThrottle
+
+	integrationConfigManager *IntegrationConfigManager
+}
+
+// pendingJobScanLimit bounds the number of jobs inspected when a plugin is reloaded
+const pendingJobScanLimit = 1000
+
+// defaultJobPriority is the priority of jobs submitted without one
+const defaultJobPriority = "normal"
+
+// jobPriorities lists the accepted job priorities
+var jobPriorities = map[string]bool{
+	"low":      true,
+	"normal":   true,
+	"high":     true,
+	"critical": true,
+}
+
+// isFinishedJobStatus reports whether a job with the status will not change again
+func isFinishedJobStatus(status string) bool {
+	switch status {
+	case "completed", jobStatusCompletedWithErrors, "failed", "cancelled", abortStatusAborted, abortStatusSkipped:
+		return true
+	}
+	return false
+}
+
+// setCompleted records when a job finished and, if it started, how long it ran
+func (job *Job) setCompleted(now time.Time) {
+	job.CompletedAt = &now
+	job.DurationSeconds = jobDurationSeconds(job.StartedAt, job.CompletedAt)
+}
+
+// jobDurationSeconds returns the time between a job's start and completion,
+// or 0 when it did not both start and complete
+func jobDurationSeconds(startedAt, completedAt *time.Time) float64 {
+	if startedAt == nil || completedAt == nil || completedAt.Before(*startedAt) {
+		return 0
+	}
+	return completedAt.Sub(*startedAt).Seconds()
 }
 
 // NewJobManager creates a new job manager with specified worker pool size
@@ -38,10 +126,47 @@ func NewJobManager(workerCount int, webhookManager *WebhookManager, config *Conf
 
 	jm := &JobManager{
 		store:          store,
-		workerPool:     make(chan struct{}, workerCount),
+		queue:          NewJobQueue(workerCount),
 		webhookManager: webhookManager,
 	}
 
+	archiver, err := NewJobArchiver(store, config.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job archiver: %v", err)
+	}
+	jm.archiver = archiver
+
+	if config.Database.Deduplication.Enabled {
+		redisStore, ok := store.(*RedisJobStore)
+		if !ok {
+			return nil, fmt.Errorf("job deduplication requires the Redis job store")
+		}
+		deduplicator, err := NewJobDeduplicator(redisStore.client, config.Database.Deduplication)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create job deduplicator: %v", err)
+		}
+		jm.deduplicator = deduplicator
+	}
+
+	if config.Database.EnrichmentCache.Enabled {
+		redisStore, ok := store.(*RedisJobStore)
+		if !ok {
+			return nil, fmt.Errorf("the enrichment cache requires the Redis job store")
+		}
+		enrichment, err := NewEnrichmentCache(redisStore.client, config.Database.EnrichmentCache)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create enrichment cache: %v", err)
+		}
+		jm.enrichment = enrichment
+	}
+
+	// Throttle buckets are shared through Redis when the job store uses it
+	if redisStore, ok := store.(*RedisJobStore); ok {
+		jm.throttle = NewPlaybookThrottle(redisStore.client)
+	} else {
+		jm.throttle = NewPlaybookThrottle(nil)
+	}
+
 	// Start background tasks
 	jm.startBackgroundTasks()
 
@@ -63,6 +188,16 @@ func (jm *JobManager) startBackgroundTasks() {
 		}
 	}()
 
+	// Start the dedup filter reset schedule
+	if jm.deduplicator != nil {
+		jm.deduplicator.Start()
+	}
+
+	// Start archival of expired jobs if a TTL is configured
+	if jm.archiver != nil {
+		jm.archiver.Start()
+	}
+
 	// Start backup ticker (every 7 days)
 	jm.backupTicker = time.NewTicker(7 * 24 * time.Hour)
 	go func() {
@@ -79,7 +214,27 @@ func (jm *JobManager) startBackgroundTasks() {
 
 // SubmitJob submits a new job for execution
 func (jm *JobManager) SubmitJob(playbook []interface{}, context map[string]interface{}) string {
+	return jm.SubmitJobWithEnv(playbook, context, nil)
+}
+
+// SubmitJobWithEnv submits a new job whose Python automations run with env
+// merged over the base environment
+func (jm *JobManager) SubmitJobWithEnv(playbook []interface{}, context map[string]interface{}, env map[string]string) string {
+	return jm.SubmitJobWithPriority(playbook, context, env, defaultJobPriority)
+}
+
+// SubmitJobWithPriority submits a new job with env and the given priority
+func (jm *JobManager) SubmitJobWithPriority(playbook []interface{}, context map[string]interface{}, env map[string]string, priority string) string {
+	return jm.SubmitJobFrom(JobSource{}, playbook, context, env, priority)
+}
+
+// SubmitJobFrom submits a new job recording the playbook it runs and who
+// submitted it. Inline playbooks are named by their content.
+func (jm *JobManager) SubmitJobFrom(source JobSource, playbook []interface{}, context map[string]interface{}, env map[string]string, priority string) string {
 	jobID := uuid.New().String()
+	if source.PlaybookName == "" {
+		source.PlaybookName = inlinePlaybookName(playbook)
+	}
 
 	logger.Info("Submitting job", map[string]interface{}{
 		"component":    "job_manager",
@@ -90,11 +245,25 @@ func (jm *JobManager) SubmitJob(playbook []interface{}, context map[string]inter
 	})
 
 	job := &Job{
-		ID:        jobID,
-		Status:    "pending",
-		Playbook:  playbook,
-		Context:   context,
-		CreatedAt: time.Now(),
+		ID:           jobID,
+		Status:       "pending",
+		Playbook:     playbook,
+		PlaybookName: source.PlaybookName,
+		TriggeredBy:  source.TriggeredBy,
+		Context:      context,
+		Env:          env,
+		Priority:     priority,
+		CreatedAt:    time.Now(),
+
+		ContinueOnError: source.ContinueOnError,
+		Strict:          source.Strict,
+	}
+
+	// Record the plugin versions the job was submitted against
+	if jm.pluginManager != nil {
+		if pluginNames := collectPluginReferences(playbook); len(pluginNames) > 0 {
+			job.PluginVersionsAtSubmission = jm.pluginManager.GetPluginVersions(pluginNames)
+		}
 	}
 
 	// Save to persistent storage
@@ -110,7 +279,19 @@ func (jm *JobManager) SubmitJob(playbook []interface{}, context map[string]inter
 		"component": "job_manager",
 		"job_id":    jobID,
 		"status":    "pending",
-		"playbook":  fmt.Sprintf("%d", len(playbook)),
+		"priority":  priority,
+		"playbook":  source.PlaybookName,
+	})
+
+	eventBus.Publish(ActivityEvent{
+		Type:     "job_submitted",
+		JobID:    jobID,
+		Playbook: source.PlaybookName,
+		Status:   "pending",
+		Details: map[string]interface{}{
+			"priority":     priority,
+			"triggered_by": source.TriggeredBy,
+		},
 	})
 
 	// Submit to worker pool
@@ -119,11 +300,177 @@ func (jm *JobManager) SubmitJob(playbook []interface{}, context map[string]inter
 	return jobID
 }
 
+// SetIntegrationConfigManager sets the integration configs available to job playbooks
+func (jm *JobManager) SetIntegrationConfigManager(integrations *IntegrationConfigManager) {
+	jm.integrationConfigManager = integrations
+}
+
+// SetPluginManager sets the plugin manager used to track plugin versions and
+// subscribes to plugin reloads
+func (jm *JobManager) SetPluginManager(pluginManager *PlatformPluginManager) {
+	jm.pluginManager = pluginManager
+	pluginManager.SetReloadHandler(jm.handlePluginReload)
+}
+
+// handlePluginReload flags pending jobs that reference a reloaded plugin and
+// sends a plugin_reloaded webhook event
+func (jm *JobManager) handlePluginReload(pluginName, previousVersion, version string) {
+	var affectedJobs []string
+	for _, listed := range jm.store.ListJobs("pending", pendingJobScanLimit) {
+		if !containsString(collectPluginReferences(listed.Playbook), pluginName) {
+			continue
+		}
+
+		// Flag the stored record, which may have started or been cancelled
+		// since it was listed; only jobs still pending are flagged
+		flagged, err := jm.store.UpdateJob(listed.ID, func(job *Job) bool {
+			if job.Status != "pending" {
+				return false
+			}
+			if job.Metadata == nil {
+				job.Metadata = make(map[string]interface{})
+			}
+			job.Metadata["plugin_version_change"] = true
+
+			changed, _ := job.Metadata["changed_plugins"].([]interface{})
+			if !containsInterface(changed, pluginName) {
+				job.Metadata["changed_plugins"] = append(changed, pluginName)
+			}
+			return true
+		})
+		if err != nil {
+			logger.Error("Failed to flag job after plugin reload", map[string]interface{}{
+				"component": "job_manager",
+				"job_id":    listed.ID,
+				"error":     err.Error(),
+			})
+			continue
+		}
+		if flagged {
+			affectedJobs = append(affectedJobs, listed.ID)
+		}
+	}
+
+	logger.Info("Plugin reloaded", map[string]interface{}{
+		"component":     "job_manager",
+		"plugin":        pluginName,
+		"affected_jobs": len(affectedJobs),
+	})
+
+	if jm.webhookManager != nil {
+		jm.webhookManager.SendWebhook(WebhookEvent{
+			Event:           "plugin_reloaded",
+			Status:          "reloaded",
+			Timestamp:       time.Now().UTC().Format(time.RFC3339),
+			Plugin:          pluginName,
+			PluginVersion:   version,
+			PreviousVersion: previousVersion,
+			AffectedJobs:    affectedJobs,
+		})
+	}
+}
+
+// collectPluginReferences returns the names of the plugins a playbook calls
+// directly, including inside nested conditions
+func collectPluginReferences(playbook []interface{}) []string {
+	seen := make(map[string]bool)
+	var names []string
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if pluginExpr, exists := v["plugin"]; exists {
+				name, _ := pluginExpr.(string)
+				if pluginMap, ok := pluginExpr.(map[string]interface{}); ok {
+					name, _ = pluginMap["name"].(string)
+				}
+				if name != "" && !seen[name] {
+					seen[name] = true
+					names = append(names, name)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(playbook)
+
+	return names
+}
+
+// containsString reports whether values contains target
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// containsInterface reports whether values contains target
+func containsInterface(values []interface{}, target interface{}) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckDuplicate reports whether an identical job was probably submitted since
+// the last filter reset, returning the submission fingerprint. Deduplication
+// fails open: if the filter cannot be reached the job is treated as new.
+func (jm *JobManager) CheckDuplicate(playbook []interface{}, context map[string]interface{}) (bool, string) {
+	if jm.deduplicator == nil {
+		return false, ""
+	}
+
+	fingerprint, err := JobFingerprint(playbook, context)
+	if err != nil {
+		logger.Warning("Failed to fingerprint job submission", map[string]interface{}{
+			"component": "job_manager",
+			"error":     err.Error(),
+		})
+		return false, ""
+	}
+
+	duplicate, err := jm.deduplicator.CheckAndAdd(fingerprint)
+	if err != nil {
+		logger.Warning("Job deduplication check failed", map[string]interface{}{
+			"component": "job_manager",
+			"error":     err.Error(),
+		})
+		return false, fingerprint
+	}
+	return duplicate, fingerprint
+}
+
 // GetJob retrieves a job by ID
 func (jm *JobManager) GetJob(jobID string) (*Job, bool) {
 	return jm.store.LoadJob(jobID)
 }
 
+// GetJobVersion returns a job's change counter
+func (jm *JobManager) GetJobVersion(jobID string) (int64, error) {
+	return jm.store.GetJobVersion(jobID)
+}
+
+// QueuePosition returns where a pending job waits for a worker, and when it
+// is expected to start. It reports false when the job is not queued on this
+// instance.
+func (jm *JobManager) QueuePosition(jobID string) (*JobQueuePosition, bool) {
+	return jm.queue.Position(jobID, func() time.Duration {
+		return time.Duration(jm.store.GetStats().AvgDuration * float64(time.Second))
+	})
+}
+
 // ListJobs retrieves jobs based on status and limit
 func (jm *JobManager) ListJobs(status string, limit int) []*Job {
 	return jm.store.ListJobs(status, limit)
@@ -134,26 +481,68 @@ func (jm *JobManager) GetStats() JobStats {
 	return jm.store.GetStats()
 }
 
-// CancelJob attempts to cancel a job by ID
-func (jm *JobManager) CancelJob(jobID string) (bool, string) {
-	job, exists := jm.store.LoadJob(jobID)
-	if !exists {
-		return false, "Job not found"
-	}
+// CancelJob attempts to cancel a job by ID, recording the optional reason and
+// the caller that cancelled it on the job and in the job_cancelled webhook
+func (jm *JobManager) CancelJob(jobID, reason, cancelledBy string) (bool, string) {
+	// Check and cancel in one update, so a job that starts in the meantime
+	// is not marked cancelled
+	now := time.Now()
+	var job *Job
+	var refusal string
+	_, err := jm.store.UpdateJob(jobID, func(stored *Job) bool {
+		job = stored
 
-	// If the job is running, we can't cancel it immediately.
-	// This is a simplified cancellation.
-	if job.Status == "running" {
-		return false, "Job is currently running and cannot be cancelled immediately."
-	}
+		// If the job is running, we can't cancel it immediately.
+		// This is a simplified cancellation.
+		if job.Status == "running" {
+			refusal = "Job is currently running and cannot be cancelled immediately."
+			return false
+		}
+		if isFinishedJobStatus(job.Status) {
+			refusal = fmt.Sprintf("Job has already finished with status %s", job.Status)
+			return false
+		}
+		refusal = ""
 
-	// Mark job as cancelled
-	if err := jm.store.UpdateJobStatus(jobID, "cancelled"); err != nil {
+		// Mark job as cancelled
+		job.Status = "cancelled"
+		job.Results = nil
+		job.Error = "Job cancelled by user"
+		job.CancelReason = reason
+		job.CancelledBy = cancelledBy
+		job.setCompleted(now)
+		return true
+	})
+	if job == nil {
+		return false, "Job not found"
+	}
+	if err != nil {
 		return false, fmt.Sprintf("Failed to cancel job: %v", err)
 	}
+	if refusal != "" {
+		return false, refusal
+	}
+	jm.queue.Remove(jobID)
 
-	if err := jm.store.UpdateJobResults(jobID, nil, "Job cancelled by user"); err != nil {
-		return false, fmt.Sprintf("Failed to update job results: %v", err)
+	logger.Info("Job cancelled", map[string]interface{}{
+		"component":    "job_manager",
+		"job_id":       jobID,
+		"reason":       reason,
+		"cancelled_by": cancelledBy,
+	})
+
+	if jm.webhookManager != nil {
+		jm.webhookManager.SendWebhook(WebhookEvent{
+			Event:       "job_cancelled",
+			JobID:       jobID,
+			Status:      job.Status,
+			Timestamp:   now.UTC().Format(time.RFC3339),
+			Playbook:    job.Playbook,
+			Context:     job.Context,
+			Error:       job.Error,
+			Reason:      reason,
+			CancelledBy: cancelledBy,
+		})
 	}
 
 	return true, "Job cancelled"
@@ -168,6 +557,12 @@ func (jm *JobManager) Cleanup() {
 	if jm.backupTicker != nil {
 		jm.backupTicker.Stop()
 	}
+	if jm.archiver != nil {
+		jm.archiver.Stop()
+	}
+	if jm.deduplicator != nil {
+		jm.deduplicator.Stop()
+	}
 
 	// Close database connection
 	if jm.store != nil {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// staleListStore returns a fixed job list, as if the jobs changed after
+// they were listed
+type staleListStore struct {
+	JobStoreInterface
+	listed []*Job
+}
+
+func (s *staleListStore) ListJobs(status string, limit int) []*Job {
+	return s.listed
+}
+
+func TestPluginReloadFlagsOnlyJobsStillPending(t *testing.T) {
+	store := NewMemoryJobStore()
+	playbook := []interface{}{map[string]interface{}{"plugin": "virustotal"}}
+	for _, id := range []string{"waiting", "cancelled", "started"} {
+		if err := store.SaveJob(&Job{ID: id, Status: "pending", Playbook: playbook, CreatedAt: time.Now()}); err != nil {
+			t.Fatalf("save %s: %v", id, err)
+		}
+	}
+	listed := store.ListJobs("pending", 0)
+
+	// Two jobs change between the listing and the flagging
+	jm := &JobManager{store: &staleListStore{JobStoreInterface: store, listed: listed}, queue: NewJobQueue(1)}
+	if ok, message := jm.CancelJob("cancelled", "duplicate", "analyst"); !ok {
+		t.Fatalf("cancel: %s", message)
+	}
+	if err := store.UpdateJobStatus("started", "running"); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	jm.handlePluginReload("virustotal", "1.0.0", "1.1.0")
+
+	want := map[string]string{"waiting": "pending", "cancelled": "cancelled", "started": "running"}
+	for id, status := range want {
+		job, _ := store.LoadJob(id)
+		if job.Status != status {
+			t.Errorf("%s status = %s, want %s", id, job.Status, status)
+		}
+		flagged := job.Metadata["plugin_version_change"] == true
+		if flagged != (id == "waiting") {
+			t.Errorf("%s flagged = %v, want only the pending job flagged", id, flagged)
+		}
+	}
+
+	cancelled, _ := store.LoadJob("cancelled")
+	if cancelled.CancelReason != "duplicate" || cancelled.CancelledBy != "analyst" {
+		t.Errorf("cancel overwritten: %+v", cancelled)
+	}
+}
+
+func TestCancelJobRefusesRunningJobs(t *testing.T) {
+	store := NewMemoryJobStore()
+	if err := store.SaveJob(&Job{ID: "busy", Status: "running", CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	jm := &JobManager{store: store}
+
+	if ok, _ := jm.CancelJob("busy", "", ""); ok {
+		t.Error("a running job was cancelled")
+	}
+	if job, _ := store.LoadJob("busy"); job.Status != "running" {
+		t.Errorf("status = %s, want running", job.Status)
+	}
+	if ok, message := jm.CancelJob("missing", "", ""); ok || message != "Job not found" {
+		t.Errorf("cancel missing job = %v, %q", ok, message)
+	}
+}
+
+func TestSynchronousPlaybookRejectsPriority(t *testing.T) {
+	server := &SecAutoServer{validator: NewValidator()}
+	body := `{"playbook": [{"if": {"conditions": [{"eq": [1, 1]}], "true": []}}], "priority": "high"}`
+
+	recorder := httptest.NewRecorder()
+	server.handlePlaybookRequest(recorder, httptest.NewRequest(http.MethodPost, "/playbook", strings.NewReader(body)), false)
+	if recorder.Code != http.StatusBadRequest || !strings.Contains(recorder.Body.String(), "asynchronous") {
+		t.Errorf("synchronous run with a priority: %d %s, want 400", recorder.Code, recorder.Body.String())
+	}
+}
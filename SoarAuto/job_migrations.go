@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// currentJobSchemaVersion is the schema version written with every job.
+// Bump it and append a migration to jobMigrations when the Job struct changes
+// in a way older records need to be upgraded for.
+const currentJobSchemaVersion = 2
+
+// jobMigrationProgressInterval is how many scanned jobs pass between progress
+// reports during a bulk migration
+const jobMigrationProgressInterval = 100
+
+// jobMigration upgrades a raw job record by one schema version. Migrations
+// must be idempotent: applying one to an already migrated record is a no-op.
+type jobMigration func(record map[string]interface{}) error
+
+// jobMigrations is the forward migration chain; jobMigrations[i] upgrades a
+// record from version i to version i+1
+var jobMigrations = []jobMigration{
+	migrateJobToV1,
+	migrateJobToV2,
+}
+
+// migrateJobToV1 upgrades records written before schema versioning. Such
+// records may carry a null playbook or context, or no status at all.
+func migrateJobToV1(record map[string]interface{}) error {
+	if record["playbook"] == nil {
+		record["playbook"] = []interface{}{}
+	}
+	if record["context"] == nil {
+		record["context"] = map[string]interface{}{}
+	}
+	if status, _ := record["status"].(string); status == "" {
+		record["status"] = "pending"
+	}
+	return nil
+}
+
+// migrateJobToV2 adds the duration of finished jobs, which was only
+// derivable from their timestamps before
+func migrateJobToV2(record map[string]interface{}) error {
+	if _, exists := record["duration_seconds"]; exists {
+		return nil
+	}
+	var timestamps [2]*time.Time
+	for i, key := range []string{"started_at", "completed_at"} {
+		value, ok := record[key].(string)
+		if !ok {
+			return nil
+		}
+		parsed, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %v", key, err)
+		}
+		timestamps[i] = &parsed
+	}
+	if duration := jobDurationSeconds(timestamps[0], timestamps[1]); duration > 0 {
+		record["duration_seconds"] = duration
+	}
+	return nil
+}
+
+// migrateJobData decodes a stored job, applying any migrations its schema
+// version needs. It reports whether the record was migrated.
+func migrateJobData(data []byte) (*Job, bool, error) {
+	var record map[string]interface{}
+	if err := unmarshalContextJSON(data, &record); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal job: %v", err)
+	}
+
+	version := 0
+	if value, ok := jsonInteger(record["schema_version"]); ok {
+		version = int(value)
+	}
+	if version > currentJobSchemaVersion {
+		return nil, false, fmt.Errorf("job schema version %d is newer than supported version %d", version, currentJobSchemaVersion)
+	}
+
+	var job Job
+	if version == currentJobSchemaVersion {
+		if err := unmarshalContextJSON(data, &job); err != nil {
+			return nil, false, fmt.Errorf("failed to unmarshal job: %v", err)
+		}
+		job.Playbook = floatPlaybookNumbers(job.Playbook)
+		return &job, false, nil
+	}
+
+	for ; version < currentJobSchemaVersion; version++ {
+		if err := jobMigrations[version](record); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate job to schema version %d: %v", version+1, err)
+		}
+	}
+	record["schema_version"] = currentJobSchemaVersion
+
+	migrated, err := json.Marshal(record)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to marshal migrated job: %v", err)
+	}
+	if err := unmarshalContextJSON(migrated, &job); err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal migrated job: %v", err)
+	}
+	job.Playbook = floatPlaybookNumbers(job.Playbook)
+	return &job, true, nil
+}
+
+// JobMigrationReport summarizes a bulk job migration
+type JobMigrationReport struct {
+	Scanned  int `json:"scanned"`
+	Migrated int `json:"migrated"`
+	Failed   int `json:"failed"`
+}
+
+// MigrateJobs rewrites every stored job at the current schema version,
+// keeping each job's remaining TTL. progress, if set, is called periodically
+// and once at the end.
+func (rjs *RedisJobStore) MigrateJobs(progress func(report JobMigrationReport)) (JobMigrationReport, error) {
+	var report JobMigrationReport
+
+	iter := rjs.client.Scan(rjs.ctx, 0, "job:*", jobMigrationProgressInterval).Iterator()
+	for iter.Next(rjs.ctx) {
+		key := iter.Val()
+		report.Scanned++
+
+		data, err := rjs.client.Get(rjs.ctx, key).Bytes()
+		if err == redis.Nil {
+			// Expired between the scan and the read
+			continue
+		}
+		if err != nil {
+			report.Failed++
+			logger.Error("Failed to read job for migration", map[string]interface{}{
+				"component": "job_store",
+				"key":       key,
+				"error":     err.Error(),
+			})
+			continue
+		}
+
+		job, migrated, err := migrateJobData(data)
+		if err != nil {
+			report.Failed++
+			logger.Error("Failed to migrate job", map[string]interface{}{
+				"component": "job_store",
+				"key":       key,
+				"error":     err.Error(),
+			})
+			continue
+		}
+
+		if migrated {
+			encoded, err := json.Marshal(job)
+			if err == nil {
+				err = rjs.client.Set(rjs.ctx, key, encoded, redis.KeepTTL).Err()
+			}
+			if err != nil {
+				report.Failed++
+				logger.Error("Failed to save migrated job", map[string]interface{}{
+					"component": "job_store",
+					"job_id":    job.ID,
+					"error":     err.Error(),
+				})
+				continue
+			}
+			report.Migrated++
+		}
+
+		if progress != nil && report.Scanned%jobMigrationProgressInterval == 0 {
+			progress(report)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return report, fmt.Errorf("failed to scan jobs: %v", err)
+	}
+
+	if progress != nil {
+		progress(report)
+	}
+	return report, nil
+}
+
+// runJobMigration migrates all jobs in the configured store to the current
+// schema version, printing progress, and returns the process exit code
+func runJobMigration(config *Config) int {
+	if config.Database.JobStore != "" && config.Database.JobStore != "redis" {
+		fmt.Fprintf(os.Stderr, "Error: -migrate-jobs only applies to the redis job store, not %s\n", config.Database.JobStore)
+		return 1
+	}
+	store, err := NewRedisJobStore(config.Database)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	fmt.Printf("Migrating jobs to schema version %d...\n", currentJobSchemaVersion)
+	start := time.Now()
+	report, err := store.MigrateJobs(func(report JobMigrationReport) {
+		fmt.Printf("  scanned %d, migrated %d, failed %d\n", report.Scanned, report.Migrated, report.Failed)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Migration finished in %s: %d scanned, %d migrated, %d failed\n",
+		time.Since(start).Round(time.Millisecond), report.Scanned, report.Migrated, report.Failed)
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
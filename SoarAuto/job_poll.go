@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// defaultJobPollTimeout is used when a poll does not give a timeout
+	defaultJobPollTimeout = 30 * time.Second
+	// maxJobPollTimeout bounds how long a poll may hold its connection
+	maxJobPollTimeout = 120 * time.Second
+	// jobPollInterval is how often a waiting poll checks the job's version
+	jobPollInterval = 250 * time.Millisecond
+)
+
+// parseJobWait reads the wait query parameter of GET /job/{id}, the number
+// of seconds to hold the request for the job to change status; 0 when absent
+func parseJobWait(r *http.Request) (time.Duration, error) {
+	value := r.URL.Query().Get("wait")
+	if value == "" {
+		return 0, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxJobPollTimeout {
+		return 0, fmt.Errorf("wait must be a number of seconds between 0 and %d", int(maxJobPollTimeout.Seconds()))
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// waitForJobStatus holds a request until the job's status differs from that
+// of job, which is returned as it then is, or until wait has passed, when the
+// current state is returned. It wakes on the job's activity events rather
+// than polling the store. It returns nil if the client goes away.
+func (s *SecAutoServer) waitForJobStatus(w http.ResponseWriter, r *http.Request, job *Job, wait time.Duration) *Job {
+	_, events, cancel := eventBus.Subscribe(eventFilter{jobID: job.ID}, "")
+	defer cancel()
+
+	// A wait can outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	latest := job
+	reload := func() bool {
+		if current, exists := s.jobManager.GetJob(job.ID); exists {
+			latest = current
+		}
+		return latest.Status != job.Status
+	}
+
+	// The job may have moved on before the subscription started
+	if reload() {
+		return latest
+	}
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	for {
+		select {
+		case <-events:
+			if reload() {
+				return latest
+			}
+		case <-deadline.C:
+			reload()
+			return latest
+		case <-r.Context().Done():
+			return nil
+		}
+	}
+}
+
+// jobPollHandler handles GET /job/{id}/poll?timeout=30&since_version=5. It
+// returns the job as soon as its version is newer than since_version, and
+// 304 Not Modified if nothing changes within the timeout.
+func (s *SecAutoServer) jobPollHandler(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	timeout := defaultJobPollTimeout
+	if value := query.Get("timeout"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxJobPollTimeout {
+			http.Error(w, fmt.Sprintf("timeout must be a number of seconds between 0 and %d", int(maxJobPollTimeout.Seconds())), http.StatusBadRequest)
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+
+	var sinceVersion int64
+	if value := query.Get("since_version"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || parsed < 0 {
+			http.Error(w, "since_version must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		sinceVersion = parsed
+	}
+
+	if _, exists := s.jobManager.GetJob(jobID); !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	// A poll can outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		version, err := s.jobManager.GetJobVersion(jobID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get job version: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		if version > sinceVersion {
+			job, exists := s.jobManager.GetJob(jobID)
+			if !exists {
+				http.Error(w, "Job not found", http.StatusNotFound)
+				return
+			}
+
+			response := JobPollResponse{
+				Success:   true,
+				JobID:     jobID,
+				Version:   version,
+				Job:       outputRedactor.RedactJob(job),
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Job-Version", strconv.FormatInt(version, 10))
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			w.Header().Set("X-Job-Version", strconv.FormatInt(version, 10))
+			w.WriteHeader(http.StatusNotModified)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// jobDurationSamples is how many recent job run times the queue averages for
+// its estimates
+const jobDurationSamples = 50
+
+// jobPriorityRanks orders job priorities; higher runs first
+var jobPriorityRanks = map[string]int{
+	"low":      0,
+	"normal":   1,
+	"high":     2,
+	"critical": 3,
+}
+
+// queuedJob is a job waiting for a worker
+type queuedJob struct {
+	jobID    string
+	rank     int
+	sequence uint64
+	queuedAt time.Time
+	ready    chan bool // Receives true when the job may start, false when it was removed
+}
+
+// JobQueue hands the job manager's workers to pending jobs in priority order,
+// and first come first served within a priority
+type JobQueue struct {
+	mutex     sync.Mutex
+	workers   int
+	running   int
+	waiting   []*queuedJob
+	sequence  uint64
+	durations []time.Duration // Run times of recently finished jobs, oldest first
+}
+
+// JobQueuePosition describes where a pending job waits. It is computed when
+// asked for, from the queue at that moment.
+type JobQueuePosition struct {
+	Position           int     `json:"position"` // 1 for the next job to start
+	QueueLength        int     `json:"queue_length"`
+	Workers            int     `json:"workers"`
+	Running            int     `json:"running"`
+	AvgDurationSeconds float64 `json:"avg_duration_seconds"`
+	ETASeconds         float64 `json:"eta_seconds"`
+	EstimatedStart     string  `json:"estimated_start,omitempty"`
+}
+
+// NewJobQueue creates a queue running up to workers jobs at a time
+func NewJobQueue(workers int) *JobQueue {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &JobQueue{workers: workers}
+}
+
+// Acquire blocks until the job may take a worker. It returns false when the
+// job was removed from the queue while waiting, and then holds no worker.
+func (jq *JobQueue) Acquire(jobID, priority string) bool {
+	jq.mutex.Lock()
+	if jq.running < jq.workers && len(jq.waiting) == 0 {
+		jq.running++
+		jq.mutex.Unlock()
+		return true
+	}
+	jq.sequence++
+	entry := &queuedJob{
+		jobID:    jobID,
+		rank:     jobPriorityRanks[priority],
+		sequence: jq.sequence,
+		queuedAt: time.Now(),
+		ready:    make(chan bool, 1),
+	}
+	jq.waiting = append(jq.waiting, entry)
+	jq.sortWaiting()
+	jq.mutex.Unlock()
+
+	return <-entry.ready
+}
+
+// Release frees the worker of a job that ran for duration and starts the next
+// waiting job
+func (jq *JobQueue) Release(duration time.Duration) {
+	jq.mutex.Lock()
+	defer jq.mutex.Unlock()
+
+	jq.durations = append(jq.durations, duration)
+	if len(jq.durations) > jobDurationSamples {
+		jq.durations = jq.durations[len(jq.durations)-jobDurationSamples:]
+	}
+
+	jq.running--
+	if len(jq.waiting) > 0 {
+		next := jq.waiting[0]
+		jq.waiting = jq.waiting[1:]
+		jq.running++
+		next.ready <- true
+	}
+}
+
+// Remove takes a waiting job off the queue, as when it is cancelled. It
+// reports whether the job was waiting.
+func (jq *JobQueue) Remove(jobID string) bool {
+	jq.mutex.Lock()
+	defer jq.mutex.Unlock()
+
+	for i, entry := range jq.waiting {
+		if entry.jobID == jobID {
+			jq.waiting = append(jq.waiting[:i], jq.waiting[i+1:]...)
+			entry.ready <- false
+			return true
+		}
+	}
+	return false
+}
+
+// sortWaiting orders the waiting jobs by priority, then submission
+func (jq *JobQueue) sortWaiting() {
+	sort.SliceStable(jq.waiting, func(i, j int) bool {
+		if jq.waiting[i].rank != jq.waiting[j].rank {
+			return jq.waiting[i].rank > jq.waiting[j].rank
+		}
+		return jq.waiting[i].sequence < jq.waiting[j].sequence
+	})
+}
+
+// averageDuration returns the mean run time of recently finished jobs, or
+// zero when none has finished yet. The caller holds the mutex.
+func (jq *JobQueue) averageDuration() time.Duration {
+	if len(jq.durations) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, duration := range jq.durations {
+		total += duration
+	}
+	return total / time.Duration(len(jq.durations))
+}
+
+// Position returns where a job waits. fallbackAverage supplies an average run
+// time when no job has finished since startup. It reports false when the job
+// is not waiting in this queue.
+func (jq *JobQueue) Position(jobID string, fallbackAverage func() time.Duration) (*JobQueuePosition, bool) {
+	jq.mutex.Lock()
+	position := 0
+	for i, entry := range jq.waiting {
+		if entry.jobID == jobID {
+			position = i + 1
+			break
+		}
+	}
+	queueLength, running, workers := len(jq.waiting), jq.running, jq.workers
+	average := jq.averageDuration()
+	jq.mutex.Unlock()
+
+	if position == 0 {
+		return nil, false
+	}
+	if average == 0 && fallbackAverage != nil {
+		average = fallbackAverage()
+	}
+
+	// The job starts once the jobs ahead of it have taken a worker, that is
+	// after this many of the running and waiting jobs have finished, which
+	// happens at about workers jobs per average run time
+	finishes := running + position - workers
+	eta := 0.0
+	if finishes > 0 {
+		eta = float64(finishes) * average.Seconds() / float64(workers)
+	}
+
+	result := &JobQueuePosition{
+		Position:           position,
+		QueueLength:        queueLength,
+		Workers:            workers,
+		Running:            running,
+		AvgDurationSeconds: math.Round(average.Seconds()*1000) / 1000,
+		ETASeconds:         math.Round(eta*1000) / 1000,
+	}
+	if average > 0 {
+		result.EstimatedStart = time.Now().Add(time.Duration(eta * float64(time.Second))).UTC().Format(time.RFC3339)
+	}
+	return result, true
+}
+
+// Waiting returns the IDs of the waiting jobs in the order they will start,
+// and when the longest waiting one was queued (zero when none waits)
+func (jq *JobQueue) Waiting() ([]string, time.Time) {
+	jq.mutex.Lock()
+	defer jq.mutex.Unlock()
+
+	jobIDs := make([]string, len(jq.waiting))
+	var oldest time.Time
+	for i, entry := range jq.waiting {
+		jobIDs[i] = entry.jobID
+		if oldest.IsZero() || entry.queuedAt.Before(oldest) {
+			oldest = entry.queuedAt
+		}
+	}
+	return jobIDs, oldest
+}
+
+// Stats returns the queue's current length and worker usage
+func (jq *JobQueue) Stats() map[string]interface{} {
+	jq.mutex.Lock()
+	defer jq.mutex.Unlock()
+
+	return map[string]interface{}{
+		"workers":              jq.workers,
+		"running":              jq.running,
+		"waiting":              len(jq.waiting),
+		"avg_duration_seconds": math.Round(jq.averageDuration().Seconds()*1000) / 1000,
+	}
+}
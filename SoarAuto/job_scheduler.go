@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -43,11 +44,15 @@ type JobSchedule struct {
 	LastRun         *time.Time             `json:"last_run,omitempty"`
 	RunCount        int                    `json:"run_count"`
 	MaxRuns         int                    `json:"max_runs,omitempty"`
+	MisfirePolicy   MisfirePolicy          `json:"misfire_policy,omitempty"`    // skip (default), fire_once or fire_all
+	MaxCatchUpRuns  int                    `json:"max_catch_up_runs,omitempty"` // Bound for fire_all; scheduler default when 0
+	MissedRuns      int                    `json:"missed_runs,omitempty"`       // Runs missed while down and not made up
 	Status          ScheduleStatus         `json:"status"`
 	Playbook        []interface{}          `json:"playbook"`
 	Context         map[string]interface{} `json:"context"`
 	Priority        int                    `json:"priority"`
 	Tags            []string               `json:"tags"`
+	Revision        int                    `json:"revision"`
 	CreatedAt       time.Time              `json:"created_at"`
 	UpdatedAt       time.Time              `json:"updated_at"`
 	CreatedBy       string                 `json:"created_by"`
@@ -60,6 +65,7 @@ type JobScheduler struct {
 	config         *SchedulerConfig
 	cronScheduler  *cron.Cron
 	schedules      map[string]*JobSchedule
+	entries        map[string]cron.EntryID // Cron entries of registered schedules, by schedule ID
 	mutex          sync.RWMutex
 	ctx            context.Context
 	cancel         context.CancelFunc
@@ -77,6 +83,7 @@ func NewJobScheduler(config *SchedulerConfig, server *SecAutoServer) (*JobSchedu
 		config:         config,
 		cronScheduler:  cron.New(cron.WithSeconds()),
 		schedules:      make(map[string]*JobSchedule),
+		entries:        make(map[string]cron.EntryID),
 		ctx:            ctx,
 		cancel:         cancel,
 		jobStore:       server.jobManager.store,
@@ -110,7 +117,15 @@ func (js *JobScheduler) loadSchedules() error {
 	schedules := js.jobStore.ListSchedules("", 0) // Load all schedules
 
 	for _, schedule := range schedules {
-		if schedule.Status == ScheduleStatusActive {
+		if schedule.Status == ScheduleStatusDeleted {
+			continue
+		}
+		if schedule.Revision == 0 {
+			schedule.Revision = 1
+		}
+		// Runs that fell due while the server was down are handled by the
+		// schedule's misfire policy before it is registered again
+		if schedule.Status == ScheduleStatusActive && js.catchUpMissedRuns(schedule) {
 			if err := js.addScheduleToCron(schedule); err != nil {
 				js.logger.Error("Failed to add schedule to cron", map[string]interface{}{
 					"component":   "job_scheduler",
@@ -141,8 +156,8 @@ func (js *JobScheduler) addScheduleToCron(schedule *JobSchedule) error {
 			return fmt.Errorf("failed to add cron schedule: %v", err)
 		}
 
-		// Store entry ID for later removal
-		schedule.ID = fmt.Sprintf("%d", entryID)
+		// Keep the entry ID for later removal
+		js.entries[schedule.ID] = entryID
 
 	case ScheduleTypeInterval:
 		if schedule.IntervalSeconds <= 0 {
@@ -156,7 +171,7 @@ func (js *JobScheduler) addScheduleToCron(schedule *JobSchedule) error {
 			return fmt.Errorf("failed to add interval schedule: %v", err)
 		}
 
-		schedule.ID = fmt.Sprintf("%d", entryID)
+		js.entries[schedule.ID] = entryID
 
 	case ScheduleTypeOnce:
 		if schedule.StartTime == nil {
@@ -185,12 +200,22 @@ func (js *JobScheduler) addScheduleToCron(schedule *JobSchedule) error {
 			return fmt.Errorf("failed to add recurring schedule: %v", err)
 		}
 
-		schedule.ID = fmt.Sprintf("%d", entryID)
+		js.entries[schedule.ID] = entryID
 	}
 
 	return nil
 }
 
+// removeScheduleFromCron removes a schedule's cron entry, if it has one. A
+// once schedule's timer cannot be removed; it checks the schedule's status
+// when it fires instead.
+func (js *JobScheduler) removeScheduleFromCron(scheduleID string) {
+	if entryID, exists := js.entries[scheduleID]; exists {
+		js.cronScheduler.Remove(entryID)
+		delete(js.entries, scheduleID)
+	}
+}
+
 // executeScheduledJob executes a scheduled job
 func (js *JobScheduler) executeScheduledJob(schedule *JobSchedule) {
 	js.logger.Info("Executing scheduled job", map[string]interface{}{
@@ -235,10 +260,11 @@ func (js *JobScheduler) executeScheduledJob(schedule *JobSchedule) {
 
 	if js.clusterManager != nil {
 		// Submit to distributed queue
-		jobID, err = js.clusterManager.SubmitJob(schedule.Playbook, schedule.Context)
+		jobID, err = js.clusterManager.SubmitJob(schedule.Playbook, schedule.Context, nil)
 	} else {
 		// Submit to local job manager
-		jobID = js.server.jobManager.SubmitJob(schedule.Playbook, schedule.Context)
+		source := JobSource{TriggeredBy: "schedule:" + schedule.ID}
+		jobID = js.server.jobManager.SubmitJobFrom(source, schedule.Playbook, schedule.Context, nil, defaultJobPriority)
 	}
 
 	if err != nil {
@@ -258,6 +284,16 @@ func (js *JobScheduler) executeScheduledJob(schedule *JobSchedule) {
 
 	js.updateSchedule(schedule)
 
+	eventBus.Publish(ActivityEvent{
+		Type:       "schedule_fired",
+		JobID:      jobID,
+		ScheduleID: schedule.ID,
+		Details: map[string]interface{}{
+			"schedule_name": schedule.Name,
+			"run_count":     schedule.RunCount,
+		},
+	})
+
 	js.logger.Info("Scheduled job submitted successfully", map[string]interface{}{
 		"component":   "job_scheduler",
 		"schedule_id": schedule.ID,
@@ -317,25 +353,28 @@ func (js *JobScheduler) CreateSchedule(schedule *JobSchedule) error {
 		schedule.CreatedAt = time.Now()
 	}
 	schedule.UpdatedAt = time.Now()
+	schedule.Revision = 1
 
 	// Calculate next run time
 	schedule.NextRun = js.calculateNextRun(schedule)
 
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	// Store in the database first, so a schedule that cannot be kept never fires
+	if err := js.jobStore.SaveSchedule(schedule); err != nil {
+		return fmt.Errorf("failed to save schedule: %v", err)
+	}
+
 	// Add to cron scheduler if active
 	if schedule.Status == ScheduleStatusActive {
 		if err := js.addScheduleToCron(schedule); err != nil {
+			js.jobStore.DeleteSchedule(schedule.ID)
 			return fmt.Errorf("failed to add schedule to cron: %v", err)
 		}
 	}
 
-	// Store in memory and database
-	js.mutex.Lock()
 	js.schedules[schedule.ID] = schedule
-	js.mutex.Unlock()
-
-	if err := js.jobStore.SaveSchedule(schedule); err != nil {
-		return fmt.Errorf("failed to save schedule: %v", err)
-	}
 
 	js.logger.Info("Schedule created", map[string]interface{}{
 		"component":     "job_scheduler",
@@ -347,7 +386,8 @@ func (js *JobScheduler) CreateSchedule(schedule *JobSchedule) error {
 	return nil
 }
 
-// UpdateSchedule updates an existing schedule
+// UpdateSchedule updates an existing schedule. When schedule.Revision is non-zero it
+// must match the stored revision, otherwise errRevisionConflict is returned.
 func (js *JobScheduler) UpdateSchedule(schedule *JobSchedule) error {
 	js.mutex.Lock()
 	defer js.mutex.Unlock()
@@ -357,6 +397,18 @@ func (js *JobScheduler) UpdateSchedule(schedule *JobSchedule) error {
 		return fmt.Errorf("schedule not found: %s", schedule.ID)
 	}
 
+	// Optimistic concurrency check
+	if schedule.Revision != 0 && schedule.Revision != existing.Revision {
+		return errRevisionConflict
+	}
+
+	if err := js.validateSchedulePlaybook(schedule.Playbook); err != nil {
+		return fmt.Errorf("invalid schedule: %v", err)
+	}
+
+	// A once schedule's timer stays set and fires only if it is still active
+	wasOnce := existing.ScheduleType == ScheduleTypeOnce
+
 	// Update fields
 	existing.Name = schedule.Name
 	existing.Description = schedule.Description
@@ -366,12 +418,15 @@ func (js *JobScheduler) UpdateSchedule(schedule *JobSchedule) error {
 	existing.StartTime = schedule.StartTime
 	existing.EndTime = schedule.EndTime
 	existing.MaxRuns = schedule.MaxRuns
+	existing.MisfirePolicy = schedule.MisfirePolicy
+	existing.MaxCatchUpRuns = schedule.MaxCatchUpRuns
 	existing.Status = schedule.Status
 	existing.Playbook = schedule.Playbook
 	existing.Context = schedule.Context
 	existing.Priority = schedule.Priority
 	existing.Tags = schedule.Tags
 	existing.UpdatedAt = time.Now()
+	existing.Revision++
 
 	// Recalculate next run
 	existing.NextRun = js.calculateNextRun(existing)
@@ -381,6 +436,14 @@ func (js *JobScheduler) UpdateSchedule(schedule *JobSchedule) error {
 		return fmt.Errorf("failed to update schedule: %v", err)
 	}
 
+	// Register the schedule again, as its timing or status may have changed
+	js.removeScheduleFromCron(existing.ID)
+	if existing.Status == ScheduleStatusActive && !(wasOnce && existing.ScheduleType == ScheduleTypeOnce) {
+		if err := js.addScheduleToCron(existing); err != nil {
+			return fmt.Errorf("failed to add schedule to cron: %v", err)
+		}
+	}
+
 	js.logger.Info("Schedule updated", map[string]interface{}{
 		"component":     "job_scheduler",
 		"schedule_id":   existing.ID,
@@ -409,7 +472,8 @@ func (js *JobScheduler) DeleteSchedule(scheduleID string) error {
 		return fmt.Errorf("failed to update schedule: %v", err)
 	}
 
-	// Remove from memory
+	// Remove from memory and stop it firing
+	js.removeScheduleFromCron(scheduleID)
 	delete(js.schedules, scheduleID)
 
 	js.logger.Info("Schedule deleted", map[string]interface{}{
@@ -436,6 +500,7 @@ func (js *JobScheduler) PauseSchedule(scheduleID string) error {
 	if err := js.jobStore.UpdateSchedule(schedule); err != nil {
 		return fmt.Errorf("failed to update schedule: %v", err)
 	}
+	js.removeScheduleFromCron(scheduleID)
 
 	js.logger.Info("Schedule paused", map[string]interface{}{
 		"component":   "job_scheduler",
@@ -464,6 +529,11 @@ func (js *JobScheduler) ResumeSchedule(scheduleID string) error {
 	if err := js.jobStore.UpdateSchedule(schedule); err != nil {
 		return fmt.Errorf("failed to update schedule: %v", err)
 	}
+	if _, registered := js.entries[scheduleID]; !registered && schedule.ScheduleType != ScheduleTypeOnce {
+		if err := js.addScheduleToCron(schedule); err != nil {
+			return fmt.Errorf("failed to add schedule to cron: %v", err)
+		}
+	}
 
 	js.logger.Info("Schedule resumed", map[string]interface{}{
 		"component":   "job_scheduler",
@@ -559,8 +629,15 @@ func (js *JobScheduler) validateSchedule(schedule *JobSchedule) error {
 		return fmt.Errorf("schedule name is required")
 	}
 
-	if len(schedule.Playbook) == 0 {
-		return fmt.Errorf("playbook is required")
+	if err := js.validateSchedulePlaybook(schedule.Playbook); err != nil {
+		return err
+	}
+
+	if !validMisfirePolicy(schedule.MisfirePolicy) {
+		return fmt.Errorf("invalid misfire policy: %s (expected skip, fire_once or fire_all)", schedule.MisfirePolicy)
+	}
+	if schedule.MaxCatchUpRuns < 0 {
+		return fmt.Errorf("max_catch_up_runs cannot be negative")
 	}
 
 	switch schedule.ScheduleType {
@@ -596,6 +673,27 @@ func (js *JobScheduler) validateSchedule(schedule *JobSchedule) error {
 	return nil
 }
 
+// validateSchedulePlaybook rejects a playbook that is structurally invalid or
+// references automations, playbooks, plugins or macros that do not exist, so
+// that a schedule cannot be saved that would fail every time it fires
+func (js *JobScheduler) validateSchedulePlaybook(playbook []interface{}) error {
+	if len(playbook) == 0 {
+		return fmt.Errorf("playbook is required")
+	}
+
+	// Macro references are not checked when the macros cannot be loaded
+	macros, _ := js.server.engine.LoadMacros()
+	errors := js.server.lintSchedulePlaybook(playbook, macros)
+	if len(errors) == 0 {
+		return nil
+	}
+	messages := make([]string, len(errors))
+	for i, validationErr := range errors {
+		messages[i] = validationErr.Message
+	}
+	return fmt.Errorf("invalid playbook: %s", strings.Join(messages, "; "))
+}
+
 // updateSchedule updates a schedule in the database
 func (js *JobScheduler) updateSchedule(schedule *JobSchedule) {
 	js.mutex.Lock()
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestScheduler creates a scheduler over store whose submitted jobs stay
+// pending: the job manager's only worker is taken
+func newTestScheduler(t *testing.T, store JobStoreInterface) *JobScheduler {
+	t.Helper()
+	queue := NewJobQueue(1)
+	queue.running = 1
+	server := &SecAutoServer{
+		engine:     NewRuleEngine(&Config{}),
+		jobManager: &JobManager{store: store, queue: queue},
+	}
+	scheduler, err := NewJobScheduler(&SchedulerConfig{Enabled: true, CleanupInterval: 3600}, server)
+	if err != nil {
+		t.Fatalf("NewJobScheduler: %v", err)
+	}
+	t.Cleanup(func() { scheduler.Close() })
+	return scheduler
+}
+
+// testSchedulePlaybook is a playbook that references no assets
+func testSchedulePlaybook() []interface{} {
+	return []interface{}{
+		map[string]interface{}{"if": map[string]interface{}{
+			"conditions": []interface{}{map[string]interface{}{"==": []interface{}{1, 1}}},
+			"true":       []interface{}{},
+		}},
+	}
+}
+
+func TestSchedulerPersistsSchedulesInStore(t *testing.T) {
+	for name, store := range scheduleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			scheduler := newTestScheduler(t, store)
+			schedule := &JobSchedule{
+				Name:            "hourly sweep",
+				ScheduleType:    ScheduleTypeInterval,
+				IntervalSeconds: 3600,
+				Playbook:        testSchedulePlaybook(),
+			}
+			if err := scheduler.CreateSchedule(schedule); err != nil {
+				t.Fatalf("CreateSchedule: %v", err)
+			}
+			if !strings.HasPrefix(schedule.ID, "schedule_") {
+				t.Errorf("schedule ID = %q, want the generated ID kept", schedule.ID)
+			}
+			stored, exists := store.LoadSchedule(schedule.ID)
+			if !exists || stored.Name != "hourly sweep" || stored.Revision != 1 {
+				t.Fatalf("stored schedule = %+v, %v", stored, exists)
+			}
+
+			update := *stored
+			update.IntervalSeconds = 1800
+			if err := scheduler.UpdateSchedule(&update); err != nil {
+				t.Fatalf("UpdateSchedule: %v", err)
+			}
+			if stored, _ := store.LoadSchedule(schedule.ID); stored.IntervalSeconds != 1800 || stored.Revision != 2 {
+				t.Errorf("stored schedule after update = %+v", stored)
+			}
+
+			invalid := update
+			invalid.Revision = 2
+			invalid.Playbook = []interface{}{map[string]interface{}{"run": "no_such_automation_for_tests"}}
+			if err := scheduler.UpdateSchedule(&invalid); err == nil || !strings.Contains(err.Error(), "invalid schedule") {
+				t.Errorf("UpdateSchedule with a missing automation = %v, want a validation error", err)
+			}
+
+			if err := scheduler.DeleteSchedule(schedule.ID); err != nil {
+				t.Fatalf("DeleteSchedule: %v", err)
+			}
+			if _, registered := scheduler.entries[schedule.ID]; registered {
+				t.Errorf("deleted schedule is still registered with cron")
+			}
+
+			// A scheduler started over the same store does not bring it back
+			if reloaded := newTestScheduler(t, store); len(reloaded.ListSchedules("", 0)) != 0 {
+				t.Errorf("deleted schedule was loaded again")
+			}
+		})
+	}
+}
+
+func TestSchedulerReloadKeepsScheduleIDs(t *testing.T) {
+	store := NewMemoryJobStore()
+	first := newTestScheduler(t, store)
+	for _, name := range []string{"a", "b"} {
+		schedule := &JobSchedule{Name: name, ScheduleType: ScheduleTypeInterval, IntervalSeconds: 3600, Playbook: testSchedulePlaybook()}
+		if err := first.CreateSchedule(schedule); err != nil {
+			t.Fatalf("CreateSchedule(%s): %v", name, err)
+		}
+		time.Sleep(time.Millisecond) // Distinct generated IDs
+	}
+	first.Close()
+
+	second := newTestScheduler(t, store)
+	for _, stored := range store.ListSchedules("", 0) {
+		if _, exists := second.GetSchedule(stored.ID); !exists {
+			t.Errorf("schedule %s was not loaded under its ID", stored.ID)
+		}
+	}
+	if len(second.entries) != 2 {
+		t.Errorf("%d schedules registered with cron after reload, want 2", len(second.entries))
+	}
+}
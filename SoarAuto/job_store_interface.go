@@ -1,7 +1,10 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"math/rand"
+	"sort"
 	"time"
 )
 
@@ -11,10 +14,19 @@ type JobStoreInterface interface {
 	SaveJob(job *Job) error
 	LoadJob(jobID string) (*Job, bool)
 	ListJobs(status string, limit int) []*Job
+	ListPlaybookRuns(playbookName string, limit int) []*Job
 	UpdateJobStatus(jobID, status string) error
 	UpdateJobResults(jobID string, results []interface{}, errorMsg string) error
 	UpdateJobContext(jobID string, context map[string]interface{}) error
 	DeleteJob(jobID string) error
+	GetJobVersion(jobID string) (int64, error)
+
+	// UpdateJob applies update to the stored job and saves the result. If
+	// another write changes the job first, update runs again on the newer
+	// record, so concurrent changes are never lost. update returns false to
+	// leave the job unchanged and must not call the store. The result reports
+	// whether the job was saved.
+	UpdateJob(jobID string, update func(job *Job) bool) (bool, error)
 
 	// Maintenance operations
 	CleanupOldJobs(maxAge time.Duration) error
@@ -26,7 +38,7 @@ type JobStoreInterface interface {
 	// Database metrics
 	GetDatabaseMetrics() map[string]interface{}
 
-	// Schedule operations (optional - may return errors if not implemented)
+	// Schedule operations
 	SaveSchedule(schedule *JobSchedule) error
 	LoadSchedule(scheduleID string) (*JobSchedule, bool)
 	ListSchedules(status string, limit int) []*JobSchedule
@@ -35,11 +47,268 @@ type JobStoreInterface interface {
 	GetSchedulesDueForExecution() []*JobSchedule
 }
 
-// NewJobStore creates a job store based on configuration
+// NewJobStore creates the job store selected by database.job_store. Redis is
+// the default; memory and sqlite serve single-node and development use.
 func NewJobStore(config *Config) (JobStoreInterface, error) {
-	// Only support Redis job store
-	if config.Database.RedisURL == "" {
-		return nil, fmt.Errorf("redis URL is required for job store")
+	switch config.Database.JobStore {
+	case "", "redis":
+		if config.Database.RedisURL == "" {
+			return nil, fmt.Errorf("redis URL is required for job store")
+		}
+		return NewRedisJobStore(config.Database)
+	case "memory":
+		return NewMemoryJobStore(), nil
+	case "sqlite":
+		return NewSQLiteJobStore(config.Database)
+	default:
+		return nil, fmt.Errorf("unknown job store %q (use redis, memory or sqlite)", config.Database.JobStore)
+	}
+}
+
+// jobUpdateAttempts bounds how often UpdateJob retries a job that keeps
+// changing under it
+const jobUpdateAttempts = 10
+
+// waitBeforeJobUpdateRetry spreads out the retries of writers that keep
+// colliding on the same job
+func waitBeforeJobUpdateRetry(attempt int) {
+	time.Sleep(time.Duration(rand.Intn(attempt+1)+1) * time.Millisecond)
+}
+
+// jobNotFoundError is returned by updates of a job that does not exist
+func jobNotFoundError(jobID string) error {
+	return fmt.Errorf("job not found: %s", jobID)
+}
+
+// jobUpdateConflictError is returned when a job changed on every update attempt
+func jobUpdateConflictError(jobID string) error {
+	return fmt.Errorf("job %s kept changing while it was being updated", jobID)
+}
+
+// jobRecordStore is the part of a job store the shared update helpers need
+type jobRecordStore interface {
+	UpdateJob(jobID string, update func(job *Job) bool) (bool, error)
+}
+
+// updateStoredJobStatus sets a stored job's status and the timestamps it implies
+func updateStoredJobStatus(store jobRecordStore, jobID, status string) error {
+	_, err := store.UpdateJob(jobID, func(job *Job) bool {
+		// Update status and timestamps
+		job.Status = status
+		now := time.Now()
+
+		switch status {
+		case "running":
+			job.StartedAt = &now
+		case "completed", jobStatusCompletedWithErrors, "failed", "cancelled", abortStatusAborted, abortStatusSkipped:
+			job.setCompleted(now)
+		}
+		return true
+	})
+	return err
+}
+
+// updateStoredJobResults sets a stored job's results and error
+func updateStoredJobResults(store jobRecordStore, jobID string, results []interface{}, errorMsg string) error {
+	// Cap the size of what is stored once, outside the retried update
+	capped, truncation := resultCap.Apply(jobID, results)
+	_, err := store.UpdateJob(jobID, func(job *Job) bool {
+		job.Results, job.ResultTruncation = capped, truncation
+		job.Error = errorMsg
+		job.setCompleted(time.Now())
+		return true
+	})
+	return err
+}
+
+// updateStoredJobContext replaces a stored job's context
+func updateStoredJobContext(store jobRecordStore, jobID string, context map[string]interface{}) error {
+	_, err := store.UpdateJob(jobID, func(job *Job) bool {
+		// Keep the submitted context so the run can be diffed later
+		if job.InitialContext == nil {
+			job.InitialContext = job.Context
+		}
+		job.Context = context
+		return true
+	})
+	return err
+}
+
+// cleanupStoredJobs deletes the jobs of a store created more than maxAge ago
+func cleanupStoredJobs(store JobStoreInterface, jobIDs []string, maxAge time.Duration) error {
+	cutoff := time.Now().Add(-maxAge)
+
+	deleted := 0
+	for _, jobID := range jobIDs {
+		job, exists := store.LoadJob(jobID)
+		if !exists {
+			continue
+		}
+
+		// Check if job is older than cutoff
+		if job.CreatedAt.Before(cutoff) {
+			if err := store.DeleteJob(jobID); err != nil {
+				logger.Error("Failed to delete old job", map[string]interface{}{
+					"component": "job_store",
+					"job_id":    jobID,
+					"error":     err.Error(),
+				})
+			} else {
+				deleted++
+			}
+		}
+	}
+
+	if deleted > 0 {
+		logger.Info("Cleaned up old jobs", map[string]interface{}{
+			"component": "job_store",
+			"deleted":   deleted,
+			"max_age":   maxAge.String(),
+		})
+	}
+
+	return nil
+}
+
+// marshalSchedule serializes a schedule for storage
+func marshalSchedule(schedule *JobSchedule) ([]byte, error) {
+	if schedule.ID == "" {
+		return nil, fmt.Errorf("schedule ID is required")
+	}
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schedule: %v", err)
+	}
+	return data, nil
+}
+
+// unmarshalSchedule decodes a stored schedule, logging records that cannot be read
+func unmarshalSchedule(scheduleID string, data []byte) (*JobSchedule, bool) {
+	var schedule JobSchedule
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		logger.Error("Failed to unmarshal schedule", map[string]interface{}{
+			"component":   "job_store",
+			"schedule_id": scheduleID,
+			"error":       err.Error(),
+		})
+		return nil, false
+	}
+	return &schedule, true
+}
+
+// filterSchedules returns the schedules of a status, or all when status is
+// empty, oldest first and at most limit when limit is positive
+func filterSchedules(schedules []*JobSchedule, status string, limit int) []*JobSchedule {
+	matched := []*JobSchedule{}
+	for _, schedule := range schedules {
+		if status == "" || string(schedule.Status) == status {
+			matched = append(matched, schedule)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].ID < matched[j].ID
+		}
+		return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+	})
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+	return matched
+}
+
+// dueSchedules returns the active schedules whose next run is not after now
+func dueSchedules(schedules []*JobSchedule, now time.Time) []*JobSchedule {
+	due := []*JobSchedule{}
+	for _, schedule := range filterSchedules(schedules, string(ScheduleStatusActive), 0) {
+		if schedule.NextRun != nil && !schedule.NextRun.After(now) {
+			due = append(due, schedule)
+		}
+	}
+	return due
+}
+
+// computeJobStats summarizes a list of jobs, newest first
+func computeJobStats(jobs []*Job) JobStats {
+	var stats JobStats
+
+	stats.TotalJobs = len(jobs)
+
+	// Calculate stats
+	var totalDuration float64
+	completedCount := 0
+
+	for _, job := range jobs {
+		switch job.Status {
+		case "completed", jobStatusCompletedWithErrors:
+			if job.Status == "completed" {
+				stats.Completed++
+			} else {
+				stats.CompletedWithErrors++
+			}
+			completedCount++
+			if job.StartedAt != nil && job.CompletedAt != nil {
+				duration := job.CompletedAt.Sub(*job.StartedAt).Seconds()
+				totalDuration += duration
+			}
+		case "failed":
+			stats.Failed++
+		case abortStatusAborted:
+			stats.Aborted++
+		case abortStatusSkipped:
+			stats.Skipped++
+		case "running":
+			stats.Running++
+		case "pending":
+			stats.Pending++
+		}
+	}
+
+	// Calculate average duration
+	if completedCount > 0 {
+		stats.AvgDuration = totalDuration / float64(completedCount)
+	}
+
+	stats.ResourceUsage = computeResourceStats(jobs)
+
+	// Get recent jobs (last 10)
+	if len(jobs) > 10 {
+		stats.RecentJobs = jobs[:10]
+	} else {
+		stats.RecentJobs = jobs
+	}
+
+	return stats
+}
+
+// recoverStoredJobs fails the jobs of a store that were running during a crash
+func recoverStoredJobs(store JobStoreInterface, webhookManager *WebhookManager) {
+	// Get all running jobs
+	runningJobs := store.ListJobs("running", 1000)
+
+	if len(runningJobs) > 0 {
+		logger.Info("Recovering jobs that were running during crash", map[string]interface{}{
+			"component": "job_store",
+			"count":     len(runningJobs),
+		})
+
+		for _, job := range runningJobs {
+			// Mark as failed since we can't guarantee the state
+			store.UpdateJobStatus(job.ID, "failed")
+			store.UpdateJobResults(job.ID, nil, "Job failed due to server restart")
+
+			// Send webhook notification
+			if webhookManager != nil {
+				webhookManager.SendWebhook(WebhookEvent{
+					Event:     "job_failed",
+					JobID:     job.ID,
+					Status:    "failed",
+					Timestamp: time.Now().UTC().Format(time.RFC3339),
+					Playbook:  job.Playbook,
+					Context:   job.Context,
+					Error:     "Job failed due to server restart",
+				})
+			}
+		}
 	}
-	return NewRedisJobStore(config.Database.RedisURL)
 }
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// scheduleStores returns a fresh store of every backend that keeps schedules
+// without an external service
+func scheduleStores(t *testing.T) map[string]JobStoreInterface {
+	t.Helper()
+	sqliteStore, err := NewSQLiteJobStore(DatabaseConfig{SQLitePath: filepath.Join(t.TempDir(), "jobs.db")})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	t.Cleanup(func() { sqliteStore.Close() })
+	return map[string]JobStoreInterface{
+		"memory": NewMemoryJobStore(),
+		"sqlite": sqliteStore,
+	}
+}
+
+func TestJobStoreSchedules(t *testing.T) {
+	for name, store := range scheduleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			now := time.Now()
+			past := now.Add(-time.Minute)
+			future := now.Add(time.Hour)
+
+			due := &JobSchedule{ID: "due", Name: "due", ScheduleType: ScheduleTypeInterval, IntervalSeconds: 60,
+				Status: ScheduleStatusActive, NextRun: &past, CreatedAt: now.Add(-2 * time.Hour),
+				Playbook: []interface{}{map[string]interface{}{"run": "noop"}}, Revision: 1}
+			later := &JobSchedule{ID: "later", Name: "later", ScheduleType: ScheduleTypeInterval, IntervalSeconds: 60,
+				Status: ScheduleStatusActive, NextRun: &future, CreatedAt: now.Add(-time.Hour), Revision: 1}
+			paused := &JobSchedule{ID: "paused", Name: "paused", ScheduleType: ScheduleTypeInterval, IntervalSeconds: 60,
+				Status: ScheduleStatusPaused, NextRun: &past, CreatedAt: now, Revision: 1}
+
+			for _, schedule := range []*JobSchedule{due, later, paused} {
+				if err := store.SaveSchedule(schedule); err != nil {
+					t.Fatalf("SaveSchedule(%s): %v", schedule.ID, err)
+				}
+			}
+
+			loaded, exists := store.LoadSchedule("due")
+			if !exists || loaded.Name != "due" || loaded.IntervalSeconds != 60 || len(loaded.Playbook) != 1 {
+				t.Fatalf("LoadSchedule(due) = %+v, %v", loaded, exists)
+			}
+			if _, exists := store.LoadSchedule("missing"); exists {
+				t.Errorf("LoadSchedule(missing) found a schedule")
+			}
+
+			if ids := scheduleIDs(store.ListSchedules("", 0)); !equalStrings(ids, []string{"due", "later", "paused"}) {
+				t.Errorf("ListSchedules(all) = %v, want oldest first", ids)
+			}
+			if ids := scheduleIDs(store.ListSchedules(string(ScheduleStatusActive), 1)); !equalStrings(ids, []string{"due"}) {
+				t.Errorf("ListSchedules(active, 1) = %v", ids)
+			}
+			if ids := scheduleIDs(store.GetSchedulesDueForExecution()); !equalStrings(ids, []string{"due"}) {
+				t.Errorf("GetSchedulesDueForExecution() = %v, want only the active schedule that is due", ids)
+			}
+
+			loaded.Status = ScheduleStatusPaused
+			loaded.Revision = 2
+			if err := store.UpdateSchedule(loaded); err != nil {
+				t.Fatalf("UpdateSchedule: %v", err)
+			}
+			if updated, _ := store.LoadSchedule("due"); updated.Status != ScheduleStatusPaused || updated.Revision != 2 {
+				t.Errorf("updated schedule = %+v", updated)
+			}
+			if len(store.GetSchedulesDueForExecution()) != 0 {
+				t.Errorf("a paused schedule is still due")
+			}
+			if err := store.UpdateSchedule(&JobSchedule{ID: "missing"}); err == nil {
+				t.Errorf("UpdateSchedule of an unknown schedule succeeded")
+			}
+
+			if err := store.DeleteSchedule("later"); err != nil {
+				t.Fatalf("DeleteSchedule: %v", err)
+			}
+			if _, exists := store.LoadSchedule("later"); exists {
+				t.Errorf("deleted schedule is still stored")
+			}
+		})
+	}
+}
+
+func TestSQLiteSchedulesSurviveReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.db")
+	store, err := NewSQLiteJobStore(DatabaseConfig{SQLitePath: path})
+	if err != nil {
+		t.Fatalf("open sqlite store: %v", err)
+	}
+	if err := store.SaveSchedule(&JobSchedule{ID: "nightly", Name: "nightly", Status: ScheduleStatusActive, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("SaveSchedule: %v", err)
+	}
+	store.Close()
+
+	reopened, err := NewSQLiteJobStore(DatabaseConfig{SQLitePath: path})
+	if err != nil {
+		t.Fatalf("reopen sqlite store: %v", err)
+	}
+	defer reopened.Close()
+	if schedule, exists := reopened.LoadSchedule("nightly"); !exists || schedule.Name != "nightly" {
+		t.Errorf("schedule after reopen = %+v, %v", schedule, exists)
+	}
+}
+
+func scheduleIDs(schedules []*JobSchedule) []string {
+	ids := make([]string, len(schedules))
+	for i, schedule := range schedules {
+		ids[i] = schedule.ID
+	}
+	return ids
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestUpdateJobKeepsConcurrentUpdates(t *testing.T) {
+	for name, store := range allScheduleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := store.SaveJob(&Job{ID: "counted", Status: "pending", CreatedAt: time.Now()}); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+
+			const writers, increments = 4, 10
+			var wg sync.WaitGroup
+			errors := make(chan error, writers*increments)
+			for i := 0; i < writers; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for j := 0; j < increments; j++ {
+						_, err := store.UpdateJob("counted", func(job *Job) bool {
+							if job.Metadata == nil {
+								job.Metadata = make(map[string]interface{})
+							}
+							count, _ := jsonInteger(job.Metadata["count"])
+							job.Metadata["count"] = count + 1
+							return true
+						})
+						if err != nil {
+							errors <- err
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			close(errors)
+			for err := range errors {
+				t.Errorf("update: %v", err)
+			}
+
+			job, _ := store.LoadJob("counted")
+			if count, _ := jsonInteger(job.Metadata["count"]); count != writers*increments {
+				t.Errorf("count = %v, want %d", job.Metadata["count"], writers*increments)
+			}
+		})
+	}
+}
+
+func TestUpdateJobRetriesAfterAnotherWrite(t *testing.T) {
+	for name, store := range allScheduleStores(t) {
+		if name == "memory" {
+			// The memory store holds its lock during the update, so no
+			// other write can come in between
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			if err := store.SaveJob(&Job{ID: "raced", Status: "pending", CreatedAt: time.Now()}); err != nil {
+				t.Fatalf("save: %v", err)
+			}
+
+			calls := 0
+			saved, err := store.UpdateJob("raced", func(job *Job) bool {
+				calls++
+				if calls == 1 {
+					// Another writer cancels the job after it was read
+					cancelled := *job
+					cancelled.Status = "cancelled"
+					if err := store.SaveJob(&cancelled); err != nil {
+						t.Fatalf("concurrent save: %v", err)
+					}
+				}
+				if job.Status != "pending" {
+					return false
+				}
+				job.Metadata = map[string]interface{}{"flagged": true}
+				return true
+			})
+			if err != nil {
+				t.Fatalf("update: %v", err)
+			}
+			if saved || calls != 2 {
+				t.Errorf("saved = %v after %d calls, want the stale write dropped and the update rerun", saved, calls)
+			}
+
+			job, _ := store.LoadJob("raced")
+			if job.Status != "cancelled" || job.Metadata["flagged"] != nil {
+				t.Errorf("job = %s %v, want the concurrent cancel kept", job.Status, job.Metadata)
+			}
+		})
+	}
+}
+
+func TestUpdateJobReportsMissingJobs(t *testing.T) {
+	for name, store := range allScheduleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			called := false
+			saved, err := store.UpdateJob("missing", func(job *Job) bool {
+				called = true
+				return true
+			})
+			if err == nil || saved || called {
+				t.Errorf("update of a missing job = %v, %v (update called: %v)", saved, err, called)
+			}
+		})
+	}
+}
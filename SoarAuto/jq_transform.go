@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/itchyny/gojq"
+)
+
+const (
+	// jqQueryTimeout bounds how long one jq query may run
+	jqQueryTimeout = 5 * time.Second
+	// maxJQOutputs bounds the number of values one jq query may produce
+	maxJQOutputs = 10000
+)
+
+// jqQueries caches compiled jq queries by their source
+var jqQueries sync.Map
+
+// compileJQQuery parses and compiles a jq query, reusing earlier compilations
+func compileJQQuery(query string) (*gojq.Code, error) {
+	if code, ok := jqQueries.Load(query); ok {
+		return code.(*gojq.Code), nil
+	}
+
+	parsed, err := gojq.Parse(query)
+	if err != nil {
+		if parseErr, ok := err.(*gojq.ParseError); ok {
+			return nil, fmt.Errorf("invalid jq query at offset %d: %v", parseErr.Offset, err)
+		}
+		return nil, fmt.Errorf("invalid jq query: %v", err)
+	}
+	code, err := gojq.Compile(parsed)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jq query: %v", err)
+	}
+
+	jqQueries.Store(query, code)
+	return code, nil
+}
+
+// jqRuleQuery returns the query of a jq rule's spec, or "" if it has none
+func jqRuleQuery(spec interface{}) string {
+	if specMap, ok := spec.(map[string]interface{}); ok {
+		spec = specMap["query"]
+	}
+	query, _ := spec.(string)
+	return query
+}
+
+// evaluateJQOperation handles the "jq" operation, which runs a jq query
+// against an evaluated input, or the whole context when no input is given.
+// A query producing one value returns it and one producing several returns
+// them as an array. "as" stores the result in the context.
+func (re *RuleEngine) evaluateJQOperation(spec interface{}, operation map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	var query string
+	var inputExpr interface{}
+	hasInput := false
+
+	switch v := spec.(type) {
+	case string:
+		query = v
+	case map[string]interface{}:
+		queryStr, ok := v["query"].(string)
+		if !ok {
+			return nil, fmt.Errorf("jq operation requires a query string")
+		}
+		query = queryStr
+		inputExpr, hasInput = v["input"]
+	default:
+		return nil, fmt.Errorf("jq operation requires a query string or an object with query and input")
+	}
+
+	if strings.TrimSpace(query) == "" {
+		return nil, fmt.Errorf("jq operation requires a query string")
+	}
+	code, err := compileJQQuery(query)
+	if err != nil {
+		return nil, fmt.Errorf("jq operation failed: %v", err)
+	}
+
+	var input interface{} = data
+	if hasInput {
+		if input, err = re.evaluate(inputExpr, data); err != nil {
+			return nil, fmt.Errorf("jq operation failed to evaluate input: %v", err)
+		}
+	}
+
+	// gojq only accepts JSON types, so values such as typed slices from
+	// plugins are normalized first
+	normalized, err := jqInput(input)
+	if err != nil {
+		return nil, fmt.Errorf("jq operation input is not JSON: %v", err)
+	}
+	switch v := normalized.(type) {
+	case []interface{}:
+		err = re.checkCollectionSize("jq", len(v))
+	case map[string]interface{}:
+		err = re.checkCollectionSize("jq", len(v))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), jqQueryTimeout)
+	defer cancel()
+
+	var outputs []interface{}
+	iter := code.RunWithContext(ctx, normalized)
+	for {
+		value, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, isErr := value.(error); isErr {
+			if haltErr, isHalt := err.(*gojq.HaltError); isHalt && haltErr.Value() == nil {
+				break
+			}
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("jq query %q timed out after %s", query, jqQueryTimeout)
+			}
+			return nil, fmt.Errorf("jq query %q failed: %v", query, err)
+		}
+		if len(outputs) == maxJQOutputs {
+			return nil, fmt.Errorf("jq query %q produced more than %d values", query, maxJQOutputs)
+		}
+		outputs = append(outputs, jqOutput(value))
+	}
+
+	var result interface{}
+	switch len(outputs) {
+	case 0:
+		result = nil
+	case 1:
+		result = outputs[0]
+	default:
+		result = outputs
+	}
+
+	if target, exists := operation["as"]; exists {
+		targetKey, ok := target.(string)
+		if !ok || targetKey == "" {
+			return nil, fmt.Errorf("jq operation 'as' must be a non-empty string")
+		}
+		if err := setContextPath(data, strings.Split(targetKey, "."), result); err != nil {
+			return nil, fmt.Errorf("jq operation failed: %v", err)
+		}
+	}
+
+	return result, nil
+}
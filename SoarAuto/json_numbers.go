@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Values of rules_engine.json_numbers
+const (
+	jsonNumbersPreserve = "preserve"
+	jsonNumbersFloat    = "float"
+)
+
+// preserveJSONNumbers keeps the numbers of request contexts, stored jobs and
+// automation output as json.Number, so integers such as incident IDs leave
+// the engine exactly as they came in. Numbers become float64 only where they
+// are compared or computed with. Set from rules_engine.json_numbers.
+var preserveJSONNumbers = true
+
+// setJSONNumbers applies rules_engine.json_numbers
+func setJSONNumbers(mode string) error {
+	switch mode {
+	case "", jsonNumbersPreserve:
+		preserveJSONNumbers = true
+	case jsonNumbersFloat:
+		preserveJSONNumbers = false
+	default:
+		return fmt.Errorf("invalid rules_engine.json_numbers %q: must be %s or %s", mode, jsonNumbersPreserve, jsonNumbersFloat)
+	}
+	return nil
+}
+
+// decodeContextJSON decodes JSON carrying context values, keeping numbers as
+// json.Number unless rules_engine.json_numbers is "float"
+func decodeContextJSON(r io.Reader, target interface{}) error {
+	decoder := json.NewDecoder(r)
+	if preserveJSONNumbers {
+		decoder.UseNumber()
+	}
+	return decoder.Decode(target)
+}
+
+// unmarshalContextJSON is json.Unmarshal with the number handling of
+// decodeContextJSON
+func unmarshalContextJSON(data []byte, target interface{}) error {
+	if !preserveJSONNumbers {
+		return json.Unmarshal(data, target)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(target); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("invalid character after top-level value")
+	}
+	return nil
+}
+
+// floatPlaybookNumbers turns the json.Number values of a playbook decoded
+// with decodeContextJSON back into float64, the type its operations read
+// counts, rates and sizes as. Integers too large for a float64 to hold
+// exactly stay json.Number, so a playbook can still compare against them.
+func floatPlaybookNumbers(playbook []interface{}) []interface{} {
+	if playbook == nil {
+		return nil
+	}
+	return floatJSONNumbers(playbook).([]interface{})
+}
+
+// floatJSONNumbers returns value with every json.Number converted to float64,
+// except integers beyond 2^53
+func floatJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if integer, err := v.Int64(); err == nil && (integer > 1<<53 || integer < -(1<<53)) {
+			return v
+		}
+		if number, err := v.Float64(); err == nil {
+			return number
+		}
+		return v.String()
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = floatJSONNumbers(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = floatJSONNumbers(item)
+		}
+		return v
+	}
+	return value
+}
+
+// jsonInteger returns a number as an int64 when it is a whole number that
+// both sides of a comparison can hold exactly
+func jsonInteger(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		if integer, err := v.Int64(); err == nil {
+			return integer, true
+		}
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case float64:
+		// Beyond 2^53 a float64 no longer holds every integer
+		if v == math.Trunc(v) && math.Abs(v) <= 1<<53 {
+			return int64(v), true
+		}
+	}
+	return 0, false
+}
+
+// compareJSONIntegers compares two whole numbers exactly when at least one
+// is a json.Number, which float64 comparison would round beyond 2^53. It
+// reports false when the values are not both such integers.
+func compareJSONIntegers(left, right interface{}, op string) (bool, bool) {
+	_, leftIsNumber := left.(json.Number)
+	_, rightIsNumber := right.(json.Number)
+	if !leftIsNumber && !rightIsNumber {
+		return false, false
+	}
+	leftInt, leftOk := jsonInteger(left)
+	rightInt, rightOk := jsonInteger(right)
+	if !leftOk || !rightOk {
+		return false, false
+	}
+
+	switch op {
+	case "==", "===", "eq":
+		return leftInt == rightInt, true
+	case "!=", "!==":
+		return leftInt != rightInt, true
+	case ">", "gt":
+		return leftInt > rightInt, true
+	case "<", "lt":
+		return leftInt < rightInt, true
+	case ">=", "gte":
+		return leftInt >= rightInt, true
+	case "<=", "lte":
+		return leftInt <= rightInt, true
+	}
+	return false, false
+}
+
+// jqInput normalizes a value for gojq, which only accepts JSON types.
+// Integers are passed as int or *big.Int, so jq sees them exactly.
+func jqInput(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := unmarshalContextJSON(data, &normalized); err != nil {
+		return nil, err
+	}
+	if !preserveJSONNumbers {
+		return normalized, nil
+	}
+	return jqIntegers(normalized), nil
+}
+
+// jqIntegers converts json.Number values to int or *big.Int for integers and
+// float64 otherwise
+func jqIntegers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if integer, err := strconv.ParseInt(v.String(), 10, 0); err == nil {
+			return int(integer)
+		}
+		if integer, ok := new(big.Int).SetString(v.String(), 10); ok {
+			return integer
+		}
+		if number, err := v.Float64(); err == nil {
+			return number
+		}
+		return v.String()
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jqIntegers(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jqIntegers(item)
+		}
+		return v
+	}
+	return value
+}
+
+// jqOutput converts the integers gojq returns to json.Number, so they are
+// handled like any other context integer
+func jqOutput(value interface{}) interface{} {
+	if !preserveJSONNumbers {
+		return value
+	}
+	switch v := value.(type) {
+	case int:
+		return json.Number(strconv.Itoa(v))
+	case *big.Int:
+		return json.Number(v.String())
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = jqOutput(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = jqOutput(item)
+		}
+		return v
+	}
+	return value
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// largeIntegerContext holds integers beyond 2^53, which a float64 rounds to
+// their even neighbour
+const largeIntegerContext = `{
+	"incident": {"id": 9007199254740993},
+	"ids": [9007199254740992, 9007199254740995],
+	"ratio": 0.25
+}`
+
+func TestLargeIntegersRoundTrip(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	var context map[string]interface{}
+	if err := unmarshalContextJSON([]byte(largeIntegerContext), &context); err != nil {
+		t.Fatal(err)
+	}
+	playbook := floatPlaybookNumbers(parsePlaybook(t, `[
+		{"conditional_set": {"key": "copied", "value": "{{incident.id}}"}},
+		{"conditional_set": {"key": "title", "value": "Incident {{incident.id}}"}}
+	]`))
+
+	if _, err := engine.EvaluatePlaybook(playbook, context); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	output, err := json.Marshal(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"id":9007199254740993`,
+		`"ids":[9007199254740992,9007199254740995]`,
+		`"copied":9007199254740993`,
+		`"title":"Incident 9007199254740993"`,
+		`"ratio":0.25`,
+	} {
+		if !strings.Contains(string(output), want) {
+			t.Errorf("context %s does not contain %s", output, want)
+		}
+	}
+}
+
+func TestLargeIntegerComparisons(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	var data map[string]interface{}
+	if err := unmarshalContextJSON([]byte(largeIntegerContext), &data); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{`{"eq": [{"var": "incident.id"}, 9007199254740993]}`, true},
+		{`{"eq": [{"var": "incident.id"}, 9007199254740992]}`, false},
+		{`{"gt": [{"var": "incident.id"}, 9007199254740992]}`, true},
+		{`{"gt": [{"var": "incident.id"}, 9007199254740993]}`, false},
+		{`{"lt": [{"var": "incident.id"}, 9007199254740994]}`, true},
+		{`{"between": [{"var": "incident.id"}, 9007199254740993, 9007199254740993]}`, true},
+		{`{"between": [{"var": "incident.id"}, 1, 9007199254740992]}`, false},
+		{`{"between": [{"var": "incident.id"}, 9007199254740994, 9007199254740999]}`, false},
+		{`{"between": [{"var": "incident.id"}, 0.5, 9007199254740993]}`, true},
+		{`{"in": [{"var": "incident.id"}, {"var": "ids"}]}`, false},
+		{`{"in": [9007199254740995, {"var": "ids"}]}`, true},
+		{`{"not_in": [{"var": "incident.id"}, {"var": "ids"}]}`, true},
+	}
+
+	for _, test := range tests {
+		// Conditions are decoded the way playbooks in requests are
+		condition := floatPlaybookNumbers(parsePlaybookFromContextJSON(t, "["+test.condition+"]"))[0]
+		got, err := engine.evaluate(condition, data)
+		if err != nil {
+			t.Errorf("%s: %v", test.condition, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s = %v, want %v", test.condition, got, test.want)
+		}
+	}
+}
+
+// parsePlaybookFromContextJSON decodes a playbook keeping its numbers as
+// json.Number, as request bodies are decoded
+func parsePlaybookFromContextJSON(t *testing.T, source string) []interface{} {
+	t.Helper()
+	var playbook []interface{}
+	if err := unmarshalContextJSON([]byte(source), &playbook); err != nil {
+		t.Fatalf("parse playbook: %v", err)
+	}
+	return playbook
+}
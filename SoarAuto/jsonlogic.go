@@ -0,0 +1,676 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// jsonLogicScopeKey wraps a non-object data value (an array element inside
+// map/filter/all/none/some) so it can be passed where the engine expects a
+// context map. {"var": ""} and other lookups unwrap it again.
+const jsonLogicScopeKey = "\x00jsonlogic_scope"
+
+// jsonLogicOperators are the operators evaluated per the JSONLogic spec when
+// the rules engine runs in JSONLogic mode
+var jsonLogicOperators = map[string]bool{
+	"var": true, "missing": true, "missing_some": true,
+	"if": true, "?:": true, "==": true, "===": true, "!=": true, "!==": true,
+	"!": true, "!!": true, "or": true, "and": true,
+	">": true, ">=": true, "<": true, "<=": true,
+	"max": true, "min": true, "+": true, "-": true, "*": true, "/": true, "%": true,
+	"map": true, "filter": true, "reduce": true, "all": true, "none": true, "some": true,
+	"merge": true, "in": true, "cat": true, "substr": true, "log": true,
+	// Extensions to the spec
+	"not_in": true, "between": true,
+}
+
+// jsonLogicExtensions are the engine operations that keep their own semantics
+// in JSONLogic mode
+var jsonLogicExtensions = []string{"run", "play", "play_async", "switch", "plugin", "conditional_set", "context_diff", "elasticsearch_index", "splunk_log", "abort", "assert", "foreach", "batch", "vars", "try", "macro_scope", "jq", "random", "checkpoint", "restore", "throttle", "metric", "group_count", "finding", "first", "last", "nth"}
+
+// isJSONLogicExtension reports whether an operation is an engine extension
+// rather than a JSONLogic operator. The object forms of "if" and "map" have no
+// JSONLogic equivalent and keep their engine behavior.
+func isJSONLogicExtension(operation map[string]interface{}) bool {
+	for _, key := range jsonLogicExtensions {
+		if _, exists := operation[key]; exists {
+			return true
+		}
+	}
+	if _, ok := operation["if"].(map[string]interface{}); ok {
+		return true
+	}
+	if _, ok := operation["map"].(map[string]interface{}); ok {
+		return true
+	}
+	return false
+}
+
+// evaluateJSONLogicOperation evaluates an operation per the JSONLogic spec.
+// Objects with more than one key are data, not logic, and are returned as-is.
+func (re *RuleEngine) evaluateJSONLogicOperation(operation map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	if len(operation) != 1 {
+		return operation, nil
+	}
+
+	var op string
+	var rawArgs interface{}
+	for key, value := range operation {
+		op, rawArgs = key, value
+	}
+
+	// A single non-array argument is shorthand for a one-element list
+	args, ok := rawArgs.([]interface{})
+	if !ok {
+		args = []interface{}{rawArgs}
+	}
+
+	logger.Debug("Evaluating JSONLogic operation", map[string]interface{}{
+		"component": "rules_engine",
+		"operator":  op,
+		"arg_count": len(args),
+	})
+
+	// Operators that evaluate their arguments lazily
+	switch op {
+	case "if", "?:":
+		return re.jsonLogicIf(args, data)
+	case "and", "or":
+		return re.jsonLogicAndOr(op, args, data)
+	case "map", "filter", "all", "none", "some":
+		return re.jsonLogicIterate(op, args, data)
+	case "reduce":
+		return re.jsonLogicReduce(args, data)
+	}
+
+	if !jsonLogicOperators[op] {
+		return nil, fmt.Errorf("unrecognized JSONLogic operation: %s", op)
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		value, err := re.evaluate(arg, data)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	arg := func(i int) interface{} {
+		if i < len(values) {
+			return values[i]
+		}
+		return nil
+	}
+
+	switch op {
+	case "var":
+		return jsonLogicVar(arg(0), arg(1), data), nil
+	case "missing":
+		return jsonLogicMissing(values, data), nil
+	case "missing_some":
+		return jsonLogicMissingSome(arg(0), arg(1), data), nil
+	case "==":
+		return jsonLogicLooseEquals(arg(0), arg(1)), nil
+	case "!=":
+		return !jsonLogicLooseEquals(arg(0), arg(1)), nil
+	case "===":
+		return jsonLogicStrictEquals(arg(0), arg(1)), nil
+	case "!==":
+		return !jsonLogicStrictEquals(arg(0), arg(1)), nil
+	case "!":
+		return !jsonLogicTruthy(arg(0)), nil
+	case "!!":
+		return jsonLogicTruthy(arg(0)), nil
+	case "<", "<=":
+		orEqual := op == "<="
+		// Three arguments test that the middle value lies between the others
+		if len(values) == 3 {
+			return jsonLogicLess(arg(0), arg(1), orEqual) && jsonLogicLess(arg(1), arg(2), orEqual), nil
+		}
+		return jsonLogicLess(arg(0), arg(1), orEqual), nil
+	case ">":
+		return jsonLogicLess(arg(1), arg(0), false), nil
+	case ">=":
+		return jsonLogicLess(arg(1), arg(0), true), nil
+	case "max", "min":
+		return jsonLogicExtreme(op, values), nil
+	case "+", "*":
+		return jsonLogicArithmetic(op, values), nil
+	case "-":
+		if len(values) == 1 {
+			return jsonLogicNumber(-jsonLogicToNumber(arg(0))), nil
+		}
+		return jsonLogicNumber(jsonLogicToNumber(arg(0)) - jsonLogicToNumber(arg(1))), nil
+	case "/":
+		return jsonLogicNumber(jsonLogicToNumber(arg(0)) / jsonLogicToNumber(arg(1))), nil
+	case "%":
+		return jsonLogicNumber(math.Mod(jsonLogicToNumber(arg(0)), jsonLogicToNumber(arg(1)))), nil
+	case "merge":
+		size := 0
+		for _, value := range values {
+			if array, ok := value.([]interface{}); ok {
+				size += len(array)
+			} else {
+				size++
+			}
+		}
+		if err := re.checkCollectionSize("merge", size); err != nil {
+			return nil, err
+		}
+		merged := make([]interface{}, 0, size)
+		for _, value := range values {
+			if array, ok := value.([]interface{}); ok {
+				merged = append(merged, array...)
+			} else {
+				merged = append(merged, value)
+			}
+		}
+		return merged, nil
+	case "in":
+		if haystack, ok := arg(1).([]interface{}); ok {
+			if err := re.checkCollectionSize("in", len(haystack)); err != nil {
+				return nil, err
+			}
+		}
+		return jsonLogicIn(arg(0), arg(1)), nil
+	case "not_in":
+		if haystack, ok := arg(1).([]interface{}); ok {
+			if err := re.checkCollectionSize("not_in", len(haystack)); err != nil {
+				return nil, err
+			}
+		}
+		return !jsonLogicIn(arg(0), arg(1)), nil
+	case "between":
+		// Inclusive at both ends, like {"<=": [low, value, high]}
+		return jsonLogicLess(arg(1), arg(0), true) && jsonLogicLess(arg(0), arg(2), true), nil
+	case "cat":
+		var builder strings.Builder
+		for _, value := range values {
+			builder.WriteString(jsonLogicString(value))
+		}
+		return builder.String(), nil
+	case "substr":
+		return jsonLogicSubstr(values), nil
+	case "log":
+		logger.Info("JSONLogic log", map[string]interface{}{
+			"component": "rules_engine",
+			"value":     arg(0),
+		})
+		return arg(0), nil
+	default:
+		return nil, fmt.Errorf("unrecognized JSONLogic operation: %s", op)
+	}
+}
+
+// jsonLogicIf evaluates [cond, then, cond, then, ..., else], stopping at the
+// first truthy condition
+func (re *RuleEngine) jsonLogicIf(args []interface{}, data map[string]interface{}) (interface{}, error) {
+	i := 0
+	for ; i+1 < len(args); i += 2 {
+		condition, err := re.evaluate(args[i], data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate if condition: %v", err)
+		}
+		if jsonLogicTruthy(condition) {
+			return re.evaluate(args[i+1], data)
+		}
+	}
+	if i < len(args) {
+		return re.evaluate(args[i], data)
+	}
+	return nil, nil
+}
+
+// jsonLogicAndOr short-circuits like JavaScript: "and" returns the first falsy
+// value and "or" the first truthy value, otherwise the last value
+func (re *RuleEngine) jsonLogicAndOr(op string, args []interface{}, data map[string]interface{}) (interface{}, error) {
+	var value interface{}
+	for _, arg := range args {
+		result, err := re.evaluate(arg, data)
+		if err != nil {
+			return nil, err
+		}
+		value = result
+		if jsonLogicTruthy(value) == (op == "or") {
+			return value, nil
+		}
+	}
+	return value, nil
+}
+
+// jsonLogicIterate applies logic to each element of an array, with the
+// element as the data for the logic
+func (re *RuleEngine) jsonLogicIterate(op string, args []interface{}, data map[string]interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("%s operation requires an array and logic", op)
+	}
+
+	source, err := re.evaluate(args[0], data)
+	if err != nil {
+		return nil, err
+	}
+	items, _ := source.([]interface{})
+	if err := re.checkCollectionSize(op, len(items)); err != nil {
+		return nil, err
+	}
+
+	results := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		value, err := re.evaluate(args[1], jsonLogicScope(item))
+		if err != nil {
+			return nil, err
+		}
+
+		truthy := jsonLogicTruthy(value)
+		switch op {
+		case "map":
+			results = append(results, value)
+		case "filter":
+			if truthy {
+				results = append(results, item)
+			}
+		case "all":
+			if !truthy {
+				return false, nil
+			}
+		case "none":
+			if truthy {
+				return false, nil
+			}
+		case "some":
+			if truthy {
+				return true, nil
+			}
+		}
+	}
+
+	switch op {
+	case "all":
+		return len(items) > 0, nil
+	case "none":
+		return true, nil
+	case "some":
+		return false, nil
+	}
+	return results, nil
+}
+
+// jsonLogicReduce folds an array with logic evaluated against
+// {"current": element, "accumulator": value}
+func (re *RuleEngine) jsonLogicReduce(args []interface{}, data map[string]interface{}) (interface{}, error) {
+	if len(args) < 2 {
+		return nil, fmt.Errorf("reduce operation requires an array and logic")
+	}
+
+	source, err := re.evaluate(args[0], data)
+	if err != nil {
+		return nil, err
+	}
+	items, _ := source.([]interface{})
+	if err := re.checkCollectionSize("reduce", len(items)); err != nil {
+		return nil, err
+	}
+
+	var accumulator interface{}
+	if len(args) > 2 {
+		if accumulator, err = re.evaluate(args[2], data); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, item := range items {
+		accumulator, err = re.evaluate(args[1], map[string]interface{}{
+			"current":     item,
+			"accumulator": accumulator,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return accumulator, nil
+}
+
+// jsonLogicScope returns the data map used while evaluating logic for an
+// array element
+func jsonLogicScope(item interface{}) map[string]interface{} {
+	if itemMap, ok := item.(map[string]interface{}); ok {
+		return itemMap
+	}
+	return map[string]interface{}{jsonLogicScopeKey: item}
+}
+
+// jsonLogicRoot unwraps data created by jsonLogicScope
+func jsonLogicRoot(data map[string]interface{}) interface{} {
+	if item, exists := data[jsonLogicScopeKey]; exists && len(data) == 1 {
+		return item
+	}
+	return data
+}
+
+// jsonLogicVar resolves a dotted path through objects and array indexes,
+// returning fallback when any step is missing or null. An empty path returns
+// the whole data.
+func jsonLogicVar(path, fallback interface{}, data map[string]interface{}) interface{} {
+	current := jsonLogicRoot(data)
+	if path == nil || path == "" {
+		return current
+	}
+
+	for _, segment := range strings.Split(jsonLogicString(path), ".") {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			current = value[segment]
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(value) {
+				return fallback
+			}
+			current = value[index]
+		default:
+			return fallback
+		}
+		if current == nil {
+			return fallback
+		}
+	}
+	return current
+}
+
+// jsonLogicMissing returns the keys whose values are null or empty. If the
+// first argument is an array (e.g. from merge) it is used as the key list.
+func jsonLogicMissing(keys []interface{}, data map[string]interface{}) []interface{} {
+	if len(keys) > 0 {
+		if list, ok := keys[0].([]interface{}); ok {
+			keys = list
+		}
+	}
+
+	missing := make([]interface{}, 0)
+	for _, key := range keys {
+		value := jsonLogicVar(key, nil, data)
+		if value == nil || value == "" {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// jsonLogicMissingSome returns an empty list if at least need of the keys are
+// present, otherwise the missing keys
+func jsonLogicMissingSome(need, keys interface{}, data map[string]interface{}) []interface{} {
+	list, _ := keys.([]interface{})
+	missing := jsonLogicMissing([]interface{}{list}, data)
+	if float64(len(list)-len(missing)) >= jsonLogicToNumber(need) {
+		return make([]interface{}, 0)
+	}
+	return missing
+}
+
+// jsonLogicTruthy follows the JSONLogic truthiness table: 0, "", [], null and
+// false are falsy; everything else, including empty objects and "0", is truthy
+func jsonLogicTruthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case string:
+		return v != ""
+	case []interface{}:
+		return len(v) > 0
+	case map[string]interface{}:
+		return true
+	}
+	if number, ok := jsonLogicNumeric(value); ok {
+		return number != 0 && !math.IsNaN(number)
+	}
+	return true
+}
+
+// jsonLogicNumeric returns the value of a Go numeric type as a float64
+func jsonLogicNumeric(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	}
+	return 0, false
+}
+
+// jsonLogicToNumber converts a value as JavaScript's Number() does; values
+// that are not numeric become NaN
+func jsonLogicToNumber(value interface{}) float64 {
+	if number, ok := jsonLogicNumeric(value); ok {
+		return number
+	}
+	switch v := value.(type) {
+	case nil:
+		return 0
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		trimmed := strings.TrimSpace(v)
+		if trimmed == "" {
+			return 0
+		}
+		number, err := strconv.ParseFloat(trimmed, 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return number
+	case []interface{}:
+		return jsonLogicToNumber(jsonLogicString(v))
+	}
+	return math.NaN()
+}
+
+// jsonLogicNumber returns a numeric result; NaN and infinities have no JSON
+// representation and become null
+func jsonLogicNumber(number float64) interface{} {
+	if math.IsNaN(number) || math.IsInf(number, 0) {
+		return nil
+	}
+	return number
+}
+
+// jsonLogicString converts a value as JavaScript's String() does
+func jsonLogicString(value interface{}) string {
+	if number, ok := jsonLogicNumeric(value); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case []interface{}:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			if item != nil {
+				parts[i] = jsonLogicString(item)
+			}
+		}
+		return strings.Join(parts, ",")
+	case map[string]interface{}:
+		return "[object Object]"
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// jsonLogicPrimitive normalizes numbers to float64 and converts arrays and
+// objects to their string form, as JavaScript does before comparing
+func jsonLogicPrimitive(value interface{}) interface{} {
+	if number, ok := jsonLogicNumeric(value); ok {
+		return number
+	}
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		return jsonLogicString(value)
+	}
+	return value
+}
+
+// jsonLogicIsObject reports whether a value is an array or object
+func jsonLogicIsObject(value interface{}) bool {
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		return true
+	}
+	return false
+}
+
+// jsonLogicLooseEquals implements JavaScript's == for JSON values. Arrays and
+// objects are never equal to each other, matching JavaScript's identity
+// comparison for distinct values.
+func jsonLogicLooseEquals(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if jsonLogicIsObject(left) && jsonLogicIsObject(right) {
+		return false
+	}
+
+	left, right = jsonLogicPrimitive(left), jsonLogicPrimitive(right)
+	leftString, leftIsString := left.(string)
+	rightString, rightIsString := right.(string)
+	if leftIsString && rightIsString {
+		return leftString == rightString
+	}
+
+	leftBool, leftIsBool := left.(bool)
+	rightBool, rightIsBool := right.(bool)
+	if leftIsBool && rightIsBool {
+		return leftBool == rightBool
+	}
+
+	return jsonLogicToNumber(left) == jsonLogicToNumber(right)
+}
+
+// jsonLogicStrictEquals implements JavaScript's === for JSON values
+func jsonLogicStrictEquals(left, right interface{}) bool {
+	if left == nil || right == nil {
+		return left == nil && right == nil
+	}
+	if jsonLogicIsObject(left) || jsonLogicIsObject(right) {
+		return false
+	}
+
+	leftNumber, leftIsNumber := jsonLogicNumeric(left)
+	rightNumber, rightIsNumber := jsonLogicNumeric(right)
+	if leftIsNumber || rightIsNumber {
+		return leftIsNumber && rightIsNumber && leftNumber == rightNumber
+	}
+	return left == right
+}
+
+// jsonLogicLess implements JavaScript's < (or <=): strings compare
+// lexicographically, anything else numerically, and NaN is never ordered
+func jsonLogicLess(left, right interface{}, orEqual bool) bool {
+	left, right = jsonLogicPrimitive(left), jsonLogicPrimitive(right)
+	leftString, leftIsString := left.(string)
+	rightString, rightIsString := right.(string)
+	if leftIsString && rightIsString {
+		return leftString < rightString || (orEqual && leftString == rightString)
+	}
+
+	leftNumber, rightNumber := jsonLogicToNumber(left), jsonLogicToNumber(right)
+	return leftNumber < rightNumber || (orEqual && leftNumber == rightNumber)
+}
+
+// jsonLogicExtreme returns the largest or smallest numeric argument, or null
+// when there are none or any is not a number
+func jsonLogicExtreme(op string, values []interface{}) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+
+	result := math.NaN()
+	for i, value := range values {
+		number := jsonLogicToNumber(value)
+		if math.IsNaN(number) {
+			return nil
+		}
+		if i == 0 || (op == "max" && number > result) || (op == "min" && number < result) {
+			result = number
+		}
+	}
+	return jsonLogicNumber(result)
+}
+
+// jsonLogicArithmetic sums or multiplies all arguments as numbers
+func jsonLogicArithmetic(op string, values []interface{}) interface{} {
+	result := 0.0
+	if op == "*" {
+		result = 1
+	}
+	for _, value := range values {
+		if op == "*" {
+			result *= jsonLogicToNumber(value)
+		} else {
+			result += jsonLogicToNumber(value)
+		}
+	}
+	return jsonLogicNumber(result)
+}
+
+// jsonLogicIn tests for a substring or an array member (strict equality)
+func jsonLogicIn(needle, haystack interface{}) bool {
+	switch h := haystack.(type) {
+	case string:
+		return strings.Contains(h, jsonLogicString(needle))
+	case []interface{}:
+		for _, item := range h {
+			if jsonLogicStrictEquals(needle, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// jsonLogicSubstr returns part of a string. A negative start counts from the
+// end; a negative length stops that many characters before the end.
+func jsonLogicSubstr(values []interface{}) string {
+	if len(values) == 0 {
+		return ""
+	}
+	source := []rune(jsonLogicString(values[0]))
+
+	start := 0
+	if len(values) > 1 {
+		start = int(jsonLogicToNumber(values[1]))
+	}
+	if start < 0 {
+		start = max(len(source)+start, 0)
+	}
+	start = min(start, len(source))
+
+	end := len(source)
+	if len(values) > 2 {
+		length := int(jsonLogicToNumber(values[2]))
+		if length < 0 {
+			end = max(len(source)+length, start)
+		} else {
+			end = min(start+length, len(source))
+		}
+	}
+	return string(source[start:end])
+}
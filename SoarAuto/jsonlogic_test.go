@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// jsonLogicConformanceCase is one [rule, data, expected] entry of the
+// JSONLogic test suite
+type jsonLogicConformanceCase struct {
+	section  string
+	rule     interface{}
+	data     interface{}
+	expected interface{}
+}
+
+// loadJSONLogicSuite reads testdata/jsonlogic/tests.json, in which strings
+// name the section of the cases that follow them
+func loadJSONLogicSuite(t *testing.T) []jsonLogicConformanceCase {
+	t.Helper()
+	source, err := os.ReadFile("testdata/jsonlogic/tests.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []interface{}
+	if err := json.Unmarshal(source, &entries); err != nil {
+		t.Fatalf("parse tests.json: %v", err)
+	}
+
+	var cases []jsonLogicConformanceCase
+	section := ""
+	for _, entry := range entries {
+		switch value := entry.(type) {
+		case string:
+			section = value
+		case []interface{}:
+			if len(value) != 3 {
+				t.Fatalf("malformed case in %q: %v", section, value)
+			}
+			cases = append(cases, jsonLogicConformanceCase{section: section, rule: value[0], data: value[1], expected: value[2]})
+		}
+	}
+	return cases
+}
+
+// normalizeJSON round-trips a value through JSON so numbers of any Go type
+// compare equal
+func normalizeJSON(t *testing.T, value interface{}) interface{} {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("marshal %v: %v", value, err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		t.Fatalf("unmarshal %s: %v", encoded, err)
+	}
+	return decoded
+}
+
+func TestJSONLogicConformance(t *testing.T) {
+	engine := NewRuleEngine(&Config{RulesEngine: RulesEngineConfig{JSONLogicMode: true}})
+
+	cases := loadJSONLogicSuite(t)
+	if len(cases) == 0 {
+		t.Fatal("tests.json holds no cases")
+	}
+	for _, tc := range cases {
+		rule, _ := json.Marshal(tc.rule)
+		data, _ := json.Marshal(tc.data)
+		t.Run(tc.section+"/"+string(rule), func(t *testing.T) {
+			got, err := engine.evaluate(tc.rule, jsonLogicScope(tc.data))
+			if err != nil {
+				t.Fatalf("%s with data %s: %v", rule, data, err)
+			}
+			if !reflect.DeepEqual(normalizeJSON(t, got), normalizeJSON(t, tc.expected)) {
+				t.Errorf("%s with data %s = %#v, want %#v", rule, data, got, tc.expected)
+			}
+		})
+	}
+}
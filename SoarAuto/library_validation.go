@@ -0,0 +1,596 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Asset types checked by the library validation
+const (
+	libraryAssetPlaybook    = "playbook"
+	libraryAssetAutomation  = "automation"
+	libraryAssetIntegration = "integration"
+	libraryAssetSchedule    = "schedule"
+)
+
+// libraryCompileCode compiles the scripts listed on stdin without running them
+// or writing bytecode, and prints the compile error of each failing script
+const libraryCompileCode = `import json, sys
+errors = {}
+for path in json.load(sys.stdin)["paths"]:
+    try:
+        with open(path, "rb") as f:
+            compile(f.read(), path, "exec")
+    except SyntaxError as e:
+        errors[path] = "line %s: %s" % (e.lineno, e.msg)
+    except Exception as e:
+        errors[path] = str(e)
+print(json.dumps(errors))`
+
+// LibraryMonitor validates the asset library and alerts when problems appear
+// that were not present on the previous run
+type LibraryMonitor struct {
+	server   *SecAutoServer
+	interval time.Duration
+	known    map[string]bool
+	mutex    sync.Mutex
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewLibraryMonitor creates a library monitor. An empty interval disables
+// scheduled runs; the library can still be validated on demand.
+func NewLibraryMonitor(server *SecAutoServer, interval string) (*LibraryMonitor, error) {
+	lm := &LibraryMonitor{
+		server:   server,
+		known:    make(map[string]bool),
+		stopChan: make(chan struct{}),
+	}
+	if interval != "" {
+		parsed, err := time.ParseDuration(interval)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid library validation interval %q", interval)
+		}
+		lm.interval = parsed
+	}
+	return lm, nil
+}
+
+// Start runs the validation on its schedule, if one is configured
+func (lm *LibraryMonitor) Start() {
+	if lm.interval == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(lm.interval)
+		defer ticker.Stop()
+
+		lm.Run()
+		for {
+			select {
+			case <-ticker.C:
+				lm.Run()
+			case <-lm.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops scheduled validation
+func (lm *LibraryMonitor) Stop() {
+	lm.stopOnce.Do(func() {
+		close(lm.stopChan)
+	})
+}
+
+// Run validates the library, marks the problems not seen on the previous run
+// and sends a library_problems_found webhook event for them
+func (lm *LibraryMonitor) Run() LibraryValidationResponse {
+	report := lm.server.ValidateLibrary()
+
+	// Every problem found by the first run counts as new
+	lm.mutex.Lock()
+	current := make(map[string]bool)
+	for _, problem := range report.Problems {
+		key := problem.key()
+		current[key] = true
+		if !lm.known[key] {
+			report.NewProblems = append(report.NewProblems, problem)
+		}
+	}
+	lm.known = current
+	lm.mutex.Unlock()
+
+	logger.Info("Library validation completed", map[string]interface{}{
+		"component":    "library_validation",
+		"healthy":      report.Healthy,
+		"problems":     len(report.Problems),
+		"new_problems": len(report.NewProblems),
+		"duration_ms":  float64(report.DurationMs),
+	})
+
+	if len(report.NewProblems) > 0 && lm.server.webhookManager != nil {
+		lm.server.webhookManager.SendWebhook(WebhookEvent{
+			Event:     "library_problems_found",
+			Status:    "unhealthy",
+			Timestamp: report.Timestamp,
+			Problems:  report.NewProblems,
+		})
+	}
+
+	return report
+}
+
+// key identifies a problem across runs
+func (p LibraryProblem) key() string {
+	return strings.Join([]string{p.Type, p.Name, p.Field, p.Message, p.Value}, "\x00")
+}
+
+// validateAllHandler handles POST /validate/all, which lints every stored
+// playbook, compiles every automation and checks every integration config and
+// scheduled playbook (admin only)
+func (s *SecAutoServer) validateAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isAdminRequest(r) {
+		http.Error(w, "Forbidden: admin API key required", http.StatusForbidden)
+		return
+	}
+
+	// Compiling a large library can outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	response := s.libraryMonitor.Run()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// maxBatchValidationPlaybooks bounds the playbooks of one POST /validate/batch
+const maxBatchValidationPlaybooks = 500
+
+// validateBatchHandler handles POST /validate/batch, which lints several
+// playbooks in one call, such as every playbook changed by a pull request.
+// The response is 200 with a result per playbook, unless fail_fast is set and
+// a playbook is invalid: then validation stops there and the response is 422.
+func (s *SecAutoServer) validateBatchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BatchValidationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	if len(req.Playbooks) == 0 {
+		http.Error(w, "playbooks must list at least one playbook", http.StatusBadRequest)
+		return
+	}
+	if len(req.Playbooks) > maxBatchValidationPlaybooks {
+		http.Error(w, fmt.Sprintf("at most %d playbooks can be validated at once", maxBatchValidationPlaybooks), http.StatusBadRequest)
+		return
+	}
+
+	response := BatchValidationResponse{
+		Success: true,
+		Valid:   true,
+		Results: []BatchValidationResult{},
+	}
+	macros, err := s.engine.LoadMacros()
+	if err != nil {
+		response.Warnings = append(response.Warnings, fmt.Sprintf("Macro references not checked: %v", err))
+	}
+
+	status := http.StatusOK
+	for i, item := range req.Playbooks {
+		name, content, errors := s.batchValidationItem(item)
+		if errors == nil {
+			errors = s.lintPlaybook(content, macros)
+		}
+
+		result := BatchValidationResult{
+			Index:  i,
+			Name:   name,
+			Valid:  len(errors) == 0,
+			Errors: errors,
+		}
+		response.Results = append(response.Results, result)
+		response.Checked++
+		if !result.Valid {
+			response.Invalid++
+			response.Valid = false
+			if req.FailFast {
+				status = http.StatusUnprocessableEntity
+				break
+			}
+		}
+	}
+	response.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	logger.Info("Batch playbook validation completed", map[string]interface{}{
+		"component": "library_validation",
+		"checked":   response.Checked,
+		"invalid":   response.Invalid,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// batchValidationItem returns the name and content of one playbook of a
+// batch, loading it when it is given by name, or the errors that prevent it
+// from being linted
+func (s *SecAutoServer) batchValidationItem(item json.RawMessage) (string, []byte, []ValidationError) {
+	var name string
+	if err := json.Unmarshal(item, &name); err == nil {
+		if name == "" || !s.validator.pathRegex.MatchString(name) {
+			return name, nil, []ValidationError{{Field: "playbook_name", Message: "Invalid playbook name format", Value: name}}
+		}
+		content, err := os.ReadFile(s.engine.getPlaybookPath(s.validator.SanitizePath(name)))
+		if err != nil {
+			return name, nil, []ValidationError{{Field: "playbook_name", Message: fmt.Sprintf("Failed to read playbook: %v", err), Value: name}}
+		}
+		return name, content, nil
+	}
+
+	var inline struct {
+		Name     string          `json:"name"`
+		Playbook json.RawMessage `json:"playbook"`
+	}
+	if err := json.Unmarshal(item, &inline); err == nil {
+		if len(inline.Playbook) == 0 {
+			return inline.Name, nil, []ValidationError{{Field: "playbook", Message: "Either a playbook name or an object with a playbook must be given"}}
+		}
+		return inline.Name, inline.Playbook, nil
+	}
+	return "", item, nil
+}
+
+// ValidateLibrary checks every playbook, automation, integration config and
+// schedule and returns a consolidated health report
+func (s *SecAutoServer) ValidateLibrary() LibraryValidationResponse {
+	start := time.Now()
+	var assets []LibraryAssetResult
+	var warnings []string
+
+	playbooks, playbookWarnings := s.validateLibraryPlaybooks()
+	assets = append(assets, playbooks...)
+	warnings = append(warnings, playbookWarnings...)
+
+	automations, automationWarnings := s.validateLibraryAutomations()
+	assets = append(assets, automations...)
+	warnings = append(warnings, automationWarnings...)
+
+	assets = append(assets, s.validateLibraryIntegrations()...)
+
+	schedules, scheduleWarnings := s.validateLibrarySchedules()
+	assets = append(assets, schedules...)
+	warnings = append(warnings, scheduleWarnings...)
+
+	summary := map[string]LibraryAssetCount{
+		libraryAssetPlaybook:    {},
+		libraryAssetAutomation:  {},
+		libraryAssetIntegration: {},
+		libraryAssetSchedule:    {},
+	}
+	var problems []LibraryProblem
+	for _, asset := range assets {
+		count := summary[asset.Type]
+		count.Checked++
+		if !asset.Valid {
+			count.Invalid++
+		}
+		summary[asset.Type] = count
+
+		for _, err := range asset.Errors {
+			problems = append(problems, LibraryProblem{
+				Type:    asset.Type,
+				Name:    asset.Name,
+				Field:   err.Field,
+				Message: err.Message,
+				Value:   err.Value,
+			})
+		}
+	}
+
+	return LibraryValidationResponse{
+		Success:    true,
+		Healthy:    len(problems) == 0,
+		Summary:    summary,
+		Assets:     assets,
+		Problems:   problems,
+		Warnings:   warnings,
+		DurationMs: time.Since(start).Milliseconds(),
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// validateLibraryPlaybooks lints each stored playbook for structural errors
+// and references to automations, playbooks, plugins and macros that do not exist
+func (s *SecAutoServer) validateLibraryPlaybooks() ([]LibraryAssetResult, []string) {
+	var warnings []string
+	dir := s.engine.config.Python.PlaybooksPath
+	files, err := listLibraryFiles(dir, ".json")
+	if err != nil {
+		return nil, []string{fmt.Sprintf("Failed to read playbooks directory: %v", err)}
+	}
+
+	macros, err := s.engine.LoadMacros()
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("Macro references not checked: %v", err))
+	}
+
+	var results []LibraryAssetResult
+	for _, file := range files {
+		if file == macrosFileName {
+			continue
+		}
+		name := strings.TrimSuffix(file, filepath.Ext(file))
+		var errors []ValidationError
+
+		content, err := os.ReadFile(filepath.Join(dir, file))
+		if err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "file_content",
+				Message: fmt.Sprintf("Failed to read playbook: %v", err),
+			})
+		} else {
+			errors = s.lintPlaybook(content, macros)
+		}
+
+		results = append(results, LibraryAssetResult{
+			Type:   libraryAssetPlaybook,
+			Name:   name,
+			Valid:  len(errors) == 0,
+			Errors: errors,
+		})
+	}
+	return results, warnings
+}
+
+// lintPlaybook checks a playbook's structure and, when it is valid, its
+// references to automations, playbooks, plugins and macros
+func (s *SecAutoServer) lintPlaybook(content []byte, macros map[string]*MacroDefinition) []ValidationError {
+	if err := s.validatePlaybookStructure(content); err != nil {
+		return []ValidationError{{
+			Field:   "content",
+			Message: fmt.Sprintf("Invalid playbook structure: %v", err),
+		}}
+	}
+	var playbook []interface{}
+	json.Unmarshal(content, &playbook)
+	return s.validatePlaybookReferences(playbook, macros)
+}
+
+// validatePlaybookReferences reports references to assets that do not exist.
+// Macro references are only checked when macros is not nil.
+func (s *SecAutoServer) validatePlaybookReferences(playbook []interface{}, macros map[string]*MacroDefinition) []ValidationError {
+	checker := &DependencyChecker{engine: s.engine}
+	errors := checker.MissingDependencies(playbook)
+
+	if macros != nil {
+		reported := make(map[string]bool)
+		walkPlaybookRules(playbook, func(rule map[string]interface{}) {
+			macro, ok := rule["macro"].(string)
+			if !ok || reported[macro] {
+				return
+			}
+			if definition, exists := macros[macro]; !exists || definition == nil {
+				reported[macro] = true
+				errors = append(errors, ValidationError{
+					Field:   "macro",
+					Message: fmt.Sprintf("Macro %s is not defined", macro),
+					Value:   macro,
+				})
+			}
+		})
+	}
+	return errors
+}
+
+// lintSchedulePlaybook lints the inline playbook of a schedule the same way
+// as a stored playbook
+func (s *SecAutoServer) lintSchedulePlaybook(playbook []interface{}, macros map[string]*MacroDefinition) []ValidationError {
+	content, err := json.Marshal(playbook)
+	if err != nil {
+		return []ValidationError{{
+			Field:   "playbook",
+			Message: fmt.Sprintf("Failed to encode playbook: %v", err),
+		}}
+	}
+	return s.lintPlaybook(content, macros)
+}
+
+// validateLibrarySchedules re-lints the playbook of each schedule, since the
+// automations, playbooks and plugins it references may have been removed
+// after the schedule was created
+func (s *SecAutoServer) validateLibrarySchedules() ([]LibraryAssetResult, []string) {
+	if s.jobScheduler == nil {
+		return nil, nil
+	}
+
+	var warnings []string
+	macros, err := s.engine.LoadMacros()
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("Schedule macro references not checked: %v", err))
+	}
+
+	schedules := s.jobScheduler.ListSchedules("", 0)
+	sort.Slice(schedules, func(i, j int) bool {
+		return schedules[i].ID < schedules[j].ID
+	})
+
+	var results []LibraryAssetResult
+	for _, schedule := range schedules {
+		errors := s.lintSchedulePlaybook(schedule.Playbook, macros)
+		results = append(results, LibraryAssetResult{
+			Type:   libraryAssetSchedule,
+			Name:   schedule.ID,
+			Valid:  len(errors) == 0,
+			Errors: errors,
+		})
+	}
+	return results, warnings
+}
+
+// validateLibraryAutomations compiles each Python automation and checks its
+// integration imports
+func (s *SecAutoServer) validateLibraryAutomations() ([]LibraryAssetResult, []string) {
+	dir := s.engine.config.Python.ScriptsPath
+	files, err := listLibraryFiles(dir, ".py")
+	if err != nil {
+		return nil, []string{fmt.Sprintf("Failed to read automations directory: %v", err)}
+	}
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	var warnings []string
+	paths := make([]string, len(files))
+	for i, file := range files {
+		paths[i] = filepath.Join(dir, file)
+	}
+
+	var compileErrors map[string]string
+	output, err := RunPythonCodeFromVenvWithJSON(s.engine.config.GetVenvPath(), libraryCompileCode, map[string]interface{}{"paths": paths})
+	if err == nil {
+		err = json.Unmarshal([]byte(strings.TrimSpace(string(output))), &compileErrors)
+	}
+	if err != nil {
+		warnings = append(warnings, fmt.Sprintf("Automations not compiled: %v", err))
+	}
+
+	var results []LibraryAssetResult
+	for i, file := range files {
+		var errors []ValidationError
+		if message, failed := compileErrors[paths[i]]; failed {
+			errors = append(errors, ValidationError{
+				Field:   "compile",
+				Message: fmt.Sprintf("Automation does not compile: %s", message),
+			})
+		}
+
+		if content, err := os.ReadFile(paths[i]); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "file_content",
+				Message: fmt.Sprintf("Failed to read automation: %v", err),
+			})
+		} else {
+			errors = append(errors, s.validateIntegrationImports(content)...)
+			errors = append(errors, validateIntegrationImportsExist(content)...)
+		}
+
+		results = append(results, LibraryAssetResult{
+			Type:   libraryAssetAutomation,
+			Name:   strings.TrimSuffix(file, ".py"),
+			Valid:  len(errors) == 0,
+			Errors: errors,
+		})
+	}
+	return results, warnings
+}
+
+// validateIntegrationImportsExist reports imports from the integrations
+// package whose integration file has been deleted
+func validateIntegrationImportsExist(content []byte) []ValidationError {
+	var errors []ValidationError
+	reported := make(map[string]bool)
+	for _, imp := range parseAutomationImports(content) {
+		name, found := strings.CutPrefix(imp.Module, "integrations.")
+		if !found {
+			continue
+		}
+		name = strings.SplitN(name, ".", 2)[0]
+		if reported[name] {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join("../integrations", name+".py")); err != nil {
+			reported[name] = true
+			errors = append(errors, ValidationError{
+				Field:   "imports",
+				Message: fmt.Sprintf("Integration %s does not exist (line %d)", name, imp.Line),
+				Value:   imp.Module,
+			})
+		}
+	}
+	return errors
+}
+
+// validateLibraryIntegrations checks each integration config and flags
+// expired credentials
+func (s *SecAutoServer) validateLibraryIntegrations() []LibraryAssetResult {
+	if s.integrationConfigManager == nil {
+		return nil
+	}
+
+	configs := s.integrationConfigManager.ListConfigs()
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	var results []LibraryAssetResult
+	for _, name := range names {
+		config := configs[name]
+		var errors []ValidationError
+		if err := s.integrationConfigManager.ValidateConfig(config); err != nil {
+			errors = append(errors, ValidationError{
+				Field:   "config",
+				Message: err.Error(),
+			})
+		}
+		if config.ExpiresAt != nil && !now.Before(*config.ExpiresAt) {
+			errors = append(errors, ValidationError{
+				Field:   "expires_at",
+				Message: "Integration credentials have expired",
+				Value:   config.ExpiresAt.UTC().Format(time.RFC3339),
+			})
+		}
+
+		results = append(results, LibraryAssetResult{
+			Type:   libraryAssetIntegration,
+			Name:   name,
+			Valid:  len(errors) == 0,
+			Errors: errors,
+		})
+	}
+	return results
+}
+
+// listLibraryFiles returns the sorted names of the files in dir with the given
+// extension. A missing directory holds no files.
+func listLibraryFiles(dir, ext string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.EqualFold(filepath.Ext(entry.Name()), ext) {
+			files = append(files, entry.Name())
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
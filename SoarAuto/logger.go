@@ -109,6 +109,7 @@ func (l *StructuredLogger) log(entry LogEntry) {
 	defer globalLogMutex.Unlock()
 
 	entry.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	outputRedactor.redactLogEntry(&entry)
 	jsonData, err := json.Marshal(entry)
 	if err != nil {
 		log.Printf("ERROR: Failed to marshal log entry: %v", err)
@@ -208,12 +209,28 @@ func (l *StructuredLogger) Warning(message string, fields map[string]interface{}
 			entry.Component = v.(string)
 		case "job_id":
 			entry.JobID = v.(string)
+		case "request_id":
+			entry.RequestID = v.(string)
+		case "remote_addr":
+			entry.RemoteAddr = v.(string)
+		case "path":
+			entry.Path = v.(string)
+		case "duration_ms":
+			entry.Duration = v.(float64)
+		case "error":
+			entry.Error = v.(string)
+		case "script":
+			entry.Script = v.(string)
+		case "playbook":
+			entry.Playbook = v.(string)
 		case "webhook_url":
 			entry.WebhookURL = v.(string)
 		case "attempt":
 			entry.Attempt = v.(int)
 		case "retry_count":
 			entry.RetryCount = v.(int)
+		case "stats":
+			entry.Stats = v.(map[string]interface{})
 		}
 	}
 	l.log(entry)
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// macrosFileName is the file in the playbooks directory that defines macros
+const macrosFileName = "macros.json"
+
+// maxMacroDepth bounds how deeply macros may reference other macros
+const maxMacroDepth = 16
+
+// macroParamReferenceRegex matches a parameter value that is exactly one
+// template reference, e.g. "{{src_ip}}"
+var macroParamReferenceRegex = regexp.MustCompile(`^\{\{[^}]+\}\}$`)
+
+// MacroDefinition is a named, reusable sequence of rules.
+//
+// In macros.json a macro is either a plain rule array or an object:
+//
+//	{"enrich_ioc": {"description": "...", "params": {"ioc_var": "ip"}, "rules": [...]}}
+//
+// where params holds default values for the macro's parameters. Parameters
+// are bound as local variables while the macro's rules run, so its rules
+// refer to them like any other variable, e.g. {"var": "{{ioc_var}}"}.
+type MacroDefinition struct {
+	Description string                 `json:"description,omitempty"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+	Rules       []interface{}          `json:"rules"`
+}
+
+// UnmarshalJSON accepts either a rule array or a full macro object
+func (md *MacroDefinition) UnmarshalJSON(data []byte) error {
+	var rules []interface{}
+	if err := json.Unmarshal(data, &rules); err == nil {
+		md.Rules = rules
+		return nil
+	}
+
+	type macroDefinition MacroDefinition
+	var def macroDefinition
+	if err := json.Unmarshal(data, &def); err != nil {
+		return err
+	}
+	*md = MacroDefinition(def)
+	return nil
+}
+
+// LoadMacros loads macro definitions from macros.json in the playbooks directory.
+// A missing file means no macros are defined.
+func (re *RuleEngine) LoadMacros() (map[string]*MacroDefinition, error) {
+	path := filepath.Join(re.config.Python.PlaybooksPath, macrosFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]*MacroDefinition{}, nil
+		}
+		return nil, fmt.Errorf("failed to read macros file: %v", err)
+	}
+
+	var macros map[string]*MacroDefinition
+	if err := json.Unmarshal(data, &macros); err != nil {
+		return nil, fmt.Errorf("failed to parse macros file: %v", err)
+	}
+
+	return macros, nil
+}
+
+// expandMacros inlines every {"macro": ...} reference in the playbook
+func (re *RuleEngine) expandMacros(playbook []interface{}) ([]interface{}, error) {
+	if !containsMacroReference(playbook) {
+		return playbook, nil
+	}
+
+	macros, err := re.LoadMacros()
+	if err != nil {
+		return nil, err
+	}
+
+	expander := &macroExpander{macros: macros}
+	expanded, err := expander.expandRules(playbook, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Debug("Expanded playbook macros", map[string]interface{}{
+		"component":  "rules_engine",
+		"rule_count": len(expanded),
+	})
+
+	return expanded, nil
+}
+
+// containsMacroReference reports whether a value contains a macro reference
+func containsMacroReference(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, ok := v["macro"]; ok {
+			return true
+		}
+		for _, val := range v {
+			if containsMacroReference(val) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, val := range v {
+			if containsMacroReference(val) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// macroExpander expands macro references while tracking the expansion chain
+type macroExpander struct {
+	macros map[string]*MacroDefinition
+}
+
+// expandRules expands a rule sequence, splicing macro bodies in place
+func (me *macroExpander) expandRules(rules []interface{}, chain []string) ([]interface{}, error) {
+	expanded := make([]interface{}, 0, len(rules))
+	for _, rule := range rules {
+		if ruleMap, ok := rule.(map[string]interface{}); ok {
+			if _, isMacro := ruleMap["macro"]; isMacro {
+				body, err := me.expandReference(ruleMap, chain)
+				if err != nil {
+					return nil, err
+				}
+				expanded = append(expanded, body...)
+				continue
+			}
+		}
+
+		value, err := me.expandValue(rule, chain)
+		if err != nil {
+			return nil, err
+		}
+		expanded = append(expanded, value)
+	}
+	return expanded, nil
+}
+
+// expandValue expands macro references nested inside a rule (e.g. in if branches)
+func (me *macroExpander) expandValue(value interface{}, chain []string) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if _, isMacro := v["macro"]; isMacro {
+			body, err := me.expandReference(v, chain)
+			if err != nil {
+				return nil, err
+			}
+			return body, nil
+		}
+		result := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			expanded, err := me.expandValue(val, chain)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = expanded
+		}
+		return result, nil
+	case []interface{}:
+		// Positional arrays (e.g. if branches) keep their shape; a macro
+		// reference becomes a nested rule list rather than being spliced in
+		result := make([]interface{}, len(v))
+		for i, val := range v {
+			expanded, err := me.expandValue(val, chain)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = expanded
+		}
+		return result, nil
+	default:
+		return value, nil
+	}
+}
+
+// expandReference resolves a single macro reference into its rules
+func (me *macroExpander) expandReference(reference map[string]interface{}, chain []string) ([]interface{}, error) {
+	name, ok := reference["macro"].(string)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("macro name must be a non-empty string")
+	}
+
+	for _, seen := range chain {
+		if seen == name {
+			return nil, fmt.Errorf("circular macro reference: %s", strings.Join(append(chain, name), " -> "))
+		}
+	}
+	if len(chain) >= maxMacroDepth {
+		return nil, fmt.Errorf("macro nesting exceeds maximum depth of %d", maxMacroDepth)
+	}
+
+	macro, exists := me.macros[name]
+	if !exists || macro == nil {
+		return nil, fmt.Errorf("unknown macro: %s", name)
+	}
+
+	// Resolve parameters: defaults first, then values from the reference
+	params := make(map[string]interface{}, len(macro.Params))
+	for k, v := range macro.Params {
+		params[k] = v
+	}
+	if refParams, exists := reference["params"]; exists {
+		refMap, ok := refParams.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("params for macro %s must be an object", name)
+		}
+		for k, v := range refMap {
+			params[k] = v
+		}
+	}
+
+	body, err := me.expandRules(macro.Rules, append(chain, name))
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return body, nil
+	}
+
+	scoped := make([]interface{}, len(body))
+	for i, rule := range body {
+		scoped[i] = macroScopeRule(name, params, rule)
+	}
+	return scoped, nil
+}
+
+// macroScopeRule wraps a rule of a macro's body so it runs with the macro's
+// parameters bound as local variables:
+//
+//	{"macro_scope": {"macro": name, "params": {...}, "rule": rule}}
+//
+// Each rule is wrapped on its own so the body still splices into the
+// playbook rule by rule; continue_on_error moves onto the wrapper, where the
+// playbook looks for it.
+func macroScopeRule(name string, params map[string]interface{}, rule interface{}) map[string]interface{} {
+	scope := map[string]interface{}{"macro": name, "params": params, "rule": rule}
+	wrapper := map[string]interface{}{"macro_scope": scope}
+	if ruleMap, ok := rule.(map[string]interface{}); ok {
+		if flag, exists := ruleMap["continue_on_error"]; exists {
+			stripped := make(map[string]interface{}, len(ruleMap)-1)
+			for key, value := range ruleMap {
+				if key != "continue_on_error" {
+					stripped[key] = value
+				}
+			}
+			scope["rule"] = stripped
+			wrapper["continue_on_error"] = flag
+		}
+	}
+	return wrapper
+}
+
+// evaluateMacroScopeOperation handles the "macro_scope" operation produced
+// by macro expansion. The parameters are bound as variables while the rule
+// runs and restored afterwards. Values are bound as given, keeping their type
+// and any {{...}} they contain, except that a value which is exactly one
+// reference, e.g. "{{src_ip}}", passes the referenced variable.
+func (re *RuleEngine) evaluateMacroScopeOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("macro_scope operation requires an object")
+	}
+	params, _ := specMap["params"].(map[string]interface{})
+
+	// Every value is resolved before any is bound, against the caller's variables
+	resolved := make(map[string]interface{}, len(params))
+	for name, value := range params {
+		if reference, ok := value.(string); ok && macroParamReferenceRegex.MatchString(reference) {
+			value = re.processTemplateVariables(reference, data)
+		}
+		resolved[name] = value
+	}
+
+	type savedVariable struct {
+		value  interface{}
+		exists bool
+	}
+	saved := make(map[string]savedVariable, len(resolved))
+	for name, value := range resolved {
+		previous, exists := data[name]
+		saved[name] = savedVariable{value: previous, exists: exists}
+		data[name] = value
+	}
+	defer func() {
+		for name, previous := range saved {
+			if previous.exists {
+				data[name] = previous.value
+			} else {
+				delete(data, name)
+			}
+		}
+	}()
+
+	return re.evaluatePlaybookRule(specMap["rule"], data)
+}
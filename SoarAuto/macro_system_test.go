@@ -0,0 +1,110 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// newMacroTestEngine returns an engine whose playbooks directory holds the
+// given macros.json
+func newMacroTestEngine(t *testing.T, macros string) *RuleEngine {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, macrosFileName), []byte(macros), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewRuleEngine(&Config{Python: PythonConfig{ScriptsPath: dir, PlaybooksPath: dir}})
+}
+
+const testMacros = `{
+	"describe": {
+		"params": {"label": "unknown"},
+		"rules": [{"map": {"text": "alert: {{msg}}", "ports": "{{ports}}", "max": "{{limits.max}}", "label": "{{label}}"}, "as": "out"}]
+	},
+	"outer": {
+		"rules": [{"macro": "describe", "params": {"msg": "{{reason}}", "ports": [], "limits": {"max": 0}}}]
+	},
+	"forward": {
+		"params": {"reason": "forwarded"},
+		"rules": [{"macro": "describe", "params": {"msg": "{{reason}}", "ports": "{{ports}}", "limits": {}}}]
+	}
+}`
+
+func TestMacroParamsAreBoundAsLocals(t *testing.T) {
+	engine := newMacroTestEngine(t, testMacros)
+	playbook := parsePlaybook(t, `[
+		{"macro": "describe", "params": {"msg": "use {{b}} here", "ports": [80, 443], "limits": {"max": 5}}}
+	]`)
+	context := map[string]interface{}{"b": "SECRET", "msg": "caller's msg"}
+
+	if _, err := engine.EvaluatePlaybook(playbook, context); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	want := map[string]interface{}{
+		"text":  "alert: use {{b}} here", // the value's template stays literal
+		"ports": []interface{}{float64(80), float64(443)},
+		"max":   float64(5),
+		"label": "unknown", // default
+	}
+	if !reflect.DeepEqual(context["out"], want) {
+		t.Errorf("out = %#v\nwant %#v", context["out"], want)
+	}
+
+	// The caller's variables are restored and the other parameters removed
+	if context["msg"] != "caller's msg" {
+		t.Errorf("msg = %v, want the caller's value restored", context["msg"])
+	}
+	for _, name := range []string{"ports", "limits", "label"} {
+		if _, exists := context[name]; exists {
+			t.Errorf("parameter %s left in the context", name)
+		}
+	}
+}
+
+func TestMacroParamReferencesPassVariables(t *testing.T) {
+	engine := newMacroTestEngine(t, testMacros)
+
+	// A value that is exactly one reference passes the caller's variable
+	context := map[string]interface{}{"reason": "{{b}}", "b": "SECRET"}
+	if _, err := engine.EvaluatePlaybook(parsePlaybook(t, `[{"macro": "outer"}]`), context); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	out := context["out"].(map[string]interface{})
+	if out["text"] != "alert: {{b}}" {
+		t.Errorf("text = %v, want the referenced value kept literal", out["text"])
+	}
+
+	// A nested macro sees its caller's parameters
+	context = map[string]interface{}{"ports": []interface{}{float64(22)}}
+	if _, err := engine.EvaluatePlaybook(parsePlaybook(t, `[{"macro": "forward"}]`), context); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+	out = context["out"].(map[string]interface{})
+	if out["text"] != "alert: forwarded" || !reflect.DeepEqual(out["ports"], []interface{}{float64(22)}) {
+		t.Errorf("out = %v, want the outer macro's parameters passed on", out)
+	}
+	if _, exists := context["reason"]; exists {
+		t.Error("outer macro parameter left in the context")
+	}
+}
+
+func TestMacroScopeKeepsContinueOnError(t *testing.T) {
+	wrapped := macroScopeRule("m", map[string]interface{}{"x": 1}, map[string]interface{}{"run": "a", "continue_on_error": true})
+	if wrapped["continue_on_error"] != true {
+		t.Errorf("continue_on_error not moved onto the wrapper: %v", wrapped)
+	}
+	rule := wrapped["macro_scope"].(map[string]interface{})["rule"].(map[string]interface{})
+	if _, exists := rule["continue_on_error"]; exists {
+		t.Errorf("continue_on_error left on the wrapped rule: %v", rule)
+	}
+}
+
+func TestMacroExpansionRejectsCircularReferences(t *testing.T) {
+	engine := newMacroTestEngine(t, `{"a": [{"macro": "b"}], "b": [{"macro": "a"}]}`)
+	if _, err := engine.expandMacros(parsePlaybook(t, `[{"macro": "a"}]`)); err == nil {
+		t.Error("circular macro reference was accepted")
+	}
+}
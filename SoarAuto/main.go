@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
@@ -25,27 +26,54 @@ import (
 var logger *StructuredLogger
 var globalLogMutex sync.Mutex // Global mutex for all logging operations
 
-// APIKeyAuth holds allowed API keys
-var allowedAPIKeys map[string]struct{}
-
 func main() {
 	// Define command line flags
 	standalone := flag.Bool("s", false, "Run in standalone mode")
-	contextFile := flag.String("c", "", "Context JSON file path")
+	contextFile := flag.String("c", "", "Context JSON file path or http(s) URL")
 	playbookFile := flag.String("p", "", "Playbook JSON file path")
 	port := flag.String("port", "8000", "Server port (for server mode)")
 	workers := flag.Int("workers", 5, "Number of worker threads for async jobs")
 	logLevel := flag.String("log-level", "INFO", "Log level (DEBUG, INFO, WARNING, ERROR)")
+	outputFormat := flag.String("o", standaloneOutputText, "Standalone output format (text, json, yaml, summary)")
+	quiet := flag.Bool("q", false, "Suppress engine log output on stderr")
+	migrateJobs := flag.Bool("migrate-jobs", false, "Migrate all stored jobs to the current schema version and exit")
+	benchPool := flag.String("bench-pool", "", "Compare spawned and pooled run latency of an automation and exit")
+	benchRuns := flag.Int("bench-runs", 20, "Runs of each kind for -bench-pool")
 	// logDest and logFile are no longer needed as variables
 
 	// Parse flags
 	flag.Parse()
 
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
 	// Load configuration
 	config, err := LoadConfig("config.yaml")
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	if err := setJSONNumbers(config.RulesEngine.JSONNumbers); err != nil {
+		log.Fatalf("Invalid config: %v", err)
+	}
+	if outputRedactor, err = NewRedactor(config.Logging.Redaction); err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if resultCap, err = NewResultCap(config.Database.Results); err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Job migration mode: upgrade stored jobs and exit
+	if *migrateJobs {
+		logger = NewStructuredLogger(LogLevel(*logLevel), "console", "", nil)
+		os.Exit(runJobMigration(config))
+	}
+
+	// Benchmark mode: measure the Python pool against spawning and exit
+	if *benchPool != "" {
+		logger = NewStructuredLogger(LogLevel(*logLevel), "console", "", nil)
+		os.Exit(runPythonPoolBenchmark(config, *benchPool, *contextFile, *benchRuns))
+	}
 
 	// Standalone mode: always log to logs/secauto_standalone.log
 	if *standalone {
@@ -59,11 +87,14 @@ func main() {
 		logger = NewStructuredLogger(LogLevel(*logLevel), "file", "logs/secauto_standalone.log", standaloneRotation)
 		if *playbookFile == "" {
 			fmt.Println("Error: Playbook file (-p) is required for standalone mode")
-			fmt.Println("Usage: ./secauto.exe -s -p <playbook.json> [-c <context.json>]")
-			os.Exit(1)
+			fmt.Println("Usage: ./secauto.exe -s -p <playbook.json> [-c <context.json>] [-o text|json|yaml|summary] [-q]")
+			os.Exit(standaloneExitInvalid)
 		}
-		runStandaloneWithFlags(*playbookFile, *contextFile)
-		return
+		if !isValidStandaloneOutput(*outputFormat) {
+			fmt.Printf("Error: Unsupported output format %q (use text, json, yaml or summary)\n", *outputFormat)
+			os.Exit(standaloneExitInvalid)
+		}
+		os.Exit(runStandaloneWithFlags(*playbookFile, *contextFile, *outputFormat))
 	}
 
 	// Server mode: use config.yaml logging config
@@ -99,6 +130,8 @@ func runServer(port string, workerCount int) {
 
 	// Create webhook manager
 	webhookManager := NewWebhookManager()
+	webhookManager.SetRuleEngine(engine)
+	webhookManager.SetDeliveryHistory(config.Webhooks.DeliveryHistory)
 
 	// Create job manager
 	jobManager, err := NewJobManager(workerCount, webhookManager, config)
@@ -106,11 +139,34 @@ func runServer(port string, workerCount int) {
 		log.Fatalf("Failed to create job manager: %v", err)
 	}
 
+	// Publish the activity feed through the job store's Redis when configured
+	if eventBus, err = NewEventBus(config.Events, jobManager.store); err != nil {
+		log.Fatalf("Failed to create event bus: %v", err)
+	}
+
+	// Synchronous executions share the enrichment cache and throttle with jobs
+	engine.SetEnrichmentCache(jobManager.enrichment)
+	engine.SetThrottle(jobManager.throttle)
+	engine.SetJobManager(jobManager)
+
 	// Create rate limiter
 	rateLimiter := NewRateLimiter(config)
 
 	// Create validator
 	validator := NewValidator()
+	if err := validator.SetFilenamePattern(config.Security.InputValidation.FilenamePattern); err != nil {
+		log.Fatalf("Invalid filename_pattern: %v", err)
+	}
+
+	// Remove Go plugin build directories left behind by previous runs
+	SweepPluginBuildDirs(config.Plugins.BuildTempDir, time.Hour)
+
+	// Remove working directories of automation and plugin runs that were in
+	// progress when the server last stopped
+	SweepExecutionWorkDirs(config.Python.WorkDir, time.Hour)
+	if config.Plugins.WorkDir != config.Python.WorkDir {
+		SweepExecutionWorkDirs(config.Plugins.WorkDir, time.Hour)
+	}
 
 	// Create platform-aware plugin manager
 	pluginManager, err := NewPlatformPluginManager(config)
@@ -121,6 +177,9 @@ func runServer(port string, workerCount int) {
 	// Set plugin manager on rule engine
 	engine.SetPluginManager(pluginManager)
 
+	// Track plugin versions for jobs and notify pending jobs of plugin reloads
+	jobManager.SetPluginManager(pluginManager)
+
 	// Create cluster manager if enabled
 	var clusterManager *ClusterManager
 	if config.Cluster.Enabled {
@@ -135,6 +194,16 @@ func runServer(port string, workerCount int) {
 		if err != nil {
 			log.Fatalf("Failed to create cluster manager: %v", err)
 		}
+
+		// A client's rate limit applies to the cluster as a whole
+		namespace := config.Cluster.RateLimiting.Namespace
+		if namespace == "" {
+			namespace = config.Cluster.ClusterName
+		}
+		if namespace == "" {
+			namespace = "default"
+		}
+		rateLimiter.UseSharedCounters(clusterManager.redisClient, namespace)
 	}
 
 	// Create job scheduler if enabled
@@ -176,6 +245,34 @@ func runServer(port string, workerCount int) {
 		}
 	}
 
+	// Replay responses to retried integration mutations
+	if config.Database.Idempotency.Enabled {
+		redisStore, ok := jobManager.store.(*RedisJobStore)
+		if !ok {
+			log.Fatalf("Integration idempotency keys require the Redis job store")
+		}
+		idempotencyStore, err := NewIdempotencyStore(redisStore.client, config.Database.Idempotency)
+		if err != nil {
+			log.Fatalf("Failed to create idempotency store: %v", err)
+		}
+		integrationConfigManager.SetIdempotencyStore(idempotencyStore)
+	}
+
+	// Resolve env: and vault: references in integration configs
+	integrationConfigManager.SetSecretResolver(NewSecretResolver(config.Integrations.Vault))
+
+	// Check integration credentials for expiry daily
+	integrationConfigManager.StartExpiryMonitor()
+	engine.SetIntegrationConfigManager(integrationConfigManager)
+	jobManager.SetIntegrationConfigManager(integrationConfigManager)
+
+	// Create chunked upload manager
+	uploadManager, err := NewUploadManager(config.Uploads)
+	if err != nil {
+		log.Fatalf("Failed to create upload manager: %v", err)
+	}
+	uploadManager.Start()
+
 	// Create server
 	server := &SecAutoServer{
 		engine:                   engine,
@@ -187,41 +284,103 @@ func runServer(port string, workerCount int) {
 		clusterManager:           clusterManager,
 		jobScheduler:             jobScheduler,
 		integrationConfigManager: integrationConfigManager,
+		syncLimiter:              NewConcurrencyLimiter(config.Performance.MaxConcurrentRequests),
+		uploadManager:            uploadManager,
+		storageLimits:            config.Storage,
+	}
+
+	// Create the asset library monitor
+	libraryMonitor, err := NewLibraryMonitor(server, config.Monitoring.LibraryValidationInterval)
+	if err != nil {
+		log.Fatalf("Failed to create library monitor: %v", err)
 	}
+	server.libraryMonitor = libraryMonitor
+	libraryMonitor.Start()
+
+	// Sync playbooks from Git if configured
+	if config.PlaybookGit.Enabled {
+		playbookGit, err := NewPlaybookGitSync(server, config.PlaybookGit)
+		if err != nil {
+			log.Fatalf("Failed to configure Git playbook sync: %v", err)
+		}
+		server.playbookGit = playbookGit
+		playbookGit.Start()
+	}
+
+	// Signed, single-use links to exports
+	downloadLinks, err := NewDownloadLinks(config.Security.DownloadLinks, jobManager.store)
+	if err != nil {
+		log.Fatalf("Failed to configure download links: %v", err)
+	}
+	server.downloadLinks = downloadLinks
 
 	// Create CORS middleware
 	corsMiddleware := corsMiddleware(config)
 
+	// Endpoints that cannot work without the Redis job store answer 503
+	// while it is down; the others keep working
+	requireRedis := redisRequiredMiddleware(jobManager.store)
+
 	// Set up routes with CORS, logging, validation, rate limiting, and auth middleware
 	http.HandleFunc("/health", corsMiddleware(loggingMiddleware(server.healthHandler)))
+	http.HandleFunc("/health/ready", corsMiddleware(loggingMiddleware(server.readinessHandler)))
+	http.HandleFunc("/selftest", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.selfTestHandler))))))
 	http.HandleFunc("/playbook", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookHandler))))))
-	http.HandleFunc("/playbook/async", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookAsyncHandler))))))
-	http.HandleFunc("/jobs", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.jobsHandler))))))
-	http.HandleFunc("/jobs/stats", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.jobStatsHandler))))))
-	http.HandleFunc("/jobs/metrics", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.jobMetricsHandler))))))
+	http.HandleFunc("/playbook/async", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(requireRedis(server.playbookAsyncHandler)))))))
+	http.HandleFunc("/jobs", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(requireRedis(server.jobsHandler)))))))
+	http.HandleFunc("/jobs/stats", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(requireRedis(server.jobStatsHandler)))))))
+	http.HandleFunc("/metrics", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.metricsHandler))))))
+	http.HandleFunc("/jobs/metrics", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(requireRedis(server.jobMetricsHandler)))))))
+	http.HandleFunc("/events", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.eventsHandler))))))
+	http.HandleFunc("/events/stream", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.eventsStreamHandler))))))
+	http.HandleFunc("/exports/links", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.downloadLinksHandler))))))
+	// Signed download links are their own authorization
+	http.HandleFunc("/download", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(server.downloadHandler)))))
+	http.HandleFunc("/results/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.resultsHandler))))))
+	http.HandleFunc("/archive", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.archiveHandler))))))
 	http.HandleFunc("/plugins", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.pluginsHandler))))))
 	http.HandleFunc("/plugins/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.pluginHandler))))))
 	http.HandleFunc("/cluster", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.clusterHandler))))))
 	http.HandleFunc("/cluster/jobs", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.clusterJobsHandler))))))
 	http.HandleFunc("/cluster/jobs/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.clusterJobHandler))))))
-	http.HandleFunc("/schedules", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.schedulesHandler))))))
-	http.HandleFunc("/schedules/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.scheduleHandler))))))
-	http.HandleFunc("/job/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.jobHandler))))))
+	http.HandleFunc("/schedules", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(requireRedis(server.schedulesHandler)))))))
+	http.HandleFunc("/schedules/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(requireRedis(server.scheduleHandler)))))))
+	http.HandleFunc("/job/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(requireRedis(server.jobHandler)))))))
+	http.HandleFunc("/context/import/csv", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.csvImportHandler))))))
 	http.HandleFunc("/context", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.contextHandler))))))
 	http.HandleFunc("/webhooks", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.webhooksHandler))))))
+	http.HandleFunc("/webhooks/deliveries", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.webhookDeliveriesHandler))))))
+	http.HandleFunc("/webhooks/deliveries/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.webhookDeliveriesHandler))))))
 	http.HandleFunc("/validate", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(server.validateHandler))))
+	http.HandleFunc("/validate/batch", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.validateBatchHandler))))))
+	http.HandleFunc("/validate/all", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.validateAllHandler))))))
 	http.HandleFunc("/automation", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.automationUploadHandler))))))
 	http.HandleFunc("/playbook/upload", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookUploadHandler))))))
 	http.HandleFunc("/playbooks", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookListHandler))))))
+	http.HandleFunc("/playbooks/stats", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookStatsHandler))))))
+	http.HandleFunc("/playbooks/sync", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookSyncHandler))))))
+	http.HandleFunc("/playbooks/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookExportHandler))))))
 	http.HandleFunc("/automations", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.automationListHandler))))))
 	http.HandleFunc("/automation/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.automationDeleteHandler))))))
-	http.HandleFunc("/playbook/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookDeleteHandler))))))
+	http.HandleFunc("/playbook/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.playbookItemHandler))))))
 	http.HandleFunc("/plugin/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.pluginUploadHandler))))))
+	http.HandleFunc("/uploads", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.uploadsHandler))))))
+	http.HandleFunc("/uploads/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.uploadHandler))))))
+	http.HandleFunc("/storage", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.storageHandler))))))
 	http.HandleFunc("/plugin/delete/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.pluginDeleteHandler))))))
 
+	// Profiling endpoints (admin only)
+	http.HandleFunc("/admin/profile/start", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.cpuProfileHandler))))))
+	http.HandleFunc("/admin/profile/heap", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.heapProfileHandler))))))
+	http.HandleFunc("/admin/queues", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.adminQueuesHandler))))))
+	http.HandleFunc("/admin/queues/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.adminQueuesHandler))))))
+
+	http.HandleFunc("/search", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.searchHandler))))))
+
 	// Integration configuration endpoints
 	http.HandleFunc("/integrations", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.integrationsHandler))))))
 	http.HandleFunc("/integrations/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.integrationHandler))))))
+	http.HandleFunc("/integrations/types", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.integrationTypesHandler))))))
 	http.HandleFunc("/integrations/upload", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.integrationUploadHandler))))))
 	http.HandleFunc("/integrations/delete/", corsMiddleware(loggingMiddleware(validationMiddleware(validator)(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(server.integrationDeleteHandler))))))
 
@@ -255,35 +414,72 @@ func runServer(port string, workerCount int) {
 		"component": "server",
 		"endpoints": []map[string]string{
 			{"method": "GET", "path": "/health", "description": "Health check"},
-			{"method": "POST", "path": "/playbook", "description": "Execute playbook (synchronous)"},
-			{"method": "POST", "path": "/playbook/async", "description": "Execute playbook (asynchronous)"},
+			{"method": "GET", "path": "/health/ready", "description": "Readiness check, including Redis availability"},
+			{"method": "POST", "path": "/selftest", "description": "Run end-to-end self-test (admin)"},
+			{"method": "POST", "path": "/playbook", "description": "Execute playbook (synchronous, or asynchronous with async set)"},
+			{"method": "POST", "path": "/playbook/async", "description": "Execute playbook (asynchronous alias of /playbook)"},
 			{"method": "GET", "path": "/jobs", "description": "List all jobs"},
 			{"method": "GET", "path": "/jobs/stats", "description": "Job statistics"},
+			{"method": "GET", "path": "/metrics", "description": "Playbook metrics in the Prometheus text format"},
 			{"method": "GET", "path": "/jobs/metrics", "description": "Database performance metrics"},
+			{"method": "GET", "path": "/events", "description": "Recent activity: jobs, schedules, plugin reloads and integration changes"},
+			{"method": "GET", "path": "/events/stream", "description": "Stream activity as Server-Sent Events"},
+			{"method": "GET", "path": "/results/{id}", "description": "Full results of a run truncated to results.max_bytes, when offloaded"},
+			{"method": "GET", "path": "/archive", "description": "Archived jobs from cold storage (from/to date range)"},
+			{"method": "POST", "path": "/exports/links", "description": "Signed, single-use download link to an export"},
+			{"method": "GET", "path": "/download", "description": "Fetch an export through a signed download link (no API key)"},
 			{"method": "GET", "path": "/plugins", "description": "List all plugins"},
 			{"method": "GET", "path": "/plugins/{name}", "description": "Get plugin information"},
 			{"method": "POST", "path": "/plugins/{name}", "description": "Execute plugin"},
+			{"method": "POST", "path": "/plugins/{name}/cache/clear", "description": "Clear the cached results of a cacheable plugin"},
 			{"method": "POST", "path": "/automation", "description": "Upload automation script"},
 			{"method": "POST", "path": "/playbook/upload", "description": "Upload playbook file"},
+			{"method": "POST", "path": "/uploads", "description": "Start a chunked upload"},
+			{"method": "GET", "path": "/uploads/{id}", "description": "Get chunked upload progress"},
+			{"method": "PATCH", "path": "/uploads/{id}", "description": "Upload a chunk at the Upload-Offset header"},
+			{"method": "DELETE", "path": "/uploads/{id}", "description": "Abandon a chunked upload"},
+			{"method": "POST", "path": "/uploads/{id}/complete", "description": "Verify and save a chunked upload"},
+			{"method": "GET", "path": "/storage", "description": "Stored playbook, automation, plugin and integration counts and sizes against the storage limits"},
 			{"method": "GET", "path": "/playbooks", "description": "List all playbooks"},
+			{"method": "GET", "path": "/playbooks/stats", "description": "Operation counts and most-used automations and plugins across all playbooks"},
+			{"method": "GET", "path": "/playbooks/sync", "description": "Commit and playbooks last synced from the Git playbook source"},
+			{"method": "POST", "path": "/playbooks/sync", "description": "Sync playbooks from the Git playbook source now"},
+			{"method": "GET", "path": "/playbooks/{name}/export", "description": "Export a playbook as JSON, YAML or Markdown"},
 			{"method": "GET", "path": "/automations", "description": "List all automations"},
 			{"method": "DELETE", "path": "/automation/{name}", "description": "Delete an automation"},
 			{"method": "GET", "path": "/cluster", "description": "Get cluster information"},
 			{"method": "POST", "path": "/cluster/jobs", "description": "Submit job to distributed queue"},
 			{"method": "GET", "path": "/cluster/jobs/{id}", "description": "Get distributed job status"},
-			{"method": "GET", "path": "/job/{id}", "description": "Get job status and results"},
+			{"method": "GET", "path": "/job/{id}", "description": "Get job status and results, optionally waiting for a status change"},
 			{"method": "DELETE", "path": "/job/{id}", "description": "Cancel/delete job"},
+			{"method": "GET", "path": "/job/{id}/diff", "description": "Get context changes made by a job"},
+			{"method": "GET", "path": "/job/{id}/poll", "description": "Long-poll for job changes since a version"},
 			{"method": "GET", "path": "/context", "description": "Get current context"},
+			{"method": "POST", "path": "/context/import/csv", "description": "Import CSV rows as playbook context"},
 			{"method": "POST", "path": "/webhooks", "description": "Configure webhooks"},
+			{"method": "GET", "path": "/webhooks/deliveries", "description": "Recent webhook deliveries with their requests and responses, filtered by event, status, webhook_url and job_id"},
+			{"method": "POST", "path": "/webhooks/deliveries/{id}/resend", "description": "Send a recorded webhook delivery again"},
 			{"method": "POST", "path": "/validate", "description": "Validate playbook/context"},
+			{"method": "POST", "path": "/validate/batch", "description": "Validate several playbooks, inline or stored, in one call"},
+			{"method": "POST", "path": "/validate/all", "description": "Validate every stored playbook, automation, integration and schedule (admin only)"},
 			{"method": "GET", "path": "/docs", "description": "Interactive API documentation (Swagger UI)"},
 			{"method": "GET", "path": "/api-docs", "description": "OpenAPI specification"},
 			{"method": "DELETE", "path": "/automation/{name}", "description": "Delete an automation"},
+			{"method": "PATCH", "path": "/playbook/{name}", "description": "Apply a JSON Patch or rule-index edits to a playbook"},
 			{"method": "DELETE", "path": "/playbook/{name}", "description": "Delete a playbook"},
+			{"method": "GET", "path": "/playbook/{name}/runs", "description": "Recent jobs of a playbook with status, duration and who triggered them"},
 			{"method": "POST", "path": "/plugin/{type}", "description": "Upload plugin file"},
 			{"method": "DELETE", "path": "/plugin/{type}/{name}", "description": "Delete a plugin"},
+			{"method": "POST", "path": "/admin/profile/start", "description": "Capture a CPU profile for ?duration= (admin API key required)"},
+			{"method": "GET", "path": "/admin/profile/heap", "description": "Capture a heap profile (admin API key required)"},
+			{"method": "GET", "path": "/admin/queues", "description": "Depth and oldest item age of the internal queues (admin API key required)"},
+			{"method": "POST", "path": "/admin/queues/{name}/flush", "description": "Drain the jobs, webhooks or plugin_reload queue (admin API key required)"},
+			{"method": "GET", "path": "/debug/pprof/", "description": "Go pprof endpoints when development.profile_enabled is set (admin API key required)"},
+			{"method": "GET", "path": "/search", "description": "Search playbooks, automations and integrations"},
 			{"method": "GET", "path": "/integrations", "description": "List all integrations"},
+			{"method": "GET", "path": "/integrations/types", "description": "Integration types with their required and optional fields"},
 			{"method": "GET", "path": "/integrations/{name}", "description": "Get integration information by name"},
+			{"method": "GET", "path": "/integrations/{name}/reveal", "description": "Get integration with unmasked secrets (admin API key required)"},
 			{"method": "POST", "path": "/integrations", "description": "Create a new integration"},
 			{"method": "PUT", "path": "/integrations/{name}", "description": "Update an existing integration by name"},
 			{"method": "DELETE", "path": "/integrations/{name}", "description": "Delete an integration by name"},
@@ -300,17 +496,53 @@ func runServer(port string, workerCount int) {
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
 
+	// SIGHUP reloads the API keys from config.yaml, rotating them without a restart
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
 	go func() {
-		if err := http.ListenAndServe(":"+serverPort, nil); err != nil {
+		for range reload {
+			if err := ReloadAPIKeys("config.yaml"); err != nil {
+				logger.Error("Failed to reload API keys", map[string]interface{}{
+					"component": "auth",
+					"error":     err.Error(),
+				})
+			}
+		}
+	}()
+
+	httpServer, err := newHTTPServer(":"+serverPort, config.Server)
+	if err != nil {
+		log.Fatalf("Invalid server configuration: %v", err)
+	}
+
+	// Profiling endpoints are hidden unless enabled, and need an admin API key
+	httpServer.Handler = profilingGate(config.Development.ProfileEnabled,
+		loggingMiddleware(rateLimitMiddleware(rateLimiter)(apiKeyAuthMiddleware(pprofHandler))), http.DefaultServeMux)
+
+	go func() {
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
 
+	// The gRPC API is served on its own port alongside REST when enabled
+	var grpcServer *GRPCServer
+	if config.GRPC.Enabled {
+		grpcServer = NewGRPCServer(server, rateLimiter, config.GRPC)
+		if err := grpcServer.Start(); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}
+
 	<-stop
 	logger.Info("Shutting down server gracefully...", map[string]interface{}{
 		"component": "server",
 	})
 
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+
 	// Close plugin manager
 	if err := server.pluginManager.Close(); err != nil {
 		logger.Error("Failed to close plugin manager", map[string]interface{}{
@@ -329,6 +561,23 @@ func runServer(port string, workerCount int) {
 		}
 	}
 
+	// Stop integration expiry monitor
+	server.integrationConfigManager.Stop()
+
+	// Stop expiring chunked uploads
+	server.uploadManager.Stop()
+
+	// Stop scheduled library validation
+	server.libraryMonitor.Stop()
+	if server.playbookGit != nil {
+		server.playbookGit.Stop()
+	}
+
+	// Stop pooled Python interpreters
+	closePythonPools()
+
+	eventBus.Close()
+
 	jobManager.Cleanup()
 	logger.Info("Job manager cleanup completed", map[string]interface{}{
 		"component": "server",
@@ -345,94 +594,82 @@ func (s *SecAutoServer) healthHandler(w http.ResponseWriter, r *http.Request) {
 	response := HealthResponse{
 		Status:    "healthy",
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
-		Version:   "1.0.0",
+		Version:   engineVersion,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// playbookHandler handles synchronous playbook execution requests
-func (s *SecAutoServer) playbookHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
+// readinessHandler reports whether the server's dependencies are available.
+// It answers 503 while Redis is down, when job endpoints are unavailable but
+// synchronous execution, validation and file management keep working.
+func (s *SecAutoServer) readinessHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Parse request
-	var req PlaybookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
-		return
+	response := ReadinessResponse{
+		Status:    "ready",
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
-
-	// Validate request
-	validationResult := s.validator.ValidatePlaybookRequest(&req)
-	if !validationResult.Valid {
-		response := ValidationResponse{
-			Success:   false,
-			Valid:     false,
-			Errors:    validationResult.Errors,
-			Message:   "Validation failed",
-			Timestamp: time.Now().UTC().Format(time.RFC3339),
+	if redisStore, ok := s.jobManager.store.(*RedisJobStore); ok {
+		redisHealth := redisStore.health.Status()
+		response.Redis = &redisHealth
+		if redisHealth.Status != "up" {
+			response.Status = "degraded"
 		}
-		w.WriteHeader(http.StatusBadRequest)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
-		return
 	}
 
-	// Sanitize inputs
-	if req.PlaybookName != "" {
-		req.PlaybookName = s.validator.SanitizePath(req.PlaybookName)
+	w.Header().Set("Content-Type", "application/json")
+	if response.Status != "ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
 	}
+	json.NewEncoder(w).Encode(response)
+}
 
-	// Set context if provided
-	if req.Context != nil {
-		s.engine.SetContext(req.Context)
+// selfTestHandler runs the built-in end-to-end self-test (admin only)
+func (s *SecAutoServer) selfTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	// Execute playbook
-	var results []interface{}
-	var err error
-
-	if req.Playbook != nil {
-		// Execute inline playbook
-		results, err = s.engine.EvaluatePlaybook(req.Playbook)
-	} else if req.PlaybookName != "" {
-		// Load and execute playbook from file
-		playbookPath := s.engine.getPlaybookPath(req.PlaybookName)
-		playbook, err := s.engine.LoadPlaybookFromFile(playbookPath)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to load playbook: %v", err), http.StatusBadRequest)
-			return
-		}
-		results, _ = s.engine.EvaluatePlaybook(playbook)
-	} else {
-		http.Error(w, "Either playbook or playbook_name must be provided", http.StatusBadRequest)
+	if !isAdminRequest(r) {
+		http.Error(w, "Forbidden: admin API key required", http.StatusForbidden)
 		return
 	}
 
-	response := PlaybookResponse{
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
-	}
+	response := s.RunSelfTest()
 
-	if err != nil {
-		response.Success = false
-		response.Error = err.Error()
-		w.WriteHeader(http.StatusInternalServerError)
-	} else {
-		response.Success = true
-		response.Results = results
-		response.Context = s.engine.GetContext()
-	}
+	logger.Info("Self-test completed", map[string]interface{}{
+		"component": "server",
+		"passed":    response.Passed,
+	})
 
 	w.Header().Set("Content-Type", "application/json")
+	if !response.Passed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
-// playbookAsyncHandler handles asynchronous playbook execution requests
+// playbookHandler handles playbook execution requests. A request with async
+// set is submitted as a job; otherwise the playbook runs synchronously.
+func (s *SecAutoServer) playbookHandler(w http.ResponseWriter, r *http.Request) {
+	s.handlePlaybookRequest(w, r, false)
+}
+
+// playbookAsyncHandler handles asynchronous playbook execution requests. It is
+// an alias of /playbook with async always set.
 func (s *SecAutoServer) playbookAsyncHandler(w http.ResponseWriter, r *http.Request) {
+	s.handlePlaybookRequest(w, r, true)
+}
+
+// handlePlaybookRequest parses, validates and resolves a playbook request,
+// then submits it as a job or executes it synchronously
+func (s *SecAutoServer) handlePlaybookRequest(w http.ResponseWriter, r *http.Request, forceAsync bool) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -440,13 +677,27 @@ func (s *SecAutoServer) playbookAsyncHandler(w http.ResponseWriter, r *http.Requ
 
 	// Parse request
 	var req PlaybookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeContextJSON(r.Body, &req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	req.Playbook = floatPlaybookNumbers(req.Playbook)
+	if forceAsync {
+		req.Async = true
+	}
+	req.Caller = apiKeyCaller(getRequestAPIKey(r))
 
 	// Validate request
 	validationResult := s.validator.ValidatePlaybookRequest(&req)
+	// Priority orders the job queue, which synchronous runs never enter
+	if req.Priority != "" && !req.Async {
+		validationResult.Valid = false
+		validationResult.Errors = append(validationResult.Errors, ValidationError{
+			Field:   "priority",
+			Message: "Priority only applies to asynchronous runs; set async to true",
+			Value:   req.Priority,
+		})
+	}
 	if !validationResult.Valid {
 		response := ValidationResponse{
 			Success:   false,
@@ -455,48 +706,188 @@ func (s *SecAutoServer) playbookAsyncHandler(w http.ResponseWriter, r *http.Requ
 			Message:   "Validation failed",
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		}
-		w.WriteHeader(http.StatusBadRequest)
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
 
-	// Sanitize inputs
-	if req.PlaybookName != "" {
-		req.PlaybookName = s.validator.SanitizePath(req.PlaybookName)
+	playbook, err := s.resolvePlaybook(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Submit job for asynchronous execution
-	var jobID string
+	if req.Async {
+		s.submitPlaybookJob(w, &req, playbook)
+	} else {
+		s.executePlaybook(w, &req, playbook)
+	}
+}
 
+// resolvePlaybook returns the playbook a validated request runs, loading it
+// by name when it is not inline
+func (s *SecAutoServer) resolvePlaybook(req *PlaybookRequest) ([]interface{}, error) {
 	if req.Playbook != nil {
-		// Submit inline playbook
-		jobID = s.jobManager.SubmitJob(req.Playbook, req.Context)
-	} else if req.PlaybookName != "" {
-		// Load playbook from file and submit
-		playbookPath := s.engine.getPlaybookPath(req.PlaybookName)
-		playbook, err := s.engine.LoadPlaybookFromFile(playbookPath)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to load playbook: %v", err), http.StatusBadRequest)
-			return
+		return req.Playbook, nil
+	}
+
+	// Sanitize inputs
+	req.PlaybookName = s.validator.SanitizePath(req.PlaybookName)
+
+	// Load playbook from file
+	playbookPath := s.engine.getPlaybookPath(req.PlaybookName)
+	playbook, err := s.engine.LoadPlaybookFromFile(playbookPath)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load playbook: %v", err)
+	}
+	return playbook, nil
+}
+
+// submitPlaybookJob submits a playbook for asynchronous execution and
+// responds with 202 Accepted and the job's status URL
+func (s *SecAutoServer) submitPlaybookJob(w http.ResponseWriter, req *PlaybookRequest, playbook []interface{}) {
+	response := s.submitPlaybook(req, playbook)
+
+	w.Header().Set("Content-Type", "application/json")
+	if response.JobID != "" {
+		w.Header().Set("Location", "/job/"+response.JobID)
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// submitPlaybook submits a playbook as a job, unless it was probably already
+// submitted, in which case no job is created
+func (s *SecAutoServer) submitPlaybook(req *PlaybookRequest, playbook []interface{}) JobResponse {
+	// Skip submissions that were probably already submitted
+	duplicate, fingerprint := s.jobManager.CheckDuplicate(playbook, req.Context)
+	if duplicate {
+		return JobResponse{
+			Success:           true,
+			Status:            "duplicate",
+			ProbableDuplicate: true,
+			Fingerprint:       fingerprint,
+			Timestamp:         time.Now().UTC().Format(time.RFC3339),
 		}
-		jobID = s.jobManager.SubmitJob(playbook, req.Context)
-	} else {
-		http.Error(w, "Either playbook or playbook_name must be provided", http.StatusBadRequest)
-		return
 	}
 
-	response := JobResponse{
-		Success:   true,
-		JobID:     jobID,
-		Status:    "pending",
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	priority := req.Priority
+	if priority == "" {
+		priority = defaultJobPriority
+	}
+
+	// Submit job for asynchronous execution
+	source := JobSource{TriggeredBy: req.Caller, ContinueOnError: req.ContinueOnError, Strict: req.Strict}
+	if req.Playbook == nil {
+		source.PlaybookName = req.PlaybookName
+	}
+	jobID := s.jobManager.SubmitJobFrom(source, playbook, req.Context, req.Env, priority)
+
+	return JobResponse{
+		Success:     true,
+		JobID:       jobID,
+		Status:      "pending",
+		Fingerprint: fingerprint,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// executePlaybook runs a playbook synchronously and responds with its results
+func (s *SecAutoServer) executePlaybook(w http.ResponseWriter, req *PlaybookRequest, playbook []interface{}) {
+	// A synchronous run can outlast the server's write timeout, which would
+	// drop the connection before the results are sent, so lift it here
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+		logger.Warning("Failed to clear write deadline for synchronous playbook", map[string]interface{}{
+			"component": "server",
+			"error":     err.Error(),
+		})
+	}
+
+	response, err := s.runPlaybook(req, playbook)
+	if err == errTooManyExecutions {
+		w.Header().Set("Retry-After", "5")
+		http.Error(w, "Too many concurrent playbook executions, set async or retry later", http.StatusServiceUnavailable)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if !response.Success {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 	json.NewEncoder(w).Encode(response)
 }
 
+// errTooManyExecutions is returned by runPlaybook when the limit on
+// concurrent synchronous executions is reached
+var errTooManyExecutions = errors.New("too many concurrent playbook executions")
+
+// runPlaybook executes a playbook synchronously with a context private to the
+// run. A failed playbook is reported in the response; the error is only set
+// when the run was rejected.
+func (s *SecAutoServer) runPlaybook(req *PlaybookRequest, playbook []interface{}) (PlaybookResponse, error) {
+	// Bound concurrent synchronous executions
+	if !s.syncLimiter.TryAcquire() {
+		logger.Warning("Synchronous playbook execution rejected, too many concurrent requests", map[string]interface{}{
+			"component": "server",
+		})
+		return PlaybookResponse{}, errTooManyExecutions
+	}
+	defer s.syncLimiter.Release()
+
+	// Build a context private to this request
+	context := NewPlaybookContext(req.Context)
+	warnings := NewTemplateWarnings()
+	findings := NewPlaybookFindings()
+	engine := s.engine.WithEnv(req.Env).WithTemplateWarnings(warnings).WithFindings(findings).WithContinueOnError(req.ContinueOnError).WithStrictMode(req.Strict)
+
+	// Execute playbook
+	results, err := engine.EvaluatePlaybook(playbook, context)
+
+	response := PlaybookResponse{
+		Warnings:  warnings.List(),
+		Findings:  findings.List(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if abort, ok := err.(*PlaybookAbort); ok {
+		// A deliberate abort is not a failure
+		response.Success = true
+		response.Status = abort.Status
+		response.AbortReason = abort.Reason
+		response.Results = results
+		response.Context = context
+	} else if ruleErrors, ok := err.(*PlaybookRuleErrors); ok {
+		// The playbook ran to the end; only rules allowed to fail did
+		response.Success = true
+		response.Status = jobStatusCompletedWithErrors
+		response.Error = ruleErrors.Error()
+		response.Results = results
+		response.Context = context
+	} else if err != nil {
+		response.Success = false
+		response.Error = err.Error()
+		response.ErrorType = playbookErrorType(err)
+	} else {
+		response.Success = true
+		response.Results = results
+		response.Context = context
+	}
+
+	// Cap the results returned, offloading the full results under an ID of
+	// their own since a synchronous run has no job
+	if len(response.Results) > 0 {
+		response.Results, response.ResultTruncation = resultCap.Apply(newSyncResultsID(), response.Results)
+	}
+
+	// Remember the context of the latest synchronous run for /context
+	s.contextMutex.Lock()
+	s.lastContext = context
+	s.contextMutex.Unlock()
+
+	return response, nil
+}
+
 // jobsHandler handles job listing requests
 func (s *SecAutoServer) jobsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -518,7 +909,7 @@ func (s *SecAutoServer) jobsHandler(w http.ResponseWriter, r *http.Request) {
 
 	response := JobListResponse{
 		Success:   true,
-		Jobs:      jobs,
+		Jobs:      outputRedactor.RedactJobs(jobs),
 		Total:     len(jobs),
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 	}
@@ -537,21 +928,96 @@ func (s *SecAutoServer) jobStatsHandler(w http.ResponseWriter, r *http.Request)
 	stats := s.jobManager.GetStats()
 
 	response := JobStatsResponse{
-		Success:     true,
-		TotalJobs:   stats.TotalJobs,
-		Completed:   stats.Completed,
-		Failed:      stats.Failed,
-		Running:     stats.Running,
-		Pending:     stats.Pending,
-		AvgDuration: stats.AvgDuration,
-		RecentJobs:  stats.RecentJobs,
-		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+		Success:             true,
+		TotalJobs:           stats.TotalJobs,
+		Completed:           stats.Completed,
+		CompletedWithErrors: stats.CompletedWithErrors,
+		Failed:              stats.Failed,
+		Aborted:             stats.Aborted,
+		Skipped:             stats.Skipped,
+		Running:             stats.Running,
+		Pending:             stats.Pending,
+		AvgDuration:         stats.AvgDuration,
+		ResourceUsage:       stats.ResourceUsage,
+		RecentJobs:          outputRedactor.RedactJobs(stats.RecentJobs),
+		Timestamp:           time.Now().UTC().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// archiveHandler returns archived jobs from cold storage
+func (s *SecAutoServer) archiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.jobManager.archiver == nil {
+		http.Error(w, "Job archival not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	// Parse date range (YYYY-MM-DD or RFC3339), defaulting to the last 24 hours
+	to := time.Now().UTC()
+	from := to.Add(-24 * time.Hour)
+	if fromStr := r.URL.Query().Get("from"); fromStr != "" {
+		parsed, err := parseArchiveTime(fromStr, false)
+		if err != nil {
+			http.Error(w, "Invalid from parameter", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if toStr := r.URL.Query().Get("to"); toStr != "" {
+		parsed, err := parseArchiveTime(toStr, true)
+		if err != nil {
+			http.Error(w, "Invalid to parameter", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		http.Error(w, "Parameter to must not be before from", http.StatusBadRequest)
+		return
+	}
+
+	jobs, err := s.jobManager.archiver.ReadArchive(from, to)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read archive: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]interface{}{
+		"success":   true,
+		"jobs":      jobs,
+		"total":     len(jobs),
+		"from":      from.Format(time.RFC3339),
+		"to":        to.Format(time.RFC3339),
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
+// parseArchiveTime parses an archive query date. Plain dates used as an upper
+// bound cover the whole day.
+func parseArchiveTime(value string, endOfDay bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
 // jobMetricsHandler handles database metrics requests
 func (s *SecAutoServer) jobMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -561,9 +1027,25 @@ func (s *SecAutoServer) jobMetricsHandler(w http.ResponseWriter, r *http.Request
 
 	metrics := s.jobManager.store.GetDatabaseMetrics()
 	response := map[string]interface{}{
-		"success":   true,
-		"metrics":   metrics,
-		"timestamp": time.Now().UTC().Format(time.RFC3339),
+		"success":        true,
+		"metrics":        metrics,
+		"sync_execution": s.syncLimiter.Stats(),
+		"job_queue":      s.jobManager.queue.Stats(),
+		"timestamp":      time.Now().UTC().Format(time.RFC3339),
+	}
+	if limiter := getPythonProcessLimiter(s.engine.config.Python.MaxConcurrentProcesses); limiter != nil {
+		response["python_processes"] = limiter.Stats()
+	}
+	if s.jobManager.deduplicator != nil {
+		response["deduplication"] = s.jobManager.deduplicator.Stats()
+	}
+	if s.jobManager.enrichment != nil {
+		response["enrichment_cache"] = s.jobManager.enrichment.Stats()
+	}
+	response["throttle"] = s.jobManager.throttle.Stats()
+	response["events"] = eventBus.Stats()
+	if s.engine.config.Monitoring.PerformanceTracking {
+		response["operations"] = operationMetrics.Stats()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -598,6 +1080,11 @@ func (s *SecAutoServer) pluginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	pluginName := pathParts[1]
 
+	if len(pathParts) == 4 && pathParts[2] == "cache" && pathParts[3] == "clear" {
+		s.pluginCacheClearHandler(w, r, pluginName)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		// Get plugin info
@@ -648,10 +1135,57 @@ func (s *SecAutoServer) pluginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// pluginCacheClearHandler handles POST /plugins/{name}/cache/clear, dropping
+// the cached results of a cacheable plugin
+func (s *SecAutoServer) pluginCacheClearHandler(w http.ResponseWriter, r *http.Request, pluginName string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var info PluginInfo
+	exists := false
+	for key, candidate := range s.pluginManager.GetPluginInfo() {
+		if key == pluginName || candidate.Name == pluginName {
+			info, exists = candidate, true
+			break
+		}
+	}
+	if !exists {
+		http.Error(w, "Plugin not found", http.StatusNotFound)
+		return
+	}
+
+	cleared := pluginResults.clear(info.Name)
+	logger.Info("Plugin result cache cleared", map[string]interface{}{
+		"component": "server",
+		"plugin":    info.Name,
+		"cleared":   cleared,
+	})
+
+	response := map[string]interface{}{
+		"success":   true,
+		"plugin":    info.Name,
+		"cacheable": info.Cacheable,
+		"cleared":   cleared,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // jobHandler handles job status and cancellation requests
 func (s *SecAutoServer) jobHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract job ID from URL path
 	jobID := r.URL.Path[len("/job/"):]
+	showDiff, poll := false, false
+	if strings.HasSuffix(jobID, "/diff") {
+		jobID = strings.TrimSuffix(jobID, "/diff")
+		showDiff = true
+	} else if strings.HasSuffix(jobID, "/poll") {
+		jobID = strings.TrimSuffix(jobID, "/poll")
+		poll = true
+	}
 	if jobID == "" {
 		http.Error(w, "Job ID required", http.StatusBadRequest)
 		return
@@ -673,21 +1207,58 @@ func (s *SecAutoServer) jobHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if showDiff {
+		s.jobDiffHandler(w, r, jobID)
+		return
+	}
+	if poll {
+		s.jobPollHandler(w, r, jobID)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
-		// Get job status
+		// Get job status, waiting up to wait seconds for it to change
+		wait, err := parseJobWait(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		job, exists := s.jobManager.GetJob(jobID)
 		if !exists {
 			http.Error(w, "Job not found", http.StatusNotFound)
 			return
 		}
+		if wait > 0 && !isFinishedJobStatus(job.Status) {
+			if job = s.waitForJobStatus(w, r, job, wait); job == nil {
+				return
+			}
+		}
+
+		response := JobStatusResponse{Job: outputRedactor.RedactJob(job)}
+		if job.Status == "pending" {
+			if position, queued := s.jobManager.QueuePosition(jobID); queued {
+				response.Queue = position
+			}
+		}
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(job)
+		json.NewEncoder(w).Encode(response)
 
 	case http.MethodDelete:
-		// Cancel job
-		success, message := s.jobManager.CancelJob(jobID)
+		// Cancel job; the reason may be given as a query parameter or in a
+		// JSON body
+		reason := r.URL.Query().Get("reason")
+		if reason == "" && r.ContentLength != 0 {
+			var body CancelJobRequest
+			if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&body); err != nil && err != io.EOF {
+				http.Error(w, fmt.Sprintf("Invalid cancel request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			reason = body.Reason
+		}
+		cancelledBy := apiKeyCaller(getRequestAPIKey(r))
+		success, message := s.jobManager.CancelJob(jobID, reason, cancelledBy)
 
 		response := CancelJobResponse{
 			Success:   success,
@@ -696,6 +1267,10 @@ func (s *SecAutoServer) jobHandler(w http.ResponseWriter, r *http.Request) {
 			Message:   message,
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		}
+		if success {
+			response.Reason = reason
+			response.CancelledBy = cancelledBy
+		}
 
 		if !success {
 			w.WriteHeader(http.StatusBadRequest)
@@ -709,6 +1284,41 @@ func (s *SecAutoServer) jobHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// jobDiffHandler returns what a job's playbook run changed in its context
+func (s *SecAutoServer) jobDiffHandler(w http.ResponseWriter, r *http.Request, jobID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	job, exists := s.jobManager.GetJob(jobID)
+	if !exists {
+		http.Error(w, "Job not found", http.StatusNotFound)
+		return
+	}
+
+	// The initial context is only recorded once the run has stored its final context
+	if job.InitialContext == nil {
+		http.Error(w, fmt.Sprintf("Context diff not available: job is %s and has no final context", job.Status), http.StatusConflict)
+		return
+	}
+
+	// The final context is flat, so compare it against the flattened submitted context
+	job = outputRedactor.RedactJob(job)
+	diff := DiffContexts(flattenPlaybookContext(job.InitialContext), job.Context)
+
+	response := JobDiffResponse{
+		Success:   true,
+		JobID:     jobID,
+		Status:    job.Status,
+		Diff:      diff,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // contextHandler handles context retrieval requests
 func (s *SecAutoServer) contextHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -716,7 +1326,13 @@ func (s *SecAutoServer) contextHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	context := s.engine.GetContext()
+	// Context of the most recent synchronous playbook execution
+	s.contextMutex.RLock()
+	context := s.lastContext
+	s.contextMutex.RUnlock()
+	if context == nil {
+		context = map[string]interface{}{}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -725,6 +1341,143 @@ func (s *SecAutoServer) contextHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// csvImportHandler imports CSV rows as playbook context. In "context" mode the rows
+// are returned as an array under a context variable (and submitted as a single job
+// when playbook_name is given); in "jobs" mode one job is submitted per row.
+func (s *SecAutoServer) csvImportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxCSVImportSize+(1<<20))
+	if err := r.ParseMultipartForm(maxCSVImportSize); err != nil {
+		http.Error(w, "Failed to parse form data", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "No CSV file provided", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if ext := strings.ToLower(filepath.Ext(header.Filename)); ext != ".csv" {
+		http.Error(w, "File must have a .csv extension", http.StatusBadRequest)
+		return
+	}
+
+	// Sniff the start of the file, then rewind it for parsing
+	prefix := make([]byte, contentSniffLength)
+	n, _ := io.ReadFull(file, prefix)
+	if typeErr := validateUploadContentType(header.Filename, prefix[:n]); typeErr != nil {
+		http.Error(w, typeErr.Message, http.StatusBadRequest)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Failed to read CSV file", http.StatusBadRequest)
+		return
+	}
+
+	var columnMapping map[string]string
+	if err := json.Unmarshal([]byte(r.FormValue("column_mapping")), &columnMapping); err != nil {
+		http.Error(w, "column_mapping must be a JSON object of CSV column to context path", http.StatusBadRequest)
+		return
+	}
+
+	mode := r.FormValue("mode")
+	if mode == "" {
+		mode = "context"
+	}
+	if mode != "context" && mode != "jobs" {
+		http.Error(w, "mode must be 'context' or 'jobs'", http.StatusBadRequest)
+		return
+	}
+
+	variable := r.FormValue("variable")
+	if variable == "" {
+		variable = defaultCSVContextVariable
+	}
+
+	// Load the playbook up front so a bad name fails before any jobs are submitted
+	var playbook []interface{}
+	playbookName := r.FormValue("playbook_name")
+	if playbookName != "" {
+		playbookName = s.validator.SanitizePath(playbookName)
+		playbook, err = s.engine.LoadPlaybookFromFile(s.engine.getPlaybookPath(playbookName))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to load playbook: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else if mode == "jobs" {
+		http.Error(w, "playbook_name is required in jobs mode", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := ParseCSVContext(file, columnMapping)
+	if err != nil {
+		if importErr, ok := err.(*CSVImportError); ok {
+			response := ValidationResponse{
+				Success:   false,
+				Valid:     false,
+				Errors:    importErr.Errors,
+				Message:   "CSV validation failed",
+				Timestamp: time.Now().UTC().Format(time.RFC3339),
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	response := CSVImportResponse{
+		Success:   true,
+		Mode:      mode,
+		Rows:      len(rows),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	source := JobSource{PlaybookName: playbookName, TriggeredBy: apiKeyCaller(getRequestAPIKey(r))}
+	if mode == "jobs" {
+		response.JobIDs = make([]string, 0, len(rows))
+		for _, row := range rows {
+			response.JobIDs = append(response.JobIDs, s.jobManager.SubmitJobFrom(source, playbook, row, nil, defaultJobPriority))
+		}
+		response.Message = fmt.Sprintf("Submitted %d jobs", len(response.JobIDs))
+	} else {
+		items := make([]interface{}, len(rows))
+		for i, row := range rows {
+			items[i] = row
+		}
+		response.Context = map[string]interface{}{variable: items}
+		response.Message = "CSV rows imported into context"
+		if playbook != nil {
+			response.JobIDs = []string{s.jobManager.SubmitJobFrom(source, playbook, response.Context, nil, defaultJobPriority)}
+			response.Message = "CSV rows imported and job submitted"
+		}
+	}
+
+	logger.Info("CSV context imported", map[string]interface{}{
+		"component": "server",
+		"filename":  header.Filename,
+		"mode":      mode,
+		"rows":      len(rows),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(response.JobIDs) > 0 {
+		if len(response.JobIDs) == 1 {
+			w.Header().Set("Location", "/job/"+response.JobIDs[0])
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
 // webhooksHandler handles webhook configuration requests
 func (s *SecAutoServer) webhooksHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -741,6 +1494,13 @@ func (s *SecAutoServer) webhooksHandler(w http.ResponseWriter, r *http.Request)
 
 	// Validate webhook configuration
 	validationResult := s.validator.ValidateWebhookConfig(&webhookConfig)
+	if err := s.webhookManager.ValidateFilter(webhookConfig.Filter); err != nil {
+		validationResult.Valid = false
+		validationResult.Errors = append(validationResult.Errors, ValidationError{
+			Field:   "filter",
+			Message: err.Error(),
+		})
+	}
 	if !validationResult.Valid {
 		response := ValidationResponse{
 			Success:   false,
@@ -781,10 +1541,11 @@ func (s *SecAutoServer) validateHandler(w http.ResponseWriter, r *http.Request)
 
 	// Parse validation request
 	var req PlaybookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeContextJSON(r.Body, &req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	req.Playbook = floatPlaybookNumbers(req.Playbook)
 
 	// Validate playbook request
 	validationResult := s.validator.ValidatePlaybookRequest(&req)
@@ -830,6 +1591,12 @@ func (s *SecAutoServer) automationUploadHandler(w http.ResponseWriter, r *http.R
 	}
 	defer file.Close()
 
+	s.processAutomationUpload(w, file, header)
+}
+
+// processAutomationUpload validates and saves an automation file and writes the
+// upload response
+func (s *SecAutoServer) processAutomationUpload(w http.ResponseWriter, file multipart.File, header *multipart.FileHeader) {
 	// Validate file
 	validationResult := s.validateAutomationFile(header, file)
 	if !validationResult.Valid {
@@ -849,6 +1616,9 @@ func (s *SecAutoServer) automationUploadHandler(w http.ResponseWriter, r *http.R
 	// Save the automation file
 	automationName, err := s.saveAutomationFile(file, header)
 	if err != nil {
+		if writeStorageQuotaError(w, err) {
+			return
+		}
 		logger.Error("Failed to save automation file", map[string]interface{}{
 			"component": "server",
 			"filename":  header.Filename,
@@ -908,6 +1678,12 @@ func (s *SecAutoServer) playbookUploadHandler(w http.ResponseWriter, r *http.Req
 	}
 	defer file.Close()
 
+	s.processPlaybookUpload(w, file, header)
+}
+
+// processPlaybookUpload validates and saves a playbook file and writes the
+// upload response
+func (s *SecAutoServer) processPlaybookUpload(w http.ResponseWriter, file multipart.File, header *multipart.FileHeader) {
 	// Validate file
 	validationResult := s.validatePlaybookFile(header, file)
 	if !validationResult.Valid {
@@ -924,9 +1700,12 @@ func (s *SecAutoServer) playbookUploadHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	// Save the playbook file
-	playbookName, err := s.savePlaybookFile(file, header)
+	// Save the playbook file (skipped if the content is unchanged)
+	playbookName, hash, changed, err := s.savePlaybookFile(file, header)
 	if err != nil {
+		if writePlaybookReadOnlyError(w, err) || writeStorageQuotaError(w, err) {
+			return
+		}
 		logger.Error("Failed to save playbook file", map[string]interface{}{
 			"component": "server",
 			"filename":  header.Filename,
@@ -937,19 +1716,25 @@ func (s *SecAutoServer) playbookUploadHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	// Return success response
+	message := "Playbook uploaded successfully"
+	if !changed {
+		message = "Playbook unchanged, no update needed"
+	}
 	response := PlaybookUploadResponse{
 		Success:      true,
-		Message:      "Playbook uploaded successfully",
+		Message:      message,
 		PlaybookName: playbookName,
 		Filename:     header.Filename,
 		Size:         header.Size,
+		ContentHash:  hash,
+		Unchanged:    !changed,
 		Timestamp:    time.Now().UTC().Format(time.RFC3339),
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 
-	logger.Info("Playbook uploaded successfully", map[string]interface{}{
+	logger.Info(message, map[string]interface{}{
 		"component": "server",
 		"filename":  header.Filename,
 		"size":      header.Size,
@@ -1059,13 +1844,14 @@ func (s *SecAutoServer) getPlaybookList() ([]PlaybookInfo, error) {
 		operationCounts := s.countPlaybookOperations(playbookData)
 
 		playbook := PlaybookInfo{
-			Name:       playbookName,
-			Filename:   file.Name(),
-			Size:       fileInfo.Size(),
-			RuleCount:  ruleCount,
-			Operations: operationCounts,
-			ModifiedAt: fileInfo.ModTime().UTC().Format(time.RFC3339),
-			IsValid:    true,
+			Name:        playbookName,
+			Filename:    file.Name(),
+			Size:        fileInfo.Size(),
+			RuleCount:   ruleCount,
+			Operations:  operationCounts,
+			ContentHash: contentHash(content),
+			ModifiedAt:  fileInfo.ModTime().UTC().Format(time.RFC3339),
+			IsValid:     true,
 		}
 
 		playbooks = append(playbooks, playbook)
@@ -1079,31 +1865,41 @@ func (s *SecAutoServer) getPlaybookList() ([]PlaybookInfo, error) {
 	return playbooks, nil
 }
 
-// countPlaybookOperations counts the different types of operations in a playbook
-func (s *SecAutoServer) countPlaybookOperations(playbookData []interface{}) map[string]int {
-	operations := make(map[string]int)
-
-	for _, rule := range playbookData {
-		ruleMap, ok := rule.(map[string]interface{})
-		if !ok {
-			continue
-		}
+// playbookOperations lists the operations a rule may hold, in the order
+// evaluateOperation checks them; a rule is counted as the first one it has.
+// Uploaded playbooks are validated against it, so a new operation is
+// registered here only.
+var playbookOperations = []string{
+	"run", "play", "play_async", "if", "switch", "plugin", "macro", "map", "conditional_set", "foreach", "batch",
+	"vars", "try", "jq", "random", "checkpoint", "restore", "throttle", "metric", "group_count", "finding", "context_diff", "abort", "assert",
+	"splunk_log", "elasticsearch_index",
+}
 
-		for op := range ruleMap {
-			switch op {
-			case "run":
-				operations["run"]++
-			case "if":
-				operations["if"]++
-			case "play":
-				operations["play"]++
-			case "plugin":
-				operations["plugin"]++
-			}
+// isPlaybookOperation holds every entry of playbookOperations, for checking
+// a rule key against them
+var isPlaybookOperation = func() map[string]bool {
+	ops := make(map[string]bool, len(playbookOperations))
+	for _, op := range playbookOperations {
+		ops[op] = true
+	}
+	return ops
+}()
+
+// playbookOperationType returns the operation a rule performs, or "" if it
+// is not a playbook operation
+func playbookOperationType(rule map[string]interface{}) string {
+	for _, op := range playbookOperations {
+		if _, exists := rule[op]; exists {
+			return op
 		}
 	}
+	return ""
+}
 
-	return operations
+// countPlaybookOperations counts the different types of operations in a
+// playbook, including those nested in if branches, loops and try blocks
+func (s *SecAutoServer) countPlaybookOperations(playbookData []interface{}) map[string]int {
+	return analyzePlaybook(playbookData).Operations
 }
 
 // validateAutomationFile validates the uploaded automation file
@@ -1128,12 +1924,8 @@ func (s *SecAutoServer) validateAutomationFile(header *multipart.FileHeader, fil
 	}
 
 	// Check filename for security
-	if !s.validator.IsValidFilename(header.Filename) {
-		errors = append(errors, ValidationError{
-			Field:   "filename",
-			Message: "Invalid filename",
-			Value:   header.Filename,
-		})
+	if filenameErr := s.validator.ValidateFilename(header.Filename); filenameErr != nil {
+		errors = append(errors, *filenameErr)
 	}
 
 	// Read and validate file content
@@ -1146,6 +1938,11 @@ func (s *SecAutoServer) validateAutomationFile(header *multipart.FileHeader, fil
 		return ValidationResult{Valid: false, Errors: errors}
 	}
 
+	// Check the content is of the type the extension claims
+	if typeErr := validateUploadContentType(header.Filename, content); typeErr != nil {
+		errors = append(errors, *typeErr)
+	}
+
 	// Check for dangerous content
 	if s.containsDangerousContent(content) {
 		errors = append(errors, ValidationError{
@@ -1154,6 +1951,9 @@ func (s *SecAutoServer) validateAutomationFile(header *multipart.FileHeader, fil
 		})
 	}
 
+	// Check integration imports against the allowlist
+	errors = append(errors, s.validateIntegrationImports(content)...)
+
 	// Check for required Python structure
 	if !s.isValidPythonScript(content) {
 		errors = append(errors, ValidationError{
@@ -1337,6 +2137,13 @@ func (s *SecAutoServer) saveAutomationFile(file multipart.File, header *multipar
 	// Create full path
 	filepath := filepath.Join(automationsDir, filename)
 
+	// Hold the storage lock until the file is written
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	if err := s.checkStorageQuota("automations", filepath, header.Size); err != nil {
+		return "", err
+	}
+
 	// Create the file
 	dst, err := os.Create(filepath)
 	if err != nil {
@@ -1376,12 +2183,8 @@ func (s *SecAutoServer) validatePlaybookFile(header *multipart.FileHeader, file
 	}
 
 	// Check filename for security
-	if !s.validator.IsValidFilename(header.Filename) {
-		errors = append(errors, ValidationError{
-			Field:   "filename",
-			Message: "Invalid filename",
-			Value:   header.Filename,
-		})
+	if filenameErr := s.validator.ValidateFilename(header.Filename); filenameErr != nil {
+		errors = append(errors, *filenameErr)
 	}
 
 	// Read and validate file content
@@ -1394,6 +2197,11 @@ func (s *SecAutoServer) validatePlaybookFile(header *multipart.FileHeader, file
 		return ValidationResult{Valid: false, Errors: errors}
 	}
 
+	// Check the content is of the type the extension claims
+	if typeErr := validateUploadContentType(header.Filename, content); typeErr != nil {
+		errors = append(errors, *typeErr)
+	}
+
 	// Validate JSON structure
 	if !s.isValidPlaybookJSON(content) {
 		errors = append(errors, ValidationError{
@@ -1447,14 +2255,49 @@ func (s *SecAutoServer) validatePlaybookStructure(content []byte) error {
 		// Check for valid operations
 		hasValidOp := false
 		for op := range ruleMap {
-			switch op {
-			case "run", "if", "play", "plugin":
+			// Any JSONLogic operator may be a rule in JSONLogic mode
+			if isPlaybookOperation[op] || (s.engine.jsonLogic && jsonLogicOperators[op]) {
 				hasValidOp = true
 			}
 		}
 
 		if !hasValidOp {
-			return fmt.Errorf("rule %d must contain a valid operation (run, if, play, plugin)", i+1)
+			return fmt.Errorf("rule %d must contain a valid operation (%s)", i+1, strings.Join(playbookOperations, ", "))
+		}
+
+		// Reject invalid metric names and labels before the playbook is saved
+		if spec, exists := ruleMap["metric"]; exists {
+			if _, _, err := validateMetricSpec(spec); err != nil {
+				return fmt.Errorf("rule %d: %v", i+1, err)
+			}
+		}
+
+		// continue_on_error is a rule flag, not an operation
+		if flag, exists := ruleMap["continue_on_error"]; exists {
+			if _, ok := flag.(bool); !ok {
+				return fmt.Errorf("rule %d: continue_on_error must be true or false", i+1)
+			}
+		}
+
+		// Reject group_count operations without an array and a field to count by
+		if spec, exists := ruleMap["group_count"]; exists {
+			if _, _, err := parseGroupCountSpec(spec); err != nil {
+				return fmt.Errorf("rule %d: %v", i+1, err)
+			}
+		}
+
+		// Reject malformed switch operations before the playbook is saved
+		if spec, exists := ruleMap["switch"]; exists {
+			if err := validateSwitchSpec(spec); err != nil {
+				return fmt.Errorf("rule %d: %v", i+1, err)
+			}
+		}
+
+		// Reject jq queries that do not compile before the playbook is saved
+		if query := jqRuleQuery(ruleMap["jq"]); query != "" {
+			if _, err := compileJQQuery(query); err != nil {
+				return fmt.Errorf("rule %d: %v", i+1, err)
+			}
 		}
 	}
 
@@ -1462,11 +2305,11 @@ func (s *SecAutoServer) validatePlaybookStructure(content []byte) error {
 }
 
 // savePlaybookFile saves the uploaded playbook file
-func (s *SecAutoServer) savePlaybookFile(file multipart.File, header *multipart.FileHeader) (string, error) {
+func (s *SecAutoServer) savePlaybookFile(file multipart.File, header *multipart.FileHeader) (string, string, bool, error) {
 	// Create playbooks directory if it doesn't exist
 	playbooksDir := "../playbooks"
 	if err := os.MkdirAll(playbooksDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create playbooks directory: %v", err)
+		return "", "", false, fmt.Errorf("failed to create playbooks directory: %v", err)
 	}
 
 	// Generate safe filename
@@ -1482,22 +2325,35 @@ func (s *SecAutoServer) savePlaybookFile(file multipart.File, header *multipart.
 
 	// Create full path
 	filepath := filepath.Join(playbooksDir, filename)
+	playbookName := strings.TrimSuffix(filename, ".json")
+	if err := s.checkPlaybookWritable(playbookName); err != nil {
+		return "", "", false, err
+	}
 
-	// Create the file
-	dst, err := os.Create(filepath)
+	// Read the upload and hash it
+	content, err := io.ReadAll(file)
 	if err != nil {
-		return "", fmt.Errorf("failed to create file: %v", err)
+		return "", "", false, fmt.Errorf("failed to read file: %v", err)
 	}
-	defer dst.Close()
+	hash := contentHash(content)
 
-	// Copy the uploaded file to the destination
-	if _, err := io.Copy(dst, file); err != nil {
-		return "", fmt.Errorf("failed to save file: %v", err)
+	// Leave the existing file untouched if the content is identical
+	if existing, err := os.ReadFile(filepath); err == nil && contentHash(existing) == hash {
+		return playbookName, hash, false, nil
 	}
 
-	// Return the playbook name (without extension)
-	playbookName := strings.TrimSuffix(filename, ".json")
-	return playbookName, nil
+	// Hold the storage lock until the file is written
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	if err := s.checkStorageQuota("playbooks", filepath, int64(len(content))); err != nil {
+		return "", "", false, err
+	}
+
+	if err := os.WriteFile(filepath, content, 0644); err != nil {
+		return "", "", false, fmt.Errorf("failed to save file: %v", err)
+	}
+
+	return playbookName, hash, true, nil
 }
 
 // executeJob executes a job in the worker pool
@@ -1529,6 +2385,48 @@ func (jm *JobManager) executeJob(jobID string) {
 		return
 	}
 
+	// Wait for a worker; pending jobs start in priority order
+	if !jm.queue.Acquire(jobID, job.Priority) {
+		logger.Info("Job left the queue before starting", map[string]interface{}{
+			"component": "job_manager",
+			"job_id":    jobID,
+		})
+		return
+	}
+	started := time.Now()
+	defer func() {
+		jm.queue.Release(time.Since(started))
+	}()
+
+	// The job may have been cancelled while it waited; checking and marking
+	// it running in one update keeps a cancel from being overwritten
+	marked, err := jm.store.UpdateJob(jobID, func(stored *Job) bool {
+		if stored.Status != "pending" {
+			return false
+		}
+		stored.Status = "running"
+		stored.StartedAt = &started
+		job = stored
+		return true
+	})
+	if err != nil {
+		logger.Error("Failed to mark job as running", map[string]interface{}{
+			"component": "job_manager",
+			"job_id":    jobID,
+			"error":     err.Error(),
+		})
+	}
+	if !marked {
+		return
+	}
+
+	eventBus.Publish(ActivityEvent{
+		Type:     "job_started",
+		JobID:    jobID,
+		Playbook: job.PlaybookName,
+		Status:   "running",
+	})
+
 	// Log before loading config
 	logger.Info("Before LoadConfig", map[string]interface{}{"job_id": jobID})
 	config, err := LoadConfig("config.yaml")
@@ -1538,7 +2436,15 @@ func (jm *JobManager) executeJob(jobID string) {
 	}
 	logger.Info("After LoadConfig", map[string]interface{}{"job_id": jobID})
 
-	engine := NewRuleEngine(config)
+	warnings := NewTemplateWarnings()
+	findings := NewPlaybookFindings()
+	provenance := NewExecutionProvenance(job.Playbook)
+	resourceUsage := newJobResourceUsageFromConfig(config)
+	engine := NewRuleEngine(config).WithEnv(job.Env).WithTemplateWarnings(warnings).WithFindings(findings).WithProvenance(provenance).WithResourceUsage(resourceUsage).WithContinueOnError(job.ContinueOnError).WithStrictMode(job.Strict)
+	engine.SetIntegrationConfigManager(jm.integrationConfigManager)
+	engine.SetEnrichmentCache(jm.enrichment)
+	engine.SetThrottle(jm.throttle)
+	engine.SetJobManager(jm)
 
 	// Create platform-aware plugin manager for job execution
 	jobPluginManager, err := NewPlatformPluginManager(config)
@@ -1555,18 +2461,31 @@ func (jm *JobManager) executeJob(jobID string) {
 	// Set plugin manager on rule engine
 	engine.SetPluginManager(jobPluginManager)
 
-	// Log before setting context
-	logger.Info("Before SetContext", map[string]interface{}{
+	// Record the plugin versions this run executes with
+	if pluginNames := collectPluginReferences(job.Playbook); len(pluginNames) > 0 {
+		versions := jobPluginManager.GetPluginVersions(pluginNames)
+		job.PluginVersionsAtExecution = versions
+		if _, err := jm.store.UpdateJob(jobID, func(stored *Job) bool {
+			stored.PluginVersionsAtExecution = versions
+			return true
+		}); err != nil {
+			logger.Error("Failed to record plugin versions for job", map[string]interface{}{
+				"component": "job_manager",
+				"job_id":    jobID,
+				"error":     err.Error(),
+			})
+		}
+	}
+
+	// Log before building context
+	logger.Info("Before NewPlaybookContext", map[string]interface{}{
 		"job_id":       jobID,
 		"context":      job.Context,
 		"context_type": fmt.Sprintf("%T", job.Context),
 		"context_keys": len(job.Context),
 	})
-	engine.SetContext(job.Context)
-	logger.Info("After SetContext", map[string]interface{}{"job_id": jobID})
-
-	// Add a simple test log to see if we reach this point
-	logger.Info("After SetContext - test log", map[string]interface{}{"job_id": jobID})
+	jobContext := NewPlaybookContext(job.Context)
+	logger.Info("After NewPlaybookContext", map[string]interface{}{"job_id": jobID})
 
 	// Log before playbook evaluation - be more careful with the playbook logging
 	logger.Info("Before EvaluatePlaybook", map[string]interface{}{
@@ -1603,10 +2522,55 @@ func (jm *JobManager) executeJob(jobID string) {
 		}
 	}()
 
-	results, err := engine.EvaluatePlaybook(job.Playbook)
+	results, err := engine.EvaluatePlaybook(job.Playbook, jobContext)
 	logger.Info("After EvaluatePlaybook", map[string]interface{}{"job_id": jobID, "results": results, "err": err})
 
-	if err != nil {
+	// Record the run's warnings, findings, error type, the code it ran and the
+	// resources it used
+	errorType := playbookErrorType(err)
+	if _, err := jm.store.UpdateJob(jobID, func(stored *Job) bool {
+		stored.Warnings = warnings.List()
+		stored.Findings = findings.List()
+		stored.ErrorType = errorType
+		stored.Provenance = provenance
+		stored.ResourceUsage = resourceUsage
+		return true
+	}); err != nil {
+		logger.Error("Failed to record run outcome for job", map[string]interface{}{
+			"component": "job_manager",
+			"job_id":    jobID,
+			"error":     err.Error(),
+		})
+	}
+
+	if abort, ok := err.(*PlaybookAbort); ok {
+		logger.Info("Playbook aborted, updating job status", map[string]interface{}{
+			"component": "job_manager",
+			"job_id":    jobID,
+			"status":    abort.Status,
+			"reason":    abort.Reason,
+		})
+		if abort.Reason != "" {
+			if _, err := jm.store.UpdateJob(jobID, func(stored *Job) bool {
+				stored.AbortReason = abort.Reason
+				return true
+			}); err != nil {
+				logger.Error("Failed to record abort reason for job", map[string]interface{}{
+					"component": "job_manager",
+					"job_id":    jobID,
+					"error":     err.Error(),
+				})
+			}
+		}
+		jm.updateJobStatusWithContext(jobID, abort.Status, results, "", jobContext)
+	} else if ruleErrors, ok := err.(*PlaybookRuleErrors); ok {
+		logger.Info("Playbook finished with failed rules, updating job status", map[string]interface{}{
+			"component": "job_manager",
+			"job_id":    jobID,
+			"failed":    ruleErrors.Count,
+		})
+		jm.updateJobStatusWithContext(jobID, jobStatusCompletedWithErrors, results, ruleErrors.Error(), jobContext)
+	} else if err != nil {
 		logger.Info("Playbook evaluation failed, updating job status to failed", map[string]interface{}{
 			"component": "job_manager",
 			"job_id":    jobID,
@@ -1614,8 +2578,8 @@ func (jm *JobManager) executeJob(jobID string) {
 		})
 		jm.updateJobStatus(jobID, "failed", nil, err.Error())
 	} else {
-		// Get the final updated context from the engine
-		finalContext := engine.GetContext()
+		// The job context now holds the final updated context
+		finalContext := jobContext
 		logger.Info("Playbook evaluation succeeded, updating job status to completed", map[string]interface{}{
 			"component":    "job_manager",
 			"job_id":       jobID,
@@ -1716,6 +2680,8 @@ func (jm *JobManager) updateJobStatusWithContext(jobID, status string, results [
 		eventType := "job_completed"
 		if status == "failed" {
 			eventType = "job_failed"
+		} else if isAbortStatus(status) {
+			eventType = "job_aborted"
 		}
 
 		jm.webhookManager.SendWebhook(WebhookEvent{
@@ -1727,11 +2693,46 @@ func (jm *JobManager) updateJobStatusWithContext(jobID, status string, results [
 			Context:   job.Context,
 			Results:   results,
 			Error:     errorMsg,
+			Reason:    job.AbortReason,
 			Duration:  duration,
+			Findings:  job.Findings,
 		})
 	}
 }
 
+// newHTTPServer creates the API server with the configured timeouts and header
+// limit. An empty timeout leaves that timeout disabled.
+func newHTTPServer(addr string, config ServerConfig) (*http.Server, error) {
+	timeouts := map[string]string{
+		"read_timeout":  config.ReadTimeout,
+		"write_timeout": config.WriteTimeout,
+		"idle_timeout":  config.IdleTimeout,
+	}
+	parsed := make(map[string]time.Duration, len(timeouts))
+	for name, value := range timeouts {
+		if value == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %v", name, value, err)
+		}
+		if duration < 0 {
+			return nil, fmt.Errorf("%s must not be negative", name)
+		}
+		parsed[name] = duration
+	}
+
+	return &http.Server{
+		Addr:              addr,
+		ReadTimeout:       parsed["read_timeout"],
+		ReadHeaderTimeout: parsed["read_timeout"],
+		WriteTimeout:      parsed["write_timeout"],
+		IdleTimeout:       parsed["idle_timeout"],
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+	}, nil
+}
+
 // getEnv gets an environment variable or returns a default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -1784,10 +2785,11 @@ func (s *SecAutoServer) clusterJobsHandler(w http.ResponseWriter, r *http.Reques
 
 	// Parse request
 	var req PlaybookRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := decodeContextJSON(r.Body, &req); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	req.Playbook = floatPlaybookNumbers(req.Playbook)
 
 	// Validate request
 	validationResult := s.validator.ValidatePlaybookRequest(&req)
@@ -1799,8 +2801,8 @@ func (s *SecAutoServer) clusterJobsHandler(w http.ResponseWriter, r *http.Reques
 			Message:   "Validation failed",
 			Timestamp: time.Now().UTC().Format(time.RFC3339),
 		}
-		w.WriteHeader(http.StatusBadRequest)
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
@@ -1810,7 +2812,7 @@ func (s *SecAutoServer) clusterJobsHandler(w http.ResponseWriter, r *http.Reques
 	var err error
 
 	if req.Playbook != nil {
-		jobID, err = s.clusterManager.SubmitJob(req.Playbook, req.Context)
+		jobID, err = s.clusterManager.SubmitJob(req.Playbook, req.Context, req.Env)
 	} else if req.PlaybookName != "" {
 		// Load playbook from file and submit
 		playbookPath := s.engine.getPlaybookPath(req.PlaybookName)
@@ -1819,15 +2821,15 @@ func (s *SecAutoServer) clusterJobsHandler(w http.ResponseWriter, r *http.Reques
 			http.Error(w, fmt.Sprintf("Failed to load playbook: %v", err), http.StatusBadRequest)
 			return
 		}
-		jobID, err = s.clusterManager.SubmitJob(playbook, req.Context)
+		jobID, err = s.clusterManager.SubmitJob(playbook, req.Context, req.Env)
 		if err != nil {
 			response := map[string]interface{}{
 				"success":   false,
 				"error":     err.Error(),
 				"timestamp": time.Now().UTC().Format(time.RFC3339),
 			}
-			w.WriteHeader(http.StatusInternalServerError)
 			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(response)
 			return
 		}
@@ -1842,8 +2844,8 @@ func (s *SecAutoServer) clusterJobsHandler(w http.ResponseWriter, r *http.Reques
 			"error":     err.Error(),
 			"timestamp": time.Now().UTC().Format(time.RFC3339),
 		}
-		w.WriteHeader(http.StatusInternalServerError)
 		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(response)
 		return
 	}
@@ -1856,6 +2858,8 @@ func (s *SecAutoServer) clusterJobsHandler(w http.ResponseWriter, r *http.Reques
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/cluster/jobs/"+jobID)
+	w.WriteHeader(http.StatusAccepted)
 	json.NewEncoder(w).Encode(response)
 }
 
@@ -1892,7 +2896,7 @@ func (s *SecAutoServer) clusterJobHandler(w http.ResponseWriter, r *http.Request
 
 		response := map[string]interface{}{
 			"success":   true,
-			"job":       job,
+			"job":       outputRedactor.RedactJob(job.jobRecord()),
 			"timestamp": time.Now().UTC().Format(time.RFC3339),
 		}
 		w.Header().Set("Content-Type", "application/json")
@@ -1934,10 +2938,11 @@ func (s *SecAutoServer) schedulesHandler(w http.ResponseWriter, r *http.Request)
 	case http.MethodPost:
 		// Create new schedule
 		var schedule JobSchedule
-		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		if err := decodeContextJSON(r.Body, &schedule); err != nil {
 			http.Error(w, "Invalid JSON", http.StatusBadRequest)
 			return
 		}
+		schedule.Playbook = floatPlaybookNumbers(schedule.Playbook)
 
 		if err := s.jobScheduler.CreateSchedule(&schedule); err != nil {
 			response := map[string]interface{}{
@@ -1993,35 +2998,57 @@ func (s *SecAutoServer) scheduleHandler(w http.ResponseWriter, r *http.Request)
 			"schedule":  schedule,
 			"timestamp": time.Now().UTC().Format(time.RFC3339),
 		}
+		w.Header().Set("ETag", formatETag(schedule.Revision))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 
 	case http.MethodPut:
+		// Updates must carry the revision they were based on
+		revision, present, err := parseIfMatch(r)
+		if !present {
+			http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Update schedule
 		var schedule JobSchedule
-		if err := json.NewDecoder(r.Body).Decode(&schedule); err != nil {
+		if err := decodeContextJSON(r.Body, &schedule); err != nil {
 			http.Error(w, "Invalid JSON", http.StatusBadRequest)
 			return
 		}
+		schedule.Playbook = floatPlaybookNumbers(schedule.Playbook)
 		schedule.ID = scheduleID
+		schedule.Revision = revision
 
 		if err := s.jobScheduler.UpdateSchedule(&schedule); err != nil {
+			statusCode := http.StatusBadRequest
+			if err == errRevisionConflict {
+				statusCode = http.StatusPreconditionFailed
+			}
 			response := map[string]interface{}{
 				"success":   false,
 				"error":     err.Error(),
 				"timestamp": time.Now().UTC().Format(time.RFC3339),
 			}
-			w.WriteHeader(http.StatusBadRequest)
+			w.WriteHeader(statusCode)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(response)
 			return
 		}
 
+		updated, _ := s.jobScheduler.GetSchedule(scheduleID)
 		response := map[string]interface{}{
 			"success":   true,
-			"schedule":  schedule,
+			"schedule":  updated,
 			"timestamp": time.Now().UTC().Format(time.RFC3339),
 		}
+		if updated != nil {
+			w.Header().Set("ETag", formatETag(updated.Revision))
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 
@@ -2151,6 +3178,7 @@ func (s *SecAutoServer) getAutomationList() ([]AutomationInfo, error) {
 			LineCount:     analysis.LineCount,
 			FunctionCount: analysis.FunctionCount,
 			ImportCount:   analysis.ImportCount,
+			ContentHash:   contentHash(content),
 			ModifiedAt:    fileInfo.ModTime().UTC().Format(time.RFC3339),
 			IsValid:       analysis.IsValid,
 		}
@@ -2498,6 +3526,10 @@ func (s *SecAutoServer) playbookDeleteHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 	playbookName := pathParts[2]
+	if err := s.checkPlaybookWritable(playbookName); err != nil {
+		writePlaybookReadOnlyError(w, err)
+		return
+	}
 
 	// Delete the playbook file
 	err := s.deletePlaybookFile(playbookName)
@@ -2595,6 +3627,12 @@ func (s *SecAutoServer) pluginUploadHandler(w http.ResponseWriter, r *http.Reque
 	}
 	defer file.Close()
 
+	s.processPluginUpload(w, file, header, pluginType)
+}
+
+// processPluginUpload validates and saves a plugin file of the given type and
+// writes the upload response
+func (s *SecAutoServer) processPluginUpload(w http.ResponseWriter, file multipart.File, header *multipart.FileHeader, pluginType string) {
 	// Validate file
 	validationResult := s.validatePluginFile(header, file, pluginType)
 	if !validationResult.Valid {
@@ -2614,6 +3652,9 @@ func (s *SecAutoServer) pluginUploadHandler(w http.ResponseWriter, r *http.Reque
 	// Save the plugin file
 	pluginName, err := s.savePluginFile(file, header, pluginType)
 	if err != nil {
+		if writeStorageQuotaError(w, err) {
+			return
+		}
 		logger.Error("Failed to save plugin file", map[string]interface{}{
 			"component": "server",
 			"filename":  header.Filename,
@@ -2681,12 +3722,8 @@ func (s *SecAutoServer) validatePluginFile(header *multipart.FileHeader, file mu
 	}
 
 	// Check filename for security
-	if !s.validator.IsValidFilename(header.Filename) {
-		errors = append(errors, ValidationError{
-			Field:   "filename",
-			Message: "Invalid filename",
-			Value:   header.Filename,
-		})
+	if filenameErr := s.validator.ValidateFilename(header.Filename); filenameErr != nil {
+		errors = append(errors, *filenameErr)
 	}
 
 	// Read and validate file content
@@ -2699,6 +3736,11 @@ func (s *SecAutoServer) validatePluginFile(header *multipart.FileHeader, file mu
 		return ValidationResult{Valid: false, Errors: errors}
 	}
 
+	// Check the content is of the type the extension claims
+	if typeErr := validateUploadContentType(header.Filename, content); typeErr != nil {
+		errors = append(errors, *typeErr)
+	}
+
 	// Validate plugin content based on type
 	if !s.isValidPluginContent(content, pluginType) {
 		errors = append(errors, ValidationError{
@@ -2776,6 +3818,13 @@ func (s *SecAutoServer) savePluginFile(file multipart.File, header *multipart.Fi
 	// Create full path
 	fullPath := filepath.Join(platformDir, filename)
 
+	// Hold the storage lock until the file is written
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	if err := s.checkStorageQuota("plugins", fullPath, header.Size); err != nil {
+		return "", err
+	}
+
 	// Create the file
 	dst, err := os.Create(fullPath)
 	if err != nil {
@@ -2884,6 +3933,64 @@ func (s *SecAutoServer) deletePluginFile(pluginName, pluginType string) error {
 	return fmt.Errorf("plugin '%s' of type '%s' not found", pluginName, pluginType)
 }
 
+// searchHandler searches asset names and contents across playbooks, automations and integrations
+func (s *SecAutoServer) searchHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		http.Error(w, "Query parameter q is required", http.StatusBadRequest)
+		return
+	}
+	if len(query) > maxSearchQueryLength {
+		http.Error(w, fmt.Sprintf("Query must be at most %d characters", maxSearchQueryLength), http.StatusBadRequest)
+		return
+	}
+
+	// Parse requested asset types, defaulting to all
+	types := []string{"playbook", "automation", "integration"}
+	if typesStr := r.URL.Query().Get("types"); typesStr != "" {
+		types = nil
+		for _, assetType := range strings.Split(typesStr, ",") {
+			assetType = strings.TrimSpace(assetType)
+			if _, ok := searchAssetDirs[assetType]; !ok {
+				http.Error(w, fmt.Sprintf("Unknown search type: %s", assetType), http.StatusBadRequest)
+				return
+			}
+			types = append(types, assetType)
+		}
+	}
+
+	limit := defaultSearchLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			limit = l
+		}
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	searcher := NewAssetSearcher(query, limit, s.validator, s.integrationConfigManager)
+	matches := searcher.Search(types)
+
+	response := SearchResponse{
+		Success:   true,
+		Query:     query,
+		Types:     types,
+		Matches:   matches,
+		Count:     len(matches),
+		Truncated: searcher.Truncated(),
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // integrationsHandler handles integration configuration management
 func (s *SecAutoServer) integrationsHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -2897,12 +4004,11 @@ func (s *SecAutoServer) integrationsHandler(w http.ResponseWriter, r *http.Reque
 			Timestamp:    time.Now().UTC().Format(time.RFC3339),
 		}
 
-		// Include integration names in the response
+		// Include integration names in the response, with secrets masked
 		for name, config := range configs {
-			// Create a copy with the name included
-			configCopy := *config
+			configCopy := config.Redacted()
 			configCopy.Name = name // Ensure the name is set correctly
-			response.Integrations = append(response.Integrations, &configCopy)
+			response.Integrations = append(response.Integrations, configCopy)
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -2910,11 +4016,21 @@ func (s *SecAutoServer) integrationsHandler(w http.ResponseWriter, r *http.Reque
 
 	case http.MethodPost:
 		// Create new integration
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
 		var config IntegrationConfig
-		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		if err := json.Unmarshal(body, &config); err != nil {
 			http.Error(w, "Invalid JSON", http.StatusBadRequest)
 			return
 		}
+		fingerprint, err := integrationRequestFingerprint(r, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Validate configuration
 		if err := s.integrationConfigManager.ValidateConfig(&config); err != nil {
@@ -2929,6 +4045,9 @@ func (s *SecAutoServer) integrationsHandler(w http.ResponseWriter, r *http.Reque
 			return
 		}
 
+		// Creation does not carry a precondition revision
+		config.Revision = 0
+
 		// Use the provided name or generate from type
 		integrationName := config.Name
 		if integrationName == "" {
@@ -2939,6 +4058,11 @@ func (s *SecAutoServer) integrationsHandler(w http.ResponseWriter, r *http.Reque
 			return
 		}
 
+		// A retried creation gets the original response
+		if s.replayIntegrationResponse(w, integrationName, fingerprint) {
+			return
+		}
+
 		// Set configuration
 		if err := s.integrationConfigManager.SetConfig(integrationName, &config); err != nil {
 			response := IntegrationResponse{
@@ -2955,12 +4079,11 @@ func (s *SecAutoServer) integrationsHandler(w http.ResponseWriter, r *http.Reque
 		response := IntegrationResponse{
 			Success:     true,
 			Message:     "Integration created successfully",
-			Integration: &config,
+			Integration: config.Redacted(),
 			Timestamp:   time.Now().UTC().Format(time.RFC3339),
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		s.writeIntegrationResponse(w, http.StatusOK, "", response, integrationName, fingerprint)
 
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -2977,6 +4100,12 @@ func (s *SecAutoServer) integrationHandler(w http.ResponseWriter, r *http.Reques
 	}
 	integrationName := pathParts[1]
 
+	// /integrations/{name}/reveal returns unmasked secrets to admin keys only
+	if len(pathParts) == 3 && pathParts[2] == "reveal" {
+		s.integrationRevealHandler(w, r, integrationName)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		// Get integration configuration
@@ -2986,27 +4115,61 @@ func (s *SecAutoServer) integrationHandler(w http.ResponseWriter, r *http.Reques
 			return
 		}
 
-		// Ensure the name is set correctly in the response
-		configCopy := *config
+		// Mask secrets and ensure the name is set correctly in the response
+		configCopy := config.Redacted()
 		configCopy.Name = integrationName
 
 		response := IntegrationResponse{
 			Success:     true,
 			Message:     "Integration retrieved successfully",
-			Integration: &configCopy,
+			Integration: configCopy,
 			Timestamp:   time.Now().UTC().Format(time.RFC3339),
 		}
 
+		w.Header().Set("ETag", formatETag(config.Revision))
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 
 	case http.MethodPut:
+		// Updates must carry the revision they were based on
+		revision, present, err := parseIfMatch(r)
+		if !present {
+			http.Error(w, "If-Match header is required", http.StatusPreconditionRequired)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		// Update integration configuration
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
 		var config IntegrationConfig
-		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+		if err := json.Unmarshal(body, &config); err != nil {
 			http.Error(w, "Invalid JSON", http.StatusBadRequest)
 			return
 		}
+		config.Revision = revision
+
+		// A retried update gets the original response rather than a
+		// revision conflict
+		fingerprint, err := integrationRequestFingerprint(r, body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if s.replayIntegrationResponse(w, integrationName, fingerprint) {
+			return
+		}
+
+		// Secrets sent back as the masks GET returned keep their stored values
+		if stored, exists := s.integrationConfigManager.GetConfig(integrationName); exists {
+			config.RestoreMaskedSecrets(stored)
+		}
 
 		// Validate configuration
 		if err := s.integrationConfigManager.ValidateConfig(&config); err != nil {
@@ -3023,12 +4186,16 @@ func (s *SecAutoServer) integrationHandler(w http.ResponseWriter, r *http.Reques
 
 		// Update configuration
 		if err := s.integrationConfigManager.SetConfig(integrationName, &config); err != nil {
+			statusCode := http.StatusInternalServerError
+			if err == errRevisionConflict {
+				statusCode = http.StatusPreconditionFailed
+			}
 			response := IntegrationResponse{
 				Success:   false,
 				Message:   fmt.Sprintf("Failed to update integration: %v", err),
 				Timestamp: time.Now().UTC().Format(time.RFC3339),
 			}
-			w.WriteHeader(http.StatusInternalServerError)
+			w.WriteHeader(statusCode)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(response)
 			return
@@ -3037,12 +4204,11 @@ func (s *SecAutoServer) integrationHandler(w http.ResponseWriter, r *http.Reques
 		response := IntegrationResponse{
 			Success:     true,
 			Message:     "Integration updated successfully",
-			Integration: &config,
+			Integration: config.Redacted(),
 			Timestamp:   time.Now().UTC().Format(time.RFC3339),
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+		s.writeIntegrationResponse(w, http.StatusOK, formatETag(config.Revision), response, integrationName, fingerprint)
 
 	case http.MethodDelete:
 		// Delete integration configuration
@@ -3072,6 +4238,56 @@ func (s *SecAutoServer) integrationHandler(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// integrationRevealHandler returns an integration configuration with secrets unmasked
+func (s *SecAutoServer) integrationRevealHandler(w http.ResponseWriter, r *http.Request, integrationName string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isAdminRequest(r) {
+		logger.Warning("Non-admin attempt to reveal integration secrets", map[string]interface{}{
+			"component":   "integrations",
+			"integration": integrationName,
+			"remote_addr": r.RemoteAddr,
+		})
+		http.Error(w, "Forbidden: admin API key required", http.StatusForbidden)
+		return
+	}
+
+	// env: and vault: references are revealed as the secrets they name
+	config, exists, err := s.integrationConfigManager.GetResolvedConfig(integrationName)
+	if !exists {
+		http.Error(w, "Integration not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to resolve integration secrets", map[string]interface{}{
+			"component":   "integrations",
+			"integration": integrationName,
+			"error":       err.Error(),
+		})
+		http.Error(w, fmt.Sprintf("Failed to resolve integration secrets: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	logger.Info("Integration secrets revealed", map[string]interface{}{
+		"component":   "integrations",
+		"integration": integrationName,
+		"remote_addr": r.RemoteAddr,
+	})
+
+	response := IntegrationResponse{
+		Success:     true,
+		Message:     "Integration retrieved successfully",
+		Integration: config,
+		Timestamp:   time.Now().UTC().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // integrationUploadHandler handles integration file uploads
 func (s *SecAutoServer) integrationUploadHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -3101,6 +4317,12 @@ func (s *SecAutoServer) integrationUploadHandler(w http.ResponseWriter, r *http.
 	}
 	defer file.Close()
 
+	s.processIntegrationUpload(w, file, header)
+}
+
+// processIntegrationUpload validates and saves an integration file and writes the
+// upload response
+func (s *SecAutoServer) processIntegrationUpload(w http.ResponseWriter, file multipart.File, header *multipart.FileHeader) {
 	// Validate file
 	validationResult := s.validateIntegrationFile(header, file)
 	if !validationResult.Valid {
@@ -3120,6 +4342,9 @@ func (s *SecAutoServer) integrationUploadHandler(w http.ResponseWriter, r *http.
 	// Save the integration file
 	integrationName, err := s.saveIntegrationFile(file, header)
 	if err != nil {
+		if writeStorageQuotaError(w, err) {
+			return
+		}
 		logger.Error("Failed to save integration file", map[string]interface{}{
 			"component": "server",
 			"filename":  header.Filename,
@@ -3172,12 +4397,8 @@ func (s *SecAutoServer) validateIntegrationFile(header *multipart.FileHeader, fi
 	}
 
 	// Check filename for security
-	if !s.validator.IsValidFilename(header.Filename) {
-		errors = append(errors, ValidationError{
-			Field:   "filename",
-			Message: "Invalid filename",
-			Value:   header.Filename,
-		})
+	if filenameErr := s.validator.ValidateFilename(header.Filename); filenameErr != nil {
+		errors = append(errors, *filenameErr)
 	}
 
 	// Read and validate file content
@@ -3190,6 +4411,11 @@ func (s *SecAutoServer) validateIntegrationFile(header *multipart.FileHeader, fi
 		return ValidationResult{Valid: false, Errors: errors}
 	}
 
+	// Check the content is of the type the extension claims
+	if typeErr := validateUploadContentType(header.Filename, content); typeErr != nil {
+		errors = append(errors, *typeErr)
+	}
+
 	// Check for dangerous content
 	if s.containsDangerousContent(content) {
 		errors = append(errors, ValidationError{
@@ -3270,6 +4496,13 @@ func (s *SecAutoServer) saveIntegrationFile(file multipart.File, header *multipa
 	// Create full path
 	filepath := filepath.Join(integrationsDir, filename)
 
+	// Hold the storage lock until the file is written
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+	if err := s.checkStorageQuota("integrations", filepath, header.Size); err != nil {
+		return "", err
+	}
+
 	// Create the file
 	dst, err := os.Create(filepath)
 	if err != nil {
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Tests only surface errors in the log
+	logger = NewStructuredLogger(LogLevelError, "console", "", nil)
+	os.Exit(m.Run())
+}
+
+func TestPlaybookStructureAcceptsEveryOperation(t *testing.T) {
+	server := &SecAutoServer{engine: NewRuleEngine(&Config{})}
+
+	for _, op := range playbookOperations {
+		// Operations with specs of their own may reject null, but not as unknown
+		content, _ := json.Marshal([]interface{}{map[string]interface{}{op: nil}})
+		if err := server.validatePlaybookStructure(content); err != nil && strings.Contains(err.Error(), "valid operation") {
+			t.Errorf("%s: %v", op, err)
+		}
+	}
+
+	err := server.validatePlaybookStructure([]byte(`[{"no_such_operation": {}}]`))
+	if err == nil || !strings.Contains(err.Error(), "valid operation") || !strings.Contains(err.Error(), "elasticsearch_index") {
+		t.Errorf("unknown operation error = %v, want one listing the operations", err)
+	}
+}
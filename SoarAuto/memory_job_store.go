@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryJobRecord is a job held by the memory job store. Jobs are kept
+// serialized, as in Redis, so callers never share a job with the store.
+type memoryJobRecord struct {
+	id           string
+	data         []byte
+	status       string
+	playbookName string
+	createdAt    time.Time
+	version      int64
+}
+
+// MemoryJobStore keeps jobs and schedules in the process. Both are lost on
+// restart, which
+// suits development, tests and single-node deployments that do not need
+// history across restarts.
+type MemoryJobStore struct {
+	mutex     sync.RWMutex
+	jobs      map[string]*memoryJobRecord
+	schedules map[string][]byte // Serialized, like jobs
+}
+
+// NewMemoryJobStore creates an empty in-memory job store
+func NewMemoryJobStore() *MemoryJobStore {
+	logger.Info("Initialized in-memory job store", map[string]interface{}{
+		"component": "job_store",
+	})
+	return &MemoryJobStore{
+		jobs:      make(map[string]*memoryJobRecord),
+		schedules: make(map[string][]byte),
+	}
+}
+
+// SaveJob stores a job, bumping its version
+func (mjs *MemoryJobStore) SaveJob(job *Job) error {
+	job.SchemaVersion = currentJobSchemaVersion
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	mjs.mutex.Lock()
+	defer mjs.mutex.Unlock()
+
+	record, exists := mjs.jobs[job.ID]
+	if !exists {
+		record = &memoryJobRecord{id: job.ID}
+		mjs.jobs[job.ID] = record
+	}
+	record.data = data
+	record.status = job.Status
+	record.playbookName = job.PlaybookName
+	record.createdAt = job.CreatedAt
+	record.version++
+	return nil
+}
+
+// GetJobVersion returns the number of times a job has changed, or 0 if it
+// has no recorded changes
+func (mjs *MemoryJobStore) GetJobVersion(jobID string) (int64, error) {
+	mjs.mutex.RLock()
+	defer mjs.mutex.RUnlock()
+
+	if record, exists := mjs.jobs[jobID]; exists {
+		return record.version, nil
+	}
+	return 0, nil
+}
+
+// UpdateJob applies update to a job while holding the store's lock, so no
+// other write can come in between
+func (mjs *MemoryJobStore) UpdateJob(jobID string, update func(job *Job) bool) (bool, error) {
+	mjs.mutex.Lock()
+	defer mjs.mutex.Unlock()
+
+	record, exists := mjs.jobs[jobID]
+	if !exists {
+		return false, jobNotFoundError(jobID)
+	}
+	job, _, err := migrateJobData(record.data)
+	if err != nil {
+		return false, err
+	}
+	if !update(job) {
+		return false, nil
+	}
+
+	job.SchemaVersion = currentJobSchemaVersion
+	data, err := json.Marshal(job)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal job: %v", err)
+	}
+	record.data = data
+	record.status = job.Status
+	record.playbookName = job.PlaybookName
+	record.createdAt = job.CreatedAt
+	record.version++
+	return true, nil
+}
+
+// LoadJob retrieves a job by ID
+func (mjs *MemoryJobStore) LoadJob(jobID string) (*Job, bool) {
+	mjs.mutex.RLock()
+	record, exists := mjs.jobs[jobID]
+	var data []byte
+	if exists {
+		data = record.data
+	}
+	mjs.mutex.RUnlock()
+	if !exists {
+		return nil, false
+	}
+
+	job, _, err := migrateJobData(data)
+	if err != nil {
+		logger.Error("Failed to unmarshal job", map[string]interface{}{
+			"component": "job_store",
+			"job_id":    jobID,
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return job, true
+}
+
+// listJobIDs returns the IDs of the jobs matching keep, newest first
+func (mjs *MemoryJobStore) listJobIDs(keep func(record *memoryJobRecord) bool, limit int) []string {
+	mjs.mutex.RLock()
+	defer mjs.mutex.RUnlock()
+
+	var matched []*memoryJobRecord
+	for _, record := range mjs.jobs {
+		if keep(record) {
+			matched = append(matched, record)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].createdAt.After(matched[j].createdAt)
+	})
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	jobIDs := make([]string, len(matched))
+	for i, record := range matched {
+		jobIDs[i] = record.id
+	}
+	return jobIDs
+}
+
+// loadJobs loads jobs by ID, skipping any deleted in the meantime
+func (mjs *MemoryJobStore) loadJobs(jobIDs []string) []*Job {
+	var jobs []*Job
+	for _, jobID := range jobIDs {
+		if job, exists := mjs.LoadJob(jobID); exists {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+// ListJobs retrieves the most recent jobs, optionally of one status
+func (mjs *MemoryJobStore) ListJobs(status string, limit int) []*Job {
+	return mjs.loadJobs(mjs.listJobIDs(func(record *memoryJobRecord) bool {
+		return status == "" || record.status == status
+	}, limit))
+}
+
+// ListPlaybookRuns returns the most recent jobs of a playbook, newest first
+func (mjs *MemoryJobStore) ListPlaybookRuns(playbookName string, limit int) []*Job {
+	return mjs.loadJobs(mjs.listJobIDs(func(record *memoryJobRecord) bool {
+		return record.playbookName == playbookName
+	}, limit))
+}
+
+// UpdateJobStatus updates a job's status
+func (mjs *MemoryJobStore) UpdateJobStatus(jobID, status string) error {
+	return updateStoredJobStatus(mjs, jobID, status)
+}
+
+// UpdateJobResults updates a job's results and error
+func (mjs *MemoryJobStore) UpdateJobResults(jobID string, results []interface{}, errorMsg string) error {
+	return updateStoredJobResults(mjs, jobID, results, errorMsg)
+}
+
+// UpdateJobContext updates a job's context
+func (mjs *MemoryJobStore) UpdateJobContext(jobID string, context map[string]interface{}) error {
+	return updateStoredJobContext(mjs, jobID, context)
+}
+
+// DeleteJob removes a job
+func (mjs *MemoryJobStore) DeleteJob(jobID string) error {
+	mjs.mutex.Lock()
+	defer mjs.mutex.Unlock()
+
+	delete(mjs.jobs, jobID)
+	return nil
+}
+
+// CleanupOldJobs removes jobs older than specified duration
+func (mjs *MemoryJobStore) CleanupOldJobs(maxAge time.Duration) error {
+	jobIDs := mjs.listJobIDs(func(record *memoryJobRecord) bool { return true }, 0)
+	return cleanupStoredJobs(mjs, jobIDs, maxAge)
+}
+
+// GetStats returns job statistics
+func (mjs *MemoryJobStore) GetStats() JobStats {
+	return computeJobStats(mjs.ListJobs("", 1000)) // Get up to 1000 jobs for stats
+}
+
+// BackupJobs does nothing: an in-memory store has nowhere durable to back
+// jobs up to
+func (mjs *MemoryJobStore) BackupJobs() error {
+	return nil
+}
+
+// RecoverJobs recovers jobs that were running during a crash. A new memory
+// store is empty, so this only matters if the store outlives a restart of
+// the job manager.
+func (mjs *MemoryJobStore) RecoverJobs(engine *RuleEngine, webhookManager *WebhookManager) {
+	recoverStoredJobs(mjs, webhookManager)
+}
+
+// Close does nothing: the jobs and schedules are released with the process
+func (mjs *MemoryJobStore) Close() error {
+	return nil
+}
+
+// GetDatabaseMetrics returns the number and size of the stored jobs
+func (mjs *MemoryJobStore) GetDatabaseMetrics() map[string]interface{} {
+	mjs.mutex.RLock()
+	defer mjs.mutex.RUnlock()
+
+	bytes := 0
+	for _, record := range mjs.jobs {
+		bytes += len(record.data)
+	}
+	return map[string]interface{}{
+		"type":       "memory",
+		"jobs":       len(mjs.jobs),
+		"size_bytes": bytes,
+	}
+}
+
+// SaveSchedule stores a schedule, replacing any with the same ID
+func (mjs *MemoryJobStore) SaveSchedule(schedule *JobSchedule) error {
+	data, err := marshalSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	mjs.mutex.Lock()
+	defer mjs.mutex.Unlock()
+
+	mjs.schedules[schedule.ID] = data
+	return nil
+}
+
+// LoadSchedule retrieves a schedule by ID
+func (mjs *MemoryJobStore) LoadSchedule(scheduleID string) (*JobSchedule, bool) {
+	mjs.mutex.RLock()
+	data, exists := mjs.schedules[scheduleID]
+	mjs.mutex.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+	return unmarshalSchedule(scheduleID, data)
+}
+
+// allSchedules decodes every stored schedule
+func (mjs *MemoryJobStore) allSchedules() []*JobSchedule {
+	mjs.mutex.RLock()
+	defer mjs.mutex.RUnlock()
+
+	schedules := make([]*JobSchedule, 0, len(mjs.schedules))
+	for scheduleID, data := range mjs.schedules {
+		if schedule, ok := unmarshalSchedule(scheduleID, data); ok {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules
+}
+
+// ListSchedules retrieves schedules, optionally of one status, oldest first
+func (mjs *MemoryJobStore) ListSchedules(status string, limit int) []*JobSchedule {
+	return filterSchedules(mjs.allSchedules(), status, limit)
+}
+
+// UpdateSchedule replaces a stored schedule
+func (mjs *MemoryJobStore) UpdateSchedule(schedule *JobSchedule) error {
+	data, err := marshalSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	mjs.mutex.Lock()
+	defer mjs.mutex.Unlock()
+
+	if _, exists := mjs.schedules[schedule.ID]; !exists {
+		return fmt.Errorf("schedule not found: %s", schedule.ID)
+	}
+	mjs.schedules[schedule.ID] = data
+	return nil
+}
+
+// DeleteSchedule removes a schedule
+func (mjs *MemoryJobStore) DeleteSchedule(scheduleID string) error {
+	mjs.mutex.Lock()
+	defer mjs.mutex.Unlock()
+
+	delete(mjs.schedules, scheduleID)
+	return nil
+}
+
+// GetSchedulesDueForExecution returns the active schedules whose next run has come
+func (mjs *MemoryJobStore) GetSchedulesDueForExecution() []*JobSchedule {
+	return dueSchedules(mjs.allSchedules(), time.Now())
+}
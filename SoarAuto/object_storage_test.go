@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 serves the path-style PutObject, GetObject and ListObjectsV2 calls
+// the object store makes, keeping objects in memory
+type fakeS3 struct {
+	mutex   sync.Mutex
+	objects map[string][]byte // bucket/key -> body
+}
+
+type fakeS3ListResult struct {
+	XMLName     xml.Name `xml:"ListBucketResult"`
+	Name        string   `xml:"Name"`
+	Prefix      string   `xml:"Prefix"`
+	KeyCount    int      `xml:"KeyCount"`
+	IsTruncated bool     `xml:"IsTruncated"`
+	Contents    []struct {
+		Key  string `xml:"Key"`
+		Size int    `xml:"Size"`
+	} `xml:"Contents"`
+}
+
+func (fake *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.mutex.Lock()
+	defer fake.mutex.Unlock()
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	switch {
+	case r.Method == http.MethodPut && key != "":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fake.objects[bucket+"/"+key] = body
+	case r.Method == http.MethodGet && key != "":
+		body, exists := fake.objects[bucket+"/"+key]
+		if !exists {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `<Error><Code>NoSuchKey</Code><Message>not found</Message></Error>`)
+			return
+		}
+		w.Write(body)
+	case r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "2":
+		prefix := r.URL.Query().Get("prefix")
+		result := fakeS3ListResult{Name: bucket, Prefix: prefix}
+		var keys []string
+		for stored := range fake.objects {
+			if objectKey, found := strings.CutPrefix(stored, bucket+"/"); found && strings.HasPrefix(objectKey, prefix) {
+				keys = append(keys, objectKey)
+			}
+		}
+		sort.Strings(keys)
+		for _, objectKey := range keys {
+			result.Contents = append(result.Contents, struct {
+				Key  string `xml:"Key"`
+				Size int    `xml:"Size"`
+			}{objectKey, len(fake.objects[bucket+"/"+objectKey])})
+		}
+		result.KeyCount = len(keys)
+		w.Header().Set("Content-Type", "application/xml")
+		xml.NewEncoder(w).Encode(result)
+	default:
+		http.Error(w, "unsupported request", http.StatusNotImplemented)
+	}
+}
+
+// newTestObjectStore returns an object store backed by an in-memory fake S3
+func newTestObjectStore(t *testing.T, backend string) (*ObjectStore, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewObjectStore(backend, ObjectStorageConfig{
+		Bucket:          "secauto",
+		Prefix:          "test/",
+		Endpoint:        server.URL,
+		AccessKeyID:     "test-key",
+		SecretAccessKey: "test-secret",
+		ForcePathStyle:  true,
+	})
+	if err != nil {
+		t.Fatalf("create object store: %v", err)
+	}
+	return store, fake
+}
+
+func TestObjectStorePutGetList(t *testing.T) {
+	for _, backend := range []string{"s3", "gcs"} {
+		t.Run(backend, func(t *testing.T) {
+			store, fake := newTestObjectStore(t, backend)
+
+			if err := store.Put("a/one.json", []byte(`{"n":1}`)); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			if err := store.Put("b/two.json", []byte(`{"n":2}`)); err != nil {
+				t.Fatalf("put: %v", err)
+			}
+			if _, exists := fake.objects["secauto/test/a/one.json"]; !exists {
+				t.Errorf("object not written under the prefix: %v", fake.objects)
+			}
+
+			data, err := store.Get("a/one.json")
+			if err != nil || string(data) != `{"n":1}` {
+				t.Errorf("get = %q, %v", data, err)
+			}
+			if _, err := store.Get("a/missing.json"); !os.IsNotExist(err) {
+				t.Errorf("get missing object error = %v, want not exist", err)
+			}
+
+			keys, err := store.List("a/")
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			if !equalStrings(keys, []string{"a/one.json"}) {
+				t.Errorf("list = %v, want [a/one.json]", keys)
+			}
+		})
+	}
+}
+
+func TestNewObjectStoreRequiresBucketAndGCSKeys(t *testing.T) {
+	if _, err := NewObjectStore("s3", ObjectStorageConfig{}); err == nil {
+		t.Error("s3 without a bucket was accepted")
+	}
+	if _, err := NewObjectStore("gcs", ObjectStorageConfig{Bucket: "secauto"}); err == nil {
+		t.Error("gcs without an HMAC key was accepted")
+	}
+	if _, err := NewObjectStore("azure", ObjectStorageConfig{Bucket: "secauto"}); err == nil {
+		t.Error("unknown backend was accepted")
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxOperationTargets bounds how many scripts, plugins and playbooks are
+// tracked individually; calls to further targets count only toward their
+// operation type
+const maxOperationTargets = 500
+
+// OperationTiming aggregates the durations of one kind of operation
+type OperationTiming struct {
+	Count   int64   `json:"count"`
+	Errors  int64   `json:"errors"`
+	Slow    int64   `json:"slow"`
+	TotalMs float64 `json:"total_ms"`
+	AvgMs   float64 `json:"avg_ms"`
+	MaxMs   float64 `json:"max_ms"`
+}
+
+// add records one call
+func (ot *OperationTiming) add(ms float64, failed, slow bool) {
+	ot.Count++
+	if failed {
+		ot.Errors++
+	}
+	if slow {
+		ot.Slow++
+	}
+	ot.TotalMs += ms
+	ot.AvgMs = ot.TotalMs / float64(ot.Count)
+	if ms > ot.MaxMs {
+		ot.MaxMs = ms
+	}
+}
+
+// OperationMetrics times the run, plugin and play operations of every engine
+// in the process, per operation type and per target
+type OperationMetrics struct {
+	mutex   sync.Mutex
+	types   map[string]*OperationTiming
+	targets map[string]*OperationTiming // Keyed by "type:target", e.g. "run:geoip_lookup"
+}
+
+// operationMetrics is shared by all engines, like the Python process limiter
+var operationMetrics = NewOperationMetrics()
+
+// NewOperationMetrics creates empty operation metrics
+func NewOperationMetrics() *OperationMetrics {
+	return &OperationMetrics{
+		types:   make(map[string]*OperationTiming),
+		targets: make(map[string]*OperationTiming),
+	}
+}
+
+// record adds a call to the metrics of its operation type and target
+func (om *OperationMetrics) record(operation, target string, duration time.Duration, failed, slow bool) {
+	ms := float64(duration.Microseconds()) / 1000
+
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+
+	timing, exists := om.types[operation]
+	if !exists {
+		timing = &OperationTiming{}
+		om.types[operation] = timing
+	}
+	timing.add(ms, failed, slow)
+
+	if target == "" {
+		return
+	}
+	key := operation + ":" + target
+	timing, exists = om.targets[key]
+	if !exists {
+		if len(om.targets) >= maxOperationTargets {
+			return
+		}
+		timing = &OperationTiming{}
+		om.targets[key] = timing
+	}
+	timing.add(ms, failed, slow)
+}
+
+// Stats returns the timings of every operation type and target
+func (om *OperationMetrics) Stats() map[string]interface{} {
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+
+	types := make(map[string]OperationTiming, len(om.types))
+	for operation, timing := range om.types {
+		types[operation] = *timing
+	}
+
+	keys := make([]string, 0, len(om.targets))
+	for key := range om.targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	targets := make(map[string]OperationTiming, len(keys))
+	for _, key := range keys {
+		targets[key] = *om.targets[key]
+	}
+
+	return map[string]interface{}{
+		"types":   types,
+		"targets": targets,
+	}
+}
+
+// operationTarget names what a run, plugin or play operation calls: the
+// script, the plugin or the playbook
+func operationTarget(spec interface{}) string {
+	switch v := spec.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// recordOperation times a run, plugin or play operation that started at
+// start. When monitoring.performance_tracking is on it is added to the
+// operation metrics, and when it took at least
+// monitoring.slow_query_threshold milliseconds it is logged as slow.
+func (re *RuleEngine) recordOperation(operation string, spec interface{}, start time.Time, err error) {
+	if re.config == nil {
+		return
+	}
+	monitoring := re.config.Monitoring
+	threshold := time.Duration(monitoring.SlowQueryThreshold) * time.Millisecond
+	duration := time.Since(start)
+	slow := threshold > 0 && duration >= threshold
+	if !monitoring.PerformanceTracking && !slow {
+		return
+	}
+
+	// An abort stops the playbook deliberately; it is not a failed call
+	_, aborted := err.(*PlaybookAbort)
+	failed := err != nil && !aborted
+
+	target := operationTarget(spec)
+	if monitoring.PerformanceTracking {
+		operationMetrics.record(operation, target, duration, failed, slow)
+	}
+
+	if slow {
+		fields := map[string]interface{}{
+			"component":   "rules_engine",
+			"duration_ms": float64(duration.Milliseconds()),
+			"stats": map[string]interface{}{
+				"operation":    operation,
+				"target":       target,
+				"threshold_ms": monitoring.SlowQueryThreshold,
+				"failed":       failed,
+			},
+		}
+		switch operation {
+		case "run":
+			fields["script"] = target
+		case "play", "play_async":
+			fields["playbook"] = target
+		}
+		logger.Warning(fmt.Sprintf("Slow %s operation: %s", operation, target), fields)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// putWithRevision sends a PUT with If-Match set to the given ETag
+func putWithRevision(handler http.HandlerFunc, path, etag, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("If-Match", etag)
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	return recorder
+}
+
+func TestStaleIntegrationUpdateIsRejected(t *testing.T) {
+	manager, err := NewIntegrationConfigManager(filepath.Join(t.TempDir(), "integrations.enc"), "test-key")
+	if err != nil {
+		t.Fatalf("NewIntegrationConfigManager: %v", err)
+	}
+	if err := manager.SetConfig("alerts", &IntegrationConfig{Type: "slack", URL: "https://hooks.example.com/original"}); err != nil {
+		t.Fatalf("SetConfig: %v", err)
+	}
+	server := &SecAutoServer{integrationConfigManager: manager}
+
+	get := httptest.NewRecorder()
+	server.integrationHandler(get, httptest.NewRequest(http.MethodGet, "/integrations/alerts", nil))
+	etag := get.Header().Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("ETag = %s, want \"1\"", etag)
+	}
+
+	// Two clients read revision 1; the first update wins
+	first := putWithRevision(server.integrationHandler, "/integrations/alerts", etag, `{"type":"slack","url":"https://hooks.example.com/first"}`)
+	if first.Code != http.StatusOK || first.Header().Get("ETag") != `"2"` {
+		t.Fatalf("first update: %d %s, ETag %s", first.Code, first.Body.String(), first.Header().Get("ETag"))
+	}
+
+	stale := putWithRevision(server.integrationHandler, "/integrations/alerts", etag, `{"type":"slack","url":"https://hooks.example.com/second"}`)
+	if stale.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale update: %d %s, want 412", stale.Code, stale.Body.String())
+	}
+	if config, _ := manager.GetConfig("alerts"); config.URL != "https://hooks.example.com/first" || config.Revision != 2 {
+		t.Errorf("stored integration = %s at revision %d, want the first update kept", config.URL, config.Revision)
+	}
+
+	// The update must name the revision it was based on
+	missing := httptest.NewRecorder()
+	server.integrationHandler(missing, httptest.NewRequest(http.MethodPut, "/integrations/alerts", strings.NewReader(`{"type":"slack"}`)))
+	if missing.Code != http.StatusPreconditionRequired {
+		t.Errorf("update without If-Match: %d, want 428", missing.Code)
+	}
+
+	// Reading again gives the current revision, which can be updated
+	retry := putWithRevision(server.integrationHandler, "/integrations/alerts", `"2"`, `{"type":"slack","url":"https://hooks.example.com/second"}`)
+	if retry.Code != http.StatusOK {
+		t.Errorf("update at the current revision: %d %s", retry.Code, retry.Body.String())
+	}
+}
+
+func TestStaleScheduleUpdateIsRejected(t *testing.T) {
+	store := NewMemoryJobStore()
+	scheduler := newTestScheduler(t, store)
+	schedule := &JobSchedule{Name: "sweep", ScheduleType: ScheduleTypeInterval, IntervalSeconds: 3600, Playbook: testSchedulePlaybook()}
+	if err := scheduler.CreateSchedule(schedule); err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+	server := &SecAutoServer{jobScheduler: scheduler}
+	path := "/schedules/" + schedule.ID
+
+	update := func(etag string, interval int) *httptest.ResponseRecorder {
+		body := `{"name":"sweep","schedule_type":"interval","interval_seconds":` + strconv.Itoa(interval) +
+			`,"status":"active","playbook":[{"if":{"conditions":[{"==":[1,1]}],"true":[]}}]}`
+		return putWithRevision(server.scheduleHandler, path, etag, body)
+	}
+
+	if first := update(`"1"`, 1800); first.Code != http.StatusOK {
+		t.Fatalf("first update: %d %s", first.Code, first.Body.String())
+	}
+	if stale := update(`"1"`, 900); stale.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale update: %d %s, want 412", stale.Code, stale.Body.String())
+	}
+
+	current, _ := scheduler.GetSchedule(schedule.ID)
+	if current.IntervalSeconds != 1800 || current.Revision != 2 {
+		t.Errorf("schedule = every %ds at revision %d, want the first update kept", current.IntervalSeconds, current.Revision)
+	}
+	if stored, _ := store.LoadSchedule(schedule.ID); stored.IntervalSeconds != 1800 || stored.Revision != 2 {
+		t.Errorf("stored schedule = every %ds at revision %d, want the first update kept", stored.IntervalSeconds, stored.Revision)
+	}
+}
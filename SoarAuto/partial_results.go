@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// jobStatusCompletedWithErrors is the status of a job whose playbook ran to
+// the end although rules allowed to fail did
+const jobStatusCompletedWithErrors = "completed_with_errors"
+
+// maxRuleFailures bounds the failures one execution records; later ones are
+// only counted
+const maxRuleFailures = 100
+
+// RuleFailure is a rule that failed without stopping its playbook
+type RuleFailure struct {
+	Rule  int    `json:"rule"` // 1-based index in the playbook the rule belongs to
+	Error string `json:"error"`
+}
+
+// PlaybookRuleErrors is returned by EvaluatePlaybook, together with the
+// results of every rule, when the playbook ran to the end but rules marked
+// continue_on_error failed. Callers record the run as completed_with_errors
+// rather than failed.
+type PlaybookRuleErrors struct {
+	Failures []RuleFailure
+	Count    int // Includes failures beyond maxRuleFailures
+}
+
+func (pe *PlaybookRuleErrors) Error() string {
+	messages := make([]string, len(pe.Failures))
+	for i, failure := range pe.Failures {
+		messages[i] = fmt.Sprintf("rule %d: %s", failure.Rule, failure.Error)
+	}
+	return fmt.Sprintf("%d %s failed: %s", pe.Count, pluralize(pe.Count, "rule", "rules"), strings.Join(messages, "; "))
+}
+
+// ruleFailures collects the rule failures of one playbook execution,
+// including those of nested playbooks
+type ruleFailures struct {
+	mu       sync.Mutex
+	failures []RuleFailure
+	count    int
+}
+
+// add records a failed rule
+func (rf *ruleFailures) add(rule int, err error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	rf.count++
+	if len(rf.failures) < maxRuleFailures {
+		rf.failures = append(rf.failures, RuleFailure{Rule: rule, Error: err.Error()})
+	}
+}
+
+// err returns the failures as a PlaybookRuleErrors, or nil when no rule failed
+func (rf *ruleFailures) err() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.count == 0 {
+		return nil
+	}
+	return &PlaybookRuleErrors{
+		Failures: append([]RuleFailure(nil), rf.failures...),
+		Count:    rf.count,
+	}
+}
+
+// WithContinueOnError returns an engine on which every rule without its own
+// continue_on_error flag continues after failing
+func (re *RuleEngine) WithContinueOnError(enabled bool) *RuleEngine {
+	if enabled == re.continueOnError {
+		return re
+	}
+	engine := *re
+	engine.continueOnError = enabled
+	return &engine
+}
+
+// ruleContinuesOnError reads the continue_on_error flag of a rule and returns
+// the rule without it. Rules without the flag follow the engine's setting.
+func (re *RuleEngine) ruleContinuesOnError(rule interface{}) (interface{}, bool) {
+	ruleMap, ok := rule.(map[string]interface{})
+	if !ok {
+		return rule, re.continueOnError
+	}
+	flag, exists := ruleMap["continue_on_error"]
+	if !exists {
+		return rule, re.continueOnError
+	}
+
+	stripped := make(map[string]interface{}, len(ruleMap)-1)
+	for key, value := range ruleMap {
+		if key != "continue_on_error" {
+			stripped[key] = value
+		}
+	}
+	enabled, _ := flag.(bool)
+	return stripped, enabled
+}
+
+// isContinuableRuleError reports whether a rule failure may be recorded
+// without stopping the playbook. Aborts and failed assertions always stop it.
+func isContinuableRuleError(err error) bool {
+	switch err.(type) {
+	case *PlaybookAbort, *AssertionError:
+		return false
+	}
+	return true
+}
+
+// failedRuleResult is the result recorded for a rule that failed and was
+// allowed to
+func failedRuleResult(rule int, err error) map[string]interface{} {
+	return map[string]interface{}{
+		"rule":   rule,
+		"status": "failed",
+		"error":  err.Error(),
+	}
+}
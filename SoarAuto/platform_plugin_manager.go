@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -13,6 +14,7 @@ import (
 type PlatformPluginManager struct {
 	platforms map[string]*PluginManager
 	config    map[string]PlatformConfig
+	disabled  map[string]PlatformConfig // Platforms turned off in the configuration
 	mutex     sync.RWMutex
 	logger    *StructuredLogger
 }
@@ -22,6 +24,7 @@ func NewPlatformPluginManager(config *Config) (*PlatformPluginManager, error) {
 	ppm := &PlatformPluginManager{
 		platforms: make(map[string]*PluginManager),
 		config:    make(map[string]PlatformConfig),
+		disabled:  make(map[string]PlatformConfig),
 		logger:    logger,
 	}
 
@@ -46,6 +49,7 @@ func (ppm *PlatformPluginManager) initializePlatforms(config *Config) error {
 				"component": "platform_plugin_manager",
 				"platform":  platformName,
 			})
+			ppm.disabled[platformName] = platformConfig
 			continue
 		}
 
@@ -74,7 +78,10 @@ func (ppm *PlatformPluginManager) initializePlatforms(config *Config) error {
 			"max_memory":           platformConfig.MaxMemory,
 			"allow_network_access": platformConfig.AllowNetworkAccess,
 			"allow_file_access":    platformConfig.AllowFileAccess,
+			"wasi_capabilities":    platformConfig.WASICapabilities,
 			"venv_path":            config.GetVenvPath(),
+			"build_temp_dir":       config.Plugins.BuildTempDir,
+			"work_dir":             config.Plugins.WorkDir,
 		}
 
 		// Create plugin manager for this platform
@@ -181,6 +188,17 @@ func (ppm *PlatformPluginManager) GetPluginInfo() map[string]PluginInfo {
 			info.Platform = platformName
 			info.Runtime = ppm.getRuntimeForPlatform(platformName)
 			info.PlatformInfo = ppm.getPlatformInfo(platformName)
+			if info.Cacheable {
+				stats := pluginResults.Stats(info.Name)
+				info.Cache = &stats
+			}
+			allPluginInfo[name] = info
+		}
+	}
+
+	// Plugins of disabled platforms are listed, but cannot be run
+	for name, info := range ppm.disabledPluginInfo() {
+		if _, exists := allPluginInfo[name]; !exists {
 			allPluginInfo[name] = info
 		}
 	}
@@ -188,6 +206,46 @@ func (ppm *PlatformPluginManager) GetPluginInfo() map[string]PluginInfo {
 	return allPluginInfo
 }
 
+// disabledPluginInfo describes the plugin files in the directories of
+// disabled platforms. Their plugins are never loaded, so each is named after
+// its file, as uploaded plugins are. Callers hold the lock.
+func (ppm *PlatformPluginManager) disabledPluginInfo() map[string]PluginInfo {
+	disabledPluginInfo := make(map[string]PluginInfo)
+	for platformName, platformConfig := range ppm.disabled {
+		if platformConfig.Directory == "" {
+			continue
+		}
+		filepath.WalkDir(platformConfig.Directory, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() || !isPluginFile(path) {
+				return nil
+			}
+			name := strings.TrimSuffix(d.Name(), filepath.Ext(d.Name()))
+			disabledPluginInfo[name] = PluginInfo{
+				Name:         name,
+				Platform:     platformName,
+				Runtime:      ppm.getRuntimeForPlatform(platformName),
+				Status:       PluginStatusDisabled,
+				Error:        fmt.Sprintf("platform %s is disabled", platformName),
+				PlatformInfo: ppm.getPlatformInfo(platformName),
+			}
+			return nil
+		})
+	}
+	return disabledPluginInfo
+}
+
+// DisabledPluginPlatform returns the platform of a plugin that is not loaded
+// because its platform is disabled
+func (ppm *PlatformPluginManager) DisabledPluginPlatform(name string) (string, bool) {
+	ppm.mutex.RLock()
+	defer ppm.mutex.RUnlock()
+
+	if info, exists := ppm.disabledPluginInfo()[name]; exists {
+		return info.Platform, true
+	}
+	return "", false
+}
+
 // ExecutePlugin executes a plugin by name across all platforms
 func (ppm *PlatformPluginManager) ExecutePlugin(name string, params map[string]interface{}) (interface{}, error) {
 	ppm.mutex.RLock()
@@ -211,9 +269,86 @@ func (ppm *PlatformPluginManager) ExecutePlugin(name string, params map[string]i
 		}
 	}
 
+	if info, exists := ppm.disabledPluginInfo()[name]; exists {
+		return nil, fmt.Errorf("plugin %s cannot run: its platform %s is disabled", name, info.Platform)
+	}
 	return nil, fmt.Errorf("plugin not found: %s", name)
 }
 
+// SetReloadHandler registers a plugin version change handler on every platform
+func (ppm *PlatformPluginManager) SetReloadHandler(handler PluginReloadHandler) {
+	ppm.mutex.RLock()
+	defer ppm.mutex.RUnlock()
+
+	for _, pm := range ppm.platforms {
+		pm.SetReloadHandler(handler)
+	}
+}
+
+// PendingReloads returns how many plugin file changes wait to be reloaded on
+// every platform
+func (ppm *PlatformPluginManager) PendingReloads() int {
+	ppm.mutex.RLock()
+	defer ppm.mutex.RUnlock()
+
+	pending := 0
+	for _, pm := range ppm.platforms {
+		pending += pm.PendingReloads()
+	}
+	return pending
+}
+
+// FlushReloads drops the plugin file changes waiting to be reloaded on every
+// platform and returns how many were dropped
+func (ppm *PlatformPluginManager) FlushReloads() int {
+	ppm.mutex.RLock()
+	defer ppm.mutex.RUnlock()
+
+	flushed := 0
+	for _, pm := range ppm.platforms {
+		flushed += pm.FlushReloads()
+	}
+	return flushed
+}
+
+// GetPluginVersion returns the loaded version of a plugin across all platforms
+func (ppm *PlatformPluginManager) GetPluginVersion(name string) (string, bool) {
+	ppm.mutex.RLock()
+	defer ppm.mutex.RUnlock()
+
+	for _, pm := range ppm.platforms {
+		if version, exists := pm.GetPluginVersion(name); exists {
+			return version, true
+		}
+	}
+	return "", false
+}
+
+// GetPluginPath returns the file a plugin was loaded from, across all platforms
+func (ppm *PlatformPluginManager) GetPluginPath(name string) (string, bool) {
+	ppm.mutex.RLock()
+	defer ppm.mutex.RUnlock()
+
+	for _, pm := range ppm.platforms {
+		if path, exists := pm.GetPluginPath(name); exists {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// GetPluginVersions returns the loaded versions of the named plugins; plugins
+// that are not loaded are omitted
+func (ppm *PlatformPluginManager) GetPluginVersions(names []string) map[string]string {
+	versions := make(map[string]string, len(names))
+	for _, name := range names {
+		if version, exists := ppm.GetPluginVersion(name); exists {
+			versions[name] = version
+		}
+	}
+	return versions
+}
+
 // GetPluginsByPlatform retrieves all plugins for a specific platform
 func (ppm *PlatformPluginManager) GetPluginsByPlatform(platformName string) map[string]interface{} {
 	ppm.mutex.RLock()
@@ -276,6 +411,8 @@ func (ppm *PlatformPluginManager) getRuntimeForPlatform(platformName string) str
 		return "go"
 	case "windows", "linux":
 		return "executable"
+	case "wasm":
+		return "wasm"
 	default:
 		return "unknown"
 	}
@@ -309,6 +446,11 @@ func (ppm *PlatformPluginManager) getPlatformInfo(platformName string) PlatformI
 		info.Requirements = map[string]string{
 			"os": "linux",
 		}
+	case "wasm":
+		info.Dependencies = []string{}
+		info.Requirements = map[string]string{
+			"runtime": "wasm",
+		}
 	}
 
 	return info
@@ -333,6 +475,8 @@ func (ppm *PlatformPluginManager) DetectPlatformForFile(filePath string) string
 		return "python"
 	case ".go", ".so":
 		return "go"
+	case ".wasm":
+		return "wasm"
 	case ".exe":
 		if runtime.GOOS == "windows" {
 			return "windows"
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+)
+
+// playAsyncTriggeredBy is recorded as the submitter of jobs started by
+// play_async
+const playAsyncTriggeredBy = "play_async"
+
+// SetJobManager sets the job manager play_async submits playbooks to
+func (re *RuleEngine) SetJobManager(jobManager *JobManager) {
+	re.jobManager = jobManager
+}
+
+// playAsyncPlaybookName returns the playbook a play_async rule submits,
+// given as {"play_async": "name"} or {"play_async": {"name": "name"}}
+func playAsyncPlaybookName(value interface{}) string {
+	if spec, ok := value.(map[string]interface{}); ok {
+		value = spec["name"]
+	}
+	name, _ := value.(string)
+	return name
+}
+
+// evaluatePlayAsyncOperation handles {"play_async": "containment"} and
+// {"play_async": {"name": "containment", "priority": "high"}}. It submits the
+// named playbook as a job of its own, with a copy of the current context as
+// its initial context, and returns the job's ID without waiting for it. The
+// job runs independently: its failure does not fail the calling playbook.
+func (re *RuleEngine) evaluatePlayAsyncOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	if re.jobManager == nil {
+		return nil, fmt.Errorf("play_async is not available: no job manager to submit to")
+	}
+
+	playbookName := playAsyncPlaybookName(spec)
+	if playbookName == "" {
+		return nil, fmt.Errorf("play_async requires a playbook name")
+	}
+	priority := defaultJobPriority
+	if specMap, ok := spec.(map[string]interface{}); ok && specMap["priority"] != nil {
+		priority, _ = specMap["priority"].(string)
+		if !jobPriorities[priority] {
+			return nil, fmt.Errorf("play_async priority must be low, normal, high or critical, got %v", specMap["priority"])
+		}
+	}
+
+	// Load the playbook now so a missing or invalid one fails this rule
+	playbookPath := re.getPlaybookPath(playbookName)
+	re.recordPlaybook(playbookName, playbookPath)
+	playbook, err := re.LoadPlaybookFromFile(playbookPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load playbook %s: %v", playbookName, err)
+	}
+
+	// The job starts from the context as it is now; later changes on either
+	// side are not shared
+	snapshot, err := deepCopyJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("play_async failed to copy the context: %v", err)
+	}
+	context, _ := snapshot.(map[string]interface{})
+
+	source := JobSource{PlaybookName: playbookName, TriggeredBy: playAsyncTriggeredBy}
+	jobID := re.jobManager.SubmitJobFrom(source, playbook, context, re.env, priority)
+
+	logger.Info("Submitted playbook asynchronously", map[string]interface{}{
+		"component": "rules_engine",
+		"playbook":  playbookName,
+		"job_id":    jobID,
+		"priority":  priority,
+	})
+	return jobID, nil
+}
@@ -0,0 +1,441 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Supported playbook export formats
+const (
+	playbookExportJSON     = "json"
+	playbookExportYAML     = "yaml"
+	playbookExportMarkdown = "markdown"
+)
+
+// playbookExportHandler handles GET /playbooks/{name}/export?format=json|yaml|markdown
+func (s *SecAutoServer) playbookExportHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/playbooks/"), "/")
+	if len(pathParts) != 2 || pathParts[1] != "export" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	playbookName := s.validator.SanitizePath(pathParts[0])
+	if playbookName == "" || playbookName == "." || strings.Contains(playbookName, "/") {
+		http.Error(w, "Invalid playbook name", http.StatusBadRequest)
+		return
+	}
+	playbookName = strings.TrimSuffix(playbookName, ".json")
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = playbookExportJSON
+	}
+
+	content, err := os.ReadFile(s.engine.getPlaybookPath(playbookName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, fmt.Sprintf("Playbook '%s' not found", playbookName), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to read playbook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var body []byte
+	var contentType, extension string
+	switch format {
+	case playbookExportJSON:
+		// The stored file is returned as is
+		body, contentType, extension = content, "application/json", "json"
+	case playbookExportYAML, playbookExportMarkdown:
+		var playbook []interface{}
+		if err := json.Unmarshal(content, &playbook); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to parse playbook: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if format == playbookExportYAML {
+			body, err = yaml.Marshal(playbook)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to convert playbook to YAML: %v", err), http.StatusInternalServerError)
+				return
+			}
+			contentType, extension = "application/yaml", "yaml"
+		} else {
+			body = []byte(renderPlaybookMarkdown(playbookName, playbook))
+			contentType, extension = "text/markdown; charset=utf-8", "md"
+		}
+	default:
+		http.Error(w, "Invalid format. Supported formats: json, yaml, markdown", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", playbookName+"."+extension))
+	w.Write(body)
+
+	logger.Info("Playbook exported", map[string]interface{}{
+		"component": "server",
+		"playbook":  playbookName,
+		"format":    format,
+	})
+}
+
+// renderPlaybookMarkdown documents a playbook as Markdown, with one section
+// per rule describing what it does
+func renderPlaybookMarkdown(name string, playbook []interface{}) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Playbook: %s\n\n", name)
+	fmt.Fprintf(&b, "This playbook has %d %s. Exported on %s.\n\n", len(playbook), pluralize(len(playbook), "step", "steps"), time.Now().UTC().Format(time.RFC3339))
+
+	for i, rule := range playbook {
+		fmt.Fprintf(&b, "## Step %d: %s\n\n", i+1, describePlaybookRule(rule))
+		writeRuleDetails(&b, rule)
+	}
+	return b.String()
+}
+
+// describePlaybookRule summarizes a rule in one sentence
+func describePlaybookRule(rule interface{}) string {
+	ruleMap, ok := rule.(map[string]interface{})
+	if !ok {
+		return fmt.Sprintf("Evaluate %s", markdownInlineCode(compactJSON(rule)))
+	}
+
+	switch {
+	case ruleMap["run"] != nil:
+		return fmt.Sprintf("Run automation %s against current context", markdownInlineCode(fmt.Sprintf("%v", ruleMap["run"])))
+	case ruleMap["play"] != nil:
+		return fmt.Sprintf("Run nested playbook %s", markdownInlineCode(fmt.Sprintf("%v", ruleMap["play"])))
+	case ruleMap["play_async"] != nil:
+		return fmt.Sprintf("Start playbook %s as a separate job without waiting for it", markdownInlineCode(playAsyncPlaybookName(ruleMap["play_async"])))
+	case ruleMap["plugin"] != nil:
+		name := ruleMap["plugin"]
+		if pluginMap, ok := name.(map[string]interface{}); ok {
+			name = pluginMap["name"]
+		}
+		return fmt.Sprintf("Execute plugin %s", markdownInlineCode(fmt.Sprintf("%v", name)))
+	case ruleMap["macro"] != nil:
+		return fmt.Sprintf("Expand macro %s", markdownInlineCode(fmt.Sprintf("%v", ruleMap["macro"])))
+	case ruleMap["if"] != nil:
+		return "Branch on a condition"
+	case ruleMap["switch"] != nil:
+		spec, _ := ruleMap["switch"].(map[string]interface{})
+		return fmt.Sprintf("Branch on the value of %s", markdownInlineCode(compactJSON(spec["on"])))
+	case ruleMap["conditional_set"] != nil:
+		spec, _ := ruleMap["conditional_set"].(map[string]interface{})
+		if _, hasCondition := spec["condition"]; hasCondition {
+			return fmt.Sprintf("Set %s when a condition holds", markdownInlineCode(fmt.Sprintf("%v", spec["key"])))
+		}
+		return fmt.Sprintf("Set %s", markdownInlineCode(fmt.Sprintf("%v", spec["key"])))
+	case ruleMap["map"] != nil:
+		if target, ok := ruleMap["as"].(string); ok {
+			return fmt.Sprintf("Build an object and store it in %s", markdownInlineCode(target))
+		}
+		return "Build an object"
+	case ruleMap["context_diff"] != nil:
+		spec, _ := ruleMap["context_diff"].(map[string]interface{})
+		return fmt.Sprintf("Compare %s with %s and store the changes in %s",
+			markdownInlineCode(fmt.Sprintf("%v", spec["before_var"])),
+			markdownInlineCode(fmt.Sprintf("%v", spec["after_var"])),
+			markdownInlineCode(fmt.Sprintf("%v", spec["output_var"])))
+	case ruleMap["elasticsearch_index"] != nil:
+		spec, _ := ruleMap["elasticsearch_index"].(map[string]interface{})
+		return fmt.Sprintf("Index %s into Elasticsearch index %s",
+			markdownInlineCode(fmt.Sprintf("%v", spec["document_var"])),
+			markdownInlineCode(fmt.Sprintf("%v", spec["index"])))
+	case ruleMap["splunk_log"] != nil:
+		spec, _ := ruleMap["splunk_log"].(map[string]interface{})
+		if sourcetype, ok := spec["sourcetype"].(string); ok {
+			return fmt.Sprintf("Send an event with sourcetype %s to Splunk", markdownInlineCode(sourcetype))
+		}
+		return "Send an event to Splunk"
+	case ruleMap["abort"] != nil:
+		status := abortStatusAborted
+		if spec, ok := ruleMap["abort"].(map[string]interface{}); ok {
+			if value, ok := spec["status"].(string); ok {
+				status = value
+			}
+		}
+		return fmt.Sprintf("Stop the playbook with status %s", markdownInlineCode(status))
+	case ruleMap["assert"] != nil:
+		spec, _ := ruleMap["assert"].(map[string]interface{})
+		if message, ok := spec["message"].(string); ok && message != "" {
+			return fmt.Sprintf("Check that %s (%s)", markdownInlineCode(compactJSON(spec["that"])), message)
+		}
+		return fmt.Sprintf("Check that %s", markdownInlineCode(compactJSON(spec["that"])))
+	case ruleMap["foreach"] != nil:
+		spec, err := normalizeForeach(ruleMap)
+		if err != nil {
+			return "Repeat rules for each item"
+		}
+		rules := len(spec["do"].([]interface{}))
+		return fmt.Sprintf("Repeat %d %s for each %s in %s", rules, pluralize(rules, "rule", "rules"),
+			markdownInlineCode(spec["as"].(string)), markdownInlineCode(compactJSON(spec["items"])))
+	case ruleMap["batch"] != nil:
+		spec, _ := ruleMap["batch"].(map[string]interface{})
+		return fmt.Sprintf("Run automation %s for each element of %s concurrently",
+			markdownInlineCode(fmt.Sprintf("%v", spec["run"])), markdownInlineCode(compactJSON(spec["over"])))
+	case ruleMap["vars"] != nil:
+		return "Check the types of context variables"
+	case ruleMap["try"] != nil:
+		spec, _ := ruleMap["try"].(map[string]interface{})
+		if _, hasCatch := spec["catch"]; hasCatch {
+			return "Run rules and handle their errors"
+		}
+		return "Run rules with cleanup rules afterwards"
+	case ruleMap["jq"] != nil:
+		if target, ok := ruleMap["as"].(string); ok {
+			return fmt.Sprintf("Transform data with jq query %s and store it in %s",
+				markdownInlineCode(jqRuleQuery(ruleMap["jq"])), markdownInlineCode(target))
+		}
+		return fmt.Sprintf("Transform data with jq query %s", markdownInlineCode(jqRuleQuery(ruleMap["jq"])))
+	case ruleMap["random"] != nil:
+		spec, _ := ruleMap["random"].(map[string]interface{})
+		if target, ok := spec["output_var"].(string); ok {
+			return fmt.Sprintf("Generate a random %v and store it in %s", spec["type"], markdownInlineCode(target))
+		}
+		return fmt.Sprintf("Generate a random %v", spec["type"])
+	case ruleMap["checkpoint"] != nil:
+		label, _ := checkpointLabel("checkpoint", ruleMap["checkpoint"])
+		return fmt.Sprintf("Save a checkpoint of the context as %s", markdownInlineCode(label))
+	case ruleMap["restore"] != nil:
+		label, _ := checkpointLabel("restore", ruleMap["restore"])
+		return fmt.Sprintf("Restore the context to checkpoint %s", markdownInlineCode(label))
+	case ruleMap["throttle"] != nil:
+		bucket, rate, per, err := parseThrottleSpec(ruleMap["throttle"])
+		if err != nil {
+			return "Wait for a throttle bucket"
+		}
+		return fmt.Sprintf("Wait until bucket %s is below %d calls per %s", markdownInlineCode(bucket), rate, per)
+	case ruleMap["metric"] != nil:
+		name, kind, err := validateMetricSpec(ruleMap["metric"])
+		if err != nil {
+			return "Record a metric"
+		}
+		if kind == "gauge" {
+			return fmt.Sprintf("Set gauge %s", markdownInlineCode(name))
+		}
+		return fmt.Sprintf("Increment counter %s", markdownInlineCode(name))
+	case ruleMap["group_count"] != nil:
+		items, by, err := parseGroupCountSpec(ruleMap["group_count"])
+		if err != nil {
+			return "Count elements by a field"
+		}
+		if target, ok := ruleMap["as"].(string); ok {
+			return fmt.Sprintf("Count the elements of %s by %s and store the counts in %s",
+				markdownInlineCode(compactJSON(items)), markdownInlineCode(by), markdownInlineCode(target))
+		}
+		return fmt.Sprintf("Count the elements of %s by %s", markdownInlineCode(compactJSON(items)), markdownInlineCode(by))
+	case ruleMap["finding"] != nil:
+		spec, _ := ruleMap["finding"].(map[string]interface{})
+		severity, ok := spec["severity"].(string)
+		if !ok {
+			severity = "info"
+		}
+		if title, ok := spec["title"].(string); ok {
+			return fmt.Sprintf("Record %s finding %s", severity, markdownInlineCode(title))
+		}
+		return "Record a finding"
+	case ruleMap["var"] != nil:
+		return fmt.Sprintf("Look up context variable %s", markdownInlineCode(fmt.Sprintf("%v", ruleMap["var"])))
+	}
+	return fmt.Sprintf("Evaluate %s", markdownInlineCode(compactJSON(rule)))
+}
+
+// writeRuleDetails writes the parameters, branches and embedded expressions
+// of a rule
+func writeRuleDetails(b *strings.Builder, rule interface{}) {
+	ruleMap, ok := rule.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if continueOnError, _ := ruleMap["continue_on_error"].(bool); continueOnError {
+		b.WriteString("If this step fails, the failure is recorded and the playbook continues.\n\n")
+	}
+
+	switch {
+	case ruleMap["if"] != nil:
+		writeIfDetails(b, ruleMap["if"])
+	case ruleMap["switch"] != nil:
+		writeSwitchDetails(b, ruleMap["switch"])
+	case ruleMap["run"] != nil:
+		params := make(map[string]interface{})
+		for key, value := range ruleMap {
+			if key != "run" && key != "continue_on_error" {
+				params[key] = value
+			}
+		}
+		writeParameterTable(b, params)
+	case ruleMap["plugin"] != nil:
+		if pluginMap, ok := ruleMap["plugin"].(map[string]interface{}); ok {
+			params, _ := pluginMap["params"].(map[string]interface{})
+			writeParameterTable(b, params)
+		}
+	case ruleMap["conditional_set"] != nil:
+		spec, _ := ruleMap["conditional_set"].(map[string]interface{})
+		b.WriteString("**Value:**\n\n")
+		writeJSONBlock(b, spec["value"])
+		if condition, exists := spec["condition"]; exists {
+			b.WriteString("**Condition:**\n\n")
+			writeJSONBlock(b, condition)
+		}
+	case ruleMap["abort"] != nil:
+		reason := ""
+		switch spec := ruleMap["abort"].(type) {
+		case string:
+			reason = spec
+		case map[string]interface{}:
+			if value, exists := spec["reason"]; exists {
+				reason = fmt.Sprintf("%v", value)
+			}
+		}
+		if reason != "" {
+			fmt.Fprintf(b, "Reason: %s\n\n", reason)
+		}
+	case ruleMap["play"] != nil, ruleMap["play_async"] != nil, ruleMap["macro"] != nil, ruleMap["var"] != nil:
+		// The summary says it all
+	default:
+		writeJSONBlock(b, rule)
+	}
+}
+
+// writeIfDetails writes the condition of an if rule and a table of its branches
+func writeIfDetails(b *strings.Builder, ifExpr interface{}) {
+	var condition, thenAction, elseAction interface{}
+	thenLabel, elseLabel := "Then", "Else"
+
+	switch v := ifExpr.(type) {
+	case []interface{}:
+		if len(v) > 0 {
+			condition = v[0]
+		}
+		if len(v) > 1 {
+			thenAction = v[1]
+		}
+		if len(v) > 2 {
+			elseAction = v[2]
+		}
+	case map[string]interface{}:
+		logic, _ := v["logic"].(string)
+		if logic == "" {
+			logic = "and"
+		}
+		condition = map[string]interface{}{logic: v["conditions"]}
+		thenAction, elseAction = v["true"], v["false"]
+		thenLabel, elseLabel = "True", "False"
+	default:
+		writeJSONBlock(b, ifExpr)
+		return
+	}
+
+	b.WriteString("**Condition:**\n\n")
+	writeJSONBlock(b, condition)
+
+	b.WriteString("| Branch | Action |\n")
+	b.WriteString("|--------|--------|\n")
+	fmt.Fprintf(b, "| %s | %s |\n", thenLabel, describeBranch(thenAction))
+	fmt.Fprintf(b, "| %s | %s |\n", elseLabel, describeBranch(elseAction))
+	b.WriteString("\n")
+}
+
+// writeSwitchDetails writes a table of a switch rule's cases
+func writeSwitchDetails(b *strings.Builder, spec interface{}) {
+	_, cases, defaultRule, err := parseSwitchSpec(spec)
+	if err != nil {
+		writeJSONBlock(b, spec)
+		return
+	}
+
+	b.WriteString("| Case | Action |\n")
+	b.WriteString("|------|--------|\n")
+	for _, key := range sortedKeys(cases) {
+		fmt.Fprintf(b, "| %s | %s |\n", markdownTableCell(key), describeBranch(cases[key]))
+	}
+	fmt.Fprintf(b, "| *default* | %s |\n", describeBranch(defaultRule))
+	b.WriteString("\n")
+}
+
+// describeBranch summarizes an if branch for a table cell
+func describeBranch(action interface{}) string {
+	if action == nil {
+		return "Do nothing"
+	}
+	return markdownTableCell(describePlaybookRule(action))
+}
+
+// writeParameterTable writes operation parameters as a table, sorted by name
+func writeParameterTable(b *strings.Builder, params map[string]interface{}) {
+	if len(params) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	b.WriteString("| Parameter | Value |\n")
+	b.WriteString("|-----------|-------|\n")
+	for _, name := range names {
+		fmt.Fprintf(b, "| %s | %s |\n", markdownTableCell(name), markdownTableCell(markdownInlineCode(compactJSON(params[name]))))
+	}
+	b.WriteString("\n")
+}
+
+// writeJSONBlock writes a value as an indented JSON code block
+func writeJSONBlock(b *strings.Builder, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		data = []byte(fmt.Sprintf("%v", value))
+	}
+	fmt.Fprintf(b, "```json\n%s\n```\n\n", data)
+}
+
+// compactJSON renders a value as single-line JSON, leaving strings unquoted
+func compactJSON(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// markdownInlineCode wraps text in a code span, using a longer fence when the
+// text contains backticks
+func markdownInlineCode(text string) string {
+	if strings.Contains(text, "`") {
+		return "`` " + text + " ``"
+	}
+	return "`" + text + "`"
+}
+
+// markdownTableCell escapes text for use in a table cell
+func markdownTableCell(text string) string {
+	text = strings.ReplaceAll(text, "|", "\\|")
+	return strings.ReplaceAll(text, "\n", " ")
+}
+
+// pluralize picks the singular or plural form for a count
+func pluralize(count int, singular, plural string) string {
+	if count == 1 {
+		return singular
+	}
+	return plural
+}
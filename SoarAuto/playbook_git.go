@@ -0,0 +1,406 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPlaybookGitBranch applies when playbook_git.branch is unset
+	defaultPlaybookGitBranch = "main"
+	// defaultPlaybookGitCheckoutDir applies when playbook_git.checkout_dir is unset
+	defaultPlaybookGitCheckoutDir = "data/playbook_git"
+	// playbookGitTimeout bounds a single git command
+	playbookGitTimeout = 5 * time.Minute
+)
+
+// PlaybookGitStatus reports the state of the Git playbook source
+type PlaybookGitStatus struct {
+	Repo      string   `json:"repo"`
+	Branch    string   `json:"branch"`
+	Path      string   `json:"path,omitempty"`
+	Commit    string   `json:"commit,omitempty"`
+	SyncedAt  string   `json:"synced_at,omitempty"`
+	Playbooks []string `json:"playbooks"`
+	Skipped   []string `json:"skipped,omitempty"` // Files in the repository that are not valid playbooks
+	LastError string   `json:"last_error,omitempty"`
+}
+
+// PlaybookGitResponse is the response for /playbooks/sync
+type PlaybookGitResponse struct {
+	Success   bool              `json:"success"`
+	Message   string            `json:"message,omitempty"`
+	Status    PlaybookGitStatus `json:"status"`
+	Timestamp string            `json:"timestamp"`
+}
+
+// playbookReadOnlyError reports an API write to a playbook managed in Git
+type playbookReadOnlyError struct {
+	name string
+}
+
+func (e *playbookReadOnlyError) Error() string {
+	return fmt.Sprintf("playbook '%s' is synced from Git and read-only; change it in the repository", e.name)
+}
+
+// PlaybookGitSync keeps the playbooks directory in step with a branch of a
+// Git repository. Playbooks it syncs are read-only through the API.
+type PlaybookGitSync struct {
+	server   *SecAutoServer
+	config   PlaybookGitConfig
+	interval time.Duration
+	syncMu   sync.Mutex // Serializes syncs
+
+	mutex     sync.RWMutex
+	status    PlaybookGitStatus
+	playbooks map[string]bool
+
+	stopChan chan struct{}
+	stopOnce sync.Once
+}
+
+// NewPlaybookGitSync creates the Git playbook source. An empty sync interval
+// syncs only at startup and when POST /playbooks/sync is called.
+func NewPlaybookGitSync(server *SecAutoServer, config PlaybookGitConfig) (*PlaybookGitSync, error) {
+	if config.RepoURL == "" {
+		return nil, fmt.Errorf("playbook_git.repo_url is required")
+	}
+	if config.Branch == "" {
+		config.Branch = defaultPlaybookGitBranch
+	}
+	if config.CheckoutDir == "" {
+		config.CheckoutDir = defaultPlaybookGitCheckoutDir
+	}
+	if strings.HasPrefix(config.Branch, "-") {
+		return nil, fmt.Errorf("invalid playbook_git.branch %q", config.Branch)
+	}
+	if cleaned := filepath.Clean(config.Path); filepath.IsAbs(cleaned) || strings.HasPrefix(cleaned, "..") {
+		return nil, fmt.Errorf("playbook_git.path must be relative to the repository")
+	}
+
+	pg := &PlaybookGitSync{
+		server:    server,
+		config:    config,
+		playbooks: make(map[string]bool),
+		stopChan:  make(chan struct{}),
+		status: PlaybookGitStatus{
+			Repo:      redactRepoURL(config.RepoURL),
+			Branch:    config.Branch,
+			Path:      config.Path,
+			Playbooks: []string{},
+		},
+	}
+	if config.SyncInterval != "" {
+		interval, err := time.ParseDuration(config.SyncInterval)
+		if err != nil || interval <= 0 {
+			return nil, fmt.Errorf("invalid playbook_git.sync_interval %q", config.SyncInterval)
+		}
+		pg.interval = interval
+	}
+	return pg, nil
+}
+
+// Start syncs once and then on the configured interval
+func (pg *PlaybookGitSync) Start() {
+	go func() {
+		pg.Sync()
+		if pg.interval == 0 {
+			return
+		}
+
+		ticker := time.NewTicker(pg.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				pg.Sync()
+			case <-pg.stopChan:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops scheduled syncs
+func (pg *PlaybookGitSync) Stop() {
+	pg.stopOnce.Do(func() {
+		close(pg.stopChan)
+	})
+}
+
+// IsReadOnly reports whether a playbook is managed in Git
+func (pg *PlaybookGitSync) IsReadOnly(playbookName string) bool {
+	if pg == nil {
+		return false
+	}
+	pg.mutex.RLock()
+	defer pg.mutex.RUnlock()
+	return pg.playbooks[strings.TrimSuffix(playbookName, ".json")]
+}
+
+// Status returns the last synced commit and playbooks
+func (pg *PlaybookGitSync) Status() PlaybookGitStatus {
+	pg.mutex.RLock()
+	defer pg.mutex.RUnlock()
+	status := pg.status
+	status.Playbooks = append([]string(nil), pg.status.Playbooks...)
+	status.Skipped = append([]string(nil), pg.status.Skipped...)
+	return status
+}
+
+// Sync fetches the branch and writes its playbooks to the playbooks
+// directory. Playbooks removed from the repository since the last sync are
+// deleted; files that are not valid playbooks are skipped and reported.
+func (pg *PlaybookGitSync) Sync() (PlaybookGitStatus, error) {
+	pg.syncMu.Lock()
+	defer pg.syncMu.Unlock()
+
+	commit, err := pg.checkout()
+	if err != nil {
+		logger.Error("Failed to sync playbooks from Git", map[string]interface{}{
+			"component": "playbook_git",
+			"repo":      pg.status.Repo,
+			"branch":    pg.config.Branch,
+			"error":     err.Error(),
+		})
+		pg.mutex.Lock()
+		pg.status.LastError = err.Error()
+		pg.mutex.Unlock()
+		return pg.Status(), err
+	}
+
+	synced, skipped, err := pg.writePlaybooks()
+	if err != nil {
+		logger.Error("Failed to write playbooks synced from Git", map[string]interface{}{
+			"component": "playbook_git",
+			"commit":    commit,
+			"error":     err.Error(),
+		})
+		pg.mutex.Lock()
+		pg.status.LastError = err.Error()
+		pg.mutex.Unlock()
+		return pg.Status(), err
+	}
+
+	names := make([]string, 0, len(synced))
+	for name := range synced {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pg.mutex.Lock()
+	pg.playbooks = synced
+	pg.status.Commit = commit
+	pg.status.SyncedAt = time.Now().UTC().Format(time.RFC3339)
+	pg.status.Playbooks = names
+	pg.status.Skipped = skipped
+	pg.status.LastError = ""
+	pg.mutex.Unlock()
+
+	logger.Info("Synced playbooks from Git", map[string]interface{}{
+		"component": "playbook_git",
+		"repo":      pg.status.Repo,
+		"branch":    pg.config.Branch,
+		"commit":    commit,
+		"playbooks": len(names),
+		"skipped":   len(skipped),
+	})
+	return pg.Status(), nil
+}
+
+// checkout clones the branch on the first sync and fetches it afterwards,
+// returning the commit checked out
+func (pg *PlaybookGitSync) checkout() (string, error) {
+	dir := pg.config.CheckoutDir
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return "", fmt.Errorf("failed to create checkout directory: %v", err)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return "", fmt.Errorf("failed to clear checkout directory: %v", err)
+		}
+		if _, err := pg.git("", "clone", "--depth", "1", "--single-branch", "--branch", pg.config.Branch, "--", pg.config.RepoURL, dir); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := pg.git(dir, "fetch", "--depth", "1", "origin", pg.config.Branch); err != nil {
+			return "", err
+		}
+		if _, err := pg.git(dir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+	return pg.git(dir, "rev-parse", "HEAD")
+}
+
+// git runs a git command and returns its trimmed output. Credentials in the
+// repository URL are kept out of errors.
+func (pg *PlaybookGitSync) git(dir string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), playbookGitTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		message := strings.TrimSpace(strings.ReplaceAll(string(output), pg.config.RepoURL, pg.status.Repo))
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s failed: %s", args[0], message)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// writePlaybooks copies the repository's playbooks into the playbooks
+// directory and removes those no longer in the repository. It returns the
+// playbooks now managed in Git and the files skipped as invalid.
+func (pg *PlaybookGitSync) writePlaybooks() (map[string]bool, []string, error) {
+	sourceDir := filepath.Join(pg.config.CheckoutDir, pg.config.Path)
+	entries, err := os.ReadDir(sourceDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s in the repository: %v", pg.config.Path, err)
+	}
+
+	s := pg.server
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+
+	pg.mutex.RLock()
+	previous := pg.playbooks
+	pg.mutex.RUnlock()
+
+	synced := make(map[string]bool)
+	var skipped []string
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), ".json")
+		if s.validator.SanitizeFilename(entry.Name()) != entry.Name() {
+			skipped = append(skipped, fmt.Sprintf("%s: invalid file name", entry.Name()))
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(sourceDir, entry.Name()))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s: %v", entry.Name(), err)
+		}
+		if err := s.validatePlaybookStructure(content); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s: %v", entry.Name(), err))
+			// Keep serving the last valid version rather than dropping it
+			if previous[name] {
+				synced[name] = true
+			}
+			continue
+		}
+
+		path := s.engine.getPlaybookPath(name)
+		existing, err := os.ReadFile(path)
+		if err == nil && contentHash(existing) == contentHash(content) {
+			synced[name] = true
+			continue
+		}
+		if err == nil && !previous[name] {
+			logger.Warning("Playbook from Git replaces a playbook uploaded through the API", map[string]interface{}{
+				"component": "playbook_git",
+				"playbook":  name,
+			})
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, nil, fmt.Errorf("failed to create playbooks directory: %v", err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write playbook %s: %v", name, err)
+		}
+		synced[name] = true
+	}
+
+	for name := range previous {
+		if synced[name] {
+			continue
+		}
+		if err := os.Remove(s.engine.getPlaybookPath(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, nil, fmt.Errorf("failed to remove playbook %s: %v", name, err)
+		}
+		logger.Info("Removed playbook deleted from Git", map[string]interface{}{
+			"component": "playbook_git",
+			"playbook":  name,
+		})
+	}
+	return synced, skipped, nil
+}
+
+// redactRepoURL removes credentials from a repository URL
+func redactRepoURL(repoURL string) string {
+	parsed, err := url.Parse(repoURL)
+	if err != nil || parsed.User == nil {
+		return repoURL
+	}
+	parsed.User = nil
+	return parsed.String()
+}
+
+// checkPlaybookWritable refuses API changes to playbooks managed in Git
+func (s *SecAutoServer) checkPlaybookWritable(playbookName string) error {
+	if s.playbookGit.IsReadOnly(playbookName) {
+		return &playbookReadOnlyError{name: strings.TrimSuffix(playbookName, ".json")}
+	}
+	return nil
+}
+
+// writePlaybookReadOnlyError answers 409 Conflict if err refuses a change to
+// a playbook managed in Git, and reports whether it did
+func writePlaybookReadOnlyError(w http.ResponseWriter, err error) bool {
+	var readOnlyErr *playbookReadOnlyError
+	if !errors.As(err, &readOnlyErr) {
+		return false
+	}
+	http.Error(w, readOnlyErr.Error(), http.StatusConflict)
+	return true
+}
+
+// playbookSyncHandler handles /playbooks/sync: GET reports the synced
+// commit and playbooks, POST syncs now (e.g. from a Git push hook)
+func (s *SecAutoServer) playbookSyncHandler(w http.ResponseWriter, r *http.Request) {
+	if s.playbookGit == nil {
+		http.Error(w, "Git playbook sync is not enabled", http.StatusNotFound)
+		return
+	}
+
+	var response PlaybookGitResponse
+	switch r.Method {
+	case http.MethodGet:
+		response = PlaybookGitResponse{Success: true, Status: s.playbookGit.Status()}
+	case http.MethodPost:
+		status, err := s.playbookGit.Sync()
+		response = PlaybookGitResponse{Success: err == nil, Status: status, Message: "Playbooks synced from Git"}
+		if err != nil {
+			response.Message = err.Error()
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadGateway)
+			response.Timestamp = time.Now().UTC().Format(time.RFC3339)
+			json.NewEncoder(w).Encode(response)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxPlaybookMetrics bounds how many metrics playbooks may define
+	maxPlaybookMetrics = 200
+	// maxMetricLabels bounds the labels of one metric
+	maxMetricLabels = 10
+	// defaultMaxMetricSeries is used when monitoring.max_metric_series is unset
+	defaultMaxMetricSeries = 100
+)
+
+// metricNamePattern matches Prometheus metric and label names
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// metricSeries is one label combination of a metric
+type metricSeries struct {
+	labelValues []string
+	value       float64
+}
+
+// playbookMetric is a counter or gauge defined by a metric operation. Its
+// label names are fixed by the first operation that records it.
+type playbookMetric struct {
+	kind       string
+	help       string
+	labelNames []string
+	series     map[string]*metricSeries // Keyed by the joined label values
+}
+
+// PlaybookMetrics holds the metrics recorded by metric operations, served in
+// the Prometheus text format on /metrics
+type PlaybookMetrics struct {
+	mutex   sync.Mutex
+	metrics map[string]*playbookMetric
+}
+
+// playbookMetrics is shared by all engines, like the operation metrics
+var playbookMetrics = NewPlaybookMetrics()
+
+// NewPlaybookMetrics creates an empty metric registry
+func NewPlaybookMetrics() *PlaybookMetrics {
+	return &PlaybookMetrics{metrics: make(map[string]*playbookMetric)}
+}
+
+// Record adds value to a counter or sets a gauge and returns the new value
+// of the series. A metric keeps the type and label names it was first
+// recorded with, and at most maxSeries label combinations.
+func (pm *PlaybookMetrics) Record(name, kind, help string, labels map[string]string, value float64, maxSeries int) (float64, error) {
+	labelNames := make([]string, 0, len(labels))
+	for label := range labels {
+		labelNames = append(labelNames, label)
+	}
+	sort.Strings(labelNames)
+	labelValues := make([]string, len(labelNames))
+	for i, label := range labelNames {
+		labelValues[i] = labels[label]
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	metric, exists := pm.metrics[name]
+	if !exists {
+		if len(pm.metrics) >= maxPlaybookMetrics {
+			return 0, fmt.Errorf("metric %s: at most %d metrics may be defined", name, maxPlaybookMetrics)
+		}
+		metric = &playbookMetric{
+			kind:       kind,
+			help:       help,
+			labelNames: labelNames,
+			series:     make(map[string]*metricSeries),
+		}
+		pm.metrics[name] = metric
+	}
+	if metric.kind != kind {
+		return 0, fmt.Errorf("metric %s is a %s, not a %s", name, metric.kind, kind)
+	}
+	if strings.Join(metric.labelNames, ",") != strings.Join(labelNames, ",") {
+		return 0, fmt.Errorf("metric %s has labels [%s], not [%s]", name, strings.Join(metric.labelNames, ", "), strings.Join(labelNames, ", "))
+	}
+	if metric.help == "" {
+		metric.help = help
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	series, exists := metric.series[key]
+	if !exists {
+		if len(metric.series) >= maxSeries {
+			return 0, fmt.Errorf("metric %s: label values would exceed %d series", name, maxSeries)
+		}
+		series = &metricSeries{labelValues: labelValues}
+		metric.series[key] = series
+	}
+
+	if kind == "counter" {
+		series.value += value
+	} else {
+		series.value = value
+	}
+	return series.value, nil
+}
+
+// WritePrometheus writes every metric in the Prometheus text exposition format
+func (pm *PlaybookMetrics) WritePrometheus(w io.Writer) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+
+	names := make([]string, 0, len(pm.metrics))
+	for name := range pm.metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		metric := pm.metrics[name]
+		if metric.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", name, escapeMetricHelp(metric.help))
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, metric.kind)
+
+		keys := make([]string, 0, len(metric.series))
+		for key := range metric.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			series := metric.series[key]
+			fmt.Fprintf(w, "%s%s %s\n", name, formatMetricLabels(metric.labelNames, series.labelValues), formatMetricValue(series.value))
+		}
+	}
+}
+
+// formatMetricLabels renders {name="value",...}, or nothing without labels
+func formatMetricLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", name, escapeMetricLabel(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeMetricLabel escapes a label value for the text format
+func escapeMetricLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeMetricHelp escapes a help text for the text format
+func escapeMetricHelp(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(value)
+}
+
+// formatMetricValue renders a sample value
+func formatMetricValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// metricsHandler serves the metrics recorded by playbooks on GET /metrics in
+// the Prometheus text format
+func (s *SecAutoServer) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	playbookMetrics.WritePrometheus(w)
+}
+
+// validateMetricSpec checks the parts of a metric operation known when a
+// playbook is saved: {"name": "phishing_blocked", "type": "counter",
+// "value": 1, "labels": {"source": "{{incident.source}}"}, "help": "..."}
+func validateMetricSpec(spec interface{}) (string, string, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return "", "", fmt.Errorf("metric operation requires an object with a name")
+	}
+	for key := range specMap {
+		switch key {
+		case "name", "type", "value", "labels", "help":
+		default:
+			return "", "", fmt.Errorf("metric operation has unknown field %q", key)
+		}
+	}
+
+	name, _ := specMap["name"].(string)
+	if !metricNamePattern.MatchString(name) {
+		return "", "", fmt.Errorf("metric name %q must start with a letter or underscore and contain only letters, digits and underscores", name)
+	}
+
+	kind := "counter"
+	if value, exists := specMap["type"]; exists {
+		kind, _ = value.(string)
+		if kind != "counter" && kind != "gauge" {
+			return "", "", fmt.Errorf("metric %s: type must be counter or gauge", name)
+		}
+	}
+
+	if help, exists := specMap["help"]; exists {
+		if _, ok := help.(string); !ok {
+			return "", "", fmt.Errorf("metric %s: help must be a string", name)
+		}
+	}
+
+	if value, ok := jsonLogicNumeric(specMap["value"]); ok && kind == "counter" && value < 0 {
+		return "", "", fmt.Errorf("metric %s: a counter cannot be decreased", name)
+	}
+
+	if labels, exists := specMap["labels"]; exists {
+		labelMap, ok := labels.(map[string]interface{})
+		if !ok {
+			return "", "", fmt.Errorf("metric %s: labels must be an object", name)
+		}
+		if len(labelMap) > maxMetricLabels {
+			return "", "", fmt.Errorf("metric %s: at most %d labels are allowed", name, maxMetricLabels)
+		}
+		for label := range labelMap {
+			if !metricNamePattern.MatchString(label) || strings.HasPrefix(label, "__") {
+				return "", "", fmt.Errorf("metric %s: invalid label name %q", name, label)
+			}
+		}
+	}
+	return name, kind, nil
+}
+
+// metricLabelValue renders an evaluated label value
+func metricLabelValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// evaluateMetricOperation handles {"metric": {"name": "phishing_blocked",
+// "type": "counter", "value": 1, "labels": {"source": "{{incident.source}}"}}},
+// which adds value (1 by default) to a counter or sets a gauge to it. Value
+// and label values may be expressions. Metrics are served on /metrics while
+// monitoring.custom_metrics is on; otherwise the operation does nothing.
+func (re *RuleEngine) evaluateMetricOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	name, kind, err := validateMetricSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+	specMap := spec.(map[string]interface{})
+
+	if re.config != nil && !re.config.Monitoring.CustomMetrics {
+		return map[string]interface{}{"metric": name, "recorded": false}, nil
+	}
+
+	value := 1.0
+	if expression, exists := specMap["value"]; exists {
+		evaluated, err := re.evaluate(expression, data)
+		if err != nil {
+			return nil, fmt.Errorf("metric %s: failed to evaluate value: %v", name, err)
+		}
+		number, ok := jsonLogicNumeric(evaluated)
+		if !ok {
+			if text, isString := evaluated.(string); isString {
+				number, err = strconv.ParseFloat(strings.TrimSpace(text), 64)
+				ok = err == nil
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("metric %s: value must be a number, got %v", name, evaluated)
+		}
+		value = number
+	}
+	if kind == "counter" && value < 0 {
+		return nil, fmt.Errorf("metric %s: a counter cannot be decreased", name)
+	}
+
+	labels := make(map[string]string)
+	if labelMap, ok := specMap["labels"].(map[string]interface{}); ok {
+		for label, expression := range labelMap {
+			evaluated, err := re.evaluate(expression, data)
+			if err != nil {
+				return nil, fmt.Errorf("metric %s: failed to evaluate label %s: %v", name, label, err)
+			}
+			labels[label] = metricLabelValue(evaluated)
+		}
+	}
+
+	help, _ := specMap["help"].(string)
+	maxSeries := defaultMaxMetricSeries
+	if re.config != nil && re.config.Monitoring.MaxMetricSeries > 0 {
+		maxSeries = re.config.Monitoring.MaxMetricSeries
+	}
+	current, err := playbookMetrics.Record(name, kind, help, labels, value, maxSeries)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"metric":   name,
+		"recorded": true,
+		"value":    current,
+	}, nil
+}
@@ -0,0 +1,496 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// playbookBackupDir holds the prior version of every patched playbook
+var playbookBackupDir = filepath.Join("data", "playbook_backups")
+
+// maxPlaybookPatchSize is the largest patch body accepted
+const maxPlaybookPatchSize = 1 << 20
+
+// JSONPatchOperation is one operation of an RFC 6902 JSON Patch
+type JSONPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// PlaybookRuleEdit is one rule-index based edit: insert a rule before
+// Index, replace or delete the rule at Index, or move it to To. Indexes are
+// zero-based and apply to the playbook as left by the previous edit.
+type PlaybookRuleEdit struct {
+	Action string                 `json:"action"` // insert, replace, delete or move
+	Index  int                    `json:"index"`
+	To     int                    `json:"to,omitempty"`
+	Rule   map[string]interface{} `json:"rule,omitempty"`
+}
+
+// PlaybookEditSpec is the rule-index alternative to a JSON Patch
+type PlaybookEditSpec struct {
+	Edits []PlaybookRuleEdit `json:"edits"`
+}
+
+// PlaybookPatchResponse represents the response for a playbook patch
+type PlaybookPatchResponse struct {
+	Success      bool          `json:"success"`
+	Message      string        `json:"message"`
+	PlaybookName string        `json:"playbook_name"`
+	Playbook     []interface{} `json:"playbook"`
+	ContentHash  string        `json:"content_hash"`
+	Backup       string        `json:"backup"` // File holding the version replaced
+	Timestamp    string        `json:"timestamp"`
+}
+
+// playbookItemHandler dispatches /playbook/{name} by method, and
+// /playbook/{name}/runs
+func (s *SecAutoServer) playbookItemHandler(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(strings.TrimPrefix(r.URL.Path, "/playbook/"), "/runs"):
+		redisRequiredMiddleware(s.jobManager.store)(s.playbookRunsHandler)(w, r)
+	case r.Method == http.MethodPatch:
+		s.playbookPatchHandler(w, r)
+	default:
+		s.playbookDeleteHandler(w, r)
+	}
+}
+
+// playbookPatchHandler handles PATCH /playbook/{name}. The body is either a
+// JSON Patch (an array of operations) or an edit spec ({"edits": [...]}).
+// The patched playbook must pass the same structural validation as an
+// upload; the replaced version is kept in playbookBackupDir.
+func (s *SecAutoServer) playbookPatchHandler(w http.ResponseWriter, r *http.Request) {
+	playbookName := s.validator.SanitizePath(strings.TrimPrefix(r.URL.Path, "/playbook/"))
+	if playbookName == "" || playbookName == "." || strings.Contains(playbookName, "/") {
+		http.Error(w, "Invalid playbook name", http.StatusBadRequest)
+		return
+	}
+	playbookName = strings.TrimSuffix(playbookName, ".json")
+	if err := s.checkPlaybookWritable(playbookName); err != nil {
+		writePlaybookReadOnlyError(w, err)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPlaybookPatchSize+1))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to read request body: %v", err), http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxPlaybookPatchSize {
+		http.Error(w, "Patch exceeds 1MB limit", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	// Hold the storage lock from reading the playbook until the patched
+	// version is written, so concurrent patches apply one after another
+	s.storageMutex.Lock()
+	defer s.storageMutex.Unlock()
+
+	playbookPath := s.engine.getPlaybookPath(playbookName)
+	original, err := os.ReadFile(playbookPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, fmt.Sprintf("Playbook '%s' not found", playbookName), http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to read playbook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	var playbook interface{}
+	if err := json.Unmarshal(original, &playbook); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to parse stored playbook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	patched, err := applyPlaybookPatch(playbook, body)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to apply patch: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	content, err := json.MarshalIndent(patched, "", "  ")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to encode patched playbook: %v", err), http.StatusInternalServerError)
+		return
+	}
+	content = append(content, '\n')
+
+	if err := s.validatePlaybookStructure(content); err != nil {
+		response := ValidationResponse{
+			Success:   false,
+			Valid:     false,
+			Errors:    []ValidationError{{Field: "playbook", Message: err.Error()}},
+			Message:   "Patched playbook validation failed",
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(response)
+		return
+	}
+
+	if err := s.checkStorageQuota("playbooks", playbookPath, int64(len(content))); err != nil {
+		if writeStorageQuotaError(w, err) {
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to check storage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	backupPath, err := backupPlaybook(playbookName, original)
+	if err != nil {
+		logger.Error("Failed to back up playbook", map[string]interface{}{
+			"component": "server",
+			"playbook":  playbookName,
+			"error":     err.Error(),
+		})
+		http.Error(w, fmt.Sprintf("Failed to back up playbook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if err := os.WriteFile(playbookPath, content, 0644); err != nil {
+		logger.Error("Failed to save patched playbook", map[string]interface{}{
+			"component": "server",
+			"playbook":  playbookName,
+			"error":     err.Error(),
+		})
+		http.Error(w, fmt.Sprintf("Failed to save playbook: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := PlaybookPatchResponse{
+		Success:      true,
+		Message:      "Playbook patched successfully",
+		PlaybookName: playbookName,
+		Playbook:     patched.([]interface{}),
+		ContentHash:  contentHash(content),
+		Backup:       backupPath,
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+
+	logger.Info("Playbook patched successfully", map[string]interface{}{
+		"component": "server",
+		"playbook":  playbookName,
+		"backup":    backupPath,
+	})
+}
+
+// applyPlaybookPatch applies a JSON Patch or an edit spec to playbook
+func applyPlaybookPatch(playbook interface{}, body []byte) (interface{}, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, fmt.Errorf("patch body is empty")
+	}
+
+	if trimmed[0] == '[' {
+		var operations []JSONPatchOperation
+		if err := json.Unmarshal(trimmed, &operations); err != nil {
+			return nil, fmt.Errorf("invalid JSON Patch: %v", err)
+		}
+		return applyJSONPatch(playbook, operations)
+	}
+
+	var spec PlaybookEditSpec
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&spec); err != nil {
+		return nil, fmt.Errorf("invalid edit spec: %v", err)
+	}
+	if len(spec.Edits) == 0 {
+		return nil, fmt.Errorf("edit spec has no edits")
+	}
+	rules, ok := playbook.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("stored playbook is not an array of rules")
+	}
+	return applyRuleEdits(rules, spec.Edits)
+}
+
+// applyRuleEdits applies rule-index based edits in order
+func applyRuleEdits(rules []interface{}, edits []PlaybookRuleEdit) ([]interface{}, error) {
+	rules = append([]interface{}(nil), rules...)
+	for i, edit := range edits {
+		switch edit.Action {
+		case "insert":
+			if edit.Index < 0 || edit.Index > len(rules) {
+				return nil, fmt.Errorf("edit %d: insert index %d out of range 0-%d", i, edit.Index, len(rules))
+			}
+			if edit.Rule == nil {
+				return nil, fmt.Errorf("edit %d: insert requires a rule", i)
+			}
+			rules = append(rules[:edit.Index], append([]interface{}{edit.Rule}, rules[edit.Index:]...)...)
+		case "replace", "delete", "move":
+			if edit.Index < 0 || edit.Index >= len(rules) {
+				return nil, fmt.Errorf("edit %d: %s index %d out of range 0-%d", i, edit.Action, edit.Index, len(rules)-1)
+			}
+			switch edit.Action {
+			case "replace":
+				if edit.Rule == nil {
+					return nil, fmt.Errorf("edit %d: replace requires a rule", i)
+				}
+				rules[edit.Index] = edit.Rule
+			case "delete":
+				rules = append(rules[:edit.Index], rules[edit.Index+1:]...)
+			case "move":
+				if edit.To < 0 || edit.To >= len(rules) {
+					return nil, fmt.Errorf("edit %d: move target %d out of range 0-%d", i, edit.To, len(rules)-1)
+				}
+				rule := rules[edit.Index]
+				rules = append(rules[:edit.Index], rules[edit.Index+1:]...)
+				rules = append(rules[:edit.To], append([]interface{}{rule}, rules[edit.To:]...)...)
+			}
+		default:
+			return nil, fmt.Errorf("edit %d: unknown action '%s', expected insert, replace, delete or move", i, edit.Action)
+		}
+	}
+	return rules, nil
+}
+
+// applyJSONPatch applies RFC 6902 operations in order. Any failing
+// operation, including a failed test, rejects the whole patch.
+func applyJSONPatch(doc interface{}, operations []JSONPatchOperation) (interface{}, error) {
+	for i, operation := range operations {
+		path, err := parseJSONPointer(operation.Path)
+		if err != nil {
+			return nil, fmt.Errorf("operation %d: %v", i, err)
+		}
+
+		switch operation.Op {
+		case "add":
+			doc, err = jsonPatchAdd(doc, path, operation.Value)
+		case "remove":
+			doc, _, err = jsonPatchRemove(doc, path)
+		case "replace":
+			if len(path) == 0 {
+				doc = operation.Value
+			} else if _, err = jsonPointerGet(doc, path); err == nil {
+				doc, _, err = jsonPatchRemove(doc, path)
+			}
+			if err == nil {
+				doc, err = jsonPatchAdd(doc, path, operation.Value)
+			}
+		case "move", "copy":
+			var from []string
+			if from, err = parseJSONPointer(operation.From); err != nil {
+				break
+			}
+			if operation.Op == "move" && len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+				err = fmt.Errorf("cannot move '%s' into its own child '%s'", operation.From, operation.Path)
+				break
+			}
+			var value interface{}
+			if operation.Op == "move" {
+				doc, value, err = jsonPatchRemove(doc, from)
+			} else if value, err = jsonPointerGet(doc, from); err == nil {
+				value, err = deepCopyJSON(value)
+			}
+			if err == nil {
+				doc, err = jsonPatchAdd(doc, path, value)
+			}
+		case "test":
+			var value interface{}
+			if value, err = jsonPointerGet(doc, path); err == nil && !reflect.DeepEqual(value, operation.Value) {
+				err = fmt.Errorf("test failed: value at '%s' does not match", operation.Path)
+			}
+		default:
+			err = fmt.Errorf("unknown op '%s'", operation.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %v", i, operation.Op, operation.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+// parseJSONPointer splits an RFC 6901 pointer into unescaped reference tokens
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer '%s': must start with '/'", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// jsonArrayIndex parses an array index token; "-" (past the end) is only
+// valid when allowEnd is set
+func jsonArrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if token == "-" && allowEnd {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index '%s'", token)
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 {
+		return 0, fmt.Errorf("invalid array index '%s'", token)
+	}
+	limit := length - 1
+	if allowEnd {
+		limit = length
+	}
+	if index > limit {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+// jsonPointerGet returns the value path points to
+func jsonPointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch container := doc.(type) {
+		case map[string]interface{}:
+			value, exists := container[token]
+			if !exists {
+				return nil, fmt.Errorf("key '%s' not found", token)
+			}
+			doc = value
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = container[index]
+		default:
+			return nil, fmt.Errorf("cannot index into %T with '%s'", doc, token)
+		}
+	}
+	return doc, nil
+}
+
+// jsonPointerUpdate rewrites the container holding the last token of path
+// with update and returns the new document
+func jsonPointerUpdate(doc interface{}, path []string, update func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return update(doc, path[0])
+	}
+
+	switch container := doc.(type) {
+	case map[string]interface{}:
+		child, exists := container[path[0]]
+		if !exists {
+			return nil, fmt.Errorf("key '%s' not found", path[0])
+		}
+		updated, err := jsonPointerUpdate(child, path[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		container[path[0]] = updated
+		return container, nil
+	case []interface{}:
+		index, err := jsonArrayIndex(path[0], len(container), false)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := jsonPointerUpdate(container[index], path[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		container[index] = updated
+		return container, nil
+	default:
+		return nil, fmt.Errorf("cannot index into %T with '%s'", doc, path[0])
+	}
+}
+
+// jsonPatchAdd adds value at path, inserting into arrays
+func jsonPatchAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return jsonPointerUpdate(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			c[token] = value
+			return c, nil
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			result := make([]interface{}, 0, len(c)+1)
+			result = append(result, c[:index]...)
+			result = append(result, value)
+			return append(result, c[index:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot add to %T", container)
+		}
+	})
+}
+
+// jsonPatchRemove removes the value at path and returns it
+func jsonPatchRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil, fmt.Errorf("cannot remove the whole document")
+	}
+	var removed interface{}
+	doc, err := jsonPointerUpdate(doc, path, func(container interface{}, token string) (interface{}, error) {
+		switch c := container.(type) {
+		case map[string]interface{}:
+			value, exists := c[token]
+			if !exists {
+				return nil, fmt.Errorf("key '%s' not found", token)
+			}
+			removed = value
+			delete(c, token)
+			return c, nil
+		case []interface{}:
+			index, err := jsonArrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = c[index]
+			result := make([]interface{}, 0, len(c)-1)
+			result = append(result, c[:index]...)
+			return append(result, c[index+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove from %T", container)
+		}
+	})
+	return doc, removed, err
+}
+
+// deepCopyJSON copies a decoded JSON value
+func deepCopyJSON(value interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(encoded, &copied)
+	return copied, err
+}
+
+// backupPlaybook keeps content, the version of a playbook about to be
+// replaced, and returns the backup's path
+func backupPlaybook(playbookName string, content []byte) (string, error) {
+	if err := os.MkdirAll(playbookBackupDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %v", err)
+	}
+	backupPath := filepath.Join(playbookBackupDir, fmt.Sprintf("%s.%s.json", playbookName, time.Now().UTC().Format("20060102T150405.000000000")))
+	if err := os.WriteFile(backupPath, content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write backup: %v", err)
+	}
+	return backupPath, nil
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// maxPlaybookRunHistory bounds the runs kept in each playbook's history
+	maxPlaybookRunHistory = 1000
+	// defaultPlaybookRuns is the number of runs returned without a limit
+	defaultPlaybookRuns = 20
+	// inlinePlaybookPrefix starts the names given to inline playbooks
+	inlinePlaybookPrefix = "inline-"
+)
+
+// JobSource identifies the playbook a job runs and who submitted it, and
+// carries the run options given with the submission
+type JobSource struct {
+	PlaybookName    string
+	TriggeredBy     string
+	ContinueOnError bool
+	Strict          *bool
+}
+
+// PlaybookRun summarizes one job in a playbook's run history
+type PlaybookRun struct {
+	JobID           string     `json:"job_id"`
+	Status          string     `json:"status"`
+	TriggeredBy     string     `json:"triggered_by,omitempty"`
+	Error           string     `json:"error,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds,omitempty"`
+}
+
+// PlaybookRunsResponse is the response for GET /playbook/{name}/runs
+type PlaybookRunsResponse struct {
+	Success      bool          `json:"success"`
+	PlaybookName string        `json:"playbook_name"`
+	Runs         []PlaybookRun `json:"runs"`
+	Total        int           `json:"total"`
+	Timestamp    string        `json:"timestamp"`
+}
+
+// inlinePlaybookName names an inline playbook by its content, so every
+// submission of the same playbook shares one run history
+func inlinePlaybookName(playbook []interface{}) string {
+	content, err := json.Marshal(playbook)
+	if err != nil {
+		return ""
+	}
+	return inlinePlaybookPrefix + contentHash(content)[:12]
+}
+
+// playbookRunsKey is the Redis key of a playbook's run history, a sorted set
+// of job IDs scored by creation time
+func playbookRunsKey(playbookName string) string {
+	return fmt.Sprintf("jobs:playbook:%s", playbookName)
+}
+
+// indexPlaybookRun adds a job to its playbook's run history, keeping the
+// most recent maxPlaybookRunHistory runs. The history expires with the jobs
+// once the playbook stops running.
+func (rjs *RedisJobStore) indexPlaybookRun(jobID string, createdAt time.Time, playbookName string) error {
+	key := playbookRunsKey(playbookName)
+	pipe := rjs.client.TxPipeline()
+	pipe.ZAdd(rjs.ctx, key, redis.Z{
+		Score:  float64(createdAt.Unix()),
+		Member: jobID,
+	})
+	pipe.ZRemRangeByRank(rjs.ctx, key, 0, -maxPlaybookRunHistory-1)
+	pipe.Expire(rjs.ctx, key, 24*time.Hour)
+	if _, err := pipe.Exec(rjs.ctx); err != nil {
+		return fmt.Errorf("failed to add job to playbook history: %v", err)
+	}
+	return nil
+}
+
+// ListPlaybookRuns returns the most recent jobs of a playbook, newest first
+func (rjs *RedisJobStore) ListPlaybookRuns(playbookName string, limit int) []*Job {
+	var jobs []*Job
+
+	key := playbookRunsKey(playbookName)
+	jobIDs, err := rjs.client.ZRevRange(rjs.ctx, key, 0, int64(limit-1)).Result()
+	if err != nil {
+		logger.Error("Failed to get playbook runs", map[string]interface{}{
+			"component": "job_store",
+			"playbook":  playbookName,
+			"error":     err.Error(),
+		})
+		return jobs
+	}
+
+	for _, jobID := range jobIDs {
+		job, exists := rjs.LoadJob(jobID)
+		if !exists {
+			// The job expired or was deleted
+			rjs.client.ZRem(rjs.ctx, key, jobID)
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// playbookRunsHandler handles GET /playbook/{name}/runs, the recent jobs of
+// one playbook. Inline playbooks are listed under their inline-<hash> name.
+func (s *SecAutoServer) playbookRunsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	playbookName := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/playbook/"), "/runs")
+	playbookName = strings.TrimSuffix(s.validator.SanitizePath(playbookName), ".json")
+	if playbookName == "" || playbookName == "." || strings.Contains(playbookName, "/") {
+		http.Error(w, "Invalid playbook name", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultPlaybookRuns
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxPlaybookRunHistory {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxPlaybookRunHistory), http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	jobs := s.jobManager.store.ListPlaybookRuns(playbookName, limit)
+	runs := make([]PlaybookRun, 0, len(jobs))
+	for _, job := range jobs {
+		run := PlaybookRun{
+			JobID:       job.ID,
+			Status:      job.Status,
+			TriggeredBy: job.TriggeredBy,
+			Error:       job.Error,
+			CreatedAt:   job.CreatedAt,
+			StartedAt:   job.StartedAt,
+			CompletedAt: job.CompletedAt,
+		}
+		if job.StartedAt != nil && job.CompletedAt != nil {
+			run.DurationSeconds = job.CompletedAt.Sub(*job.StartedAt).Seconds()
+		}
+		runs = append(runs, run)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(PlaybookRunsResponse{
+		Success:      true,
+		PlaybookName: playbookName,
+		Runs:         runs,
+		Total:        len(runs),
+		Timestamp:    time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxTopReferences bounds the most-referenced automations, plugins and
+// playbooks listed in the statistics
+const maxTopReferences = 10
+
+// playbookAnalysis describes the operations of one playbook
+type playbookAnalysis struct {
+	Operations  map[string]int
+	Automations map[string]int // References per automation run
+	Plugins     map[string]int // References per plugin executed
+	Playbooks   map[string]int // References per nested playbook played
+	Total       int            // Operations at any depth
+	MaxDepth    int            // Deepest operation nesting; top-level rules are 1
+}
+
+// analyzePlaybook walks a playbook, including rules nested in if branches,
+// loops and try blocks, and counts its operations and references
+func analyzePlaybook(playbook []interface{}) playbookAnalysis {
+	analysis := playbookAnalysis{
+		Operations:  make(map[string]int),
+		Automations: make(map[string]int),
+		Plugins:     make(map[string]int),
+		Playbooks:   make(map[string]int),
+	}
+
+	var walk func(value interface{}, depth int)
+	walk = func(value interface{}, depth int) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if op := playbookOperationType(v); op != "" {
+				depth++
+				analysis.Operations[op]++
+				analysis.Total++
+				if depth > analysis.MaxDepth {
+					analysis.MaxDepth = depth
+				}
+
+				switch op {
+				case "run":
+					if name, ok := v["run"].(string); ok && name != "" {
+						analysis.Automations[name]++
+					}
+				case "play":
+					if name, ok := v["play"].(string); ok && name != "" {
+						analysis.Playbooks[name]++
+					}
+				case "play_async":
+					if name := playAsyncPlaybookName(v["play_async"]); name != "" {
+						analysis.Playbooks[name]++
+					}
+				case "plugin":
+					name, _ := v["plugin"].(string)
+					if pluginMap, ok := v["plugin"].(map[string]interface{}); ok {
+						name, _ = pluginMap["name"].(string)
+					}
+					if name != "" {
+						analysis.Plugins[name]++
+					}
+				}
+			}
+			for _, child := range v {
+				walk(child, depth)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child, depth)
+			}
+		}
+	}
+	walk(playbook, 0)
+
+	return analysis
+}
+
+// ReferenceCount is a name and how often the playbook library references it
+type ReferenceCount struct {
+	Name       string `json:"name"`
+	References int    `json:"references"`
+	Playbooks  int    `json:"playbooks"` // Playbooks referencing it at least once
+}
+
+// PlaybookStatsResponse is the library-wide view returned by /playbooks/stats
+type PlaybookStatsResponse struct {
+	Success           bool             `json:"success"`
+	PlaybookCount     int              `json:"playbook_count"`
+	InvalidPlaybooks  []string         `json:"invalid_playbooks,omitempty"` // Files that could not be parsed
+	TotalRules        int              `json:"total_rules"`
+	TotalOperations   int              `json:"total_operations"`
+	Operations        map[string]int   `json:"operations"`
+	TopAutomations    []ReferenceCount `json:"top_automations"`
+	TopPlugins        []ReferenceCount `json:"top_plugins"`
+	TopPlaybooks      []ReferenceCount `json:"top_playbooks"` // Most played as nested playbooks
+	AverageRules      float64          `json:"average_rules"`
+	AverageOperations float64          `json:"average_operations"`
+	AverageMaxDepth   float64          `json:"average_max_depth"`
+	MaxDepth          int              `json:"max_depth"`
+	GeneratedAt       string           `json:"generated_at"`
+	Cached            bool             `json:"cached"`
+	Timestamp         string           `json:"timestamp"`
+}
+
+// playbookStatsCache holds the latest statistics and the state of the
+// playbooks directory they were computed from
+var playbookStatsCache struct {
+	sync.Mutex
+	fingerprint string
+	stats       PlaybookStatsResponse
+}
+
+// playbookFiles returns the JSON files in dir and a fingerprint of their
+// names, sizes and modification times that changes when any playbook does
+func playbookFiles(dir string) ([]string, string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to read playbooks directory: %v", err)
+	}
+
+	var files []string
+	var fingerprint strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(strings.ToLower(entry.Name()), ".json") || entry.Name() == macrosFileName {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, entry.Name())
+		fmt.Fprintf(&fingerprint, "%s:%d:%d;", entry.Name(), info.Size(), info.ModTime().UnixNano())
+	}
+	return files, fingerprint.String(), nil
+}
+
+// computePlaybookStats aggregates the analysis of every playbook in dir
+func computePlaybookStats(dir string, files []string) PlaybookStatsResponse {
+	stats := PlaybookStatsResponse{Operations: make(map[string]int)}
+	automations := make(map[string]*ReferenceCount)
+	plugins := make(map[string]*ReferenceCount)
+	playbooks := make(map[string]*ReferenceCount)
+	depthSum := 0
+
+	for _, file := range files {
+		content, err := os.ReadFile(filepath.Join(dir, file))
+		var playbook []interface{}
+		if err == nil {
+			err = json.Unmarshal(content, &playbook)
+		}
+		if err != nil {
+			stats.InvalidPlaybooks = append(stats.InvalidPlaybooks, strings.TrimSuffix(file, ".json"))
+			continue
+		}
+
+		analysis := analyzePlaybook(playbook)
+		stats.PlaybookCount++
+		stats.TotalRules += len(playbook)
+		stats.TotalOperations += analysis.Total
+		depthSum += analysis.MaxDepth
+		if analysis.MaxDepth > stats.MaxDepth {
+			stats.MaxDepth = analysis.MaxDepth
+		}
+		for op, count := range analysis.Operations {
+			stats.Operations[op] += count
+		}
+		addReferenceCounts(automations, analysis.Automations)
+		addReferenceCounts(plugins, analysis.Plugins)
+		addReferenceCounts(playbooks, analysis.Playbooks)
+	}
+
+	if stats.PlaybookCount > 0 {
+		count := float64(stats.PlaybookCount)
+		stats.AverageRules = float64(stats.TotalRules) / count
+		stats.AverageOperations = float64(stats.TotalOperations) / count
+		stats.AverageMaxDepth = float64(depthSum) / count
+	}
+	stats.TopAutomations = topReferences(automations)
+	stats.TopPlugins = topReferences(plugins)
+	stats.TopPlaybooks = topReferences(playbooks)
+	stats.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+	return stats
+}
+
+// addReferenceCounts adds one playbook's references to the library totals
+func addReferenceCounts(totals map[string]*ReferenceCount, references map[string]int) {
+	for name, count := range references {
+		total, exists := totals[name]
+		if !exists {
+			total = &ReferenceCount{Name: name}
+			totals[name] = total
+		}
+		total.References += count
+		total.Playbooks++
+	}
+}
+
+// topReferences returns the most-referenced names, most first
+func topReferences(totals map[string]*ReferenceCount) []ReferenceCount {
+	references := make([]ReferenceCount, 0, len(totals))
+	for _, total := range totals {
+		references = append(references, *total)
+	}
+	sort.Slice(references, func(i, j int) bool {
+		if references[i].References != references[j].References {
+			return references[i].References > references[j].References
+		}
+		return references[i].Name < references[j].Name
+	})
+	if len(references) > maxTopReferences {
+		references = references[:maxTopReferences]
+	}
+	return references
+}
+
+// playbookStatsHandler handles GET /playbooks/stats, returning operation
+// counts, the most-referenced automations and plugins and the average
+// complexity across the playbook library. The statistics are recomputed only
+// when a playbook file has been added, changed or removed.
+func (s *SecAutoServer) playbookStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	dir := s.engine.config.Python.PlaybooksPath
+	files, fingerprint, err := playbookFiles(dir)
+	if err != nil {
+		logger.Error("Failed to compute playbook statistics", map[string]interface{}{
+			"component": "server",
+			"error":     err.Error(),
+		})
+		http.Error(w, fmt.Sprintf("Failed to compute playbook statistics: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	playbookStatsCache.Lock()
+	cached := playbookStatsCache.stats.GeneratedAt != "" && playbookStatsCache.fingerprint == fingerprint
+	if !cached {
+		playbookStatsCache.stats = computePlaybookStats(dir, files)
+		playbookStatsCache.fingerprint = fingerprint
+	}
+	stats := playbookStatsCache.stats
+	playbookStatsCache.Unlock()
+
+	stats.Success = true
+	stats.Cached = cached
+	stats.Timestamp = time.Now().UTC().Format(time.RFC3339)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+
+	logger.Info("Playbook statistics retrieved", map[string]interface{}{
+		"component": "server",
+		"playbooks": stats.PlaybookCount,
+		"cached":    cached,
+	})
+}
@@ -0,0 +1,476 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PlaybookTransformer rewrites or checks a playbook before it is evaluated.
+// Transformers run in a pipeline, each on the output of the previous one, and
+// must not modify the playbook they are given.
+type PlaybookTransformer interface {
+	Transform(playbook []interface{}) ([]interface{}, error)
+}
+
+// RegisterTransformer adds a transformer to the pipeline. Registered
+// transformers run after the built-in ones, in registration order.
+func (re *RuleEngine) RegisterTransformer(transformer PlaybookTransformer) {
+	re.transformers = append(re.transformers, transformer)
+}
+
+// playbookTransformers returns the pipeline for this engine. The built-in
+// transformers are bound to the engine they run for, so copies made with
+// WithEnv check dependencies against their own plugin manager.
+func (re *RuleEngine) playbookTransformers() []PlaybookTransformer {
+	transformers := []PlaybookTransformer{
+		&MacroExpander{engine: re},
+		&LoopNormalizer{},
+		&SchemaValidator{},
+		&DependencyChecker{engine: re},
+	}
+	return append(transformers, re.transformers...)
+}
+
+// TransformPlaybook runs the playbook through the transformer pipeline
+func (re *RuleEngine) TransformPlaybook(playbook []interface{}) ([]interface{}, error) {
+	for _, transformer := range re.playbookTransformers() {
+		transformed, err := transformer.Transform(playbook)
+		if err != nil {
+			return nil, err
+		}
+		playbook = transformed
+	}
+	return playbook, nil
+}
+
+// MacroExpander inlines {"macro": ...} references
+type MacroExpander struct {
+	engine *RuleEngine
+}
+
+// Transform expands the playbook's macro references
+func (me *MacroExpander) Transform(playbook []interface{}) ([]interface{}, error) {
+	expanded, err := me.engine.expandMacros(playbook)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand macros: %v", err)
+	}
+	return expanded, nil
+}
+
+// LoopNormalizer rewrites every foreach operation into its canonical form,
+// {"foreach": {"items": ..., "as": ..., "do": [...]}}
+type LoopNormalizer struct{}
+
+// Transform normalizes the playbook's foreach operations
+func (ln *LoopNormalizer) Transform(playbook []interface{}) ([]interface{}, error) {
+	normalized, err := rewritePlaybookRules(playbook, func(rule map[string]interface{}) (map[string]interface{}, error) {
+		if _, exists := rule["foreach"]; !exists {
+			return rule, nil
+		}
+		spec, err := normalizeForeach(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid foreach: %v", err)
+		}
+		return map[string]interface{}{"foreach": spec}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return normalized.([]interface{}), nil
+}
+
+// SchemaValidator checks the type declarations of vars operations and
+// rewrites them into their canonical {"type": ..., "required": ...} form. A
+// variable may be declared more than once, but always with the same type.
+type SchemaValidator struct{}
+
+// Transform validates the playbook's variable declarations
+func (sv *SchemaValidator) Transform(playbook []interface{}) ([]interface{}, error) {
+	declared := make(map[string]string)
+	normalized, err := rewritePlaybookRules(playbook, func(rule map[string]interface{}) (map[string]interface{}, error) {
+		spec, exists := rule["vars"]
+		if !exists {
+			return rule, nil
+		}
+		declarations, err := normalizeVarDeclarations(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vars declaration: %v", err)
+		}
+		for name, declaration := range declarations {
+			varType := declaration.(map[string]interface{})["type"].(string)
+			if previous, exists := declared[name]; exists && previous != varType {
+				return nil, fmt.Errorf("invalid vars declaration: variable %s is declared as both %s and %s", name, previous, varType)
+			}
+			declared[name] = varType
+		}
+		return map[string]interface{}{"vars": declarations}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return normalized.([]interface{}), nil
+}
+
+// DependencyChecker verifies that the automations, playbooks and plugins a
+// playbook references exist
+type DependencyChecker struct {
+	engine *RuleEngine
+}
+
+// Transform fails if the playbook references a missing dependency
+func (dc *DependencyChecker) Transform(playbook []interface{}) ([]interface{}, error) {
+	missing := dc.MissingDependencies(playbook)
+	if len(missing) == 0 {
+		return playbook, nil
+	}
+
+	messages := make([]string, len(missing))
+	for i, dependency := range missing {
+		messages[i] = dependency.Message
+	}
+	return nil, fmt.Errorf("missing dependencies: %s", strings.Join(messages, "; "))
+}
+
+// MissingDependencies lists the referenced automations, playbooks and plugins
+// that do not exist. Names built from templates are resolved at run time and
+// are not checked; plugins are only checked when a plugin manager is set.
+func (dc *DependencyChecker) MissingDependencies(playbook []interface{}) []ValidationError {
+	var missing []ValidationError
+	reported := make(map[string]bool)
+	report := func(field, message, value string) {
+		if reported[field+"\x00"+value] {
+			return
+		}
+		reported[field+"\x00"+value] = true
+		missing = append(missing, ValidationError{Field: field, Message: message, Value: value})
+	}
+
+	walkPlaybookRules(playbook, func(rule map[string]interface{}) {
+		checkAutomation := func(field string, script string) {
+			if script == "" || strings.Contains(script, "{{") {
+				return
+			}
+			if _, err := os.Stat(dc.engine.getScriptPath(script)); err != nil {
+				report(field, fmt.Sprintf("Automation %s does not exist", script), script)
+			}
+		}
+		switch ruleOperation(rule) {
+		case "run":
+			script, _ := rule["run"].(string)
+			checkAutomation("run", script)
+		case "batch":
+			// A batch runs its automation once per group of items
+			spec, _ := rule["batch"].(map[string]interface{})
+			script, _ := spec["run"].(string)
+			checkAutomation("batch.run", script)
+		}
+		if nested, ok := rule["play"].(string); ok && !strings.Contains(nested, "{{") {
+			if _, err := os.Stat(dc.engine.getPlaybookPath(nested)); err != nil {
+				report("play", fmt.Sprintf("Playbook %s does not exist", nested), nested)
+			}
+		}
+		if nested := playAsyncPlaybookName(rule["play_async"]); nested != "" && !strings.Contains(nested, "{{") {
+			if _, err := os.Stat(dc.engine.getPlaybookPath(nested)); err != nil {
+				report("play_async", fmt.Sprintf("Playbook %s does not exist", nested), nested)
+			}
+		}
+		if plugin := playbookPluginName(rule["plugin"]); plugin != "" && !strings.Contains(plugin, "{{") && dc.engine.pluginManager != nil {
+			if _, exists := dc.engine.pluginManager.GetPlugin(plugin); !exists {
+				if platform, disabled := dc.engine.pluginManager.DisabledPluginPlatform(plugin); disabled {
+					report("plugin", fmt.Sprintf("Plugin %s is on platform %s, which is disabled", plugin, platform), plugin)
+				} else {
+					report("plugin", fmt.Sprintf("Plugin %s is not loaded", plugin), plugin)
+				}
+			}
+		}
+	})
+	return missing
+}
+
+// ruleOperationOrder lists the operations whose specs hold nested rules,
+// together with the operations evaluateOperation checks before them, in the
+// order it checks them. A rule evaluates as the first of these it has.
+var ruleOperationOrder = []string{"run", "play", "play_async", "if", "switch", "plugin", "map", "conditional_set", "foreach", "batch", "vars", "try", "macro_scope"}
+
+// ruleOperation returns the operation of ruleOperationOrder a rule object
+// evaluates as, or "" for other operations
+func ruleOperation(rule map[string]interface{}) string {
+	for _, operation := range ruleOperationOrder {
+		if _, exists := rule[operation]; exists {
+			return operation
+		}
+	}
+	return ""
+}
+
+// mapNestedRules returns a copy of a rule in which each value holding nested
+// rules has been replaced by apply's result: the true and false branches of
+// if, the cases and default of switch, the do of foreach, the do, catch and
+// finally of try, and the rule of an expanded macro's scope. Values in other positions, such as data literals, are
+// not passed to apply. The rule is left unchanged.
+func mapNestedRules(rule map[string]interface{}, apply func(rules interface{}) (interface{}, error)) (map[string]interface{}, error) {
+	copied := make(map[string]interface{}, len(rule))
+	for key, value := range rule {
+		copied[key] = value
+	}
+
+	// applyFields replaces the named fields of an object spec
+	applyFields := func(spec map[string]interface{}, fields ...string) (map[string]interface{}, error) {
+		specCopy := make(map[string]interface{}, len(spec))
+		for key, value := range spec {
+			specCopy[key] = value
+		}
+		for _, field := range fields {
+			value, exists := spec[field]
+			if !exists {
+				continue
+			}
+			applied, err := apply(value)
+			if err != nil {
+				return nil, err
+			}
+			specCopy[field] = applied
+		}
+		return specCopy, nil
+	}
+
+	var err error
+	switch operation := ruleOperation(rule); operation {
+	case "if":
+		switch spec := rule["if"].(type) {
+		case map[string]interface{}:
+			copied["if"], err = applyFields(spec, "true", "false")
+		case []interface{}:
+			// [condition, then] or [condition, then, else]
+			specCopy := append([]interface{}(nil), spec...)
+			for i := 1; i < len(specCopy) && i < 3; i++ {
+				if specCopy[i], err = apply(spec[i]); err != nil {
+					return nil, err
+				}
+			}
+			copied["if"] = specCopy
+		}
+	case "switch":
+		spec, ok := rule["switch"].(map[string]interface{})
+		if !ok {
+			break
+		}
+		var specCopy map[string]interface{}
+		if specCopy, err = applyFields(spec, "default"); err != nil {
+			return nil, err
+		}
+		if cases, ok := spec["cases"].(map[string]interface{}); ok {
+			keys := make([]string, 0, len(cases))
+			for key := range cases {
+				keys = append(keys, key)
+			}
+			specCopy["cases"], err = applyFields(cases, keys...)
+		}
+		copied["switch"] = specCopy
+	case "foreach":
+		// The forms accepted by normalizeForeach
+		switch spec := rule["foreach"].(type) {
+		case map[string]interface{}:
+			copied["foreach"], err = applyFields(spec, "do")
+		case string:
+			copied, err = applyFields(copied, "do")
+		case []interface{}:
+			if len(spec) == 2 || len(spec) == 3 {
+				specCopy := append([]interface{}(nil), spec...)
+				specCopy[len(spec)-1], err = apply(spec[len(spec)-1])
+				copied["foreach"] = specCopy
+			}
+		}
+	case "try":
+		if spec, ok := rule["try"].(map[string]interface{}); ok {
+			copied["try"], err = applyFields(spec, "do", "catch", "finally")
+		}
+	case "macro_scope":
+		if spec, ok := rule["macro_scope"].(map[string]interface{}); ok {
+			copied["macro_scope"], err = applyFields(spec, "rule")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
+
+// walkPlaybookRules calls visit for every rule in a playbook: each top-level
+// rule and the rules nested in the branches and bodies of other operations
+func walkPlaybookRules(value interface{}, visit func(rule map[string]interface{})) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		visit(v)
+		mapNestedRules(v, func(nested interface{}) (interface{}, error) {
+			walkPlaybookRules(nested, visit)
+			return nested, nil
+		})
+	case []interface{}:
+		for _, nested := range v {
+			walkPlaybookRules(nested, visit)
+		}
+	}
+}
+
+// rewritePlaybookRules returns a copy of value in which every rule, as found
+// by walkPlaybookRules, has been passed through rewrite, innermost rules
+// first. The original is left unchanged.
+func rewritePlaybookRules(value interface{}, rewrite func(rule map[string]interface{}) (map[string]interface{}, error)) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		rule, err := mapNestedRules(v, func(nested interface{}) (interface{}, error) {
+			return rewritePlaybookRules(nested, rewrite)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return rewrite(rule)
+	case []interface{}:
+		rules := make([]interface{}, len(v))
+		for i, nested := range v {
+			rewritten, err := rewritePlaybookRules(nested, rewrite)
+			if err != nil {
+				return nil, err
+			}
+			rules[i] = rewritten
+		}
+		return rules, nil
+	}
+	return value, nil
+}
+
+// playbookPluginName returns the plugin named by a plugin operation
+func playbookPluginName(pluginExpr interface{}) string {
+	switch v := pluginExpr.(type) {
+	case string:
+		return v
+	case map[string]interface{}:
+		name, _ := v["name"].(string)
+		return name
+	}
+	return ""
+}
+
+// normalizeForeach returns the canonical spec of a foreach rule. Besides the
+// canonical object it accepts a variable path with "as" and "do" beside it,
+// {"foreach": "iocs", "as": "ioc", "do": [...]}, and the positional forms
+// [items, do] and [items, as, do]. An items string is a variable path.
+func normalizeForeach(rule map[string]interface{}) (map[string]interface{}, error) {
+	var items, as, do interface{}
+	switch spec := rule["foreach"].(type) {
+	case map[string]interface{}:
+		items, as, do = spec["items"], spec["as"], spec["do"]
+	case string:
+		items, as, do = spec, rule["as"], rule["do"]
+	case []interface{}:
+		switch len(spec) {
+		case 2:
+			items, do = spec[0], spec[1]
+		case 3:
+			items, as, do = spec[0], spec[1], spec[2]
+		default:
+			return nil, fmt.Errorf("array form must be [items, do] or [items, as, do]")
+		}
+	default:
+		return nil, fmt.Errorf("expected an object, a variable path or an array")
+	}
+
+	if items == nil {
+		return nil, fmt.Errorf("items is required")
+	}
+	if path, ok := items.(string); ok {
+		items = map[string]interface{}{"var": path}
+	}
+
+	if as == nil {
+		as = "item"
+	}
+	asName, ok := as.(string)
+	if !ok || asName == "" {
+		return nil, fmt.Errorf("as must be a non-empty variable name")
+	}
+
+	var rules []interface{}
+	switch body := do.(type) {
+	case []interface{}:
+		rules = body
+	case map[string]interface{}:
+		rules = []interface{}{body}
+	default:
+		return nil, fmt.Errorf("do must be a rule or an array of rules")
+	}
+
+	return map[string]interface{}{
+		"items": items,
+		"as":    asName,
+		"do":    rules,
+	}, nil
+}
+
+// varTypeAliases maps accepted type names in vars declarations to their
+// canonical names
+var varTypeAliases = map[string]string{
+	"string":  "string",
+	"str":     "string",
+	"number":  "number",
+	"int":     "number",
+	"integer": "number",
+	"float":   "number",
+	"boolean": "boolean",
+	"bool":    "boolean",
+	"object":  "object",
+	"dict":    "object",
+	"map":     "object",
+	"array":   "array",
+	"list":    "array",
+	"any":     "any",
+}
+
+// normalizeVarDeclarations returns the canonical form of a vars operation.
+// Each variable is declared by a type name or by an object with "type" and
+// an optional "required" flag.
+func normalizeVarDeclarations(spec interface{}) (map[string]interface{}, error) {
+	declarations, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an object of variable declarations")
+	}
+
+	normalized := make(map[string]interface{}, len(declarations))
+	for name, declaration := range declarations {
+		if name == "" {
+			return nil, fmt.Errorf("variable name cannot be empty")
+		}
+
+		var typeName interface{}
+		required := false
+		switch d := declaration.(type) {
+		case string:
+			typeName = d
+		case map[string]interface{}:
+			typeName = d["type"]
+			if value, exists := d["required"]; exists {
+				flag, ok := value.(bool)
+				if !ok {
+					return nil, fmt.Errorf("required flag of variable %s must be a boolean", name)
+				}
+				required = flag
+			}
+		default:
+			return nil, fmt.Errorf("variable %s must be declared with a type name or an object", name)
+		}
+
+		typeString, _ := typeName.(string)
+		varType, known := varTypeAliases[strings.ToLower(typeString)]
+		if !known {
+			return nil, fmt.Errorf("variable %s has unknown type %v", name, typeName)
+		}
+
+		normalized[name] = map[string]interface{}{
+			"type":     varType,
+			"required": required,
+		}
+	}
+	return normalized, nil
+}
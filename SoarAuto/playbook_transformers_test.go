@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// newTransformerTestEngine returns an engine whose automations directory holds
+// enrich.py and whose playbooks directory holds triage.json
+func newTransformerTestEngine(t *testing.T) *RuleEngine {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "enrich.py"), []byte("print('{}')\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "triage.json"), []byte("[]"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewRuleEngine(&Config{Python: PythonConfig{ScriptsPath: dir, PlaybooksPath: dir}})
+}
+
+// parsePlaybook decodes a playbook written as JSON
+func parsePlaybook(t *testing.T, source string) []interface{} {
+	t.Helper()
+	var playbook []interface{}
+	if err := json.Unmarshal([]byte(source), &playbook); err != nil {
+		t.Fatalf("parse playbook: %v", err)
+	}
+	return playbook
+}
+
+// dataLiteralPlaybook holds values that reuse operation names as data
+const dataLiteralPlaybook = `[
+	{"conditional_set": {"key": "meta", "value": {"play": "football", "run": "5k"}}},
+	{"map": {"sport": {"play": "football"}}, "as": "hobbies"},
+	{"conditional_set": {"key": "counts", "value": {"vars": 3}}},
+	{"conditional_set": {"key": "loop", "value": {"foreach": 7}}},
+	{"run": "enrich", "args": {"plugin": "not-a-plugin", "play": "football"}}
+]`
+
+func TestDependencyCheckerIgnoresDataLiterals(t *testing.T) {
+	checker := &DependencyChecker{engine: newTransformerTestEngine(t)}
+	if missing := checker.MissingDependencies(parsePlaybook(t, dataLiteralPlaybook)); len(missing) != 0 {
+		t.Errorf("data literals reported as missing dependencies: %+v", missing)
+	}
+}
+
+func TestDependencyCheckerFindsNestedRules(t *testing.T) {
+	checker := &DependencyChecker{engine: newTransformerTestEngine(t)}
+	playbook := parsePlaybook(t, `[
+		{"run": "enrich"},
+		{"play": "triage"},
+		{"if": {"conditions": [{"==": [1, 1]}], "true": [{"run": "if_true"}], "false": {"play": "if_false"}}},
+		{"if": [true, {"run": "array_then"}, [{"run": "array_else"}]]},
+		{"switch": {"on": "x", "cases": {"a": [{"run": "case_a"}]}, "default": {"run": "switch_default"}}},
+		{"foreach": {"items": [1], "as": "n", "do": [{"run": "foreach_object"}]}},
+		{"foreach": "iocs", "as": "ioc", "do": [{"play": "foreach_path"}]},
+		{"foreach": [[1], [{"run": "foreach_array"}]]},
+		{"batch": {"run": "batch_script", "over": [1, 2]}},
+		{"try": {"do": [{"run": "try_do"}], "catch": {"run": "try_catch"}, "finally": [{"play_async": "try_finally"}]}},
+		{"if": {"conditions": [], "true": [{"foreach": {"items": [1], "do": [{"try": {"do": [{"run": "deep"}], "catch": []}}]}}]}}
+	]`)
+
+	var values []string
+	for _, dependency := range checker.MissingDependencies(playbook) {
+		values = append(values, dependency.Field+":"+dependency.Value)
+	}
+	sort.Strings(values)
+	want := []string{
+		"batch.run:batch_script",
+		"play:foreach_path",
+		"play:if_false",
+		"play_async:try_finally",
+		"run:array_else",
+		"run:array_then",
+		"run:case_a",
+		"run:deep",
+		"run:foreach_array",
+		"run:foreach_object",
+		"run:if_true",
+		"run:switch_default",
+		"run:try_catch",
+		"run:try_do",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("missing dependencies =\n%v\nwant\n%v", values, want)
+	}
+}
+
+func TestSchemaValidatorIgnoresDataLiterals(t *testing.T) {
+	playbook := parsePlaybook(t, dataLiteralPlaybook)
+	transformed, err := (&SchemaValidator{}).Transform(playbook)
+	if err != nil {
+		t.Fatalf("data literal rejected as a vars declaration: %v", err)
+	}
+	if !reflect.DeepEqual(transformed, playbook) {
+		t.Errorf("data literals were rewritten:\n%v\nwant\n%v", transformed, playbook)
+	}
+
+	// A real declaration nested in a try block is still checked
+	nested := parsePlaybook(t, `[{"try": {"do": [{"vars": {"ip": "ip_address"}}], "catch": []}}]`)
+	if _, err := (&SchemaValidator{}).Transform(nested); err == nil {
+		t.Error("nested vars declaration with an unknown type was accepted")
+	}
+}
+
+func TestLoopNormalizerRewritesOnlyRules(t *testing.T) {
+	playbook := parsePlaybook(t, `[
+		{"conditional_set": {"key": "loop", "value": {"foreach": "not a loop", "do": "nothing"}}},
+		{"if": {"conditions": [], "true": [{"foreach": "iocs", "as": "ioc", "do": {"run": "enrich"}}]}}
+	]`)
+	original := parsePlaybook(t, `[
+		{"conditional_set": {"key": "loop", "value": {"foreach": "not a loop", "do": "nothing"}}},
+		{"if": {"conditions": [], "true": [{"foreach": "iocs", "as": "ioc", "do": {"run": "enrich"}}]}}
+	]`)
+
+	transformed, err := (&LoopNormalizer{}).Transform(playbook)
+	if err != nil {
+		t.Fatalf("normalize: %v", err)
+	}
+
+	want := parsePlaybook(t, `[
+		{"conditional_set": {"key": "loop", "value": {"foreach": "not a loop", "do": "nothing"}}},
+		{"if": {"conditions": [], "true": [{"foreach": {"items": {"var": "iocs"}, "as": "ioc", "do": [{"run": "enrich"}]}}]}}
+	]`)
+	if !reflect.DeepEqual(transformed, want) {
+		t.Errorf("normalized playbook =\n%v\nwant\n%v", transformed, want)
+	}
+	if !reflect.DeepEqual(playbook, original) {
+		t.Errorf("normalizing modified the original playbook: %v", playbook)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPluginCacheTTL applies to cacheable plugins that declare no cache_ttl
+	defaultPluginCacheTTL = 10 * time.Minute
+	// maxPluginCacheEntries bounds the cached results across all plugins
+	maxPluginCacheEntries = 10000
+)
+
+// PluginCacheStats reports the result cache activity of a cacheable plugin
+type PluginCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// pluginCacheEntry is a cached plugin result, stored as JSON so each hit
+// decodes a private copy the playbook may modify
+type pluginCacheEntry struct {
+	plugin  string
+	result  []byte
+	expires time.Time
+}
+
+// pluginResultCache caches the results of plugins that declare themselves
+// cacheable, i.e. pure functions of their parameters. It is shared by every
+// plugin manager in the process, so jobs reuse the results of earlier jobs.
+type pluginResultCache struct {
+	mutex   sync.Mutex
+	entries map[string]pluginCacheEntry
+	stats   map[string]*PluginCacheStats
+}
+
+// pluginResults is the process-wide plugin result cache
+var pluginResults = &pluginResultCache{
+	entries: make(map[string]pluginCacheEntry),
+	stats:   make(map[string]*PluginCacheStats),
+}
+
+// pluginCacheKey hashes a plugin's name, loaded version and parameters. JSON
+// encoding sorts object keys, so equal parameters give equal keys, and a
+// reloaded plugin version never reuses results of the previous one.
+func pluginCacheKey(name, version string, params map[string]interface{}) (string, error) {
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	hash.Write([]byte(name))
+	hash.Write([]byte{0})
+	hash.Write([]byte(version))
+	hash.Write([]byte{0})
+	hash.Write(encoded)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// pluginCacheTTL returns the result TTL a plugin declares
+func pluginCacheTTL(info PluginInfo) time.Duration {
+	if info.CacheTTL == "" {
+		return defaultPluginCacheTTL
+	}
+	ttl, err := time.ParseDuration(info.CacheTTL)
+	if err != nil || ttl <= 0 {
+		logger.Warning("Invalid plugin cache_ttl, using the default", map[string]interface{}{
+			"component": "plugin_manager",
+			"plugin":    info.Name,
+			"cache_ttl": info.CacheTTL,
+		})
+		return defaultPluginCacheTTL
+	}
+	return ttl
+}
+
+// execute returns the cached result of the plugin call, or runs it and
+// caches a successful result for ttl
+func (pc *pluginResultCache) execute(name, version string, ttl time.Duration, params map[string]interface{}, run func() (interface{}, error)) (interface{}, error) {
+	key, err := pluginCacheKey(name, version, params)
+	if err != nil {
+		// Parameters that cannot be hashed are never cached
+		return run()
+	}
+
+	if result, found := pc.get(name, key); found {
+		return result, nil
+	}
+
+	result, err := run()
+	if err != nil {
+		return nil, err
+	}
+	pc.set(name, key, result, ttl)
+	return result, nil
+}
+
+// get returns a private copy of the cached result under key, if any
+func (pc *pluginResultCache) get(name, key string) (interface{}, bool) {
+	pc.mutex.Lock()
+	entry, exists := pc.entries[key]
+	if exists && time.Now().After(entry.expires) {
+		delete(pc.entries, key)
+		exists = false
+	}
+	stats := pc.statsFor(name)
+	if exists {
+		stats.Hits++
+	} else {
+		stats.Misses++
+	}
+	pc.mutex.Unlock()
+
+	if !exists {
+		return nil, false
+	}
+	var result interface{}
+	if err := json.Unmarshal(entry.result, &result); err != nil {
+		return nil, false
+	}
+	return result, true
+}
+
+// set caches result under key for ttl. When the cache is full, expired
+// entries are dropped first; if it is still full the result is not cached.
+func (pc *pluginResultCache) set(name, key string, result interface{}, ttl time.Duration) {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	if len(pc.entries) >= maxPluginCacheEntries {
+		now := time.Now()
+		for k, entry := range pc.entries {
+			if now.After(entry.expires) {
+				delete(pc.entries, k)
+			}
+		}
+		if len(pc.entries) >= maxPluginCacheEntries {
+			return
+		}
+	}
+	pc.entries[key] = pluginCacheEntry{plugin: name, result: encoded, expires: time.Now().Add(ttl)}
+}
+
+// clear drops every cached result of a plugin and returns how many there were
+func (pc *pluginResultCache) clear(name string) int {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	cleared := 0
+	for key, entry := range pc.entries {
+		if entry.plugin == name {
+			delete(pc.entries, key)
+			cleared++
+		}
+	}
+	return cleared
+}
+
+// Stats returns the cache activity of a plugin
+func (pc *pluginResultCache) Stats(name string) PluginCacheStats {
+	pc.mutex.Lock()
+	defer pc.mutex.Unlock()
+
+	stats := *pc.statsFor(name)
+	now := time.Now()
+	for _, entry := range pc.entries {
+		if entry.plugin == name && !now.After(entry.expires) {
+			stats.Entries++
+		}
+	}
+	return stats
+}
+
+// statsFor returns the counters of a plugin; the caller holds the mutex
+func (pc *pluginResultCache) statsFor(name string) *PluginCacheStats {
+	stats, exists := pc.stats[name]
+	if !exists {
+		stats = &PluginCacheStats{}
+		pc.stats[name] = stats
+	}
+	return stats
+}
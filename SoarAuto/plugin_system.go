@@ -51,6 +51,12 @@ type PluginInfo struct {
 	LastReload  time.Time    `json:"last_reload,omitempty"`
 	Config      interface{}  `json:"config,omitempty"`
 
+	// Cacheable plugins are pure functions of their parameters, so their
+	// results are cached for CacheTTL (a duration such as "1h")
+	Cacheable bool              `json:"cacheable,omitempty"`
+	CacheTTL  string            `json:"cache_ttl,omitempty"`
+	Cache     *PluginCacheStats `json:"cache,omitempty"` // Set in listings of cacheable plugins
+
 	// Platform-specific metadata
 	PlatformInfo PlatformInfo `json:"platform_info,omitempty"`
 }
@@ -113,8 +119,17 @@ type PluginManager struct {
 	stopChan    chan struct{}
 	config      map[string]interface{}
 	logger      *StructuredLogger
+
+	// Loaded plugin versions by plugin name, and plugin names by file path
+	pluginVersions map[string]string
+	pluginNames    map[string]string
+	reloadHandler  PluginReloadHandler
 }
 
+// PluginReloadHandler is notified when hot-reloading changes a plugin's version.
+// previousVersion is empty when the plugin was not loaded before.
+type PluginReloadHandler func(pluginName, previousVersion, version string)
+
 // NewPluginManager creates a new plugin manager
 func NewPluginManager(pluginsDir string, config map[string]interface{}) (*PluginManager, error) {
 	pm := &PluginManager{
@@ -125,6 +140,9 @@ func NewPluginManager(pluginsDir string, config map[string]interface{}) (*Plugin
 		stopChan:    make(chan struct{}),
 		config:      config,
 		logger:      logger,
+
+		pluginVersions: make(map[string]string),
+		pluginNames:    make(map[string]string),
 	}
 
 	// Create plugins directory if it doesn't exist
@@ -173,7 +191,7 @@ func (pm *PluginManager) loadAllPlugins() error {
 		}
 
 		// Check if it's a plugin file
-		if pm.isPluginFile(path) {
+		if isPluginFile(path) {
 			if err := pm.loadPlugin(path); err != nil {
 				pm.logger.Error("Failed to load plugin", map[string]interface{}{
 					"component":   "plugin_manager",
@@ -188,14 +206,14 @@ func (pm *PluginManager) loadAllPlugins() error {
 }
 
 // isPluginFile checks if a file is a plugin file
-func (pm *PluginManager) isPluginFile(path string) bool {
+func isPluginFile(path string) bool {
 	ext := strings.ToLower(filepath.Ext(path))
 	// On Windows, only support Python plugins and Go executables
 	// Go source files (.go) and plugins (.so) are not supported on Windows
 	if runtime.GOOS == "windows" {
-		return ext == ".py" || ext == ".exe"
+		return ext == ".py" || ext == ".exe" || ext == ".wasm"
 	}
-	return ext == ".py" || ext == ".exe" || ext == ".go" || ext == ".so"
+	return ext == ".py" || ext == ".exe" || ext == ".go" || ext == ".so" || ext == ".wasm"
 }
 
 // loadPlugin loads a single plugin
@@ -225,6 +243,8 @@ func (pm *PluginManager) loadPlugin(pluginPath string) error {
 		}
 		// For Go source files, we'll compile them first
 		pluginInstance, err = pm.loadGoSourcePlugin(pluginPath)
+	case ".wasm":
+		pluginInstance, err = pm.loadWasmPlugin(pluginPath)
 	default:
 		return fmt.Errorf("unsupported plugin type: %s", ext)
 	}
@@ -261,6 +281,8 @@ func (pm *PluginManager) loadPlugin(pluginPath string) error {
 
 	// Store the plugin by its actual name, not the filename
 	pm.plugins[actualPluginName] = pluginInstance
+	pm.pluginVersions[actualPluginName] = pluginVersion(info.Version, pluginPath)
+	pm.pluginNames[pluginPath] = actualPluginName
 	pm.logger.Info("Plugin loaded successfully", map[string]interface{}{
 		"component":   "plugin_manager",
 		"plugin_name": actualPluginName,
@@ -293,13 +315,33 @@ func (pm *PluginManager) loadGoPlugin(pluginPath string) (interface{}, error) {
 }
 
 // loadGoSourcePlugin compiles and loads a Go source plugin
-func (pm *PluginManager) loadGoSourcePlugin(pluginPath string) (interface{}, error) {
+func (pm *PluginManager) loadGoSourcePlugin(pluginPath string) (pluginInstance interface{}, err error) {
 	// Create temporary directory for compilation
-	tempDir, err := os.MkdirTemp("", "secauto_plugin_*")
+	buildDir, _ := pm.config["build_temp_dir"].(string)
+	if buildDir != "" {
+		if err := os.MkdirAll(buildDir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create build temp directory: %v", err)
+		}
+	}
+	tempDir, err := os.MkdirTemp(buildDir, pluginBuildDirPrefix+"*")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create temp directory: %v", err)
 	}
-	defer os.RemoveAll(tempDir)
+
+	// Always remove the build directory, turning a panic during compilation into an error
+	defer func() {
+		if r := recover(); r != nil {
+			pluginInstance = nil
+			err = fmt.Errorf("plugin compilation panicked: %v", r)
+		}
+		if removeErr := os.RemoveAll(tempDir); removeErr != nil {
+			pm.logger.Error("Failed to remove plugin build directory", map[string]interface{}{
+				"component": "plugin_manager",
+				"path":      tempDir,
+				"error":     removeErr.Error(),
+			})
+		}
+	}()
 
 	// Copy plugin source to temp directory
 	pluginName := strings.TrimSuffix(filepath.Base(pluginPath), ".go")
@@ -340,6 +382,82 @@ go 1.23.1
 	return pm.loadGoPlugin(outputPath)
 }
 
+// pluginBuildDirPrefix prefixes the temporary directories used to compile Go source plugins
+const pluginBuildDirPrefix = "secauto_plugin_"
+
+// SweepPluginBuildDirs removes orphaned Go plugin build directories older than
+// maxAge from baseDir (the system temp dir when empty) and logs the space used.
+func SweepPluginBuildDirs(baseDir string, maxAge time.Duration) {
+	if baseDir == "" {
+		baseDir = os.TempDir()
+	}
+
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Failed to read plugin build directory", map[string]interface{}{
+				"component": "plugin_manager",
+				"path":      baseDir,
+				"error":     err.Error(),
+			})
+		}
+		return
+	}
+
+	removed := 0
+	var freedBytes, remainingBytes int64
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), pluginBuildDirPrefix) {
+			continue
+		}
+
+		path := filepath.Join(baseDir, entry.Name())
+		size := directorySize(path)
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < maxAge {
+			// Possibly in use by a compilation in progress
+			remainingBytes += size
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			logger.Error("Failed to remove orphaned plugin build directory", map[string]interface{}{
+				"component": "plugin_manager",
+				"path":      path,
+				"error":     err.Error(),
+			})
+			remainingBytes += size
+			continue
+		}
+		removed++
+		freedBytes += size
+	}
+
+	logger.Info("Swept plugin build directories", map[string]interface{}{
+		"component":       "plugin_manager",
+		"path":            baseDir,
+		"removed":         removed,
+		"freed_bytes":     freedBytes,
+		"remaining_bytes": remainingBytes,
+	})
+}
+
+// directorySize returns the total size of the files under path
+func directorySize(path string) int64 {
+	var size int64
+	filepath.WalkDir(path, func(_ string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && !d.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
 // wrapGoSource wraps Go source code to make it a proper plugin
 func (pm *PluginManager) wrapGoSource(source, pluginName string) string {
 	// Simple wrapper - in a real implementation, you'd want more sophisticated parsing
@@ -434,7 +552,15 @@ func (gew *GoExecutablePluginWrapper) Execute(params map[string]interface{}) (in
 		return nil, fmt.Errorf("failed to marshal params: %v", err)
 	}
 
+	workDir, cleanup, err := newExecutionWorkDir(gew.manager.workDir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %v", err)
+	}
+	defer cleanup()
+
 	cmd := exec.Command(gew.execPath, "execute", string(paramsJSON))
+	cmd.Dir = workDir
+	cmd.Env = append(os.Environ(), workDirEnvVar+"="+workDir)
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute plugin: %v", err)
@@ -512,7 +638,13 @@ func (pw *PythonPluginWrapper) Execute(params map[string]interface{}) (interface
 		return nil, fmt.Errorf("failed to marshal params: %v", err)
 	}
 
-	output, err := RunPythonFromVenvStdoutOnly(venvPath, pw.scriptPath, "execute", string(paramsJSON))
+	workDir, cleanup, err := newExecutionWorkDir(pw.manager.workDir())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory: %v", err)
+	}
+	defer cleanup()
+
+	output, err := RunPythonFromVenvWithJSONAndEnv(venvPath, pw.scriptPath, nil, withWorkDir(nil, workDir), "execute", string(paramsJSON))
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute plugin: %v", err)
 	}
@@ -537,6 +669,13 @@ func (pw *PythonPluginWrapper) Cleanup() error {
 	return err
 }
 
+// workDir returns the base directory of the working directories plugin runs
+// get, or "" for the system temp dir
+func (pm *PluginManager) workDir() string {
+	workDir, _ := pm.config["work_dir"].(string)
+	return workDir
+}
+
 // initializePlugin initializes a loaded plugin
 func (pm *PluginManager) initializePlugin(pluginInstance interface{}, pluginName string) error {
 	plugin, ok := pluginInstance.(PluginInterface)
@@ -625,7 +764,7 @@ func (pm *PluginManager) hotReloadWorker() {
 // handleFileEvent handles file system events
 func (pm *PluginManager) handleFileEvent(event fsnotify.Event) {
 	if event.Op&fsnotify.Write == fsnotify.Write || event.Op&fsnotify.Create == fsnotify.Create {
-		if pm.isPluginFile(event.Name) {
+		if isPluginFile(event.Name) {
 			// Debounce reload events
 			select {
 			case pm.reloadChan <- event.Name:
@@ -646,6 +785,11 @@ func (pm *PluginManager) reloadPlugin(pluginPath string) {
 		"plugin_path": pluginPath,
 	})
 
+	// Remember the version being replaced
+	pm.mutex.RLock()
+	previousVersion := pm.pluginVersions[pm.pluginNames[pluginPath]]
+	pm.mutex.RUnlock()
+
 	// Update status to reloading
 	pm.updatePluginInfo(pluginName, PluginInfo{
 		Name:       pluginName,
@@ -671,7 +815,60 @@ func (pm *PluginManager) reloadPlugin(pluginPath string) {
 			"component":   "plugin_manager",
 			"plugin_name": pluginName,
 		})
+
+		pm.mutex.RLock()
+		actualPluginName := pm.pluginNames[pluginPath]
+		version := pm.pluginVersions[actualPluginName]
+		handler := pm.reloadHandler
+		pm.mutex.RUnlock()
+
+		// Repeated file events for the same content do not change the version
+		if handler != nil && version != previousVersion {
+			handler(actualPluginName, previousVersion, version)
+		}
+	}
+}
+
+// SetReloadHandler registers a handler for plugin version changes
+func (pm *PluginManager) SetReloadHandler(handler PluginReloadHandler) {
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.reloadHandler = handler
+}
+
+// GetPluginVersion returns the version of a loaded plugin
+func (pm *PluginManager) GetPluginVersion(pluginName string) (string, bool) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	version, exists := pm.pluginVersions[pluginName]
+	return version, exists
+}
+
+// GetPluginPath returns the file a loaded plugin was loaded from
+func (pm *PluginManager) GetPluginPath(pluginName string) (string, bool) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	for path, name := range pm.pluginNames {
+		if name == pluginName {
+			return path, true
+		}
 	}
+	return "", false
+}
+
+// pluginVersion identifies a loaded plugin build by its declared version and a
+// short hash of the plugin file, so re-uploads with the same version are distinguishable
+func pluginVersion(declaredVersion, pluginPath string) string {
+	if declaredVersion == "" {
+		declaredVersion = "unknown"
+	}
+	content, err := os.ReadFile(pluginPath)
+	if err != nil {
+		return declaredVersion
+	}
+	return declaredVersion + "+" + contentHash(content)[:12]
 }
 
 // unloadPlugin unloads a specific plugin
@@ -772,7 +969,50 @@ func (pm *PluginManager) ExecutePlugin(name string, params map[string]interface{
 		return nil, fmt.Errorf("plugin does not implement PluginInterface")
 	}
 
-	return pluginInterface.Execute(params)
+	info, _ := pm.getPluginInfoByName(name)
+	if !info.Cacheable {
+		return pluginInterface.Execute(params)
+	}
+
+	version, _ := pm.GetPluginVersion(info.Name)
+	return pluginResults.execute(info.Name, version, pluginCacheTTL(info), params, func() (interface{}, error) {
+		return pluginInterface.Execute(params)
+	})
+}
+
+// getPluginInfoByName returns the info of a plugin by filename or actual name
+func (pm *PluginManager) getPluginInfoByName(pluginName string) (PluginInfo, bool) {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+
+	if info, exists := pm.pluginInfos[pluginName]; exists {
+		return info, true
+	}
+	for _, info := range pm.pluginInfos {
+		if info.Name == pluginName {
+			return info, true
+		}
+	}
+	return PluginInfo{}, false
+}
+
+// PendingReloads returns how many plugin file changes wait to be reloaded
+func (pm *PluginManager) PendingReloads() int {
+	return len(pm.reloadChan)
+}
+
+// FlushReloads drops the plugin file changes waiting to be reloaded and
+// returns how many were dropped
+func (pm *PluginManager) FlushReloads() int {
+	flushed := 0
+	for {
+		select {
+		case <-pm.reloadChan:
+			flushed++
+		default:
+			return flushed
+		}
+	}
 }
 
 // Close closes the plugin manager
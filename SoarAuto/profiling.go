@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	runtimepprof "runtime/pprof"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultCPUProfileDuration is used when no duration is requested
+	defaultCPUProfileDuration = 30 * time.Second
+	// maxCPUProfileDuration bounds on-demand CPU profiles
+	maxCPUProfileDuration = 5 * time.Minute
+	// pprofPathPrefix is where net/http/pprof serves its handlers
+	pprofPathPrefix = "/debug/pprof"
+)
+
+// profilingGate guards the /debug/pprof handlers that net/http/pprof
+// registers on the default mux as a side effect of being imported. They are
+// hidden unless profiling is enabled, and are otherwise served by handler.
+func profilingGate(enabled bool, handler http.HandlerFunc, mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, pprofPathPrefix) {
+			if !enabled {
+				http.NotFound(w, r)
+				return
+			}
+			handler(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// pprofHandler serves the net/http/pprof endpoints to admin API keys
+func pprofHandler(w http.ResponseWriter, r *http.Request) {
+	if !isAdminRequest(r) {
+		logAdminAudit(r, "pprof", false, nil)
+		http.Error(w, "Forbidden: admin API key required", http.StatusForbidden)
+		return
+	}
+	logAdminAudit(r, "pprof", true, nil)
+
+	switch strings.TrimPrefix(r.URL.Path, pprofPathPrefix+"/") {
+	case "cmdline":
+		pprof.Cmdline(w, r)
+	case "profile":
+		pprof.Profile(w, r)
+	case "symbol":
+		pprof.Symbol(w, r)
+	case "trace":
+		pprof.Trace(w, r)
+	default:
+		// The index also serves named profiles such as heap and goroutine
+		if !strings.HasPrefix(r.URL.Path, pprofPathPrefix+"/") {
+			http.Redirect(w, r, pprofPathPrefix+"/", http.StatusMovedPermanently)
+			return
+		}
+		pprof.Index(w, r)
+	}
+}
+
+// cpuProfileHandler handles POST /admin/profile/start?duration=30s, which
+// captures a CPU profile for the given duration and returns it for download
+func (s *SecAutoServer) cpuProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isAdminRequest(r) {
+		logAdminAudit(r, "cpu_profile", false, nil)
+		http.Error(w, "Forbidden: admin API key required", http.StatusForbidden)
+		return
+	}
+
+	duration := defaultCPUProfileDuration
+	if value := r.URL.Query().Get("duration"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 || parsed > maxCPUProfileDuration {
+			http.Error(w, fmt.Sprintf("duration must be a positive duration up to %s", maxCPUProfileDuration), http.StatusBadRequest)
+			return
+		}
+		duration = parsed
+	}
+	logAdminAudit(r, "cpu_profile", true, map[string]interface{}{
+		"duration": duration.String(),
+	})
+
+	var profile bytes.Buffer
+	if err := runtimepprof.StartCPUProfile(&profile); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to start CPU profile: %v", err), http.StatusConflict)
+		return
+	}
+
+	// The capture can outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	select {
+	case <-time.After(duration):
+	case <-r.Context().Done():
+	}
+	runtimepprof.StopCPUProfile()
+
+	if r.Context().Err() != nil {
+		logger.Warning("CPU profile abandoned, client disconnected", map[string]interface{}{
+			"component": "profiling",
+		})
+		return
+	}
+
+	writeProfile(w, "cpu", profile.Bytes())
+}
+
+// heapProfileHandler handles GET /admin/profile/heap, which captures a heap
+// profile on demand
+func (s *SecAutoServer) heapProfileHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isAdminRequest(r) {
+		logAdminAudit(r, "heap_profile", false, nil)
+		http.Error(w, "Forbidden: admin API key required", http.StatusForbidden)
+		return
+	}
+	logAdminAudit(r, "heap_profile", true, nil)
+
+	// Collect garbage first so the profile reflects live objects
+	runtime.GC()
+
+	var profile bytes.Buffer
+	if err := runtimepprof.Lookup("heap").WriteTo(&profile, 0); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to capture heap profile: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	writeProfile(w, "heap", profile.Bytes())
+}
+
+// writeProfile sends a pprof profile as a file download
+func writeProfile(w http.ResponseWriter, kind string, profile []byte) {
+	filename := fmt.Sprintf("secauto-%s-%s.pprof", kind, time.Now().UTC().Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Write(profile)
+
+	logger.Info("Profile captured", map[string]interface{}{
+		"component": "profiling",
+		"profile":   kind,
+		"size":      len(profile),
+	})
+}
+
+// logAdminAudit records an attempt to use an admin endpoint, identifying the
+// requesting API key by its last characters only
+func logAdminAudit(r *http.Request, action string, allowed bool, details map[string]interface{}) {
+	fields := map[string]interface{}{
+		"component":   "audit",
+		"action":      action,
+		"allowed":     allowed,
+		"api_key":     maskSecret(getRequestAPIKey(r)),
+		"remote_addr": r.RemoteAddr,
+		"path":        r.URL.Path,
+	}
+	for key, value := range details {
+		fields[key] = value
+	}
+
+	if allowed {
+		logger.Info("Admin endpoint accessed", fields)
+	} else {
+		logger.Warning("Admin endpoint access denied", fields)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// engineVersion is the SecAuto release reported by /health and recorded in
+// job provenance
+const engineVersion = "1.0.0"
+
+// ExecutionProvenance records exactly what code a job ran: the engine build
+// and the content hash of the playbook and of every nested playbook,
+// automation and plugin it invoked. Hashes are "sha256:<hex>"; comparing
+// the provenance of two runs of the same playbook shows which code changed.
+type ExecutionProvenance struct {
+	EngineVersion string            `json:"engine_version"`
+	GoVersion     string            `json:"go_version"`
+	BuildRevision string            `json:"build_revision,omitempty"` // VCS commit the binary was built from
+	BuildModified bool              `json:"build_modified,omitempty"` // Built from a tree with uncommitted changes
+	PlaybookHash  string            `json:"playbook_hash"`            // Hash of the submitted playbook
+	Playbooks     map[string]string `json:"playbooks,omitempty"`      // Nested playbooks played, by name
+	Automations   map[string]string `json:"automations,omitempty"`    // Automations run, by name
+	Plugins       map[string]string `json:"plugins,omitempty"`        // Plugin files executed, by plugin name
+
+	mu sync.Mutex
+}
+
+// NewExecutionProvenance starts the provenance of a run of playbook
+func NewExecutionProvenance(playbook []interface{}) *ExecutionProvenance {
+	provenance := &ExecutionProvenance{
+		EngineVersion: engineVersion,
+		GoVersion:     runtime.Version(),
+		Playbooks:     make(map[string]string),
+		Automations:   make(map[string]string),
+		Plugins:       make(map[string]string),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				provenance.BuildRevision = setting.Value
+			case "vcs.modified":
+				provenance.BuildModified = setting.Value == "true"
+			}
+		}
+	}
+	// JSON encoding sorts object keys, so equal playbooks hash equally
+	if encoded, err := json.Marshal(playbook); err == nil {
+		provenance.PlaybookHash = "sha256:" + contentHash(encoded)
+	}
+	return provenance
+}
+
+// record stores the hash of the file at path under name in one of the maps;
+// files that cannot be read are not recorded
+func (ep *ExecutionProvenance) record(hashes map[string]string, name, path string) {
+	hash, err := fileContentHash(path)
+	if err != nil {
+		logger.Warning("Failed to hash file for provenance", map[string]interface{}{
+			"component": "rules_engine",
+			"name":      name,
+			"path":      path,
+			"error":     err.Error(),
+		})
+		return
+	}
+
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+	hashes[name] = hash
+}
+
+// MarshalJSON encodes the provenance under its lock, as runs abandoned by a
+// batch timeout may still be recording
+func (ep *ExecutionProvenance) MarshalJSON() ([]byte, error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	type fields struct {
+		EngineVersion string            `json:"engine_version"`
+		GoVersion     string            `json:"go_version"`
+		BuildRevision string            `json:"build_revision,omitempty"`
+		BuildModified bool              `json:"build_modified,omitempty"`
+		PlaybookHash  string            `json:"playbook_hash"`
+		Playbooks     map[string]string `json:"playbooks,omitempty"`
+		Automations   map[string]string `json:"automations,omitempty"`
+		Plugins       map[string]string `json:"plugins,omitempty"`
+	}
+	return json.Marshal(fields{
+		EngineVersion: ep.EngineVersion,
+		GoVersion:     ep.GoVersion,
+		BuildRevision: ep.BuildRevision,
+		BuildModified: ep.BuildModified,
+		PlaybookHash:  ep.PlaybookHash,
+		Playbooks:     ep.Playbooks,
+		Automations:   ep.Automations,
+		Plugins:       ep.Plugins,
+	})
+}
+
+// WithProvenance returns a copy of the engine that records the code it runs
+// in provenance
+func (re *RuleEngine) WithProvenance(provenance *ExecutionProvenance) *RuleEngine {
+	engine := *re
+	engine.provenance = provenance
+	return &engine
+}
+
+// recordAutomation records the automation about to run
+func (re *RuleEngine) recordAutomation(name, path string) {
+	if re.provenance != nil {
+		re.provenance.record(re.provenance.Automations, name, path)
+	}
+}
+
+// recordPlaybook records the nested playbook about to be played
+func (re *RuleEngine) recordPlaybook(name, path string) {
+	if re.provenance != nil {
+		re.provenance.record(re.provenance.Playbooks, name, path)
+	}
+}
+
+// recordPlugin records the file of the plugin about to execute
+func (re *RuleEngine) recordPlugin(name string) {
+	if re.provenance == nil || re.pluginManager == nil {
+		return
+	}
+	if path, exists := re.pluginManager.GetPluginPath(name); exists {
+		re.provenance.record(re.provenance.Plugins, name, path)
+	}
+}
+
+// fileHashes caches file hashes by path, size and modification time, so a
+// file used by many runs is read once per change
+var fileHashes = struct {
+	sync.Mutex
+	entries map[string]fileHashEntry
+}{entries: make(map[string]fileHashEntry)}
+
+type fileHashEntry struct {
+	size    int64
+	modTime time.Time
+	hash    string
+}
+
+// fileContentHash returns the provenance hash of the file at path
+func fileContentHash(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	fileHashes.Lock()
+	entry, exists := fileHashes.entries[path]
+	fileHashes.Unlock()
+	if exists && entry.size == info.Size() && entry.modTime.Equal(info.ModTime()) {
+		return entry.hash, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", err
+	}
+	hash := "sha256:" + hex.EncodeToString(hasher.Sum(nil))
+
+	fileHashes.Lock()
+	fileHashes.entries[path] = fileHashEntry{size: info.Size(), modTime: info.ModTime(), hash: hash}
+	fileHashes.Unlock()
+	return hash, nil
+}
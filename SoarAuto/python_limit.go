@@ -0,0 +1,91 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// PythonProcessLimiter caps the automations running at once across the
+// whole process, so playbooks fanning out through foreach, batch or nested
+// play cannot start more interpreters than the host can take. A nil limiter
+// imposes no limit.
+type PythonProcessLimiter struct {
+	slots chan struct{}
+
+	acquired     atomic.Int64
+	waits        atomic.Int64 // Acquisitions that found every slot taken
+	blockedNanos atomic.Int64 // Total time spent waiting for a slot
+	maxBlocked   atomic.Int64 // Longest single wait, in nanoseconds
+}
+
+// pythonProcessLimit holds the process-wide limiter, sized from the
+// configuration the first time an automation runs
+var pythonProcessLimit struct {
+	once    sync.Once
+	limiter *PythonProcessLimiter
+}
+
+// getPythonProcessLimiter returns the process-wide limiter, or nil when
+// maxProcesses leaves Python processes unlimited
+func getPythonProcessLimiter(maxProcesses int) *PythonProcessLimiter {
+	pythonProcessLimit.once.Do(func() {
+		if maxProcesses > 0 {
+			pythonProcessLimit.limiter = &PythonProcessLimiter{slots: make(chan struct{}, maxProcesses)}
+			logger.Info("Limiting concurrent Python processes", map[string]interface{}{
+				"component":     "rules_engine",
+				"max_processes": maxProcesses,
+			})
+		}
+	})
+	return pythonProcessLimit.limiter
+}
+
+// Acquire takes a slot, blocking until one is free, and returns how long it
+// waited
+func (pl *PythonProcessLimiter) Acquire() time.Duration {
+	if pl == nil {
+		return 0
+	}
+	pl.acquired.Add(1)
+
+	select {
+	case pl.slots <- struct{}{}:
+		return 0
+	default:
+	}
+
+	start := time.Now()
+	pl.slots <- struct{}{}
+	waited := time.Since(start)
+
+	pl.waits.Add(1)
+	pl.blockedNanos.Add(int64(waited))
+	for {
+		longest := pl.maxBlocked.Load()
+		if int64(waited) <= longest || pl.maxBlocked.CompareAndSwap(longest, int64(waited)) {
+			break
+		}
+	}
+	return waited
+}
+
+// Release frees a slot taken by Acquire
+func (pl *PythonProcessLimiter) Release() {
+	if pl == nil {
+		return
+	}
+	<-pl.slots
+}
+
+// Stats returns the limiter's usage and the time runs spent blocked on it
+func (pl *PythonProcessLimiter) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"max_processes":   cap(pl.slots),
+		"running":         len(pl.slots),
+		"acquired_total":  pl.acquired.Load(),
+		"waits_total":     pl.waits.Load(),
+		"blocked_seconds": time.Duration(pl.blockedNanos.Load()).Seconds(),
+		"max_blocked_ms":  time.Duration(pl.maxBlocked.Load()).Milliseconds(),
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultPythonPoolSize is how many warm interpreters a pool keeps by default
+	defaultPythonPoolSize = 4
+	// defaultPythonPoolMaxRuns is how many runs an interpreter serves before it
+	// is replaced, bounding leaks from scripts and their imports
+	defaultPythonPoolMaxRuns = 100
+)
+
+// pythonPoolWorkerCode is the program a pooled interpreter runs. It reads
+// one JSON request per line, runs the requested script as __main__ with the
+// input on stdin, the given environment and the working directory named by
+// SECAUTO_WORK_DIR in it, and answers with one JSON line holding the exit
+// code and the script's output. Modules imported by a
+// script stay loaded, which is where the time is saved. The protocol is
+// written to a private copy of stdout; file descriptor 1 is pointed at
+// stderr so scripts writing to it directly cannot corrupt the protocol.
+const pythonPoolWorkerCode = `
+import io, json, os, runpy, sys, traceback
+proto = os.fdopen(os.dup(1), "w")
+os.dup2(2, 1)
+requests = sys.stdin
+base_env = dict(os.environ)
+base_cwd = os.getcwd()
+base_path = list(sys.path)
+while True:
+    line = requests.readline()
+    if not line:
+        break
+    req = json.loads(line)
+    os.environ.clear()
+    os.environ.update(base_env)
+    os.environ.update(req.get("env") or {})
+    os.chdir(os.environ.get("SECAUTO_WORK_DIR") or base_cwd)
+    out, err = io.StringIO(), io.StringIO()
+    sys.stdin = io.StringIO(req.get("input") or "")
+    sys.stdout, sys.stderr = out, err
+    sys.argv = [req["script"]]
+    sys.path[:] = [os.path.dirname(req["script"])] + base_path
+    code = 0
+    try:
+        runpy.run_path(req["script"], run_name="__main__")
+    except SystemExit as e:
+        if e.code is None:
+            code = 0
+        elif isinstance(e.code, int):
+            code = e.code
+        else:
+            print(e.code, file=err)
+            code = 1
+    except BaseException:
+        traceback.print_exc(file=err)
+        code = 1
+    finally:
+        sys.stdout.flush()
+        sys.stdin, sys.stdout, sys.stderr = requests, sys.__stdout__, sys.__stderr__
+    proto.write(json.dumps({"exit_code": code, "stdout": out.getvalue(), "stderr": err.getvalue()}) + "\n")
+    proto.flush()
+`
+
+// errPythonPoolUnavailable is returned when no interpreter could be started;
+// callers fall back to spawning the script
+var errPythonPoolUnavailable = errors.New("python pool unavailable")
+
+// pythonPoolRequest is one script run sent to a pooled interpreter
+type pythonPoolRequest struct {
+	Script string            `json:"script"`
+	Input  string            `json:"input"`
+	Env    map[string]string `json:"env,omitempty"`
+}
+
+// pythonPoolResponse is a pooled interpreter's answer to a request
+type pythonPoolResponse struct {
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+}
+
+// pythonWorker is a long-lived interpreter running pythonPoolWorkerCode
+type pythonWorker struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+	runs   int
+}
+
+// stop closes the worker's input, which ends its loop, and reaps it
+func (w *pythonWorker) stop() {
+	w.stdin.Close()
+	done := make(chan struct{})
+	go func() {
+		w.cmd.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		w.cmd.Process.Kill()
+		<-done
+	}
+}
+
+// PythonPool runs automations in warm interpreters instead of starting a
+// new Python process per run. At most size interpreters exist at a time;
+// runs beyond that wait for one to become idle.
+type PythonPool struct {
+	venvPath string
+	maxRuns  int
+	slots    chan struct{}
+	idle     chan *pythonWorker
+
+	mutex  sync.Mutex
+	closed bool
+}
+
+// pythonPools holds the process-wide pool of each venv
+var pythonPools = struct {
+	sync.Mutex
+	pools map[string]*PythonPool
+}{pools: make(map[string]*PythonPool)}
+
+// getPythonPool returns the pool for a venv, creating it on first use
+func getPythonPool(venvPath string, poolConfig PythonPoolConfig) *PythonPool {
+	pythonPools.Lock()
+	defer pythonPools.Unlock()
+
+	if pool, exists := pythonPools.pools[venvPath]; exists {
+		return pool
+	}
+	size := poolConfig.Size
+	if size <= 0 {
+		size = defaultPythonPoolSize
+	}
+	maxRuns := poolConfig.MaxRunsPerWorker
+	if maxRuns <= 0 {
+		maxRuns = defaultPythonPoolMaxRuns
+	}
+	pool := &PythonPool{
+		venvPath: venvPath,
+		maxRuns:  maxRuns,
+		slots:    make(chan struct{}, size),
+		idle:     make(chan *pythonWorker, size),
+	}
+	pythonPools.pools[venvPath] = pool
+
+	logger.Info("Created Python worker pool", map[string]interface{}{
+		"component": "python_pool",
+		"venv":      venvPath,
+		"size":      size,
+		"max_runs":  maxRuns,
+	})
+	return pool
+}
+
+// closePythonPools stops every pooled interpreter
+func closePythonPools() {
+	pythonPools.Lock()
+	defer pythonPools.Unlock()
+
+	for venvPath, pool := range pythonPools.pools {
+		pool.Close()
+		delete(pythonPools.pools, venvPath)
+	}
+}
+
+// pythonExecutable returns the interpreter of a venv
+func pythonExecutable(venvPath string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(venvPath, "Scripts", "python.exe")
+	}
+	return filepath.Join(venvPath, "bin", "python")
+}
+
+// startWorker starts a new interpreter
+func (p *PythonPool) startWorker() (*pythonWorker, error) {
+	cmd := exec.Command(pythonExecutable(p.venvPath), "-u", "-c", pythonPoolWorkerCode)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %v", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start python worker: %v", err)
+	}
+
+	// Output written outside a run, e.g. by background threads of a script
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			logger.Debug("Python worker stderr output", map[string]interface{}{
+				"component": "python_pool",
+				"pid":       cmd.Process.Pid,
+				"stderr":    scanner.Text(),
+			})
+		}
+	}()
+
+	return &pythonWorker{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+// acquire returns an idle interpreter, or starts one while the pool has
+// room, waiting for one to become free otherwise
+func (p *PythonPool) acquire() (*pythonWorker, error) {
+	p.slots <- struct{}{}
+
+	select {
+	case worker := <-p.idle:
+		return worker, nil
+	default:
+	}
+
+	worker, err := p.startWorker()
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return worker, nil
+}
+
+// release returns a healthy interpreter to the pool, or stops it when it
+// failed, has served its runs or the pool is closed
+func (p *PythonPool) release(worker *pythonWorker, healthy bool) {
+	defer func() { <-p.slots }()
+
+	p.mutex.Lock()
+	closed := p.closed
+	p.mutex.Unlock()
+
+	if healthy && !closed && worker.runs < p.maxRuns {
+		p.idle <- worker
+		return
+	}
+	go worker.stop()
+}
+
+// Run runs a script in a pooled interpreter with jsonInput on its stdin and
+// env merged over the base environment, returning its stdout like
+// RunPythonFromVenvWithJSONAndEnv. It returns errPythonPoolUnavailable when
+// no interpreter could be started.
+func (p *PythonPool) Run(scriptPath string, jsonInput interface{}, env map[string]string) ([]byte, error) {
+	request := pythonPoolRequest{Env: env}
+	absPath, err := filepath.Abs(scriptPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve script path: %v", err)
+	}
+	request.Script = absPath
+	if jsonInput != nil {
+		jsonBytes, err := json.Marshal(jsonInput)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal JSON input: %v", err)
+		}
+		request.Input = string(jsonBytes)
+	}
+	line, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal pool request: %v", err)
+	}
+
+	worker, err := p.acquire()
+	if err != nil {
+		logger.Warning("Failed to start pooled Python worker", map[string]interface{}{
+			"component": "python_pool",
+			"error":     err.Error(),
+		})
+		return nil, errPythonPoolUnavailable
+	}
+
+	worker.runs++
+	response, err := worker.request(append(line, '\n'))
+	if err != nil {
+		// The interpreter died or broke the protocol; it is not reused
+		p.release(worker, false)
+		return nil, fmt.Errorf("python worker failed: %v", err)
+	}
+	p.release(worker, true)
+
+	if len(response.Stderr) > 0 {
+		logger.Debug("Python script stderr output", map[string]interface{}{
+			"component": "python_pool",
+			"script":    scriptPath,
+			"stderr":    response.Stderr,
+		})
+	}
+	if response.ExitCode != 0 {
+		return nil, fmt.Errorf("python execution failed: exit status %d, stderr: %s", response.ExitCode, response.Stderr)
+	}
+	return []byte(response.Stdout), nil
+}
+
+// request sends one request line and reads the answer
+func (w *pythonWorker) request(line []byte) (*pythonPoolResponse, error) {
+	if _, err := w.stdin.Write(line); err != nil {
+		return nil, err
+	}
+	answer, err := w.stdout.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	var response pythonPoolResponse
+	if err := json.Unmarshal(answer, &response); err != nil {
+		return nil, fmt.Errorf("invalid worker response: %v", err)
+	}
+	return &response, nil
+}
+
+// Close stops the idle interpreters; busy ones are stopped when released
+func (p *PythonPool) Close() {
+	p.mutex.Lock()
+	p.closed = true
+	p.mutex.Unlock()
+
+	for {
+		select {
+		case worker := <-p.idle:
+			worker.stop()
+		default:
+			return
+		}
+	}
+}
+
+// runPythonPoolBenchmark runs an automation repeatedly, spawned and pooled,
+// printing the latency of each, and returns the process exit code
+func runPythonPoolBenchmark(config *Config, scriptName, contextFile string, runs int) int {
+	context := map[string]interface{}{}
+	if contextFile != "" {
+		loaded, err := loadContextFromFile(contextFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		context = loaded
+	}
+	if runs <= 0 {
+		runs = 20
+	}
+
+	venvPath := config.GetVenvPath()
+	scriptPath := config.GetScriptPath(scriptName)
+	pool := getPythonPool(venvPath, config.Python.Pool)
+	defer closePythonPools()
+
+	measure := func(run func() ([]byte, error)) ([]time.Duration, error) {
+		latencies := make([]time.Duration, 0, runs)
+		for i := 0; i < runs; i++ {
+			start := time.Now()
+			if _, err := run(); err != nil {
+				return nil, err
+			}
+			latencies = append(latencies, time.Since(start))
+		}
+		return latencies, nil
+	}
+
+	fmt.Printf("Benchmarking %s, %d runs each...\n", scriptName, runs)
+	spawned, err := measure(func() ([]byte, error) {
+		return RunPythonFromVenvWithJSONAndEnv(venvPath, scriptPath, context, nil)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: spawned run failed: %v\n", err)
+		return 1
+	}
+	pooled, err := measure(func() ([]byte, error) {
+		return pool.Run(scriptPath, context, nil)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: pooled run failed: %v\n", err)
+		return 1
+	}
+
+	spawnedMedian, pooledMedian := medianDuration(spawned), medianDuration(pooled)
+	fmt.Printf("  spawned: median %s, mean %s\n", spawnedMedian, meanDuration(spawned))
+	fmt.Printf("  pooled:  median %s, mean %s (includes interpreter start on the first run)\n", pooledMedian, meanDuration(pooled))
+	if pooledMedian > 0 {
+		fmt.Printf("  speedup: %.1fx\n", float64(spawnedMedian)/float64(pooledMedian))
+	}
+	return 0
+}
+
+// medianDuration returns the median of latencies
+func medianDuration(latencies []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), latencies...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2].Round(time.Microsecond)
+}
+
+// meanDuration returns the mean of latencies
+func meanDuration(latencies []time.Duration) time.Duration {
+	var total time.Duration
+	for _, latency := range latencies {
+		total += latency
+	}
+	return (total / time.Duration(len(latencies))).Round(time.Microsecond)
+}
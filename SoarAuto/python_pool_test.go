@@ -0,0 +1,105 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// pythonPoolTestScript echoes the input it is given and the run it serves
+const pythonPoolTestScript = `
+import json, os, sys
+data = json.load(sys.stdin)
+print(json.dumps({"echo": data, "pid": os.getpid()}))
+`
+
+// newPythonTestVenv returns a directory laid out like a venv whose
+// interpreter is the python3 on PATH, and an automation script in it.
+// The test is skipped when Python is not installed.
+func newPythonTestVenv(tb testing.TB) (string, string) {
+	tb.Helper()
+	if runtime.GOOS == "windows" {
+		tb.Skip("venv layout differs on windows")
+	}
+	output, err := exec.Command("python3", "-c", "import sys; print(sys.executable)").Output()
+	if err != nil {
+		tb.Skip("python3 not available")
+	}
+
+	venvPath := tb.TempDir()
+	if err := os.Mkdir(filepath.Join(venvPath, "bin"), 0755); err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.Symlink(strings.TrimSpace(string(output)), pythonExecutable(venvPath)); err != nil {
+		tb.Fatal(err)
+	}
+	scriptPath := filepath.Join(venvPath, "echo.py")
+	if err := os.WriteFile(scriptPath, []byte(pythonPoolTestScript), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return venvPath, scriptPath
+}
+
+func TestPythonPoolReusesAndReplacesWorkers(t *testing.T) {
+	venvPath, scriptPath := newPythonTestVenv(t)
+	pool := getPythonPool(venvPath, PythonPoolConfig{Size: 1, MaxRunsPerWorker: 2})
+	t.Cleanup(closePythonPools)
+
+	var pids []int64
+	for i := 0; i < 3; i++ {
+		output, err := pool.Run(scriptPath, map[string]interface{}{"run": i}, nil)
+		if err != nil {
+			t.Fatalf("run %d: %v", i, err)
+		}
+		var result struct {
+			Echo map[string]interface{} `json:"echo"`
+			Pid  int64                  `json:"pid"`
+		}
+		if err := unmarshalContextJSON(output, &result); err != nil {
+			t.Fatalf("run %d output %q: %v", i, output, err)
+		}
+		if run, _ := jsonInteger(result.Echo["run"]); run != int64(i) {
+			t.Errorf("run %d echoed %v", i, result.Echo)
+		}
+		pids = append(pids, result.Pid)
+	}
+
+	// Two runs share an interpreter, then it is replaced
+	if pids[0] != pids[1] || pids[1] == pids[2] {
+		t.Errorf("worker pids = %v, want the first two equal and the third new", pids)
+	}
+}
+
+// BenchmarkPythonSpawnedRun starts a new interpreter for every run
+func BenchmarkPythonSpawnedRun(b *testing.B) {
+	venvPath, scriptPath := newPythonTestVenv(b)
+	input := map[string]interface{}{"indicator": "198.51.100.7"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := RunPythonFromVenvWithJSONAndEnv(venvPath, scriptPath, input, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPythonPooledRun runs in a warm interpreter, started before timing
+func BenchmarkPythonPooledRun(b *testing.B) {
+	venvPath, scriptPath := newPythonTestVenv(b)
+	input := map[string]interface{}{"indicator": "198.51.100.7"}
+	pool := getPythonPool(venvPath, PythonPoolConfig{Size: 1, MaxRunsPerWorker: 1 << 30})
+	b.Cleanup(closePythonPools)
+	if _, err := pool.Run(scriptPath, input, nil); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := pool.Run(scriptPath, input, nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
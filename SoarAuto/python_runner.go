@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
@@ -168,8 +169,79 @@ func RunPythonFromVenvStdoutOnly(venvPath, scriptPath string, args ...string) ([
 	return output, nil
 }
 
+// Ways an automation can receive its context
+const (
+	contextPassingStdin = "stdin" // JSON written to stdin
+	contextPassingFile  = "file"  // Path of a temporary JSON file as the first argument
+	contextPassingEnv   = "env"   // JSON in the SECAUTO_CONTEXT environment variable
+)
+
+// Environment variables set for automations by the file and env modes
+const (
+	contextFileEnvVar = "SECAUTO_CONTEXT_FILE"
+	contextEnvVar     = "SECAUTO_CONTEXT"
+)
+
+// RunPythonFromVenvWithContext runs a Python script like
+// RunPythonFromVenvWithJSONAndEnv, passing jsonInput as the script's
+// context in the given mode. In file mode the temporary file is removed
+// when the script exits. exited, when not nil, is called with the state of
+// the exited process, whether or not the script succeeded.
+func RunPythonFromVenvWithContext(venvPath, scriptPath string, jsonInput interface{}, env map[string]string, mode string, exited func(*os.ProcessState)) ([]byte, error) {
+	if mode == "" || mode == contextPassingStdin {
+		return runPythonFromVenvWithJSONAndEnv(venvPath, scriptPath, jsonInput, env, exited)
+	}
+
+	jsonBytes, err := json.Marshal(jsonInput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON input: %v", err)
+	}
+
+	// Copied so the caller's environment map is not modified
+	scriptEnv := make(map[string]string, len(env)+1)
+	for key, value := range env {
+		scriptEnv[key] = value
+	}
+
+	switch mode {
+	case contextPassingFile:
+		file, err := os.CreateTemp(env[workDirEnvVar], "secauto-context-*.json")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create context file: %v", err)
+		}
+		defer os.Remove(file.Name())
+		_, err = file.Write(jsonBytes)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to write context file: %v", err)
+		}
+		scriptEnv[contextFileEnvVar] = file.Name()
+		return runPythonFromVenvWithJSONAndEnv(venvPath, scriptPath, nil, scriptEnv, exited, file.Name())
+	case contextPassingEnv:
+		scriptEnv[contextEnvVar] = string(jsonBytes)
+		return runPythonFromVenvWithJSONAndEnv(venvPath, scriptPath, nil, scriptEnv, exited)
+	default:
+		return nil, fmt.Errorf("unknown context_passing mode %q (expected %s, %s or %s)", mode, contextPassingStdin, contextPassingFile, contextPassingEnv)
+	}
+}
+
 // Run Python script with JSON input via stdin and separate stdout/stderr
 func RunPythonFromVenvWithJSONSeparateOutput(venvPath, scriptPath string, jsonInput interface{}, args ...string) ([]byte, error) {
+	return RunPythonFromVenvWithJSONAndEnv(venvPath, scriptPath, jsonInput, nil, args...)
+}
+
+// RunPythonFromVenvWithJSONAndEnv runs a Python script like
+// RunPythonFromVenvWithJSONSeparateOutput, with env merged over the base environment
+func RunPythonFromVenvWithJSONAndEnv(venvPath, scriptPath string, jsonInput interface{}, env map[string]string, args ...string) ([]byte, error) {
+	return runPythonFromVenvWithJSONAndEnv(venvPath, scriptPath, jsonInput, env, nil, args...)
+}
+
+// runPythonFromVenvWithJSONAndEnv runs a Python script like
+// RunPythonFromVenvWithJSONAndEnv and, when exited is not nil, calls it with
+// the state of the exited process
+func runPythonFromVenvWithJSONAndEnv(venvPath, scriptPath string, jsonInput interface{}, env map[string]string, exited func(*os.ProcessState), args ...string) ([]byte, error) {
 	var pythonExe string
 	if runtime.GOOS == "windows" {
 		pythonExe = filepath.Join(venvPath, "Scripts", "python.exe")
@@ -179,6 +251,16 @@ func RunPythonFromVenvWithJSONSeparateOutput(venvPath, scriptPath string, jsonIn
 	cmdArgs := append([]string{scriptPath}, args...)
 	cmd := exec.Command(pythonExe, cmdArgs...)
 
+	// Later entries take precedence, so env overrides the base environment
+	if len(env) > 0 {
+		cmd.Env = os.Environ()
+		for key, value := range env {
+			cmd.Env = append(cmd.Env, key+"="+value)
+		}
+	}
+	// A run given a working directory is started in it
+	cmd.Dir = env[workDirEnvVar]
+
 	// Create pipes for stdout and stderr
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -225,7 +307,11 @@ func RunPythonFromVenvWithJSONSeparateOutput(venvPath, scriptPath string, jsonIn
 	}()
 
 	// Wait for command to complete
-	if err := cmd.Wait(); err != nil {
+	err = cmd.Wait()
+	if exited != nil && cmd.ProcessState != nil {
+		exited(cmd.ProcessState)
+	}
+	if err != nil {
 		stderrOutput := <-stderrChan
 		return nil, fmt.Errorf("python execution failed: %v, stderr: %s", err, string(stderrOutput))
 	}
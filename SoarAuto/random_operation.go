@@ -0,0 +1,181 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// randomFloatBits is the number of random bits in a generated float, the
+// precision of a float64 mantissa
+const randomFloatBits = 53
+
+// evaluateRandomOperation handles the "random" operation, which generates a
+// random value for sampling and test data. All values come from crypto/rand.
+// Types are choice (an element of "choices"), int (between "min" and "max"
+// inclusive), float (from "min" up to "max"), bool (true with probability
+// "p") and uuid. "output_var" stores the value in the context.
+func (re *RuleEngine) evaluateRandomOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("random operation requires an object")
+	}
+
+	randomType, ok := specMap["type"].(string)
+	if !ok || randomType == "" {
+		return nil, fmt.Errorf("random operation requires a type (choice, int, float, bool or uuid)")
+	}
+
+	var value interface{}
+	var err error
+	switch randomType {
+	case "choice":
+		value, err = re.randomChoice(specMap, data)
+	case "int":
+		value, err = re.randomInt(specMap, data)
+	case "float":
+		value, err = re.randomFloat(specMap, data)
+	case "bool":
+		value, err = re.randomBool(specMap, data)
+	case "uuid":
+		value = uuid.NewString()
+	default:
+		return nil, fmt.Errorf("random type must be one of choice, int, float, bool or uuid, got %q", randomType)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("random %s failed: %v", randomType, err)
+	}
+
+	if target, exists := specMap["output_var"]; exists {
+		targetKey, ok := target.(string)
+		if !ok || targetKey == "" {
+			return nil, fmt.Errorf("random operation output_var must be a non-empty string")
+		}
+		if err := setContextPath(data, strings.Split(targetKey, "."), value); err != nil {
+			return nil, fmt.Errorf("random operation failed: %v", err)
+		}
+	}
+
+	return value, nil
+}
+
+// randomChoice picks an element of the evaluated "choices" array
+func (re *RuleEngine) randomChoice(specMap map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	choicesExpr, exists := specMap["choices"]
+	if !exists {
+		return nil, fmt.Errorf("choices are required")
+	}
+	evaluated, err := re.evaluate(choicesExpr, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate choices: %v", err)
+	}
+	choices, ok := evaluated.([]interface{})
+	if !ok || len(choices) == 0 {
+		return nil, fmt.Errorf("choices must be a non-empty array")
+	}
+	if err := re.checkCollectionSize("choices", len(choices)); err != nil {
+		return nil, err
+	}
+
+	index, err := rand.Int(rand.Reader, big.NewInt(int64(len(choices))))
+	if err != nil {
+		return nil, err
+	}
+	return choices[index.Int64()], nil
+}
+
+// randomInt returns a whole number between min and max inclusive
+func (re *RuleEngine) randomInt(specMap map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	low, err := re.randomNumberParam(specMap, "min", 0, data)
+	if err != nil {
+		return nil, err
+	}
+	high, err := re.randomNumberParam(specMap, "max", 100, data)
+	if err != nil {
+		return nil, err
+	}
+	if low != math.Trunc(low) || high != math.Trunc(high) {
+		return nil, fmt.Errorf("min and max must be whole numbers")
+	}
+	if low > high {
+		return nil, fmt.Errorf("min cannot be greater than max")
+	}
+	if high-low >= 1<<62 {
+		return nil, fmt.Errorf("range between min and max is too large")
+	}
+
+	offset, err := rand.Int(rand.Reader, big.NewInt(int64(high-low)+1))
+	if err != nil {
+		return nil, err
+	}
+	return int64(low) + offset.Int64(), nil
+}
+
+// randomFloat returns a number from min up to, but excluding, max
+func (re *RuleEngine) randomFloat(specMap map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	low, err := re.randomNumberParam(specMap, "min", 0, data)
+	if err != nil {
+		return nil, err
+	}
+	high, err := re.randomNumberParam(specMap, "max", 1, data)
+	if err != nil {
+		return nil, err
+	}
+	if low > high {
+		return nil, fmt.Errorf("min cannot be greater than max")
+	}
+
+	fraction, err := randomFraction()
+	if err != nil {
+		return nil, err
+	}
+	return low + (high-low)*fraction, nil
+}
+
+// randomBool returns true with probability p, 0.5 by default
+func (re *RuleEngine) randomBool(specMap map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	p, err := re.randomNumberParam(specMap, "p", 0.5, data)
+	if err != nil {
+		return nil, err
+	}
+	if p < 0 || p > 1 {
+		return nil, fmt.Errorf("p must be between 0 and 1")
+	}
+
+	fraction, err := randomFraction()
+	if err != nil {
+		return nil, err
+	}
+	return fraction < p, nil
+}
+
+// randomFraction returns a uniformly distributed number in [0, 1)
+func randomFraction() (float64, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1<<randomFloatBits))
+	if err != nil {
+		return 0, err
+	}
+	return float64(n.Int64()) / (1 << randomFloatBits), nil
+}
+
+// randomNumberParam evaluates a numeric parameter, returning fallback when
+// it is absent
+func (re *RuleEngine) randomNumberParam(specMap map[string]interface{}, name string, fallback float64, data map[string]interface{}) (float64, error) {
+	expr, exists := specMap[name]
+	if !exists {
+		return fallback, nil
+	}
+	evaluated, err := re.evaluate(expr, data)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate %s: %v", name, err)
+	}
+	number, ok := jsonLogicNumeric(evaluated)
+	if !ok || math.IsNaN(number) || math.IsInf(number, 0) {
+		return 0, fmt.Errorf("%s must be a number, got %T", name, evaluated)
+	}
+	return number, nil
+}
@@ -1,12 +1,46 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
+// rateLimitKeyPrefix prefixes the Redis keys of shared rate limit counters
+const rateLimitKeyPrefix = "secauto:ratelimit:"
+
+// slidingWindowScript records a request in a client's sliding window if the
+// limit allows it. It returns whether the request was allowed, the number of
+// requests already in the window and the time of the oldest, in milliseconds.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call("ZREMRANGEBYSCORE", key, "-inf", now - window)
+local count = redis.call("ZCARD", key)
+local allowed = 0
+if count < limit then
+	redis.call("ZADD", key, now, ARGV[4])
+	allowed = 1
+end
+redis.call("PEXPIRE", key, window)
+local oldest = redis.call("ZRANGE", key, 0, 0, "WITHSCORES")
+local oldestScore = now
+if oldest[2] then
+	oldestScore = tonumber(oldest[2])
+end
+return {allowed, count, oldestScore}
+`)
+
 // RateLimitConfig defines rate limits for different endpoints
 type RateLimitConfig struct {
 	Requests int           `json:"requests"`
@@ -19,6 +53,13 @@ type RateLimiter struct {
 	requests map[string][]time.Time // IP -> slice of request times
 	mutex    sync.RWMutex
 	cleanup  chan struct{}
+
+	trustedNetworks []*net.IPNet // Sources exempt from rate limiting
+	trustedProxies  []*net.IPNet // Peers whose forwarded headers are believed
+
+	// Set in cluster mode so all nodes count requests in the same Redis keys
+	shared          *redis.Client
+	sharedNamespace string
 }
 
 // NewRateLimiter creates a new rate limiter
@@ -117,6 +158,7 @@ func NewRateLimiter(config *Config) *RateLimiter {
 			"/job/":           {Requests: jobStatusLimit, Window: windowSize},
 			"/context":        {Requests: contextLimit, Window: windowSize},
 			"/webhooks":       {Requests: webhooksLimit, Window: windowSize},
+			"/webhooks/":      {Requests: webhooksLimit, Window: windowSize},
 			"/plugins":        {Requests: pluginsLimit, Window: windowSize},
 			"/plugins/":       {Requests: pluginsLimit, Window: windowSize},
 			"/cluster":        {Requests: clusterLimit, Window: windowSize},
@@ -127,6 +169,9 @@ func NewRateLimiter(config *Config) *RateLimiter {
 		},
 		requests: make(map[string][]time.Time),
 		cleanup:  make(chan struct{}),
+
+		trustedNetworks: parseTrustedNetworks("trusted_cidr_ranges", config.Security.RateLimiting.TrustedCIDRRanges),
+		trustedProxies:  parseTrustedNetworks("trusted_proxies", config.Security.RateLimiting.TrustedProxies),
 	}
 
 	// Start cleanup goroutine
@@ -151,22 +196,141 @@ func NewRateLimiter(config *Config) *RateLimiter {
 			"schedules":      schedulesLimit,
 			"default":        defaultLimit,
 		},
-		"window_size": windowSize.String(),
+		"window_size":         windowSize.String(),
+		"trusted_cidr_ranges": len(rl.trustedNetworks),
+		"trusted_proxies":     len(rl.trustedProxies),
 	})
 
 	return rl
 }
 
+// parseTrustedNetworks parses CIDRs and single IPs, logging and skipping
+// invalid entries so a typo never widens the set of trusted sources
+func parseTrustedNetworks(setting string, entries []string) []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				bits := 8 * len(ip.To16())
+				if ip.To4() != nil {
+					ip, bits = ip.To4(), 32
+				}
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+				continue
+			}
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			logger.Error("Ignoring invalid rate limiting network", map[string]interface{}{
+				"component": "rate_limit",
+				"setting":   setting,
+				"entry":     entry,
+				"error":     err.Error(),
+			})
+			continue
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// containsIP reports whether ip lies in any of the networks
+func containsIP(networks []*net.IPNet, ip string) bool {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTrusted reports whether requests from ip are exempt from rate limiting
+func (rl *RateLimiter) isTrusted(ip string) bool {
+	return containsIP(rl.trustedNetworks, ip)
+}
+
+// clientIP returns the IP a request is rate limited by, and whether it is
+// reliable enough to grant a trusted network exemption. Forwarded headers are
+// believed only from configured trusted proxies; with none configured they
+// are still used for limiting, but a forwarded IP never earns an exemption.
+func (rl *RateLimiter) clientIP(r *http.Request) (string, bool) {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+
+	if len(rl.trustedProxies) == 0 {
+		forwarded := r.Header.Get("X-Forwarded-For") != "" || r.Header.Get("X-Real-IP") != ""
+		return getClientIP(r), !forwarded
+	}
+	if !containsIP(rl.trustedProxies, peer) {
+		return peer, true
+	}
+
+	// The client is the nearest X-Forwarded-For hop that is not one of our proxies
+	if header := r.Header.Get("X-Forwarded-For"); header != "" {
+		hops := strings.Split(header, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if !containsIP(rl.trustedProxies, hop) || i == 0 {
+				return hop, true
+			}
+		}
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip, true
+	}
+	return peer, true
+}
+
+// UseSharedCounters makes the limiter count requests in Redis under the
+// namespace instead of in memory, so a client's limit applies across all
+// cluster nodes rather than to each node separately
+func (rl *RateLimiter) UseSharedCounters(client *redis.Client, namespace string) {
+	rl.shared = client
+	rl.sharedNamespace = namespace
+
+	logger.Info("Rate limiting shared across cluster", map[string]interface{}{
+		"component": "rate_limit",
+		"namespace": namespace,
+	})
+}
+
+// limitFor returns the limit applying to a path
+func (rl *RateLimiter) limitFor(path string) RateLimitConfig {
+	limit, exists := rl.limits[path]
+	if !exists {
+		limit = rl.limits["default"]
+	}
+	return limit
+}
+
 // isAllowed checks if the request is within rate limits
 func (rl *RateLimiter) isAllowed(ip, path string) (bool, int, int, time.Time) {
+	if rl.shared != nil {
+		allowed, remaining, limit, resetTime, err := rl.isAllowedShared(ip, path)
+		if err == nil {
+			return allowed, remaining, limit, resetTime
+		}
+		// Keep limiting per node rather than failing requests while Redis is unavailable
+		logger.Warning("Shared rate limit check failed, using local counters", map[string]interface{}{
+			"component": "rate_limit",
+			"ip":        ip,
+			"error":     err.Error(),
+		})
+	}
+
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
 	// Get limit for this path
-	limit, exists := rl.limits[path]
-	if !exists {
-		limit = rl.limits["default"]
-	}
+	limit := rl.limitFor(path)
 
 	// Clean old requests for this IP
 	now := time.Now()
@@ -197,6 +361,29 @@ func (rl *RateLimiter) isAllowed(ip, path string) (bool, int, int, time.Time) {
 	return allowed, remaining, limit.Requests, resetTime
 }
 
+// isAllowedShared checks a request against the client's sliding window in
+// Redis. Like the local counters, one window per client is shared by all
+// paths, each applying its own limit.
+func (rl *RateLimiter) isAllowedShared(ip, path string) (bool, int, int, time.Time, error) {
+	limit := rl.limitFor(path)
+	key := rateLimitKeyPrefix + rl.sharedNamespace + ":" + ip
+
+	now := time.Now()
+	result, err := slidingWindowScript.Run(context.Background(), rl.shared, []string{key},
+		now.UnixMilli(), limit.Window.Milliseconds(), limit.Requests, uuid.NewString()).Int64Slice()
+	if err != nil {
+		return false, 0, 0, time.Time{}, err
+	}
+	if len(result) != 3 {
+		return false, 0, 0, time.Time{}, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+
+	allowed := result[0] == 1
+	remaining := limit.Requests - int(result[1])
+	resetTime := time.UnixMilli(result[2]).Add(limit.Window)
+	return allowed, remaining, limit.Requests, resetTime, nil
+}
+
 // cleanupOldRequests periodically cleans up old request records
 func (rl *RateLimiter) cleanupOldRequests() {
 	ticker := time.NewTicker(5 * time.Minute)
@@ -231,9 +418,19 @@ func (rl *RateLimiter) cleanupOldRequests() {
 func rateLimitMiddleware(rl *RateLimiter) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
 		return func(w http.ResponseWriter, r *http.Request) {
-			ip := getClientIP(r)
+			ip, verified := rl.clientIP(r)
 			path := r.URL.Path
 
+			if verified && rl.isTrusted(ip) {
+				logger.Info("Rate limit bypassed for trusted network", map[string]interface{}{
+					"component": "rate_limit",
+					"ip":        ip,
+					"path":      path,
+				})
+				next(w, r)
+				return
+			}
+
 			allowed, remaining, limit, resetTime := rl.isAllowed(ip, path)
 
 			// Set rate limit headers
@@ -258,3 +455,44 @@ func rateLimitMiddleware(rl *RateLimiter) func(http.HandlerFunc) http.HandlerFun
 		}
 	}
 }
+
+// ConcurrencyLimiter bounds the number of executions running at the same time
+type ConcurrencyLimiter struct {
+	slots    chan struct{}
+	rejected uint64
+}
+
+// NewConcurrencyLimiter creates a limiter allowing up to maxConcurrent executions
+func NewConcurrencyLimiter(maxConcurrent int) *ConcurrencyLimiter {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 100
+	}
+	return &ConcurrencyLimiter{
+		slots: make(chan struct{}, maxConcurrent),
+	}
+}
+
+// TryAcquire takes a slot without blocking, recording a rejection if none is free
+func (cl *ConcurrencyLimiter) TryAcquire() bool {
+	select {
+	case cl.slots <- struct{}{}:
+		return true
+	default:
+		atomic.AddUint64(&cl.rejected, 1)
+		return false
+	}
+}
+
+// Release frees a slot taken by TryAcquire
+func (cl *ConcurrencyLimiter) Release() {
+	<-cl.slots
+}
+
+// Stats returns the limiter's current usage and rejection count
+func (cl *ConcurrencyLimiter) Stats() map[string]interface{} {
+	return map[string]interface{}{
+		"max_concurrent": cap(cl.slots),
+		"in_flight":      len(cl.slots),
+		"rejected_total": atomic.LoadUint64(&cl.rejected),
+	}
+}
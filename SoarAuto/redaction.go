@@ -0,0 +1,218 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// redactionMask replaces every redacted value
+const redactionMask = "***"
+
+// defaultRedactionPatterns catch common credentials in free text. When a
+// pattern has a capture group, only the first group is masked, so
+// "Bearer abc..." becomes "Bearer ***".
+var defaultRedactionPatterns = []string{
+	// JSON Web Tokens
+	`eyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`,
+	// AWS access key IDs
+	`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`,
+	// AWS secret access keys given as key=value or "key": "value"
+	`(?i)aws_secret_access_key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40,})`,
+	// Authorization header values
+	`(?i)\b(?:bearer|basic|token)\s+([A-Za-z0-9._~+/=-]{8,})`,
+	// PEM private keys
+	`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`,
+}
+
+// defaultRedactionKeys are object keys whose values are always masked,
+// compared case-insensitively
+var defaultRedactionKeys = []string{
+	"authorization", "proxy-authorization", "x-api-key", "api_key", "apikey",
+	"password", "passwd", "secret", "client_secret", "access_token",
+	"refresh_token", "aws_secret_access_key", "private_key",
+}
+
+// Redactor masks credentials in log entries and in job contexts and results
+// before they leave the process. Values are only masked on output: the
+// contexts playbooks run on and the jobs stored in Redis are unchanged.
+type Redactor struct {
+	patterns []*regexp.Regexp
+	keys     map[string]bool
+}
+
+// outputRedactor is the redactor configured by logging.redaction; nil when
+// redaction is disabled
+var outputRedactor *Redactor
+
+// NewRedactor compiles the redaction configuration. It returns nil when
+// redaction is disabled and an error when a pattern does not compile.
+func NewRedactor(config RedactionConfig) (*Redactor, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	patterns := config.Patterns
+	keys := config.Keys
+	if config.UseDefaults {
+		patterns = append(append([]string{}, defaultRedactionPatterns...), patterns...)
+		keys = append(append([]string{}, defaultRedactionKeys...), keys...)
+	}
+
+	redactor := &Redactor{keys: make(map[string]bool)}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+		}
+		redactor.patterns = append(redactor.patterns, compiled)
+	}
+	for _, key := range keys {
+		redactor.keys[strings.ToLower(key)] = true
+	}
+	return redactor, nil
+}
+
+// Redact masks every match of the redaction patterns in s
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, pattern := range r.patterns {
+		if pattern.NumSubexp() == 0 {
+			s = pattern.ReplaceAllLiteralString(s, redactionMask)
+			continue
+		}
+		var masked strings.Builder
+		last := 0
+		for _, match := range pattern.FindAllStringSubmatchIndex(s, -1) {
+			start, end := match[0], match[1]
+			if match[2] >= 0 {
+				start, end = match[2], match[3]
+			}
+			masked.WriteString(s[last:start])
+			masked.WriteString(redactionMask)
+			last = end
+		}
+		masked.WriteString(s[last:])
+		s = masked.String()
+	}
+	return s
+}
+
+// RedactValue returns a copy of value with strings masked by the redaction
+// patterns and the values of redacted keys replaced entirely. Objects and
+// arrays are copied, so the original is never modified.
+func (r *Redactor) RedactValue(value interface{}) interface{} {
+	if r == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case string:
+		return r.Redact(v)
+	case map[string]interface{}:
+		return r.RedactMap(v)
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = r.RedactValue(item)
+		}
+		return redacted
+	case map[string]string:
+		redacted := make(map[string]string, len(v))
+		for key, item := range v {
+			if r.keys[strings.ToLower(key)] {
+				redacted[key] = redactionMask
+			} else {
+				redacted[key] = r.Redact(item)
+			}
+		}
+		return redacted
+	default:
+		return value
+	}
+}
+
+// RedactMap is RedactValue for an object
+func (r *Redactor) RedactMap(value map[string]interface{}) map[string]interface{} {
+	if r == nil || value == nil {
+		return value
+	}
+	redacted := make(map[string]interface{}, len(value))
+	for key, item := range value {
+		if r.keys[strings.ToLower(key)] && item != nil {
+			redacted[key] = redactionMask
+			continue
+		}
+		redacted[key] = r.RedactValue(item)
+	}
+	return redacted
+}
+
+// RedactJob returns a copy of a job for output with its contexts, results,
+// environment and errors redacted
+func (r *Redactor) RedactJob(job *Job) *Job {
+	if r == nil || job == nil {
+		return job
+	}
+	redacted := *job
+	redacted.Context = r.RedactMap(job.Context)
+	redacted.InitialContext = r.RedactMap(job.InitialContext)
+	if job.Results != nil {
+		redacted.Results = r.RedactValue(job.Results).([]interface{})
+	}
+	if job.Env != nil {
+		redacted.Env = r.RedactValue(job.Env).(map[string]string)
+	}
+	redacted.Error = r.Redact(job.Error)
+	redacted.AbortReason = r.Redact(job.AbortReason)
+	redacted.Findings = r.RedactFindings(job.Findings)
+	return &redacted
+}
+
+// RedactFindings returns a copy of findings for output with their titles and
+// details redacted
+func (r *Redactor) RedactFindings(findings []Finding) []Finding {
+	if r == nil || findings == nil {
+		return findings
+	}
+	redacted := make([]Finding, len(findings))
+	for i, finding := range findings {
+		finding.Title = r.Redact(finding.Title)
+		finding.Detail = r.RedactValue(finding.Detail)
+		redacted[i] = finding
+	}
+	return redacted
+}
+
+// RedactJobs redacts a list of jobs for output
+func (r *Redactor) RedactJobs(jobs []*Job) []*Job {
+	if r == nil {
+		return jobs
+	}
+	redacted := make([]*Job, len(jobs))
+	for i, job := range jobs {
+		redacted[i] = r.RedactJob(job)
+	}
+	return redacted
+}
+
+// redactLogEntry masks the free-text fields and values of a log entry
+func (r *Redactor) redactLogEntry(entry *LogEntry) {
+	if r == nil {
+		return
+	}
+	entry.Message = r.Redact(entry.Message)
+	entry.Error = r.Redact(entry.Error)
+	entry.Condition = r.Redact(entry.Condition)
+	entry.Script = r.Redact(entry.Script)
+	entry.Path = r.Redact(entry.Path)
+	entry.WebhookURL = r.Redact(entry.WebhookURL)
+	if entry.Variable != "" && r.keys[strings.ToLower(entry.Variable)] && entry.Value != nil {
+		entry.Value = redactionMask
+	} else {
+		entry.Value = r.RedactValue(entry.Value)
+	}
+	entry.Stats = r.RedactMap(entry.Stats)
+	entry.Context = r.RedactMap(entry.Context)
+}
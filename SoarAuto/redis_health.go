@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultRedisStartupAttempts = 5
+	defaultRedisStartupBackoff  = time.Second
+	// maxRedisStartupBackoff caps the wait between startup connection attempts
+	maxRedisStartupBackoff     = 30 * time.Second
+	defaultRedisHealthInterval = 5 * time.Second
+	redisHealthCheckTimeout    = 2 * time.Second
+	// maxPendingJobWrites bounds the job writes held while Redis is unavailable
+	maxPendingJobWrites = 10000
+)
+
+// connectRedis pings Redis until it answers or the configured attempts are
+// used up, waiting between attempts with exponential backoff
+func connectRedis(ctx context.Context, client *redis.Client, settings RedisConnectionConfig) error {
+	attempts := settings.StartupAttempts
+	if attempts <= 0 {
+		attempts = defaultRedisStartupAttempts
+	}
+	backoff := defaultRedisStartupBackoff
+	if parsed, err := time.ParseDuration(settings.StartupBackoff); err == nil && parsed > 0 {
+		backoff = parsed
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = client.Ping(ctx).Err(); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+
+		logger.Warning("Redis is not reachable, retrying", map[string]interface{}{
+			"component": "job_store",
+			"attempt":   attempt,
+			"attempts":  attempts,
+			"retry_in":  backoff.String(),
+			"error":     err.Error(),
+		})
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxRedisStartupBackoff {
+			backoff = maxRedisStartupBackoff
+		}
+	}
+	return fmt.Errorf("failed to connect to Redis after %d attempts: %v", attempts, err)
+}
+
+// RedisHealth is the Redis availability reported by /health/ready
+type RedisHealth struct {
+	Status        string `json:"status"` // up or down
+	LastError     string `json:"last_error,omitempty"`
+	DownSince     string `json:"down_since,omitempty"`
+	LastCheck     string `json:"last_check,omitempty"`
+	PendingWrites int    `json:"pending_writes"` // Job writes waiting for Redis to return
+}
+
+// pendingJobWrite is a job record that could not be written to Redis
+type pendingJobWrite struct {
+	data         []byte
+	createdAt    time.Time
+	playbookName string
+	seq          uint64 // Increases each time the record is replaced
+}
+
+// redisHealth tracks whether Redis is reachable and holds the job writes
+// made while it was not. Writes are kept per job, latest record only, and
+// replayed in the order jobs were first queued once Redis answers again.
+// The latest records of unfinished jobs are also kept, so jobs running
+// through an outage can still be loaded and updated.
+type redisHealth struct {
+	mutex     sync.Mutex
+	available bool
+	lastError string
+	downSince time.Time
+	lastCheck time.Time
+	active    map[string][]byte
+	pending   map[string]*pendingJobWrite
+	order     []string
+	seq       uint64
+	flushing  bool
+	stop      chan struct{}
+}
+
+func newRedisHealth() *redisHealth {
+	return &redisHealth{
+		available: true,
+		active:    make(map[string][]byte),
+		pending:   make(map[string]*pendingJobWrite),
+		stop:      make(chan struct{}),
+	}
+}
+
+// Available reports whether Redis answered the latest check and write
+func (h *redisHealth) Available() bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.available
+}
+
+// Status returns the current Redis health
+func (h *redisHealth) Status() RedisHealth {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	status := RedisHealth{Status: "up", LastError: h.lastError, PendingWrites: len(h.pending)}
+	if !h.available {
+		status.Status = "down"
+		status.DownSince = h.downSince.UTC().Format(time.RFC3339)
+	}
+	if !h.lastCheck.IsZero() {
+		status.LastCheck = h.lastCheck.UTC().Format(time.RFC3339)
+	}
+	return status
+}
+
+// markDown records a failed Redis call
+func (h *redisHealth) markDown(err error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lastError = err.Error()
+	if !h.available {
+		return
+	}
+	h.available = false
+	h.downSince = time.Now()
+	logger.Error("Redis is unavailable, job writes will be queued", map[string]interface{}{
+		"component": "job_store",
+		"error":     err.Error(),
+	})
+}
+
+// markUp records a successful health check
+func (h *redisHealth) markUp() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	h.lastCheck = time.Now()
+	if h.available {
+		return
+	}
+	h.available = true
+	h.lastError = ""
+	logger.Info("Redis is available again", map[string]interface{}{
+		"component":      "job_store",
+		"down_for":       time.Since(h.downSince).Round(time.Second).String(),
+		"pending_writes": len(h.pending),
+	})
+}
+
+// queue holds a job record for a later write. It fails when the queue is
+// full and the job has no record in it already.
+func (h *redisHealth) queue(jobID string, createdAt time.Time, playbookName string, data []byte) error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.queueLocked(jobID, createdAt, playbookName, data)
+}
+
+func (h *redisHealth) queueLocked(jobID string, createdAt time.Time, playbookName string, data []byte) error {
+	h.seq++
+	if write, exists := h.pending[jobID]; exists {
+		write.data = data
+		write.seq = h.seq
+		return nil
+	}
+	if len(h.pending) >= maxPendingJobWrites {
+		return fmt.Errorf("redis is unavailable and %d job writes are already queued", maxPendingJobWrites)
+	}
+	h.pending[jobID] = &pendingJobWrite{data: data, createdAt: createdAt, playbookName: playbookName, seq: h.seq}
+	h.order = append(h.order, jobID)
+	return nil
+}
+
+// queueIfBehind queues the record when earlier writes are still waiting or
+// being replayed, so a job's writes reach Redis in order. It reports whether
+// the record was queued.
+func (h *redisHealth) queueIfBehind(jobID string, createdAt time.Time, playbookName string, data []byte) (bool, error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.pending) == 0 && !h.flushing {
+		return false, nil
+	}
+	return true, h.queueLocked(jobID, createdAt, playbookName, data)
+}
+
+// track keeps the latest record of an unfinished job and forgets finished ones
+func (h *redisHealth) track(jobID, status string, data []byte) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if isFinishedJobStatus(status) {
+		delete(h.active, jobID)
+	} else {
+		h.active[jobID] = data
+	}
+}
+
+// activeJob returns the latest record of an unfinished job
+func (h *redisHealth) activeJob(jobID string) ([]byte, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	data, exists := h.active[jobID]
+	return data, exists
+}
+
+// pendingJob returns the queued record of a job, which is newer than the
+// one in Redis
+func (h *redisHealth) pendingJob(jobID string) ([]byte, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	write, exists := h.pending[jobID]
+	if !exists {
+		return nil, false
+	}
+	return write.data, true
+}
+
+// next returns the oldest queued write and marks the queue as being
+// replayed. When the queue is empty the replay is over.
+func (h *redisHealth) next() (string, pendingJobWrite, bool) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if len(h.order) == 0 || !h.available {
+		h.flushing = false
+		return "", pendingJobWrite{}, false
+	}
+	h.flushing = true
+	jobID := h.order[0]
+	return jobID, *h.pending[jobID], true
+}
+
+// endFlush ends a replay that stopped on a failed write
+func (h *redisHealth) endFlush() {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.flushing = false
+}
+
+// written drops a replayed write unless the job was written again meanwhile
+func (h *redisHealth) written(jobID string, seq uint64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if write, exists := h.pending[jobID]; exists && write.seq == seq {
+		delete(h.pending, jobID)
+		h.order = h.order[1:]
+	}
+}
+
+// monitorHealth pings Redis until the store is closed, replaying queued
+// writes whenever it answers
+func (rjs *RedisJobStore) monitorHealth(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rjs.checkHealth()
+		case <-rjs.health.stop:
+			return
+		}
+	}
+}
+
+// checkHealth pings Redis and replays queued writes if it answers
+func (rjs *RedisJobStore) checkHealth() {
+	ctx, cancel := context.WithTimeout(rjs.ctx, redisHealthCheckTimeout)
+	err := rjs.client.Ping(ctx).Err()
+	cancel()
+	if err != nil {
+		rjs.health.markDown(err)
+		return
+	}
+	rjs.health.markUp()
+	rjs.flushPendingWrites()
+}
+
+// flushPendingWrites replays queued job writes, oldest first, stopping at
+// the first failure
+func (rjs *RedisJobStore) flushPendingWrites() {
+	flushed := 0
+	for {
+		jobID, write, ok := rjs.health.next()
+		if !ok {
+			break
+		}
+		if err := rjs.writeJob(jobID, write.createdAt, write.playbookName, write.data); err != nil {
+			rjs.health.markDown(err)
+			rjs.health.endFlush()
+			break
+		}
+		rjs.health.written(jobID, write.seq)
+		flushed++
+	}
+
+	if flushed > 0 {
+		logger.Info("Replayed queued job writes", map[string]interface{}{
+			"component": "job_store",
+			"written":   flushed,
+			"pending":   rjs.health.Status().PendingWrites,
+		})
+	}
+}
+
+// redisRequiredMiddleware answers 503 Service Unavailable while the Redis
+// job store is down, for endpoints that cannot work without it
+func redisRequiredMiddleware(store JobStoreInterface) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			redisStore, ok := store.(*RedisJobStore)
+			if ok && redisStore.health != nil && !redisStore.health.Available() {
+				logger.Warning("Rejected request while Redis is unavailable", map[string]interface{}{
+					"component": "server",
+					"path":      r.URL.Path,
+				})
+				w.Header().Set("Retry-After", fmt.Sprintf("%.0f", redisStore.healthInterval.Seconds()))
+				http.Error(w, "Service Unavailable: the job store (Redis) is unavailable, retry later", http.StatusServiceUnavailable)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
@@ -11,14 +11,17 @@ import (
 
 // RedisJobStore provides persistent storage for jobs using Redis
 type RedisJobStore struct {
-	client *redis.Client
-	ctx    context.Context
+	client         *redis.Client
+	ctx            context.Context
+	health         *redisHealth
+	healthInterval time.Duration
 }
 
-// NewRedisJobStore creates a new Redis job store
-func NewRedisJobStore(redisURL string) (*RedisJobStore, error) {
+// NewRedisJobStore creates a new Redis job store, retrying the connection
+// with backoff so the server can start while Redis is still coming up
+func NewRedisJobStore(dbConfig DatabaseConfig) (*RedisJobStore, error) {
 	// Parse Redis URL (format: redis://host:port/db)
-	opt, err := redis.ParseURL(redisURL)
+	opt, err := redis.ParseURL(dbConfig.RedisURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse Redis URL: %v", err)
 	}
@@ -27,34 +30,68 @@ func NewRedisJobStore(redisURL string) (*RedisJobStore, error) {
 	ctx := context.Background()
 
 	// Test the connection
-	if err := client.Ping(ctx).Err(); err != nil {
-		return nil, fmt.Errorf("failed to connect to Redis: %v", err)
+	if err := connectRedis(ctx, client, dbConfig.Connection); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	interval := defaultRedisHealthInterval
+	if parsed, err := time.ParseDuration(dbConfig.Connection.HealthCheckInterval); err == nil && parsed > 0 {
+		interval = parsed
 	}
 
 	store := &RedisJobStore{
-		client: client,
-		ctx:    ctx,
+		client:         client,
+		ctx:            ctx,
+		health:         newRedisHealth(),
+		healthInterval: interval,
 	}
+	go store.monitorHealth(interval)
 
 	logger.Info("Initialized Redis job store", map[string]interface{}{
 		"component": "job_store",
-		"redis_url": redisURL,
+		"redis_url": dbConfig.RedisURL,
 	})
 
 	return store, nil
 }
 
-// SaveJob persists a job to Redis
+// SaveJob persists a job to Redis. While Redis is unavailable the record is
+// queued and written once it returns, so running jobs are not failed by an
+// outage; an error is returned only when the queue is full.
 func (rjs *RedisJobStore) SaveJob(job *Job) error {
-	// Serialize job to JSON
+	// Serialize job to JSON at the current schema version
+	job.SchemaVersion = currentJobSchemaVersion
 	data, err := json.Marshal(job)
 	if err != nil {
 		return fmt.Errorf("failed to marshal job: %v", err)
 	}
+	rjs.health.track(job.ID, job.Status, data)
 
+	// Writes queue behind earlier ones so they reach Redis in order
+	if queued, err := rjs.health.queueIfBehind(job.ID, job.CreatedAt, job.PlaybookName, data); queued {
+		return err
+	}
+
+	if err := rjs.writeJob(job.ID, job.CreatedAt, job.PlaybookName, data); err != nil {
+		rjs.health.markDown(err)
+		if queueErr := rjs.health.queue(job.ID, job.CreatedAt, job.PlaybookName, data); queueErr != nil {
+			return fmt.Errorf("%v (%v)", err, queueErr)
+		}
+		logger.Warning("Queued job write until Redis is available", map[string]interface{}{
+			"component": "job_store",
+			"job_id":    job.ID,
+			"error":     err.Error(),
+		})
+	}
+	return nil
+}
+
+// writeJob writes a serialized job record to Redis
+func (rjs *RedisJobStore) writeJob(jobID string, createdAt time.Time, playbookName string, data []byte) error {
 	// Store job with 24-hour TTL
-	key := fmt.Sprintf("job:%s", job.ID)
-	err = rjs.client.Set(rjs.ctx, key, data, 24*time.Hour).Err()
+	key := fmt.Sprintf("job:%s", jobID)
+	err := rjs.client.Set(rjs.ctx, key, data, 24*time.Hour).Err()
 	if err != nil {
 		return fmt.Errorf("failed to save job: %v", err)
 	}
@@ -62,24 +99,138 @@ func (rjs *RedisJobStore) SaveJob(job *Job) error {
 	// Also store in job list for easy querying
 	listKey := "jobs:list"
 	err = rjs.client.ZAdd(rjs.ctx, listKey, redis.Z{
-		Score:  float64(job.CreatedAt.Unix()),
-		Member: job.ID,
+		Score:  float64(createdAt.Unix()),
+		Member: jobID,
 	}).Err()
 	if err != nil {
 		return fmt.Errorf("failed to add job to list: %v", err)
 	}
 
+	// And in the run history of its playbook
+	if playbookName != "" {
+		if err := rjs.indexPlaybookRun(jobID, createdAt, playbookName); err != nil {
+			return err
+		}
+	}
+
+	// Every change bumps the job's version so long-polling clients notice it
+	versionKey := jobVersionKey(jobID)
+	pipe := rjs.client.TxPipeline()
+	pipe.Incr(rjs.ctx, versionKey)
+	pipe.Expire(rjs.ctx, versionKey, 24*time.Hour)
+	if _, err := pipe.Exec(rjs.ctx); err != nil {
+		return fmt.Errorf("failed to update job version: %v", err)
+	}
+
 	return nil
 }
 
+// jobVersionKey is the Redis key of a job's change counter. It sits outside
+// the job:* namespace, which holds only job records.
+func jobVersionKey(jobID string) string {
+	return fmt.Sprintf("jobs:version:%s", jobID)
+}
+
+// GetJobVersion returns the number of times a job has changed, or 0 if it
+// has no recorded changes
+func (rjs *RedisJobStore) GetJobVersion(jobID string) (int64, error) {
+	version, err := rjs.client.Get(rjs.ctx, jobVersionKey(jobID)).Int64()
+	if err != nil {
+		if err == redis.Nil {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get job version: %v", err)
+	}
+	return version, nil
+}
+
+// UpdateJob applies update to a job inside a WATCH on its key, so the write
+// is dropped and retried on the newer record if another client changed the
+// job first. While Redis is unavailable, or writes of the job are queued,
+// writes reach Redis in order through the queue and the update is a plain
+// load and save.
+func (rjs *RedisJobStore) UpdateJob(jobID string, update func(job *Job) bool) (bool, error) {
+	if _, queued := rjs.health.pendingJob(jobID); queued || !rjs.health.Available() {
+		job, exists := rjs.LoadJob(jobID)
+		if !exists {
+			return false, jobNotFoundError(jobID)
+		}
+		if !update(job) {
+			return false, nil
+		}
+		return true, rjs.SaveJob(job)
+	}
+
+	key := fmt.Sprintf("job:%s", jobID)
+	versionKey := jobVersionKey(jobID)
+	for attempt := 0; attempt < jobUpdateAttempts; attempt++ {
+		saved := false
+		err := rjs.client.Watch(rjs.ctx, func(tx *redis.Tx) error {
+			stored, err := tx.Get(rjs.ctx, key).Bytes()
+			if err == redis.Nil {
+				return jobNotFoundError(jobID)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to load job: %v", err)
+			}
+
+			job, _, err := migrateJobData(stored)
+			if err != nil {
+				return err
+			}
+			if !update(job) {
+				return nil
+			}
+
+			job.SchemaVersion = currentJobSchemaVersion
+			data, err := json.Marshal(job)
+			if err != nil {
+				return fmt.Errorf("failed to marshal job: %v", err)
+			}
+			_, err = tx.TxPipelined(rjs.ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(rjs.ctx, key, data, 24*time.Hour)
+				pipe.Incr(rjs.ctx, versionKey)
+				pipe.Expire(rjs.ctx, versionKey, 24*time.Hour)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			rjs.health.track(job.ID, job.Status, data)
+			saved = true
+			return nil
+		}, key)
+
+		if err == redis.TxFailedErr {
+			waitBeforeJobUpdateRetry(attempt)
+			continue
+		}
+		return saved, err
+	}
+	return false, jobUpdateConflictError(jobID)
+}
+
 // LoadJob retrieves a job by ID from Redis
 func (rjs *RedisJobStore) LoadJob(jobID string) (*Job, bool) {
+	// A queued write is newer than the record in Redis
+	if data, queued := rjs.health.pendingJob(jobID); queued {
+		job, _, err := migrateJobData(data)
+		return job, err == nil
+	}
+
 	key := fmt.Sprintf("job:%s", jobID)
 	data, err := rjs.client.Get(rjs.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
 			return nil, false
 		}
+		rjs.health.markDown(err)
+
+		// Unfinished jobs are known without Redis
+		if data, active := rjs.health.activeJob(jobID); active {
+			job, _, err := migrateJobData(data)
+			return job, err == nil
+		}
 		logger.Error("Failed to load job", map[string]interface{}{
 			"component": "job_store",
 			"job_id":    jobID,
@@ -88,8 +239,10 @@ func (rjs *RedisJobStore) LoadJob(jobID string) (*Job, bool) {
 		return nil, false
 	}
 
-	var job Job
-	if err := json.Unmarshal([]byte(data), &job); err != nil {
+	// Older records are migrated in memory; they are rewritten on the next
+	// save or by running with -migrate-jobs
+	job, _, err := migrateJobData([]byte(data))
+	if err != nil {
 		logger.Error("Failed to unmarshal job", map[string]interface{}{
 			"component": "job_store",
 			"job_id":    jobID,
@@ -98,7 +251,7 @@ func (rjs *RedisJobStore) LoadJob(jobID string) (*Job, bool) {
 		return nil, false
 	}
 
-	return &job, true
+	return job, true
 }
 
 // ListJobs retrieves jobs based on status and limit from Redis
@@ -134,66 +287,32 @@ func (rjs *RedisJobStore) ListJobs(status string, limit int) []*Job {
 
 // UpdateJobStatus updates a job's status in Redis
 func (rjs *RedisJobStore) UpdateJobStatus(jobID, status string) error {
-	// Load current job
-	job, exists := rjs.LoadJob(jobID)
-	if !exists {
-		return fmt.Errorf("job not found: %s", jobID)
-	}
-
-	// Update status and timestamps
-	job.Status = status
-	now := time.Now()
-
-	switch status {
-	case "running":
-		job.StartedAt = &now
-	case "completed", "failed", "cancelled":
-		job.CompletedAt = &now
-	}
-
-	// Save updated job
-	return rjs.SaveJob(job)
+	return updateStoredJobStatus(rjs, jobID, status)
 }
 
 // UpdateJobResults updates a job's results and error in Redis
 func (rjs *RedisJobStore) UpdateJobResults(jobID string, results []interface{}, errorMsg string) error {
-	// Load current job
-	job, exists := rjs.LoadJob(jobID)
-	if !exists {
-		return fmt.Errorf("job not found: %s", jobID)
-	}
-
-	// Update results and error
-	job.Results = results
-	job.Error = errorMsg
-	now := time.Now()
-	job.CompletedAt = &now
-
-	// Save updated job
-	return rjs.SaveJob(job)
+	return updateStoredJobResults(rjs, jobID, results, errorMsg)
 }
 
 // UpdateJobContext updates a job's context in Redis
 func (rjs *RedisJobStore) UpdateJobContext(jobID string, context map[string]interface{}) error {
-	// Load current job
-	job, exists := rjs.LoadJob(jobID)
-	if !exists {
-		return fmt.Errorf("job not found: %s", jobID)
-	}
-
-	// Update context
-	job.Context = context
-
-	// Save updated job
-	return rjs.SaveJob(job)
+	return updateStoredJobContext(rjs, jobID, context)
 }
 
 // DeleteJob removes a job from Redis
 func (rjs *RedisJobStore) DeleteJob(jobID string) error {
 	key := fmt.Sprintf("job:%s", jobID)
 
+	// Remove from its playbook's run history
+	if job, exists := rjs.LoadJob(jobID); exists && job.PlaybookName != "" {
+		if err := rjs.client.ZRem(rjs.ctx, playbookRunsKey(job.PlaybookName), jobID).Err(); err != nil {
+			return fmt.Errorf("failed to remove job from playbook history: %v", err)
+		}
+	}
+
 	// Remove from job storage
-	err := rjs.client.Del(rjs.ctx, key).Err()
+	err := rjs.client.Del(rjs.ctx, key, jobVersionKey(jobID)).Err()
 	if err != nil {
 		return fmt.Errorf("failed to delete job: %v", err)
 	}
@@ -210,91 +329,18 @@ func (rjs *RedisJobStore) DeleteJob(jobID string) error {
 
 // CleanupOldJobs removes jobs older than specified duration from Redis
 func (rjs *RedisJobStore) CleanupOldJobs(maxAge time.Duration) error {
-	cutoff := time.Now().Add(-maxAge)
-
 	// Get all job IDs
 	listKey := "jobs:list"
 	jobIDs, err := rjs.client.ZRange(rjs.ctx, listKey, 0, -1).Result()
 	if err != nil {
 		return fmt.Errorf("failed to get job IDs: %v", err)
 	}
-
-	deleted := 0
-	for _, jobID := range jobIDs {
-		job, exists := rjs.LoadJob(jobID)
-		if !exists {
-			continue
-		}
-
-		// Check if job is older than cutoff
-		if job.CreatedAt.Before(cutoff) {
-			if err := rjs.DeleteJob(jobID); err != nil {
-				logger.Error("Failed to delete old job", map[string]interface{}{
-					"component": "job_store",
-					"job_id":    jobID,
-					"error":     err.Error(),
-				})
-			} else {
-				deleted++
-			}
-		}
-	}
-
-	if deleted > 0 {
-		logger.Info("Cleaned up old jobs", map[string]interface{}{
-			"component": "job_store",
-			"deleted":   deleted,
-			"max_age":   maxAge.String(),
-		})
-	}
-
-	return nil
+	return cleanupStoredJobs(rjs, jobIDs, maxAge)
 }
 
 // GetStats returns job statistics from Redis
 func (rjs *RedisJobStore) GetStats() JobStats {
-	var stats JobStats
-
-	// Get all jobs
-	jobs := rjs.ListJobs("", 1000) // Get up to 1000 jobs for stats
-
-	stats.TotalJobs = len(jobs)
-
-	// Calculate stats
-	var totalDuration float64
-	completedCount := 0
-
-	for _, job := range jobs {
-		switch job.Status {
-		case "completed":
-			stats.Completed++
-			completedCount++
-			if job.StartedAt != nil && job.CompletedAt != nil {
-				duration := job.CompletedAt.Sub(*job.StartedAt).Seconds()
-				totalDuration += duration
-			}
-		case "failed":
-			stats.Failed++
-		case "running":
-			stats.Running++
-		case "pending":
-			stats.Pending++
-		}
-	}
-
-	// Calculate average duration
-	if completedCount > 0 {
-		stats.AvgDuration = totalDuration / float64(completedCount)
-	}
-
-	// Get recent jobs (last 10)
-	if len(jobs) > 10 {
-		stats.RecentJobs = jobs[:10]
-	} else {
-		stats.RecentJobs = jobs
-	}
-
-	return stats
+	return computeJobStats(rjs.ListJobs("", 1000)) // Get up to 1000 jobs for stats
 }
 
 // BackupJobs creates a backup of jobs from Redis
@@ -341,39 +387,22 @@ func (rjs *RedisJobStore) BackupJobs() error {
 
 // RecoverJobs recovers jobs that were running during a crash
 func (rjs *RedisJobStore) RecoverJobs(engine *RuleEngine, webhookManager *WebhookManager) {
-	// Get all running jobs
-	runningJobs := rjs.ListJobs("running", 1000)
-
-	if len(runningJobs) > 0 {
-		logger.Info("Recovering jobs that were running during crash", map[string]interface{}{
-			"component": "job_store",
-			"count":     len(runningJobs),
-		})
-
-		for _, job := range runningJobs {
-			// Mark as failed since we can't guarantee the state
-			rjs.UpdateJobStatus(job.ID, "failed")
-			rjs.UpdateJobResults(job.ID, nil, "Job failed due to server restart")
-
-			// Send webhook notification
-			if webhookManager != nil {
-				webhookManager.SendWebhook(WebhookEvent{
-					Event:     "job_failed",
-					JobID:     job.ID,
-					Status:    "failed",
-					Timestamp: time.Now().UTC().Format(time.RFC3339),
-					Playbook:  job.Playbook,
-					Context:   job.Context,
-					Error:     "Job failed due to server restart",
-				})
-			}
-		}
-	}
+	recoverStoredJobs(rjs, webhookManager)
 }
 
-// Close closes the Redis connection
+// Close stops the health monitor, makes a last attempt to write queued
+// jobs and closes the Redis connection
 func (rjs *RedisJobStore) Close() error {
 	if rjs.client != nil {
+		close(rjs.health.stop)
+		rjs.checkHealth()
+		if pending := rjs.health.Status().PendingWrites; pending > 0 {
+			logger.Error("Job writes lost: Redis unavailable at shutdown", map[string]interface{}{
+				"component": "job_store",
+				"pending":   pending,
+			})
+		}
+
 		logger.Info("Closing Redis job store", map[string]interface{}{
 			"component": "job_store",
 		})
@@ -397,33 +426,125 @@ func (rjs *RedisJobStore) GetDatabaseMetrics() map[string]interface{} {
 	}
 }
 
-// Schedule-related methods (placeholder implementations)
+// scheduleKey is the Redis key of a schedule record. Schedules have no TTL:
+// they last until deleted.
+func scheduleKey(scheduleID string) string {
+	return fmt.Sprintf("schedule:%s", scheduleID)
+}
+
+// schedulesListKey indexes schedule IDs by creation time
+const schedulesListKey = "schedules:list"
+
+// SaveSchedule persists a schedule, replacing any with the same ID
 func (rjs *RedisJobStore) SaveSchedule(schedule *JobSchedule) error {
-	// TODO: Implement schedule storage in Redis
-	return fmt.Errorf("schedule storage not implemented in Redis job store")
+	data, err := marshalSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	pipe := rjs.client.TxPipeline()
+	pipe.Set(rjs.ctx, scheduleKey(schedule.ID), data, 0)
+	pipe.ZAdd(rjs.ctx, schedulesListKey, redis.Z{
+		Score:  float64(schedule.CreatedAt.Unix()),
+		Member: schedule.ID,
+	})
+	if _, err := pipe.Exec(rjs.ctx); err != nil {
+		return fmt.Errorf("failed to save schedule: %v", err)
+	}
+	return nil
 }
 
+// LoadSchedule retrieves a schedule by ID
 func (rjs *RedisJobStore) LoadSchedule(scheduleID string) (*JobSchedule, bool) {
-	// TODO: Implement schedule loading from Redis
-	return nil, false
+	data, err := rjs.client.Get(rjs.ctx, scheduleKey(scheduleID)).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			logger.Error("Failed to load schedule", map[string]interface{}{
+				"component":   "job_store",
+				"schedule_id": scheduleID,
+				"error":       err.Error(),
+			})
+		}
+		return nil, false
+	}
+	return unmarshalSchedule(scheduleID, data)
+}
+
+// allSchedules loads every stored schedule
+func (rjs *RedisJobStore) allSchedules() []*JobSchedule {
+	scheduleIDs, err := rjs.client.ZRange(rjs.ctx, schedulesListKey, 0, -1).Result()
+	if err != nil {
+		logger.Error("Failed to list schedules", map[string]interface{}{
+			"component": "job_store",
+			"error":     err.Error(),
+		})
+		return nil
+	}
+	if len(scheduleIDs) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(scheduleIDs))
+	for i, scheduleID := range scheduleIDs {
+		keys[i] = scheduleKey(scheduleID)
+	}
+	values, err := rjs.client.MGet(rjs.ctx, keys...).Result()
+	if err != nil {
+		logger.Error("Failed to load schedules", map[string]interface{}{
+			"component": "job_store",
+			"error":     err.Error(),
+		})
+		return nil
+	}
+
+	schedules := make([]*JobSchedule, 0, len(values))
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			continue // Deleted since it was listed
+		}
+		if schedule, ok := unmarshalSchedule(scheduleIDs[i], []byte(data)); ok {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules
 }
 
+// ListSchedules retrieves schedules, optionally of one status, oldest first
 func (rjs *RedisJobStore) ListSchedules(status string, limit int) []*JobSchedule {
-	// TODO: Implement schedule listing from Redis
-	return []*JobSchedule{}
+	return filterSchedules(rjs.allSchedules(), status, limit)
 }
 
+// UpdateSchedule replaces a stored schedule
 func (rjs *RedisJobStore) UpdateSchedule(schedule *JobSchedule) error {
-	// TODO: Implement schedule updating in Redis
-	return fmt.Errorf("schedule updating not implemented in Redis job store")
+	data, err := marshalSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	// XX only replaces a schedule that exists
+	updated, err := rjs.client.SetXX(rjs.ctx, scheduleKey(schedule.ID), data, 0).Result()
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %v", err)
+	}
+	if !updated {
+		return fmt.Errorf("schedule not found: %s", schedule.ID)
+	}
+	return nil
 }
 
+// DeleteSchedule removes a schedule
 func (rjs *RedisJobStore) DeleteSchedule(scheduleID string) error {
-	// TODO: Implement schedule deletion from Redis
-	return fmt.Errorf("schedule deletion not implemented in Redis job store")
+	pipe := rjs.client.TxPipeline()
+	pipe.Del(rjs.ctx, scheduleKey(scheduleID))
+	pipe.ZRem(rjs.ctx, schedulesListKey, scheduleID)
+	if _, err := pipe.Exec(rjs.ctx); err != nil {
+		return fmt.Errorf("failed to delete schedule: %v", err)
+	}
+	return nil
 }
 
+// GetSchedulesDueForExecution returns the active schedules whose next run has come
 func (rjs *RedisJobStore) GetSchedulesDueForExecution() []*JobSchedule {
-	// TODO: Implement schedule execution querying from Redis
-	return []*JobSchedule{}
+	return dueSchedules(rjs.allSchedules(), time.Now())
 }
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+)
+
+// maxResourceStatsAutomations bounds the automations listed by /jobs/stats
+const maxResourceStatsAutomations = 10
+
+// JobResourceUsage is the CPU time and memory used by the Python automation
+// processes of one job. Automations run on pooled workers share a process
+// with other jobs and are only counted. Peak RSS is left out on platforms
+// whose process accounting does not report it.
+type JobResourceUsage struct {
+	Processes        int                                 `json:"processes"`                    // Automation processes measured
+	PooledRuns       int                                 `json:"pooled_runs,omitempty"`        // Automations run on pool workers, not measured
+	CPUTimeSeconds   float64                             `json:"cpu_time_seconds,omitempty"`   // User plus system CPU time
+	UserCPUSeconds   float64                             `json:"user_cpu_seconds,omitempty"`   // CPU time spent in user mode
+	SystemCPUSeconds float64                             `json:"system_cpu_seconds,omitempty"` // CPU time spent in the kernel
+	PeakRSSBytes     int64                               `json:"peak_rss_bytes,omitempty"`     // Largest peak RSS of a single process
+	Automations      map[string]*AutomationResourceUsage `json:"automations,omitempty"`        // Usage by automation name
+
+	trackCPU    bool
+	trackMemory bool
+	mu          sync.Mutex
+}
+
+// AutomationResourceUsage is the usage of the processes of one automation
+type AutomationResourceUsage struct {
+	Runs           int     `json:"runs"`
+	CPUTimeSeconds float64 `json:"cpu_time_seconds,omitempty"`
+	PeakRSSBytes   int64   `json:"peak_rss_bytes,omitempty"`
+}
+
+// NewJobResourceUsage starts recording the usage of a job, tracking CPU time
+// and memory as enabled
+func NewJobResourceUsage(trackCPU, trackMemory bool) *JobResourceUsage {
+	return &JobResourceUsage{
+		Automations: make(map[string]*AutomationResourceUsage),
+		trackCPU:    trackCPU,
+		trackMemory: trackMemory,
+	}
+}
+
+// newJobResourceUsageFromConfig returns the usage recorder for a job, or nil
+// when neither memory nor CPU tracking is enabled
+func newJobResourceUsageFromConfig(config *Config) *JobResourceUsage {
+	monitoring := config.Monitoring
+	if !monitoring.MemoryUsageTracking && !monitoring.CPUUsageTracking {
+		return nil
+	}
+	return NewJobResourceUsage(monitoring.CPUUsageTracking, monitoring.MemoryUsageTracking)
+}
+
+// addProcess records the usage of an exited automation process
+func (ju *JobResourceUsage) addProcess(name string, state *os.ProcessState) {
+	if state == nil {
+		return
+	}
+	user := state.UserTime().Seconds()
+	system := state.SystemTime().Seconds()
+	peakRSS, _ := peakRSSBytes(state)
+
+	ju.mu.Lock()
+	defer ju.mu.Unlock()
+
+	ju.Processes++
+	automation := ju.automation(name)
+	automation.Runs++
+	if ju.trackCPU {
+		ju.UserCPUSeconds += user
+		ju.SystemCPUSeconds += system
+		ju.CPUTimeSeconds += user + system
+		automation.CPUTimeSeconds += user + system
+	}
+	if ju.trackMemory {
+		ju.PeakRSSBytes = max(ju.PeakRSSBytes, peakRSS)
+		automation.PeakRSSBytes = max(automation.PeakRSSBytes, peakRSS)
+	}
+}
+
+// addPooledRun counts an automation run on a pool worker
+func (ju *JobResourceUsage) addPooledRun(name string) {
+	ju.mu.Lock()
+	defer ju.mu.Unlock()
+
+	ju.PooledRuns++
+	ju.automation(name).Runs++
+}
+
+// automation returns the usage of the named automation; callers hold the lock
+func (ju *JobResourceUsage) automation(name string) *AutomationResourceUsage {
+	if ju.Automations == nil {
+		ju.Automations = make(map[string]*AutomationResourceUsage)
+	}
+	automation, exists := ju.Automations[name]
+	if !exists {
+		automation = &AutomationResourceUsage{}
+		ju.Automations[name] = automation
+	}
+	return automation
+}
+
+// MarshalJSON encodes the usage under its lock, as runs abandoned by a batch
+// timeout may still be recording
+func (ju *JobResourceUsage) MarshalJSON() ([]byte, error) {
+	ju.mu.Lock()
+	defer ju.mu.Unlock()
+
+	type fields struct {
+		Processes        int                                 `json:"processes"`
+		PooledRuns       int                                 `json:"pooled_runs,omitempty"`
+		CPUTimeSeconds   float64                             `json:"cpu_time_seconds,omitempty"`
+		UserCPUSeconds   float64                             `json:"user_cpu_seconds,omitempty"`
+		SystemCPUSeconds float64                             `json:"system_cpu_seconds,omitempty"`
+		PeakRSSBytes     int64                               `json:"peak_rss_bytes,omitempty"`
+		Automations      map[string]*AutomationResourceUsage `json:"automations,omitempty"`
+	}
+	return json.Marshal(fields{
+		Processes:        ju.Processes,
+		PooledRuns:       ju.PooledRuns,
+		CPUTimeSeconds:   ju.CPUTimeSeconds,
+		UserCPUSeconds:   ju.UserCPUSeconds,
+		SystemCPUSeconds: ju.SystemCPUSeconds,
+		PeakRSSBytes:     ju.PeakRSSBytes,
+		Automations:      ju.Automations,
+	})
+}
+
+// WithResourceUsage returns a copy of the engine that records the resource
+// usage of the automations it runs in usage
+func (re *RuleEngine) WithResourceUsage(usage *JobResourceUsage) *RuleEngine {
+	engine := *re
+	engine.resourceUsage = usage
+	return &engine
+}
+
+// automationExited returns the function that records the usage of a process
+// of the named automation, or nil when usage is not recorded
+func (re *RuleEngine) automationExited(name string) func(*os.ProcessState) {
+	if re.resourceUsage == nil {
+		return nil
+	}
+	return func(state *os.ProcessState) {
+		re.resourceUsage.addProcess(name, state)
+	}
+}
+
+// recordPooledRun counts an automation run on a pool worker
+func (re *RuleEngine) recordPooledRun(name string) {
+	if re.resourceUsage != nil {
+		re.resourceUsage.addPooledRun(name)
+	}
+}
+
+// computeResourceStats aggregates the usage recorded for jobs, listing the
+// automations that used the most CPU time first. It returns nil when no job
+// recorded usage.
+func computeResourceStats(jobs []*Job) *JobResourceStats {
+	stats := &JobResourceStats{}
+	automations := make(map[string]*AutomationResourceStats)
+
+	for _, job := range jobs {
+		if job.ResourceUsage == nil {
+			continue
+		}
+		usage := job.ResourceUsage
+		stats.MeasuredJobs++
+		stats.TotalCPUSeconds += usage.CPUTimeSeconds
+		stats.MaxPeakRSSBytes = max(stats.MaxPeakRSSBytes, usage.PeakRSSBytes)
+
+		for name, automationUsage := range usage.Automations {
+			automation, exists := automations[name]
+			if !exists {
+				automation = &AutomationResourceStats{Name: name}
+				automations[name] = automation
+			}
+			automation.Runs += automationUsage.Runs
+			automation.TotalCPUSeconds += automationUsage.CPUTimeSeconds
+			automation.MaxPeakRSSBytes = max(automation.MaxPeakRSSBytes, automationUsage.PeakRSSBytes)
+		}
+	}
+	if stats.MeasuredJobs == 0 {
+		return nil
+	}
+	stats.AvgCPUSeconds = stats.TotalCPUSeconds / float64(stats.MeasuredJobs)
+
+	for _, automation := range automations {
+		if automation.Runs > 0 {
+			automation.AvgCPUSeconds = automation.TotalCPUSeconds / float64(automation.Runs)
+		}
+		stats.TopAutomations = append(stats.TopAutomations, *automation)
+	}
+	sort.Slice(stats.TopAutomations, func(i, j int) bool {
+		a, b := stats.TopAutomations[i], stats.TopAutomations[j]
+		if a.TotalCPUSeconds != b.TotalCPUSeconds {
+			return a.TotalCPUSeconds > b.TotalCPUSeconds
+		}
+		if a.MaxPeakRSSBytes != b.MaxPeakRSSBytes {
+			return a.MaxPeakRSSBytes > b.MaxPeakRSSBytes
+		}
+		return a.Name < b.Name
+	})
+	if len(stats.TopAutomations) > maxResourceStatsAutomations {
+		stats.TopAutomations = stats.TopAutomations[:maxResourceStatsAutomations]
+	}
+	return stats
+}
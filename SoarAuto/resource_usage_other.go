@@ -0,0 +1,11 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// peakRSSBytes reports that the peak resident set size of a process is not
+// available on this platform
+func peakRSSBytes(state *os.ProcessState) (int64, bool) {
+	return 0, false
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// peakRSSBytes returns the peak resident set size of an exited process, as
+// reported by wait4
+func peakRSSBytes(state *os.ProcessState) (int64, bool) {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok || rusage == nil {
+		return 0, false
+	}
+	// macOS reports ru_maxrss in bytes, Linux and the BSDs in kilobytes
+	if runtime.GOOS == "darwin" || runtime.GOOS == "ios" {
+		return int64(rusage.Maxrss), true
+	}
+	return int64(rusage.Maxrss) * 1024, true
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ResultTruncation reports that a run's results were cut down to the
+// configured size cap, results.max_bytes. It is embedded in jobs and playbook
+// responses, so its fields appear alongside their results.
+type ResultTruncation struct {
+	Truncated bool `json:"truncated,omitempty"`
+	// OriginalSize is the serialized size of the full results in bytes
+	OriginalSize int `json:"original_size,omitempty"`
+	// FullResults is where the full results can be fetched when they were
+	// offloaded, e.g. /results/{id}
+	FullResults string `json:"full_results,omitempty"`
+}
+
+// ResultsResponse is the response for GET /results/{id}
+type ResultsResponse struct {
+	Success   bool          `json:"success"`
+	ID        string        `json:"id"`
+	Results   []interface{} `json:"results"`
+	Timestamp string        `json:"timestamp"`
+}
+
+// resultIDPattern matches the IDs results are offloaded under: job IDs and
+// sync-<uuid> for synchronous runs
+var resultIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
+// newSyncResultsID returns the ID a synchronous run's results are offloaded
+// under
+func newSyncResultsID() string {
+	return "sync-" + uuid.NewString()
+}
+
+// ResultStore keeps the full results of truncated runs outside the job store
+type ResultStore interface {
+	WriteResults(id string, results []interface{}) error
+	ReadResults(id string) ([]interface{}, error)
+}
+
+// NewResultStore creates the offload backend described by the results config
+func NewResultStore(config ResultsConfig) (ResultStore, error) {
+	switch config.Backend {
+	case "", "filesystem":
+		directory := config.Directory
+		if directory == "" {
+			directory = filepath.Join("data", "results")
+		}
+		return NewFileResultStore(directory)
+	case "s3", "gcs":
+		objectStore, err := NewObjectStore(config.Backend, config.ObjectStorage)
+		if err != nil {
+			return nil, err
+		}
+		return NewObjectResultStore(objectStore), nil
+	default:
+		return nil, fmt.Errorf("unknown results backend: %s", config.Backend)
+	}
+}
+
+// FileResultStore stores each run's full results as a JSON file
+type FileResultStore struct {
+	directory string
+}
+
+// NewFileResultStore creates a filesystem result store rooted at directory
+func NewFileResultStore(directory string) (*FileResultStore, error) {
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create results directory: %v", err)
+	}
+	return &FileResultStore{directory: directory}, nil
+}
+
+// filePath returns the file holding the results stored under id
+func (frs *FileResultStore) filePath(id string) (string, error) {
+	if !resultIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid results ID: %s", id)
+	}
+	return filepath.Join(frs.directory, id+".json"), nil
+}
+
+// WriteResults stores results under id, replacing any stored before
+func (frs *FileResultStore) WriteResults(id string, results []interface{}) error {
+	path, err := frs.filePath(id)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %v", err)
+	}
+	// Write then rename so a reader never sees a partial file
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write results: %v", err)
+	}
+	return os.Rename(temp, path)
+}
+
+// ReadResults returns the results stored under id; the error satisfies
+// os.IsNotExist when there are none
+func (frs *FileResultStore) ReadResults(id string) ([]interface{}, error) {
+	path, err := frs.filePath(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []interface{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %v", err)
+	}
+	return results, nil
+}
+
+// ObjectResultStore stores each run's full results as a JSON object in an
+// S3 or GCS bucket
+type ObjectResultStore struct {
+	objects *ObjectStore
+}
+
+// NewObjectResultStore creates a result store on an object store
+func NewObjectResultStore(objects *ObjectStore) *ObjectResultStore {
+	return &ObjectResultStore{objects: objects}
+}
+
+// objectKey returns the key of the object holding the results stored under id
+func (ors *ObjectResultStore) objectKey(id string) (string, error) {
+	if !resultIDPattern.MatchString(id) {
+		return "", fmt.Errorf("invalid results ID: %s", id)
+	}
+	return id + ".json", nil
+}
+
+// WriteResults stores results under id, replacing any stored before
+func (ors *ObjectResultStore) WriteResults(id string, results []interface{}) error {
+	key, err := ors.objectKey(id)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(results)
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %v", err)
+	}
+	return ors.objects.Put(key, data)
+}
+
+// ReadResults returns the results stored under id; the error satisfies
+// os.IsNotExist when there are none
+func (ors *ObjectResultStore) ReadResults(id string) ([]interface{}, error) {
+	key, err := ors.objectKey(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ors.objects.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	var results []interface{}
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %v", err)
+	}
+	return results, nil
+}
+
+// ResultCap truncates results larger than results.max_bytes, so one run that
+// returns, say, a full enumeration does not bloat the job store and every
+// response carrying the job
+type ResultCap struct {
+	maxBytes int
+	store    ResultStore // nil unless results.offload is set
+}
+
+// resultCap is the cap configured by results; nil when results are not capped
+var resultCap *ResultCap
+
+// NewResultCap creates the result size cap. It returns nil when
+// results.max_bytes is not set.
+func NewResultCap(config ResultsConfig) (*ResultCap, error) {
+	if config.MaxBytes <= 0 {
+		return nil, nil
+	}
+	rc := &ResultCap{maxBytes: config.MaxBytes}
+	if config.Offload {
+		store, err := NewResultStore(config)
+		if err != nil {
+			return nil, err
+		}
+		rc.store = store
+	}
+	return rc, nil
+}
+
+// Apply returns results cut down to the cap and a report of the truncation.
+// Whole results are kept in order while they fit, so the results returned
+// are always valid, and the full results are offloaded under id when
+// offloading is enabled. Results within the cap are returned unchanged.
+func (rc *ResultCap) Apply(id string, results []interface{}) ([]interface{}, ResultTruncation) {
+	if rc == nil || len(results) == 0 {
+		return results, ResultTruncation{}
+	}
+
+	sizes := make([]int, len(results))
+	total := len("[]") + len(results) - 1 // Brackets and separating commas
+	for i, result := range results {
+		encoded, err := json.Marshal(result)
+		if err != nil {
+			// Leave results that cannot be measured to fail where they are stored
+			return results, ResultTruncation{}
+		}
+		sizes[i] = len(encoded)
+		total += sizes[i]
+	}
+	if total <= rc.maxBytes {
+		return results, ResultTruncation{}
+	}
+
+	kept, size := 0, len("[]")
+	for kept < len(results) {
+		next := size + sizes[kept]
+		if kept > 0 {
+			next++
+		}
+		if next > rc.maxBytes {
+			break
+		}
+		size = next
+		kept++
+	}
+
+	truncation := ResultTruncation{Truncated: true, OriginalSize: total}
+	if rc.store != nil {
+		if err := rc.store.WriteResults(id, results); err != nil {
+			logger.Error("Failed to offload full results", map[string]interface{}{
+				"component": "result_cap",
+				"id":        id,
+				"error":     err.Error(),
+			})
+		} else {
+			truncation.FullResults = "/results/" + id
+		}
+	}
+
+	logger.Warning("Results truncated to size cap", map[string]interface{}{
+		"component":     "result_cap",
+		"id":            id,
+		"original_size": total,
+		"max_bytes":     rc.maxBytes,
+		"kept":          kept,
+		"results":       len(results),
+		"offloaded":     truncation.FullResults != "",
+	})
+	return results[:kept:kept], truncation
+}
+
+// resultsHandler handles GET /results/{id}, returning the full results a
+// truncated run offloaded
+func (s *SecAutoServer) resultsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.URL.Path[len("/results/"):]
+	if !resultIDPattern.MatchString(id) {
+		http.Error(w, "Invalid results ID", http.StatusBadRequest)
+		return
+	}
+	if resultCap == nil || resultCap.store == nil {
+		http.Error(w, "Result offloading is not enabled", http.StatusNotFound)
+		return
+	}
+
+	results, err := resultCap.store.ReadResults(id)
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "Results not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, fmt.Sprintf("Failed to read results: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if redacted, ok := outputRedactor.RedactValue(results).([]interface{}); ok {
+		results = redacted
+	}
+
+	response := ResultsResponse{
+		Success:   true,
+		ID:        id,
+		Results:   results,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
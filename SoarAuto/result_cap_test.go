@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestResultStoreBackends(t *testing.T) {
+	fileStore, err := NewResultStore(ResultsConfig{Backend: "filesystem", Directory: t.TempDir()})
+	if err != nil {
+		t.Fatalf("filesystem backend: %v", err)
+	}
+	s3Store, fake := newTestObjectStore(t, "s3")
+	gcsStore, _ := newTestObjectStore(t, "gcs")
+	stores := map[string]ResultStore{
+		"filesystem": fileStore,
+		"s3":         NewObjectResultStore(s3Store),
+		"gcs":        NewObjectResultStore(gcsStore),
+	}
+
+	results := []interface{}{map[string]interface{}{"verdict": "malicious", "score": float64(97)}, "done"}
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if err := store.WriteResults("job-1", results); err != nil {
+				t.Fatalf("write results: %v", err)
+			}
+			got, err := store.ReadResults("job-1")
+			if err != nil {
+				t.Fatalf("read results: %v", err)
+			}
+			if !reflect.DeepEqual(got, results) {
+				t.Errorf("results = %v, want %v", got, results)
+			}
+
+			if _, err := store.ReadResults("job-2"); !os.IsNotExist(err) {
+				t.Errorf("missing results error = %v, want not exist", err)
+			}
+			if err := store.WriteResults("../escape", results); err == nil {
+				t.Error("results ID with a path was accepted")
+			}
+		})
+	}
+
+	if _, exists := fake.objects["secauto/test/job-1.json"]; !exists {
+		t.Errorf("s3 results not stored as an object under the prefix: %v", fake.objects)
+	}
+}
+
+func TestNewResultStoreRejectsUnknownBackend(t *testing.T) {
+	if _, err := NewResultStore(ResultsConfig{Backend: "s3"}); err == nil {
+		t.Error("s3 backend without a bucket was accepted")
+	}
+	if _, err := NewResultStore(ResultsConfig{Backend: "ftp"}); err == nil {
+		t.Error("unknown backend was accepted")
+	}
+}
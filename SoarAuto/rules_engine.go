@@ -6,67 +6,133 @@ import (
 	"os"
 	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 	"unicode"
 )
 
-// RuleEngine represents the SOAR rules engine
+// RuleEngine represents the SOAR rules engine.
+// The engine holds no per-execution state: the context is passed explicitly to
+// each evaluation so one engine can safely run playbooks concurrently.
 type RuleEngine struct {
-	config        *Config
-	context       map[string]interface{}
-	pluginManager *PlatformPluginManager
+	config             *Config
+	pluginManager      *PlatformPluginManager
+	jsonLogic          bool              // Evaluate core operators per the JSONLogic spec
+	strictTemplates    bool              // Fail rules that reference unresolved template variables
+	maxCollection      int               // Largest collection one operation may work on
+	maxCheckpoints     int               // Most context checkpoints one execution may hold
+	maxCheckpointBytes int               // Total size of one execution's checkpoints, in bytes
+	maxContextDepth    int               // Deepest nesting allowed in a context
+	maxContextValues   int               // Most values a context may hold in total
+	env                map[string]string // Extra environment variables for Python automations
+	integrations       *IntegrationConfigManager
+	transformers       []PlaybookTransformer    // Registered in addition to the built-in transformers
+	enrichmentCache    *EnrichmentCache         // Caches lookups that declare a cache key; nil when disabled
+	splunkEvents       *splunkEventBuffer       // Set on the per-execution copy made by EvaluatePlaybook
+	elasticsearchBulk  *elasticsearchBulkBuffer // Bulk-indexed documents of the current execution
+	templateWarnings   *TemplateWarnings        // Unresolved template variables of the current execution
+	provenance         *ExecutionProvenance     // Code run by the current execution; nil when not recorded
+	checkpoints        *contextCheckpoints      // Context snapshots of the current execution
+	throttle           *PlaybookThrottle        // Rate limits throttle operations across jobs
+	executionDeadline  time.Time                // When the current execution exceeds max_execution_time
+	continueOnError    bool                     // Rules without their own continue_on_error flag continue after failing
+	ruleFailures       *ruleFailures            // Rules of the current execution that failed and were allowed to
+	resourceUsage      *JobResourceUsage        // CPU and memory used by the current execution's automations; nil when not recorded
+	strictComparisons  bool                     // Comparing values of different types is an error
+	lenientOperations  bool                     // Unknown operations evaluate to themselves instead of failing
+	jobManager         *JobManager              // Submits play_async jobs; nil in standalone runs
+	findings           *PlaybookFindings        // Findings recorded by the current execution
+}
+
+// Statuses a playbook may finish with when it aborts deliberately
+const (
+	abortStatusAborted   = "aborted"
+	abortStatusSkipped   = "skipped"
+	abortStatusCompleted = "completed"
+)
+
+// PlaybookAbort is returned by EvaluatePlaybook when an "abort" operation
+// stops the playbook early. It is a deliberate exit, not a failure: callers
+// record Status as the job's terminal status rather than "failed".
+type PlaybookAbort struct {
+	Status string
+	Reason string
+}
+
+func (pa *PlaybookAbort) Error() string {
+	if pa.Reason == "" {
+		return fmt.Sprintf("playbook aborted with status %s", pa.Status)
+	}
+	return fmt.Sprintf("playbook aborted with status %s: %s", pa.Status, pa.Reason)
+}
+
+// isAbortStatus reports whether status is a terminal status set by an abort
+func isAbortStatus(status string) bool {
+	return status == abortStatusAborted || status == abortStatusSkipped
 }
 
 // NewRuleEngine creates a new rule engine instance
 func NewRuleEngine(config *Config) *RuleEngine {
 	return &RuleEngine{
-		config:        config,
-		context:       make(map[string]interface{}),
-		pluginManager: nil, // Will be set by SetPluginManager
+		config:             config,
+		pluginManager:      nil, // Will be set by SetPluginManager
+		jsonLogic:          config.RulesEngine.JSONLogicMode,
+		strictTemplates:    config.RulesEngine.StrictTemplates,
+		maxCollection:      config.RulesEngine.MaxCollectionSize,
+		maxCheckpoints:     config.RulesEngine.MaxCheckpoints,
+		maxCheckpointBytes: config.RulesEngine.MaxCheckpointBytes,
+		maxContextDepth:    config.RulesEngine.MaxContextDepth,
+		maxContextValues:   config.RulesEngine.MaxContextValues,
 	}
 }
 
-// SetContext sets the context for the rule engine
-func (re *RuleEngine) SetContext(context map[string]interface{}) {
+// defaultMaxCollectionSize applies when rules_engine.max_collection_size is unset
+const defaultMaxCollectionSize = 100000
+
+// checkCollectionSize fails an operation asked to work on a collection larger
+// than max_collection_size, before it spends memory processing it
+func (re *RuleEngine) checkCollectionSize(op string, size int) error {
+	limit := re.maxCollection
+	if limit <= 0 {
+		limit = defaultMaxCollectionSize
+	}
+	if size > limit {
+		return fmt.Errorf("%s: collection of %d elements exceeds max_collection_size of %d", op, size, limit)
+	}
+	return nil
+}
+
+// NewPlaybookContext builds the flat execution context for a playbook run
+func NewPlaybookContext(context map[string]interface{}) map[string]interface{} {
 	logger.Info("Setting context", map[string]interface{}{
 		"component": "rules_engine",
 		"context":   context,
 	})
 
-	// Create a single, flat context object
-	re.context = make(map[string]interface{})
-
-	// If context already has a nested structure, extract and merge it
 	if nestedContext, exists := context["context"]; exists {
 		logger.Info("Found nested context", map[string]interface{}{
 			"component": "rules_engine",
 			"nested":    nestedContext,
 		})
-		if contextMap, ok := nestedContext.(map[string]interface{}); ok {
-			// Merge nested context into flat structure
-			for k, v := range contextMap {
-				re.context[k] = v
-			}
-		}
 	} else {
 		logger.Info("No nested context found, using direct context", map[string]interface{}{
 			"component": "rules_engine",
 		})
-		// No nested context, merge all data directly into the flat context
-		for k, v := range context {
-			re.context[k] = v
-		}
 	}
 
+	// Create a single, flat context object
+	flatContext := flattenPlaybookContext(context)
+
 	logger.Info("Final context", map[string]interface{}{
 		"component":    "rules_engine",
-		"context":      re.context,
-		"context_keys": len(re.context),
-		"has_incident": re.context["incident"] != nil,
+		"context":      flatContext,
+		"context_keys": len(flatContext),
+		"has_incident": flatContext["incident"] != nil,
 	})
 
 	// Log the incident object specifically
-	if incident, exists := re.context["incident"]; exists {
+	if incident, exists := flatContext["incident"]; exists {
 		logger.Info("Incident object found", map[string]interface{}{
 			"component":     "rules_engine",
 			"incident":      incident,
@@ -86,11 +152,40 @@ func (re *RuleEngine) SetContext(context map[string]interface{}) {
 			"component": "rules_engine",
 		})
 	}
+
+	return flatContext
+}
+
+// flattenPlaybookContext merges a nested "context" object, if present, into a
+// single flat map; otherwise the top-level keys are copied as-is
+func flattenPlaybookContext(context map[string]interface{}) map[string]interface{} {
+	flatContext := make(map[string]interface{})
+
+	if nestedContext, exists := context["context"]; exists {
+		if contextMap, ok := nestedContext.(map[string]interface{}); ok {
+			for k, v := range contextMap {
+				flatContext[k] = v
+			}
+		}
+		return flatContext
+	}
+
+	for k, v := range context {
+		flatContext[k] = v
+	}
+	return flatContext
 }
 
-// GetContext returns the current context
-func (re *RuleEngine) GetContext() map[string]interface{} {
-	return re.context
+// WithEnv returns a copy of the engine that passes env to Python automations.
+// The copy shares the configuration and plugin manager, so it is cheap to
+// create per execution.
+func (re *RuleEngine) WithEnv(env map[string]string) *RuleEngine {
+	if len(env) == 0 {
+		return re
+	}
+	engine := *re
+	engine.env = env
+	return &engine
 }
 
 // SetPluginManager sets the plugin manager for the rule engine
@@ -98,15 +193,86 @@ func (re *RuleEngine) SetPluginManager(pluginManager *PlatformPluginManager) {
 	re.pluginManager = pluginManager
 }
 
-// EvaluateRule evaluates a single rule
-func (re *RuleEngine) EvaluateRule(rule interface{}) (interface{}, error) {
-	return re.evaluate(rule, re.context)
+// SetIntegrationConfigManager sets the integration configs used by output operations
+func (re *RuleEngine) SetIntegrationConfigManager(integrations *IntegrationConfigManager) {
+	re.integrations = integrations
 }
 
-// EvaluatePlaybook evaluates a playbook (array of rules)
-func (re *RuleEngine) EvaluatePlaybook(playbook []interface{}) ([]interface{}, error) {
+// SetEnrichmentCache sets the cache shared by run and plugin lookups
+func (re *RuleEngine) SetEnrichmentCache(cache *EnrichmentCache) {
+	re.enrichmentCache = cache
+}
+
+// EvaluateRule evaluates a single rule against the given context
+func (re *RuleEngine) EvaluateRule(rule interface{}, context map[string]interface{}) (interface{}, error) {
+	return re.evaluatePlaybookRule(rule, context)
+}
+
+// EvaluatePlaybook evaluates a playbook (array of rules). The context is
+// updated in place with the data produced by automations and plugins.
+func (re *RuleEngine) EvaluatePlaybook(playbook []interface{}, context map[string]interface{}) ([]interface{}, error) {
+	// The outermost playbook gets an engine copy that buffers its Splunk
+	// events and Elasticsearch bulk documents and collects its template
+	// warnings; nested playbooks share them
+	if re.splunkEvents == nil {
+		engine := *re
+		engine.splunkEvents = newSplunkEventBuffer(&engine)
+		engine.elasticsearchBulk = newElasticsearchBulkBuffer(&engine)
+		if engine.templateWarnings == nil {
+			engine.templateWarnings = NewTemplateWarnings()
+		}
+		if engine.checkpoints == nil {
+			engine.checkpoints = newContextCheckpoints()
+		}
+		if engine.ruleFailures == nil {
+			engine.ruleFailures = &ruleFailures{}
+		}
+		if engine.findings == nil {
+			engine.findings = NewPlaybookFindings()
+		}
+		if engine.executionDeadline.IsZero() && engine.config != nil && engine.config.RulesEngine.MaxExecutionTime > 0 {
+			engine.executionDeadline = time.Now().Add(time.Duration(engine.config.RulesEngine.MaxExecutionTime) * time.Second)
+		}
+		if err := engine.applyDefaultContext(context); err != nil {
+			return nil, err
+		}
+		if err := engine.checkContextBounds(context); err != nil {
+			return nil, err
+		}
+		results, err := engine.EvaluatePlaybook(playbook, context)
+		if flushErr := engine.splunkEvents.Flush(); flushErr != nil {
+			logger.Error("Failed to send Splunk events", map[string]interface{}{
+				"component": "rules_engine",
+				"error":     flushErr.Error(),
+			})
+			if err == nil {
+				return nil, fmt.Errorf("failed to send Splunk events: %v", flushErr)
+			}
+		}
+		if flushErr := engine.elasticsearchBulk.Flush(); flushErr != nil {
+			logger.Error("Failed to send Elasticsearch bulk documents", map[string]interface{}{
+				"component": "rules_engine",
+				"error":     flushErr.Error(),
+			})
+			if err == nil {
+				return nil, fmt.Errorf("failed to send Elasticsearch bulk documents: %v", flushErr)
+			}
+		}
+		if err == nil {
+			// The playbook ran to the end; report the rules that failed on the way
+			err = engine.ruleFailures.err()
+		}
+		return results, err
+	}
+
 	var results []interface{}
 
+	// Expand, normalize and check the playbook before evaluation
+	playbook, err := re.TransformPlaybook(playbook)
+	if err != nil {
+		return nil, err
+	}
+
 	logger.Info("Evaluating playbook", map[string]interface{}{
 		"component":  "rules_engine",
 		"rule_count": len(playbook),
@@ -118,7 +284,38 @@ func (re *RuleEngine) EvaluatePlaybook(playbook []interface{}) ([]interface{}, e
 			"rule_index": i + 1,
 			"rule":       rule,
 		})
-		result, err := re.evaluate(rule, re.context)
+		rule, continueOnError := re.ruleContinuesOnError(rule)
+		result, err := re.evaluatePlaybookRule(rule, context)
+		if err != nil && continueOnError && re.ruleFailures != nil && isContinuableRuleError(err) {
+			// Record the failure and go on with the next rule
+			logger.Warning("Rule failed, continuing with the next rule", map[string]interface{}{
+				"component":  "rules_engine",
+				"rule_index": i + 1,
+				"error":      err.Error(),
+			})
+			re.ruleFailures.add(i+1, err)
+			results = append(results, failedRuleResult(i+1, err))
+			continue
+		}
+		if abort, ok := err.(*PlaybookAbort); ok {
+			// Deliberate early exit: keep the results gathered so far
+			logger.Info("Playbook aborted", map[string]interface{}{
+				"component":  "rules_engine",
+				"rule_index": i + 1,
+				"status":     abort.Status,
+				"reason":     abort.Reason,
+			})
+			results = append(results, map[string]interface{}{
+				"abort":  abort.Reason,
+				"status": abort.Status,
+			})
+			return results, abort
+		}
+		if assertion, ok := err.(*AssertionError); ok {
+			// Reported unwrapped; the outermost playbook's rule index wins
+			assertion.Rule = i + 1
+			return nil, assertion
+		}
 		if err != nil {
 			logger.Error("Rule evaluation failed", map[string]interface{}{
 				"component":  "rules_engine",
@@ -157,7 +354,35 @@ func (re *RuleEngine) EvaluatePlaybook(playbook []interface{}) ([]interface{}, e
 	return results, nil
 }
 
-// evaluate recursively evaluates JSONLogic expressions
+// evaluatePlaybookRule evaluates a rule as written in a playbook: its
+// template variables are resolved once against data, then it is evaluated.
+// Sub-expressions are not resolved again, so a context value that itself
+// contains {{...}} is used as-is rather than expanded. With strict templates
+// a rule referencing a missing variable fails instead of running with the
+// literal placeholder.
+func (re *RuleEngine) evaluatePlaybookRule(rule interface{}, data map[string]interface{}) (interface{}, error) {
+	var mark int
+	if re.strictTemplates && re.templateWarnings != nil {
+		mark = re.templateWarnings.mark()
+	}
+	processedRule := re.processTemplateVariables(rule, data)
+	if re.strictTemplates && re.templateWarnings != nil {
+		if missing := re.templateWarnings.since(mark); len(missing) > 0 {
+			return nil, unresolvedVariablesError(missing)
+		}
+	}
+
+	logger.Debug("Template variable processing", map[string]interface{}{
+		"component":      "rules_engine",
+		"original_rule":  rule,
+		"processed_rule": processedRule,
+	})
+
+	return re.evaluate(processedRule, data)
+}
+
+// evaluate recursively evaluates JSONLogic expressions whose template
+// variables have already been resolved
 func (re *RuleEngine) evaluate(expr interface{}, data map[string]interface{}) (interface{}, error) {
 	logger.Info("Evaluating expression", map[string]interface{}{
 		"component": "rules_engine",
@@ -169,19 +394,7 @@ func (re *RuleEngine) evaluate(expr interface{}, data map[string]interface{}) (i
 		return nil, nil
 	}
 
-	// Process template variables in the expression
-	processedExpr := re.processTemplateVariables(expr, data)
-
-	logger.Info("Template variable processing in evaluate", map[string]interface{}{
-		"component":       "rules_engine",
-		"original_expr":   expr,
-		"processed_expr":  processedExpr,
-		"data_keys":       len(data),
-		"has_virustotal":  data["virustotal"] != nil,
-		"virustotal_type": fmt.Sprintf("%T", data["virustotal"]),
-	})
-
-	switch v := processedExpr.(type) {
+	switch v := expr.(type) {
 	case map[string]interface{}:
 		logger.Info("Evaluating map operation", map[string]interface{}{
 			"component": "rules_engine",
@@ -194,8 +407,9 @@ func (re *RuleEngine) evaluate(expr interface{}, data map[string]interface{}) (i
 			"array_len": len(v),
 		})
 
-		// Check if this is a comparison operation array (e.g., [">=", {"var": "..."}, 10])
-		if len(v) == 3 {
+		// Check if this is a comparison operation array (e.g., [">=", {"var": "..."}, 10]).
+		// JSONLogic has no such form, so in JSONLogic mode arrays are always literals.
+		if len(v) == 3 && !re.jsonLogic {
 			operator, ok1 := v[0].(string)
 			if ok1 {
 				// Check if it's a comparison operator
@@ -216,8 +430,8 @@ func (re *RuleEngine) evaluate(expr interface{}, data map[string]interface{}) (i
 			}
 		}
 
-		// Handle as regular array
-		var results []interface{}
+		// Handle as regular array; an empty literal stays an empty array
+		results := make([]interface{}, 0, len(v))
 		for _, item := range v {
 			result, err := re.evaluate(item, data)
 			if err != nil {
@@ -243,19 +457,45 @@ func (re *RuleEngine) evaluateOperation(operation map[string]interface{}, data m
 		"operation": operation,
 	})
 
+	// An empty object is a literal, e.g. when comparing against {}
+	if len(operation) == 0 {
+		return operation, nil
+	}
+
+	// In JSONLogic mode core operators follow the spec; extensions keep their behavior
+	if re.jsonLogic && !isJSONLogicExtension(operation) {
+		return re.evaluateJSONLogicOperation(operation, data)
+	}
+
 	// Check for custom operations first
 	if _, exists := operation["run"]; exists {
 		logger.Info("Found run operation", map[string]interface{}{
 			"component": "rules_engine",
 		})
-		return re.evaluateRunOperation(operation["run"], operation, data)
+		start := time.Now()
+		result, err := re.evaluateRunOperation(operation["run"], operation, data)
+		re.recordOperation("run", operation["run"], start, err)
+		return result, err
 	}
 
 	if _, exists := operation["play"]; exists {
 		logger.Info("Found play operation", map[string]interface{}{
 			"component": "rules_engine",
 		})
-		return re.evaluatePlayOperation(operation["play"], data)
+		start := time.Now()
+		result, err := re.evaluatePlayOperation(operation["play"], data)
+		re.recordOperation("play", operation["play"], start, err)
+		return result, err
+	}
+
+	if _, exists := operation["play_async"]; exists {
+		logger.Info("Found play_async operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		start := time.Now()
+		result, err := re.evaluatePlayAsyncOperation(operation["play_async"], data)
+		re.recordOperation("play_async", playAsyncPlaybookName(operation["play_async"]), start, err)
+		return result, err
 	}
 
 	if _, exists := operation["if"]; exists {
@@ -265,11 +505,158 @@ func (re *RuleEngine) evaluateOperation(operation map[string]interface{}, data m
 		return re.evaluateIfOperation(operation["if"], data)
 	}
 
+	if _, exists := operation["switch"]; exists {
+		logger.Info("Found switch operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateSwitchOperation(operation["switch"], data)
+	}
+
 	if _, exists := operation["plugin"]; exists {
 		logger.Info("Found plugin operation", map[string]interface{}{
 			"component": "rules_engine",
 		})
-		return re.evaluatePluginOperation(operation["plugin"], data)
+		start := time.Now()
+		result, err := re.evaluatePluginOperation(operation["plugin"], data)
+		re.recordOperation("plugin", operation["plugin"], start, err)
+		return result, err
+	}
+
+	if _, exists := operation["map"]; exists {
+		logger.Info("Found map operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateMapOperation(operation["map"], operation, data)
+	}
+
+	if _, exists := operation["conditional_set"]; exists {
+		logger.Info("Found conditional_set operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateConditionalSetOperation(operation["conditional_set"], data)
+	}
+
+	if _, exists := operation["foreach"]; exists {
+		logger.Info("Found foreach operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateForeachOperation(operation, data)
+	}
+
+	if _, exists := operation["batch"]; exists {
+		logger.Info("Found batch operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateBatchOperation(operation["batch"], data)
+	}
+
+	if _, exists := operation["vars"]; exists {
+		logger.Info("Found vars operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateVarsOperation(operation["vars"], data)
+	}
+
+	if _, exists := operation["try"]; exists {
+		logger.Info("Found try operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateTryOperation(operation["try"], data)
+	}
+
+	if _, exists := operation["macro_scope"]; exists {
+		return re.evaluateMacroScopeOperation(operation["macro_scope"], data)
+	}
+
+	if _, exists := operation["jq"]; exists {
+		logger.Info("Found jq operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateJQOperation(operation["jq"], operation, data)
+	}
+
+	if _, exists := operation["random"]; exists {
+		logger.Info("Found random operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateRandomOperation(operation["random"], data)
+	}
+
+	if _, exists := operation["checkpoint"]; exists {
+		logger.Info("Found checkpoint operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateCheckpointOperation(operation["checkpoint"], data)
+	}
+
+	if _, exists := operation["restore"]; exists {
+		logger.Info("Found restore operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateRestoreOperation(operation["restore"], data)
+	}
+
+	if _, exists := operation["throttle"]; exists {
+		logger.Info("Found throttle operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateThrottleOperation(operation["throttle"], data)
+	}
+
+	if _, exists := operation["metric"]; exists {
+		logger.Info("Found metric operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateMetricOperation(operation["metric"], data)
+	}
+
+	if _, exists := operation["finding"]; exists {
+		logger.Info("Found finding operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateFindingOperation(operation["finding"], data)
+	}
+
+	if _, exists := operation["group_count"]; exists {
+		logger.Info("Found group_count operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateGroupCountOperation(operation["group_count"], operation, data)
+	}
+
+	if _, exists := operation["context_diff"]; exists {
+		logger.Info("Found context_diff operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateContextDiffOperation(operation["context_diff"], data)
+	}
+
+	if _, exists := operation["abort"]; exists {
+		logger.Info("Found abort operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateAbortOperation(operation["abort"])
+	}
+
+	if _, exists := operation["assert"]; exists {
+		logger.Info("Found assert operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateAssertOperation(operation["assert"], data)
+	}
+
+	if _, exists := operation["splunk_log"]; exists {
+		logger.Info("Found splunk_log operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateSplunkLogOperation(operation["splunk_log"])
+	}
+
+	if _, exists := operation["elasticsearch_index"]; exists {
+		logger.Info("Found elasticsearch_index operation", map[string]interface{}{
+			"component": "rules_engine",
+		})
+		return re.evaluateElasticsearchIndexOperation(operation["elasticsearch_index"], data)
 	}
 
 	// Check for variable operations
@@ -289,6 +676,18 @@ func (re *RuleEngine) evaluateOperation(operation map[string]interface{}, data m
 				"operator":  op,
 			})
 			return re.evaluateComparison(operation, op, data)
+		case "in", "not_in":
+			return re.evaluateMembership(operation, op, data)
+		case "between":
+			return re.evaluateBetween(operation, data)
+		}
+	}
+
+	// Check for array element operations
+	for op := range operation {
+		switch op {
+		case "first", "last", "nth":
+			return re.evaluateArrayElement(operation, op, data)
 		}
 	}
 
@@ -304,11 +703,7 @@ func (re *RuleEngine) evaluateOperation(operation map[string]interface{}, data m
 		}
 	}
 
-	logger.Error("Unknown operation", map[string]interface{}{
-		"component": "rules_engine",
-		"operation": operation,
-	})
-	return nil, fmt.Errorf("unknown operation: %v", operation)
+	return re.unknownOperation(operation)
 }
 
 // evaluateRunOperation handles the "run" operation
@@ -326,25 +721,19 @@ func (re *RuleEngine) evaluateRunOperation(scriptName interface{}, operation map
 
 	logger.Debug("Context before Python script", map[string]interface{}{
 		"component": "rules_engine",
-		"context":   re.context,
+		"context":   data,
 	})
 
-	// Process template variables in the operation parameters before passing to Python script
-	// This ensures that parameters like "urls": "{{threat_intelligence.domains}}" are resolved
-	processedOperation := re.processTemplateVariables(operation, re.context)
-
-	// Merge the processed operation parameters with the context data
+	// Merge the operation parameters with the context data. Template
+	// variables in them, such as "urls": "{{threat_intelligence.domains}}",
+	// were resolved with the rule.
 	processedData := make(map[string]interface{})
 	for k, v := range data {
 		processedData[k] = v
 	}
-
-	// Type assert processedOperation to map for merging
-	if processedOperationMap, ok := processedOperation.(map[string]interface{}); ok {
-		for k, v := range processedOperationMap {
-			if k != "run" { // Don't override the script name
-				processedData[k] = v
-			}
+	for k, v := range operation {
+		if k != "run" && k != "cache" { // Don't override the script name
+			processedData[k] = v
 		}
 	}
 
@@ -357,35 +746,15 @@ func (re *RuleEngine) evaluateRunOperation(scriptName interface{}, operation map
 		"urls_value":     processedData["urls"],
 	})
 
-	// Pass the processed context to Python scripts
-	outputBytes, err := RunPythonFromVenvWithJSONSeparateOutput(re.config.GetVenvPath(), scriptPath, processedData)
+	// Pass the processed context to Python scripts, unless an identical
+	// lookup is cached
+	output, cached, err := re.cachedEnrichment("run", scriptNameStr, operation["cache"], func() (interface{}, error) {
+		return re.runScript(scriptNameStr, scriptPath, processedData)
+	})
 	if err != nil {
-		logger.Error("Python script execution failed", map[string]interface{}{
-			"component": "rules_engine",
-			"script":    scriptNameStr,
-			"error":     err.Error(),
-		})
-		return nil, fmt.Errorf("failed to run Python script %s: %v", scriptNameStr, err)
-	}
-
-	// Parse the raw JSON output from the Python script
-	var resultData map[string]interface{}
-	if err := json.Unmarshal(outputBytes, &resultData); err != nil {
-		// Try to clean the output by removing any non-JSON content
-		outputStr := string(outputBytes)
-		cleanedOutput := cleanPythonOutput(outputStr)
-
-		if err := json.Unmarshal([]byte(cleanedOutput), &resultData); err != nil {
-			logger.Error("Failed to parse Python script output", map[string]interface{}{
-				"component": "rules_engine",
-				"script":    scriptNameStr,
-				"error":     err.Error(),
-				"output":    string(outputBytes),
-				"cleaned":   cleanedOutput,
-			})
-			return nil, fmt.Errorf("failed to parse Python script output: %v", err)
-		}
+		return nil, err
 	}
+	resultData, _ := output.(map[string]interface{})
 
 	logger.Debug("Python script output structure", map[string]interface{}{
 		"component":   "rules_engine",
@@ -394,6 +763,10 @@ func (re *RuleEngine) evaluateRunOperation(scriptName interface{}, operation map
 
 	// Merge the result into the context
 	if resultData != nil {
+		if err := re.checkContextMerge(data, resultData); err != nil {
+			return nil, fmt.Errorf("result of automation %s: %v", scriptNameStr, err)
+		}
+
 		logger.Debug("Merging Python script result", map[string]interface{}{
 			"component": "rules_engine",
 			"result":    resultData,
@@ -406,10 +779,10 @@ func (re *RuleEngine) evaluateRunOperation(scriptName interface{}, operation map
 				"updates":   incidentUpdates,
 			})
 
-			if re.context["incident"] == nil {
-				re.context["incident"] = make(map[string]interface{})
+			if data["incident"] == nil {
+				data["incident"] = make(map[string]interface{})
 			}
-			if incidentMap, ok := re.context["incident"].(map[string]interface{}); ok {
+			if incidentMap, ok := data["incident"].(map[string]interface{}); ok {
 				if updatesMap, ok := incidentUpdates.(map[string]interface{}); ok {
 					for k, v := range updatesMap {
 						incidentMap[k] = v
@@ -427,12 +800,12 @@ func (re *RuleEngine) evaluateRunOperation(scriptName interface{}, operation map
 
 		// Merge remaining context data directly into the flat context structure
 		for k, v := range resultData {
-			re.context[k] = v
+			data[k] = v
 		}
 
 		logger.Debug("Context after Python script merge", map[string]interface{}{
 			"component": "rules_engine",
-			"context":   re.context,
+			"context":   data,
 		})
 	}
 
@@ -443,10 +816,79 @@ func (re *RuleEngine) evaluateRunOperation(scriptName interface{}, operation map
 
 	// Python scripts update context but don't return results to be added to the results array
 	// Return a simple success indicator instead of the full context
-	return map[string]interface{}{
+	result := map[string]interface{}{
 		"script": scriptNameStr,
 		"status": "completed",
-	}, nil
+	}
+	if cached {
+		result["cached"] = true
+	}
+	return result, nil
+}
+
+// runScript runs a Python script and parses the JSON object it prints
+func (re *RuleEngine) runScript(scriptName, scriptPath string, processedData map[string]interface{}) (map[string]interface{}, error) {
+	re.recordAutomation(scriptName, scriptPath)
+
+	limiter := getPythonProcessLimiter(re.config.Python.MaxConcurrentProcesses)
+	if waited := limiter.Acquire(); waited > 0 {
+		logger.Debug("Waited for a free Python process slot", map[string]interface{}{
+			"component": "rules_engine",
+			"script":    scriptName,
+			"waited_ms": waited.Milliseconds(),
+		})
+	}
+	defer limiter.Release()
+
+	// Each run writes its temporary files to a private directory, removed
+	// once the script has exited
+	workDir, cleanup, err := newExecutionWorkDir(re.config.Python.WorkDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create working directory for Python script %s: %v", scriptName, err)
+	}
+	defer cleanup()
+	env := withWorkDir(re.env, workDir)
+
+	var outputBytes []byte
+	err = errPythonPoolUnavailable
+	if re.config.UsesPythonPool(scriptName) {
+		pool := getPythonPool(re.config.GetVenvPath(), re.config.Python.Pool)
+		outputBytes, err = pool.Run(scriptPath, processedData, env)
+		if err != errPythonPoolUnavailable {
+			re.recordPooledRun(scriptName)
+		}
+	}
+	if err == errPythonPoolUnavailable {
+		outputBytes, err = RunPythonFromVenvWithContext(re.config.GetVenvPath(), scriptPath, processedData, env, re.config.GetContextPassing(scriptName), re.automationExited(scriptName))
+	}
+	if err != nil {
+		logger.Error("Python script execution failed", map[string]interface{}{
+			"component": "rules_engine",
+			"script":    scriptName,
+			"error":     err.Error(),
+		})
+		return nil, fmt.Errorf("failed to run Python script %s: %v", scriptName, err)
+	}
+
+	// Parse the raw JSON output from the Python script
+	var resultData map[string]interface{}
+	if err := unmarshalContextJSON(outputBytes, &resultData); err != nil {
+		// Try to clean the output by removing any non-JSON content
+		outputStr := string(outputBytes)
+		cleanedOutput := cleanPythonOutput(outputStr)
+
+		if err := unmarshalContextJSON([]byte(cleanedOutput), &resultData); err != nil {
+			logger.Error("Failed to parse Python script output", map[string]interface{}{
+				"component": "rules_engine",
+				"script":    scriptName,
+				"error":     err.Error(),
+				"output":    string(outputBytes),
+				"cleaned":   cleanedOutput,
+			})
+			return nil, fmt.Errorf("failed to parse Python script output: %v", err)
+		}
+	}
+	return resultData, nil
 }
 
 // evaluatePlayOperation handles the "play" operation
@@ -463,6 +905,7 @@ func (re *RuleEngine) evaluatePlayOperation(playbookName interface{}, data map[s
 	})
 
 	// Load and evaluate the nested playbook
+	re.recordPlaybook(playbookNameStr, playbookPath)
 	playbookData, err := re.LoadPlaybookFromFile(playbookPath)
 	if err != nil {
 		logger.Error("Failed to load playbook", map[string]interface{}{
@@ -473,7 +916,14 @@ func (re *RuleEngine) evaluatePlayOperation(playbookName interface{}, data map[s
 		return nil, fmt.Errorf("failed to load playbook %s: %v", playbookNameStr, err)
 	}
 
-	results, err := re.EvaluatePlaybook(playbookData)
+	results, err := re.EvaluatePlaybook(playbookData, data)
+	if abort, ok := err.(*PlaybookAbort); ok {
+		// An abort in a nested playbook stops the calling playbook too
+		return nil, abort
+	}
+	if assertion, ok := err.(*AssertionError); ok {
+		return nil, assertion
+	}
 	if err != nil {
 		logger.Error("Failed to evaluate playbook", map[string]interface{}{
 			"component": "rules_engine",
@@ -499,6 +949,7 @@ func (re *RuleEngine) evaluatePluginOperation(pluginExpr interface{}, data map[s
 	// Parse plugin expression
 	var pluginName string
 	var params map[string]interface{}
+	var cacheSpec interface{}
 
 	switch v := pluginExpr.(type) {
 	case string:
@@ -512,6 +963,7 @@ func (re *RuleEngine) evaluatePluginOperation(pluginExpr interface{}, data map[s
 		} else {
 			return nil, fmt.Errorf("plugin name is required")
 		}
+		cacheSpec = v["cache"]
 
 		// Extract parameters
 		if pluginParams, ok := v["params"].(map[string]interface{}); ok {
@@ -534,8 +986,11 @@ func (re *RuleEngine) evaluatePluginOperation(pluginExpr interface{}, data map[s
 		"params":    params,
 	})
 
-	// Execute the plugin
-	result, err := re.pluginManager.ExecutePlugin(pluginName, params)
+	// Execute the plugin, unless an identical lookup is cached
+	result, cached, err := re.cachedEnrichment("plugin", pluginName, cacheSpec, func() (interface{}, error) {
+		re.recordPlugin(pluginName)
+		return re.pluginManager.ExecutePlugin(pluginName, params)
+	})
 	if err != nil {
 		logger.Error("Plugin execution failed", map[string]interface{}{
 			"component": "rules_engine",
@@ -547,6 +1002,10 @@ func (re *RuleEngine) evaluatePluginOperation(pluginExpr interface{}, data map[s
 
 	// Merge plugin result into context if it's a map
 	if resultMap, ok := result.(map[string]interface{}); ok {
+		if err := re.checkContextMerge(data, resultMap); err != nil {
+			return nil, fmt.Errorf("result of plugin %s: %v", pluginName, err)
+		}
+
 		logger.Debug("Merging plugin result", map[string]interface{}{
 			"component": "rules_engine",
 			"result":    resultMap,
@@ -559,10 +1018,10 @@ func (re *RuleEngine) evaluatePluginOperation(pluginExpr interface{}, data map[s
 				"updates":   incidentUpdates,
 			})
 
-			if re.context["incident"] == nil {
-				re.context["incident"] = make(map[string]interface{})
+			if data["incident"] == nil {
+				data["incident"] = make(map[string]interface{})
 			}
-			if incidentMap, ok := re.context["incident"].(map[string]interface{}); ok {
+			if incidentMap, ok := data["incident"].(map[string]interface{}); ok {
 				if updatesMap, ok := incidentUpdates.(map[string]interface{}); ok {
 					for k, v := range updatesMap {
 						incidentMap[k] = v
@@ -580,7 +1039,7 @@ func (re *RuleEngine) evaluatePluginOperation(pluginExpr interface{}, data map[s
 
 		// Merge remaining context data directly into the flat context structure
 		for k, v := range resultMap {
-			re.context[k] = v
+			data[k] = v
 		}
 	}
 
@@ -589,11 +1048,15 @@ func (re *RuleEngine) evaluatePluginOperation(pluginExpr interface{}, data map[s
 		"plugin":    pluginName,
 	})
 
-	return map[string]interface{}{
+	response := map[string]interface{}{
 		"plugin": pluginName,
 		"status": "completed",
 		"result": result,
-	}, nil
+	}
+	if cached {
+		response["cached"] = true
+	}
+	return response, nil
 }
 
 // LoadPlaybookFromFile loads a playbook from a JSON file
@@ -671,6 +1134,479 @@ func (re *RuleEngine) evaluateIfOperation(ifExpr interface{}, data map[string]in
 	return nil, nil
 }
 
+// evaluateAbortOperation handles the "abort" operation, which stops the
+// playbook with an optional reason and terminal status (default "aborted")
+func (re *RuleEngine) evaluateAbortOperation(spec interface{}) (interface{}, error) {
+	abort := &PlaybookAbort{Status: abortStatusAborted}
+
+	switch v := spec.(type) {
+	case nil:
+	case string:
+		abort.Reason = v
+	case map[string]interface{}:
+		if reason, exists := v["reason"]; exists && reason != nil {
+			abort.Reason = fmt.Sprintf("%v", reason)
+		}
+		if status, exists := v["status"]; exists {
+			statusStr, ok := status.(string)
+			if !ok {
+				return nil, fmt.Errorf("abort status must be a string")
+			}
+			switch statusStr {
+			case abortStatusAborted, abortStatusSkipped, abortStatusCompleted:
+				abort.Status = statusStr
+			default:
+				return nil, fmt.Errorf("abort status must be one of %s, %s, %s", abortStatusAborted, abortStatusSkipped, abortStatusCompleted)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("abort operation requires an object or reason string")
+	}
+
+	return nil, abort
+}
+
+// evaluateConditionalSetOperation handles the "conditional_set" operation, which
+// writes value to key only when the optional condition is truthy
+func (re *RuleEngine) evaluateConditionalSetOperation(setExpr interface{}, data map[string]interface{}) (interface{}, error) {
+	setMap, ok := setExpr.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("conditional_set operation requires an object")
+	}
+
+	key, ok := setMap["key"].(string)
+	if !ok || key == "" {
+		return nil, fmt.Errorf("conditional_set operation requires a string key")
+	}
+
+	value, exists := setMap["value"]
+	if !exists {
+		return nil, fmt.Errorf("conditional_set operation requires a value")
+	}
+
+	// A missing condition always applies the set
+	applied := true
+	if condition, exists := setMap["condition"]; exists {
+		result, err := re.evaluate(condition, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate conditional_set condition: %v", err)
+		}
+		applied = re.isTruthy(result)
+	}
+
+	// Operations are allowed as values, e.g. {"var": "..."}
+	if _, isOperation := value.(map[string]interface{}); isOperation {
+		evaluated, err := re.evaluate(value, data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate conditional_set value: %v", err)
+		}
+		value = evaluated
+	}
+
+	if applied {
+		if err := setContextPath(data, strings.Split(key, "."), value); err != nil {
+			return nil, fmt.Errorf("conditional_set failed: %v", err)
+		}
+	}
+
+	logger.Debug("Conditional set evaluated", map[string]interface{}{
+		"component": "rules_engine",
+		"variable":  key,
+		"value":     value,
+	})
+
+	return map[string]interface{}{
+		"conditional_set": key,
+		"applied":         applied,
+		"value":           value,
+	}, nil
+}
+
+// evaluateForeachOperation handles the "foreach" operation, which evaluates
+// its rules once per item with the item stored in the context under "as".
+// The previous value of that variable is restored when the loop ends.
+func (re *RuleEngine) evaluateForeachOperation(operation map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	spec, err := normalizeForeach(operation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid foreach: %v", err)
+	}
+	as := spec["as"].(string)
+	rules := spec["do"].([]interface{})
+
+	evaluated, err := re.evaluatePlaybookRule(spec["items"], data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate foreach items: %v", err)
+	}
+	var items []interface{}
+	switch v := evaluated.(type) {
+	case nil:
+		// Nothing to iterate over
+	case []interface{}:
+		items = v
+	default:
+		return nil, fmt.Errorf("foreach items must be an array, got %T", evaluated)
+	}
+	if err := re.checkCollectionSize("foreach", len(items)); err != nil {
+		return nil, err
+	}
+
+	previous, hadPrevious := data[as]
+	defer func() {
+		if hadPrevious {
+			data[as] = previous
+		} else {
+			delete(data, as)
+		}
+	}()
+
+	iterations := make([]interface{}, 0, len(items))
+	for i, item := range items {
+		data[as] = item
+
+		var results []interface{}
+		for _, rule := range rules {
+			result, err := re.evaluatePlaybookRule(rule, data)
+			if abort, ok := err.(*PlaybookAbort); ok {
+				return nil, abort
+			}
+			if assertion, ok := err.(*AssertionError); ok {
+				return nil, assertion
+			}
+			if err != nil {
+				return nil, fmt.Errorf("foreach item %d: %v", i+1, err)
+			}
+			results = append(results, result)
+		}
+		iterations = append(iterations, results)
+	}
+
+	logger.Debug("Foreach evaluated", map[string]interface{}{
+		"component":  "rules_engine",
+		"variable":   as,
+		"iterations": len(items),
+	})
+
+	return map[string]interface{}{
+		"foreach":    as,
+		"iterations": len(items),
+		"results":    iterations,
+	}, nil
+}
+
+// evaluateVarsOperation handles the "vars" operation, which checks context
+// variables against their declared types. Undeclared variables are ignored,
+// and missing variables are only an error when declared as required.
+func (re *RuleEngine) evaluateVarsOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	declarations, err := normalizeVarDeclarations(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vars declaration: %v", err)
+	}
+
+	names := make([]string, 0, len(declarations))
+	for name := range declarations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		declaration := declarations[name].(map[string]interface{})
+		varType := declaration["type"].(string)
+
+		value, err := re.evaluateVarOperation(name, data)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			if declaration["required"].(bool) {
+				return nil, fmt.Errorf("variable %s is required", name)
+			}
+			continue
+		}
+		if !valueHasVarType(value, varType) {
+			return nil, fmt.Errorf("variable %s must be of type %s, got %T", name, varType, value)
+		}
+	}
+
+	return map[string]interface{}{
+		"vars":  names,
+		"valid": true,
+	}, nil
+}
+
+// evaluateTryOperation handles the "try" operation, which evaluates its do
+// rules and, if one fails, stores the error message in the context under
+// error_var (default "error") and evaluates the catch rules instead of
+// failing the playbook. The finally rules are always evaluated. Aborts are
+// not caught, and errors in catch or finally fail the operation.
+func (re *RuleEngine) evaluateTryOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("try operation requires an object")
+	}
+
+	block := func(name string) ([]interface{}, error) {
+		switch rules := specMap[name].(type) {
+		case nil:
+			return nil, nil
+		case []interface{}:
+			return rules, nil
+		case map[string]interface{}:
+			return []interface{}{rules}, nil
+		}
+		return nil, fmt.Errorf("try %s must be a rule or an array of rules", name)
+	}
+	doRules, err := block("do")
+	if err != nil {
+		return nil, err
+	}
+	if doRules == nil {
+		return nil, fmt.Errorf("try operation requires do rules")
+	}
+	catchRules, err := block("catch")
+	if err != nil {
+		return nil, err
+	}
+	finallyRules, err := block("finally")
+	if err != nil {
+		return nil, err
+	}
+	if catchRules == nil && finallyRules == nil {
+		return nil, fmt.Errorf("try operation requires catch or finally rules")
+	}
+
+	errorVar := "error"
+	if value, exists := specMap["error_var"]; exists {
+		errorVar, _ = value.(string)
+		if errorVar == "" {
+			return nil, fmt.Errorf("try error_var must be a non-empty variable name")
+		}
+	}
+
+	evaluateRules := func(rules []interface{}) ([]interface{}, error) {
+		results := make([]interface{}, 0, len(rules))
+		for _, rule := range rules {
+			result, err := re.evaluatePlaybookRule(rule, data)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, result)
+		}
+		return results, nil
+	}
+
+	response := map[string]interface{}{"try": "succeeded"}
+
+	doResults, doErr := evaluateRules(doRules)
+	response["results"] = doResults
+
+	var blockErr error
+	if _, aborted := doErr.(*PlaybookAbort); aborted {
+		blockErr = doErr
+	} else if doErr != nil {
+		logger.Warning("Try rules failed", map[string]interface{}{
+			"component": "rules_engine",
+			"variable":  errorVar,
+			"error":     doErr.Error(),
+		})
+		response["try"] = "failed"
+		response["error"] = doErr.Error()
+		if err := setContextPath(data, strings.Split(errorVar, "."), doErr.Error()); err != nil {
+			return nil, fmt.Errorf("try failed to store error: %v", err)
+		}
+
+		if catchRules != nil {
+			catchResults, err := evaluateRules(catchRules)
+			response["catch"] = catchResults
+			if _, aborted := err.(*PlaybookAbort); aborted {
+				blockErr = err
+			} else if err != nil {
+				blockErr = fmt.Errorf("try catch failed: %v", err)
+			}
+		}
+	}
+
+	if finallyRules != nil {
+		finallyResults, err := evaluateRules(finallyRules)
+		response["finally"] = finallyResults
+		if _, aborted := err.(*PlaybookAbort); aborted {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("try finally failed: %v", err)
+		}
+	}
+
+	if blockErr != nil {
+		return nil, blockErr
+	}
+	return response, nil
+}
+
+// valueHasVarType reports whether a context value matches a declared type
+func valueHasVarType(value interface{}, varType string) bool {
+	switch varType {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "number":
+		switch value.(type) {
+		case float64, float32, int, int64, int32, json.Number:
+			return true
+		}
+		return false
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	}
+	return true
+}
+
+// evaluateContextDiffOperation handles the "context_diff" operation, which
+// diffs two context objects and stores the result in output_var. In "full"
+// mode the result holds the added, removed and changed entries with their
+// values (an empty object when nothing changed); in "keys_only" mode it is
+// the sorted list of changed paths.
+func (re *RuleEngine) evaluateContextDiffOperation(diffExpr interface{}, data map[string]interface{}) (interface{}, error) {
+	diffMap, ok := diffExpr.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("context_diff operation requires an object")
+	}
+
+	var vars [3]string
+	for i, field := range []string{"before_var", "after_var", "output_var"} {
+		name, ok := diffMap[field].(string)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("context_diff operation requires a string %s", field)
+		}
+		vars[i] = name
+	}
+
+	mode := "full"
+	if value, exists := diffMap["mode"]; exists {
+		mode, _ = value.(string)
+		if mode != "full" && mode != "keys_only" {
+			return nil, fmt.Errorf("context_diff mode must be full or keys_only")
+		}
+	}
+
+	// A snapshot that does not exist yet (e.g. the first poll) diffs as empty
+	snapshots := make([]map[string]interface{}, 2)
+	for i, name := range vars[:2] {
+		value, err := re.evaluateDotNotation(name, data)
+		if err != nil || value == nil {
+			snapshots[i] = map[string]interface{}{}
+			continue
+		}
+		snapshot, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("context_diff %s must reference an object, got %T", name, value)
+		}
+		snapshots[i] = snapshot
+	}
+
+	diff := DiffContexts(snapshots[0], snapshots[1])
+	var output interface{}
+	if mode == "keys_only" {
+		output = diff.Paths()
+	} else {
+		output = diff.ContextValue()
+	}
+
+	if err := setContextPath(data, strings.Split(vars[2], "."), output); err != nil {
+		return nil, fmt.Errorf("context_diff failed: %v", err)
+	}
+
+	logger.Debug("Context diff evaluated", map[string]interface{}{
+		"component": "rules_engine",
+		"variable":  vars[2],
+		"changes":   diff.Count(),
+	})
+
+	return map[string]interface{}{
+		"context_diff": vars[2],
+		"changes":      diff.Count(),
+		"diff":         output,
+	}, nil
+}
+
+// mapExpressionOperators are the operations recognised as expressions inside a
+// map spec; any other object is treated as a nested object to build
+var mapExpressionOperators = map[string]bool{
+	"var": true, "if": true, "switch": true, "map": true, "plugin": true,
+	"eq": true, "gt": true, "lt": true, "gte": true, "lte": true,
+	"in": true, "not_in": true, "between": true,
+	"and": true, "or": true, "not": true,
+}
+
+// evaluateMapOperation handles the "map" operation, which builds a new object
+// from a spec whose values are evaluated against the context. With an "as" key
+// the object is also stored in the context under that name.
+func (re *RuleEngine) evaluateMapOperation(spec interface{}, operation map[string]interface{}, data map[string]interface{}) (interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("map operation requires an object")
+	}
+
+	result, err := re.buildMapValue(specMap, data)
+	if err != nil {
+		return nil, fmt.Errorf("map operation failed: %v", err)
+	}
+
+	if target, exists := operation["as"]; exists {
+		targetKey, ok := target.(string)
+		if !ok || targetKey == "" {
+			return nil, fmt.Errorf("map operation 'as' must be a non-empty string")
+		}
+		if err := setContextPath(data, strings.Split(targetKey, "."), result); err != nil {
+			return nil, fmt.Errorf("map operation failed: %v", err)
+		}
+	}
+
+	return result, nil
+}
+
+// buildMapValue evaluates a map spec value: expressions are evaluated, nested
+// objects and arrays are built recursively, and other values are copied as-is
+func (re *RuleEngine) buildMapValue(value interface{}, data map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 1 {
+			for key := range v {
+				if mapExpressionOperators[key] || (re.jsonLogic && jsonLogicOperators[key]) {
+					return re.evaluate(v, data)
+				}
+			}
+		}
+
+		result := make(map[string]interface{}, len(v))
+		for key, child := range v {
+			built, err := re.buildMapValue(child, data)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			result[key] = built
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, child := range v {
+			built, err := re.buildMapValue(child, data)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			result[i] = built
+		}
+		return result, nil
+	default:
+		return v, nil
+	}
+}
+
 // evaluateObjectBasedIf handles the new object-based if structure
 func (re *RuleEngine) evaluateObjectBasedIf(ifMap map[string]interface{}, data map[string]interface{}) (interface{}, error) {
 	// Extract conditions
@@ -990,6 +1926,9 @@ func (re *RuleEngine) isTruthy(value interface{}) bool {
 		return v != 0
 	case int:
 		return v != 0
+	case json.Number:
+		number, err := v.Float64()
+		return err != nil || number != 0
 	case []interface{}:
 		return len(v) > 0
 	case map[string]interface{}:
@@ -1010,6 +1949,15 @@ func (re *RuleEngine) compareValues(left, right interface{}, op string) (bool, e
 		"operator":   op,
 	})
 
+	if err := re.checkComparisonTypes(left, right, op); err != nil {
+		return false, err
+	}
+
+	// Integers that arrived as JSON are compared exactly, not as float64
+	if result, ok := compareJSONIntegers(left, right, op); ok {
+		return result, nil
+	}
+
 	// Normalize values for comparison
 	leftNorm := re.normalizeValue(left)
 	rightNorm := re.normalizeValue(right)
@@ -1186,6 +2134,10 @@ func (re *RuleEngine) compareNumeric(left, right interface{}, op string) (bool,
 		return false, fmt.Errorf("numeric comparison requires float64 operands")
 	}
 	switch op {
+	case "==", "===", "eq":
+		return leftNum == rightNum, nil
+	case "!=", "!==":
+		return leftNum != rightNum, nil
 	case ">", "gt":
 		return leftNum > rightNum, nil
 	case "<", "lt":
@@ -1199,7 +2151,9 @@ func (re *RuleEngine) compareNumeric(left, right interface{}, op string) (bool,
 	}
 }
 
-// processTemplateVariables processes {{variable}} syntax in strings
+// processTemplateVariables processes {{variable}} syntax in strings. Values
+// are resolved in a single pass: a resolved value is never scanned for
+// templates again, so values from the context cannot inject templates.
 func (re *RuleEngine) processTemplateVariables(value interface{}, data map[string]interface{}) interface{} {
 	switch v := value.(type) {
 	case string:
@@ -1238,6 +2192,24 @@ func (re *RuleEngine) processTemplateVariables(value interface{}, data map[strin
 		}
 		return re.processStringTemplate(v, data)
 	case map[string]interface{}:
+		// The blocks of a try run after earlier blocks have changed the
+		// context, e.g. catch reads the error variable, and foreach rules
+		// read the current item, so their templates are resolved as each
+		// rule runs
+		if _, isTry := v["try"]; isTry {
+			return v
+		}
+		if _, isForeach := v["foreach"]; isForeach {
+			return v
+		}
+		// Batch parameters are resolved per element
+		if _, isBatch := v["batch"]; isBatch {
+			return v
+		}
+		// A macro's rules are resolved once its parameters are bound
+		if _, isMacroScope := v["macro_scope"]; isMacroScope {
+			return v
+		}
 		result := make(map[string]interface{})
 		for key, val := range v {
 			result[key] = re.processTemplateVariables(val, data)
@@ -1282,11 +2254,7 @@ func (re *RuleEngine) processStringTemplate(template string, data map[string]int
 		}
 
 		// If variable not found, return the original template
-		logger.Warning("Template variable not found", map[string]interface{}{
-			"component": "rules_engine",
-			"variable":  variableName,
-			"template":  template,
-		})
+		re.recordUnresolvedVariable(variableName, template)
 		return match
 	})
 }
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestEvaluatePlaybookConcurrentContexts runs one engine on many contexts at
+// once. Run with -race: each run must only see and change its own context.
+func TestEvaluatePlaybookConcurrentContexts(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	playbook := parsePlaybook(t, `[
+		{"foreach": {"items": {"var": "items"}, "as": "item", "do": [{"map": {"seen": "{{item}}"}, "as": "last"}]}},
+		{"conditional_set": {"key": "owner", "value": "{{id}}"}},
+		{"if": {"conditions": [{"eq": [{"var": "id"}, {"var": "id"}]}], "true": [{"map": {"copy": "{{id}}"}, "as": "copied"}]}},
+		{"try": {"do": [{"map": {"step": "{{id}}"}, "as": "tried"}], "finally": []}}
+	]`)
+
+	const runs = 32
+	var wg sync.WaitGroup
+	errors := make(chan error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			items := []interface{}{id + "-a", id + "-b", id + "-c"}
+			context := map[string]interface{}{"id": id, "items": items}
+			if _, err := engine.EvaluatePlaybook(playbook, context); err != nil {
+				errors <- fmt.Errorf("run %s: %v", id, err)
+				return
+			}
+
+			want := map[string]interface{}{
+				"id":     id,
+				"items":  items,
+				"owner":  id,
+				"last":   map[string]interface{}{"seen": id + "-c"},
+				"copied": map[string]interface{}{"copy": id},
+				"tried":  map[string]interface{}{"step": id},
+			}
+			if !reflect.DeepEqual(context, want) {
+				errors <- fmt.Errorf("run %s context = %v, want %v", id, context, want)
+			}
+		}(fmt.Sprintf("run%d", i))
+	}
+	wg.Wait()
+	close(errors)
+
+	for err := range errors {
+		t.Error(err)
+	}
+}
+
+// TestTemplateValuesAreNotExpandedAgain checks that a context value holding
+// {{...}} is used as literal text, whether it fills a whole template, part
+// of a string or a loop variable
+func TestTemplateValuesAreNotExpandedAgain(t *testing.T) {
+	engine := NewRuleEngine(&Config{})
+	playbook := parsePlaybook(t, `[
+		{"map": {"whole": "{{a}}", "embedded": "title: {{a}}"}, "as": "out"},
+		{"foreach": {"items": {"var": "titles"}, "as": "title", "do": [{"map": {"seen": "{{title}}"}, "as": "last"}]}}
+	]`)
+	context := map[string]interface{}{
+		"a":      "{{b}}",
+		"b":      "secret",
+		"titles": []interface{}{"{{b}}"},
+	}
+
+	if _, err := engine.EvaluatePlaybook(playbook, context); err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	want := map[string]interface{}{"whole": "{{b}}", "embedded": "title: {{b}}"}
+	if !reflect.DeepEqual(context["out"], want) {
+		t.Errorf("out = %v, want %v", context["out"], want)
+	}
+	if last := context["last"]; !reflect.DeepEqual(last, map[string]interface{}{"seen": "{{b}}"}) {
+		t.Errorf("last = %v, want the loop item kept literal", last)
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// MisfirePolicy decides what happens to the runs of a schedule that fell due
+// while the server was down
+type MisfirePolicy string
+
+const (
+	// MisfirePolicySkip ignores missed runs (the default)
+	MisfirePolicySkip MisfirePolicy = "skip"
+	// MisfirePolicyFireOnce runs the schedule once on startup if any run was missed
+	MisfirePolicyFireOnce MisfirePolicy = "fire_once"
+	// MisfirePolicyFireAll runs the schedule once per missed run, up to a bound
+	MisfirePolicyFireAll MisfirePolicy = "fire_all"
+)
+
+const (
+	// defaultMaxCatchUpRuns bounds fire_all catch-up when neither the
+	// schedule nor the scheduler configuration sets a limit
+	defaultMaxCatchUpRuns = 10
+	// maxMissedRunsCounted bounds the missed runs counted for a schedule
+	maxMissedRunsCounted = 1000
+)
+
+// validMisfirePolicy reports whether policy is a known misfire policy; an
+// empty policy means skip
+func validMisfirePolicy(policy MisfirePolicy) bool {
+	switch policy {
+	case "", MisfirePolicySkip, MisfirePolicyFireOnce, MisfirePolicyFireAll:
+		return true
+	}
+	return false
+}
+
+// missedRuns returns the times a schedule was due between its last planned
+// run and now, stopping after limit times. truncated is set when more were
+// missed than returned.
+func (js *JobScheduler) missedRuns(schedule *JobSchedule, now time.Time, limit int) (missed []time.Time, truncated bool) {
+	// One-time schedules have no run after their first
+	next := func(time.Time) time.Time { return time.Time{} }
+	switch schedule.ScheduleType {
+	case ScheduleTypeCron:
+		parser := cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow)
+		sched, err := parser.Parse(schedule.CronExpression)
+		if err != nil {
+			return nil, false
+		}
+		next = sched.Next
+	case ScheduleTypeInterval, ScheduleTypeRecurring:
+		if schedule.IntervalSeconds <= 0 {
+			return nil, false
+		}
+		interval := time.Duration(schedule.IntervalSeconds) * time.Second
+		next = func(t time.Time) time.Time { return t.Add(interval) }
+	}
+
+	var due time.Time
+	switch {
+	case schedule.ScheduleType == ScheduleTypeOnce:
+		if schedule.RunCount > 0 || schedule.StartTime == nil {
+			return nil, false
+		}
+		due = *schedule.StartTime
+	case schedule.NextRun != nil:
+		due = *schedule.NextRun
+	case schedule.LastRun != nil:
+		due = next(*schedule.LastRun)
+	default:
+		return nil, false
+	}
+
+	for t := due; !t.IsZero() && !t.After(now); t = next(t) {
+		if schedule.EndTime != nil && t.After(*schedule.EndTime) {
+			break
+		}
+		if len(missed) == limit {
+			return missed, true
+		}
+		missed = append(missed, t)
+	}
+	return missed, false
+}
+
+// maxCatchUpRuns returns the most runs fire_all may make up for a schedule
+func (js *JobScheduler) maxCatchUpRuns(schedule *JobSchedule) int {
+	if schedule.MaxCatchUpRuns > 0 {
+		return schedule.MaxCatchUpRuns
+	}
+	if js.config.MaxCatchUpRuns > 0 {
+		return js.config.MaxCatchUpRuns
+	}
+	return defaultMaxCatchUpRuns
+}
+
+// catchUpMissedRuns applies the schedule's misfire policy to the runs it
+// missed while the server was down. It reports whether the schedule still
+// needs to be registered with the cron scheduler: a one-time schedule whose
+// time has passed is finished once its misfire is handled.
+func (js *JobScheduler) catchUpMissedRuns(schedule *JobSchedule) bool {
+	now := time.Now()
+	policy := schedule.MisfirePolicy
+	if policy == "" {
+		policy = MisfirePolicySkip
+	}
+
+	missed, truncated := js.missedRuns(schedule, now, maxMissedRunsCounted)
+	pastOnce := schedule.ScheduleType == ScheduleTypeOnce && schedule.StartTime != nil && !schedule.StartTime.After(now)
+	if len(missed) == 0 {
+		return !pastOnce
+	}
+
+	missedCount := fmt.Sprintf("%d", len(missed))
+	if truncated {
+		missedCount += "+"
+	}
+
+	fires := 0
+	switch policy {
+	case MisfirePolicyFireOnce:
+		fires = 1
+	case MisfirePolicyFireAll:
+		fires = len(missed)
+		if limit := js.maxCatchUpRuns(schedule); fires > limit {
+			js.logger.Warning("Schedule missed more runs than max_catch_up_runs, catching up only that many", map[string]interface{}{
+				"component":         "job_scheduler",
+				"schedule_id":       schedule.ID,
+				"missed":            missedCount,
+				"max_catch_up_runs": limit,
+			})
+			fires = limit
+		}
+	}
+	schedule.MissedRuns += len(missed) - fires
+
+	if fires == 0 {
+		js.logger.Warning("Schedule missed runs while the server was down, skipping them", map[string]interface{}{
+			"component":      "job_scheduler",
+			"schedule_id":    schedule.ID,
+			"schedule_name":  schedule.Name,
+			"missed":         missedCount,
+			"first_missed":   missed[0].UTC().Format(time.RFC3339),
+			"misfire_policy": string(policy),
+		})
+		schedule.NextRun = js.calculateNextRun(schedule)
+		js.updateSchedule(schedule)
+		return !pastOnce
+	}
+
+	js.logger.Info("Catching up schedule runs missed while the server was down", map[string]interface{}{
+		"component":      "job_scheduler",
+		"schedule_id":    schedule.ID,
+		"schedule_name":  schedule.Name,
+		"missed":         missedCount,
+		"first_missed":   missed[0].UTC().Format(time.RFC3339),
+		"runs":           fires,
+		"misfire_policy": string(policy),
+	})
+	for i := 0; i < fires; i++ {
+		js.executeScheduledJob(schedule)
+	}
+	return !pastOnce
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// allScheduleStores adds a Redis job store backed by an in-process Redis to
+// scheduleStores
+func allScheduleStores(t *testing.T) map[string]JobStoreInterface {
+	t.Helper()
+	stores := scheduleStores(t)
+	server := miniredis.RunT(t)
+	redisStore, err := NewRedisJobStore(DatabaseConfig{RedisURL: "redis://" + server.Addr() + "/0"})
+	if err != nil {
+		t.Fatalf("open redis store: %v", err)
+	}
+	t.Cleanup(func() { redisStore.Close() })
+	stores["redis"] = redisStore
+	return stores
+}
+
+// scheduledJobs returns the jobs a schedule submitted
+func scheduledJobs(store JobStoreInterface, scheduleID string) []*Job {
+	var jobs []*Job
+	for _, job := range store.ListJobs("", 0) {
+		if job.TriggeredBy == "schedule:"+scheduleID {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs
+}
+
+func TestSchedulerCatchesUpMissedRuns(t *testing.T) {
+	tests := []struct {
+		policy         MisfirePolicy
+		maxCatchUpRuns int
+		wantRuns       int
+		wantMissed     int
+	}{
+		{policy: MisfirePolicySkip, wantRuns: 0, wantMissed: 5},
+		{policy: "", wantRuns: 0, wantMissed: 5},
+		{policy: MisfirePolicyFireOnce, wantRuns: 1, wantMissed: 4},
+		{policy: MisfirePolicyFireAll, wantRuns: 5, wantMissed: 0},
+		{policy: MisfirePolicyFireAll, maxCatchUpRuns: 3, wantRuns: 3, wantMissed: 2},
+	}
+
+	for name, store := range allScheduleStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, test := range tests {
+				// The server went down before five runs, a minute apart, were due
+				now := time.Now()
+				nextRun := now.Add(-4*time.Minute - 30*time.Second)
+				lastRun := nextRun.Add(-time.Minute)
+				schedule := &JobSchedule{
+					ID:              "missed_" + string(test.policy) + "_" + string(rune('a'+i)),
+					Name:            "every minute",
+					ScheduleType:    ScheduleTypeInterval,
+					IntervalSeconds: 60,
+					NextRun:         &nextRun,
+					LastRun:         &lastRun,
+					RunCount:        7,
+					MisfirePolicy:   test.policy,
+					MaxCatchUpRuns:  test.maxCatchUpRuns,
+					Status:          ScheduleStatusActive,
+					Playbook:        testSchedulePlaybook(),
+					Revision:        1,
+					CreatedAt:       now.Add(-time.Hour),
+				}
+				if err := store.SaveSchedule(schedule); err != nil {
+					t.Fatalf("SaveSchedule: %v", err)
+				}
+
+				// Starting the scheduler applies the misfire policy
+				scheduler := newTestScheduler(t, store)
+
+				if jobs := scheduledJobs(store, schedule.ID); len(jobs) != test.wantRuns {
+					t.Errorf("policy %q (max %d): %d runs submitted, want %d", test.policy, test.maxCatchUpRuns, len(jobs), test.wantRuns)
+				}
+				stored, exists := store.LoadSchedule(schedule.ID)
+				if !exists {
+					t.Fatalf("schedule %s is no longer stored", schedule.ID)
+				}
+				if stored.RunCount != 7+test.wantRuns {
+					t.Errorf("policy %q: run_count = %d, want %d", test.policy, stored.RunCount, 7+test.wantRuns)
+				}
+				if stored.MissedRuns != test.wantMissed {
+					t.Errorf("policy %q: missed_runs = %d, want %d", test.policy, stored.MissedRuns, test.wantMissed)
+				}
+				if stored.NextRun == nil || !stored.NextRun.After(now) {
+					t.Errorf("policy %q: next_run = %v, want a time after startup", test.policy, stored.NextRun)
+				}
+				if _, registered := scheduler.entries[schedule.ID]; !registered {
+					t.Errorf("policy %q: schedule is not registered with cron after catching up", test.policy)
+				}
+
+				// Once caught up, a restart finds nothing more to make up
+				newTestScheduler(t, store)
+				if jobs := scheduledJobs(store, schedule.ID); len(jobs) != test.wantRuns {
+					t.Errorf("policy %q: %d runs after a second restart, want still %d", test.policy, len(jobs), test.wantRuns)
+				}
+				store.DeleteSchedule(schedule.ID)
+			}
+		})
+	}
+}
+
+func TestSchedulerRunsMissedOnceSchedule(t *testing.T) {
+	store := NewMemoryJobStore()
+	start := time.Now().Add(-time.Hour)
+	schedule := &JobSchedule{
+		ID:            "once_missed",
+		Name:          "one-off",
+		ScheduleType:  ScheduleTypeOnce,
+		StartTime:     &start,
+		MisfirePolicy: MisfirePolicyFireOnce,
+		Status:        ScheduleStatusActive,
+		Playbook:      testSchedulePlaybook(),
+		CreatedAt:     start.Add(-time.Hour),
+	}
+	if err := store.SaveSchedule(schedule); err != nil {
+		t.Fatalf("SaveSchedule: %v", err)
+	}
+
+	newTestScheduler(t, store)
+	if jobs := scheduledJobs(store, schedule.ID); len(jobs) != 1 {
+		t.Fatalf("%d runs of the missed one-time schedule, want 1", len(jobs))
+	}
+
+	// Its run is recorded, so it does not run again on the next start
+	newTestScheduler(t, store)
+	if jobs := scheduledJobs(store, schedule.ID); len(jobs) != 1 {
+		t.Errorf("%d runs after a second restart, want 1", len(jobs))
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// maxSearchQueryLength bounds the accepted search query
+	maxSearchQueryLength = 256
+	// defaultSearchLimit and maxSearchLimit bound the number of returned matches
+	defaultSearchLimit = 50
+	maxSearchLimit     = 500
+	// maxSearchFileSize skips files too large to scan line by line
+	maxSearchFileSize = 5 * 1024 * 1024
+	// maxSnippetLength truncates long matching lines
+	maxSnippetLength = 200
+)
+
+// searchAssetDirs maps searchable asset types to their directories
+var searchAssetDirs = map[string]string{
+	"playbook":    "../playbooks",
+	"automation":  "../automations",
+	"integration": "../integrations",
+}
+
+// AssetSearcher searches asset names and file contents
+type AssetSearcher struct {
+	validator   *Validator
+	integration *IntegrationConfigManager
+	query       string
+	limit       int
+	matches     []SearchMatch
+}
+
+// NewAssetSearcher creates a case-insensitive searcher for query
+func NewAssetSearcher(query string, limit int, validator *Validator, integration *IntegrationConfigManager) *AssetSearcher {
+	return &AssetSearcher{
+		validator:   validator,
+		integration: integration,
+		query:       strings.ToLower(query),
+		limit:       limit,
+	}
+}
+
+// Search runs the search over the given asset types
+func (as *AssetSearcher) Search(types []string) []SearchMatch {
+	for _, assetType := range types {
+		if as.full() {
+			break
+		}
+
+		switch assetType {
+		case "playbook":
+			as.searchDirectory(assetType, ".json", true)
+		case "automation":
+			as.searchDirectory(assetType, "", true)
+		case "integration":
+			as.searchIntegrationConfigs()
+			as.searchDirectory(assetType, ".py", false)
+		}
+	}
+
+	if as.matches == nil {
+		as.matches = []SearchMatch{}
+	}
+	return as.matches
+}
+
+// Truncated reports whether the limit was reached, so further matches may exist
+func (as *AssetSearcher) Truncated() bool {
+	return as.full()
+}
+
+// full reports whether the result limit has been reached
+func (as *AssetSearcher) full() bool {
+	return len(as.matches) >= as.limit
+}
+
+// add records a match and reports whether more matches can be accepted
+func (as *AssetSearcher) add(match SearchMatch) bool {
+	as.matches = append(as.matches, match)
+	return !as.full()
+}
+
+// searchIntegrationConfigs matches integration configuration names, types and descriptions
+func (as *AssetSearcher) searchIntegrationConfigs() {
+	if as.integration == nil {
+		return
+	}
+
+	configs := as.integration.ListConfigs()
+	names := make([]string, 0, len(configs))
+	for name := range configs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		config := configs[name]
+		if strings.Contains(strings.ToLower(name), as.query) {
+			if !as.add(SearchMatch{Type: "integration", Name: name, Field: "name", Snippet: name}) {
+				return
+			}
+			continue
+		}
+		if strings.Contains(strings.ToLower(config.Description), as.query) {
+			if !as.add(SearchMatch{Type: "integration", Name: name, Field: "description", Snippet: truncateSnippet(config.Description)}) {
+				return
+			}
+		}
+	}
+}
+
+// searchDirectory matches file names and, if requested, file contents in an asset directory.
+// Only regular files directly inside the directory are considered.
+func (as *AssetSearcher) searchDirectory(assetType, extension string, contents bool) {
+	dir := searchAssetDirs[assetType]
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if as.full() {
+			return
+		}
+
+		filename := entry.Name()
+		if !entry.Type().IsRegular() || !as.validator.IsValidFilename(filename) {
+			continue
+		}
+		if extension != "" && !strings.HasSuffix(strings.ToLower(filename), extension) {
+			continue
+		}
+		name := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+		if strings.Contains(strings.ToLower(filename), as.query) {
+			if !as.add(SearchMatch{Type: assetType, Name: name, Field: "name", Snippet: filename}) {
+				return
+			}
+		}
+
+		if contents {
+			as.searchFile(assetType, name, filepath.Join(dir, filename))
+		}
+	}
+}
+
+// searchFile matches the lines of a single file
+func (as *AssetSearcher) searchFile(assetType, name, path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > maxSearchFileSize {
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSearchFileSize)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if !strings.Contains(strings.ToLower(line), as.query) {
+			continue
+		}
+		if !as.add(SearchMatch{
+			Type:    assetType,
+			Name:    name,
+			Field:   "content",
+			Line:    lineNumber,
+			Snippet: truncateSnippet(strings.TrimSpace(line)),
+		}) {
+			return
+		}
+	}
+}
+
+// truncateSnippet shortens a snippet to maxSnippetLength characters
+func truncateSnippet(snippet string) string {
+	runes := []rune(snippet)
+	if len(runes) > maxSnippetLength {
+		return string(runes[:maxSnippetLength]) + "..."
+	}
+	return snippet
+}
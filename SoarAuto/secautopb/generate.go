@@ -0,0 +1,5 @@
+// Package secautopb holds the gRPC service definition of the SecAuto API and
+// the Go stubs generated from it.
+package secautopb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative secauto.proto
@@ -0,0 +1,315 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: secauto.proto
+
+package secautopb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	SecAuto_ExecutePlaybook_FullMethodName = "/secauto.v1.SecAuto/ExecutePlaybook"
+	SecAuto_SubmitPlaybook_FullMethodName  = "/secauto.v1.SecAuto/SubmitPlaybook"
+	SecAuto_WatchJob_FullMethodName        = "/secauto.v1.SecAuto/WatchJob"
+	SecAuto_GetJob_FullMethodName          = "/secauto.v1.SecAuto/GetJob"
+	SecAuto_ListJobs_FullMethodName        = "/secauto.v1.SecAuto/ListJobs"
+	SecAuto_CancelJob_FullMethodName       = "/secauto.v1.SecAuto/CancelJob"
+)
+
+// SecAutoClient is the client API for SecAuto service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SecAutoClient interface {
+	ExecutePlaybook(ctx context.Context, in *PlaybookRequest, opts ...grpc.CallOption) (*PlaybookResult, error)
+	SubmitPlaybook(ctx context.Context, in *PlaybookRequest, opts ...grpc.CallOption) (*JobSubmission, error)
+	WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error)
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error)
+	CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error)
+}
+
+type secAutoClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSecAutoClient(cc grpc.ClientConnInterface) SecAutoClient {
+	return &secAutoClient{cc}
+}
+
+func (c *secAutoClient) ExecutePlaybook(ctx context.Context, in *PlaybookRequest, opts ...grpc.CallOption) (*PlaybookResult, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PlaybookResult)
+	err := c.cc.Invoke(ctx, SecAuto_ExecutePlaybook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secAutoClient) SubmitPlaybook(ctx context.Context, in *PlaybookRequest, opts ...grpc.CallOption) (*JobSubmission, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(JobSubmission)
+	err := c.cc.Invoke(ctx, SecAuto_SubmitPlaybook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secAutoClient) WatchJob(ctx context.Context, in *WatchJobRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Job], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &SecAuto_ServiceDesc.Streams[0], SecAuto_WatchJob_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchJobRequest, Job]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SecAuto_WatchJobClient = grpc.ServerStreamingClient[Job]
+
+func (c *secAutoClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Job)
+	err := c.cc.Invoke(ctx, SecAuto_GetJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secAutoClient) ListJobs(ctx context.Context, in *ListJobsRequest, opts ...grpc.CallOption) (*ListJobsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListJobsResponse)
+	err := c.cc.Invoke(ctx, SecAuto_ListJobs_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secAutoClient) CancelJob(ctx context.Context, in *CancelJobRequest, opts ...grpc.CallOption) (*CancelJobResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancelJobResponse)
+	err := c.cc.Invoke(ctx, SecAuto_CancelJob_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SecAutoServer is the server API for SecAuto service.
+// All implementations must embed UnimplementedSecAutoServer
+// for forward compatibility.
+type SecAutoServer interface {
+	ExecutePlaybook(context.Context, *PlaybookRequest) (*PlaybookResult, error)
+	SubmitPlaybook(context.Context, *PlaybookRequest) (*JobSubmission, error)
+	WatchJob(*WatchJobRequest, grpc.ServerStreamingServer[Job]) error
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error)
+	CancelJob(context.Context, *CancelJobRequest) (*CancelJobResponse, error)
+	mustEmbedUnimplementedSecAutoServer()
+}
+
+// UnimplementedSecAutoServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSecAutoServer struct{}
+
+func (UnimplementedSecAutoServer) ExecutePlaybook(context.Context, *PlaybookRequest) (*PlaybookResult, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecutePlaybook not implemented")
+}
+func (UnimplementedSecAutoServer) SubmitPlaybook(context.Context, *PlaybookRequest) (*JobSubmission, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitPlaybook not implemented")
+}
+func (UnimplementedSecAutoServer) WatchJob(*WatchJobRequest, grpc.ServerStreamingServer[Job]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchJob not implemented")
+}
+func (UnimplementedSecAutoServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedSecAutoServer) ListJobs(context.Context, *ListJobsRequest) (*ListJobsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListJobs not implemented")
+}
+func (UnimplementedSecAutoServer) CancelJob(context.Context, *CancelJobRequest) (*CancelJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CancelJob not implemented")
+}
+func (UnimplementedSecAutoServer) mustEmbedUnimplementedSecAutoServer() {}
+func (UnimplementedSecAutoServer) testEmbeddedByValue()                 {}
+
+// UnsafeSecAutoServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SecAutoServer will
+// result in compilation errors.
+type UnsafeSecAutoServer interface {
+	mustEmbedUnimplementedSecAutoServer()
+}
+
+func RegisterSecAutoServer(s grpc.ServiceRegistrar, srv SecAutoServer) {
+	// If the following call pancis, it indicates UnimplementedSecAutoServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SecAuto_ServiceDesc, srv)
+}
+
+func _SecAuto_ExecutePlaybook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaybookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecAutoServer).ExecutePlaybook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecAuto_ExecutePlaybook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecAutoServer).ExecutePlaybook(ctx, req.(*PlaybookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecAuto_SubmitPlaybook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PlaybookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecAutoServer).SubmitPlaybook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecAuto_SubmitPlaybook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecAutoServer).SubmitPlaybook(ctx, req.(*PlaybookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecAuto_WatchJob_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchJobRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SecAutoServer).WatchJob(m, &grpc.GenericServerStream[WatchJobRequest, Job]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type SecAuto_WatchJobServer = grpc.ServerStreamingServer[Job]
+
+func _SecAuto_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecAutoServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecAuto_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecAutoServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecAuto_ListJobs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListJobsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecAutoServer).ListJobs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecAuto_ListJobs_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecAutoServer).ListJobs(ctx, req.(*ListJobsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecAuto_CancelJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecAutoServer).CancelJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SecAuto_CancelJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecAutoServer).CancelJob(ctx, req.(*CancelJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SecAuto_ServiceDesc is the grpc.ServiceDesc for SecAuto service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SecAuto_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "secauto.v1.SecAuto",
+	HandlerType: (*SecAutoServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ExecutePlaybook",
+			Handler:    _SecAuto_ExecutePlaybook_Handler,
+		},
+		{
+			MethodName: "SubmitPlaybook",
+			Handler:    _SecAuto_SubmitPlaybook_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _SecAuto_GetJob_Handler,
+		},
+		{
+			MethodName: "ListJobs",
+			Handler:    _SecAuto_ListJobs_Handler,
+		},
+		{
+			MethodName: "CancelJob",
+			Handler:    _SecAuto_CancelJob_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchJob",
+			Handler:       _SecAuto_WatchJob_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "secauto.proto",
+}
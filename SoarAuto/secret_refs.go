@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prefixes of integration config values that name a secret held elsewhere
+const (
+	envSecretPrefix   = "env:"
+	vaultSecretPrefix = "vault:"
+)
+
+// isSecretReference reports whether an integration config value is an env:
+// or vault: reference rather than a literal
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, envSecretPrefix) || strings.HasPrefix(value, vaultSecretPrefix)
+}
+
+// validateSecretReference checks the form of an env: or vault: reference
+// when an integration is saved; whether it resolves is only known when used
+func validateSecretReference(value string) error {
+	switch {
+	case strings.HasPrefix(value, envSecretPrefix):
+		if strings.TrimPrefix(value, envSecretPrefix) == "" {
+			return fmt.Errorf("env reference %q requires a variable name", value)
+		}
+	case strings.HasPrefix(value, vaultSecretPrefix):
+		path, field, found := strings.Cut(strings.TrimPrefix(value, vaultSecretPrefix), "#")
+		if !found || strings.Trim(path, "/") == "" || field == "" {
+			return fmt.Errorf("vault reference %q must have the form vault:path#field", value)
+		}
+	}
+	return nil
+}
+
+// vaultSecret is a cached read of a Vault secret's fields
+type vaultSecret struct {
+	fields    map[string]interface{}
+	fetchedAt time.Time
+}
+
+// SecretResolver resolves env:NAME and vault:path#field references in
+// integration configs. Vault secrets are cached for the configured TTL so a
+// busy integration does not read Vault on every call.
+type SecretResolver struct {
+	address    string
+	token      string
+	namespace  string
+	cacheTTL   time.Duration
+	httpClient *http.Client
+
+	mutex sync.Mutex
+	cache map[string]vaultSecret
+}
+
+// NewSecretResolver creates a resolver for the Vault server in config. The
+// address and token fall back to VAULT_ADDR and VAULT_TOKEN.
+func NewSecretResolver(config VaultConfig) *SecretResolver {
+	address := config.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	token := config.Token
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	timeout := time.Duration(config.Timeout) * time.Second
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &SecretResolver{
+		address:    strings.TrimRight(address, "/"),
+		token:      token,
+		namespace:  config.Namespace,
+		cacheTTL:   time.Duration(config.CacheTTL) * time.Second,
+		httpClient: &http.Client{Timeout: timeout},
+		cache:      make(map[string]vaultSecret),
+	}
+}
+
+// Resolve returns the secret a value refers to, or the value itself when it
+// is a literal
+func (sr *SecretResolver) Resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, envSecretPrefix):
+		name := strings.TrimPrefix(value, envSecretPrefix)
+		if name == "" {
+			return "", fmt.Errorf("env reference requires a variable name")
+		}
+		secret, exists := os.LookupEnv(name)
+		if !exists {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, vaultSecretPrefix):
+		return sr.resolveVault(strings.TrimPrefix(value, vaultSecretPrefix))
+	}
+	return value, nil
+}
+
+// resolveVault reads the field of a "path#field" reference
+func (sr *SecretResolver) resolveVault(reference string) (string, error) {
+	path, field, found := strings.Cut(reference, "#")
+	path = strings.Trim(path, "/")
+	if !found || path == "" || field == "" {
+		return "", fmt.Errorf("vault reference %q must have the form vault:path#field", reference)
+	}
+	if sr == nil || sr.address == "" {
+		return "", fmt.Errorf("vault reference %q cannot be resolved: no Vault address is configured", reference)
+	}
+
+	fields, err := sr.readVaultSecret(path)
+	if err != nil {
+		return "", fmt.Errorf("vault secret %s: %v", path, err)
+	}
+	value, exists := fields[field]
+	if !exists {
+		return "", fmt.Errorf("vault secret %s has no field %s", path, field)
+	}
+	secret, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret %s field %s is not a string", path, field)
+	}
+	return secret, nil
+}
+
+// readVaultSecret returns the fields stored at a Vault path, from the cache
+// when it was read within the TTL. Both KV version 1 and version 2 responses
+// are understood.
+func (sr *SecretResolver) readVaultSecret(path string) (map[string]interface{}, error) {
+	sr.mutex.Lock()
+	cached, exists := sr.cache[path]
+	sr.mutex.Unlock()
+	if exists && time.Since(cached.fetchedAt) < sr.cacheTTL {
+		return cached.fields, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, sr.address+"/v1/"+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if sr.token != "" {
+		req.Header.Set("X-Vault-Token", sr.token)
+	}
+	if sr.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", sr.namespace)
+	}
+
+	resp, err := sr.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Vault returned status %d", resp.StatusCode)
+	}
+
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	fields := secret.Data
+	// KV version 2 nests the fields under data.data next to data.metadata
+	if nested, ok := fields["data"].(map[string]interface{}); ok {
+		if _, versioned := fields["metadata"]; versioned {
+			fields = nested
+		}
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("response has no data")
+	}
+
+	sr.mutex.Lock()
+	sr.cache[path] = vaultSecret{fields: fields, fetchedAt: time.Now()}
+	sr.mutex.Unlock()
+	return fields, nil
+}
+
+// ResolveConfig returns a copy of an integration config with every secret
+// reference in its credential fields and string settings replaced by the
+// secret. The error names the field that could not be resolved.
+func (sr *SecretResolver) ResolveConfig(config *IntegrationConfig) (*IntegrationConfig, error) {
+	resolved := *config
+	fields := []struct {
+		name  string
+		value *string
+	}{
+		{"url", &resolved.URL},
+		{"apikey", &resolved.APIKey},
+		{"username", &resolved.Username},
+		{"password", &resolved.Password},
+		{"token", &resolved.Token},
+		{"secret", &resolved.Secret},
+	}
+	for _, field := range fields {
+		secret, err := sr.Resolve(*field.value)
+		if err != nil {
+			return nil, fmt.Errorf("integration %s field %s: %v", config.Name, field.name, err)
+		}
+		*field.value = secret
+	}
+
+	if config.Settings != nil {
+		resolved.Settings = make(map[string]interface{}, len(config.Settings))
+		for key, value := range config.Settings {
+			if text, ok := value.(string); ok {
+				secret, err := sr.Resolve(text)
+				if err != nil {
+					return nil, fmt.Errorf("integration %s setting %s: %v", config.Name, key, err)
+				}
+				value = secret
+			}
+			resolved.Settings[key] = value
+		}
+	}
+	return &resolved, nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// selfTestPlaybook is the built-in playbook executed by the self-test. It is
+// embedded so the self-test does not depend on any user playbooks.
+const selfTestPlaybook = `[
+	{"conditional_set": {"key": "selftest.max_score", "value": "{{incident.threat_score}}",
+		"condition": {"gt": [{"var": "incident.threat_score"}, {"var": "baseline_score"}]}}},
+	{"if": [
+		{"gte": [{"var": "selftest.max_score"}, 50]},
+		{"conditional_set": {"key": "selftest.verdict", "value": "escalate"}},
+		{"conditional_set": {"key": "selftest.verdict", "value": "ignore"}}
+	]}
+]`
+
+// selfTestPythonCode is the trivial automation run in the Python venv; it echoes
+// its JSON input back so the round trip can be verified
+const selfTestPythonCode = `import json, sys
+data = json.load(sys.stdin)
+print(json.dumps({"echo": data.get("value")}))`
+
+// RunSelfTest exercises the rule engine, the Python venv and the plugin manager
+func (s *SecAutoServer) RunSelfTest() SelfTestResponse {
+	checks := []SelfTestCheck{
+		runSelfTestCheck("engine", s.selfTestEngine),
+		runSelfTestCheck("python", s.selfTestPython),
+		runSelfTestCheck("plugins", s.selfTestPlugins),
+	}
+
+	passed := true
+	for _, check := range checks {
+		passed = passed && check.Passed
+	}
+
+	return SelfTestResponse{
+		Success:   true,
+		Passed:    passed,
+		Checks:    checks,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	}
+}
+
+// runSelfTestCheck times a check and converts its error into a report entry
+func runSelfTestCheck(name string, check func() (map[string]interface{}, error)) SelfTestCheck {
+	start := time.Now()
+	details, err := check()
+
+	result := SelfTestCheck{
+		Name:       name,
+		Passed:     err == nil,
+		Message:    "ok",
+		DurationMs: time.Since(start).Milliseconds(),
+		Details:    details,
+	}
+	if err != nil {
+		result.Message = err.Error()
+	}
+	return result
+}
+
+// selfTestEngine runs the built-in playbook against a sample context
+func (s *SecAutoServer) selfTestEngine() (map[string]interface{}, error) {
+	var playbook []interface{}
+	if err := json.Unmarshal([]byte(selfTestPlaybook), &playbook); err != nil {
+		return nil, fmt.Errorf("invalid built-in playbook: %v", err)
+	}
+
+	context := NewPlaybookContext(map[string]interface{}{
+		"incident": map[string]interface{}{
+			"id":           "selftest",
+			"threat_score": 75.0,
+		},
+		"baseline_score": 10.0,
+	})
+
+	results, err := s.engine.EvaluatePlaybook(playbook, context)
+	if err != nil {
+		return nil, fmt.Errorf("playbook execution failed: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"max_score": 75.0,
+		"verdict":   "escalate",
+	}
+	if !reflect.DeepEqual(context["selftest"], expected) {
+		return nil, fmt.Errorf("unexpected playbook output: %v", context["selftest"])
+	}
+
+	return map[string]interface{}{
+		"rules":   len(playbook),
+		"results": len(results),
+	}, nil
+}
+
+// selfTestPython runs a trivial automation in the configured venv
+func (s *SecAutoServer) selfTestPython() (map[string]interface{}, error) {
+	venvPath := s.engine.config.GetVenvPath()
+	output, err := RunPythonCodeFromVenvWithJSON(venvPath, selfTestPythonCode, map[string]interface{}{"value": "selftest"})
+	if err != nil {
+		return nil, err
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(string(output))), &result); err != nil {
+		return nil, fmt.Errorf("invalid automation output: %v", err)
+	}
+	if result["echo"] != "selftest" {
+		return nil, fmt.Errorf("unexpected automation output: %v", result)
+	}
+
+	return map[string]interface{}{
+		"venv_path": venvPath,
+	}, nil
+}
+
+// selfTestPlugins verifies the plugin manager is running with at least one platform
+func (s *SecAutoServer) selfTestPlugins() (map[string]interface{}, error) {
+	if s.pluginManager == nil {
+		return nil, fmt.Errorf("plugin manager not available")
+	}
+
+	platforms := s.pluginManager.GetEnabledPlatforms()
+	details := map[string]interface{}{
+		"platforms":      platforms,
+		"loaded_plugins": len(s.pluginManager.GetAllPlugins()),
+	}
+	if len(platforms) == 0 {
+		return details, fmt.Errorf("no plugin platforms are enabled")
+	}
+
+	// Plugins that failed to load are reported but do not fail the check
+	var failed []string
+	for name, info := range s.pluginManager.GetPluginInfo() {
+		if info.Status == PluginStatusError {
+			failed = append(failed, name)
+		}
+	}
+	if len(failed) > 0 {
+		details["failed_plugins"] = failed
+	}
+
+	return details, nil
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// splunkIntegrationType is the integration type holding Splunk HEC settings
+	splunkIntegrationType = "splunk"
+	// defaultSplunkIntegration is the integration used when none is named
+	defaultSplunkIntegration = "splunk"
+	// splunkEventBufferSize is the number of events buffered per playbook
+	// execution; a full buffer is sent early
+	splunkEventBufferSize = 500
+	// splunkRequestTimeout bounds each request to the event collector
+	splunkRequestTimeout = 30 * time.Second
+	// maxSplunkResponseSize bounds the response body read from the event collector
+	maxSplunkResponseSize = 1 << 20
+)
+
+// Event formats of the splunk_log operation
+const (
+	splunkFormatJSON = "json"
+	splunkFormatRaw  = "raw"
+)
+
+// SplunkHECClient sends events to a Splunk HTTP Event Collector
+type SplunkHECClient struct {
+	baseURL    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewSplunkHECClient creates a client from a splunk integration config. The
+// collector address is the hec_url setting, or the integration's url.
+func NewSplunkHECClient(config *IntegrationConfig) (*SplunkHECClient, error) {
+	if config.Type != splunkIntegrationType {
+		return nil, fmt.Errorf("integration %s is of type %q, not %q", config.Name, config.Type, splunkIntegrationType)
+	}
+	if config.Token == "" {
+		return nil, fmt.Errorf("integration %s has no HEC token", config.Name)
+	}
+
+	baseURL, err := splunkHECBaseURL(config)
+	if err != nil {
+		return nil, fmt.Errorf("integration %s: %v", config.Name, err)
+	}
+
+	return &SplunkHECClient{
+		baseURL:    baseURL,
+		token:      config.Token,
+		httpClient: &http.Client{Timeout: splunkRequestTimeout},
+	}, nil
+}
+
+// splunkHECBaseURL returns the collector address of an integration without a
+// trailing /services/collector path, which is added per request
+func splunkHECBaseURL(config *IntegrationConfig) (string, error) {
+	hecURL, _ := config.Settings["hec_url"].(string)
+	if hecURL == "" {
+		hecURL = config.URL
+	}
+	parsed, err := url.Parse(hecURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("invalid hec_url %q", hecURL)
+	}
+	if index := strings.Index(parsed.Path, "/services/collector"); index >= 0 {
+		parsed.Path = parsed.Path[:index]
+	}
+	parsed.RawQuery = ""
+	return strings.TrimRight(parsed.String(), "/"), nil
+}
+
+// SendEvents posts JSON events in one request. Each event is an HEC event
+// object with its own metadata.
+func (sc *SplunkHECClient) SendEvents(events []map[string]interface{}) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for i, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return fmt.Errorf("failed to encode event %d: %v", i, err)
+		}
+	}
+	return sc.do("/services/collector/event", body.Bytes())
+}
+
+// SendRaw posts raw text events, one per line, in one request. Raw events
+// share the metadata given as query parameters.
+func (sc *SplunkHECClient) SendRaw(lines []string, metadata splunkRawMetadata) error {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"sourcetype": metadata.SourceType,
+		"index":      metadata.Index,
+		"source":     metadata.Source,
+		"host":       metadata.Host,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	path := "/services/collector/raw"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	return sc.do(path, []byte(strings.Join(lines, "\n")))
+}
+
+// do sends a request to the collector, returning an error for non-2xx statuses
+func (sc *SplunkHECClient) do(path string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, sc.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Authorization", "Splunk "+sc.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := sc.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("splunk HEC request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSplunkResponseSize))
+	if err != nil {
+		return fmt.Errorf("failed to read splunk HEC response: %v", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("splunk HEC returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// splunkRawMetadata is the metadata shared by a request of raw events
+type splunkRawMetadata struct {
+	SourceType string
+	Index      string
+	Source     string
+	Host       string
+}
+
+// splunkEvent is an event queued by a splunk_log operation
+type splunkEvent struct {
+	Integration string
+	Format      string
+	Event       map[string]interface{} // JSON format: the HEC event object
+	Line        string                 // Raw format: the event text
+	Metadata    splunkRawMetadata      // Raw format: the event metadata
+}
+
+// splunkEventBuffer collects the events of one playbook execution so they can
+// be sent together when the playbook ends
+type splunkEventBuffer struct {
+	engine *RuleEngine
+	events chan splunkEvent
+}
+
+// newSplunkEventBuffer creates an event buffer that sends through engine's
+// integrations
+func newSplunkEventBuffer(engine *RuleEngine) *splunkEventBuffer {
+	return &splunkEventBuffer{
+		engine: engine,
+		events: make(chan splunkEvent, splunkEventBufferSize),
+	}
+}
+
+// Add queues an event, sending the buffered events first if the buffer is full
+func (sb *splunkEventBuffer) Add(event splunkEvent) error {
+	select {
+	case sb.events <- event:
+		return nil
+	default:
+	}
+
+	if err := sb.Flush(); err != nil {
+		return err
+	}
+	sb.events <- event
+	return nil
+}
+
+// Flush sends the buffered events, one request per integration for JSON
+// events and one per integration and metadata for raw events
+func (sb *splunkEventBuffer) Flush() error {
+	type rawBatch struct {
+		integration string
+		metadata    splunkRawMetadata
+	}
+
+	var integrations []string
+	jsonEvents := make(map[string][]map[string]interface{})
+	var rawBatches []rawBatch
+	rawLines := make(map[rawBatch][]string)
+
+	for drained := false; !drained; {
+		select {
+		case event := <-sb.events:
+			if _, seen := jsonEvents[event.Integration]; !seen {
+				integrations = append(integrations, event.Integration)
+				jsonEvents[event.Integration] = nil
+			}
+			if event.Format == splunkFormatRaw {
+				batch := rawBatch{integration: event.Integration, metadata: event.Metadata}
+				if _, seen := rawLines[batch]; !seen {
+					rawBatches = append(rawBatches, batch)
+				}
+				rawLines[batch] = append(rawLines[batch], event.Line)
+			} else {
+				jsonEvents[event.Integration] = append(jsonEvents[event.Integration], event.Event)
+			}
+		default:
+			drained = true
+		}
+	}
+
+	clients := make(map[string]*SplunkHECClient)
+	for _, name := range integrations {
+		client, err := sb.engine.splunkClient(name)
+		if err != nil {
+			return err
+		}
+		clients[name] = client
+	}
+
+	sent, requests := 0, 0
+	for _, name := range integrations {
+		if events := jsonEvents[name]; len(events) > 0 {
+			if err := clients[name].SendEvents(events); err != nil {
+				return fmt.Errorf("integration %s: %v", name, err)
+			}
+			sent += len(events)
+			requests++
+		}
+	}
+	for _, batch := range rawBatches {
+		if err := clients[batch.integration].SendRaw(rawLines[batch], batch.metadata); err != nil {
+			return fmt.Errorf("integration %s: %v", batch.integration, err)
+		}
+		sent += len(rawLines[batch])
+		requests++
+	}
+
+	if sent > 0 {
+		logger.Info("Sent Splunk events", map[string]interface{}{
+			"component": "rules_engine",
+			"events":    sent,
+			"requests":  requests,
+		})
+	}
+	return nil
+}
+
+// evaluateSplunkLogOperation handles the "splunk_log" operation, which queues
+// an event for Splunk's HTTP Event Collector. Events are sent together when
+// the playbook ends.
+func (re *RuleEngine) evaluateSplunkLogOperation(spec interface{}) (interface{}, error) {
+	if re.splunkEvents == nil {
+		return nil, fmt.Errorf("splunk_log can only be used in a playbook")
+	}
+
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("splunk_log operation requires an object")
+	}
+
+	event, exists := specMap["event"]
+	if !exists || event == nil {
+		return nil, fmt.Errorf("splunk_log operation requires an event")
+	}
+
+	format := splunkFormatJSON
+	if value, exists := specMap["format"]; exists {
+		format, _ = value.(string)
+		if format != splunkFormatJSON && format != splunkFormatRaw {
+			return nil, fmt.Errorf("splunk_log format must be %q or %q", splunkFormatJSON, splunkFormatRaw)
+		}
+	}
+
+	metadata := splunkRawMetadata{}
+	for key, target := range map[string]*string{
+		"sourcetype": &metadata.SourceType,
+		"index":      &metadata.Index,
+		"source":     &metadata.Source,
+		"host":       &metadata.Host,
+	} {
+		if value, exists := specMap[key]; exists && value != nil {
+			*target = fmt.Sprintf("%v", value)
+		}
+	}
+
+	integration := defaultSplunkIntegration
+	if value, ok := specMap["integration"].(string); ok && value != "" {
+		integration = value
+	}
+	// Fail at the rule rather than at the end of the playbook
+	if _, err := re.splunkClient(integration); err != nil {
+		return nil, fmt.Errorf("splunk_log failed: %v", err)
+	}
+
+	queued := splunkEvent{Integration: integration, Format: format}
+	if format == splunkFormatRaw {
+		line, ok := event.(string)
+		if !ok {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return nil, fmt.Errorf("splunk_log failed to encode event: %v", err)
+			}
+			line = string(data)
+		}
+		queued.Line = line
+		queued.Metadata = metadata
+	} else {
+		hecEvent := map[string]interface{}{
+			"event": event,
+			"time":  float64(time.Now().UnixMilli()) / 1000,
+		}
+		if metadata.SourceType != "" {
+			hecEvent["sourcetype"] = metadata.SourceType
+		}
+		if metadata.Index != "" {
+			hecEvent["index"] = metadata.Index
+		}
+		if metadata.Source != "" {
+			hecEvent["source"] = metadata.Source
+		}
+		if metadata.Host != "" {
+			hecEvent["host"] = metadata.Host
+		}
+		queued.Event = hecEvent
+	}
+
+	if err := re.splunkEvents.Add(queued); err != nil {
+		return nil, fmt.Errorf("splunk_log failed: %v", err)
+	}
+
+	return map[string]interface{}{
+		"splunk_log": integration,
+		"status":     "queued",
+		"format":     format,
+	}, nil
+}
+
+// splunkClient creates a client for the named enabled splunk integration
+func (re *RuleEngine) splunkClient(name string) (*SplunkHECClient, error) {
+	if re.integrations == nil {
+		return nil, fmt.Errorf("integration configs are not available")
+	}
+
+	config, exists, err := re.integrations.GetResolvedConfig(name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("integration %s not found", name)
+	}
+	if !config.Enabled {
+		return nil, fmt.Errorf("integration %s is disabled", name)
+	}
+	return NewSplunkHECClient(config)
+}
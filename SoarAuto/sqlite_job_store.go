@@ -0,0 +1,460 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteJobSchema creates the jobs and schedules tables. Jobs and schedules
+// are stored as the same JSON records as in Redis; the columns beside them
+// only serve queries.
+const sqliteJobSchema = `
+CREATE TABLE IF NOT EXISTS jobs (
+	id            TEXT PRIMARY KEY,
+	status        TEXT NOT NULL,
+	playbook_name TEXT NOT NULL DEFAULT '',
+	created_at    INTEGER NOT NULL,
+	version       INTEGER NOT NULL DEFAULT 1,
+	data          BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS jobs_created_at ON jobs (created_at);
+CREATE INDEX IF NOT EXISTS jobs_status ON jobs (status, created_at);
+CREATE INDEX IF NOT EXISTS jobs_playbook ON jobs (playbook_name, created_at);
+CREATE TABLE IF NOT EXISTS schedules (
+	id         TEXT PRIMARY KEY,
+	status     TEXT NOT NULL,
+	next_run   INTEGER,
+	created_at INTEGER NOT NULL,
+	data       BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS schedules_status ON schedules (status, next_run);
+`
+
+// SQLiteJobStore keeps jobs in a SQLite database file, for single-node
+// deployments that want job history across restarts without running Redis
+type SQLiteJobStore struct {
+	db   *sql.DB
+	path string
+}
+
+// NewSQLiteJobStore opens or creates the database at database.sqlite_path
+func NewSQLiteJobStore(dbConfig DatabaseConfig) (*SQLiteJobStore, error) {
+	path := dbConfig.SQLitePath
+	if path == "" {
+		path = "data/jobs.db"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create job database directory: %v", err)
+	}
+
+	// WAL lets API reads proceed while workers write; the busy timeout
+	// covers the brief lock each write takes
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open job database: %v", err)
+	}
+	if _, err := db.Exec(sqliteJobSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create job database schema: %v", err)
+	}
+
+	logger.Info("Initialized SQLite job store", map[string]interface{}{
+		"component": "job_store",
+		"path":      path,
+	})
+
+	return &SQLiteJobStore{db: db, path: path}, nil
+}
+
+// SaveJob inserts or replaces a job, bumping its version
+func (sjs *SQLiteJobStore) SaveJob(job *Job) error {
+	job.SchemaVersion = currentJobSchemaVersion
+	data, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %v", err)
+	}
+
+	_, err = sjs.db.Exec(`
+		INSERT INTO jobs (id, status, playbook_name, created_at, data)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			playbook_name = excluded.playbook_name,
+			created_at = excluded.created_at,
+			data = excluded.data,
+			version = jobs.version + 1`,
+		job.ID, job.Status, job.PlaybookName, job.CreatedAt.UnixNano(), data)
+	if err != nil {
+		return fmt.Errorf("failed to save job: %v", err)
+	}
+	return nil
+}
+
+// GetJobVersion returns the number of times a job has changed, or 0 if it
+// has no recorded changes
+func (sjs *SQLiteJobStore) GetJobVersion(jobID string) (int64, error) {
+	var version int64
+	err := sjs.db.QueryRow(`SELECT version FROM jobs WHERE id = ?`, jobID).Scan(&version)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get job version: %v", err)
+	}
+	return version, nil
+}
+
+// UpdateJob applies update to a job and saves it only if its version is
+// still the one that was read, retrying on the newer record otherwise
+func (sjs *SQLiteJobStore) UpdateJob(jobID string, update func(job *Job) bool) (bool, error) {
+	for attempt := 0; attempt < jobUpdateAttempts; attempt++ {
+		var data []byte
+		var version int64
+		err := sjs.db.QueryRow(`SELECT data, version FROM jobs WHERE id = ?`, jobID).Scan(&data, &version)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return false, jobNotFoundError(jobID)
+			}
+			return false, fmt.Errorf("failed to load job: %v", err)
+		}
+
+		job, _, err := migrateJobData(data)
+		if err != nil {
+			return false, err
+		}
+		if !update(job) {
+			return false, nil
+		}
+
+		job.SchemaVersion = currentJobSchemaVersion
+		data, err = json.Marshal(job)
+		if err != nil {
+			return false, fmt.Errorf("failed to marshal job: %v", err)
+		}
+		result, err := sjs.db.Exec(`
+			UPDATE jobs SET status = ?, playbook_name = ?, created_at = ?, data = ?, version = version + 1
+			WHERE id = ? AND version = ?`,
+			job.Status, job.PlaybookName, job.CreatedAt.UnixNano(), data, jobID, version)
+		if err != nil {
+			return false, fmt.Errorf("failed to save job: %v", err)
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to save job: %v", err)
+		}
+		if updated == 1 {
+			return true, nil
+		}
+		waitBeforeJobUpdateRetry(attempt)
+	}
+	return false, jobUpdateConflictError(jobID)
+}
+
+// LoadJob retrieves a job by ID
+func (sjs *SQLiteJobStore) LoadJob(jobID string) (*Job, bool) {
+	var data []byte
+	err := sjs.db.QueryRow(`SELECT data FROM jobs WHERE id = ?`, jobID).Scan(&data)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Error("Failed to load job", map[string]interface{}{
+				"component": "job_store",
+				"job_id":    jobID,
+				"error":     err.Error(),
+			})
+		}
+		return nil, false
+	}
+
+	job, _, err := migrateJobData(data)
+	if err != nil {
+		logger.Error("Failed to unmarshal job", map[string]interface{}{
+			"component": "job_store",
+			"job_id":    jobID,
+			"error":     err.Error(),
+		})
+		return nil, false
+	}
+	return job, true
+}
+
+// queryJobs loads the jobs returned by a query selecting the data column
+func (sjs *SQLiteJobStore) queryJobs(query string, args ...interface{}) []*Job {
+	var jobs []*Job
+
+	rows, err := sjs.db.Query(query, args...)
+	if err != nil {
+		logger.Error("Failed to query jobs", map[string]interface{}{
+			"component": "job_store",
+			"error":     err.Error(),
+		})
+		return jobs
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			continue
+		}
+		job, _, err := migrateJobData(data)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs
+}
+
+// ListJobs retrieves the most recent jobs, optionally of one status
+func (sjs *SQLiteJobStore) ListJobs(status string, limit int) []*Job {
+	if limit <= 0 {
+		limit = -1 // No limit, as in the other stores
+	}
+	if status == "" {
+		return sjs.queryJobs(`SELECT data FROM jobs ORDER BY created_at DESC LIMIT ?`, limit)
+	}
+	return sjs.queryJobs(`SELECT data FROM jobs WHERE status = ? ORDER BY created_at DESC LIMIT ?`, status, limit)
+}
+
+// ListPlaybookRuns returns the most recent jobs of a playbook, newest first
+func (sjs *SQLiteJobStore) ListPlaybookRuns(playbookName string, limit int) []*Job {
+	if limit <= 0 {
+		limit = -1
+	}
+	return sjs.queryJobs(`SELECT data FROM jobs WHERE playbook_name = ? ORDER BY created_at DESC LIMIT ?`, playbookName, limit)
+}
+
+// UpdateJobStatus updates a job's status
+func (sjs *SQLiteJobStore) UpdateJobStatus(jobID, status string) error {
+	return updateStoredJobStatus(sjs, jobID, status)
+}
+
+// UpdateJobResults updates a job's results and error
+func (sjs *SQLiteJobStore) UpdateJobResults(jobID string, results []interface{}, errorMsg string) error {
+	return updateStoredJobResults(sjs, jobID, results, errorMsg)
+}
+
+// UpdateJobContext updates a job's context
+func (sjs *SQLiteJobStore) UpdateJobContext(jobID string, context map[string]interface{}) error {
+	return updateStoredJobContext(sjs, jobID, context)
+}
+
+// DeleteJob removes a job
+func (sjs *SQLiteJobStore) DeleteJob(jobID string) error {
+	if _, err := sjs.db.Exec(`DELETE FROM jobs WHERE id = ?`, jobID); err != nil {
+		return fmt.Errorf("failed to delete job: %v", err)
+	}
+	return nil
+}
+
+// CleanupOldJobs removes jobs older than specified duration
+func (sjs *SQLiteJobStore) CleanupOldJobs(maxAge time.Duration) error {
+	cutoff := time.Now().Add(-maxAge).UnixNano()
+	result, err := sjs.db.Exec(`DELETE FROM jobs WHERE created_at < ?`, cutoff)
+	if err != nil {
+		return fmt.Errorf("failed to delete old jobs: %v", err)
+	}
+
+	if deleted, _ := result.RowsAffected(); deleted > 0 {
+		logger.Info("Cleaned up old jobs", map[string]interface{}{
+			"component": "job_store",
+			"deleted":   deleted,
+			"max_age":   maxAge.String(),
+		})
+	}
+	return nil
+}
+
+// GetStats returns job statistics
+func (sjs *SQLiteJobStore) GetStats() JobStats {
+	return computeJobStats(sjs.ListJobs("", 1000)) // Get up to 1000 jobs for stats
+}
+
+// BackupJobs copies the database to a timestamped file beside it, keeping
+// backups for 7 days as the Redis store does
+func (sjs *SQLiteJobStore) BackupJobs() error {
+	dir := filepath.Join(filepath.Dir(sjs.path), "backups")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, fmt.Sprintf("jobs-%s.db", time.Now().Format("2006-01-02-15-04-05")))
+	if _, err := sjs.db.Exec(`VACUUM INTO ?`, backupPath); err != nil {
+		return fmt.Errorf("failed to back up job database: %v", err)
+	}
+
+	cutoff := time.Now().Add(-7 * 24 * time.Hour)
+	if old, err := filepath.Glob(filepath.Join(dir, "jobs-*.db")); err == nil {
+		for _, path := range old {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(path)
+			}
+		}
+	}
+
+	logger.Info("Created job backup", map[string]interface{}{
+		"component": "job_store",
+		"path":      backupPath,
+	})
+	return nil
+}
+
+// RecoverJobs recovers jobs that were running during a crash
+func (sjs *SQLiteJobStore) RecoverJobs(engine *RuleEngine, webhookManager *WebhookManager) {
+	recoverStoredJobs(sjs, webhookManager)
+}
+
+// Close closes the database
+func (sjs *SQLiteJobStore) Close() error {
+	logger.Info("Closing SQLite job store", map[string]interface{}{
+		"component": "job_store",
+	})
+	return sjs.db.Close()
+}
+
+// GetDatabaseMetrics returns the number of stored jobs and the database size
+func (sjs *SQLiteJobStore) GetDatabaseMetrics() map[string]interface{} {
+	var jobs int64
+	if err := sjs.db.QueryRow(`SELECT COUNT(*) FROM jobs`).Scan(&jobs); err != nil {
+		return map[string]interface{}{
+			"error": err.Error(),
+		}
+	}
+
+	metrics := map[string]interface{}{
+		"type": "sqlite",
+		"path": sjs.path,
+		"jobs": jobs,
+	}
+	if info, err := os.Stat(sjs.path); err == nil {
+		metrics["size_bytes"] = info.Size()
+	}
+	return metrics
+}
+
+// scheduleNextRun returns the next_run column value of a schedule
+func scheduleNextRun(schedule *JobSchedule) interface{} {
+	if schedule.NextRun == nil {
+		return nil
+	}
+	return schedule.NextRun.UnixNano()
+}
+
+// SaveSchedule inserts or replaces a schedule
+func (sjs *SQLiteJobStore) SaveSchedule(schedule *JobSchedule) error {
+	data, err := marshalSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	_, err = sjs.db.Exec(`
+		INSERT INTO schedules (id, status, next_run, created_at, data)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			status = excluded.status,
+			next_run = excluded.next_run,
+			created_at = excluded.created_at,
+			data = excluded.data`,
+		schedule.ID, string(schedule.Status), scheduleNextRun(schedule), schedule.CreatedAt.UnixNano(), data)
+	if err != nil {
+		return fmt.Errorf("failed to save schedule: %v", err)
+	}
+	return nil
+}
+
+// LoadSchedule retrieves a schedule by ID
+func (sjs *SQLiteJobStore) LoadSchedule(scheduleID string) (*JobSchedule, bool) {
+	var data []byte
+	err := sjs.db.QueryRow(`SELECT data FROM schedules WHERE id = ?`, scheduleID).Scan(&data)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			logger.Error("Failed to load schedule", map[string]interface{}{
+				"component":   "job_store",
+				"schedule_id": scheduleID,
+				"error":       err.Error(),
+			})
+		}
+		return nil, false
+	}
+	return unmarshalSchedule(scheduleID, data)
+}
+
+// querySchedules loads the schedules returned by a query selecting the id
+// and data columns
+func (sjs *SQLiteJobStore) querySchedules(query string, args ...interface{}) []*JobSchedule {
+	schedules := []*JobSchedule{}
+
+	rows, err := sjs.db.Query(query, args...)
+	if err != nil {
+		logger.Error("Failed to query schedules", map[string]interface{}{
+			"component": "job_store",
+			"error":     err.Error(),
+		})
+		return schedules
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scheduleID string
+		var data []byte
+		if err := rows.Scan(&scheduleID, &data); err != nil {
+			continue
+		}
+		if schedule, ok := unmarshalSchedule(scheduleID, data); ok {
+			schedules = append(schedules, schedule)
+		}
+	}
+	return schedules
+}
+
+// ListSchedules retrieves schedules, optionally of one status, oldest first
+func (sjs *SQLiteJobStore) ListSchedules(status string, limit int) []*JobSchedule {
+	if limit <= 0 {
+		limit = -1 // No limit
+	}
+	if status == "" {
+		return sjs.querySchedules(`SELECT id, data FROM schedules ORDER BY created_at, id LIMIT ?`, limit)
+	}
+	return sjs.querySchedules(`SELECT id, data FROM schedules WHERE status = ? ORDER BY created_at, id LIMIT ?`, status, limit)
+}
+
+// UpdateSchedule replaces a stored schedule
+func (sjs *SQLiteJobStore) UpdateSchedule(schedule *JobSchedule) error {
+	data, err := marshalSchedule(schedule)
+	if err != nil {
+		return err
+	}
+
+	result, err := sjs.db.Exec(`UPDATE schedules SET status = ?, next_run = ?, data = ? WHERE id = ?`,
+		string(schedule.Status), scheduleNextRun(schedule), data, schedule.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update schedule: %v", err)
+	}
+	if updated, err := result.RowsAffected(); err == nil && updated == 0 {
+		return fmt.Errorf("schedule not found: %s", schedule.ID)
+	}
+	return nil
+}
+
+// DeleteSchedule removes a schedule
+func (sjs *SQLiteJobStore) DeleteSchedule(scheduleID string) error {
+	if _, err := sjs.db.Exec(`DELETE FROM schedules WHERE id = ?`, scheduleID); err != nil {
+		return fmt.Errorf("failed to delete schedule: %v", err)
+	}
+	return nil
+}
+
+// GetSchedulesDueForExecution returns the active schedules whose next run has come
+func (sjs *SQLiteJobStore) GetSchedulesDueForExecution() []*JobSchedule {
+	return sjs.querySchedules(`
+		SELECT id, data FROM schedules
+		WHERE status = ? AND next_run IS NOT NULL AND next_run <= ?
+		ORDER BY next_run, id`,
+		string(ScheduleStatusActive), time.Now().UnixNano())
+}
@@ -3,15 +3,65 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxRemoteContextSize caps the size of a context document fetched over HTTP
+const maxRemoteContextSize = 10 << 20
+
+// Standalone output formats selected with -o
+const (
+	standaloneOutputText    = "text"
+	standaloneOutputJSON    = "json"
+	standaloneOutputYAML    = "yaml"
+	standaloneOutputSummary = "summary"
 )
 
-func runStandaloneWithFlags(playbookFile, contextFile string) {
+// Standalone exit codes, so CI pipelines can gate on playbook success
+const (
+	standaloneExitSuccess = 0
+	standaloneExitFailed  = 1
+	standaloneExitInvalid = 2
+)
+
+// StandaloneResult is the outcome of a standalone playbook run
+type StandaloneResult struct {
+	Playbook   string                 `json:"playbook" yaml:"playbook"`
+	Success    bool                   `json:"success" yaml:"success"`
+	Error      string                 `json:"error,omitempty" yaml:"error,omitempty"`
+	ErrorType  string                 `json:"error_type,omitempty" yaml:"error_type,omitempty"`     // "assertion_failed" when an assert operation failed
+	Status     string                 `json:"status,omitempty" yaml:"status,omitempty"`             // Set when the playbook aborted or rules failed
+	Reason     string                 `json:"abort_reason,omitempty" yaml:"abort_reason,omitempty"` // Reason given by the abort operation
+	Results    []interface{}          `json:"results" yaml:"results"`
+	Context    map[string]interface{} `json:"context" yaml:"context"`
+	DurationMs int64                  `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// isValidStandaloneOutput reports whether format is a supported -o value
+func isValidStandaloneOutput(format string) bool {
+	switch format {
+	case standaloneOutputText, standaloneOutputJSON, standaloneOutputYAML, standaloneOutputSummary:
+		return true
+	}
+	return false
+}
+
+// runStandaloneWithFlags runs a playbook once, writes the result to stdout in
+// the requested format and returns the process exit code
+func runStandaloneWithFlags(playbookFile, contextFile, outputFormat string) int {
 	// Load configuration
 	config, err := LoadConfig("config.yaml")
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		log.Printf("Failed to load config: %v", err)
+		return standaloneExitInvalid
 	}
 
 	// Create rule engine
@@ -36,9 +86,14 @@ func runStandaloneWithFlags(playbookFile, contextFile string) {
 		},
 	}
 
-	// Load custom context if provided
+	// Load custom context if provided, either from a local file or an http(s) URL
 	if contextFile != "" {
-		customContext, err := loadContextFromFile(contextFile)
+		var customContext map[string]interface{}
+		if isRemoteContextSource(contextFile) {
+			customContext, err = loadContextFromURL(contextFile, config.Integrations.Network)
+		} else {
+			customContext, err = loadContextFromFile(contextFile)
+		}
 		if err != nil {
 			log.Printf("Warning: Failed to load context file %s: %v", contextFile, err)
 			log.Printf("Using default context")
@@ -47,38 +102,114 @@ func runStandaloneWithFlags(playbookFile, contextFile string) {
 		}
 	}
 
-	engine.SetContext(initialContext)
+	context := NewPlaybookContext(initialContext)
 
 	// Load playbook from file
 	playbookData, err := loadPlaybookFromFile(playbookFile)
 	if err != nil {
 		log.Printf("Error loading playbook: %v", err)
-		return
+		return standaloneExitInvalid
 	}
 
-	fmt.Println("=== Rules Engine ===")
-	fmt.Printf("Playbook file: %s\n", playbookFile)
-	if contextFile != "" {
-		fmt.Printf("Context file: %s\n", contextFile)
+	if outputFormat == standaloneOutputText {
+		fmt.Println("=== Rules Engine ===")
+		fmt.Printf("Playbook file: %s\n", playbookFile)
+		if contextFile != "" {
+			fmt.Printf("Context file: %s\n", contextFile)
+		}
+		fmt.Printf("Initial context: %+v\n", context)
 	}
-	fmt.Printf("Initial context: %+v\n", engine.GetContext())
 
 	// Evaluate the playbook
-	results, err := engine.EvaluatePlaybook(playbookData)
-	if err != nil {
+	start := time.Now()
+	results, err := engine.EvaluatePlaybook(playbookData, context)
+	result := StandaloneResult{
+		Playbook:   playbookFile,
+		Success:    err == nil,
+		Results:    results,
+		Context:    context,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if abort, ok := err.(*PlaybookAbort); ok {
+		// A deliberate abort is not a failure
+		result.Success = true
+		result.Status = abort.Status
+		result.Reason = abort.Reason
+	} else if ruleErrors, ok := err.(*PlaybookRuleErrors); ok {
+		// The playbook ran to the end; only rules allowed to fail did
+		result.Success = true
+		result.Status = jobStatusCompletedWithErrors
+		result.Error = ruleErrors.Error()
+	} else if err != nil {
 		log.Printf("Error evaluating playbook: %v", err)
-		return
+		result.Error = err.Error()
+		result.ErrorType = playbookErrorType(err)
+	}
+	if result.Results == nil {
+		result.Results = []interface{}{}
+	}
+
+	if err := writeStandaloneResult(os.Stdout, result, outputFormat); err != nil {
+		log.Printf("Failed to write output: %v", err)
+		return standaloneExitFailed
 	}
 
-	fmt.Println("\n=== Playbook Results ===")
-	for i, result := range results {
-		fmt.Printf("Rule %d result: %+v\n", i+1, result)
+	if !result.Success {
+		return standaloneExitFailed
 	}
+	return standaloneExitSuccess
+}
+
+// writeStandaloneResult formats a standalone run result
+func writeStandaloneResult(w io.Writer, result StandaloneResult, outputFormat string) error {
+	switch outputFormat {
+	case standaloneOutputJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+
+	case standaloneOutputYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(result); err != nil {
+			return err
+		}
+		return encoder.Close()
+
+	case standaloneOutputSummary:
+		status := "PASSED"
+		if !result.Success {
+			status = "FAILED"
+		} else if result.Status != "" {
+			status = strings.ToUpper(result.Status)
+		}
+		fmt.Fprintf(w, "Playbook: %s\n", result.Playbook)
+		fmt.Fprintf(w, "Status:   %s\n", status)
+		fmt.Fprintf(w, "Results:  %d\n", len(result.Results))
+		fmt.Fprintf(w, "Context:  %d keys\n", len(result.Context))
+		fmt.Fprintf(w, "Duration: %dms\n", result.DurationMs)
+		if result.Error != "" {
+			fmt.Fprintf(w, "Error:    %s\n", result.Error)
+		}
+		if result.Reason != "" {
+			fmt.Fprintf(w, "Reason:   %s\n", result.Reason)
+		}
+		return nil
+
+	default:
+		if !result.Success {
+			return nil
+		}
+		fmt.Fprintln(w, "\n=== Playbook Results ===")
+		for i, ruleResult := range result.Results {
+			fmt.Fprintf(w, "Rule %d result: %+v\n", i+1, ruleResult)
+		}
 
-	fmt.Println("\n=== Final Context ===")
-	finalContext := engine.GetContext()
-	contextJSON, _ := json.MarshalIndent(finalContext, "", "  ")
-	fmt.Println(string(contextJSON))
+		fmt.Fprintln(w, "\n=== Final Context ===")
+		contextJSON, _ := json.MarshalIndent(result.Context, "", "  ")
+		fmt.Fprintln(w, string(contextJSON))
+		return nil
+	}
 }
 
 // loadContextFromFile loads context from a JSON file
@@ -91,7 +222,69 @@ func loadContextFromFile(filename string) (map[string]interface{}, error) {
 
 	// Parse JSON
 	var context map[string]interface{}
-	if err := json.Unmarshal(data, &context); err != nil {
+	if err := unmarshalContextJSON(data, &context); err != nil {
+		return nil, fmt.Errorf("failed to parse context JSON: %v", err)
+	}
+
+	return context, nil
+}
+
+// isRemoteContextSource reports whether a context source is an http(s) URL
+func isRemoteContextSource(source string) bool {
+	lower := strings.ToLower(source)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// loadContextFromURL fetches a JSON context document over HTTP, honouring the
+// configured network allow/block lists (including on redirects)
+func loadContextFromURL(rawURL string, network NetworkConfig) (map[string]interface{}, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid context URL: %v", err)
+	}
+	if !network.IsHostAllowed(parsed.Hostname()) {
+		return nil, fmt.Errorf("host %s is not allowed by the network configuration", parsed.Hostname())
+	}
+
+	timeout := time.Duration(network.ConnectionTimeout) * time.Second
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	client := &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			if !network.IsHostAllowed(req.URL.Hostname()) {
+				return fmt.Errorf("redirect to host %s is not allowed by the network configuration", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+
+	resp, err := client.Get(parsed.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch context: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch context: server returned %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxRemoteContextSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read context response: %v", err)
+	}
+	if len(data) > maxRemoteContextSize {
+		return nil, fmt.Errorf("context response exceeds %d bytes", maxRemoteContextSize)
+	}
+
+	// Parse JSON
+	var context map[string]interface{}
+	if err := unmarshalContextJSON(data, &context); err != nil {
 		return nil, fmt.Errorf("failed to parse context JSON: %v", err)
 	}
 
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// storageCategoryDirs maps each upload category to the directory it is
+// stored in. Plugins are kept in one subdirectory per platform.
+var storageCategoryDirs = map[string]string{
+	"playbooks":    "../playbooks",
+	"automations":  "../automations",
+	"plugins":      "../plugins",
+	"integrations": "../integrations",
+}
+
+// StorageCategoryUsage is the number and total size of the files stored in
+// one category
+type StorageCategoryUsage struct {
+	Count    int   `json:"count"`
+	Bytes    int64 `json:"bytes"`
+	MaxCount int   `json:"max_count,omitempty"`
+}
+
+// StorageUsage is the disk used by uploaded files against the configured
+// limits. StagedBytes is reserved by chunked uploads still in progress and
+// counts against MaxTotalBytes alongside TotalBytes.
+type StorageUsage struct {
+	Categories    map[string]StorageCategoryUsage `json:"categories"`
+	TotalBytes    int64                           `json:"total_bytes"`
+	StagedBytes   int64                           `json:"staged_bytes"`
+	MaxTotalBytes int64                           `json:"max_total_bytes,omitempty"`
+}
+
+// StorageResponse is the response for GET /storage and for uploads rejected
+// by a storage limit
+type StorageResponse struct {
+	Success   bool         `json:"success"`
+	Error     string       `json:"error,omitempty"`
+	Usage     StorageUsage `json:"usage"`
+	Timestamp string       `json:"timestamp"`
+}
+
+// storageQuotaError reports an upload that would exceed a storage limit
+type storageQuotaError struct {
+	message string
+	usage   StorageUsage
+}
+
+func (e *storageQuotaError) Error() string {
+	return e.message
+}
+
+// maxCount returns the file limit for a category, 0 when unlimited
+func (c StorageConfig) maxCount(category string) int {
+	switch category {
+	case "playbooks":
+		return c.MaxPlaybooks
+	case "automations":
+		return c.MaxAutomations
+	case "plugins":
+		return c.MaxPlugins
+	case "integrations":
+		return c.MaxIntegrations
+	}
+	return 0
+}
+
+// storageUsage walks the upload directories and totals their files
+func (s *SecAutoServer) storageUsage() (StorageUsage, error) {
+	usage := StorageUsage{
+		Categories:    make(map[string]StorageCategoryUsage, len(storageCategoryDirs)),
+		MaxTotalBytes: s.storageLimits.MaxTotalBytes,
+	}
+
+	for category, dir := range storageCategoryDirs {
+		categoryUsage := StorageCategoryUsage{MaxCount: s.storageLimits.maxCount(category)}
+		err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			categoryUsage.Count++
+			categoryUsage.Bytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			return StorageUsage{}, fmt.Errorf("failed to measure %s storage: %v", category, err)
+		}
+		usage.Categories[category] = categoryUsage
+		usage.TotalBytes += categoryUsage.Bytes
+	}
+	if s.uploadManager != nil {
+		usage.StagedBytes = s.uploadManager.StagedBytes()
+	}
+	return usage, nil
+}
+
+// checkStorageQuota checks that writing size bytes to path, replacing any
+// file already there, keeps the category within its limits. Callers hold
+// storageMutex until the file is written, so concurrent uploads cannot both
+// take the last of the space.
+func (s *SecAutoServer) checkStorageQuota(category, path string, size int64) error {
+	maxCount := s.storageLimits.maxCount(category)
+	if maxCount <= 0 && s.storageLimits.MaxTotalBytes <= 0 {
+		return nil
+	}
+
+	usage, err := s.storageUsage()
+	if err != nil {
+		return err
+	}
+
+	replaced := int64(-1)
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		replaced = info.Size()
+	}
+
+	categoryUsage := usage.Categories[category]
+	if replaced < 0 && maxCount > 0 && categoryUsage.Count >= maxCount {
+		return &storageQuotaError{
+			message: fmt.Sprintf("storage limit reached: %d of %d %s stored", categoryUsage.Count, maxCount, category),
+			usage:   usage,
+		}
+	}
+
+	total := usage.TotalBytes + usage.StagedBytes + size
+	if replaced > 0 {
+		total -= replaced
+	}
+	if s.storageLimits.MaxTotalBytes > 0 && total > s.storageLimits.MaxTotalBytes {
+		return &storageQuotaError{
+			message: fmt.Sprintf("storage limit reached: %d bytes would exceed the %d byte limit", total, s.storageLimits.MaxTotalBytes),
+			usage:   usage,
+		}
+	}
+	return nil
+}
+
+// checkStagingQuota checks that a chunked upload of size bytes fits the total
+// byte limit alongside stored files and other uploads in progress. File
+// counts are checked when the upload completes, once its path is known.
+// Callers hold storageMutex.
+func (s *SecAutoServer) checkStagingQuota(size int64) error {
+	if s.storageLimits.MaxTotalBytes <= 0 {
+		return nil
+	}
+
+	usage, err := s.storageUsage()
+	if err != nil {
+		return err
+	}
+
+	total := usage.TotalBytes + usage.StagedBytes + size
+	if total > s.storageLimits.MaxTotalBytes {
+		return &storageQuotaError{
+			message: fmt.Sprintf("storage limit reached: %d bytes would exceed the %d byte limit", total, s.storageLimits.MaxTotalBytes),
+			usage:   usage,
+		}
+	}
+	return nil
+}
+
+// writeStorageQuotaError answers 507 Insufficient Storage with the current
+// usage if err is a storage limit error, and reports whether it did
+func writeStorageQuotaError(w http.ResponseWriter, err error) bool {
+	var quotaErr *storageQuotaError
+	if !errors.As(err, &quotaErr) {
+		return false
+	}
+
+	logger.Warning("Upload rejected by storage limit", map[string]interface{}{
+		"component": "server",
+		"error":     quotaErr.Error(),
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusInsufficientStorage)
+	json.NewEncoder(w).Encode(StorageResponse{
+		Success:   false,
+		Error:     quotaErr.Error(),
+		Usage:     quotaErr.usage,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+	return true
+}
+
+// storageHandler reports the storage used by each upload category
+func (s *SecAutoServer) storageHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	usage, err := s.storageUsage()
+	if err != nil {
+		logger.Error("Failed to measure storage", map[string]interface{}{
+			"component": "server",
+			"error":     err.Error(),
+		})
+		http.Error(w, fmt.Sprintf("Failed to measure storage: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StorageResponse{
+		Success:   true,
+		Usage:     usage,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// WithStrictMode returns an engine that evaluates strictly or leniently for
+// one run, without changing the configuration. Strictly, rules fail on
+// unresolved template variables and unknown operations, and comparing values
+// of different types is an error. Leniently, unresolved variables are only
+// reported as warnings, unknown operations are logged and evaluate to
+// themselves, and such comparisons are false. A nil strict keeps the
+// configured behavior.
+func (re *RuleEngine) WithStrictMode(strict *bool) *RuleEngine {
+	if strict == nil {
+		return re
+	}
+	engine := *re
+	engine.strictTemplates = *strict
+	engine.strictComparisons = *strict
+	engine.lenientOperations = !*strict
+	return &engine
+}
+
+// checkComparisonTypes fails a comparison of values of different types, such
+// as the string "5" and the number 5, when comparisons are strict. null may
+// be compared with anything, so a missing value can still be tested for.
+func (re *RuleEngine) checkComparisonTypes(left, right interface{}, op string) error {
+	if !re.strictComparisons || left == nil || right == nil {
+		return nil
+	}
+	leftKind, rightKind := comparisonKind(left), comparisonKind(right)
+	if leftKind != rightKind {
+		return fmt.Errorf("strict mode: comparison operator %s cannot compare %s with %s", op, leftKind, rightKind)
+	}
+	return nil
+}
+
+// comparisonKind returns the JSON type of a value: number, string, boolean,
+// array or object
+func comparisonKind(value interface{}) string {
+	if _, ok := jsonLogicNumeric(value); ok {
+		return "number"
+	}
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case map[string]interface{}:
+		return "object"
+	}
+	if kind := reflect.ValueOf(value).Kind(); kind == reflect.Slice || kind == reflect.Array {
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// unknownOperation fails an operation the engine does not know, or when
+// operations are lenient logs it and returns it unevaluated, as a literal
+func (re *RuleEngine) unknownOperation(operation map[string]interface{}) (interface{}, error) {
+	if re.lenientOperations {
+		logger.Warning("Unknown operation returned as a literal", map[string]interface{}{
+			"component": "rules_engine",
+			"operation": operation,
+		})
+		return operation, nil
+	}
+	logger.Error("Unknown operation", map[string]interface{}{
+		"component": "rules_engine",
+		"operation": operation,
+	})
+	return nil, fmt.Errorf("unknown operation: %v", operation)
+}
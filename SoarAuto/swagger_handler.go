@@ -1,14 +1,22 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 )
 
+// openAPIFieldOrder is the order the top-level fields of the spec are written
+// in, that of the OpenAPI specification, so the version comes first
+var openAPIFieldOrder = []string{"openapi", "info", "servers", "tags", "security", "paths", "components"}
+
 // SwaggerUIHandler handles serving the Swagger UI documentation
 type SwaggerUIHandler struct {
 	openAPISpec []byte
+	specETag    string
 }
 
 // NewSwaggerUIHandler creates a new Swagger UI handler
@@ -21,6 +29,7 @@ func NewSwaggerUIHandler(serverPort string) (*SwaggerUIHandler, error) {
 
 	return &SwaggerUIHandler{
 		openAPISpec: spec,
+		specETag:    fmt.Sprintf(`"%x"`, sha256.Sum256(spec)),
 	}, nil
 }
 
@@ -28,6 +37,13 @@ func NewSwaggerUIHandler(serverPort string) (*SwaggerUIHandler, error) {
 func (h *SwaggerUIHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Handle API spec request
 	if r.URL.Path == "/api-docs" {
+		// The spec is byte-stable, so its ETag only changes with the API
+		w.Header().Set("ETag", h.specETag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == h.specETag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
 		w.Write(h.openAPISpec)
 		return
@@ -191,10 +207,98 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
+			"/health/ready": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Readiness Check",
+					"description": "Check whether the server's dependencies are available. While Redis is down the job endpoints answer 503, job writes are queued and retried, and Redis-independent endpoints such as /playbook, /validate and /playbooks keep working.",
+					"tags":        []string{"Health"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "All dependencies are available",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"status": map[string]interface{}{
+												"type": "string",
+												"enum": []string{"ready", "degraded"},
+											},
+											"timestamp": map[string]interface{}{
+												"type":   "string",
+												"format": "date-time",
+											},
+											"redis": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"status":         map[string]interface{}{"type": "string", "enum": []string{"up", "down"}},
+													"last_error":     map[string]interface{}{"type": "string"},
+													"down_since":     map[string]interface{}{"type": "string", "format": "date-time"},
+													"last_check":     map[string]interface{}{"type": "string", "format": "date-time"},
+													"pending_writes": map[string]interface{}{"type": "integer", "description": "Job writes waiting for Redis to return"},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+						"503": map[string]interface{}{
+							"description": "Redis is unavailable; the server is degraded",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"status": map[string]interface{}{
+												"type": "string",
+												"enum": []string{"ready", "degraded"},
+											},
+											"timestamp": map[string]interface{}{
+												"type":   "string",
+												"format": "date-time",
+											},
+											"redis": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"status":         map[string]interface{}{"type": "string", "enum": []string{"up", "down"}},
+													"last_error":     map[string]interface{}{"type": "string"},
+													"down_since":     map[string]interface{}{"type": "string", "format": "date-time"},
+													"last_check":     map[string]interface{}{"type": "string", "format": "date-time"},
+													"pending_writes": map[string]interface{}{"type": "integer", "description": "Job writes waiting for Redis to return"},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/selftest": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Self-Test",
+					"description": "Run a built-in playbook end to end and check the rule engine, Python venv and plugin manager. Requires an admin API key.",
+					"tags":        []string{"Health"},
+					"security":    []map[string]interface{}{{"ApiKeyAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "All subsystem checks passed",
+						},
+						"403": map[string]interface{}{
+							"description": "Admin API key required",
+						},
+						"503": map[string]interface{}{
+							"description": "One or more subsystem checks failed; see the per-check report",
+						},
+					},
+				},
+			},
 			"/playbook": map[string]interface{}{
 				"post": map[string]interface{}{
-					"summary":     "Execute Playbook Synchronously",
-					"description": "Execute a playbook immediately and return results",
+					"summary":     "Execute Playbook",
+					"description": "Execute a playbook immediately and return results. With async set the playbook is submitted as a job instead, as with /playbook/async.",
 					"tags":        []string{"Playbooks"},
 					"requestBody": map[string]interface{}{
 						"required": true,
@@ -212,6 +316,31 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 											"type":        "object",
 											"description": "Initial context data",
 										},
+										"env": map[string]interface{}{
+											"type":                 "object",
+											"additionalProperties": map[string]interface{}{"type": "string"},
+											"description":          "Environment variables passed to Python automations for this run",
+										},
+										"async": map[string]interface{}{
+											"type":        "boolean",
+											"default":     false,
+											"description": "Submit the playbook as a job and return its ID instead of executing it synchronously",
+										},
+										"priority": map[string]interface{}{
+											"type":        "string",
+											"enum":        []string{"low", "normal", "high", "critical"},
+											"default":     "normal",
+											"description": "Priority recorded on the job when async is set",
+										},
+										"continue_on_error": map[string]interface{}{
+											"type":        "boolean",
+											"default":     false,
+											"description": "Let rules without their own continue_on_error flag fail without stopping the playbook; the run then finishes with status completed_with_errors",
+										},
+										"strict": map[string]interface{}{
+											"type":        "boolean",
+											"description": "Evaluate this playbook strictly (true) or leniently (false) instead of as configured. Strictly, unresolved template variables, unknown operations and comparisons of values of different types fail the rule; leniently, they are a warning, a literal and false",
+										},
 										"options": map[string]interface{}{
 											"type":        "object",
 											"description": "Execution options",
@@ -220,11 +349,6 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 													"type":        "integer",
 													"description": "Execution timeout in seconds",
 												},
-												"priority": map[string]interface{}{
-													"type":        "string",
-													"enum":        []string{"low", "normal", "high", "critical"},
-													"description": "Job priority",
-												},
 											},
 										},
 									},
@@ -250,6 +374,24 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 											"context": map[string]interface{}{
 												"type": "object",
 											},
+											"warnings": map[string]interface{}{
+												"type":        "array",
+												"description": "Template variables that could not be resolved",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"type":     map[string]interface{}{"type": "string"},
+														"variable": map[string]interface{}{"type": "string"},
+														"template": map[string]interface{}{"type": "string"},
+														"count":    map[string]interface{}{"type": "integer"},
+													},
+												},
+											},
+											"findings": map[string]interface{}{
+												"type":        "array",
+												"description": "Recorded by finding operations, apart from results",
+												"items":       map[string]interface{}{"$ref": "#/components/schemas/Finding"},
+											},
 											"timestamp": map[string]interface{}{
 												"type": "string",
 											},
@@ -258,16 +400,28 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 								},
 							},
 						},
+						"202": map[string]interface{}{
+							"description": "Job submitted successfully (async set)",
+							"headers": map[string]interface{}{
+								"Location": map[string]interface{}{
+									"description": "Status URL of the job, /job/{id}",
+									"schema":      map[string]interface{}{"type": "string"},
+								},
+							},
+						},
 						"400": map[string]interface{}{
 							"description": "Invalid request",
 						},
+						"503": map[string]interface{}{
+							"description": "Too many concurrent synchronous executions (see Retry-After)",
+						},
 					},
 				},
 			},
 			"/playbook/async": map[string]interface{}{
 				"post": map[string]interface{}{
 					"summary":     "Execute Playbook Asynchronously",
-					"description": "Submit a playbook for asynchronous execution",
+					"description": "Submit a playbook for asynchronous execution. When deduplication is enabled, a submission whose playbook and context were probably already submitted returns status \"duplicate\" with probable_duplicate set and no job is created.",
 					"tags":        []string{"Playbooks"},
 					"requestBody": map[string]interface{}{
 						"required": true,
@@ -283,6 +437,25 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 										"context": map[string]interface{}{
 											"type": "object",
 										},
+										"env": map[string]interface{}{
+											"type":                 "object",
+											"additionalProperties": map[string]interface{}{"type": "string"},
+											"description":          "Environment variables passed to Python automations for this run",
+										},
+										"priority": map[string]interface{}{
+											"type":    "string",
+											"enum":    []string{"low", "normal", "high", "critical"},
+											"default": "normal",
+										},
+										"continue_on_error": map[string]interface{}{
+											"type":        "boolean",
+											"default":     false,
+											"description": "Let rules without their own continue_on_error flag fail without stopping the playbook; the job then finishes with status completed_with_errors",
+										},
+										"strict": map[string]interface{}{
+											"type":        "boolean",
+											"description": "Evaluate this playbook strictly (true) or leniently (false) instead of as configured. Strictly, unresolved template variables, unknown operations and comparisons of values of different types fail the rule; leniently, they are a warning, a literal and false",
+										},
 									},
 									"required": []string{"playbook"},
 								},
@@ -290,8 +463,17 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 						},
 					},
 					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Probable duplicate submission; no job was created",
+						},
 						"202": map[string]interface{}{
 							"description": "Job submitted successfully",
+							"headers": map[string]interface{}{
+								"Location": map[string]interface{}{
+									"description": "Status URL of the job, /job/{id}",
+									"schema":      map[string]interface{}{"type": "string"},
+								},
+							},
 						},
 						"400": map[string]interface{}{
 							"description": "Invalid request",
@@ -311,7 +493,7 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 							"description": "Filter by job status",
 							"schema": map[string]interface{}{
 								"type": "string",
-								"enum": []string{"pending", "running", "completed", "failed", "cancelled"},
+								"enum": []string{"pending", "running", "completed", "completed_with_errors", "failed", "cancelled", "aborted", "skipped"},
 							},
 						},
 						{
@@ -337,6 +519,19 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
 							"description": "Jobs retrieved successfully",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success":   map[string]interface{}{"type": "boolean"},
+											"jobs":      map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/components/schemas/Job"}},
+											"total":     map[string]interface{}{"type": "integer"},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
 						},
 					},
 				},
@@ -344,7 +539,7 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 			"/jobs/stats": map[string]interface{}{
 				"get": map[string]interface{}{
 					"summary":     "Job Statistics",
-					"description": "Get comprehensive job statistics and metrics",
+					"description": "Get comprehensive job statistics and metrics. While CPU or memory usage tracking is enabled, resource_usage totals the CPU time and peak memory of the jobs' automation processes and lists the automations that used the most CPU time.",
 					"tags":        []string{"Jobs"},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
@@ -353,169 +548,679 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
-			"/jobs/metrics": map[string]interface{}{
+			"/metrics": map[string]interface{}{
 				"get": map[string]interface{}{
-					"summary":     "Database Performance Metrics",
-					"description": "Get database performance metrics and connection pool statistics",
+					"summary":     "Playbook Metrics",
+					"description": "Counters and gauges recorded by metric operations in playbooks, in the Prometheus text exposition format. Empty while monitoring.custom_metrics is off.",
 					"tags":        []string{"Jobs"},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "Metrics retrieved successfully",
+							"description": "Metrics in the Prometheus text format",
+							"content": map[string]interface{}{
+								"text/plain": map[string]interface{}{
+									"schema": map[string]interface{}{"type": "string"},
+								},
+							},
 						},
 					},
 				},
 			},
-			"/plugins": map[string]interface{}{
+			"/jobs/metrics": map[string]interface{}{
 				"get": map[string]interface{}{
-					"summary":     "List All Plugins",
-					"description": "Retrieve a list of all available plugins",
-					"tags":        []string{"Plugins"},
+					"summary":     "Database Performance Metrics",
+					"description": "Get database performance metrics and connection pool statistics, plus enrichment cache hits and misses when the cache is enabled, calls and wait time per throttle bucket, the job queue's length and worker usage, and the timing of run, plugin and play operations per type and target when monitoring.performance_tracking is on",
+					"tags":        []string{"Jobs"},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "Plugins retrieved successfully",
+							"description": "Metrics retrieved successfully",
 						},
 					},
 				},
 			},
-			"/automation": map[string]interface{}{
-				"post": map[string]interface{}{
-					"summary":     "Upload Automation Script",
-					"description": "Upload a new automation script to the system. The script will be validated for security and stored in the automations directory.",
-					"tags":        []string{"Automations"},
-					"requestBody": map[string]interface{}{
-						"required": true,
-						"content": map[string]interface{}{
-							"multipart/form-data": map[string]interface{}{
-								"schema": map[string]interface{}{
-									"type": "object",
-									"properties": map[string]interface{}{
-										"automation": map[string]interface{}{
-											"type":        "string",
-											"format":      "binary",
-											"description": "Python automation script file (.py)",
-										},
-									},
-									"required": []string{"automation"},
-								},
+			"/job/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get Job Status",
+					"description": "Get a job's status, results and context. A pending job also reports its queue position; jobs run in priority order, then in submission order. eta_seconds estimates when it starts from the average run time of recent jobs and the number of workers. With wait, the request is held until the job's status changes, returning at once if the job has already finished, and returns the current state when the wait passes without a change.",
+					"tags":        []string{"Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "wait",
+							"in":          "query",
+							"description": "Seconds to wait for the job's status to change (default 0, maximum 120)",
+							"schema": map[string]interface{}{
+								"type":    "integer",
+								"default": 0,
+								"maximum": 120,
 							},
 						},
 					},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "Automation uploaded successfully",
+							"description": "Job found",
 							"content": map[string]interface{}{
 								"application/json": map[string]interface{}{
 									"schema": map[string]interface{}{
-										"type": "object",
-										"properties": map[string]interface{}{
-											"success": map[string]interface{}{
-												"type": "boolean",
-											},
-											"message": map[string]interface{}{
-												"type": "string",
-											},
-											"automation_name": map[string]interface{}{
-												"type": "string",
-											},
-											"filename": map[string]interface{}{
-												"type": "string",
-											},
-											"size": map[string]interface{}{
-												"type": "integer",
-											},
-											"timestamp": map[string]interface{}{
-												"type":   "string",
-												"format": "date-time",
-											},
+										"allOf": []map[string]interface{}{
+											{"$ref": "#/components/schemas/Job"},
 										},
-									},
-								},
-							},
-						},
-						"400": map[string]interface{}{
-							"description": "Validation failed",
-							"content": map[string]interface{}{
-								"application/json": map[string]interface{}{
-									"schema": map[string]interface{}{
 										"type": "object",
 										"properties": map[string]interface{}{
-											"success": map[string]interface{}{
-												"type": "boolean",
-											},
-											"valid": map[string]interface{}{
-												"type": "boolean",
-											},
-											"errors": map[string]interface{}{
-												"type": "array",
-												"items": map[string]interface{}{
-													"type": "object",
-													"properties": map[string]interface{}{
-														"field": map[string]interface{}{
-															"type": "string",
-														},
-														"message": map[string]interface{}{
-															"type": "string",
-														},
-														"value": map[string]interface{}{
-															"type": "string",
-														},
-													},
+											"queue": map[string]interface{}{
+												"type":        "object",
+												"description": "Present while the job is pending",
+												"properties": map[string]interface{}{
+													"position":             map[string]interface{}{"type": "integer", "description": "1 for the next job to start"},
+													"queue_length":         map[string]interface{}{"type": "integer"},
+													"workers":              map[string]interface{}{"type": "integer"},
+													"running":              map[string]interface{}{"type": "integer"},
+													"avg_duration_seconds": map[string]interface{}{"type": "number"},
+													"eta_seconds":          map[string]interface{}{"type": "number"},
+													"estimated_start":      map[string]interface{}{"type": "string", "format": "date-time"},
 												},
 											},
-											"message": map[string]interface{}{
-												"type": "string",
-											},
-											"timestamp": map[string]interface{}{
-												"type":   "string",
-												"format": "date-time",
-											},
 										},
 									},
 								},
 							},
 						},
+						"404": map[string]interface{}{
+							"description": "Job not found",
+						},
 					},
 				},
-			},
-			"/playbook/upload": map[string]interface{}{
-				"post": map[string]interface{}{
-					"summary":     "Upload Playbook File",
-					"description": "Upload a new playbook file to the system. The file will be validated for structure and stored in the playbooks directory.",
-					"tags":        []string{"Playbooks"},
-					"requestBody": map[string]interface{}{
-						"required": true,
-						"content": map[string]interface{}{
-							"multipart/form-data": map[string]interface{}{
-								"schema": map[string]interface{}{
-									"type": "object",
-									"properties": map[string]interface{}{
-										"playbook": map[string]interface{}{
-											"type":        "string",
-											"format":      "binary",
-											"description": "Playbook JSON file (.json)",
-										},
-									},
-									"required": []string{"playbook"},
-								},
+				"delete": map[string]interface{}{
+					"summary":     "Cancel Job",
+					"description": "Cancel a pending job. The optional reason and the calling API key (masked) are stored on the job as cancel_reason and cancelled_by and sent in the job_cancelled webhook.",
+					"tags":        []string{"Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "reason",
+							"in":          "query",
+							"required":    false,
+							"description": "Why the job was cancelled; may instead be sent as {\"reason\": \"...\"} in the body",
+							"schema": map[string]interface{}{
+								"type": "string",
 							},
 						},
 					},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "Playbook uploaded successfully",
-							"content": map[string]interface{}{
-								"application/json": map[string]interface{}{
-									"schema": map[string]interface{}{
-										"type": "object",
-										"properties": map[string]interface{}{
-											"success": map[string]interface{}{
-												"type": "boolean",
-											},
-											"message": map[string]interface{}{
-												"type": "string",
-											},
-											"playbook_name": map[string]interface{}{
-												"type": "string",
+							"description": "Job cancelled",
+						},
+						"400": map[string]interface{}{
+							"description": "Job not found, running, or already finished",
+						},
+					},
+				},
+			},
+			"/job/{id}/diff": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Job Context Diff",
+					"description": "Get the keys a job's playbook run added, removed, or changed in its context, with before and after values",
+					"tags":        []string{"Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Context diff computed successfully",
+						},
+						"404": map[string]interface{}{
+							"description": "Job not found",
+						},
+						"409": map[string]interface{}{
+							"description": "Job has not stored a final context yet",
+						},
+					},
+				},
+			},
+			"/job/{id}/poll": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Long-Poll Job",
+					"description": "Wait for a job to change. Every status, results or context update increments the job's version; the request returns as soon as the version is newer than since_version, or 304 Not Modified when the timeout passes without a change",
+					"tags":        []string{"Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "timeout",
+							"in":          "query",
+							"description": "Seconds to wait for a change (default 30, maximum 120)",
+							"schema": map[string]interface{}{
+								"type":    "integer",
+								"default": 30,
+								"maximum": 120,
+							},
+						},
+						{
+							"name":        "since_version",
+							"in":          "query",
+							"description": "Version from the previous poll; 0 returns the job immediately",
+							"schema": map[string]interface{}{
+								"type":    "integer",
+								"default": 0,
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Job changed; the response includes the job and its current version",
+						},
+						"304": map[string]interface{}{
+							"description": "No change within the timeout; the X-Job-Version header holds the current version",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid timeout or since_version",
+						},
+						"404": map[string]interface{}{
+							"description": "Job not found",
+						},
+					},
+				},
+			},
+			"/results/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Full Results",
+					"description": "Get the full results of a run whose results were truncated to results.max_bytes, when results.offload is enabled. The ID is the job ID, or the one in a synchronous response's full_results.",
+					"tags":        []string{"Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Full results retrieved successfully",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid results ID",
+						},
+						"404": map[string]interface{}{
+							"description": "No offloaded results with this ID, or offloading not enabled",
+						},
+					},
+				},
+			},
+			"/archive": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Archived Jobs",
+					"description": "Get jobs that were archived to cold storage after their TTL expired",
+					"tags":        []string{"Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "from",
+							"in":          "query",
+							"description": "Start date (YYYY-MM-DD or RFC3339), defaults to 24 hours ago",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "to",
+							"in":          "query",
+							"description": "End date (YYYY-MM-DD or RFC3339), defaults to now",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Archived jobs retrieved successfully",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid date range",
+						},
+						"503": map[string]interface{}{
+							"description": "Job archival not enabled",
+						},
+					},
+				},
+			},
+			"/events": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Activity Feed",
+					"description": "Recent events in chronological order: jobs submitted, started and finished, schedules fired, plugins reloaded, library problems found and integrations changed. Only the newest events.history_size events are kept.",
+					"tags":        []string{"Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "type",
+							"in":          "query",
+							"description": "Event types, comma-separated, e.g. job_failed,job_aborted",
+							"schema":      map[string]interface{}{"type": "string"},
+						},
+						{
+							"name":        "job_id",
+							"in":          "query",
+							"description": "Only events of this job",
+							"schema":      map[string]interface{}{"type": "string"},
+						},
+						{
+							"name":        "since",
+							"in":          "query",
+							"description": "Only events at or after this RFC 3339 time",
+							"schema":      map[string]interface{}{"type": "string", "format": "date-time"},
+						},
+						{
+							"name":        "limit",
+							"in":          "query",
+							"description": "Most events returned, newest kept",
+							"schema":      map[string]interface{}{"type": "integer", "default": 100},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Events retrieved successfully",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{"type": "boolean"},
+											"events": map[string]interface{}{
+												"type": "array",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"id":          map[string]interface{}{"type": "string"},
+														"type":        map[string]interface{}{"type": "string", "example": "job_completed"},
+														"timestamp":   map[string]interface{}{"type": "string", "format": "date-time"},
+														"job_id":      map[string]interface{}{"type": "string"},
+														"playbook":    map[string]interface{}{"type": "string"},
+														"schedule_id": map[string]interface{}{"type": "string"},
+														"plugin":      map[string]interface{}{"type": "string"},
+														"integration": map[string]interface{}{"type": "string"},
+														"status":      map[string]interface{}{"type": "string"},
+														"details":     map[string]interface{}{"type": "object"},
+													},
+												},
+											},
+											"count":     map[string]interface{}{"type": "integer"},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid filter",
+						},
+					},
+				},
+			},
+			"/events/stream": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Stream Activity",
+					"description": "Stream events as Server-Sent Events, each with the event type as its SSE event name and the event as JSON data. Reconnecting with a Last-Event-ID header first replays the events missed, if still retained.",
+					"tags":        []string{"Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "type",
+							"in":          "query",
+							"description": "Event types, comma-separated, e.g. job_failed,job_aborted",
+							"schema":      map[string]interface{}{"type": "string"},
+						},
+						{
+							"name":        "job_id",
+							"in":          "query",
+							"description": "Only events of this job",
+							"schema":      map[string]interface{}{"type": "string"},
+						},
+						{
+							"name":        "since",
+							"in":          "query",
+							"description": "Only events at or after this RFC 3339 time",
+							"schema":      map[string]interface{}{"type": "string", "format": "date-time"},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Event stream",
+							"content": map[string]interface{}{
+								"text/event-stream": map[string]interface{}{
+									"schema": map[string]interface{}{"type": "string"},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid filter",
+						},
+					},
+				},
+			},
+			"/exports/links": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Create Download Link",
+					"description": "Issue a signed link that lets an export (/archive or /playbooks/{name}/export) be fetched once, before it expires, without an API key",
+					"tags":        []string{"Jobs"},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"path"},
+									"properties": map[string]interface{}{
+										"path": map[string]interface{}{"type": "string", "example": "/playbooks/triage/export?format=yaml"},
+										"ttl":  map[string]interface{}{"type": "string", "example": "10m", "description": "Defaults to security.download_links.default_ttl, at most max_ttl"},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{
+							"description": "Link created",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success":    map[string]interface{}{"type": "boolean"},
+											"url":        map[string]interface{}{"type": "string", "description": "Relative URL of the link, under /download"},
+											"path":       map[string]interface{}{"type": "string"},
+											"expires_at": map[string]interface{}{"type": "string", "format": "date-time"},
+											"timestamp":  map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Path is not an export or ttl is invalid",
+						},
+					},
+				},
+			},
+			"/download": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Fetch Download Link",
+					"description": "Serve the export a signed link grants. No API key is needed; the link works once.",
+					"tags":        []string{"Jobs"},
+					"security":    []map[string]interface{}{},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "The export",
+						},
+						"403": map[string]interface{}{
+							"description": "Invalid signature",
+						},
+						"410": map[string]interface{}{
+							"description": "Link expired or already used",
+						},
+					},
+				},
+			},
+			"/context/import/csv": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Import CSV Context",
+					"description": "Import CSV rows as playbook context using a column mapping. In context mode the rows are returned as an array under a context variable for use with foreach (and submitted as one job when playbook_name is set); in jobs mode one job is submitted per row.",
+					"tags":        []string{"Playbooks"},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"multipart/form-data": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"file": map[string]interface{}{
+											"type":        "string",
+											"format":      "binary",
+											"description": "CSV file with a header row",
+										},
+										"column_mapping": map[string]interface{}{
+											"type":        "string",
+											"description": "JSON object mapping CSV columns to context paths, e.g. {\"src_ip\": \"context.incident.src_ip\"}",
+										},
+										"mode": map[string]interface{}{
+											"type":    "string",
+											"enum":    []string{"context", "jobs"},
+											"default": "context",
+										},
+										"variable": map[string]interface{}{
+											"type":        "string",
+											"default":     "rows",
+											"description": "Context variable holding the row array in context mode",
+										},
+										"playbook_name": map[string]interface{}{
+											"type":        "string",
+											"description": "Playbook to run; required in jobs mode",
+										},
+									},
+									"required": []string{"file", "column_mapping"},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "CSV imported successfully; no job was submitted",
+						},
+						"202": map[string]interface{}{
+							"description": "CSV imported and jobs submitted",
+							"headers": map[string]interface{}{
+								"Location": map[string]interface{}{
+									"description": "Status URL of the job, /job/{id}, when exactly one job was submitted",
+									"schema":      map[string]interface{}{"type": "string"},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request or CSV header does not contain the mapped columns",
+						},
+					},
+				},
+			},
+			"/plugins": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "List All Plugins",
+					"description": "Retrieve a list of all available plugins",
+					"tags":        []string{"Plugins"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Plugins retrieved successfully",
+						},
+					},
+				},
+			},
+			"/plugins/{name}/cache/clear": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Clear Plugin Result Cache",
+					"description": "Drop the cached results of a plugin that declares itself cacheable",
+					"tags":        []string{"Plugins"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "name",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Cache cleared; cleared is the number of results dropped",
+						},
+						"404": map[string]interface{}{
+							"description": "Plugin not found",
+						},
+					},
+				},
+			},
+			"/automation": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Upload Automation Script",
+					"description": "Upload a new automation script to the system. The script will be validated for security and stored in the automations directory.",
+					"tags":        []string{"Automations"},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"multipart/form-data": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"automation": map[string]interface{}{
+											"type":        "string",
+											"format":      "binary",
+											"description": "Python automation script file (.py)",
+										},
+									},
+									"required": []string{"automation"},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Automation uploaded successfully",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{
+												"type": "boolean",
+											},
+											"message": map[string]interface{}{
+												"type": "string",
+											},
+											"automation_name": map[string]interface{}{
+												"type": "string",
+											},
+											"filename": map[string]interface{}{
+												"type": "string",
+											},
+											"size": map[string]interface{}{
+												"type": "integer",
+											},
+											"timestamp": map[string]interface{}{
+												"type":   "string",
+												"format": "date-time",
+											},
+										},
+									},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Validation failed",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{
+												"type": "boolean",
+											},
+											"valid": map[string]interface{}{
+												"type": "boolean",
+											},
+											"errors": map[string]interface{}{
+												"type": "array",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"field": map[string]interface{}{
+															"type": "string",
+														},
+														"message": map[string]interface{}{
+															"type": "string",
+														},
+														"value": map[string]interface{}{
+															"type": "string",
+														},
+													},
+												},
+											},
+											"message": map[string]interface{}{
+												"type": "string",
+											},
+											"timestamp": map[string]interface{}{
+												"type":   "string",
+												"format": "date-time",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/playbook/upload": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Upload Playbook File",
+					"description": "Upload a new playbook file to the system. The file will be validated for structure and stored in the playbooks directory.",
+					"tags":        []string{"Playbooks"},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"multipart/form-data": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"playbook": map[string]interface{}{
+											"type":        "string",
+											"format":      "binary",
+											"description": "Playbook JSON file (.json)",
+										},
+									},
+									"required": []string{"playbook"},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Playbook uploaded successfully",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{
+												"type": "boolean",
+											},
+											"message": map[string]interface{}{
+												"type": "string",
+											},
+											"playbook_name": map[string]interface{}{
+												"type": "string",
 											},
 											"filename": map[string]interface{}{
 												"type": "string",
@@ -523,134 +1228,709 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 											"size": map[string]interface{}{
 												"type": "integer",
 											},
-											"timestamp": map[string]interface{}{
-												"type":   "string",
-												"format": "date-time",
+											"timestamp": map[string]interface{}{
+												"type":   "string",
+												"format": "date-time",
+											},
+										},
+									},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Validation failed",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{
+												"type": "boolean",
+											},
+											"valid": map[string]interface{}{
+												"type": "boolean",
+											},
+											"errors": map[string]interface{}{
+												"type": "array",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"field": map[string]interface{}{
+															"type": "string",
+														},
+														"message": map[string]interface{}{
+															"type": "string",
+														},
+														"value": map[string]interface{}{
+															"type": "string",
+														},
+													},
+												},
+											},
+											"message": map[string]interface{}{
+												"type": "string",
+											},
+											"timestamp": map[string]interface{}{
+												"type":   "string",
+												"format": "date-time",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/playbooks": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "List All Playbooks",
+					"description": "Retrieve a list of all available playbooks with their metadata including rule counts, operation types, and modification dates.",
+					"tags":        []string{"Playbooks"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Playbooks retrieved successfully",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{
+												"type": "boolean",
+											},
+											"message": map[string]interface{}{
+												"type": "string",
+											},
+											"playbooks": map[string]interface{}{
+												"type": "array",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"name": map[string]interface{}{
+															"type": "string",
+														},
+														"filename": map[string]interface{}{
+															"type": "string",
+														},
+														"size": map[string]interface{}{
+															"type": "integer",
+														},
+														"rule_count": map[string]interface{}{
+															"type": "integer",
+														},
+														"operations": map[string]interface{}{
+															"type": "object",
+															"additionalProperties": map[string]interface{}{
+																"type": "integer",
+															},
+														},
+														"modified_at": map[string]interface{}{
+															"type":   "string",
+															"format": "date-time",
+														},
+														"is_valid": map[string]interface{}{
+															"type": "boolean",
+														},
+													},
+												},
+											},
+											"count": map[string]interface{}{
+												"type": "integer",
+											},
+											"timestamp": map[string]interface{}{
+												"type":   "string",
+												"format": "date-time",
+											},
+										},
+									},
+								},
+							},
+						},
+						"500": map[string]interface{}{
+							"description": "Internal server error",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"error": map[string]interface{}{
+												"type": "string",
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/playbooks/stats": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Playbook Library Statistics",
+					"description": "Aggregate operation counts, including nested operations, the most-referenced automations, plugins and playbooks, and average complexity across all playbooks. Cached until a playbook file changes.",
+					"tags":        []string{"Playbooks"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Statistics computed successfully",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"playbook_count":     map[string]interface{}{"type": "integer"},
+											"total_rules":        map[string]interface{}{"type": "integer"},
+											"total_operations":   map[string]interface{}{"type": "integer"},
+											"operations":         map[string]interface{}{"type": "object"},
+											"top_automations":    map[string]interface{}{"type": "array"},
+											"top_plugins":        map[string]interface{}{"type": "array"},
+											"top_playbooks":      map[string]interface{}{"type": "array"},
+											"average_rules":      map[string]interface{}{"type": "number"},
+											"average_operations": map[string]interface{}{"type": "number"},
+											"average_max_depth":  map[string]interface{}{"type": "number"},
+											"max_depth":          map[string]interface{}{"type": "integer"},
+											"cached":             map[string]interface{}{"type": "boolean"},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			"/playbooks/sync": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Git Playbook Sync Status",
+					"description": "Report the commit, playbooks and skipped files of the last sync from the playbook_git repository. Returns 404 when Git sync is not enabled.",
+					"tags":        []string{"Playbooks"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Sync status",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{"type": "boolean"},
+											"message": map[string]interface{}{"type": "string"},
+											"status": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"repo":       map[string]interface{}{"type": "string"},
+													"branch":     map[string]interface{}{"type": "string"},
+													"path":       map[string]interface{}{"type": "string"},
+													"commit":     map[string]interface{}{"type": "string"},
+													"synced_at":  map[string]interface{}{"type": "string", "format": "date-time"},
+													"playbooks":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+													"skipped":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+													"last_error": map[string]interface{}{"type": "string"},
+												},
+											},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
+						},
+						"404": map[string]interface{}{
+							"description": "Git playbook sync is not enabled",
+						},
+					},
+				},
+				"post": map[string]interface{}{
+					"summary":     "Sync Playbooks from Git",
+					"description": "Fetch the configured branch and sync its playbooks now, e.g. from a Git push hook. Synced playbooks are read-only through the API: uploads, patches and deletes of them return 409.",
+					"tags":        []string{"Playbooks"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Playbooks synced",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{"type": "boolean"},
+											"message": map[string]interface{}{"type": "string"},
+											"status": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"repo":       map[string]interface{}{"type": "string"},
+													"branch":     map[string]interface{}{"type": "string"},
+													"path":       map[string]interface{}{"type": "string"},
+													"commit":     map[string]interface{}{"type": "string"},
+													"synced_at":  map[string]interface{}{"type": "string", "format": "date-time"},
+													"playbooks":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+													"skipped":    map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+													"last_error": map[string]interface{}{"type": "string"},
+												},
 											},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
 										},
 									},
 								},
 							},
 						},
+						"404": map[string]interface{}{
+							"description": "Git playbook sync is not enabled",
+						},
+						"502": map[string]interface{}{
+							"description": "The repository could not be fetched",
+						},
+					},
+				},
+			},
+			"/playbooks/{name}/export": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Export Playbook",
+					"description": "Export a playbook as its raw JSON, converted to YAML, or as a Markdown document describing each step for compliance documentation.",
+					"tags":        []string{"Playbooks"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "name",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "format",
+							"in":          "query",
+							"description": "Export format (default json)",
+							"schema": map[string]interface{}{
+								"type": "string",
+								"enum": []string{"json", "yaml", "markdown"},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Playbook exported successfully",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "array",
+									},
+								},
+								"application/yaml": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "string",
+									},
+								},
+								"text/markdown": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "string",
+									},
+								},
+							},
+						},
 						"400": map[string]interface{}{
-							"description": "Validation failed",
+							"description": "Invalid playbook name or format",
+						},
+						"404": map[string]interface{}{
+							"description": "Playbook not found",
+						},
+					},
+				},
+			},
+			"/admin/profile/start": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Capture CPU Profile",
+					"description": "Capture a CPU profile for the requested duration and return it as a pprof file. Requires an admin API key.",
+					"tags":        []string{"Admin"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "duration",
+							"in":          "query",
+							"description": "Capture duration, up to 5m (default 30s)",
+							"schema": map[string]interface{}{
+								"type":    "string",
+								"example": "30s",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "CPU profile",
+							"content": map[string]interface{}{
+								"application/octet-stream": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type":   "string",
+										"format": "binary",
+									},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid duration",
+						},
+						"403": map[string]interface{}{
+							"description": "Admin API key required",
+						},
+						"409": map[string]interface{}{
+							"description": "A CPU profile is already being captured",
+						},
+					},
+				},
+			},
+			"/admin/profile/heap": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Capture Heap Profile",
+					"description": "Capture a heap profile and return it as a pprof file. Requires an admin API key.",
+					"tags":        []string{"Admin"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Heap profile",
+							"content": map[string]interface{}{
+								"application/octet-stream": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type":   "string",
+										"format": "binary",
+									},
+								},
+							},
+						},
+						"403": map[string]interface{}{
+							"description": "Admin API key required",
+						},
+					},
+				},
+			},
+			"/admin/queues": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Inspect Internal Queues",
+					"description": "Report the depth and oldest item age of the pending job, webhook delivery and plugin reload queues. oldest_age_seconds is null when a queue is empty or does not record ages. Requires an admin API key.",
+					"tags":        []string{"Admin"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Queue depths",
 							"content": map[string]interface{}{
 								"application/json": map[string]interface{}{
 									"schema": map[string]interface{}{
 										"type": "object",
 										"properties": map[string]interface{}{
-											"success": map[string]interface{}{
-												"type": "boolean",
-											},
-											"valid": map[string]interface{}{
-												"type": "boolean",
-											},
-											"errors": map[string]interface{}{
+											"success": map[string]interface{}{"type": "boolean"},
+											"queues": map[string]interface{}{
 												"type": "array",
 												"items": map[string]interface{}{
 													"type": "object",
 													"properties": map[string]interface{}{
-														"field": map[string]interface{}{
-															"type": "string",
-														},
-														"message": map[string]interface{}{
-															"type": "string",
-														},
-														"value": map[string]interface{}{
-															"type": "string",
-														},
+														"name":               map[string]interface{}{"type": "string", "enum": []string{"jobs", "webhooks", "plugin_reload"}},
+														"description":        map[string]interface{}{"type": "string"},
+														"depth":              map[string]interface{}{"type": "integer"},
+														"oldest_age_seconds": map[string]interface{}{"type": "number", "nullable": true},
+														"details":            map[string]interface{}{"type": "object"},
 													},
 												},
 											},
-											"message": map[string]interface{}{
-												"type": "string",
-											},
-											"timestamp": map[string]interface{}{
-												"type":   "string",
-												"format": "date-time",
-											},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
+						},
+						"403": map[string]interface{}{
+							"description": "Admin API key required",
+						},
+					},
+				},
+			},
+			"/admin/queues/{name}/flush": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Flush Internal Queue",
+					"description": "Drain a stuck queue. Flushing jobs cancels the jobs waiting for a worker, webhooks abandons the deliveries still being sent or retried, and plugin_reload drops the queued plugin reloads. Requires an admin API key.",
+					"tags":        []string{"Admin"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "name",
+							"in":       "path",
+							"required": true,
+							"schema":   map[string]interface{}{"type": "string", "enum": []string{"jobs", "webhooks", "plugin_reload"}},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Queue flushed",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success":   map[string]interface{}{"type": "boolean"},
+											"queue":     map[string]interface{}{"type": "string"},
+											"flushed":   map[string]interface{}{"type": "integer"},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
+						},
+						"403": map[string]interface{}{
+							"description": "Admin API key required",
+						},
+						"404": map[string]interface{}{
+							"description": "Unknown queue",
+						},
+					},
+				},
+			},
+			"/uploads": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Start Chunked Upload",
+					"description": "Start a resumable upload of an automation, playbook, plugin or integration file. Send the file in chunks with PATCH /uploads/{id}, then finish with POST /uploads/{id}/complete.",
+					"tags":        []string{"Uploads"},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"type", "filename", "size", "checksum"},
+									"properties": map[string]interface{}{
+										"type": map[string]interface{}{
+											"type": "string",
+											"enum": []string{"automation", "playbook", "plugin", "integration"},
+										},
+										"plugin_type": map[string]interface{}{
+											"type":        "string",
+											"description": "Required for plugin uploads",
+											"enum":        []string{"linux", "windows", "python", "go"},
+										},
+										"filename": map[string]interface{}{
+											"type": "string",
+										},
+										"size": map[string]interface{}{
+											"type":        "integer",
+											"description": "Total file size in bytes",
+										},
+										"checksum": map[string]interface{}{
+											"type":        "string",
+											"description": "Hex-encoded SHA-256 of the whole file",
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"201": map[string]interface{}{
+							"description": "Upload started",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success":     map[string]interface{}{"type": "boolean"},
+											"message":     map[string]interface{}{"type": "string"},
+											"upload_id":   map[string]interface{}{"type": "string"},
+											"type":        map[string]interface{}{"type": "string"},
+											"plugin_type": map[string]interface{}{"type": "string"},
+											"filename":    map[string]interface{}{"type": "string"},
+											"size":        map[string]interface{}{"type": "integer"},
+											"received":    map[string]interface{}{"type": "integer"},
+											"expires_at":  map[string]interface{}{"type": "string"},
+											"timestamp":   map[string]interface{}{"type": "string"},
+										},
+									},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Validation failed",
+						},
+					},
+				},
+			},
+			"/uploads/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get Chunked Upload Progress",
+					"description": "Report how many bytes have been received, so an interrupted upload can resume from that offset.",
+					"tags":        []string{"Uploads"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Upload progress",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success":     map[string]interface{}{"type": "boolean"},
+											"message":     map[string]interface{}{"type": "string"},
+											"upload_id":   map[string]interface{}{"type": "string"},
+											"type":        map[string]interface{}{"type": "string"},
+											"plugin_type": map[string]interface{}{"type": "string"},
+											"filename":    map[string]interface{}{"type": "string"},
+											"size":        map[string]interface{}{"type": "integer"},
+											"received":    map[string]interface{}{"type": "integer"},
+											"expires_at":  map[string]interface{}{"type": "string"},
+											"timestamp":   map[string]interface{}{"type": "string"},
 										},
 									},
 								},
 							},
 						},
+						"404": map[string]interface{}{
+							"description": "Upload not found",
+						},
+					},
+				},
+				"patch": map[string]interface{}{
+					"summary":     "Upload Chunk",
+					"description": "Append the request body to the upload. The Upload-Offset header must equal the bytes received so far.",
+					"tags":        []string{"Uploads"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":     "Upload-Offset",
+							"in":       "header",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "integer",
+							},
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/octet-stream": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":   "string",
+									"format": "binary",
+								},
+							},
+						},
 					},
-				},
-			},
-			"/playbooks": map[string]interface{}{
-				"get": map[string]interface{}{
-					"summary":     "List All Playbooks",
-					"description": "Retrieve a list of all available playbooks with their metadata including rule counts, operation types, and modification dates.",
-					"tags":        []string{"Playbooks"},
 					"responses": map[string]interface{}{
 						"200": map[string]interface{}{
-							"description": "Playbooks retrieved successfully",
+							"description": "Chunk received",
 							"content": map[string]interface{}{
 								"application/json": map[string]interface{}{
 									"schema": map[string]interface{}{
 										"type": "object",
 										"properties": map[string]interface{}{
-											"success": map[string]interface{}{
-												"type": "boolean",
-											},
-											"message": map[string]interface{}{
-												"type": "string",
-											},
-											"playbooks": map[string]interface{}{
-												"type": "array",
-												"items": map[string]interface{}{
-													"type": "object",
-													"properties": map[string]interface{}{
-														"name": map[string]interface{}{
-															"type": "string",
-														},
-														"filename": map[string]interface{}{
-															"type": "string",
-														},
-														"size": map[string]interface{}{
-															"type": "integer",
-														},
-														"rule_count": map[string]interface{}{
-															"type": "integer",
-														},
-														"operations": map[string]interface{}{
-															"type": "object",
-															"additionalProperties": map[string]interface{}{
-																"type": "integer",
-															},
-														},
-														"modified_at": map[string]interface{}{
-															"type":   "string",
-															"format": "date-time",
-														},
-														"is_valid": map[string]interface{}{
-															"type": "boolean",
-														},
-													},
-												},
-											},
-											"count": map[string]interface{}{
-												"type": "integer",
-											},
-											"timestamp": map[string]interface{}{
-												"type":   "string",
-												"format": "date-time",
-											},
+											"success":     map[string]interface{}{"type": "boolean"},
+											"message":     map[string]interface{}{"type": "string"},
+											"upload_id":   map[string]interface{}{"type": "string"},
+											"type":        map[string]interface{}{"type": "string"},
+											"plugin_type": map[string]interface{}{"type": "string"},
+											"filename":    map[string]interface{}{"type": "string"},
+											"size":        map[string]interface{}{"type": "integer"},
+											"received":    map[string]interface{}{"type": "integer"},
+											"expires_at":  map[string]interface{}{"type": "string"},
+											"timestamp":   map[string]interface{}{"type": "string"},
 										},
 									},
 								},
 							},
 						},
-						"500": map[string]interface{}{
-							"description": "Internal server error",
+						"400": map[string]interface{}{
+							"description": "Missing offset or oversized chunk",
+						},
+						"404": map[string]interface{}{
+							"description": "Upload not found",
+						},
+						"409": map[string]interface{}{
+							"description": "Offset mismatch; the Upload-Offset response header holds the offset to resume from",
+						},
+					},
+				},
+				"delete": map[string]interface{}{
+					"summary":     "Abandon Chunked Upload",
+					"description": "Discard an upload and the chunks received so far.",
+					"tags":        []string{"Uploads"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"204": map[string]interface{}{
+							"description": "Upload abandoned",
+						},
+						"404": map[string]interface{}{
+							"description": "Upload not found",
+						},
+					},
+				},
+			},
+			"/uploads/{id}/complete": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Complete Chunked Upload",
+					"description": "Verify the assembled file against its checksum, then validate and save it like a regular upload of its type. The response is that of the matching upload endpoint.",
+					"tags":        []string{"Uploads"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "File validated and saved",
+						},
+						"400": map[string]interface{}{
+							"description": "Upload incomplete, checksum mismatch, or file validation failed",
+						},
+						"404": map[string]interface{}{
+							"description": "Upload not found",
+						},
+						"507": map[string]interface{}{
+							"description": "Saving the file would exceed a storage limit; the body reports current usage",
+						},
+					},
+				},
+			},
+			"/storage": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get Storage Usage",
+					"description": "Report the number and total size of stored playbooks, automations, plugins and integrations against the configured storage limits. Uploads that would exceed a limit are rejected with 507 Insufficient Storage.",
+					"tags":        []string{"Uploads"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Storage usage retrieved successfully",
 							"content": map[string]interface{}{
 								"application/json": map[string]interface{}{
 									"schema": map[string]interface{}{
 										"type": "object",
 										"properties": map[string]interface{}{
-											"error": map[string]interface{}{
-												"type": "string",
+											"success": map[string]interface{}{"type": "boolean"},
+											"usage": map[string]interface{}{
+												"type": "object",
+												"properties": map[string]interface{}{
+													"categories": map[string]interface{}{
+														"type":        "object",
+														"description": "Usage per category: count, bytes and max_count when limited",
+													},
+													"total_bytes":     map[string]interface{}{"type": "integer"},
+													"max_total_bytes": map[string]interface{}{"type": "integer"},
+												},
 											},
+											"timestamp": map[string]interface{}{"type": "string"},
 										},
 									},
 								},
@@ -844,7 +2124,123 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
+			"/playbook/{name}/runs": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Playbook Run History",
+					"description": "List the most recent jobs of one playbook, newest first, with status, duration and who triggered them. Jobs of inline playbooks are listed under inline-<hash>, the playbook_name recorded on the job.",
+					"tags":        []string{"Playbooks", "Jobs"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "name",
+							"in":          "path",
+							"required":    true,
+							"description": "Playbook name",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "limit",
+							"in":          "query",
+							"description": "Number of runs to return (1-1000, default 20)",
+							"schema": map[string]interface{}{
+								"type": "integer",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Recent runs",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success":       map[string]interface{}{"type": "boolean"},
+											"playbook_name": map[string]interface{}{"type": "string"},
+											"runs": map[string]interface{}{
+												"type": "array",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"job_id":           map[string]interface{}{"type": "string"},
+														"status":           map[string]interface{}{"type": "string"},
+														"triggered_by":     map[string]interface{}{"type": "string"},
+														"error":            map[string]interface{}{"type": "string"},
+														"created_at":       map[string]interface{}{"type": "string", "format": "date-time"},
+														"started_at":       map[string]interface{}{"type": "string", "format": "date-time"},
+														"completed_at":     map[string]interface{}{"type": "string", "format": "date-time"},
+														"duration_seconds": map[string]interface{}{"type": "number"},
+													},
+												},
+											},
+											"total":     map[string]interface{}{"type": "integer"},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid playbook name or limit",
+						},
+					},
+				},
+			},
 			"/playbook/{name}": map[string]interface{}{
+				"patch": map[string]interface{}{
+					"summary":     "Patch Playbook",
+					"description": "Apply a JSON Patch (RFC 6902) or a rule-index edit spec to a stored playbook. The result must pass playbook validation; the replaced version is backed up under data/playbook_backups. Returns the new content.",
+					"tags":        []string{"Playbooks"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "name",
+							"in":          "path",
+							"required":    true,
+							"description": "Name of the playbook to patch",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"requestBody": map[string]interface{}{
+						"required":    true,
+						"description": `Either a JSON Patch array, e.g. [{"op": "replace", "path": "/0/run", "value": "enrich"}], or an edit spec, e.g. {"edits": [{"action": "insert", "index": 1, "rule": {"run": "notify"}}]}. Edit actions are insert, replace, delete and move (with "to").`,
+						"content": map[string]interface{}{
+							"application/json-patch+json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":  "array",
+									"items": map[string]interface{}{"type": "object"},
+								},
+							},
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"edits": map[string]interface{}{
+											"type":  "array",
+											"items": map[string]interface{}{"type": "object"},
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Playbook patched; the body holds the new content, its content_hash and the backup path",
+						},
+						"400": map[string]interface{}{
+							"description": "Patch could not be applied or produced an invalid playbook",
+						},
+						"404": map[string]interface{}{
+							"description": "Playbook not found",
+						},
+						"507": map[string]interface{}{
+							"description": "Saving the playbook would exceed a storage limit",
+						},
+					},
+				},
 				"delete": map[string]interface{}{
 					"summary":     "Delete Playbook",
 					"description": "Delete a playbook file from the system.",
@@ -989,6 +2385,87 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
+			"/cluster/jobs": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Submit Distributed Job",
+					"description": "Submit a playbook to the distributed job queue",
+					"tags":        []string{"Cluster"},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"playbook": map[string]interface{}{
+											"type":  "array",
+											"items": map[string]interface{}{"type": "object"},
+										},
+										"playbook_name": map[string]interface{}{
+											"type": "string",
+										},
+										"context": map[string]interface{}{
+											"type": "object",
+										},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"202": map[string]interface{}{
+							"description": "Job submitted successfully",
+							"headers": map[string]interface{}{
+								"Location": map[string]interface{}{
+									"description": "Status URL of the job, /cluster/jobs/{id}",
+									"schema":      map[string]interface{}{"type": "string"},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request",
+						},
+						"503": map[string]interface{}{
+							"description": "Cluster mode not enabled",
+						},
+					},
+				},
+			},
+			"/cluster/jobs/{id}": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Get Distributed Job Status",
+					"description": "Retrieve the status of a job in the distributed queue",
+					"tags":        []string{"Cluster"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":     "id",
+							"in":       "path",
+							"required": true,
+							"schema":   map[string]interface{}{"type": "string"},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Job status retrieved successfully",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success":   map[string]interface{}{"type": "boolean"},
+											"job":       map[string]interface{}{"$ref": "#/components/schemas/Job"},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
+						},
+						"404": map[string]interface{}{
+							"description": "Job not found",
+						},
+					},
+				},
+			},
 			"/schedules": map[string]interface{}{
 				"get": map[string]interface{}{
 					"summary":     "List All Schedules",
@@ -1028,14 +2505,23 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 											"type":        "string",
 											"description": "Cron expression (for cron type)",
 										},
-										"interval_seconds": map[string]interface{}{
+										"interval_seconds": map[string]interface{}{
+											"type":        "integer",
+											"description": "Interval in seconds (for interval type)",
+										},
+										"misfire_policy": map[string]interface{}{
+											"type":        "string",
+											"enum":        []string{"skip", "fire_once", "fire_all"},
+											"description": "What to do on startup with runs missed while the server was down: ignore them (default), run once, or run once per missed run up to max_catch_up_runs",
+										},
+										"max_catch_up_runs": map[string]interface{}{
 											"type":        "integer",
-											"description": "Interval in seconds (for interval type)",
+											"description": "Most missed runs fire_all makes up (defaults to scheduler.max_catch_up_runs)",
 										},
 										"playbook": map[string]interface{}{
 											"type":        "array",
 											"items":       map[string]interface{}{"type": "object"},
-											"description": "Playbook rules to execute",
+											"description": "Playbook rules to execute. Rejected when the playbook is invalid or references automations, playbooks, plugins or macros that do not exist.",
 										},
 										"context": map[string]interface{}{
 											"type":        "object",
@@ -1083,7 +2569,7 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 											"type": "array",
 											"items": map[string]interface{}{
 												"type": "string",
-												"enum": []string{"job_started", "job_completed", "job_failed", "job_cancelled"},
+												"enum": []string{"job_started", "job_completed", "job_failed", "job_cancelled", "job_aborted", "plugin_reloaded", "library_problems_found"},
 											},
 											"description": "Events to trigger webhook",
 										},
@@ -1099,6 +2585,10 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 											"type":        "integer",
 											"description": "Number of retry attempts",
 										},
+										"filter": map[string]interface{}{
+											"type":        "object",
+											"description": "Rules engine expression evaluated against the event payload (e.g. {\"eq\": [{\"var\": \"context.severity\"}, \"high\"]}); the webhook is only sent when it is truthy. Operations that run code or have side effects are rejected.",
+										},
 									},
 									"required": []string{"url", "events"},
 								},
@@ -1115,6 +2605,94 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
+			"/webhooks/deliveries": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "List Webhook Deliveries",
+					"description": "List the newest recorded webhook deliveries, successful or not, with the request sent and the status code and response body of every attempt. The values of custom headers are masked. The newest webhooks.delivery_history deliveries of each webhook are kept.",
+					"tags":        []string{"Webhooks"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "event",
+							"in":          "query",
+							"description": "Only deliveries of this event",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "status",
+							"in":          "query",
+							"description": "Only deliveries with this status",
+							"schema": map[string]interface{}{
+								"type": "string",
+								"enum": []string{"pending", "delivered", "failed", "abandoned"},
+							},
+						},
+						{
+							"name":        "webhook_url",
+							"in":          "query",
+							"description": "Only deliveries to this webhook",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "job_id",
+							"in":          "query",
+							"description": "Only deliveries about this job",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "limit",
+							"in":          "query",
+							"description": "Most deliveries to return, newest first",
+							"schema": map[string]interface{}{
+								"type":    "integer",
+								"default": 50,
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Recorded deliveries, newest first",
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid status or limit",
+						},
+					},
+				},
+			},
+			"/webhooks/deliveries/{id}/resend": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Re-send Webhook Delivery",
+					"description": "Send the payload of a recorded delivery again, with the current headers and retry settings of its webhook. The new delivery is recorded with resent_from set and runs in the background.",
+					"tags":        []string{"Webhooks"},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "id",
+							"in":          "path",
+							"required":    true,
+							"description": "Delivery ID",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"202": map[string]interface{}{
+							"description": "Delivery re-sent",
+						},
+						"404": map[string]interface{}{
+							"description": "Delivery not found",
+						},
+						"409": map[string]interface{}{
+							"description": "The webhook is no longer configured or is disabled",
+						},
+					},
+				},
+			},
 			"/validate": map[string]interface{}{
 				"post": map[string]interface{}{
 					"summary":     "Validate Playbook/Context",
@@ -1152,6 +2730,87 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
+			"/validate/batch": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Validate Playbooks in Bulk",
+					"description": "Lint several playbooks in one call, as a CI check: each is checked for structural errors and references to missing automations, playbooks, plugins and macros. Responds 200 with a result per playbook; with fail_fast, validation stops at the first invalid playbook and the response is 422.",
+					"tags":        []string{"Validation"},
+					"requestBody": map[string]interface{}{
+						"required": true,
+						"content": map[string]interface{}{
+							"application/json": map[string]interface{}{
+								"schema": map[string]interface{}{
+									"type":     "object",
+									"required": []string{"playbooks"},
+									"properties": map[string]interface{}{
+										"playbooks": map[string]interface{}{
+											"type":        "array",
+											"description": "Stored playbook names, playbook arrays, or {\"name\": \"...\", \"playbook\": [...]} objects (at most 500)",
+											"items":       map[string]interface{}{},
+										},
+										"fail_fast": map[string]interface{}{"type": "boolean", "description": "Stop at the first invalid playbook and respond 422"},
+									},
+								},
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Validation results",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"valid":    map[string]interface{}{"type": "boolean", "description": "Whether every checked playbook is valid"},
+											"checked":  map[string]interface{}{"type": "integer"},
+											"invalid":  map[string]interface{}{"type": "integer"},
+											"results":  map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object", "description": "index, name, valid and errors of one playbook"}},
+											"warnings": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+										},
+									},
+								},
+							},
+						},
+						"400": map[string]interface{}{
+							"description": "Invalid request",
+						},
+						"422": map[string]interface{}{
+							"description": "fail_fast was set and a playbook is invalid",
+						},
+					},
+				},
+			},
+			"/validate/all": map[string]interface{}{
+				"post": map[string]interface{}{
+					"summary":     "Validate Asset Library",
+					"description": "Lint every stored playbook for structural errors and references to missing automations, playbooks, plugins and macros, compile every automation, check every integration config, and re-lint the playbook of every schedule. Problems not found by the previous validation are listed in new_problems and sent as a library_problems_found webhook event. Requires an admin API key.",
+					"tags":        []string{"Validation"},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Library health report",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"healthy":      map[string]interface{}{"type": "boolean"},
+											"summary":      map[string]interface{}{"type": "object", "description": "Checked and invalid counts per asset type"},
+											"assets":       map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+											"problems":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+											"new_problems": map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+											"warnings":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+										},
+									},
+								},
+							},
+						},
+						"403": map[string]interface{}{
+							"description": "Admin API key required",
+						},
+					},
+				},
+			},
 			"/plugin/{type}": map[string]interface{}{
 				"post": map[string]interface{}{
 					"summary":     "Upload Plugin File",
@@ -1267,6 +2926,49 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
+			"/search": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Search Assets",
+					"description": "Case-insensitive search over playbook, automation and integration names, and playbook/automation file contents",
+					"tags":        []string{"Search"},
+					"security":    []map[string]interface{}{{"ApiKeyAuth": []string{}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "q",
+							"in":          "query",
+							"required":    true,
+							"description": "Text to search for",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "types",
+							"in":          "query",
+							"description": "Comma-separated asset types (playbook, automation, integration)",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "limit",
+							"in":          "query",
+							"description": "Maximum number of matches (default 50, max 500)",
+							"schema": map[string]interface{}{
+								"type": "integer",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Search completed",
+						},
+						"400": map[string]interface{}{
+							"description": "Missing or invalid query",
+						},
+					},
+				},
+			},
 			"/integrations": map[string]interface{}{
 				"get": map[string]interface{}{
 					"summary":     "List All Integrations",
@@ -1315,6 +3017,20 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 															"type":   "string",
 															"format": "date-time",
 														},
+														"expires_at": map[string]interface{}{
+															"type":   "string",
+															"format": "date-time",
+														},
+														"rotation_webhook_url": map[string]interface{}{
+															"type": "string",
+														},
+														"days_until_expiry": map[string]interface{}{
+															"type":        "integer",
+															"description": "Whole days until the credentials expire (only present when expires_at is set)",
+														},
+														"is_expired": map[string]interface{}{
+															"type": "boolean",
+														},
 													},
 												},
 											},
@@ -1331,9 +3047,21 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 				},
 				"post": map[string]interface{}{
 					"summary":     "Create Integration",
-					"description": "Create a new integration configuration with encrypted credentials",
+					"description": "Create a new integration configuration with encrypted credentials. A retried request with the same X-Idempotency-Key gets the original response, marked with X-Idempotency-Replayed.",
 					"tags":        []string{"Integrations"},
 					"security":    []map[string]interface{}{{"ApiKeyAuth": []string{}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "X-Idempotency-Key",
+							"in":          "header",
+							"required":    false,
+							"description": "Client-chosen key identifying the creation, replayed when idempotency is enabled",
+							"schema": map[string]interface{}{
+								"type":      "string",
+								"maxLength": 255,
+							},
+						},
+					},
 					"requestBody": map[string]interface{}{
 						"required": true,
 						"content": map[string]interface{}{
@@ -1446,6 +3174,54 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
+			"/integrations/types": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "List Integration Types",
+					"description": "List the registered integration types with their required and optional fields, for rendering type-specific forms. An enabled integration must set every required field of its type; a field with a fallback may be left empty when the fallback is set.",
+					"tags":        []string{"Integrations"},
+					"security":    []map[string]interface{}{{"ApiKeyAuth": []string{}}},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Integration types",
+							"content": map[string]interface{}{
+								"application/json": map[string]interface{}{
+									"schema": map[string]interface{}{
+										"type": "object",
+										"properties": map[string]interface{}{
+											"success": map[string]interface{}{"type": "boolean"},
+											"types": map[string]interface{}{
+												"type": "array",
+												"items": map[string]interface{}{
+													"type": "object",
+													"properties": map[string]interface{}{
+														"type":        map[string]interface{}{"type": "string", "example": "slack"},
+														"description": map[string]interface{}{"type": "string"},
+														"fields": map[string]interface{}{
+															"type": "array",
+															"items": map[string]interface{}{
+																"type": "object",
+																"properties": map[string]interface{}{
+																	"name":        map[string]interface{}{"type": "string", "example": "settings.hec_url"},
+																	"required":    map[string]interface{}{"type": "boolean"},
+																	"secret":      map[string]interface{}{"type": "boolean"},
+																	"fallback":    map[string]interface{}{"type": "string", "example": "url"},
+																	"description": map[string]interface{}{"type": "string"},
+																},
+															},
+														},
+													},
+												},
+											},
+											"timestamp": map[string]interface{}{"type": "string", "format": "date-time"},
+										},
+									},
+								},
+							},
+						},
+						"401": map[string]interface{}{"description": "Unauthorized"},
+					},
+				},
+			},
 			"/integrations/upload": map[string]interface{}{
 				"post": map[string]interface{}{
 					"summary":     "Upload Integration File",
@@ -1639,7 +3415,7 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 			"/integrations/{name}": map[string]interface{}{
 				"get": map[string]interface{}{
 					"summary":     "Get Integration",
-					"description": "Retrieve a specific integration configuration by name",
+					"description": "Retrieve a specific integration configuration by name. Secret fields are masked.",
 					"tags":        []string{"Integrations"},
 					"security":    []map[string]interface{}{{"ApiKeyAuth": []string{}}},
 					"parameters": []map[string]interface{}{
@@ -1686,7 +3462,7 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 				},
 				"put": map[string]interface{}{
 					"summary":     "Update Integration",
-					"description": "Update an existing integration configuration by name",
+					"description": "Update an existing integration configuration by name. Requires the ETag returned by GET in the If-Match header. Secrets sent back as the masks GET returned (starting with ****) keep their stored values.",
 					"tags":        []string{"Integrations"},
 					"security":    []map[string]interface{}{{"ApiKeyAuth": []string{}}},
 					"parameters": []map[string]interface{}{
@@ -1699,6 +3475,25 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 								"type": "string",
 							},
 						},
+						{
+							"name":        "If-Match",
+							"in":          "header",
+							"required":    true,
+							"description": "ETag of the integration revision being updated",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+						{
+							"name":        "X-Idempotency-Key",
+							"in":          "header",
+							"required":    false,
+							"description": "Client-chosen key identifying the update; a retry with the same key and body gets the original response",
+							"schema": map[string]interface{}{
+								"type":      "string",
+								"maxLength": 255,
+							},
+						},
 					},
 					"requestBody": map[string]interface{}{
 						"required": true,
@@ -1792,6 +3587,12 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 						"404": map[string]interface{}{
 							"description": "Integration not found",
 						},
+						"412": map[string]interface{}{
+							"description": "Integration was modified since the given revision",
+						},
+						"428": map[string]interface{}{
+							"description": "If-Match header is required",
+						},
 					},
 				},
 				"delete": map[string]interface{}{
@@ -1839,6 +3640,36 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 					},
 				},
 			},
+			"/integrations/{name}/reveal": map[string]interface{}{
+				"get": map[string]interface{}{
+					"summary":     "Reveal Integration Secrets",
+					"description": "Retrieve an integration configuration with secret fields unmasked. Requires an admin API key.",
+					"tags":        []string{"Integrations"},
+					"security":    []map[string]interface{}{{"ApiKeyAuth": []string{}}},
+					"parameters": []map[string]interface{}{
+						{
+							"name":        "name",
+							"in":          "path",
+							"required":    true,
+							"description": "Integration name (e.g., 'virustotal', 'slack')",
+							"schema": map[string]interface{}{
+								"type": "string",
+							},
+						},
+					},
+					"responses": map[string]interface{}{
+						"200": map[string]interface{}{
+							"description": "Integration retrieved successfully",
+						},
+						"403": map[string]interface{}{
+							"description": "Admin API key required",
+						},
+						"404": map[string]interface{}{
+							"description": "Integration not found",
+						},
+					},
+				},
+			},
 			"/cache": map[string]interface{}{
 				"get": map[string]interface{}{
 					"summary":     "Get Cache Information",
@@ -2099,6 +3930,85 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 			},
 		},
 		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"Finding": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"severity", "title", "recorded_at"},
+					"properties": map[string]interface{}{
+						"severity":    map[string]interface{}{"type": "string", "enum": findingSeverities},
+						"title":       map[string]interface{}{"type": "string"},
+						"detail":      map[string]interface{}{"description": "Any JSON value"},
+						"recorded_at": map[string]interface{}{"type": "string", "format": "date-time"},
+					},
+				},
+				"Job": map[string]interface{}{
+					"type":        "object",
+					"description": "A playbook execution job, as returned by every job endpoint",
+					"required":    []string{"id", "status", "playbook", "context", "created_at", "retries", "schema_version"},
+					"properties": map[string]interface{}{
+						"id": map[string]interface{}{"type": "string"},
+						"status": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"pending", "running", "completed", "completed_with_errors", "failed", "cancelled", "aborted", "skipped"},
+						},
+						"playbook":          map[string]interface{}{"type": "array", "items": map[string]interface{}{}, "description": "The rules the job runs"},
+						"playbook_name":     map[string]interface{}{"type": "string", "description": "Stored playbook the job runs, or inline-<hash> for an inline playbook"},
+						"triggered_by":      map[string]interface{}{"type": "string", "description": "Caller or schedule that submitted the job"},
+						"context":           map[string]interface{}{"type": "object", "description": "Submitted context, replaced by the final context once the run completed"},
+						"initial_context":   map[string]interface{}{"type": "object", "description": "Submitted context, once context holds the final context"},
+						"metadata":          map[string]interface{}{"type": "object"},
+						"env":               map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+						"priority":          map[string]interface{}{"type": "string", "enum": []string{"low", "normal", "high", "critical"}},
+						"labels":            map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}, "description": "Tags of a cluster job"},
+						"retries":           map[string]interface{}{"type": "integer", "description": "Times the job was run again after failing"},
+						"node":              map[string]interface{}{"type": "string", "description": "Cluster node a cluster job was assigned to"},
+						"continue_on_error": map[string]interface{}{"type": "boolean"},
+						"strict":            map[string]interface{}{"type": "boolean", "description": "Strictness the job was submitted with, when not the configured one"},
+						"plugin_versions_at_submission": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": map[string]interface{}{"type": "string"},
+						},
+						"plugin_versions_at_execution": map[string]interface{}{
+							"type":                 "object",
+							"additionalProperties": map[string]interface{}{"type": "string"},
+						},
+						"results":       map[string]interface{}{"type": "array", "items": map[string]interface{}{}, "description": "Result of each rule"},
+						"error":         map[string]interface{}{"type": "string"},
+						"error_type":    map[string]interface{}{"type": "string", "description": "assertion_failed when an assert operation failed"},
+						"abort_reason":  map[string]interface{}{"type": "string"},
+						"cancel_reason": map[string]interface{}{"type": "string"},
+						"cancelled_by":  map[string]interface{}{"type": "string"},
+						"warnings": map[string]interface{}{
+							"type":        "array",
+							"description": "Template variables that could not be resolved",
+							"items": map[string]interface{}{
+								"type": "object",
+								"properties": map[string]interface{}{
+									"type":     map[string]interface{}{"type": "string"},
+									"variable": map[string]interface{}{"type": "string"},
+									"template": map[string]interface{}{"type": "string"},
+									"count":    map[string]interface{}{"type": "integer"},
+								},
+							},
+						},
+						"findings": map[string]interface{}{
+							"type":        "array",
+							"description": "Recorded by finding operations, apart from results",
+							"items":       map[string]interface{}{"$ref": "#/components/schemas/Finding"},
+						},
+						"provenance":       map[string]interface{}{"type": "object", "description": "Hashes of the code the run executed"},
+						"resource_usage":   map[string]interface{}{"type": "object", "description": "CPU time and peak memory of the run's automation processes"},
+						"created_at":       map[string]interface{}{"type": "string", "format": "date-time"},
+						"started_at":       map[string]interface{}{"type": "string", "format": "date-time"},
+						"completed_at":     map[string]interface{}{"type": "string", "format": "date-time"},
+						"duration_seconds": map[string]interface{}{"type": "number", "description": "From start to completion; absent for jobs that never started"},
+						"schema_version":   map[string]interface{}{"type": "integer"},
+						"truncated":        map[string]interface{}{"type": "boolean", "description": "Present when results were cut down to results.max_bytes, keeping the leading results that fit"},
+						"original_size":    map[string]interface{}{"type": "integer", "description": "Serialized size in bytes of the full results of a truncated run"},
+						"full_results":     map[string]interface{}{"type": "string", "description": "Path the full results of a truncated run can be fetched from, when offloaded"},
+					},
+				},
+			},
 			"securitySchemes": map[string]interface{}{
 				"ApiKeyAuth": map[string]interface{}{
 					"type":        "apiKey",
@@ -2148,6 +4058,14 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 				"name":        "Automations",
 				"description": "Automation script management endpoints",
 			},
+			{
+				"name":        "Admin",
+				"description": "Administrative endpoints (admin API key required)",
+			},
+			{
+				"name":        "Uploads",
+				"description": "Resumable chunked file uploads",
+			},
 			{
 				"name":        "Integrations",
 				"description": "Integration management endpoints",
@@ -2156,8 +4074,54 @@ func readOpenAPISpec(serverPort string) ([]byte, error) {
 				"name":        "Cache",
 				"description": "Redis cache management endpoints",
 			},
+			{
+				"name":        "Search",
+				"description": "Asset search endpoints",
+			},
 		},
 	}
 
-	return json.Marshal(spec)
+	return marshalOpenAPISpec(spec)
+}
+
+// marshalOpenAPISpec encodes the spec so that the same spec always gives the
+// same bytes, across restarts too: the top-level fields in openAPIFieldOrder,
+// then any others sorted, and every object below them with its keys sorted,
+// as encoding/json writes maps. Lists keep their order, so no part of the
+// spec may be built by ranging over a map.
+func marshalOpenAPISpec(spec map[string]interface{}) ([]byte, error) {
+	keys := make([]string, 0, len(spec))
+	ordered := make(map[string]bool, len(openAPIFieldOrder))
+	for _, key := range openAPIFieldOrder {
+		ordered[key] = true
+		if _, exists := spec[key]; exists {
+			keys = append(keys, key)
+		}
+	}
+	var others []string
+	for key := range spec {
+		if !ordered[key] {
+			others = append(others, key)
+		}
+	}
+	sort.Strings(others)
+	keys = append(keys, others...)
+
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		value, err := json.Marshal(spec[key])
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", key, err)
+		}
+		b.Write(name)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
 }
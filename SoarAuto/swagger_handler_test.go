@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAPISpecIsByteStable(t *testing.T) {
+	first, err := readOpenAPISpec("8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(first) {
+		t.Fatal("spec is not valid JSON")
+	}
+	if !bytes.HasPrefix(first, []byte(`{"openapi":"3.0.3","info":`)) {
+		t.Errorf("spec starts %.40s, want the version first", first)
+	}
+
+	// Map iteration order differs between runs, so build it several times
+	for i := 0; i < 20; i++ {
+		again, err := readOpenAPISpec("8000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, again) {
+			t.Fatalf("build %d differs from the first", i+2)
+		}
+	}
+}
+
+func TestOpenAPISpecETag(t *testing.T) {
+	handler, err := NewSwaggerUIHandler("8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/api-docs", nil)
+		if ifNoneMatch != "" {
+			request.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	response := get("")
+	etag := response.Header().Get("ETag")
+	if response.Code != http.StatusOK || len(etag) < 3 || etag[0] != '"' || etag[len(etag)-1] != '"' {
+		t.Fatalf("GET /api-docs: %d, ETag %q", response.Code, etag)
+	}
+	if !bytes.Equal(response.Body.Bytes(), handler.openAPISpec) {
+		t.Error("body differs from the spec")
+	}
+
+	if cached := get(etag); cached.Code != http.StatusNotModified || cached.Body.Len() != 0 || cached.Header().Get("ETag") != etag {
+		t.Errorf("GET with a matching If-None-Match: %d, %d body bytes, ETag %q", cached.Code, cached.Body.Len(), cached.Header().Get("ETag"))
+	}
+	if stale := get(`"stale"`); stale.Code != http.StatusOK || stale.Body.Len() == 0 {
+		t.Errorf("GET with a stale If-None-Match: %d", stale.Code)
+	}
+
+	// A restart serves the same ETag; a different server URL changes it
+	restarted, err := NewSwaggerUIHandler("8000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted.specETag != etag {
+		t.Errorf("ETag after restart = %s, want %s", restarted.specETag, etag)
+	}
+	otherPort, err := NewSwaggerUIHandler("9000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otherPort.specETag == etag {
+		t.Error("ETag unchanged by a different server port")
+	}
+}
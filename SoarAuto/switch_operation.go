@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseSwitchSpec reads {"on": <selector>, "cases": {"value": <rule>, ...},
+// "default": <rule>}
+func parseSwitchSpec(spec interface{}) (interface{}, map[string]interface{}, interface{}, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("switch operation requires an object with on and cases")
+	}
+	selector, exists := specMap["on"]
+	if !exists {
+		return nil, nil, nil, fmt.Errorf("switch operation requires an on selector")
+	}
+	cases, ok := specMap["cases"].(map[string]interface{})
+	if !ok || len(cases) == 0 {
+		return nil, nil, nil, fmt.Errorf("switch operation requires a non-empty cases object")
+	}
+	for key := range specMap {
+		if key != "on" && key != "cases" && key != "default" {
+			return nil, nil, nil, fmt.Errorf("switch operation has unknown field %q", key)
+		}
+	}
+	return selector, cases, specMap["default"], nil
+}
+
+// validateSwitchSpec checks a switch operation when a playbook is saved. Case
+// keys are matched ignoring case, so keys differing only in case could never
+// both be chosen.
+func validateSwitchSpec(spec interface{}) error {
+	_, cases, _, err := parseSwitchSpec(spec)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]string, len(cases))
+	for _, key := range sortedKeys(cases) {
+		folded := strings.ToLower(strings.TrimSpace(key))
+		if other, exists := seen[folded]; exists {
+			return fmt.Errorf("switch cases %q and %q match the same values", other, key)
+		}
+		seen[folded] = key
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of an object in order
+func sortedKeys(value map[string]interface{}) []string {
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// switchCaseKey renders a selector value the way it is written as a case key:
+// numbers without trailing zeros, so 3 and 3.0 both match "3", booleans as
+// "true" and "false", null as "null", and strings with surrounding spaces
+// trimmed. Objects and arrays never match a case.
+func switchCaseKey(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case nil:
+		return "null", true
+	case string:
+		return strings.TrimSpace(v), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case json.Number:
+		if integer, err := v.Int64(); err == nil {
+			return strconv.FormatInt(integer, 10), true
+		}
+		if f, err := v.Float64(); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64), true
+		}
+		return v.String(), true
+	}
+	return "", false
+}
+
+// matchSwitchCase returns the case key a selector value selects: the key
+// equal to it, or else the first key equal to it ignoring case
+func matchSwitchCase(value interface{}, cases map[string]interface{}) (string, bool) {
+	selected, ok := switchCaseKey(value)
+	if !ok {
+		return "", false
+	}
+	if _, exists := cases[selected]; exists {
+		return selected, true
+	}
+	for _, key := range sortedKeys(cases) {
+		if strings.EqualFold(strings.TrimSpace(key), selected) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// evaluateSwitchOperation handles {"switch": {"on": {"var": "incident.severity"},
+// "cases": {"critical": <rule>, "high": <rule>}, "default": <rule>}}, which
+// evaluates the selector and then the rule of the case it matches, or the
+// default rule when none does. The result is that of the rule evaluated, or
+// null when no case matches and there is no default.
+func (re *RuleEngine) evaluateSwitchOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	selector, cases, defaultRule, err := parseSwitchSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := re.evaluate(selector, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate switch selector: %v", err)
+	}
+
+	if key, ok := matchSwitchCase(value, cases); ok {
+		logger.Debug("Executing switch case", map[string]interface{}{
+			"component": "rules_engine",
+			"value":     value,
+			"case":      key,
+		})
+		return re.evaluate(cases[key], data)
+	}
+
+	logger.Debug("No switch case matched", map[string]interface{}{
+		"component":   "rules_engine",
+		"value":       value,
+		"has_default": defaultRule != nil,
+	})
+	return re.evaluate(defaultRule, data)
+}
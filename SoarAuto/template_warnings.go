@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxTemplateWarnings bounds the distinct warnings kept for one execution
+const maxTemplateWarnings = 100
+
+// TemplateWarning reports a template variable that could not be resolved
+// and was left in place as a literal {{...}} placeholder
+type TemplateWarning struct {
+	Type     string `json:"type"` // Always "unresolved_variable"
+	Variable string `json:"variable"`
+	Template string `json:"template"`
+	Count    int    `json:"count"` // Times the placeholder went unresolved
+
+	lastSeen int // Occurrence number of the latest miss
+}
+
+// TemplateWarnings collects the unresolved template variables of one
+// playbook execution. Repeated misses of the same placeholder, e.g. in each
+// foreach iteration, are counted rather than reported again.
+type TemplateWarnings struct {
+	mu          sync.Mutex
+	warnings    []*TemplateWarning
+	index       map[string]*TemplateWarning
+	occurrences int
+}
+
+// NewTemplateWarnings creates an empty warnings collector
+func NewTemplateWarnings() *TemplateWarnings {
+	return &TemplateWarnings{index: make(map[string]*TemplateWarning)}
+}
+
+// add records a miss of variable in template
+func (tw *TemplateWarnings) add(variable, template string) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	tw.occurrences++
+	key := variable + "\x00" + template
+	if warning, exists := tw.index[key]; exists {
+		warning.Count++
+		warning.lastSeen = tw.occurrences
+		return
+	}
+	if len(tw.warnings) == maxTemplateWarnings {
+		return
+	}
+	warning := &TemplateWarning{
+		Type:     "unresolved_variable",
+		Variable: variable,
+		Template: template,
+		Count:    1,
+		lastSeen: tw.occurrences,
+	}
+	tw.warnings = append(tw.warnings, warning)
+	tw.index[key] = warning
+}
+
+// mark returns the number of misses recorded so far, for use with since
+func (tw *TemplateWarnings) mark() int {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	return tw.occurrences
+}
+
+// since returns the distinct variables missed after mark was taken
+func (tw *TemplateWarnings) since(mark int) []string {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	var variables []string
+	seen := make(map[string]bool)
+	for _, warning := range tw.warnings {
+		if warning.lastSeen > mark && !seen[warning.Variable] {
+			seen[warning.Variable] = true
+			variables = append(variables, warning.Variable)
+		}
+	}
+	return variables
+}
+
+// List returns the warnings in the order they were first recorded
+func (tw *TemplateWarnings) List() []TemplateWarning {
+	if tw == nil {
+		return nil
+	}
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if len(tw.warnings) == 0 {
+		return nil
+	}
+	list := make([]TemplateWarning, len(tw.warnings))
+	for i, warning := range tw.warnings {
+		list[i] = *warning
+	}
+	return list
+}
+
+// WithTemplateWarnings returns a copy of the engine that records unresolved
+// template variables in warnings
+func (re *RuleEngine) WithTemplateWarnings(warnings *TemplateWarnings) *RuleEngine {
+	engine := *re
+	engine.templateWarnings = warnings
+	return &engine
+}
+
+// recordUnresolvedVariable records a template variable that could not be
+// resolved
+func (re *RuleEngine) recordUnresolvedVariable(variable, template string) {
+	logger.Warning("Template variable not found", map[string]interface{}{
+		"component": "rules_engine",
+		"variable":  variable,
+		"template":  template,
+	})
+	if re.templateWarnings != nil {
+		re.templateWarnings.add(variable, template)
+	}
+}
+
+// unresolvedVariablesError fails a rule whose templates referenced missing
+// variables when strict templates are enabled
+func unresolvedVariablesError(variables []string) error {
+	if len(variables) == 1 {
+		return fmt.Errorf("unresolved template variable: %s", variables[0])
+	}
+	return fmt.Errorf("unresolved template variables: %s", strings.Join(variables, ", "))
+}
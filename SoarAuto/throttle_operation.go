@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// throttleKeyPrefix prefixes the Redis keys of throttle buckets
+	throttleKeyPrefix = "throttle:"
+	// defaultMaxThrottleWait bounds a throttle's wait when
+	// rules_engine.max_execution_time is unset
+	defaultMaxThrottleWait = 5 * time.Minute
+)
+
+// throttleScript admits a call to a sliding-window bucket: it drops calls
+// older than the window and records this one if fewer than the limit remain,
+// returning 0, or else returns the milliseconds until the oldest call leaves
+// the window. Redis' clock is used so every node agrees on the window.
+var throttleScript = redis.NewScript(`
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) < limit then
+	redis.call('ZADD', KEYS[1], now, ARGV[3])
+	redis.call('PEXPIRE', KEYS[1], window)
+	return 0
+end
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+return math.max(1, tonumber(oldest[2]) + window - now)
+`)
+
+// ThrottleBucketStats reports the activity of one throttle bucket
+type ThrottleBucketStats struct {
+	Calls          int64   `json:"calls_total"`
+	Throttled      int64   `json:"throttled_total"`
+	TimedOut       int64   `json:"timed_out_total"`
+	WaitSeconds    float64 `json:"wait_seconds_total"`
+	MaxWaitSeconds float64 `json:"max_wait_seconds"`
+}
+
+// PlaybookThrottle rate limits the "throttle" operation per named bucket.
+// Buckets are kept in Redis when the job store uses it, so every node shares
+// one rate; otherwise, or while Redis fails, they are kept in the process.
+type PlaybookThrottle struct {
+	client *redis.Client
+	ctx    context.Context
+
+	mutex sync.Mutex
+	local map[string][]time.Time
+	stats map[string]*ThrottleBucketStats
+}
+
+// localPlaybookThrottle serves engines that were given no throttle, such as
+// standalone runs
+var localPlaybookThrottle = NewPlaybookThrottle(nil)
+
+// NewPlaybookThrottle creates a throttle; a nil client keeps buckets in the
+// process
+func NewPlaybookThrottle(client *redis.Client) *PlaybookThrottle {
+	return &PlaybookThrottle{
+		client: client,
+		ctx:    context.Background(),
+		local:  make(map[string][]time.Time),
+		stats:  make(map[string]*ThrottleBucketStats),
+	}
+}
+
+// reserve records a call in a bucket if it is within the rate, returning 0,
+// or returns how long to wait before trying again
+func (pt *PlaybookThrottle) reserve(bucket string, rate int, per time.Duration) time.Duration {
+	if pt.client != nil {
+		wait, err := throttleScript.Run(pt.ctx, pt.client, []string{throttleKeyPrefix + bucket},
+			per.Milliseconds(), rate, uuid.New().String()).Int64()
+		if err == nil {
+			return time.Duration(wait) * time.Millisecond
+		}
+		logger.Warning("Throttle bucket unavailable in Redis, limiting on this node only", map[string]interface{}{
+			"component": "rules_engine",
+			"bucket":    bucket,
+			"error":     err.Error(),
+		})
+	}
+
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	now := time.Now()
+	calls := pt.local[bucket]
+	for len(calls) > 0 && !calls[0].After(now.Add(-per)) {
+		calls = calls[1:]
+	}
+	if len(calls) < rate {
+		pt.local[bucket] = append(calls, now)
+		return 0
+	}
+	pt.local[bucket] = calls
+	return calls[0].Add(per).Sub(now)
+}
+
+// Wait blocks until a call to bucket is within rate calls per interval and
+// returns how long it waited. It fails rather than wait longer than maxWait.
+func (pt *PlaybookThrottle) Wait(bucket string, rate int, per, maxWait time.Duration) (time.Duration, error) {
+	start := time.Now()
+	var waited time.Duration
+	for {
+		wait := pt.reserve(bucket, rate, per)
+		if wait == 0 {
+			pt.record(bucket, waited, false)
+			return waited, nil
+		}
+		if waited+wait > maxWait {
+			pt.record(bucket, waited, true)
+			return waited, fmt.Errorf("throttle %s: rate of %d per %s would exceed the wait limit of %s", bucket, rate, per, maxWait)
+		}
+		time.Sleep(wait)
+		waited = time.Since(start)
+	}
+}
+
+// record adds a call to a bucket's statistics
+func (pt *PlaybookThrottle) record(bucket string, waited time.Duration, timedOut bool) {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	stats, exists := pt.stats[bucket]
+	if !exists {
+		stats = &ThrottleBucketStats{}
+		pt.stats[bucket] = stats
+	}
+	stats.Calls++
+	if timedOut {
+		stats.TimedOut++
+	}
+	if waited > 0 {
+		seconds := waited.Seconds()
+		stats.Throttled++
+		stats.WaitSeconds += seconds
+		if seconds > stats.MaxWaitSeconds {
+			stats.MaxWaitSeconds = seconds
+		}
+	}
+}
+
+// Stats returns the activity of every bucket used on this node
+func (pt *PlaybookThrottle) Stats() map[string]interface{} {
+	pt.mutex.Lock()
+	defer pt.mutex.Unlock()
+
+	buckets := make([]string, 0, len(pt.stats))
+	for bucket := range pt.stats {
+		buckets = append(buckets, bucket)
+	}
+	sort.Strings(buckets)
+
+	stats := make(map[string]ThrottleBucketStats, len(buckets))
+	var waitSeconds float64
+	for _, bucket := range buckets {
+		stats[bucket] = *pt.stats[bucket]
+		waitSeconds += pt.stats[bucket].WaitSeconds
+	}
+
+	backend := "local"
+	if pt.client != nil {
+		backend = "redis"
+	}
+	return map[string]interface{}{
+		"backend":            backend,
+		"wait_seconds_total": waitSeconds,
+		"buckets":            stats,
+	}
+}
+
+// SetThrottle sets the throttle shared by the throttle operations of all jobs
+func (re *RuleEngine) SetThrottle(throttle *PlaybookThrottle) {
+	re.throttle = throttle
+}
+
+// parseThrottleSpec reads {"bucket": "name", "rate": n, "per": "1m"}
+func parseThrottleSpec(spec interface{}) (string, int, time.Duration, error) {
+	specMap, ok := spec.(map[string]interface{})
+	if !ok {
+		return "", 0, 0, fmt.Errorf("throttle operation requires an object with bucket, rate and per")
+	}
+
+	bucket, ok := specMap["bucket"].(string)
+	if !ok || bucket == "" {
+		return "", 0, 0, fmt.Errorf("throttle operation requires a non-empty bucket")
+	}
+	rate, ok := specMap["rate"].(float64)
+	if !ok || rate < 1 || rate != float64(int(rate)) {
+		return "", 0, 0, fmt.Errorf("throttle %s: rate must be a positive whole number", bucket)
+	}
+	perValue, ok := specMap["per"].(string)
+	if !ok {
+		return "", 0, 0, fmt.Errorf("throttle %s: per must be a duration such as \"1s\" or \"1m\"", bucket)
+	}
+	per, err := time.ParseDuration(perValue)
+	if err != nil || per < time.Millisecond {
+		return "", 0, 0, fmt.Errorf("throttle %s: per must be a duration of at least 1ms", bucket)
+	}
+	return bucket, int(rate), per, nil
+}
+
+// evaluateThrottleOperation handles {"throttle": {"bucket": "virustotal",
+// "rate": 4, "per": "1m"}}, which waits until fewer than rate calls have
+// passed through the bucket in the last interval. Placed before a run or
+// plugin in a loop, it keeps the loop within an external API's quota. The
+// wait is bounded by what remains of rules_engine.max_execution_time.
+func (re *RuleEngine) evaluateThrottleOperation(spec interface{}, data map[string]interface{}) (interface{}, error) {
+	bucket, rate, per, err := parseThrottleSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	maxWait := defaultMaxThrottleWait
+	if re.config != nil && re.config.RulesEngine.MaxExecutionTime > 0 {
+		maxWait = time.Duration(re.config.RulesEngine.MaxExecutionTime) * time.Second
+	}
+	if !re.executionDeadline.IsZero() {
+		maxWait = time.Until(re.executionDeadline)
+	}
+
+	throttle := re.throttle
+	if throttle == nil {
+		throttle = localPlaybookThrottle
+	}
+	waited, err := throttle.Wait(bucket, rate, per, maxWait)
+	if err != nil {
+		return nil, err
+	}
+
+	if waited > 0 {
+		logger.Info("Throttle delayed playbook", map[string]interface{}{
+			"component":   "rules_engine",
+			"bucket":      bucket,
+			"duration_ms": float64(waited.Milliseconds()),
+		})
+	}
+
+	return map[string]interface{}{
+		"bucket":    bucket,
+		"waited_ms": waited.Milliseconds(),
+	}, nil
+}
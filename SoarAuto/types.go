@@ -1,6 +1,9 @@
 package main
 
 import (
+	"encoding/json"
+	"sync"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -21,6 +24,15 @@ type SecAutoServer struct {
 	clusterManager           *ClusterManager
 	jobScheduler             *JobScheduler
 	integrationConfigManager *IntegrationConfigManager
+	syncLimiter              *ConcurrencyLimiter
+	uploadManager            *UploadManager
+	libraryMonitor           *LibraryMonitor
+	storageLimits            StorageConfig
+	storageMutex             sync.Mutex // Held while an upload is checked against the storage limits and written
+	playbookGit              *PlaybookGitSync
+	downloadLinks            *DownloadLinks
+	lastContext              map[string]interface{}
+	contextMutex             sync.RWMutex
 }
 
 // JobListResponse represents the response for listing jobs
@@ -33,32 +45,73 @@ type JobListResponse struct {
 
 // JobStatsResponse represents job statistics
 type JobStatsResponse struct {
-	Success     bool    `json:"success"`
-	TotalJobs   int     `json:"total_jobs"`
-	Completed   int     `json:"completed"`
-	Failed      int     `json:"failed"`
-	Running     int     `json:"running"`
-	Pending     int     `json:"pending"`
-	AvgDuration float64 `json:"avg_duration_seconds"`
-	RecentJobs  []*Job  `json:"recent_jobs"`
-	Timestamp   string  `json:"timestamp"`
+	Success             bool    `json:"success"`
+	TotalJobs           int     `json:"total_jobs"`
+	Completed           int     `json:"completed"`
+	CompletedWithErrors int     `json:"completed_with_errors"`
+	Failed              int     `json:"failed"`
+	Aborted             int     `json:"aborted"`
+	Skipped             int     `json:"skipped"`
+	Running             int     `json:"running"`
+	Pending             int     `json:"pending"`
+	AvgDuration         float64 `json:"avg_duration_seconds"`
+	// ResourceUsage aggregates the usage recorded for jobs; absent when
+	// memory and CPU tracking are disabled
+	ResourceUsage *JobResourceStats `json:"resource_usage,omitempty"`
+	RecentJobs    []*Job            `json:"recent_jobs"`
+	Timestamp     string            `json:"timestamp"`
+}
+
+// CancelJobRequest is the optional body of DELETE /job/{id}
+type CancelJobRequest struct {
+	Reason string `json:"reason"`
 }
 
 // CancelJobResponse represents the response for canceling a job
 type CancelJobResponse struct {
+	Success     bool   `json:"success"`
+	JobID       string `json:"job_id"`
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	Reason      string `json:"reason,omitempty"`
+	CancelledBy string `json:"cancelled_by,omitempty"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// JobDiffResponse represents the context changes made by a job's playbook run
+type JobDiffResponse struct {
+	Success   bool         `json:"success"`
+	JobID     string       `json:"job_id"`
+	Status    string       `json:"status"`
+	Diff      *ContextDiff `json:"diff"`
+	Timestamp string       `json:"timestamp"`
+}
+
+// JobPollResponse represents a job returned by a long poll, with the version
+// to pass as since_version on the next poll
+type JobPollResponse struct {
 	Success   bool   `json:"success"`
 	JobID     string `json:"job_id"`
-	Status    string `json:"status"`
-	Message   string `json:"message"`
+	Version   int64  `json:"version"`
+	Job       *Job   `json:"job"`
 	Timestamp string `json:"timestamp"`
 }
 
+// JobStatusResponse is a job as returned by GET /job/{id}; a pending job
+// also reports its place in the queue
+type JobStatusResponse struct {
+	*Job
+	Queue *JobQueuePosition `json:"queue,omitempty"`
+}
+
 // JobResponse represents the response for job submission
 type JobResponse struct {
-	Success   bool   `json:"success"`
-	JobID     string `json:"job_id"`
-	Status    string `json:"status"`
-	Timestamp string `json:"timestamp"`
+	Success           bool   `json:"success"`
+	JobID             string `json:"job_id"`
+	Status            string `json:"status"`
+	ProbableDuplicate bool   `json:"probable_duplicate,omitempty"`
+	Fingerprint       string `json:"fingerprint,omitempty"`
+	Timestamp         string `json:"timestamp"`
 }
 
 // HealthResponse represents the health check response
@@ -68,15 +121,46 @@ type HealthResponse struct {
 	Version   string `json:"version"`
 }
 
+// ReadinessResponse represents the readiness check response
+type ReadinessResponse struct {
+	Status    string       `json:"status"` // ready, or degraded while a dependency is down
+	Timestamp string       `json:"timestamp"`
+	Redis     *RedisHealth `json:"redis,omitempty"`
+}
+
 // JobStats represents job statistics
 type JobStats struct {
-	TotalJobs   int     `json:"total_jobs"`
-	Completed   int     `json:"completed"`
-	Failed      int     `json:"failed"`
-	Running     int     `json:"running"`
-	Pending     int     `json:"pending"`
-	AvgDuration float64 `json:"avg_duration_seconds"`
-	RecentJobs  []*Job  `json:"recent_jobs"`
+	TotalJobs           int     `json:"total_jobs"`
+	Completed           int     `json:"completed"`
+	CompletedWithErrors int     `json:"completed_with_errors"`
+	Failed              int     `json:"failed"`
+	Aborted             int     `json:"aborted"`
+	Skipped             int     `json:"skipped"`
+	Running             int     `json:"running"`
+	Pending             int     `json:"pending"`
+	AvgDuration         float64 `json:"avg_duration_seconds"`
+	// ResourceUsage is nil when no job recorded resource usage
+	ResourceUsage *JobResourceStats `json:"resource_usage,omitempty"`
+	RecentJobs    []*Job            `json:"recent_jobs"`
+}
+
+// JobResourceStats aggregates the CPU time and memory used by the
+// automations of the jobs recording resource usage
+type JobResourceStats struct {
+	MeasuredJobs    int                       `json:"measured_jobs"`
+	TotalCPUSeconds float64                   `json:"total_cpu_seconds"`
+	AvgCPUSeconds   float64                   `json:"avg_cpu_seconds"`
+	MaxPeakRSSBytes int64                     `json:"max_peak_rss_bytes"`
+	TopAutomations  []AutomationResourceStats `json:"top_automations,omitempty"` // Most CPU time first
+}
+
+// AutomationResourceStats aggregates the usage of one automation across jobs
+type AutomationResourceStats struct {
+	Name            string  `json:"name"`
+	Runs            int     `json:"runs"`
+	TotalCPUSeconds float64 `json:"total_cpu_seconds"`
+	AvgCPUSeconds   float64 `json:"avg_cpu_seconds"`
+	MaxPeakRSSBytes int64   `json:"max_peak_rss_bytes"`
 }
 
 // PlaybookRequest represents a request to execute a playbook
@@ -85,15 +169,34 @@ type PlaybookRequest struct {
 	PlaybookName string                 `json:"playbook_name,omitempty"`
 	Context      map[string]interface{} `json:"context,omitempty"`
 	Options      map[string]interface{} `json:"options,omitempty"`
+	Env          map[string]string      `json:"env,omitempty"`      // Extra environment variables for Python automations
+	Async        bool                   `json:"async,omitempty"`    // Submit as a job instead of executing synchronously
+	Priority     string                 `json:"priority,omitempty"` // Job priority: low, normal, high or critical
+	// ContinueOnError lets every rule without its own continue_on_error flag
+	// fail without stopping the playbook
+	ContinueOnError bool `json:"continue_on_error,omitempty"`
+	// Strict runs this playbook in strict (true) or lenient (false) mode
+	// instead of the configured behavior; see RuleEngine.WithStrictMode
+	Strict *bool `json:"strict,omitempty"`
+	// Caller identifies who made the request; set by the server, not the client
+	Caller string `json:"-"`
 }
 
 // PlaybookResponse represents the response from a playbook execution
 type PlaybookResponse struct {
-	Success   bool                   `json:"success"`
-	Results   []interface{}          `json:"results,omitempty"`
-	Context   map[string]interface{} `json:"context"`
-	Error     string                 `json:"error,omitempty"`
-	Timestamp string                 `json:"timestamp"`
+	Success     bool                   `json:"success"`
+	Status      string                 `json:"status,omitempty"`       // Set when the playbook aborted or rules failed
+	AbortReason string                 `json:"abort_reason,omitempty"` // Reason given by the abort operation
+	Results     []interface{}          `json:"results,omitempty"`
+	Context     map[string]interface{} `json:"context"`
+	Error       string                 `json:"error,omitempty"`
+	ErrorType   string                 `json:"error_type,omitempty"` // "assertion_failed" when an assert operation failed
+	Warnings    []TemplateWarning      `json:"warnings,omitempty"`   // Template variables that could not be resolved
+	Findings    []Finding              `json:"findings,omitempty"`   // Recorded by finding operations, apart from Results
+	Timestamp   string                 `json:"timestamp"`
+
+	// Set when Results were cut down to the size cap
+	ResultTruncation
 }
 
 // AutomationUploadResponse represents the response for automation upload
@@ -113,18 +216,113 @@ type PlaybookUploadResponse struct {
 	PlaybookName string `json:"playbook_name"`
 	Filename     string `json:"filename"`
 	Size         int64  `json:"size"`
+	ContentHash  string `json:"content_hash"`
+	Unchanged    bool   `json:"unchanged"`
 	Timestamp    string `json:"timestamp"`
 }
 
+// CSVImportResponse represents the response for a CSV context import
+type CSVImportResponse struct {
+	Success   bool                   `json:"success"`
+	Message   string                 `json:"message"`
+	Mode      string                 `json:"mode"`
+	Rows      int                    `json:"rows"`
+	Context   map[string]interface{} `json:"context,omitempty"`
+	JobIDs    []string               `json:"job_ids,omitempty"`
+	Timestamp string                 `json:"timestamp"`
+}
+
+// SelfTestCheck is the result of one self-test subsystem check
+type SelfTestCheck struct {
+	Name       string                 `json:"name"`
+	Passed     bool                   `json:"passed"`
+	Message    string                 `json:"message"`
+	DurationMs int64                  `json:"duration_ms"`
+	Details    map[string]interface{} `json:"details,omitempty"`
+}
+
+// SelfTestResponse is the per-subsystem report returned by POST /selftest
+type SelfTestResponse struct {
+	Success   bool            `json:"success"`
+	Passed    bool            `json:"passed"`
+	Checks    []SelfTestCheck `json:"checks"`
+	Timestamp string          `json:"timestamp"`
+}
+
+// LibraryAssetResult is the validation outcome of one stored asset
+type LibraryAssetResult struct {
+	Type   string            `json:"type"` // "playbook", "automation" or "integration"
+	Name   string            `json:"name"`
+	Valid  bool              `json:"valid"`
+	Errors []ValidationError `json:"errors,omitempty"`
+}
+
+// LibraryAssetCount counts the checked and invalid assets of one type
+type LibraryAssetCount struct {
+	Checked int `json:"checked"`
+	Invalid int `json:"invalid"`
+}
+
+// LibraryProblem is a single validation error of a stored asset
+type LibraryProblem struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+	Value   string `json:"value,omitempty"`
+}
+
+// LibraryValidationResponse is the asset library health report returned by
+// POST /validate/all
+type LibraryValidationResponse struct {
+	Success     bool                         `json:"success"`
+	Healthy     bool                         `json:"healthy"`
+	Summary     map[string]LibraryAssetCount `json:"summary"`
+	Assets      []LibraryAssetResult         `json:"assets"`
+	Problems    []LibraryProblem             `json:"problems"`
+	NewProblems []LibraryProblem             `json:"new_problems"` // Problems not found by the previous validation
+	Warnings    []string                     `json:"warnings,omitempty"`
+	DurationMs  int64                        `json:"duration_ms"`
+	Timestamp   string                       `json:"timestamp"`
+}
+
+// BatchValidationRequest is the body of POST /validate/batch. Each playbook
+// is the name of a stored playbook, a playbook array, or an object with a
+// playbook and an optional name to report it under.
+type BatchValidationRequest struct {
+	Playbooks []json.RawMessage `json:"playbooks"`
+	FailFast  bool              `json:"fail_fast"` // Stop at the first invalid playbook and respond 422
+}
+
+// BatchValidationResult is the validation of one playbook of a batch
+type BatchValidationResult struct {
+	Index  int               `json:"index"`
+	Name   string            `json:"name,omitempty"`
+	Valid  bool              `json:"valid"`
+	Errors []ValidationError `json:"errors,omitempty"`
+}
+
+// BatchValidationResponse reports the validation of a batch of playbooks
+type BatchValidationResponse struct {
+	Success   bool                    `json:"success"`
+	Valid     bool                    `json:"valid"` // Whether every checked playbook is valid
+	Checked   int                     `json:"checked"`
+	Invalid   int                     `json:"invalid"`
+	Results   []BatchValidationResult `json:"results"`
+	Warnings  []string                `json:"warnings,omitempty"`
+	Timestamp string                  `json:"timestamp"`
+}
+
 // PlaybookInfo represents information about a playbook
 type PlaybookInfo struct {
-	Name       string         `json:"name"`
-	Filename   string         `json:"filename"`
-	Size       int64          `json:"size"`
-	RuleCount  int            `json:"rule_count"`
-	Operations map[string]int `json:"operations"`
-	ModifiedAt string         `json:"modified_at"`
-	IsValid    bool           `json:"is_valid"`
+	Name        string         `json:"name"`
+	Filename    string         `json:"filename"`
+	Size        int64          `json:"size"`
+	RuleCount   int            `json:"rule_count"`
+	Operations  map[string]int `json:"operations"`
+	ContentHash string         `json:"content_hash"`
+	ModifiedAt  string         `json:"modified_at"`
+	IsValid     bool           `json:"is_valid"`
 }
 
 // PlaybookListResponse represents the response for playbook list
@@ -174,6 +372,7 @@ type AutomationInfo struct {
 	LineCount     int    `json:"line_count"`
 	FunctionCount int    `json:"function_count"`
 	ImportCount   int    `json:"import_count"`
+	ContentHash   string `json:"content_hash"`
 	ModifiedAt    string `json:"modified_at"`
 	IsValid       bool   `json:"is_valid"`
 }
@@ -205,6 +404,29 @@ type IntegrationResponse struct {
 	Timestamp    string               `json:"timestamp"`
 }
 
+// UploadSessionRequest starts a chunked upload
+type UploadSessionRequest struct {
+	Type       string `json:"type"`                  // automation, playbook, plugin or integration
+	PluginType string `json:"plugin_type,omitempty"` // Required for plugin uploads
+	Filename   string `json:"filename"`
+	Size       int64  `json:"size"`
+	Checksum   string `json:"checksum"` // Hex-encoded SHA-256 of the whole file
+}
+
+// UploadSessionResponse describes a chunked upload in progress
+type UploadSessionResponse struct {
+	Success    bool   `json:"success"`
+	Message    string `json:"message"`
+	UploadID   string `json:"upload_id"`
+	Type       string `json:"type"`
+	PluginType string `json:"plugin_type,omitempty"`
+	Filename   string `json:"filename"`
+	Size       int64  `json:"size"`
+	Received   int64  `json:"received"`
+	ExpiresAt  string `json:"expires_at"`
+	Timestamp  string `json:"timestamp"`
+}
+
 // IntegrationUploadResponse represents the response for integration upload
 type IntegrationUploadResponse struct {
 	Success         bool   `json:"success"`
@@ -239,3 +461,23 @@ type CacheResponse struct {
 	ErrorMessage string      `json:"error_message,omitempty"`
 	Timestamp    string      `json:"timestamp"`
 }
+
+// SearchMatch represents a single search hit in a playbook, automation or integration
+type SearchMatch struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Field   string `json:"field"`          // "name" or "content"
+	Line    int    `json:"line,omitempty"` // 1-based line number for content matches
+	Snippet string `json:"snippet"`
+}
+
+// SearchResponse represents the response for asset search
+type SearchResponse struct {
+	Success   bool          `json:"success"`
+	Query     string        `json:"query"`
+	Types     []string      `json:"types"`
+	Matches   []SearchMatch `json:"matches"`
+	Count     int           `json:"count"`
+	Truncated bool          `json:"truncated"`
+	Timestamp string        `json:"timestamp"`
+}
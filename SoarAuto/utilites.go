@@ -2,18 +2,31 @@ package main
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// errRevisionConflict is returned when an update is based on a stale revision
+var errRevisionConflict = errors.New("resource was modified by another request")
+
+// contentHash returns the hex encoded SHA-256 hash of data
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
 func generateRandomAPIKey() string {
 	b := make([]byte, 32)
 	_, err := rand.Read(b)
@@ -23,27 +36,59 @@ func generateRandomAPIKey() string {
 	return hex.EncodeToString(b)
 }
 
-// loadAPIKeysFromConfig loads API keys from the loaded configuration
-func loadAPIKeysFromConfig(config *Config) {
-	// Initialize the allowed API keys map
-	allowedAPIKeys = make(map[string]struct{})
+// apiKeySet holds the API keys the server accepts. A set is never modified
+// once stored in apiKeys; a reload stores a new one, so requests check
+// against either the old or the new keys, never a mix.
+type apiKeySet struct {
+	allowed map[string]struct{}
+	admin   map[string]struct{}
+}
+
+// apiKeys is the current API key set
+var apiKeys atomic.Pointer[apiKeySet]
+
+// startupEnvAPIKey is SECAUTO_API_KEY as the server was started with.
+// setEnvironmentVariablesForIntegrations overwrites the variable for Python
+// integrations, so reloads must not read it back as a configured key.
+var startupEnvAPIKey = os.Getenv("SECAUTO_API_KEY")
+
+// buildAPIKeySet collects the API keys of a configuration and SECAUTO_API_KEY
+func buildAPIKeySet(config *Config) *apiKeySet {
+	keys := &apiKeySet{
+		allowed: make(map[string]struct{}),
+		admin:   make(map[string]struct{}),
+	}
 
 	// Add API keys from config
 	for _, key := range config.Security.APIKeys {
 		if key != "" && key != "your-secauto-api-key-here" {
-			allowedAPIKeys[key] = struct{}{}
+			keys.allowed[key] = struct{}{}
 		}
 	}
 
 	// Also check for environment variable (for backward compatibility)
-	if envKey := os.Getenv("SECAUTO_API_KEY"); envKey != "" {
-		allowedAPIKeys[envKey] = struct{}{}
+	if startupEnvAPIKey != "" {
+		keys.allowed[startupEnvAPIKey] = struct{}{}
 	}
 
+	// Admin keys grant access to privileged endpoints and are valid API keys as well
+	for _, key := range config.Security.AdminAPIKeys {
+		if key != "" {
+			keys.admin[key] = struct{}{}
+			keys.allowed[key] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// loadAPIKeysFromConfig loads API keys from the loaded configuration
+func loadAPIKeysFromConfig(config *Config) {
+	keys := buildAPIKeySet(config)
+
 	// If no API keys found, generate a random one
-	if len(allowedAPIKeys) == 0 {
+	if len(keys.allowed) == 0 {
 		apiKey := generateRandomAPIKey()
-		allowedAPIKeys[apiKey] = struct{}{}
+		keys.allowed[apiKey] = struct{}{}
 		logger.Warning("No API keys found in config or environment, generated random API key", map[string]interface{}{
 			"component": "auth",
 			"api_key":   apiKey,
@@ -51,18 +96,91 @@ func loadAPIKeysFromConfig(config *Config) {
 	} else {
 		logger.Info("Loaded API keys from configuration", map[string]interface{}{
 			"component": "auth",
-			"key_count": len(allowedAPIKeys),
+			"key_count": len(keys.allowed),
 		})
 	}
+	apiKeys.Store(keys)
 
 	// Set environment variables for Python integrations
 	setEnvironmentVariablesForIntegrations(config)
 }
 
+// ReloadAPIKeys re-reads the API keys from a configuration file and replaces
+// the accepted keys in one step, so keys can be rotated without a restart.
+// Requests already authenticated are unaffected. A configuration without any
+// key is rejected and the current keys are kept, since the random key
+// generated at startup would lock every client out.
+func ReloadAPIKeys(configPath string) error {
+	config, err := LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %v", err)
+	}
+
+	// LoadConfig appends SECAUTO_API_KEY, which by now holds the key handed to
+	// Python integrations rather than the one the server was started with;
+	// keeping that copy would re-admit a key the file just rotated out
+	if injected := os.Getenv("SECAUTO_API_KEY"); injected != "" && injected != startupEnvAPIKey {
+		if n := len(config.Security.APIKeys); n > 0 && config.Security.APIKeys[n-1] == injected {
+			config.Security.APIKeys = config.Security.APIKeys[:n-1]
+		}
+	}
+
+	keys := buildAPIKeySet(config)
+	if len(keys.allowed) == 0 {
+		return fmt.Errorf("%s configures no API keys; keeping the current keys", configPath)
+	}
+
+	previous := apiKeys.Swap(keys)
+	if previous == nil {
+		previous = &apiKeySet{}
+	}
+	added, removed := 0, 0
+	for key := range keys.allowed {
+		if _, exists := previous.allowed[key]; !exists {
+			added++
+		}
+	}
+	for key := range previous.allowed {
+		if _, exists := keys.allowed[key]; !exists {
+			removed++
+		}
+	}
+	logger.Info("Reloaded API keys", map[string]interface{}{
+		"component": "auth",
+		"key_count": len(keys.allowed),
+		"added":     added,
+		"removed":   removed,
+	})
+
+	// Python integrations must not keep using a key that was removed
+	setEnvironmentVariablesForIntegrations(config)
+	return nil
+}
+
+// isAllowedAPIKey reports whether key is an accepted API key
+func isAllowedAPIKey(key string) bool {
+	keys := apiKeys.Load()
+	if keys == nil {
+		return false
+	}
+	_, ok := keys.allowed[key]
+	return ok
+}
+
+// isAdminAPIKey reports whether key is an admin API key
+func isAdminAPIKey(key string) bool {
+	keys := apiKeys.Load()
+	if keys == nil {
+		return false
+	}
+	_, ok := keys.admin[key]
+	return ok
+}
+
 // setEnvironmentVariablesForIntegrations sets environment variables that Python integrations can use
 func setEnvironmentVariablesForIntegrations(config *Config) {
 	// Set SECAUTO_API_KEY to the first valid API key
-	for key := range allowedAPIKeys {
+	for key := range apiKeys.Load().allowed {
 		if key != "" && key != "your-secauto-api-key-here" {
 			os.Setenv("SECAUTO_API_KEY", key)
 			logger.Info("Set SECAUTO_API_KEY environment variable for Python integrations", map[string]interface{}{
@@ -99,7 +217,7 @@ func setEnvironmentVariablesForIntegrations(config *Config) {
 func writeIntegrationConfigFile(config *Config, secautoURL string) {
 	// Find the first valid API key
 	var apiKey string
-	for key := range allowedAPIKeys {
+	for key := range apiKeys.Load().allowed {
 		if key != "" && key != "your-secauto-api-key-here" {
 			apiKey = key
 			break
@@ -167,11 +285,8 @@ func apiKeyAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		key := r.Header.Get("X-API-Key")
-		if key == "" {
-			key = r.URL.Query().Get("api_key")
-		}
-		if _, ok := allowedAPIKeys[key]; !ok {
+		key := getRequestAPIKey(r)
+		if !isAllowedAPIKey(key) {
 			logger.Error("Unauthorized API access", map[string]interface{}{
 				"component":   "auth",
 				"remote_addr": r.RemoteAddr,
@@ -185,6 +300,53 @@ func apiKeyAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	}
 }
 
+// getRequestAPIKey returns the API key supplied with the request
+func getRequestAPIKey(r *http.Request) string {
+	key := r.Header.Get("X-API-Key")
+	if key == "" {
+		key = r.URL.Query().Get("api_key")
+	}
+	return key
+}
+
+// apiKeyCaller identifies the caller using an API key in audit records,
+// without revealing the key
+func apiKeyCaller(key string) string {
+	if key == "" {
+		return "anonymous"
+	}
+	return "api_key:" + maskSecret(key)
+}
+
+// isAdminRequest reports whether the request was made with an admin API key
+func isAdminRequest(r *http.Request) bool {
+	return isAdminAPIKey(getRequestAPIKey(r))
+}
+
+// formatETag formats a resource revision as an ETag header value
+func formatETag(revision int) string {
+	return fmt.Sprintf("\"%d\"", revision)
+}
+
+// parseIfMatch reads the revision from the If-Match header.
+// A wildcard ("*") matches any revision and is returned as 0.
+func parseIfMatch(r *http.Request) (int, bool, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, false, nil
+	}
+	if header == "*" {
+		return 0, true, nil
+	}
+
+	value := strings.Trim(strings.TrimPrefix(header, "W/"), "\"")
+	revision, err := strconv.Atoi(value)
+	if err != nil || revision <= 0 {
+		return 0, true, fmt.Errorf("invalid If-Match header: %s", header)
+	}
+	return revision, true, nil
+}
+
 // getClientIP extracts the real client IP
 func getClientIP(r *http.Request) string {
 	// Check for forwarded headers
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// writeAPIKeyConfig writes a configuration file accepting the given keys
+func writeAPIKeyConfig(t *testing.T, path string, keys ...string) {
+	t.Helper()
+	content := "security:\n  api_keys:\n"
+	for _, key := range keys {
+		content += "    - \"" + key + "\"\n"
+	}
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestReloadAPIKeysDuringRequests checks keys while they are reloaded. Run
+// with -race: a key kept across reloads must never be refused, and a rotated
+// key must be refused once the reload returns.
+func TestReloadAPIKeysDuringRequests(t *testing.T) {
+	const (
+		kept    = "kept-key-0123456789abcdef"
+		rotated = "rotated-key-0123456789abcdef"
+		added   = "added-key-0123456789abcdef"
+	)
+	t.Setenv("SECAUTO_API_KEY", "")
+	previous := apiKeys.Load()
+	t.Cleanup(func() { apiKeys.Store(previous) })
+
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "first.yaml"), filepath.Join(dir, "second.yaml")
+	writeAPIKeyConfig(t, first, kept, rotated)
+	writeAPIKeyConfig(t, second, kept, added)
+	if err := ReloadAPIKeys(first); err != nil {
+		t.Fatal(err)
+	}
+
+	var stop atomic.Bool
+	var refused atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for !stop.Load() {
+				if !isAllowedAPIKey(kept) {
+					refused.Add(1)
+				}
+				isAllowedAPIKey(rotated)
+				isAdminAPIKey(added)
+			}
+		}()
+	}
+
+	for i := 0; i < 50; i++ {
+		path := first
+		if i%2 == 1 {
+			path = second
+		}
+		if err := ReloadAPIKeys(path); err != nil {
+			t.Errorf("reload %d: %v", i, err)
+		}
+	}
+	stop.Store(true)
+	wg.Wait()
+
+	if n := refused.Load(); n > 0 {
+		t.Errorf("the kept key was refused %d times during reloads", n)
+	}
+	// The last reload used the second file
+	if isAllowedAPIKey(rotated) || !isAllowedAPIKey(added) {
+		t.Errorf("after the last reload rotated allowed = %v, added allowed = %v", isAllowedAPIKey(rotated), isAllowedAPIKey(added))
+	}
+}
+
+func TestReloadAPIKeysKeepsKeysWhenNoneConfigured(t *testing.T) {
+	const kept = "kept-key-0123456789abcdef"
+	t.Setenv("SECAUTO_API_KEY", "")
+	previous := apiKeys.Load()
+	t.Cleanup(func() { apiKeys.Store(previous) })
+
+	dir := t.TempDir()
+	withKey, blank := filepath.Join(dir, "with_key.yaml"), filepath.Join(dir, "blank.yaml")
+	writeAPIKeyConfig(t, withKey, kept)
+	// An absent list is replaced by the default keys; a blank key is ignored
+	writeAPIKeyConfig(t, blank, "")
+	if err := ReloadAPIKeys(withKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReloadAPIKeys(blank); err == nil {
+		t.Error("reloading a configuration without keys succeeded")
+	}
+	if !isAllowedAPIKey(kept) {
+		t.Error("the current key was dropped by a rejected reload")
+	}
+}
@@ -36,17 +36,49 @@ type Validator struct {
 	scriptNameRegex *regexp.Regexp
 	pathRegex       *regexp.Regexp
 	urlRegex        *regexp.Regexp
+	filenameRegex   *regexp.Regexp
+	envKeyRegex     *regexp.Regexp
 }
 
+// defaultFilenamePattern is the allowlist applied to uploaded file names
+const defaultFilenamePattern = `^[a-zA-Z0-9_\-]+\.[a-zA-Z0-9]+$`
+
+const (
+	// maxJobEnvVars caps the number of per-job environment variables
+	maxJobEnvVars = 50
+	// maxJobEnvSize caps the combined size of per-job environment keys and values
+	maxJobEnvSize = 32 * 1024
+)
+
+// reservedEnvPrefixes are environment variables a request may not override
+// because they change how the interpreter or loader behaves
+var reservedEnvPrefixes = []string{"PATH", "LD_", "DYLD_", "PYTHON", "VIRTUAL_ENV", "HOME"}
+
 // NewValidator creates a new validator
 func NewValidator() *Validator {
 	return &Validator{
 		scriptNameRegex: regexp.MustCompile(`^[a-zA-Z0-9_-]+$`),
 		pathRegex:       regexp.MustCompile(`^[a-zA-Z0-9/._-]+$`),
 		urlRegex:        regexp.MustCompile(`^https?://[^\s/$.?#].[^\s]*$`),
+		filenameRegex:   regexp.MustCompile(defaultFilenamePattern),
+		envKeyRegex:     regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`),
 	}
 }
 
+// SetFilenamePattern sets the allowlist pattern file names must match; an empty
+// pattern keeps the default
+func (v *Validator) SetFilenamePattern(pattern string) error {
+	if pattern == "" {
+		pattern = defaultFilenamePattern
+	}
+	regex, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("invalid filename pattern %q: %v", pattern, err)
+	}
+	v.filenameRegex = regex
+	return nil
+}
+
 // ValidatePlaybookRequest validates a playbook execution request
 func (v *Validator) ValidatePlaybookRequest(req *PlaybookRequest) ValidationResult {
 	var errors []ValidationError
@@ -82,6 +114,18 @@ func (v *Validator) ValidatePlaybookRequest(req *PlaybookRequest) ValidationResu
 		}
 	}
 
+	// Validate environment variables if provided
+	errors = append(errors, v.validateEnv(req.Env)...)
+
+	// Validate priority if provided
+	if req.Priority != "" && !jobPriorities[req.Priority] {
+		errors = append(errors, ValidationError{
+			Field:   "priority",
+			Message: "Priority must be one of low, normal, high or critical",
+			Value:   req.Priority,
+		})
+	}
+
 	// Ensure either playbook or playbook_name is provided
 	if req.Playbook == nil && req.PlaybookName == "" {
 		errors = append(errors, ValidationError{
@@ -122,10 +166,13 @@ func (v *Validator) ValidateWebhookConfig(config *WebhookConfig) ValidationResul
 		})
 	} else {
 		validEvents := map[string]bool{
-			"job_started":   true,
-			"job_completed": true,
-			"job_failed":    true,
-			"job_cancelled": true,
+			"job_started":            true,
+			"job_completed":          true,
+			"job_failed":             true,
+			"job_cancelled":          true,
+			"job_aborted":            true,
+			"plugin_reloaded":        true,
+			"library_problems_found": true,
 		}
 		for _, event := range config.Events {
 			if !validEvents[event] {
@@ -273,6 +320,55 @@ func (v *Validator) validateContext(context map[string]interface{}) error {
 	return nil
 }
 
+// validateEnv validates per-job environment variable names and size
+func (v *Validator) validateEnv(env map[string]string) []ValidationError {
+	var errors []ValidationError
+	if len(env) > maxJobEnvVars {
+		errors = append(errors, ValidationError{
+			Field:   "env",
+			Message: fmt.Sprintf("Too many environment variables (max %d)", maxJobEnvVars),
+		})
+	}
+
+	size := 0
+	for key, value := range env {
+		size += len(key) + len(value)
+		if !v.envKeyRegex.MatchString(key) {
+			errors = append(errors, ValidationError{
+				Field:   "env",
+				Message: "Invalid environment variable name",
+				Value:   key,
+			})
+			continue
+		}
+		for _, prefix := range reservedEnvPrefixes {
+			if strings.HasPrefix(strings.ToUpper(key), prefix) {
+				errors = append(errors, ValidationError{
+					Field:   "env",
+					Message: "Environment variable is reserved",
+					Value:   key,
+				})
+				break
+			}
+		}
+		if strings.ContainsRune(value, 0) {
+			errors = append(errors, ValidationError{
+				Field:   "env",
+				Message: "Environment variable value contains a NUL byte",
+				Value:   key,
+			})
+		}
+	}
+
+	if size > maxJobEnvSize {
+		errors = append(errors, ValidationError{
+			Field:   "env",
+			Message: fmt.Sprintf("Environment variables too large (max %d bytes)", maxJobEnvSize),
+		})
+	}
+	return errors
+}
+
 // SanitizePath sanitizes file paths
 func (v *Validator) SanitizePath(path string) string {
 	// Remove any directory traversal attempts
@@ -297,25 +393,52 @@ func (v *Validator) SanitizeScriptName(name string) string {
 
 // IsValidFilename validates a filename for security
 func (v *Validator) IsValidFilename(filename string) bool {
+	return v.ValidateFilename(filename) == nil
+}
+
+// ValidateFilename validates a filename for security, returning an error that
+// describes the rule that was violated
+func (v *Validator) ValidateFilename(filename string) *ValidationError {
 	// Check for path traversal attempts
 	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || strings.Contains(filename, "\\") {
-		return false
+		return &ValidationError{
+			Field:   "filename",
+			Message: "Filename must not contain path separators or '..'",
+			Value:   filename,
+		}
 	}
 
 	// Check for dangerous characters
 	dangerousChars := []string{"<", ">", ":", "\"", "|", "?", "*"}
 	for _, char := range dangerousChars {
 		if strings.Contains(filename, char) {
-			return false
+			return &ValidationError{
+				Field:   "filename",
+				Message: fmt.Sprintf("Filename must not contain %q", char),
+				Value:   filename,
+			}
 		}
 	}
 
 	// Check length
 	if len(filename) > 255 {
-		return false
+		return &ValidationError{
+			Field:   "filename",
+			Message: "Filename must be at most 255 characters",
+			Value:   filename,
+		}
+	}
+
+	// Check against the configured allowlist pattern
+	if !v.filenameRegex.MatchString(filename) {
+		return &ValidationError{
+			Field:   "filename",
+			Message: fmt.Sprintf("Filename does not match the allowed pattern %s", v.filenameRegex.String()),
+			Value:   filename,
+		}
 	}
 
-	return true
+	return nil
 }
 
 // SanitizeFilename sanitizes a filename for safe storage
@@ -353,6 +476,11 @@ func (v *Validator) SanitizeFilename(filename string) string {
 		sanitized = "automation"
 	}
 
+	// Never produce a name outside the allowlist; callers fall back to a generated name
+	if !v.filenameRegex.MatchString(sanitized) {
+		return ""
+	}
+
 	return sanitized
 }
 
@@ -367,6 +495,11 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer to http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // validationMiddleware adds validation to handlers
 func validationMiddleware(validator *Validator) func(http.HandlerFunc) http.HandlerFunc {
 	return func(next http.HandlerFunc) http.HandlerFunc {
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// wasmMagic is the header every WebAssembly binary module starts with (\0asm, version 1)
+var wasmMagic = []byte{0x00, 0x61, 0x73, 0x6d, 0x01, 0x00, 0x00, 0x00}
+
+// supportedWASICapabilities lists the WASI capabilities a wasm platform may grant
+var supportedWASICapabilities = map[string]bool{
+	"fd_read":     true,
+	"fd_write":    true,
+	"sock_accept": true,
+}
+
+// wasiErrnoNotCapable is the WASI errno returned by capabilities that were
+// not granted (ENOTCAPABLE)
+const wasiErrnoNotCapable = 76
+
+// defaultWasmTimeout bounds a wasm plugin call when the platform sets no timeout
+const defaultWasmTimeout = 60 * time.Second
+
+// wasmOutputLimit bounds how much a module may write to stdout and stderr
+const wasmOutputLimit = 64 * 1024
+
+// WasmPluginWrapper runs a WebAssembly plugin in a wazero sandbox. The module
+// sees no filesystem, environment or arguments, and the WASI functions of
+// capabilities the platform does not grant fail with ENOTCAPABLE.
+//
+// A plugin exports its linear memory as "memory" and three functions:
+//
+//	alloc(size i32) i32         reserves size bytes and returns their address
+//	info() i64                  returns the plugin's PluginInfo JSON
+//	execute(ptr, len i32) i64   runs the plugin on the params JSON at ptr
+//
+// info and execute return the address of their JSON result in the upper 32
+// bits and its length in the lower 32. Every call runs in a fresh instance
+// of the module, so no state is shared between executions.
+type WasmPluginWrapper struct {
+	modulePath string
+	runtime    wazero.Runtime
+	compiled   wazero.CompiledModule
+	timeout    time.Duration
+	info       PluginInfo
+	closeOnce  sync.Once
+}
+
+// loadWasmPlugin compiles a WebAssembly plugin into a sandboxed runtime
+// limited to the platform's memory, timeout and granted WASI capabilities
+func (pm *PluginManager) loadWasmPlugin(pluginPath string) (interface{}, error) {
+	if err := validateWasmModule(pluginPath); err != nil {
+		return nil, err
+	}
+
+	capabilities, _ := pm.config["wasi_capabilities"].([]string)
+	if err := validateWASICapabilities(capabilities); err != nil {
+		return nil, err
+	}
+
+	code, err := os.ReadFile(pluginPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wasm plugin: %v", err)
+	}
+
+	timeout := defaultWasmTimeout
+	if seconds, ok := pm.config["plugin_timeout"].(int); ok && seconds > 0 {
+		timeout = time.Duration(seconds) * time.Second
+	}
+	maxMemory, _ := pm.config["max_memory"].(int)
+
+	return newWasmPlugin(pluginPath, code, capabilities, maxMemory, timeout)
+}
+
+// newWasmPlugin compiles a module into its own runtime. maxMemory is in MB;
+// 0 leaves the module's own limit.
+func newWasmPlugin(modulePath string, code []byte, capabilities []string, maxMemory int, timeout time.Duration) (*WasmPluginWrapper, error) {
+	ctx := context.Background()
+
+	runtimeConfig := wazero.NewRuntimeConfig().WithCloseOnContextDone(true)
+	if maxMemory > 0 {
+		// A page of wasm memory is 64KiB
+		pages := maxMemory * 16
+		if pages > 65536 {
+			pages = 65536
+		}
+		runtimeConfig = runtimeConfig.WithMemoryLimitPages(uint32(pages))
+	}
+	runtime := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+
+	if err := instantiateWASI(ctx, runtime, capabilities); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to set up WASI for wasm plugin: %v", err)
+	}
+
+	compiled, err := runtime.CompileModule(ctx, code)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile wasm plugin %s: %v", modulePath, err)
+	}
+
+	exports := compiled.ExportedFunctions()
+	for _, name := range []string{"alloc", "info", "execute"} {
+		if _, exists := exports[name]; !exists {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("wasm plugin %s does not export %s", modulePath, name)
+		}
+	}
+	if _, exists := compiled.ExportedMemories()["memory"]; !exists {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("wasm plugin %s does not export memory", modulePath)
+	}
+
+	return &WasmPluginWrapper{
+		modulePath: modulePath,
+		runtime:    runtime,
+		compiled:   compiled,
+		timeout:    timeout,
+	}, nil
+}
+
+// instantiateWASI provides the WASI host functions, replacing those of
+// capabilities that are not granted with ones failing with ENOTCAPABLE
+func instantiateWASI(ctx context.Context, runtime wazero.Runtime, capabilities []string) error {
+	granted := make(map[string]bool)
+	for _, capability := range capabilities {
+		granted[capability] = true
+	}
+
+	builder := runtime.NewHostModuleBuilder(wasi_snapshot_preview1.ModuleName)
+	wasi_snapshot_preview1.NewFunctionExporter().ExportFunctions(builder)
+
+	if !granted["fd_read"] {
+		builder.NewFunctionBuilder().
+			WithFunc(func(ctx context.Context, fd, iovs, iovsLen, resultNread uint32) uint32 {
+				return wasiErrnoNotCapable
+			}).Export("fd_read")
+	}
+	if !granted["fd_write"] {
+		builder.NewFunctionBuilder().
+			WithFunc(func(ctx context.Context, fd, iovs, iovsLen, resultNwritten uint32) uint32 {
+				return wasiErrnoNotCapable
+			}).Export("fd_write")
+	}
+	if !granted["sock_accept"] {
+		builder.NewFunctionBuilder().
+			WithFunc(func(ctx context.Context, fd, flags, resultFd uint32) uint32 {
+				return wasiErrnoNotCapable
+			}).Export("sock_accept")
+	}
+
+	_, err := builder.Instantiate(ctx)
+	return err
+}
+
+// call instantiates the module, runs one of its exported functions and
+// returns the JSON it points to. input, when not nil, is copied into the
+// module's memory and passed as the function's (ptr, len) arguments.
+func (wp *WasmPluginWrapper) call(function string, input []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), wp.timeout)
+	defer cancel()
+
+	var output limitedBuffer
+	output.limit = wasmOutputLimit
+	moduleConfig := wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStdout(&output).
+		WithStderr(&output)
+
+	module, err := wp.runtime.InstantiateModule(ctx, wp.compiled, moduleConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate wasm plugin: %v", err)
+	}
+	defer module.Close(context.Background())
+
+	var params []uint64
+	if input != nil {
+		ptr, err := writeWasmInput(ctx, module, input)
+		if err != nil {
+			return nil, err
+		}
+		params = []uint64{uint64(ptr), uint64(len(input))}
+	}
+
+	results, err := module.ExportedFunction(function).Call(ctx, params...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("wasm plugin %s timed out after %v", function, wp.timeout)
+		}
+		if message := strings.TrimSpace(output.String()); message != "" {
+			return nil, fmt.Errorf("wasm plugin %s failed: %v: %s", function, err, message)
+		}
+		return nil, fmt.Errorf("wasm plugin %s failed: %v", function, err)
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("wasm plugin %s must return one i64", function)
+	}
+
+	ptr, length := uint32(results[0]>>32), uint32(results[0])
+	data, ok := module.Memory().Read(ptr, length)
+	if !ok {
+		return nil, fmt.Errorf("wasm plugin %s returned a result outside its memory", function)
+	}
+	// The view into the module's memory is only valid until it is closed
+	return bytes.Clone(data), nil
+}
+
+// writeWasmInput copies input into memory reserved by the module's alloc
+func writeWasmInput(ctx context.Context, module api.Module, input []byte) (uint32, error) {
+	results, err := module.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+	if err != nil {
+		return 0, fmt.Errorf("wasm plugin alloc failed: %v", err)
+	}
+	if len(results) != 1 {
+		return 0, fmt.Errorf("wasm plugin alloc must return one i32")
+	}
+	ptr := uint32(results[0])
+	if !module.Memory().Write(ptr, input) {
+		return 0, fmt.Errorf("wasm plugin alloc returned memory outside its memory")
+	}
+	return ptr, nil
+}
+
+func (wp *WasmPluginWrapper) GetInfo() PluginInfo {
+	return wp.info
+}
+
+func (wp *WasmPluginWrapper) Initialize(config map[string]interface{}) error {
+	output, err := wp.call("info", nil)
+	if err != nil {
+		return fmt.Errorf("failed to get plugin info: %v", err)
+	}
+
+	if err := json.Unmarshal(output, &wp.info); err != nil {
+		return fmt.Errorf("failed to parse plugin info: %v", err)
+	}
+
+	wp.info.Status = PluginStatusLoaded
+	wp.info.LoadedAt = time.Now()
+
+	return nil
+}
+
+func (wp *WasmPluginWrapper) Execute(params map[string]interface{}) (interface{}, error) {
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal params: %v", err)
+	}
+
+	output, err := wp.call("execute", paramsJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute plugin: %v", err)
+	}
+
+	var result interface{}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse plugin result: %v", err)
+	}
+
+	return result, nil
+}
+
+func (wp *WasmPluginWrapper) Cleanup() error {
+	var err error
+	wp.closeOnce.Do(func() {
+		err = wp.runtime.Close(context.Background())
+	})
+	return err
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (lb *limitedBuffer) Write(p []byte) (int, error) {
+	if room := lb.limit - lb.Len(); room > 0 {
+		if len(p) > room {
+			lb.Buffer.Write(p[:room])
+		} else {
+			lb.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// validateWasmModule checks that the file is a WebAssembly binary module
+func validateWasmModule(pluginPath string) error {
+	file, err := os.Open(pluginPath)
+	if err != nil {
+		return fmt.Errorf("failed to open wasm plugin: %v", err)
+	}
+	defer file.Close()
+
+	header := make([]byte, len(wasmMagic))
+	if _, err := io.ReadFull(file, header); err != nil || !bytes.Equal(header, wasmMagic) {
+		return fmt.Errorf("invalid wasm module: %s", pluginPath)
+	}
+
+	return nil
+}
+
+// validateWASICapabilities rejects capabilities the sandbox does not know how to grant
+func validateWASICapabilities(capabilities []string) error {
+	for _, capability := range capabilities {
+		if !supportedWASICapabilities[capability] {
+			return fmt.Errorf("unsupported WASI capability: %s", capability)
+		}
+	}
+	return nil
+}
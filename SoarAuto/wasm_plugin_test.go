@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Layout of the test plugin's memory, filled by its data segments
+const (
+	testWasmIovec   = 16  // iovec pointing at the stderr message
+	testWasmWritten = 32  // where fd_write stores the bytes written
+	testWasmMessage = 64  // "hello"
+	testWasmDenied  = 128 // result when fd_write fails
+	testWasmInfo    = 256 // PluginInfo JSON
+)
+
+const testWasmDeniedJSON = `{"error":"fd_write denied"}`
+const testWasmInfoJSON = `{"name":"wasm_echo","version":"1.0.0","description":"Echoes its params","type":"automation"}`
+
+func uleb128(value uint64) []byte {
+	var out []byte
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if value != 0 {
+			out = append(out, b|0x80)
+			continue
+		}
+		return append(out, b)
+	}
+}
+
+func sleb128(value int64) []byte {
+	var out []byte
+	for {
+		b := byte(value & 0x7f)
+		value >>= 7
+		if (value == 0 && b&0x40 == 0) || (value == -1 && b&0x40 != 0) {
+			return append(out, b)
+		}
+		out = append(out, b|0x80)
+	}
+}
+
+func wasmSection(id byte, items ...[]byte) []byte {
+	content := uleb128(uint64(len(items)))
+	for _, item := range items {
+		content = append(content, item...)
+	}
+	return append(append([]byte{id}, uleb128(uint64(len(content)))...), content...)
+}
+
+func wasmName(name string) []byte {
+	return append(uleb128(uint64(len(name))), name...)
+}
+
+func packedResult(ptr, length int) []byte {
+	return sleb128(int64(ptr)<<32 | int64(length))
+}
+
+// echoWasmPlugin assembles a plugin whose execute writes "hello" to stderr
+// with fd_write and returns its params unchanged, or testWasmDeniedJSON when
+// fd_write fails. executeBody replaces the body of execute when not nil.
+func echoWasmPlugin(executeBody []byte) []byte {
+	if executeBody == nil {
+		executeBody = []byte{
+			0x41, 2, 0x41, testWasmIovec, 0x41, 1, 0x41, testWasmWritten, // fd_write(2, iovec, 1, written)
+			0x10, 0, // call fd_write
+			0x04, 0x7e, // if (result i64)
+			0x42}
+		executeBody = append(executeBody, packedResult(testWasmDenied, len(testWasmDeniedJSON))...)
+		executeBody = append(executeBody,
+			0x05,                          // else
+			0x20, 0, 0xad, 0x42, 32, 0x86, // i64(ptr) << 32
+			0x20, 1, 0xad, 0x84, // | i64(len)
+			0x0b) // end if
+	}
+	executeBody = append(executeBody, 0x0b)
+
+	infoBody := append(append([]byte{0x42}, packedResult(testWasmInfo, len(testWasmInfoJSON))...), 0x0b)
+	// alloc returns the heap pointer in global 0 and advances it by size
+	allocBody := []byte{0x23, 0, 0x23, 0, 0x20, 0, 0x6a, 0x24, 0, 0x0b}
+
+	code := func(body []byte) []byte {
+		entry := append([]byte{0}, body...) // no locals
+		return append(uleb128(uint64(len(entry))), entry...)
+	}
+	data := func(offset int, content []byte) []byte {
+		segment := append([]byte{0, 0x41}, sleb128(int64(offset))...)
+		segment = append(segment, 0x0b)
+		segment = append(segment, uleb128(uint64(len(content)))...)
+		return append(segment, content...)
+	}
+
+	var module []byte
+	module = append(module, wasmMagic...)
+	module = append(module, wasmSection(1,
+		[]byte{0x60, 1, 0x7f, 1, 0x7f},                   // alloc (i32) -> i32
+		[]byte{0x60, 0, 1, 0x7e},                         // info () -> i64
+		[]byte{0x60, 2, 0x7f, 0x7f, 1, 0x7e},             // execute (i32, i32) -> i64
+		[]byte{0x60, 4, 0x7f, 0x7f, 0x7f, 0x7f, 1, 0x7f}, // fd_write
+	)...)
+	module = append(module, wasmSection(2,
+		append(append(wasmName("wasi_snapshot_preview1"), wasmName("fd_write")...), 0, 3))...)
+	module = append(module, wasmSection(3, []byte{0}, []byte{1}, []byte{2})...)
+	module = append(module, wasmSection(5, []byte{0, 1})...)
+	module = append(module, wasmSection(6, append(append([]byte{0x7f, 1, 0x41}, sleb128(1024)...), 0x0b))...)
+	module = append(module, wasmSection(7,
+		append(wasmName("memory"), 2, 0),
+		append(wasmName("alloc"), 0, 1),
+		append(wasmName("info"), 0, 2),
+		append(wasmName("execute"), 0, 3),
+	)...)
+	module = append(module, wasmSection(10, code(allocBody), code(infoBody), code(executeBody))...)
+	module = append(module, wasmSection(11,
+		data(testWasmIovec, []byte{testWasmMessage, 0, 0, 0, 5, 0, 0, 0}),
+		data(testWasmMessage, []byte("hello")),
+		data(testWasmDenied, []byte(testWasmDeniedJSON)),
+		data(testWasmInfo, []byte(testWasmInfoJSON)),
+	)...)
+	return module
+}
+
+func writeWasmPlugin(t *testing.T, module []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "plugin.wasm")
+	if err := os.WriteFile(path, module, 0644); err != nil {
+		t.Fatalf("write wasm plugin: %v", err)
+	}
+	return path
+}
+
+func loadTestWasmPlugin(t *testing.T, module []byte, config map[string]interface{}) *WasmPluginWrapper {
+	t.Helper()
+	pm := &PluginManager{config: config}
+	instance, err := pm.loadWasmPlugin(writeWasmPlugin(t, module))
+	if err != nil {
+		t.Fatalf("load wasm plugin: %v", err)
+	}
+	plugin := instance.(*WasmPluginWrapper)
+	t.Cleanup(func() { plugin.Cleanup() })
+	if err := plugin.Initialize(nil); err != nil {
+		t.Fatalf("initialize wasm plugin: %v", err)
+	}
+	return plugin
+}
+
+func TestWasmPluginExecutesWithJSONParams(t *testing.T) {
+	plugin := loadTestWasmPlugin(t, echoWasmPlugin(nil), map[string]interface{}{
+		"wasi_capabilities": []string{"fd_write"},
+		"max_memory":        16,
+		"plugin_timeout":    5,
+	})
+
+	if info := plugin.GetInfo(); info.Name != "wasm_echo" || info.Version != "1.0.0" || info.Status != PluginStatusLoaded {
+		t.Errorf("info = %+v", info)
+	}
+
+	params := map[string]interface{}{"indicator": "8.8.8.8", "nested": map[string]interface{}{"n": float64(3)}}
+	result, err := plugin.Execute(params)
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !reflect.DeepEqual(result, params) {
+		t.Errorf("result = %v, want the params echoed %v", result, params)
+	}
+
+	// Each call gets a fresh instance, so a second call sees the same memory layout
+	if _, err := plugin.Execute(params); err != nil {
+		t.Errorf("second execute: %v", err)
+	}
+}
+
+func TestWasmPluginDeniesUngrantedCapabilities(t *testing.T) {
+	plugin := loadTestWasmPlugin(t, echoWasmPlugin(nil), map[string]interface{}{
+		"wasi_capabilities": []string{"fd_read"},
+	})
+
+	result, err := plugin.Execute(map[string]interface{}{"x": 1})
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if !reflect.DeepEqual(result, map[string]interface{}{"error": "fd_write denied"}) {
+		t.Errorf("result = %v, want fd_write to be denied", result)
+	}
+}
+
+func TestWasmPluginTimesOut(t *testing.T) {
+	loop := []byte{0x03, 0x40, 0x0c, 0, 0x0b, 0x42, 0} // loop br 0 end; i64.const 0
+	plugin := loadTestWasmPlugin(t, echoWasmPlugin(loop), map[string]interface{}{})
+	plugin.timeout = 100 * time.Millisecond
+
+	started := time.Now()
+	_, err := plugin.Execute(map[string]interface{}{})
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("execute error = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("timed out execution took %v", elapsed)
+	}
+}
+
+func TestLoadWasmPluginRejectsInvalidModules(t *testing.T) {
+	pm := &PluginManager{config: map[string]interface{}{}}
+
+	if _, err := pm.loadWasmPlugin(writeWasmPlugin(t, []byte("not wasm"))); err == nil {
+		t.Error("a file without the wasm header was loaded")
+	}
+
+	// A valid module without the plugin exports
+	if _, err := pm.loadWasmPlugin(writeWasmPlugin(t, wasmMagic)); err == nil || !strings.Contains(err.Error(), "does not export") {
+		t.Errorf("module without exports error = %v", err)
+	}
+
+	pm.config["wasi_capabilities"] = []string{"path_open"}
+	if _, err := pm.loadWasmPlugin(writeWasmPlugin(t, echoWasmPlugin(nil))); err == nil {
+		t.Error("an unsupported WASI capability was accepted")
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buffer := limitedBuffer{limit: 4}
+	buffer.Write([]byte("abc"))
+	if n, err := buffer.Write([]byte("defg")); n != 4 || err != nil {
+		t.Errorf("write = %d, %v; want all bytes reported written", n, err)
+	}
+	if !bytes.Equal(buffer.Bytes(), []byte("abcd")) {
+		t.Errorf("buffer = %q, want abcd", buffer.Bytes())
+	}
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultWebhookDeliveryHistory is the number of deliveries kept per webhook
+// when webhooks.delivery_history is not set
+const defaultWebhookDeliveryHistory = 100
+
+// maxWebhookResponseBody bounds the response body recorded for an attempt
+const maxWebhookResponseBody = 4096
+
+// Statuses of a webhook delivery
+const (
+	webhookDeliveryPending   = "pending"   // Being sent or waiting to retry
+	webhookDeliveryDelivered = "delivered" // Acknowledged with a 2xx response
+	webhookDeliveryFailed    = "failed"    // Every attempt failed
+	webhookDeliveryAbandoned = "abandoned" // Dropped when the delivery queue was flushed
+)
+
+// Errors returned by ResendDelivery
+var (
+	errWebhookDeliveryNotFound = errors.New("webhook delivery not found")
+	errWebhookNotConfigured    = errors.New("webhook is no longer configured or is disabled")
+)
+
+// WebhookDeliveryRecord is one delivery of a webhook notification, kept in
+// the delivery history whether or not it succeeded
+type WebhookDeliveryRecord struct {
+	ID          string                   `json:"id"`
+	WebhookURL  string                   `json:"webhook_url"`
+	Event       string                   `json:"event"`
+	JobID       string                   `json:"job_id,omitempty"`
+	Status      string                   `json:"status"` // pending, delivered, failed or abandoned
+	Request     WebhookDeliveryRequest   `json:"request"`
+	Attempts    []WebhookDeliveryAttempt `json:"attempts"`
+	ResentFrom  string                   `json:"resent_from,omitempty"` // Delivery this one re-sent
+	CreatedAt   time.Time                `json:"created_at"`
+	CompletedAt *time.Time               `json:"completed_at,omitempty"`
+}
+
+// WebhookDeliveryRequest is the request a delivery sends. The values of
+// custom headers are masked, as they usually carry credentials; the body is
+// the payload as sent, with contexts and results already redacted.
+type WebhookDeliveryRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// WebhookDeliveryAttempt is one attempt to send a delivery
+type WebhookDeliveryAttempt struct {
+	Attempt      int       `json:"attempt"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"` // Truncated to 4 KiB
+	Error        string    `json:"error,omitempty"`
+	DurationMs   int64     `json:"duration_ms"`
+	SentAt       time.Time `json:"sent_at"`
+}
+
+// WebhookDeliveriesResponse is the response for GET /webhooks/deliveries
+type WebhookDeliveriesResponse struct {
+	Success    bool                    `json:"success"`
+	Deliveries []WebhookDeliveryRecord `json:"deliveries"`
+	Count      int                     `json:"count"`
+	Timestamp  string                  `json:"timestamp"`
+}
+
+// WebhookResendResponse is the response for POST /webhooks/deliveries/{id}/resend
+type WebhookResendResponse struct {
+	Success   bool                  `json:"success"`
+	Message   string                `json:"message"`
+	Delivery  WebhookDeliveryRecord `json:"delivery"`
+	Timestamp string                `json:"timestamp"`
+}
+
+// webhookDeliveryFilter selects deliveries from the history; empty fields
+// match every delivery
+type webhookDeliveryFilter struct {
+	event      string
+	status     string
+	webhookURL string
+	jobID      string
+}
+
+func (f webhookDeliveryFilter) matches(record *WebhookDeliveryRecord) bool {
+	return (f.event == "" || record.Event == f.event) &&
+		(f.status == "" || record.Status == f.status) &&
+		(f.webhookURL == "" || record.WebhookURL == f.webhookURL) &&
+		(f.jobID == "" || record.JobID == f.jobID)
+}
+
+// SetDeliveryHistory sets how many deliveries are kept per webhook; values
+// below 1 keep the default
+func (wm *WebhookManager) SetDeliveryHistory(size int) {
+	if size < 1 {
+		size = defaultWebhookDeliveryHistory
+	}
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+	wm.historySize = size
+}
+
+// newWebhookDeliveryRecord starts the history record of a delivery
+func newWebhookDeliveryRecord(delivery *webhookDelivery, jobID, resentFrom string) *WebhookDeliveryRecord {
+	headers := map[string]string{
+		"Content-Type": "application/json",
+		"User-Agent":   "SecAuto-Webhook/1.0",
+	}
+	for key := range delivery.config.Headers {
+		headers[key] = redactionMask
+	}
+
+	return &WebhookDeliveryRecord{
+		ID:         fmt.Sprintf("delivery_%d", delivery.id),
+		WebhookURL: outputRedactor.Redact(delivery.config.URL),
+		Event:      delivery.event,
+		JobID:      jobID,
+		Status:     webhookDeliveryPending,
+		Request: WebhookDeliveryRequest{
+			Method:  http.MethodPost,
+			URL:     outputRedactor.Redact(delivery.config.URL),
+			Headers: headers,
+			Body:    json.RawMessage(delivery.payload),
+		},
+		Attempts:   []WebhookDeliveryAttempt{},
+		ResentFrom: resentFrom,
+		CreatedAt:  delivery.startedAt,
+	}
+}
+
+// addDeliveryRecord adds a record to the history of the webhook at url,
+// dropping the oldest records beyond the history size. Callers hold the lock.
+func (wm *WebhookManager) addDeliveryRecord(url string, record *WebhookDeliveryRecord) {
+	history := append(wm.history[url], record)
+	if excess := len(history) - wm.historySize; excess > 0 {
+		for _, dropped := range history[:excess] {
+			delete(wm.records, dropped.ID)
+		}
+		history = append([]*WebhookDeliveryRecord(nil), history[excess:]...)
+	}
+	wm.history[url] = history
+	wm.records[record.ID] = record
+}
+
+// recordAttempt adds an attempt to a delivery's history record, reading at
+// most maxWebhookResponseBody bytes of the response body
+func (wm *WebhookManager) recordAttempt(delivery *webhookDelivery, attempt int, sentAt time.Time, resp *http.Response, err error) {
+	entry := WebhookDeliveryAttempt{
+		Attempt:    attempt,
+		DurationMs: time.Since(sentAt).Milliseconds(),
+		SentAt:     sentAt,
+	}
+	if err != nil {
+		entry.Error = outputRedactor.Redact(err.Error())
+	}
+	if resp != nil {
+		entry.StatusCode = resp.StatusCode
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseBody))
+		entry.ResponseBody = outputRedactor.Redact(string(body))
+	}
+
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+	delivery.record.Attempts = append(delivery.record.Attempts, entry)
+}
+
+// ListDeliveries returns up to limit of the newest deliveries matching
+// filter, newest first
+func (wm *WebhookManager) ListDeliveries(filter webhookDeliveryFilter, limit int) []WebhookDeliveryRecord {
+	wm.mutex.RLock()
+	defer wm.mutex.RUnlock()
+
+	var matched []*WebhookDeliveryRecord
+	for _, record := range wm.records {
+		if filter.matches(record) {
+			matched = append(matched, record)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if !matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CreatedAt.After(matched[j].CreatedAt)
+		}
+		return matched[i].ID > matched[j].ID
+	})
+	if len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	deliveries := make([]WebhookDeliveryRecord, len(matched))
+	for i, record := range matched {
+		deliveries[i] = copyDeliveryRecord(record)
+	}
+	return deliveries
+}
+
+// ResendDelivery sends the request of a recorded delivery again, with the
+// current headers and retry settings of its webhook, and returns the new
+// delivery. The delivery runs in the background.
+func (wm *WebhookManager) ResendDelivery(id string) (WebhookDeliveryRecord, error) {
+	wm.mutex.RLock()
+	original, exists := wm.records[id]
+	var config WebhookConfig
+	configured := false
+	if exists {
+		for _, webhook := range wm.webhooks {
+			if webhook.Enabled && outputRedactor.Redact(webhook.URL) == original.WebhookURL {
+				config = webhook
+				configured = true
+				break
+			}
+		}
+	}
+	var event, jobID string
+	var payload []byte
+	if exists {
+		event, jobID, payload = original.Event, original.JobID, original.Request.Body
+	}
+	wm.mutex.RUnlock()
+
+	if !exists {
+		return WebhookDeliveryRecord{}, errWebhookDeliveryNotFound
+	}
+	if !configured {
+		return WebhookDeliveryRecord{}, errWebhookNotConfigured
+	}
+
+	delivery := wm.startDelivery(config, event, jobID, payload, id)
+	go wm.deliver(delivery)
+
+	logger.Info("Webhook delivery re-sent", map[string]interface{}{
+		"component":   "webhook",
+		"webhook_url": original.WebhookURL,
+		"event":       event,
+		"resent_from": id,
+		"delivery_id": delivery.record.ID,
+	})
+
+	wm.mutex.RLock()
+	defer wm.mutex.RUnlock()
+	return copyDeliveryRecord(delivery.record), nil
+}
+
+// copyDeliveryRecord copies a record so it can be read without the lock.
+// Callers hold the lock.
+func copyDeliveryRecord(record *WebhookDeliveryRecord) WebhookDeliveryRecord {
+	copied := *record
+	copied.Attempts = append([]WebhookDeliveryAttempt{}, record.Attempts...)
+	return copied
+}
+
+// webhookDeliveriesHandler handles GET /webhooks/deliveries?event=...&status=...&webhook_url=...&job_id=...&limit=50,
+// which lists the newest recorded deliveries, and
+// POST /webhooks/deliveries/{id}/resend, which sends a delivery again
+func (s *SecAutoServer) webhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/webhooks/deliveries"), "/")
+	if path == "" {
+		s.listWebhookDeliveries(w, r)
+		return
+	}
+
+	id, action, found := strings.Cut(path, "/")
+	if !found || action != "resend" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	delivery, err := s.webhookManager.ResendDelivery(id)
+	switch err {
+	case nil:
+	case errWebhookDeliveryNotFound:
+		http.Error(w, "Webhook delivery not found: "+id, http.StatusNotFound)
+		return
+	default:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(WebhookResendResponse{
+		Success:   true,
+		Message:   "Webhook delivery re-sent",
+		Delivery:  delivery,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+}
+
+// listWebhookDeliveries handles GET /webhooks/deliveries
+func (s *SecAutoServer) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := webhookDeliveryFilter{
+		event:      query.Get("event"),
+		status:     query.Get("status"),
+		webhookURL: query.Get("webhook_url"),
+		jobID:      query.Get("job_id"),
+	}
+	switch filter.status {
+	case "", webhookDeliveryPending, webhookDeliveryDelivered, webhookDeliveryFailed, webhookDeliveryAbandoned:
+	default:
+		http.Error(w, "status must be pending, delivered, failed or abandoned", http.StatusBadRequest)
+		return
+	}
+
+	limit := 50
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries := s.webhookManager.ListDeliveries(filter, limit)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(WebhookDeliveriesResponse{
+		Success:    true,
+		Deliveries: deliveries,
+		Count:      len(deliveries),
+		Timestamp:  time.Now().UTC().Format(time.RFC3339),
+	})
+}
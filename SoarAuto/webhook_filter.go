@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// webhookFilterForbiddenOps are the operations a webhook filter may not use:
+// filters run on every event and must not execute code or change anything
+var webhookFilterForbiddenOps = map[string]bool{
+	"run": true, "play": true, "play_async": true, "plugin": true, "batch": true, "foreach": true,
+	"macro": true, "try": true, "conditional_set": true, "vars": true,
+	"abort": true, "assert": true, "splunk_log": true, "elasticsearch_index": true,
+	"checkpoint": true, "restore": true, "throttle": true, "metric": true, "finding": true,
+}
+
+// SetRuleEngine sets the engine webhook filters are evaluated with
+func (wm *WebhookManager) SetRuleEngine(engine *RuleEngine) {
+	wm.engine = engine
+}
+
+// webhookFilterData returns an event as the data a filter is evaluated
+// against, with the same field names as the webhook payload, e.g.
+// {"var": "context.severity"} or {"var": "status"}
+func webhookFilterData(event WebhookEvent) (map[string]interface{}, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(payload, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// findWebhookFilterOp returns the first forbidden operation in a filter
+func findWebhookFilterOp(expr interface{}) string {
+	switch v := expr.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if webhookFilterForbiddenOps[key] {
+				return key
+			}
+			if op := findWebhookFilterOp(value); op != "" {
+				return op
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if op := findWebhookFilterOp(item); op != "" {
+				return op
+			}
+		}
+	}
+	return ""
+}
+
+// ValidateFilter checks a webhook filter expression: it must be an object or
+// array, may only use side-effect free operations, and must evaluate against
+// a sample event without referring to an unknown operation
+func (wm *WebhookManager) ValidateFilter(filter interface{}) error {
+	if filter == nil {
+		return nil
+	}
+	switch filter.(type) {
+	case map[string]interface{}, []interface{}:
+	default:
+		return fmt.Errorf("filter must be an expression object")
+	}
+	if op := findWebhookFilterOp(filter); op != "" {
+		return fmt.Errorf("filter may not use the %s operation", op)
+	}
+	if wm.engine == nil {
+		return nil
+	}
+
+	data, err := webhookFilterData(WebhookEvent{Event: "job_completed", Status: "completed"})
+	if err != nil {
+		return err
+	}
+	if _, err := wm.engine.evaluate(filter, data); err != nil && strings.Contains(err.Error(), "unknown operation") {
+		return fmt.Errorf("invalid filter: %v", err)
+	}
+	return nil
+}
+
+// matchesFilter reports whether an event passes a webhook's filter. A filter
+// that fails to evaluate suppresses the webhook rather than letting through
+// events the subscriber asked not to receive.
+func (wm *WebhookManager) matchesFilter(webhook WebhookConfig, event WebhookEvent) bool {
+	if webhook.Filter == nil {
+		return true
+	}
+	if wm.engine == nil {
+		return true
+	}
+
+	data, err := webhookFilterData(event)
+	if err == nil {
+		var result interface{}
+		result, err = wm.engine.evaluate(webhook.Filter, data)
+		if err == nil {
+			return wm.engine.isTruthy(result)
+		}
+	}
+
+	logger.Warning("Webhook filter failed to evaluate, not sending", map[string]interface{}{
+		"component":   "webhook",
+		"webhook_url": webhook.URL,
+		"event":       event.Event,
+		"job_id":      event.JobID,
+		"error":       err.Error(),
+	})
+	return false
+}
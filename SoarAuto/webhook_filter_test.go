@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// newWebhookFilterTestManager returns a webhook manager that evaluates
+// filters with a default engine
+func newWebhookFilterTestManager() *WebhookManager {
+	manager := NewWebhookManager()
+	manager.SetRuleEngine(NewRuleEngine(&Config{}))
+	return manager
+}
+
+// parseWebhookFilter decodes a filter the way webhook configs are decoded
+func parseWebhookFilter(t *testing.T, source string) interface{} {
+	t.Helper()
+	var filter interface{}
+	if err := json.Unmarshal([]byte(source), &filter); err != nil {
+		t.Fatalf("parse filter: %v", err)
+	}
+	return filter
+}
+
+func TestWebhookFilterMatchesEvents(t *testing.T) {
+	manager := newWebhookFilterTestManager()
+	highSeverity := parseWebhookFilter(t, `{"and": [
+		{"eq": [{"var": "status"}, "completed"]},
+		{"gte": [{"var": "context.severity"}, 7]}
+	]}`)
+
+	tests := []struct {
+		name   string
+		filter interface{}
+		event  WebhookEvent
+		want   bool
+	}{
+		{"no filter", nil, WebhookEvent{Event: "job_completed"}, true},
+		{"matching event", highSeverity, WebhookEvent{Event: "job_completed", Status: "completed", Context: map[string]interface{}{"severity": 9}}, true},
+		{"at the bound", highSeverity, WebhookEvent{Event: "job_completed", Status: "completed", Context: map[string]interface{}{"severity": 7}}, true},
+		{"below the bound", highSeverity, WebhookEvent{Event: "job_completed", Status: "completed", Context: map[string]interface{}{"severity": 3}}, false},
+		{"other status", highSeverity, WebhookEvent{Event: "job_failed", Status: "failed", Context: map[string]interface{}{"severity": 9}}, false},
+		{"field from a nested array", parseWebhookFilter(t, `{"in": ["phishing", {"var": "context.tags"}]}`),
+			WebhookEvent{Event: "job_completed", Context: map[string]interface{}{"tags": []interface{}{"phishing", "vip"}}}, true},
+		// A filter that fails to evaluate suppresses the webhook
+		{"evaluation error", parseWebhookFilter(t, `{"between": [{"var": "context.severity"}, 1, 10]}`),
+			WebhookEvent{Event: "job_completed"}, false},
+	}
+
+	for _, test := range tests {
+		webhook := WebhookConfig{URL: "https://hooks.example.com/soc", Events: []string{test.event.Event}, Enabled: true, Filter: test.filter}
+		if got := manager.matchesFilter(webhook, test.event); got != test.want {
+			t.Errorf("%s: matchesFilter = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestValidateWebhookFilter(t *testing.T) {
+	manager := newWebhookFilterTestManager()
+
+	valid := []string{
+		`{"eq": [{"var": "event"}, "job_failed"]}`,
+		`{"or": [{"in": [{"var": "context.host"}, ["web-1", "web-2"]]}, {"gt": [{"var": "duration_seconds"}, 60]}]}`,
+	}
+	for _, source := range valid {
+		if err := manager.ValidateFilter(parseWebhookFilter(t, source)); err != nil {
+			t.Errorf("%s: %v", source, err)
+		}
+	}
+	if err := manager.ValidateFilter(nil); err != nil {
+		t.Errorf("no filter: %v", err)
+	}
+
+	invalid := []struct {
+		source string
+		want   string
+	}{
+		{`"job_failed"`, "must be an expression object"},
+		{`true`, "must be an expression object"},
+		{`{"run": "isolate_host"}`, "may not use the run operation"},
+		{`{"and": [{"eq": [1, 1]}, {"if": {"conditions": [true], "true": [{"play": "contain"}]}}]}`, "may not use the play operation"},
+		{`{"conditional_set": {"key": "seen", "value": true}}`, "may not use the conditional_set operation"},
+		{`{"no_such_operation": [1, 2]}`, "invalid filter"},
+	}
+	for _, test := range invalid {
+		err := manager.ValidateFilter(parseWebhookFilter(t, test.source))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%s: error = %v, want one containing %q", test.source, err, test.want)
+		}
+	}
+}
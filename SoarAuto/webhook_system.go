@@ -13,12 +13,15 @@ import (
 // WebhookConfig represents webhook configuration
 type WebhookConfig struct {
 	URL        string            `json:"url"`
-	Events     []string          `json:"events"` // "job_started", "job_completed", "job_failed", "job_cancelled"
+	Events     []string          `json:"events"` // "job_started", "job_completed", "job_failed", "job_cancelled", "job_aborted", "plugin_reloaded", "library_problems_found"
 	Headers    map[string]string `json:"headers,omitempty"`
 	Timeout    int               `json:"timeout_seconds,omitempty"`
 	RetryCount int               `json:"retry_count,omitempty"`
 	RetryDelay int               `json:"retry_delay_seconds,omitempty"`
 	Enabled    bool              `json:"enabled"`
+	// Filter is an optional rules engine expression evaluated against the
+	// event payload; the webhook is only sent when it is truthy
+	Filter interface{} `json:"filter,omitempty"`
 }
 
 // WebhookEvent represents a webhook event
@@ -31,20 +34,61 @@ type WebhookEvent struct {
 	Context   map[string]interface{} `json:"context,omitempty"`
 	Results   []interface{}          `json:"results,omitempty"`
 	Error     string                 `json:"error,omitempty"`
+	Reason    string                 `json:"reason,omitempty"`
 	Duration  float64                `json:"duration_seconds,omitempty"`
+	Findings  []Finding              `json:"findings,omitempty"`
+
+	// Cancellation events
+	CancelledBy string `json:"cancelled_by,omitempty"`
+
+	// Plugin events
+	Plugin          string   `json:"plugin,omitempty"`
+	PluginVersion   string   `json:"plugin_version,omitempty"`
+	PreviousVersion string   `json:"previous_version,omitempty"`
+	AffectedJobs    []string `json:"affected_jobs,omitempty"`
+
+	// Library validation events
+	Problems []LibraryProblem `json:"problems,omitempty"`
 }
 
 // WebhookManager manages webhook notifications
 type WebhookManager struct {
 	webhooks []WebhookConfig
 	client   *http.Client
+	engine   *RuleEngine // Evaluates webhook filters
 	mutex    sync.RWMutex
+
+	// Deliveries being sent or waiting to retry, by delivery number
+	deliveries    map[uint64]*webhookDelivery
+	deliveryCount uint64
+
+	// Recent deliveries of each webhook, oldest first, and every kept
+	// delivery by ID
+	history     map[string][]*WebhookDeliveryRecord
+	records     map[string]*WebhookDeliveryRecord
+	historySize int
+}
+
+// webhookDelivery is one webhook notification still being delivered
+type webhookDelivery struct {
+	id        uint64
+	config    WebhookConfig
+	event     string
+	payload   []byte
+	record    *WebhookDeliveryRecord
+	startedAt time.Time
+	ctx       context.Context
+	cancel    context.CancelFunc
 }
 
 // NewWebhookManager creates a new webhook manager
 func NewWebhookManager() *WebhookManager {
 	return &WebhookManager{
-		webhooks: make([]WebhookConfig, 0),
+		webhooks:    make([]WebhookConfig, 0),
+		deliveries:  make(map[uint64]*webhookDelivery),
+		history:     make(map[string][]*WebhookDeliveryRecord),
+		records:     make(map[string]*WebhookDeliveryRecord),
+		historySize: defaultWebhookDeliveryHistory,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 			Transport: &http.Transport{
@@ -63,6 +107,9 @@ func (wm *WebhookManager) AddWebhook(config WebhookConfig) {
 
 // SendWebhook sends a webhook notification
 func (wm *WebhookManager) SendWebhook(event WebhookEvent) {
+	// Everything webhooks are told about also appears in the activity feed
+	eventBus.Publish(activityEventFromWebhook(event))
+
 	wm.mutex.RLock()
 	webhooks := make([]WebhookConfig, len(wm.webhooks))
 	copy(webhooks, wm.webhooks)
@@ -82,7 +129,7 @@ func (wm *WebhookManager) SendWebhook(event WebhookEvent) {
 			}
 		}
 
-		if !interested {
+		if !interested || !wm.matchesFilter(webhook, event) {
 			continue
 		}
 
@@ -91,8 +138,104 @@ func (wm *WebhookManager) SendWebhook(event WebhookEvent) {
 	}
 }
 
+// startDelivery records a delivery of payload in the delivery history and
+// tracks it until finishDelivery is called. Its context is cancelled when the
+// delivery queue is flushed.
+func (wm *WebhookManager) startDelivery(config WebhookConfig, event, jobID string, payload []byte, resentFrom string) *webhookDelivery {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	wm.deliveryCount++
+	delivery := &webhookDelivery{
+		id:        wm.deliveryCount,
+		config:    config,
+		event:     event,
+		payload:   payload,
+		startedAt: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	delivery.record = newWebhookDeliveryRecord(delivery, jobID, resentFrom)
+	wm.deliveries[delivery.id] = delivery
+	wm.addDeliveryRecord(config.URL, delivery.record)
+	return delivery
+}
+
+// finishDelivery stops tracking a delivery and records how it ended
+func (wm *WebhookManager) finishDelivery(delivery *webhookDelivery, status string) {
+	wm.mutex.Lock()
+	delete(wm.deliveries, delivery.id)
+	// A flushed delivery has already been recorded as abandoned
+	if delivery.record.CompletedAt == nil {
+		now := time.Now()
+		delivery.record.Status = status
+		delivery.record.CompletedAt = &now
+	}
+	wm.mutex.Unlock()
+	delivery.cancel()
+}
+
+// PendingDeliveries returns how many notifications are being delivered or
+// waiting to retry, and when the oldest of them was sent (zero when none)
+func (wm *WebhookManager) PendingDeliveries() (int, time.Time) {
+	wm.mutex.RLock()
+	defer wm.mutex.RUnlock()
+
+	var oldest time.Time
+	for _, delivery := range wm.deliveries {
+		if oldest.IsZero() || delivery.startedAt.Before(oldest) {
+			oldest = delivery.startedAt
+		}
+	}
+	return len(wm.deliveries), oldest
+}
+
+// FlushDeliveries abandons every notification still being delivered, so a
+// backlog of retries against an unreachable endpoint stops. It returns how
+// many were abandoned.
+func (wm *WebhookManager) FlushDeliveries() int {
+	wm.mutex.Lock()
+	defer wm.mutex.Unlock()
+
+	flushed := len(wm.deliveries)
+	now := time.Now()
+	for id, delivery := range wm.deliveries {
+		delivery.cancel()
+		delivery.record.Status = webhookDeliveryAbandoned
+		delivery.record.CompletedAt = &now
+		delete(wm.deliveries, id)
+	}
+	return flushed
+}
+
+// waitForRetry waits out the delay before the next attempt. It reports false
+// when the delivery was flushed meanwhile.
+func waitForRetry(delivery context.Context, config WebhookConfig, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-delivery.Done():
+		logger.Warning("Webhook delivery abandoned, delivery queue was flushed", map[string]interface{}{
+			"component":   "webhook",
+			"webhook_url": config.URL,
+		})
+		return false
+	}
+}
+
 // sendWebhookWithRetry sends a webhook with retry logic
 func (wm *WebhookManager) sendWebhookWithRetry(config WebhookConfig, event WebhookEvent) {
+	event.Context = outputRedactor.RedactMap(event.Context)
+	if event.Results != nil {
+		event.Results = outputRedactor.RedactValue(event.Results).([]interface{})
+	}
+	event.Error = outputRedactor.Redact(event.Error)
+	event.Findings = outputRedactor.RedactFindings(event.Findings)
 	payload, err := json.Marshal(event)
 	if err != nil {
 		logger.Error("Failed to marshal webhook payload", map[string]interface{}{
@@ -103,32 +246,26 @@ func (wm *WebhookManager) sendWebhookWithRetry(config WebhookConfig, event Webho
 		return
 	}
 
+	wm.deliver(wm.startDelivery(config, event.Event, event.JobID, payload, ""))
+}
+
+// deliver posts a delivery's payload to its webhook, retrying as configured,
+// and records each attempt in the delivery history
+func (wm *WebhookManager) deliver(delivery *webhookDelivery) {
+	config := delivery.config
+	status := webhookDeliveryFailed
+	defer func() {
+		wm.finishDelivery(delivery, status)
+	}()
+
 	timeout := time.Duration(config.Timeout) * time.Second
 	if timeout == 0 {
 		timeout = 30 * time.Second
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(delivery.ctx, timeout)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", config.URL, bytes.NewBuffer(payload))
-	if err != nil {
-		logger.Error("Failed to create webhook request", map[string]interface{}{
-			"component":   "webhook",
-			"webhook_url": config.URL,
-			"error":       err.Error(),
-		})
-		return
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("User-Agent", "SecAuto-Webhook/1.0")
-
-	// Add custom headers
-	for key, value := range config.Headers {
-		req.Header.Set(key, value)
-	}
-
 	retryCount := config.RetryCount
 	if retryCount == 0 {
 		retryCount = 3
@@ -140,7 +277,22 @@ func (wm *WebhookManager) sendWebhookWithRetry(config WebhookConfig, event Webho
 	}
 
 	for attempt := 0; attempt <= retryCount; attempt++ {
+		// A request body can only be read once, so each attempt gets its own request
+		req, err := http.NewRequestWithContext(ctx, "POST", config.URL, bytes.NewReader(delivery.payload))
+		if err != nil {
+			logger.Error("Failed to create webhook request", map[string]interface{}{
+				"component":   "webhook",
+				"webhook_url": config.URL,
+				"error":       err.Error(),
+			})
+			wm.recordAttempt(delivery, attempt+1, time.Now(), nil, err)
+			return
+		}
+		setWebhookHeaders(req, config)
+
+		sentAt := time.Now()
 		resp, err := wm.client.Do(req)
+		wm.recordAttempt(delivery, attempt+1, sentAt, resp, err)
 		if err != nil {
 			logger.Error("Webhook attempt failed", map[string]interface{}{
 				"component":   "webhook",
@@ -150,7 +302,10 @@ func (wm *WebhookManager) sendWebhookWithRetry(config WebhookConfig, event Webho
 				"error":       err.Error(),
 			})
 			if attempt < retryCount {
-				time.Sleep(retryDelay)
+				if !waitForRetry(delivery.ctx, config, retryDelay) {
+					status = webhookDeliveryAbandoned
+					return
+				}
 				continue
 			}
 			return
@@ -162,10 +317,11 @@ func (wm *WebhookManager) sendWebhookWithRetry(config WebhookConfig, event Webho
 			logger.Info("Webhook sent successfully", map[string]interface{}{
 				"component":   "webhook",
 				"webhook_url": config.URL,
-				"event":       event.Event,
-				"job_id":      event.JobID,
+				"event":       delivery.event,
+				"job_id":      delivery.record.JobID,
 				"status_code": resp.StatusCode,
 			})
+			status = webhookDeliveryDelivered
 			return
 		}
 
@@ -176,8 +332,9 @@ func (wm *WebhookManager) sendWebhookWithRetry(config WebhookConfig, event Webho
 			"retry_count": retryCount,
 			"status_code": resp.StatusCode,
 		})
-		if attempt < retryCount {
-			time.Sleep(retryDelay)
+		if attempt < retryCount && !waitForRetry(delivery.ctx, config, retryDelay) {
+			status = webhookDeliveryAbandoned
+			return
 		}
 	}
 
@@ -187,3 +344,15 @@ func (wm *WebhookManager) sendWebhookWithRetry(config WebhookConfig, event Webho
 		"retry_count": retryCount,
 	})
 }
+
+// setWebhookHeaders sets the content type, user agent and custom headers of
+// a webhook request
+func setWebhookHeaders(req *http.Request, config WebhookConfig) {
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SecAuto-Webhook/1.0")
+
+	// Add custom headers
+	for key, value := range config.Headers {
+		req.Header.Set(key, value)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// workDirEnvVar gives automations and plugins the path of the working
+// directory they run in, which they may write temporary files to
+const workDirEnvVar = "SECAUTO_WORK_DIR"
+
+// executionWorkDirPrefix prefixes the per-execution working directories
+const executionWorkDirPrefix = "secauto_run_"
+
+// newExecutionWorkDir creates a private working directory for one automation
+// or plugin run under baseDir (the system temp dir when empty). The returned
+// function removes it with everything the run left there; callers defer it
+// so the directory goes once the process has exited, however the run ended.
+func newExecutionWorkDir(baseDir string) (string, func(), error) {
+	if baseDir != "" {
+		if err := os.MkdirAll(baseDir, 0755); err != nil {
+			return "", nil, err
+		}
+	}
+	dir, err := os.MkdirTemp(baseDir, executionWorkDirPrefix)
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warning("Failed to remove execution working directory", map[string]interface{}{
+				"component": "work_dir",
+				"path":      dir,
+				"error":     err.Error(),
+			})
+		}
+	}
+	return dir, cleanup, nil
+}
+
+// withWorkDir returns a copy of env that runs a process in dir
+func withWorkDir(env map[string]string, dir string) map[string]string {
+	workEnv := make(map[string]string, len(env)+1)
+	for key, value := range env {
+		workEnv[key] = value
+	}
+	workEnv[workDirEnvVar] = dir
+	return workEnv
+}
+
+// SweepExecutionWorkDirs removes working directories older than maxAge from
+// baseDir (the system temp dir when empty), such as those of runs that were
+// in progress when the server stopped
+func SweepExecutionWorkDirs(baseDir string, maxAge time.Duration) {
+	if baseDir == "" {
+		baseDir = os.TempDir()
+	}
+
+	entries, err := os.ReadDir(baseDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Error("Failed to read execution working directory base", map[string]interface{}{
+				"component": "work_dir",
+				"path":      baseDir,
+				"error":     err.Error(),
+			})
+		}
+		return
+	}
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), executionWorkDirPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < maxAge {
+			// Possibly in use by a run in progress
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(baseDir, entry.Name())); err == nil {
+			removed++
+		}
+	}
+
+	if removed > 0 {
+		logger.Info("Swept orphaned execution working directories", map[string]interface{}{
+			"component": "work_dir",
+			"path":      baseDir,
+			"removed":   removed,
+		})
+	}
+}